@@ -0,0 +1,135 @@
+// Package auth recognises callers. Admins present the ADMIN_TOKEN shared by
+// the gateway and the services as "Authorization: Bearer <token>". Users
+// present a token from UserToken, which an admin obtains for them from the
+// user-service; it is signed with the same ADMIN_TOKEN, so every service can
+// check it without a lookup.
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"mopcare/problem"
+)
+
+// IsAdmin reports whether the Authorization header value carries token. An
+// empty token admits nobody, so admin-only features are off until one is
+// configured.
+func IsAdmin(authorization, token string) bool {
+	if token == "" {
+		return false
+	}
+	given, ok := strings.CutPrefix(authorization, "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// AdminActor is the actor recorded for writes made with the admin token.
+const AdminActor = "admin"
+
+// userTokenPrefix starts every user token: "user.<id>.<expires>.<signature>",
+// with expires in Unix seconds.
+const userTokenPrefix = "user."
+
+// UserToken returns a bearer token identifying userID until expires. An
+// empty key yields a token nobody accepts.
+func UserToken(userID int, expires time.Time, key string) string {
+	payload := strconv.Itoa(userID) + "." + strconv.FormatInt(expires.Unix(), 10)
+	return userTokenPrefix + payload + "." + userMAC(payload, key)
+}
+
+func userMAC(payload, key string) string {
+	m := hmac.New(sha256.New, []byte(key))
+	m.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
+
+// Caller is who is making a request: an admin, a user, or, as the zero
+// value, nobody in particular.
+type Caller struct {
+	Admin  bool
+	UserID int
+}
+
+// Identify reads the Authorization header value. It accepts the admin token
+// and unexpired user tokens signed with it; anything else is anonymous.
+func Identify(authorization, token string, now time.Time) Caller {
+	if IsAdmin(authorization, token) {
+		return Caller{Admin: true}
+	}
+	given, ok := strings.CutPrefix(authorization, "Bearer "+userTokenPrefix)
+	if !ok || token == "" {
+		return Caller{}
+	}
+	parts := strings.Split(given, ".")
+	if len(parts) != 3 || !hmac.Equal([]byte(parts[2]), []byte(userMAC(parts[0]+"."+parts[1], token))) {
+		return Caller{}
+	}
+	id, err := strconv.Atoi(parts[0])
+	expires, errExpires := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || errExpires != nil || id < 1 || now.Unix() >= expires {
+		return Caller{}
+	}
+	return Caller{UserID: id}
+}
+
+// Actor is the name revisions and uploads record for the caller: "admin",
+// "user:<id>", or "" for anonymous callers.
+func (c Caller) Actor() string {
+	switch {
+	case c.Admin:
+		return AdminActor
+	case c.UserID != 0:
+		return "user:" + strconv.Itoa(c.UserID)
+	}
+	return ""
+}
+
+type actorKey struct{}
+
+type callerKey struct{}
+
+// WithActor returns ctx carrying the name of who is making the request, for
+// audit records such as course revisions.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// WithCaller returns ctx carrying the caller, and their name as the actor.
+func WithCaller(ctx context.Context, c Caller) context.Context {
+	return context.WithValue(WithActor(ctx, c.Actor()), callerKey{}, c)
+}
+
+// CallerOf returns the caller stored by WithCaller, or the anonymous zero
+// Caller.
+func CallerOf(ctx context.Context) Caller {
+	c, _ := ctx.Value(callerKey{}).(Caller)
+	return c
+}
+
+// Actor returns the actor stored by WithActor, or "" for anonymous callers.
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// IncludeDeleted parses the raw ?include_deleted= value. Soft-deleted rows
+// are only shown to admins: anyone else asking for them gets a 403.
+func IncludeDeleted(raw, authorization, token string) (bool, error) {
+	if raw == "" {
+		return false, nil
+	}
+	include, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, problem.BadRequest(problem.CodeInvalidQuery, "include_deleted must be true or false")
+	}
+	if include && !IsAdmin(authorization, token) {
+		return false, problem.Forbidden(problem.CodeForbidden, "include_deleted requires an admin token")
+	}
+	return include, nil
+}
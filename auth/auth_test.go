@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"mopcare/problem"
+)
+
+func TestIsAdmin(t *testing.T) {
+	cases := []struct {
+		header, token string
+		want          bool
+	}{
+		{"Bearer secret", "secret", true},
+		{"Bearer wrong", "secret", false},
+		{"secret", "secret", false},
+		{"", "secret", false},
+		{"Bearer ", "", false},
+	}
+	for _, tc := range cases {
+		if got := IsAdmin(tc.header, tc.token); got != tc.want {
+			t.Errorf("IsAdmin(%q, %q) = %v, want %v", tc.header, tc.token, got, tc.want)
+		}
+	}
+}
+
+func TestIncludeDeleted(t *testing.T) {
+	cases := []struct {
+		raw, header string
+		want        bool
+		status      int
+	}{
+		{raw: "", want: false},
+		{raw: "false", want: false},
+		{raw: "true", header: "Bearer secret", want: true},
+		{raw: "true", status: 403},
+		{raw: "true", header: "Bearer nope", status: 403},
+		{raw: "maybe", header: "Bearer secret", status: 400},
+	}
+	for _, tc := range cases {
+		got, err := IncludeDeleted(tc.raw, tc.header, "secret")
+		var p *problem.Problem
+		switch {
+		case tc.status != 0 && (!errors.As(err, &p) || p.Status != tc.status):
+			t.Errorf("IncludeDeleted(%q, %q) error = %v, want status %d", tc.raw, tc.header, err, tc.status)
+		case tc.status == 0 && (err != nil || got != tc.want):
+			t.Errorf("IncludeDeleted(%q, %q) = %v, %v, want %v", tc.raw, tc.header, got, err, tc.want)
+		}
+	}
+}
+
+func TestActor(t *testing.T) {
+	ctx := context.Background()
+	if got := Actor(ctx); got != "" {
+		t.Errorf("Actor of a bare context = %q, want empty", got)
+	}
+	if got := Actor(WithActor(ctx, AdminActor)); got != AdminActor {
+		t.Errorf("Actor = %q, want %q", got, AdminActor)
+	}
+}
+
+func TestIdentify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	valid := UserToken(7, now.Add(time.Hour), "secret")
+	cases := []struct {
+		name, header string
+		want         Caller
+	}{
+		{"admin", "Bearer secret", Caller{Admin: true}},
+		{"user", "Bearer " + valid, Caller{UserID: 7}},
+		{"anonymous", "", Caller{}},
+		{"expired", "Bearer " + UserToken(7, now, "secret"), Caller{}},
+		{"other key", "Bearer " + UserToken(7, now.Add(time.Hour), "other"), Caller{}},
+		{"altered user", "Bearer user.8" + valid[len("user.7"):], Caller{}},
+		{"malformed", "Bearer user.7", Caller{}},
+	}
+	for _, tc := range cases {
+		if got := Identify(tc.header, "secret", now); got != tc.want {
+			t.Errorf("%s: Identify = %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+	if got := Identify("Bearer "+UserToken(7, now.Add(time.Hour), ""), "", now); got != (Caller{}) {
+		t.Errorf("Identify without a key = %+v, want anonymous", got)
+	}
+}
+
+func TestCaller(t *testing.T) {
+	ctx := WithCaller(context.Background(), Caller{UserID: 7})
+	if got := CallerOf(ctx); got.UserID != 7 {
+		t.Errorf("CallerOf = %+v, want user 7", got)
+	}
+	if got := Actor(ctx); got != "user:7" {
+		t.Errorf("Actor = %q, want user:7", got)
+	}
+	if got := CallerOf(context.Background()); got != (Caller{}) {
+		t.Errorf("CallerOf a bare context = %+v, want anonymous", got)
+	}
+}
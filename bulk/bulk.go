@@ -0,0 +1,128 @@
+// Package bulk reads CSV imports and writes CSV and JSON-lines exports for
+// the services' bulk endpoints. An import names its columns in a header row,
+// which a Mapping can match to fields under other names, and is validated
+// row by row into a Report before anything is written.
+package bulk
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"mopcare/problem"
+)
+
+// MaxRows is the most data rows one import may have.
+const MaxRows = 10000
+
+// MaxBytes is the largest import body the handlers read.
+const MaxBytes = 16 << 20
+
+// Import modes.
+const (
+	// ModeAtomic imports every row in one transaction, or none if any row
+	// is invalid.
+	ModeAtomic = "atomic"
+	// ModeBestEffort imports the valid rows one by one and reports the
+	// others.
+	ModeBestEffort = "best_effort"
+)
+
+// Mapping maps field names to the header of the column holding them, for
+// spreadsheets whose headers are not the field names.
+type Mapping map[string]string
+
+// Options are what an import request asks for.
+type Options struct {
+	Mapping Mapping
+	DryRun  bool
+	Mode    string
+}
+
+// ParseOptions reads ?map=<field>:<column> (repeatable), ?dry_run= and
+// ?mode=, which defaults to ModeAtomic.
+func ParseOptions(q url.Values) (Options, error) {
+	opts := Options{Mapping: Mapping{}, Mode: ModeAtomic}
+	for _, m := range q["map"] {
+		field, column, ok := strings.Cut(m, ":")
+		if !ok || strings.TrimSpace(field) == "" || strings.TrimSpace(column) == "" {
+			return Options{}, problem.BadRequest(problem.CodeInvalidQuery, fmt.Sprintf("map %q must be <field>:<column>", m))
+		}
+		opts.Mapping[strings.TrimSpace(field)] = strings.TrimSpace(column)
+	}
+	if raw := q.Get("dry_run"); raw != "" {
+		dryRun, err := strconv.ParseBool(raw)
+		if err != nil {
+			return Options{}, problem.BadRequest(problem.CodeInvalidQuery, "dry_run must be true or false")
+		}
+		opts.DryRun = dryRun
+	}
+	switch mode := q.Get("mode"); mode {
+	case "":
+	case ModeAtomic, ModeBestEffort:
+		opts.Mode = mode
+	default:
+		return Options{}, problem.BadRequest(problem.CodeInvalidQuery, "mode must be atomic or best_effort")
+	}
+	return opts, nil
+}
+
+// RowError is one thing wrong with one row. Field is empty when the row as
+// a whole is at fault.
+type RowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Report is the outcome of an import. Rows are numbered as in a
+// spreadsheet, so the header is row 1 and the first data row is row 2.
+type Report struct {
+	DryRun bool   `json:"dry_run"`
+	Mode   string `json:"mode"`
+	// Rows is how many data rows were read.
+	Rows int `json:"rows"`
+	// Imported is how many rows were written: none on a dry run or when an
+	// atomic import had errors.
+	Imported int        `json:"imported"`
+	Failed   int        `json:"failed"`
+	Errors   []RowError `json:"errors"`
+
+	failed map[int]bool
+}
+
+// NewReport starts the report of an import run with opts.
+func NewReport(opts Options) *Report {
+	return &Report{DryRun: opts.DryRun, Mode: opts.Mode, Errors: []RowError{}, failed: map[int]bool{}}
+}
+
+// Fail records that row is wrong about field.
+func (r *Report) Fail(row int, field, message string) {
+	r.Errors = append(r.Errors, RowError{Row: row, Field: field, Message: message})
+	if !r.failed[row] {
+		r.failed[row] = true
+		r.Failed++
+	}
+}
+
+// FailWith records err against row: the detail of a *problem.Problem, which
+// is what validation returns, or the error itself.
+func (r *Report) FailWith(row int, field string, err error) {
+	if p, ok := err.(*problem.Problem); ok {
+		r.Fail(row, field, p.Detail)
+		return
+	}
+	r.Fail(row, field, err.Error())
+}
+
+// HasFailed reports whether row has errors.
+func (r *Report) HasFailed(row int) bool {
+	return r.failed[row]
+}
+
+// Commit reports whether rows should be written: never on a dry run, and
+// in atomic mode only if no row failed.
+func (r *Report) Commit() bool {
+	return !r.DryRun && (r.Mode == ModeBestEffort || r.Failed == 0)
+}
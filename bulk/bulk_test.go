@@ -0,0 +1,151 @@
+package bulk
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"mopcare/problem"
+)
+
+func TestParseOptions(t *testing.T) {
+	opts, err := ParseOptions(url.Values{"map": {"first_name:Given name", "email: E-mail "}, "dry_run": {"true"}})
+	want := Options{Mapping: Mapping{"first_name": "Given name", "email": "E-mail"}, DryRun: true, Mode: ModeAtomic}
+	if err != nil || !reflect.DeepEqual(opts, want) {
+		t.Fatalf("ParseOptions = %+v, %v, want %+v", opts, err, want)
+	}
+	for _, q := range []url.Values{{"map": {"first_name"}}, {"dry_run": {"maybe"}}, {"mode": {"yolo"}}} {
+		var p *problem.Problem
+		if _, err := ParseOptions(q); !errors.As(err, &p) || p.Code != problem.CodeInvalidQuery {
+			t.Errorf("ParseOptions(%v) = %v, want INVALID_QUERY", q, err)
+		}
+	}
+}
+
+var fields = []string{"first_name", "last_name", "email"}
+
+func read(t *testing.T, csv string, m Mapping) ([]Row, error) {
+	t.Helper()
+	r, err := NewReader(strings.NewReader(csv), fields, []string{"email"}, m)
+	if err != nil {
+		return nil, err
+	}
+	return r.ReadAll()
+}
+
+func TestReaderMatchesColumns(t *testing.T) {
+	rows, err := read(t, "\ufeffFirst Name,Surname,E-mail,Room\n Ada ,Lovelace,ada@example.com,12\n,,,\n\"Grace\nBrewster\",Hopper,grace@example.com\n",
+		Mapping{"last_name": "surname", "email": "E-mail"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("rows = %+v, want the blank one skipped", rows)
+	}
+	if rows[0].Number != 2 || rows[0].Get("first_name") != "Ada" || rows[0].Get("last_name") != "Lovelace" || rows[0].Get("email") != "ada@example.com" {
+		t.Errorf("first row = %+v", rows[0])
+	}
+	if rows[1].Number != 4 || rows[1].Get("first_name") != "Grace\nBrewster" {
+		t.Errorf("second row = %+v, want row 4 however many lines row 2 takes", rows[1])
+	}
+}
+
+func TestReaderRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name, csv string
+		m         Mapping
+		code      string
+	}{
+		{"empty", "", nil, problem.CodeInvalidBody},
+		{"malformed", "email\n\"unterminated\n", nil, problem.CodeInvalidBody},
+		{"missing required column", "first_name,last_name\nAda,Lovelace\n", nil, problem.CodeValidationFailed},
+		{"mapped column not in header", "email\na@example.com\n", Mapping{"first_name": "Given"}, problem.CodeValidationFailed},
+		{"unknown field mapped", "email\na@example.com\n", Mapping{"phone": "Phone"}, problem.CodeInvalidQuery},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var p *problem.Problem
+			if _, err := read(t, tc.csv, tc.m); !errors.As(err, &p) || p.Code != tc.code {
+				t.Fatalf("err = %v, want %s", err, tc.code)
+			}
+		})
+	}
+}
+
+func TestReaderLimitsRows(t *testing.T) {
+	csv := "email\n" + strings.Repeat("a@example.com\n", MaxRows+1)
+	var p *problem.Problem
+	if _, err := read(t, csv, nil); !errors.As(err, &p) || p.Code != problem.CodeValidationFailed {
+		t.Fatalf("err = %v, want VALIDATION_FAILED", err)
+	}
+}
+
+func TestReport(t *testing.T) {
+	r := NewReport(Options{Mode: ModeAtomic})
+	if !r.Commit() {
+		t.Error("an atomic import without errors is not committed")
+	}
+	r.Fail(2, "email", "email is required")
+	r.FailWith(2, "", problem.Conflict(problem.CodeUserEmailTaken, "taken"))
+	if r.Failed != 1 || len(r.Errors) != 2 || r.Errors[1].Message != "taken" || !r.HasFailed(2) || r.HasFailed(3) {
+		t.Errorf("report = %+v", r)
+	}
+	if r.Commit() {
+		t.Error("an atomic import with errors is committed")
+	}
+	if !NewReport(Options{Mode: ModeBestEffort}).Commit() || NewReport(Options{Mode: ModeBestEffort, DryRun: true}).Commit() {
+		t.Error("best effort imports are committed unless they are dry runs")
+	}
+}
+
+type person struct {
+	Name string `json:"name"`
+}
+
+func TestWriter(t *testing.T) {
+	var out strings.Builder
+	w := NewWriter(&out, FormatCSV, "name", "note")
+	if err := w.Close(); err != nil || out.String() != "name,note\n" {
+		t.Fatalf("empty CSV export = %q, %v", out.String(), err)
+	}
+
+	out.Reset()
+	w = NewWriter(&out, FormatCSV, "name", "note")
+	w.Write(person{"Ada"}, "Ada", "says \"hi\", twice")
+	if err := w.Close(); err != nil || out.String() != "name,note\nAda,\"says \"\"hi\"\", twice\"\n" {
+		t.Fatalf("CSV export = %q, %v", out.String(), err)
+	}
+
+	out.Reset()
+	w = NewWriter(&out, FormatJSONLines, "name")
+	w.Write(person{"Ada"}, "Ada")
+	w.Write(person{"Grace"}, "Grace")
+	if err := w.Close(); err != nil || out.String() != "{\"name\":\"Ada\"}\n{\"name\":\"Grace\"}\n" {
+		t.Fatalf("JSON lines export = %q, %v", out.String(), err)
+	}
+}
+
+func TestResponseSendsHeadersWithFirstBytes(t *testing.T) {
+	rec := httptest.NewRecorder()
+	resp := NewResponse(rec, FormatJSONLines, "users")
+	resp.Flush()
+	if resp.Started() || rec.Header().Get("Content-Type") != "" {
+		t.Fatal("headers were set before anything was written")
+	}
+	io.WriteString(resp, "{}\n")
+	if !resp.Started() || rec.Header().Get("Content-Type") != "application/x-ndjson" ||
+		rec.Header().Get("Content-Disposition") != `attachment; filename="users.jsonl"` {
+		t.Fatalf("headers = %v", rec.Header())
+	}
+}
+
+func TestCell(t *testing.T) {
+	for in, want := range map[string]string{"Ada": "Ada", "=HYPERLINK(1)": "'=HYPERLINK(1)", "@SUM": "'@SUM", "": ""} {
+		if got := Cell(in); got != want {
+			t.Errorf("Cell(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package bulk
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"mopcare/problem"
+)
+
+// Row is one data row of an import.
+type Row struct {
+	// Number is the row's number in the spreadsheet; the header is row 1.
+	Number int
+	values map[string]string
+}
+
+// Get returns the field's value without surrounding spaces, or "" if the
+// import has no column for it.
+func (r Row) Get(field string) string {
+	return r.values[field]
+}
+
+// Reader reads the rows of a CSV import.
+type Reader struct {
+	csv     *csv.Reader
+	columns map[string]int // field -> column index
+	// records counts the records read after the header, blank or not.
+	records int
+	rows    int
+}
+
+// NewReader reads the header and finds the column of each field: the one
+// the mapping names, or else the one headed like the field, ignoring case,
+// spaces, underscores and hyphens. Fields in required must have a column;
+// columns no field matches are ignored.
+func NewReader(r io.Reader, fields, required []string, m Mapping) (*Reader, error) {
+	known := map[string]bool{}
+	for _, f := range fields {
+		known[f] = true
+	}
+	for field := range m {
+		if !known[field] {
+			return nil, problem.BadRequest(problem.CodeInvalidQuery,
+				fmt.Sprintf("Cannot map unknown field %q; the fields are %s", field, strings.Join(fields, ", ")))
+		}
+	}
+
+	c := csv.NewReader(r)
+	c.FieldsPerRecord = -1
+	header, err := c.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, problem.BadRequest(problem.CodeInvalidBody, "The CSV is empty; its first row must name the columns")
+	}
+	if err != nil {
+		return nil, invalidCSV(err)
+	}
+	// Spreadsheet programs like to start UTF-8 files with a byte order mark.
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	index := map[string]int{}
+	for i, h := range header {
+		if _, dup := index[normalize(h)]; !dup {
+			index[normalize(h)] = i
+		}
+	}
+
+	reader := &Reader{csv: c, columns: map[string]int{}}
+	for _, field := range fields {
+		column, mapped := m[field]
+		if !mapped {
+			column = field
+		}
+		i, ok := index[normalize(column)]
+		if !ok && mapped {
+			return nil, problem.BadRequest(problem.CodeValidationFailed, fmt.Sprintf("Column %q mapped to %s is not in the header", column, field))
+		}
+		if ok {
+			reader.columns[field] = i
+		}
+	}
+	for _, field := range required {
+		if _, ok := reader.columns[field]; !ok {
+			return nil, problem.BadRequest(problem.CodeValidationFailed,
+				fmt.Sprintf("No column for %s; name one %q or map one to it with ?map=%s:<column>", field, field, field))
+		}
+	}
+	return reader, nil
+}
+
+// normalize lets "First Name", "first-name" and "FIRST_NAME" all head the
+// first_name column.
+func normalize(header string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '_' || r == '-' {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, header)
+}
+
+func invalidCSV(err error) error {
+	return problem.BadRequest(problem.CodeInvalidBody, "The body is not valid CSV: "+err.Error())
+}
+
+// Next returns the next row that is not blank, or io.EOF after the last.
+func (r *Reader) Next() (Row, error) {
+	for {
+		record, err := r.csv.Read()
+		if errors.Is(err, io.EOF) {
+			return Row{}, io.EOF
+		}
+		if err != nil {
+			return Row{}, invalidCSV(err)
+		}
+		r.records++
+		row := Row{Number: r.records + 1, values: map[string]string{}}
+		blank := true
+		for field, i := range r.columns {
+			if i < len(record) {
+				row.values[field] = strings.TrimSpace(record[i])
+				blank = blank && row.values[field] == ""
+			}
+		}
+		if blank {
+			continue
+		}
+		if r.rows++; r.rows > MaxRows {
+			return Row{}, problem.BadRequest(problem.CodeValidationFailed, fmt.Sprintf("An import may have at most %d rows", MaxRows))
+		}
+		return row, nil
+	}
+}
+
+// ReadAll returns every row that is not blank.
+func (r *Reader) ReadAll() ([]Row, error) {
+	var rows []Row
+	for {
+		row, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+}
@@ -0,0 +1,181 @@
+package bulk
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"mopcare/problem"
+)
+
+// Format is how an export is written.
+type Format string
+
+// Export formats.
+const (
+	FormatCSV       Format = "csv"
+	FormatJSONLines Format = "jsonl"
+)
+
+// ParseFormat reads ?format=, which defaults to CSV.
+func ParseFormat(raw string) (Format, error) {
+	switch Format(raw) {
+	case "", FormatCSV:
+		return FormatCSV, nil
+	case FormatJSONLines:
+		return FormatJSONLines, nil
+	}
+	return "", problem.BadRequest(problem.CodeInvalidQuery, "format must be csv or jsonl")
+}
+
+func (f Format) ContentType() string {
+	if f == FormatJSONLines {
+		return "application/x-ndjson"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// flushEvery is how many rows are written between flushes, so a long export
+// reaches the client as it is read rather than all at the end.
+const flushEvery = 100
+
+// Writer writes the rows of an export.
+type Writer struct {
+	out     io.Writer
+	format  Format
+	buf     *bufio.Writer
+	csv     *csv.Writer
+	json    *json.Encoder
+	header  []string
+	started bool
+	rows    int
+}
+
+// NewWriter writes rows in format to out. header names the CSV columns;
+// JSON lines carry whatever each row marshals to.
+func NewWriter(out io.Writer, format Format, header ...string) *Writer {
+	w := &Writer{out: out, format: format, buf: bufio.NewWriter(out), header: header}
+	if format == FormatJSONLines {
+		w.json = json.NewEncoder(w.buf)
+	} else {
+		w.csv = csv.NewWriter(w.buf)
+	}
+	return w
+}
+
+// Write writes one row: v as a JSON line, or record as a CSV row with the
+// columns of the header.
+func (w *Writer) Write(v interface{}, record ...string) error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	var err error
+	if w.json != nil {
+		err = w.json.Encode(v)
+	} else {
+		err = w.csv.Write(record)
+	}
+	if err != nil {
+		return err
+	}
+	if w.rows++; w.rows%flushEvery == 0 {
+		return w.flush()
+	}
+	return nil
+}
+
+// Close writes whatever is buffered, including the CSV header of an export
+// without rows.
+func (w *Writer) Close() error {
+	if err := w.start(); err != nil {
+		return err
+	}
+	return w.flush()
+}
+
+func (w *Writer) start() error {
+	if w.started {
+		return nil
+	}
+	w.started = true
+	if w.csv == nil {
+		return nil
+	}
+	return w.csv.Write(w.header)
+}
+
+func (w *Writer) flush() error {
+	if w.csv != nil {
+		w.csv.Flush()
+		if err := w.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if err := w.buf.Flush(); err != nil {
+		return err
+	}
+	if f, ok := w.out.(http.Flusher); ok {
+		f.Flush()
+	}
+	return nil
+}
+
+// Cell makes text safe to open in a spreadsheet, which would run a cell
+// starting with =, +, - or @ as a formula, by prefixing such text with a
+// quote.
+func Cell(text string) string {
+	if text != "" && strings.ContainsRune("=+-@\t\r", rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
+
+// Response is the HTTP response of an export. Its headers only go out with
+// its first bytes, so an export that fails before writing anything can still
+// answer with a problem.
+type Response struct {
+	w       http.ResponseWriter
+	format  Format
+	name    string
+	started bool
+}
+
+// NewResponse sends an export in format as a download called name plus the
+// format's extension.
+func NewResponse(w http.ResponseWriter, format Format, name string) *Response {
+	return &Response{w: w, format: format, name: name}
+}
+
+func (r *Response) Write(p []byte) (int, error) {
+	if !r.started {
+		r.started = true
+		h := r.w.Header()
+		h.Set("Content-Type", r.format.ContentType())
+		h.Set("Content-Disposition", `attachment; filename="`+r.name+"."+string(r.format)+`"`)
+		h.Set("Cache-Control", "no-store")
+	}
+	return r.w.Write(p)
+}
+
+// Flush sends what was written so far, if anything was.
+func (r *Response) Flush() {
+	if f, ok := r.w.(http.Flusher); ok && r.started {
+		f.Flush()
+	}
+}
+
+// Close sends the headers of an export that wrote nothing, as a JSON-lines
+// export without rows does.
+func (r *Response) Close() {
+	if !r.started {
+		r.Write(nil)
+	}
+}
+
+// Started reports whether any of the export was sent.
+func (r *Response) Started() bool {
+	return r.started
+}
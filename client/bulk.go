@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+// Import modes.
+const (
+	// ImportAtomic imports every row, or none if any row is invalid.
+	ImportAtomic = "atomic"
+	// ImportBestEffort imports the valid rows and reports the others.
+	ImportBestEffort = "best_effort"
+)
+
+// Export formats.
+const (
+	ExportCSV       = "csv"
+	ExportJSONLines = "jsonl"
+)
+
+// ImportOptions control an import. Zero values import every row
+// atomically, matching columns to fields by header.
+type ImportOptions struct {
+	// Mapping maps fields to the headers of the columns holding them.
+	Mapping map[string]string
+	// DryRun only validates the rows and reports.
+	DryRun bool
+	Mode   string
+}
+
+func (o ImportOptions) query() url.Values {
+	q := url.Values{}
+	fields := make([]string, 0, len(o.Mapping))
+	for field := range o.Mapping {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		q.Add("map", field+":"+o.Mapping[field])
+	}
+	if o.DryRun {
+		q.Set("dry_run", "true")
+	}
+	if o.Mode != "" {
+		q.Set("mode", o.Mode)
+	}
+	return q
+}
+
+// ImportUsers creates users from a CSV with first_name, last_name and email
+// columns. It needs an admin Config.Token. Invalid rows do not fail the call;
+// they are listed in the report.
+func (c *Client) ImportUsers(ctx context.Context, csv []byte, opts ImportOptions) (*ImportReport, error) {
+	return c.importCSV(ctx, "/users/import", csv, opts)
+}
+
+// ImportEnrollments enrolls users in courses from a CSV with user_id or
+// email, course_id and, optionally, status columns. It needs an admin
+// Config.Token.
+func (c *Client) ImportEnrollments(ctx context.Context, csv []byte, opts ImportOptions) (*ImportReport, error) {
+	return c.importCSV(ctx, "/enrollments/import", csv, opts)
+}
+
+func (c *Client) importCSV(ctx context.Context, path string, csv []byte, opts ImportOptions) (*ImportReport, error) {
+	var report ImportReport
+	// Only dry runs change nothing and may be retried.
+	r := call{method: "POST", path: path, query: opts.query(), body: csv, contentType: "text/csv", out: &report, idempotent: opts.DryRun}
+	if err := c.do(ctx, r); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ExportUsers returns every user in format, ExportCSV if empty; deleted
+// users too if includeDeleted is set. It needs an admin Config.Token.
+func (c *Client) ExportUsers(ctx context.Context, format string, includeDeleted bool) ([]byte, error) {
+	q := url.Values{}
+	if includeDeleted {
+		q.Set("include_deleted", "true")
+	}
+	return c.export(ctx, "/users/export", format, q)
+}
+
+// ExportEnrollments returns the enrollments in a course, or in every course
+// if courseID is 0. It needs an admin Config.Token.
+func (c *Client) ExportEnrollments(ctx context.Context, format string, courseID int) ([]byte, error) {
+	q := url.Values{}
+	if courseID != 0 {
+		q.Set("course_id", strconv.Itoa(courseID))
+	}
+	return c.export(ctx, "/enrollments/export", format, q)
+}
+
+// ExportPayments returns every payment, oldest first. It needs an admin
+// Config.Token.
+func (c *Client) ExportPayments(ctx context.Context, format string) ([]byte, error) {
+	return c.export(ctx, "/payments/export", format, url.Values{})
+}
+
+func (c *Client) export(ctx context.Context, path, format string, q url.Values) ([]byte, error) {
+	if format != "" {
+		q.Set("format", format)
+	}
+	var data []byte
+	if err := c.do(ctx, call{method: "GET", path: path, query: q, out: &data, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// BrowseCourses returns one page of the courses matching opts together with
+// the total and facet counts over every match.
+func (c *Client) BrowseCourses(ctx context.Context, opts ListOptions) (*Catalog, error) {
+	var catalog Catalog
+	if err := c.do(ctx, call{method: "GET", path: "/courses/catalog", query: opts.query(), out: &catalog, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &catalog, nil
+}
+
+// ListCategories returns every category, ordered by name. Categories form a
+// tree through ParentID.
+func (c *Client) ListCategories(ctx context.Context) ([]Category, error) {
+	var categories []Category
+	err := c.do(ctx, call{method: "GET", path: "/categories", out: &categories, idempotent: true})
+	return categories, err
+}
+
+func (c *Client) GetCategory(ctx context.Context, id int) (*Category, error) {
+	var category Category
+	if err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/categories/%d", id), out: &category, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// CreateCategory needs an admin Config.Token.
+func (c *Client) CreateCategory(ctx context.Context, in CategoryInput) (*Category, error) {
+	var category Category
+	if err := c.do(ctx, call{method: "POST", path: "/categories", body: in, out: &category}); err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// UpdateCategory replaces the category's parent, name and slug. It needs an
+// admin Config.Token.
+func (c *Client) UpdateCategory(ctx context.Context, id int, in CategoryInput) (*Category, error) {
+	var category Category
+	if err := c.do(ctx, call{method: "PUT", path: fmt.Sprintf("/categories/%d", id), body: in, out: &category, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &category, nil
+}
+
+// DeleteCategory fails with ErrConflict while the category has
+// subcategories or courses. It needs an admin Config.Token.
+func (c *Client) DeleteCategory(ctx context.Context, id int) error {
+	return c.do(ctx, call{method: "DELETE", path: fmt.Sprintf("/categories/%d", id), idempotent: true})
+}
+
+// ListTags counts the tags on the courses the caller may see, most used
+// first. Only Status and IncludeDeleted of opts apply.
+func (c *Client) ListTags(ctx context.Context, opts ListOptions) ([]TagCount, error) {
+	var tags []TagCount
+	err := c.do(ctx, call{method: "GET", path: "/tags", query: opts.query(), out: &tags, idempotent: true})
+	return tags, err
+}
+
+// RenameTag renames tag on every course, merging it into to where a course
+// already has both. It needs an admin Config.Token.
+func (c *Client) RenameTag(ctx context.Context, tag, to string) (*TagRename, error) {
+	var rename TagRename
+	err := c.do(ctx, call{method: "PUT", path: "/tags/" + url.PathEscape(tag), body: TagRenameInput{Tag: to}, out: &rename, idempotent: true})
+	if err != nil {
+		return nil, err
+	}
+	return &rename, nil
+}
+
+// DeleteTag removes tag from every course. It needs an admin Config.Token.
+func (c *Client) DeleteTag(ctx context.Context, tag string) error {
+	return c.do(ctx, call{method: "DELETE", path: "/tags/" + url.PathEscape(tag), idempotent: true})
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// ListCertificates returns one page of a user's certificates, oldest first.
+// Config.Token must belong to that user or be the admin token.
+func (c *Client) ListCertificates(ctx context.Context, userID int, opts ListOptions) ([]Certificate, error) {
+	var certificates []Certificate
+	err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/users/%d/certificates", userID), query: opts.query(), out: &certificates, idempotent: true})
+	return certificates, err
+}
+
+// VerifyCertificate looks a certificate up by its code and needs no token.
+// Unknown and revoked codes fail with ErrNotFound.
+func (c *Client) VerifyCertificate(ctx context.Context, code string) (*CertificateVerification, error) {
+	var verification CertificateVerification
+	if err := c.do(ctx, call{method: "GET", path: "/certificates/" + url.PathEscape(code) + "/verify", out: &verification, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &verification, nil
+}
+
+// DownloadCertificate returns the certificate as a PDF. Only its learner and
+// admins may download it; anyone else gets ErrNotFound.
+func (c *Client) DownloadCertificate(ctx context.Context, code string) ([]byte, error) {
+	var data []byte
+	if err := c.do(ctx, call{method: "GET", path: "/certificates/" + url.PathEscape(code) + "/pdf", out: &data, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
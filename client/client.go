@@ -0,0 +1,295 @@
+// Package client is a typed Go client for the Mopcare API gateway. Request
+// and response types are generated from the OpenAPI document; every call
+// takes a context, idempotent calls are retried on transient failures, list
+// endpoints have page iterators, and API errors come back as *Error.
+package client
+
+//go:generate go run mopcare/cmd/genclient -o types_gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config configures a Client. Only BaseURL is required.
+type Config struct {
+	// BaseURL is the gateway address, e.g. "http://localhost:9090".
+	BaseURL string
+	// HTTPClient defaults to a client with a 30s timeout.
+	HTTPClient *http.Client
+	// MaxRetries is how many times an idempotent call is retried after a
+	// network error or a 429/502/503/504. Zero means the default of 3; a
+	// negative value disables retries.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubled for each one
+	// after it. Defaults to 200ms.
+	RetryBackoff time.Duration
+	// UserAgent is sent with every request.
+	UserAgent string
+	// Token, when set, is sent as "Authorization: Bearer <Token>". It is
+	// either the admin token or a user token from IssueUserToken; writes to
+	// courses need one or the other.
+	Token string
+}
+
+// Client calls the Mopcare API. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	http       *http.Client
+	maxRetries int
+	backoff    time.Duration
+	userAgent  string
+	token      string
+}
+
+// New returns a client for cfg, filling in defaults for zero fields.
+func New(cfg Config) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(cfg.BaseURL, "/"),
+		http:       cfg.HTTPClient,
+		maxRetries: cfg.MaxRetries,
+		backoff:    cfg.RetryBackoff,
+		userAgent:  cfg.UserAgent,
+		token:      cfg.Token,
+	}
+	if c.http == nil {
+		c.http = &http.Client{Timeout: 30 * time.Second}
+	}
+	if c.maxRetries == 0 {
+		c.maxRetries = 3
+	} else if c.maxRetries < 0 {
+		c.maxRetries = 0
+	}
+	if c.backoff == 0 {
+		c.backoff = 200 * time.Millisecond
+	}
+	if c.userAgent == "" {
+		c.userAgent = "mopcare-go-client"
+	}
+	return c
+}
+
+// ListOptions selects one page of a list endpoint. Zero values are omitted,
+// which returns every row.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	// IncludeDeleted also returns soft-deleted rows. It needs an admin
+	// Config.Token; without one the call fails with ErrForbidden.
+	IncludeDeleted bool
+	// Status only returns courses in that workflow status. Anything but
+	// "published" needs an admin Config.Token. Other lists ignore it.
+	Status string
+	// Role only returns the courses an instructor holds that role on. Other
+	// lists ignore it.
+	Role string
+
+	// The catalog filters narrow course lists; other lists ignore them.
+
+	// Category is a category ID or slug; courses in categories below it
+	// match too.
+	Category string
+	// Tags only returns courses carrying every one of them.
+	Tags                     []string
+	MinPrice, MaxPrice       *float64
+	MinDuration, MaxDuration *int
+	// FreePreview only returns courses that do, or do not, have a free
+	// preview series.
+	FreePreview *bool
+}
+
+func (o ListOptions) query() url.Values {
+	q := url.Values{}
+	if o.Limit > 0 {
+		q.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Offset > 0 {
+		q.Set("offset", strconv.Itoa(o.Offset))
+	}
+	if o.IncludeDeleted {
+		q.Set("include_deleted", "true")
+	}
+	if o.Status != "" {
+		q.Set("status", o.Status)
+	}
+	if o.Role != "" {
+		q.Set("role", o.Role)
+	}
+	if o.Category != "" {
+		q.Set("category", o.Category)
+	}
+	for _, tag := range o.Tags {
+		q.Add("tag", tag)
+	}
+	if o.MinPrice != nil {
+		q.Set("min_price", strconv.FormatFloat(*o.MinPrice, 'f', -1, 64))
+	}
+	if o.MaxPrice != nil {
+		q.Set("max_price", strconv.FormatFloat(*o.MaxPrice, 'f', -1, 64))
+	}
+	if o.MinDuration != nil {
+		q.Set("min_duration", strconv.Itoa(*o.MinDuration))
+	}
+	if o.MaxDuration != nil {
+		q.Set("max_duration", strconv.Itoa(*o.MaxDuration))
+	}
+	if o.FreePreview != nil {
+		q.Set("free_preview", strconv.FormatBool(*o.FreePreview))
+	}
+	return q
+}
+
+// call describes one API request.
+type call struct {
+	method string
+	path   string
+	query  url.Values
+	// body is sent as is when it is a []byte and as JSON otherwise.
+	body interface{}
+	// out receives the raw response when it is a *[]byte and the decoded
+	// JSON otherwise.
+	out interface{}
+	// contentType overrides application/json for the body.
+	contentType string
+	// ifMatch, when non-zero, makes the write conditional on that version.
+	ifMatch int
+	// idempotent calls may be retried; anything that changes state
+	// cumulatively (creating rows, recording payments) must not be.
+	idempotent bool
+}
+
+func (c *Client) do(ctx context.Context, r call) error {
+	var payload []byte
+	switch body := r.body.(type) {
+	case nil:
+	case []byte:
+		payload = body
+	default:
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("mopcare: encoding request: %w", err)
+		}
+	}
+	target := c.baseURL + r.path
+	if len(r.query) > 0 {
+		target += "?" + r.query.Encode()
+	}
+
+	retries := 0
+	if r.idempotent {
+		retries = c.maxRetries
+	}
+	for attempt := 0; ; attempt++ {
+		wait, err := c.attempt(ctx, r, target, payload)
+		if err == nil || attempt >= retries || wait < 0 {
+			return err
+		}
+		if wait == 0 {
+			wait = c.backoff << attempt
+			wait += time.Duration(rand.Int63n(int64(wait)/2 + 1))
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// attempt performs one round trip. It returns a negative wait for errors that
+// must not be retried, zero to use the default backoff, or the server's
+// Retry-After hint.
+func (c *Client) attempt(ctx context.Context, r call, target string, payload []byte) (time.Duration, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, target, body)
+	if err != nil {
+		return -1, fmt.Errorf("mopcare: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if payload != nil {
+		contentType := r.contentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
+	if r.ifMatch != 0 {
+		req.Header.Set("If-Match", `"`+strconv.Itoa(r.ifMatch)+`"`)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return -1, ctx.Err()
+		}
+		return 0, fmt.Errorf("mopcare: %s %s: %w", r.method, r.path, err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("mopcare: reading response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		apiErr := newError(resp.StatusCode, raw)
+		if !retryable(resp.StatusCode) {
+			return -1, apiErr
+		}
+		return retryAfter(resp.Header.Get("Retry-After")), apiErr
+	}
+	if out, ok := r.out.(*[]byte); ok {
+		*out = raw
+		return 0, nil
+	}
+	if r.out != nil && len(bytes.TrimSpace(raw)) > 0 {
+		if err := json.Unmarshal(raw, r.out); err != nil {
+			return -1, fmt.Errorf("mopcare: decoding %s %s response: %w", r.method, r.path, err)
+		}
+	}
+	return 0, nil
+}
+
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter parses a Retry-After header given in seconds.
+func retryAfter(header string) time.Duration {
+	if secs, err := strconv.Atoi(header); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return 0
+}
+
+// notFoundIsEmpty turns the 404 some list endpoints answer with when there is
+// nothing to list into an empty result.
+func notFoundIsEmpty(err error, code string) error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound && apiErr.Problem.Code == code {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,993 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"mopcare/notify"
+	"mopcare/openapi"
+	"mopcare/problem"
+)
+
+func TestCoursesAndSeries(t *testing.T) {
+	_, c := newFake(t)
+	ctx := context.Background()
+
+	course, err := c.CreateCourse(ctx, CourseInput{Title: "Heart Health", Content: "Cardio basics"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetCourse(ctx, course.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetCourse on a draft = %v, want ErrNotFound", err)
+	}
+	if course, err = c.SubmitCourse(ctx, course.ID, course.Version); err != nil || course.Status != "in_review" {
+		t.Fatalf("SubmitCourse = %+v, %v", course, err)
+	}
+	if _, err := c.SubmitCourse(ctx, course.ID, 0); !errors.Is(err, ErrConflict) || Code(err) != problem.CodeCourseStatusConflict {
+		t.Fatalf("SubmitCourse twice = %v, want ErrConflict", err)
+	}
+	if course, err = c.PublishCourse(ctx, course.ID, nil, 0); err != nil || course.Status != "published" || course.PublishedAt == nil {
+		t.Fatalf("PublishCourse = %+v, %v", course, err)
+	}
+	if err := c.UpdateCourse(ctx, course.ID, CourseInput{Title: "Heart Health After 65", Content: "Cardio basics"}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GetCourse(ctx, course.ID)
+	if err != nil || got.Title != "Heart Health After 65" || got.CreatedAt.IsZero() {
+		t.Fatalf("GetCourse = %+v, %v", got, err)
+	}
+
+	cover := "cover.png"
+	patched, err := c.PatchCourse(ctx, course.ID, CoursePatch{CoverImageURL: &cover}, got.Version)
+	if err != nil || patched.CoverImageURL != cover || patched.Title != got.Title || patched.Version != got.Version+1 {
+		t.Fatalf("PatchCourse = %+v, %v", patched, err)
+	}
+	if _, err := c.PatchCourse(ctx, course.ID, map[string]interface{}{"cover_image_url": nil}, got.Version); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("PatchCourse with stale version = %v, want ErrPreconditionFailed", err)
+	}
+	if patched, err = c.PatchCourse(ctx, course.ID, map[string]interface{}{"cover_image_url": nil}, 0); err != nil || patched.CoverImageURL != "" {
+		t.Fatalf("PatchCourse clearing cover = %+v, %v", patched, err)
+	}
+
+	series, err := c.CreateSeries(ctx, course.ID, SeriesInput{Title: "Blood pressure"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UpdateSeries(ctx, series.ID, SeriesInput{Title: "Blood pressure", Description: "Basics"}); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := c.GetSeries(ctx, series.ID); err != nil || s.Description != "Basics" || s.CourseID != course.ID {
+		t.Fatalf("GetSeries = %+v, %v", s, err)
+	}
+	if s, err := c.PatchSeries(ctx, series.ID, SeriesPatch{Title: "Hypertension"}, 0); err != nil || s.Title != "Hypertension" || s.Description != "Basics" {
+		t.Fatalf("PatchSeries = %+v, %v", s, err)
+	}
+	all, err := c.CourseSeries(course.ID, 0).All(ctx)
+	if err != nil || len(all) != 1 {
+		t.Fatalf("CourseSeries = %+v, %v", all, err)
+	}
+
+	if err := c.DeleteSeries(ctx, series.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteCourse(ctx, course.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetCourse(ctx, course.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetCourse after delete = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUsersPaymentsAndEnrollments(t *testing.T) {
+	_, c := newFake(t)
+	ctx := context.Background()
+
+	users, err := c.ListUsers(ctx, ListOptions{})
+	if err != nil || len(users) != 0 {
+		t.Fatalf("empty ListUsers = %v, %v", users, err)
+	}
+	user, err := c.CreateUser(ctx, UserInput{FirstName: "Margaret", LastName: "Johnson", Email: "margaret@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.RecordPayment(ctx, user.ID, 49.5); err != nil {
+		t.Fatal(err)
+	}
+	course, err := c.CreateCourse(ctx, CourseInput{Title: "T", Content: "C"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if list, err := c.ListUserEnrollments(ctx, user.ID, ListOptions{}); err != nil || len(list) != 0 {
+		t.Fatalf("empty ListUserEnrollments = %v, %v", list, err)
+	}
+	enrollment, err := c.Enroll(ctx, EnrollmentInput{UserID: user.ID, CourseID: course.ID, Status: "enrolled"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile, err := c.GetUserProfile(ctx, user.ID)
+	if err != nil || profile.TotalAmountPaid != 49.5 || profile.EnrolledCoursesCount != 1 {
+		t.Fatalf("GetUserProfile = %+v, %v", profile, err)
+	}
+	list, err := c.UserEnrollments(user.ID, 10).All(ctx)
+	if err != nil || len(list) != 1 || list[0].ID != enrollment.ID {
+		t.Fatalf("UserEnrollments = %+v, %v", list, err)
+	}
+
+	if err := c.DeleteEnrollment(ctx, enrollment.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetUser(ctx, user.ID); Code(err) != problem.CodeUserNotFound {
+		t.Fatalf("GetUser after delete = %v", err)
+	}
+}
+
+func TestSoftDeleteAndRestore(t *testing.T) {
+	f, c := newFake(t)
+	admin := New(Config{BaseURL: f.url, Token: fakeAdminToken})
+	ctx := context.Background()
+
+	user, err := c.CreateUser(ctx, UserInput{FirstName: "Margaret", LastName: "Johnson", Email: "margaret@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DeleteUser(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ListUsers(ctx, ListOptions{IncludeDeleted: true}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("ListUsers with IncludeDeleted and no token = %v, want ErrForbidden", err)
+	}
+	users, err := admin.ListUsers(ctx, ListOptions{IncludeDeleted: true})
+	if err != nil || len(users) != 1 || users[0].DeletedAt == nil {
+		t.Fatalf("admin ListUsers = %+v, %v", users, err)
+	}
+
+	if _, err := c.RestoreUser(ctx, user.ID); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("RestoreUser without the admin token = %v, want ErrForbidden", err)
+	}
+	restored, err := admin.RestoreUser(ctx, user.ID)
+	if err != nil || restored.DeletedAt != nil {
+		t.Fatalf("RestoreUser = %+v, %v", restored, err)
+	}
+	if _, err := c.GetUser(ctx, user.ID); err != nil {
+		t.Fatalf("GetUser after restore = %v", err)
+	}
+	if _, err := c.RestoreCourse(ctx, 99); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("RestoreCourse on missing course = %v", err)
+	}
+}
+
+func TestScheduledPublishAndStatusFilter(t *testing.T) {
+	f, c := newFake(t)
+	admin := New(Config{BaseURL: f.url, Token: fakeAdminToken})
+	ctx := context.Background()
+
+	course, err := c.CreateCourse(ctx, CourseInput{Title: "T", Content: "C"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	scheduled, err := c.PublishCourse(ctx, course.ID, &at, course.Version)
+	if err != nil || scheduled.Status != "draft" || scheduled.PublishAt == nil || !scheduled.PublishAt.Equal(at) {
+		t.Fatalf("scheduled PublishCourse = %+v, %v", scheduled, err)
+	}
+	if _, err := c.ListCourses(ctx, ListOptions{Status: "draft"}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("ListCourses of drafts without a token = %v, want ErrForbidden", err)
+	}
+	drafts, err := admin.ListCourses(ctx, ListOptions{Status: "draft"})
+	if err != nil || len(drafts) != 1 {
+		t.Fatalf("admin ListCourses of drafts = %+v, %v", drafts, err)
+	}
+	if archived, err := c.ArchiveCourse(ctx, course.ID, 0); err != nil || archived.Status != "archived" || archived.PublishAt != nil {
+		t.Fatalf("ArchiveCourse = %+v, %v; want the schedule cancelled", archived, err)
+	}
+}
+
+func TestRevisionsAndRollback(t *testing.T) {
+	f, _ := newFake(t)
+	admin := New(Config{BaseURL: f.url, Token: fakeAdminToken})
+	ctx := context.Background()
+
+	course, err := admin.CreateCourse(ctx, CourseInput{Title: "T", Content: "first"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited, err := admin.PatchCourse(ctx, course.ID, CoursePatch{Content: "second"}, course.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revisions, err := admin.ListCourseRevisions(ctx, course.ID, ListOptions{})
+	if err != nil || len(revisions) != 2 || revisions[1].Changes["content"] != (Change{From: "first", To: "second"}) {
+		t.Fatalf("ListCourseRevisions = %+v, %v", revisions, err)
+	}
+	diff, err := admin.DiffCourse(ctx, course.ID, edited.Version, course.Version)
+	if err != nil || len(diff.Changes) != 1 || diff.Changes["content"].To != "first" {
+		t.Fatalf("DiffCourse = %+v, %v", diff, err)
+	}
+
+	if _, err := admin.RollbackCourse(ctx, course.ID, course.Version, course.Version); !errors.Is(err, ErrPreconditionFailed) {
+		t.Fatalf("RollbackCourse with a stale version = %v, want ErrPreconditionFailed", err)
+	}
+	restored, err := admin.RollbackCourse(ctx, course.ID, course.Version, edited.Version)
+	if err != nil || restored.Content != "first" || restored.Version != edited.Version+1 {
+		t.Fatalf("RollbackCourse = %+v, %v", restored, err)
+	}
+	latest, err := admin.GetCourseRevision(ctx, course.ID, restored.Version)
+	if err != nil || latest.RollbackOf != course.Version {
+		t.Fatalf("GetCourseRevision = %+v, %v", latest, err)
+	}
+	if _, err := admin.GetCourseRevision(ctx, course.ID, 99); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetCourseRevision of a missing version = %v, want ErrNotFound", err)
+	}
+}
+
+func TestGetCourseBySlugFollowsRenames(t *testing.T) {
+	f, _ := newFake(t)
+	admin := New(Config{BaseURL: f.url, Token: fakeAdminToken})
+	ctx := context.Background()
+
+	course, err := admin.CreateCourse(ctx, CourseInput{Title: "Falls Prevention", Content: "C"})
+	if err != nil || course.UniqueID != "falls-prevention" {
+		t.Fatalf("CreateCourse = %+v, %v", course, err)
+	}
+	slug := "falls"
+	if _, err := admin.PatchCourse(ctx, course.ID, CoursePatch{UniqueID: &slug}, 0); err != nil {
+		t.Fatal(err)
+	}
+	got, err := admin.GetCourseBySlug(ctx, "falls-prevention")
+	if err != nil || got.ID != course.ID || got.UniqueID != "falls" {
+		t.Fatalf("GetCourseBySlug of the former slug = %+v, %v", got, err)
+	}
+	if _, err := admin.GetCourseBySlug(ctx, "nope"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetCourseBySlug of an unknown slug = %v, want ErrNotFound", err)
+	}
+}
+
+func TestUploadAndDownloadMedia(t *testing.T) {
+	f, c := newFake(t)
+	ctx := context.Background()
+	data := []byte("\x89PNG\r\n\x1a\nimage")
+
+	// Uploads are deduplicated by content, so a failed attempt is retried.
+	f.failNext("POST /media", 1, 503)
+	m, err := c.UploadMedia(ctx, data, "image/png")
+	if err != nil || m.Kind != "image" || m.ContentType != "image/png" || m.Size != len(data) {
+		t.Fatalf("UploadMedia = %+v, %v", m, err)
+	}
+	again, err := c.UploadMedia(ctx, data, "image/png")
+	if err != nil || again.ID != m.ID {
+		t.Fatalf("second UploadMedia = %+v, %v; want media %d", again, err, m.ID)
+	}
+	got, err := c.DownloadMedia(ctx, m.URL)
+	if err != nil || string(got) != string(data) {
+		t.Fatalf("DownloadMedia = %q, %v", got, err)
+	}
+	if _, err := c.DownloadMedia(ctx, fmt.Sprintf("/media/%d/content", m.ID)); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("DownloadMedia of an unsigned link = %v, want ErrForbidden", err)
+	}
+	if _, err := c.GetMedia(ctx, 99); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetMedia of a missing media = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCategoriesTagsAndCatalog(t *testing.T) {
+	f, c := newFake(t)
+	admin := New(Config{BaseURL: f.url, Token: fakeAdminToken})
+	ctx := context.Background()
+
+	if _, err := c.CreateCategory(ctx, CategoryInput{Name: "Heart"}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("CreateCategory without a token = %v, want ErrForbidden", err)
+	}
+	heart, err := admin.CreateCategory(ctx, CategoryInput{Name: "Heart Health"})
+	if err != nil || heart.Slug != "heart-health" {
+		t.Fatalf("CreateCategory = %+v, %v", heart, err)
+	}
+	for _, in := range []CourseInput{
+		{Title: "Heart Health After 65", Content: "x", CategoryID: &heart.ID, Tags: []string{"seniors", "cardio"}, Price: 20},
+		{Title: "Managing Diabetes", Content: "x", Tags: []string{"seniors"}},
+	} {
+		if _, err := admin.CreateCourse(ctx, in); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	catalog, err := admin.BrowseCourses(ctx, ListOptions{Limit: 1, Tags: []string{"seniors"}})
+	if err != nil || catalog.Total != 2 || len(catalog.Courses) != 1 || len(catalog.Facets.Tags) != 2 || catalog.Facets.Tags[0] != (TagCount{Tag: "seniors", Count: 2}) {
+		t.Fatalf("BrowseCourses = %+v, %v", catalog, err)
+	}
+	inHeart, err := admin.ListCourses(ctx, ListOptions{Category: strconv.Itoa(heart.ID)})
+	if err != nil || len(inHeart) != 1 || inHeart[0].Price != 20 {
+		t.Fatalf("ListCourses by category = %+v, %v", inHeart, err)
+	}
+
+	rename, err := admin.RenameTag(ctx, "seniors", "older adults")
+	if err != nil || rename.Courses != 2 {
+		t.Fatalf("RenameTag = %+v, %v", rename, err)
+	}
+	tags, err := admin.ListTags(ctx, ListOptions{})
+	if err != nil || len(tags) != 2 || tags[0].Tag != "older adults" {
+		t.Fatalf("ListTags = %+v, %v", tags, err)
+	}
+	if err := admin.DeleteCategory(ctx, heart.ID); !errors.Is(err, ErrConflict) {
+		t.Fatalf("DeleteCategory in use = %v, want ErrConflict", err)
+	}
+}
+
+func TestInstructors(t *testing.T) {
+	f, c := newFake(t)
+	admin := New(Config{BaseURL: f.url, Token: fakeAdminToken})
+	ctx := context.Background()
+
+	var users []*User
+	for _, email := range []string{"owner@example.com", "reviewer@example.com"} {
+		u, err := admin.CreateUser(ctx, UserInput{FirstName: "A", LastName: "B", Email: email})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, u)
+	}
+	if _, err := c.IssueUserToken(ctx, users[0].ID); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("IssueUserToken without a token = %v, want ErrForbidden", err)
+	}
+	clients := make([]*Client, len(users))
+	for i, u := range users {
+		token, err := admin.IssueUserToken(ctx, u.ID)
+		if err != nil || token.UserID != u.ID || token.Token == "" {
+			t.Fatalf("IssueUserToken = %+v, %v", token, err)
+		}
+		clients[i] = New(Config{BaseURL: f.url, Token: token.Token})
+	}
+	owner, reviewer := clients[0], clients[1]
+
+	course, err := owner.CreateCourse(ctx, CourseInput{Title: "Heart Health", Content: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drafts, err := owner.ListCourses(ctx, ListOptions{}); err != nil || len(drafts) != 1 || drafts[0].Status != "draft" {
+		t.Fatalf("ListCourses by the owner = %+v, %v, want their draft", drafts, err)
+	}
+	if drafts, err := reviewer.ListCourses(ctx, ListOptions{}); err != nil || len(drafts) != 0 {
+		t.Fatalf("ListCourses by a stranger = %+v, %v, want nothing", drafts, err)
+	}
+	if _, err := reviewer.SetInstructor(ctx, course.ID, users[1].ID, RoleOwner); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("SetInstructor by a stranger = %v, want ErrForbidden", err)
+	}
+	if _, err := c.SetInstructor(ctx, course.ID, users[1].ID, RoleOwner); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("SetInstructor without a token = %v, want ErrUnauthorized", err)
+	}
+	if in, err := owner.SetInstructor(ctx, course.ID, users[1].ID, RoleReviewer); err != nil || in.Role != RoleReviewer {
+		t.Fatalf("SetInstructor = %+v, %v", in, err)
+	}
+	if err := reviewer.UpdateCourse(ctx, course.ID, CourseInput{Title: "T", Content: "x"}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("UpdateCourse by a reviewer = %v, want ErrForbidden", err)
+	}
+	if _, err := reviewer.PublishCourse(ctx, course.ID, nil, 0); err != nil {
+		t.Fatalf("PublishCourse by a reviewer: %v", err)
+	}
+	if err := owner.RemoveInstructor(ctx, course.ID, users[0].ID); !errors.Is(err, ErrConflict) || Code(err) != problem.CodeLastOwner {
+		t.Fatalf("removing the last owner = %v, want LAST_OWNER", err)
+	}
+
+	instructors, err := c.ListInstructors(ctx, course.ID)
+	if err != nil || len(instructors) != 2 || instructors[0].Role != RoleOwner {
+		t.Fatalf("ListInstructors = %+v, %v", instructors, err)
+	}
+	reviewing, err := c.ListInstructorCourses(ctx, users[1].ID, ListOptions{Role: RoleReviewer})
+	if err != nil || len(reviewing) != 1 || reviewing[0].ID != course.ID {
+		t.Fatalf("ListInstructorCourses = %+v, %v", reviewing, err)
+	}
+	if err := reviewer.RemoveInstructor(ctx, course.ID, users[1].ID); err != nil {
+		t.Fatalf("removing oneself: %v", err)
+	}
+}
+
+func TestIteratorFetchesPages(t *testing.T) {
+	f, c := newFake(t)
+	ctx := context.Background()
+	for i := 0; i < 7; i++ {
+		course, err := c.CreateCourse(ctx, CourseInput{Title: "T", Content: "C"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.PublishCourse(ctx, course.ID, nil, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	it := c.Courses(3)
+	var ids []int
+	for it.Next(ctx) {
+		ids = append(ids, it.Item().ID)
+	}
+	if it.Err() != nil || len(ids) != 7 || ids[0] != 1 || ids[6] != 7 {
+		t.Fatalf("iterated %v, %v", ids, it.Err())
+	}
+	// Pages of 3, 3 and a short final page of 1.
+	if n := f.hitCount("GET /courses"); n != 3 {
+		t.Fatalf("fetched %d pages, want 3", n)
+	}
+
+	users, err := c.Users(0).All(ctx)
+	if err != nil || len(users) != 0 {
+		t.Fatalf("Users over empty table = %v, %v", users, err)
+	}
+}
+
+func TestIteratorStopsOnError(t *testing.T) {
+	f, c := newFake(t)
+	f.failNext("GET /courses", 10, 500)
+	it := c.Courses(0)
+	if it.Next(context.Background()) {
+		t.Fatal("Next succeeded")
+	}
+	var apiErr *Error
+	if !errors.As(it.Err(), &apiErr) || apiErr.StatusCode != 500 {
+		t.Fatalf("Err = %v", it.Err())
+	}
+}
+
+func TestRetriesIdempotentCalls(t *testing.T) {
+	f, c := newFake(t)
+	ctx := context.Background()
+	course, err := c.CreateCourse(ctx, CourseInput{Title: "T", Content: "C"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.PublishCourse(ctx, course.ID, nil, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	f.failNext("GET /courses/1", 2, 503)
+	if _, err := c.GetCourse(ctx, course.ID); err != nil {
+		t.Fatalf("GetCourse after two 503s: %v", err)
+	}
+	if n := f.hitCount("GET /courses/1"); n != 3 {
+		t.Fatalf("GET hit %d times, want 3", n)
+	}
+
+	f.failNext("DELETE /courses/1", 10, 502)
+	err = c.DeleteCourse(ctx, course.ID)
+	if !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("DeleteCourse = %v, want ErrUnavailable", err)
+	}
+	if n := f.hitCount("DELETE /courses/1"); n != 4 {
+		t.Fatalf("DELETE hit %d times, want 1 + 3 retries", n)
+	}
+}
+
+func TestDoesNotRetryNonIdempotentCalls(t *testing.T) {
+	f, c := newFake(t)
+	ctx := context.Background()
+
+	f.failNext("POST /courses", 1, 503)
+	if _, err := c.CreateCourse(ctx, CourseInput{Title: "T", Content: "C"}); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("CreateCourse = %v", err)
+	}
+	f.failNext("PUT /users/1/payment", 1, 503)
+	if err := c.RecordPayment(ctx, 1, 10); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("RecordPayment = %v", err)
+	}
+	if f.hitCount("POST /courses") != 1 || f.hitCount("PUT /users/1/payment") != 1 {
+		t.Fatal("non-idempotent call was retried")
+	}
+}
+
+func TestRetryHonoursContext(t *testing.T) {
+	f, _ := newFake(t)
+	c := New(Config{BaseURL: f.url, RetryBackoff: time.Hour})
+	f.failNext("GET /courses", 10, 503)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := c.ListCourses(ctx, ListOptions{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("ListCourses = %v, want deadline exceeded", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatal("retry backoff ignored the context")
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	_, c := newFake(t)
+	ctx := context.Background()
+
+	_, err := c.GetSeries(ctx, 42)
+	if !errors.Is(err, ErrNotFound) || Code(err) != problem.CodeSeriesNotFound {
+		t.Fatalf("GetSeries = %v", err)
+	}
+
+	_, err = c.CreateCourse(ctx, CourseInput{Content: "no title"})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || !errors.Is(err, ErrBadRequest) || apiErr.Problem.Code != problem.CodeValidationFailed || len(apiErr.Problem.Errors) == 0 {
+		t.Fatalf("CreateCourse without title = %#v", err)
+	}
+
+	in := UserInput{FirstName: "A", LastName: "B", Email: "ab@example.com"}
+	if _, err := c.CreateUser(ctx, in); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateUser(ctx, in); !errors.Is(err, ErrConflict) || Code(err) != problem.CodeUserEmailTaken {
+		t.Fatalf("duplicate CreateUser = %v", err)
+	}
+
+	if _, err := c.CreateSeries(ctx, 99, SeriesInput{Title: "Orphan"}); !errors.Is(err, ErrBadRequest) || Code(err) != problem.CodeCourseNotFound {
+		t.Fatalf("CreateSeries on missing course = %v", err)
+	}
+	if err := c.UpdateCourse(ctx, 99, CourseInput{Title: "a", Content: "b"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("UpdateCourse on missing course = %v", err)
+	}
+	if _, err := c.PatchSeries(ctx, 99, SeriesPatch{}, 0); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("PatchSeries on missing series = %v", err)
+	}
+}
+
+func TestGeneratedTypesAreUpToDate(t *testing.T) {
+	want, err := openapi.MustLoad().GenerateGo("client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("types_gen.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("types_gen.go is stale; run go generate ./client")
+	}
+}
+
+func TestQuizzes(t *testing.T) {
+	f, c := newFake(t)
+	admin := New(Config{BaseURL: f.url, Token: fakeAdminToken})
+	ctx := context.Background()
+
+	user, err := admin.CreateUser(ctx, UserInput{FirstName: "A", LastName: "B", Email: "learner@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := admin.IssueUserToken(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	learner := New(Config{BaseURL: f.url, Token: token.Token})
+	course, err := admin.CreateCourse(ctx, CourseInput{Title: "Heart Health", Content: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	series, err := admin.CreateSeries(ctx, course.ID, SeriesInput{Title: "Blood pressure"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	answer := 0
+	quiz, err := admin.CreateQuiz(ctx, series.ID, QuizInput{Title: "Check-up", PassingScore: 100, MaxAttempts: 2, Required: true,
+		Questions: []Question{{Type: QuestionTrueFalse, Prompt: "Salt raises blood pressure.", Answer: &answer}}})
+	if err != nil || len(quiz.Questions) != 1 || len(quiz.Questions[0].Options) != 2 {
+		t.Fatalf("CreateQuiz = %+v, %v", quiz, err)
+	}
+
+	if _, err := learner.SubmitAttempt(ctx, quiz.ID, []int{0}); Code(err) != problem.CodeNotEnrolled {
+		t.Fatalf("SubmitAttempt before enrolling = %v, want NOT_ENROLLED", err)
+	}
+	enrollment, err := c.Enroll(ctx, EnrollmentInput{UserID: user.ID, CourseID: course.ID, Status: "enrolled"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := learner.GetQuiz(ctx, quiz.ID); err != nil || got.Questions[0].Answer != nil {
+		t.Fatalf("GetQuiz as a learner = %+v, %v, want the answer hidden", got, err)
+	}
+	if a, err := learner.SubmitAttempt(ctx, quiz.ID, []int{1}); err != nil || a.Passed || a.Score != 0 {
+		t.Fatalf("wrong attempt = %+v, %v", a, err)
+	}
+	if _, err := c.CompleteEnrollment(ctx, enrollment.ID); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("CompleteEnrollment without a token = %v, want ErrUnauthorized", err)
+	}
+	if _, err := learner.CompleteEnrollment(ctx, enrollment.ID); !errors.Is(err, ErrConflict) || Code(err) != problem.CodeQuizzesNotPassed {
+		t.Fatalf("CompleteEnrollment before passing = %v, want QUIZZES_NOT_PASSED", err)
+	}
+	if a, err := learner.SubmitAttempt(ctx, quiz.ID, []int{0}); err != nil || !a.Passed || a.Score != 100 {
+		t.Fatalf("right attempt = %+v, %v", a, err)
+	}
+	if _, err := learner.SubmitAttempt(ctx, quiz.ID, []int{0}); !errors.Is(err, ErrConflict) || Code(err) != problem.CodeQuizAttemptsUsed {
+		t.Fatalf("third attempt = %v, want QUIZ_ATTEMPTS_USED", err)
+	}
+	if e, err := learner.CompleteEnrollment(ctx, enrollment.ID); err != nil || e.Status != "completed" {
+		t.Fatalf("CompleteEnrollment = %+v, %v", e, err)
+	}
+	if attempts, err := learner.ListAttempts(ctx, quiz.ID); err != nil || len(attempts) != 2 {
+		t.Fatalf("ListAttempts = %+v, %v", attempts, err)
+	}
+}
+
+func TestCertificates(t *testing.T) {
+	f, c := newFake(t)
+	admin := New(Config{BaseURL: f.url, Token: fakeAdminToken})
+	ctx := context.Background()
+
+	user, err := admin.CreateUser(ctx, UserInput{FirstName: "Margaret", LastName: "Johnson", Email: "margaret@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := admin.IssueUserToken(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	learner := New(Config{BaseURL: f.url, Token: token.Token})
+	course, err := admin.CreateCourse(ctx, CourseInput{Title: "Heart Health", Content: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enrollment, err := c.Enroll(ctx, EnrollmentInput{UserID: user.ID, CourseID: course.ID, Status: "enrolled"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ListCertificates(ctx, user.ID, ListOptions{}); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("ListCertificates without a token = %v, want ErrUnauthorized", err)
+	}
+	if _, err := learner.CompleteEnrollment(ctx, enrollment.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	certificates, err := learner.ListCertificates(ctx, user.ID, ListOptions{})
+	if err != nil || len(certificates) != 1 || certificates[0].CourseTitle != "Heart Health" {
+		t.Fatalf("ListCertificates = %+v, %v", certificates, err)
+	}
+	code := certificates[0].Code
+	if v, err := c.VerifyCertificate(ctx, code); err != nil || v.LearnerName != "Margaret Johnson" {
+		t.Fatalf("VerifyCertificate = %+v, %v", v, err)
+	}
+	if _, err := c.VerifyCertificate(ctx, "NOPE-NOPE-NOPE"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("VerifyCertificate of an unknown code = %v, want ErrNotFound", err)
+	}
+	if data, err := learner.DownloadCertificate(ctx, code); err != nil || !bytes.HasPrefix(data, []byte("%PDF-")) {
+		t.Fatalf("DownloadCertificate = %q, %v", data, err)
+	}
+	if _, err := c.DownloadCertificate(ctx, code); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("DownloadCertificate without a token = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	f, c := newFake(t)
+	admin := New(Config{BaseURL: f.url, Token: fakeAdminToken})
+	ctx := context.Background()
+
+	in := WebhookInput{URL: "https://clinic.example/hooks", EventTypes: []string{"enrollment.created"}}
+	if _, err := c.CreateWebhook(ctx, in); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("CreateWebhook without the admin token = %v, want ErrForbidden", err)
+	}
+	hook, err := admin.CreateWebhook(ctx, in)
+	if err != nil || !hook.Active || hook.Secret == "" {
+		t.Fatalf("CreateWebhook = %+v, %v", hook, err)
+	}
+	inactive := false
+	in.Active = &inactive
+	if hook, err = admin.UpdateWebhook(ctx, hook.ID, in); err != nil || hook.Active {
+		t.Fatalf("UpdateWebhook = %+v, %v", hook, err)
+	}
+	in.Active = nil
+	if hook, err = admin.UpdateWebhook(ctx, hook.ID, in); err != nil || hook.Active {
+		t.Fatalf("UpdateWebhook leaving active out = %+v, %v, want it kept", hook, err)
+	}
+	active := true
+	in.Active = &active
+	if _, err := admin.UpdateWebhook(ctx, hook.ID, in); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Enroll(ctx, EnrollmentInput{UserID: 1, CourseID: 2, Status: "enrolled"}); err != nil {
+		t.Fatal(err)
+	}
+	deliveries, err := admin.ListWebhookDeliveries(ctx, hook.ID, "pending", ListOptions{})
+	if err != nil || len(deliveries) != 1 || deliveries[0].EventType != "enrollment.created" {
+		t.Fatalf("ListWebhookDeliveries = %+v, %v", deliveries, err)
+	}
+	if dead, err := admin.ListDeadLetters(ctx, ListOptions{}); err != nil || len(dead) != 0 {
+		t.Fatalf("ListDeadLetters = %+v, %v", dead, err)
+	}
+	if d, err := admin.RedeliverWebhook(ctx, hook.ID, deliveries[0].ID); err != nil || d.Status != "pending" {
+		t.Fatalf("RedeliverWebhook = %+v, %v", d, err)
+	}
+	if _, err := admin.RedeliverWebhook(ctx, hook.ID, 999); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("RedeliverWebhook of an unknown delivery = %v, want ErrNotFound", err)
+	}
+
+	if err := admin.DeleteWebhook(ctx, hook.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.GetWebhook(ctx, hook.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetWebhook after deleting = %v, want ErrNotFound", err)
+	}
+	if hooks, err := admin.ListWebhooks(ctx, ListOptions{}); err != nil || len(hooks) != 0 {
+		t.Fatalf("ListWebhooks = %+v, %v", hooks, err)
+	}
+}
+
+func TestNotifications(t *testing.T) {
+	f, c := newFake(t)
+	admin := New(Config{BaseURL: f.url, Token: fakeAdminToken})
+	ctx := context.Background()
+
+	u, err := admin.CreateUser(ctx, UserInput{FirstName: "Margaret", LastName: "Johnson", Email: "a@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetNotificationPreferences(ctx, u.ID); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("GetNotificationPreferences without a token = %v, want ErrUnauthorized", err)
+	}
+	token, err := admin.IssueUserToken(ctx, u.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	self := New(Config{BaseURL: f.url, Token: token.Token})
+	prefs, err := self.GetNotificationPreferences(ctx, u.ID)
+	if err != nil || !prefs.EmailEnabled || prefs.Locale != "en" {
+		t.Fatalf("GetNotificationPreferences = %+v, %v", prefs, err)
+	}
+	if prefs, err = self.UpdateNotificationPreferences(ctx, u.ID, PreferencesInput{Locale: "es"}); err != nil || prefs.Locale != "es" || !prefs.EmailEnabled {
+		t.Fatalf("UpdateNotificationPreferences = %+v, %v", prefs, err)
+	}
+	if _, err := self.UpdateNotificationPreferences(ctx, u.ID, PreferencesInput{Locale: "xx"}); !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("UpdateNotificationPreferences with an unknown locale = %v, want ErrBadRequest", err)
+	}
+
+	notifications, err := self.ListNotifications(ctx, u.ID, ListOptions{})
+	if err != nil || len(notifications) != 1 || notifications[0].EventType != "user.created" {
+		t.Fatalf("ListNotifications = %+v, %v", notifications, err)
+	}
+
+	links := notify.NewLinks(f.url, fakeAdminToken)
+	if err := c.Unsubscribe(ctx, links.Token(u.ID, "payment.recorded")); err != nil {
+		t.Fatal(err)
+	}
+	if prefs, err = self.GetNotificationPreferences(ctx, u.ID); err != nil || len(prefs.Muted) != 1 || prefs.Muted[0] != "payment.recorded" {
+		t.Fatalf("after unsubscribing: %+v, %v", prefs, err)
+	}
+	if err := c.Unsubscribe(ctx, "1.all.forged"); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("Unsubscribe with a forged token = %v, want ErrForbidden", err)
+	}
+}
+
+func TestImportAndExport(t *testing.T) {
+	f, c := newFake(t)
+	admin := New(Config{BaseURL: f.url, Token: fakeAdminToken})
+	ctx := context.Background()
+
+	csv := []byte("First Name,Last Name,Email Address\nAda,Lovelace,ada@example.com\n")
+	if _, err := c.ImportUsers(ctx, csv, ImportOptions{}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("ImportUsers without the admin token = %v, want ErrForbidden", err)
+	}
+	if _, err := admin.ImportUsers(ctx, csv, ImportOptions{}); !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("ImportUsers without a column for email = %v, want ErrBadRequest", err)
+	}
+	opts := ImportOptions{Mapping: map[string]string{"email": "Email Address"}, DryRun: true}
+	report, err := admin.ImportUsers(ctx, csv, opts)
+	if err != nil || report.Rows != 1 || report.Imported != 0 || !report.DryRun {
+		t.Fatalf("dry run = %+v, %v", report, err)
+	}
+	opts.DryRun = false
+	if report, err = admin.ImportUsers(ctx, csv, opts); err != nil || report.Imported != 1 {
+		t.Fatalf("ImportUsers = %+v, %v", report, err)
+	}
+	report, err = admin.ImportUsers(ctx, csv, opts)
+	if err != nil || report.Imported != 0 || report.Failed != 1 || report.Errors[0].Row != 2 || report.Errors[0].Field != "email" {
+		t.Fatalf("importing the same user again = %+v, %v", report, err)
+	}
+
+	users, err := admin.ExportUsers(ctx, ExportJSONLines, false)
+	if err != nil || !strings.Contains(string(users), `"email":"ada@example.com"`) {
+		t.Fatalf("ExportUsers = %s, %v", users, err)
+	}
+	if report, err = admin.ImportEnrollments(ctx, []byte("user_id,course_id\n1,2\n"), ImportOptions{Mode: ImportBestEffort}); err != nil || report.Imported != 1 {
+		t.Fatalf("ImportEnrollments = %+v, %v", report, err)
+	}
+	enrollments, err := admin.ExportEnrollments(ctx, ExportJSONLines, 2)
+	if err != nil || !strings.Contains(string(enrollments), `"status":"enrolled"`) {
+		t.Fatalf("ExportEnrollments = %s, %v", enrollments, err)
+	}
+	if _, err := admin.ExportPayments(ctx, ExportCSV); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.ExportPayments(ctx, ""); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("ExportPayments without the admin token = %v, want ErrForbidden", err)
+	}
+}
+
+func TestCohortsAndBatches(t *testing.T) {
+	f, c := newFake(t)
+	admin := New(Config{BaseURL: f.url, Token: fakeAdminToken})
+	ctx := context.Background()
+
+	course, err := c.CreateCourse(ctx, CourseInput{Title: "T", Content: "C"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ada, err := c.CreateUser(ctx, UserInput{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := CohortInput{CourseID: course.ID, Name: "Spring", StartsOn: "2027-03-01"}
+	if _, err := c.CreateCohort(ctx, in); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("CreateCohort without the admin token = %v, want ErrForbidden", err)
+	}
+	cohort, err := admin.CreateCohort(ctx, in)
+	if err != nil || cohort.Name != "Spring" {
+		t.Fatalf("CreateCohort = %+v, %v", cohort, err)
+	}
+	if _, err := admin.CreateCohort(ctx, in); !errors.Is(err, ErrConflict) {
+		t.Fatalf("CreateCohort with a taken name = %v, want ErrConflict", err)
+	}
+
+	batch := BatchInput{CohortID: cohort.ID, Enrollments: []BatchItem{{UserID: ada.ID}, {UserID: 999}}}
+	result, err := admin.EnrollBatch(ctx, batch)
+	if err != nil || result.Created != 0 || result.Results[0].Status != "skipped" || result.Results[1].Error.Code != "USER_NOT_FOUND" {
+		t.Fatalf("atomic EnrollBatch with an unknown user = %+v, %v", result, err)
+	}
+	batch.Mode = BatchBestEffort
+	if result, err = admin.EnrollBatch(ctx, batch); err != nil || result.Created != 1 || result.Results[0].Enrollment.CohortID != cohort.ID {
+		t.Fatalf("best-effort EnrollBatch = %+v, %v", result, err)
+	}
+	if cohort, err = admin.GetCohort(ctx, cohort.ID); err != nil || cohort.Enrollments != 1 {
+		t.Fatalf("GetCohort = %+v, %v", cohort, err)
+	}
+	if enrollments, err := admin.ListCohortEnrollments(ctx, cohort.ID, ListOptions{}); err != nil || len(enrollments) != 1 {
+		t.Fatalf("ListCohortEnrollments = %+v, %v", enrollments, err)
+	}
+	if cohorts, err := admin.ListCohorts(ctx, course.ID, ListOptions{}); err != nil || len(cohorts) != 1 {
+		t.Fatalf("ListCohorts = %+v, %v", cohorts, err)
+	}
+
+	if err := admin.DeleteCohort(ctx, cohort.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.GetCohort(ctx, cohort.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetCohort after deleting = %v, want ErrNotFound", err)
+	}
+	if _, err := admin.EnrollBatch(ctx, batch); !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("EnrollBatch into a deleted cohort = %v, want ErrBadRequest", err)
+	}
+}
+
+func TestPrerequisites(t *testing.T) {
+	f, c := newFake(t)
+	admin := New(Config{BaseURL: f.url, Token: fakeAdminToken})
+	ctx := context.Background()
+
+	user, err := admin.CreateUser(ctx, UserInput{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := admin.IssueUserToken(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	learner := New(Config{BaseURL: f.url, Token: token.Token})
+	var courses []*Course
+	for _, title := range []string{"Heart Health", "Heart Surgery"} {
+		course, err := admin.CreateCourse(ctx, CourseInput{Title: title, Content: "x"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if course, err = admin.PublishCourse(ctx, course.ID, nil, 0); err != nil {
+			t.Fatal(err)
+		}
+		courses = append(courses, course)
+	}
+	basics, surgery := courses[0], courses[1]
+
+	if p, err := admin.AddPrerequisite(ctx, surgery.ID, basics.ID); err != nil || p.Title != "Heart Health" {
+		t.Fatalf("AddPrerequisite = %+v, %v", p, err)
+	}
+	if _, err := admin.AddPrerequisite(ctx, basics.ID, surgery.ID); !errors.Is(err, ErrConflict) || Code(err) != problem.CodePrerequisiteCycle {
+		t.Fatalf("AddPrerequisite making a cycle = %v, want PREREQUISITE_CYCLE", err)
+	}
+	if _, err := admin.AddPrerequisite(ctx, surgery.ID, 999); !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("AddPrerequisite of an unknown course = %v, want ErrBadRequest", err)
+	}
+	draft, err := admin.CreateCourse(ctx, CourseInput{Title: "Heart Research", Content: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.AddPrerequisite(ctx, surgery.ID, draft.ID); !errors.Is(err, ErrBadRequest) {
+		t.Fatalf("AddPrerequisite of a draft = %v, want ErrBadRequest", err)
+	}
+	if list, err := c.ListPrerequisites(ctx, surgery.ID); err != nil || len(list) != 1 || list[0].PrerequisiteID != basics.ID {
+		t.Fatalf("ListPrerequisites = %+v, %v", list, err)
+	}
+
+	if _, err := c.GetEligibility(ctx, user.ID, surgery.ID); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("GetEligibility without a token = %v, want ErrUnauthorized", err)
+	}
+	e, err := learner.GetEligibility(ctx, user.ID, surgery.ID)
+	if err != nil || e.Eligible || len(e.MissingPrerequisites) != 1 || e.MissingPrerequisites[0].CourseID != basics.ID {
+		t.Fatalf("GetEligibility = %+v, %v", e, err)
+	}
+	if _, err := c.Enroll(ctx, EnrollmentInput{UserID: user.ID, CourseID: surgery.ID, Status: "enrolled"}); !errors.Is(err, ErrConflict) ||
+		Code(err) != problem.CodePrerequisitesMissing {
+		t.Fatalf("Enroll without the prerequisite = %v, want PREREQUISITES_MISSING", err)
+	}
+	if _, err := c.Enroll(ctx, EnrollmentInput{UserID: user.ID, CourseID: basics.ID, Status: "completed"}); err != nil {
+		t.Fatal(err)
+	}
+	if e, err := learner.GetEligibility(ctx, user.ID, surgery.ID); err != nil || !e.Eligible || len(e.MissingPrerequisites) != 0 {
+		t.Fatalf("GetEligibility after completing the prerequisite = %+v, %v", e, err)
+	}
+
+	if err := admin.RemovePrerequisite(ctx, surgery.ID, basics.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := admin.RemovePrerequisite(ctx, surgery.ID, basics.ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("RemovePrerequisite twice = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	f, c := newFake(t)
+	admin := New(Config{BaseURL: f.url, Token: fakeAdminToken})
+	ctx := context.Background()
+
+	var users []*User
+	for _, email := range []string{"ada@example.com", "grace@example.com", "alan@example.com"} {
+		user, err := admin.CreateUser(ctx, UserInput{FirstName: "A", LastName: "B", Email: email})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, user)
+	}
+	course, err := admin.CreateCourse(ctx, CourseInput{Title: "Live Cardio", Content: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	one := 1
+	if course, err = admin.PatchCourse(ctx, course.ID, CoursePatch{Capacity: &one}, 0); err != nil || course.Capacity == nil || *course.Capacity != 1 {
+		t.Fatalf("PatchCourse = %+v, %v", course, err)
+	}
+
+	var enrollments []*UserCourseEnrollment
+	for _, user := range users {
+		e, err := c.Enroll(ctx, EnrollmentInput{UserID: user.ID, CourseID: course.ID, Status: "enrolled"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		enrollments = append(enrollments, e)
+	}
+	if enrollments[0].Status != "enrolled" || enrollments[1].Status != "waitlisted" || enrollments[2].Status != "waitlisted" {
+		t.Fatalf("Enroll statuses = %s, %s, %s; want the last two waitlisted", enrollments[0].Status, enrollments[1].Status, enrollments[2].Status)
+	}
+	if _, err := admin.CompleteEnrollment(ctx, enrollments[1].ID); !errors.Is(err, ErrConflict) || Code(err) != problem.CodeWaitlisted {
+		t.Fatalf("CompleteEnrollment while waitlisted = %v, want WAITLISTED", err)
+	}
+	if _, err := c.ListWaitlist(ctx, course.ID, ListOptions{}); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("ListWaitlist without the admin token = %v, want ErrForbidden", err)
+	}
+
+	if err := c.DeleteEnrollment(ctx, enrollments[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	roster, err := admin.ListRoster(ctx, course.ID, ListOptions{})
+	if err != nil || len(roster) != 1 || roster[0].ID != enrollments[1].ID {
+		t.Fatalf("ListRoster = %+v, %v; want the first waitlisted promoted", roster, err)
+	}
+	waitlist, err := admin.ListWaitlist(ctx, course.ID, ListOptions{})
+	if err != nil || len(waitlist) != 1 || waitlist[0].EnrollmentID != enrollments[2].ID || waitlist[0].Position != 1 {
+		t.Fatalf("ListWaitlist = %+v, %v", waitlist, err)
+	}
+
+	closed := time.Now().Add(-time.Hour)
+	if _, err := admin.PatchCourse(ctx, course.ID, CoursePatch{EnrollmentClosesAt: &closed}, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Enroll(ctx, EnrollmentInput{UserID: users[0].ID, CourseID: course.ID, Status: "enrolled"}); !errors.Is(err, ErrConflict) ||
+		Code(err) != problem.CodeEnrollmentClosed {
+		t.Fatalf("Enroll after closing = %v, want ENROLLMENT_CLOSED", err)
+	}
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// Batch modes.
+const (
+	// BatchAtomic creates every enrollment of a batch, or none if any is
+	// invalid.
+	BatchAtomic = ImportAtomic
+	// BatchBestEffort creates the valid enrollments of a batch.
+	BatchBestEffort = ImportBestEffort
+)
+
+// EnrollBatch enrolls many users at once, in one transaction. It needs an
+// admin Config.Token. Invalid items do not fail the call; the result says
+// what became of each item, in order.
+func (c *Client) EnrollBatch(ctx context.Context, in BatchInput) (*BatchResult, error) {
+	var result BatchResult
+	if err := c.do(ctx, call{method: "POST", path: "/enrollments/batch", body: in, out: &result}); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListCohorts returns one page of cohorts by start date; only the course's
+// if courseID is not zero. Like every cohort call, it needs an admin
+// Config.Token.
+func (c *Client) ListCohorts(ctx context.Context, courseID int, opts ListOptions) ([]Cohort, error) {
+	q := pageQuery(opts)
+	if courseID != 0 {
+		q.Set("course_id", strconv.Itoa(courseID))
+	}
+	var cohorts []Cohort
+	err := c.do(ctx, call{method: "GET", path: "/cohorts", query: q, out: &cohorts, idempotent: true})
+	return cohorts, err
+}
+
+// CreateCohort creates a cohort of in.CourseID starting on in.StartsOn, a
+// YYYY-MM-DD date.
+func (c *Client) CreateCohort(ctx context.Context, in CohortInput) (*Cohort, error) {
+	var cohort Cohort
+	if err := c.do(ctx, call{method: "POST", path: "/cohorts", body: in, out: &cohort}); err != nil {
+		return nil, err
+	}
+	return &cohort, nil
+}
+
+func (c *Client) GetCohort(ctx context.Context, id int) (*Cohort, error) {
+	var cohort Cohort
+	if err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/cohorts/%d", id), out: &cohort, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &cohort, nil
+}
+
+// DeleteCohort deletes the cohort. Its enrollments are kept without one.
+func (c *Client) DeleteCohort(ctx context.Context, id int) error {
+	return c.do(ctx, call{method: "DELETE", path: fmt.Sprintf("/cohorts/%d", id), idempotent: true})
+}
+
+// ListCohortEnrollments returns one page of the cohort's enrollments, oldest
+// first.
+func (c *Client) ListCohortEnrollments(ctx context.Context, id int, opts ListOptions) ([]UserCourseEnrollment, error) {
+	var enrollments []UserCourseEnrollment
+	err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/cohorts/%d/enrollments", id), query: pageQuery(opts), out: &enrollments, idempotent: true})
+	return enrollments, err
+}
@@ -0,0 +1,255 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const mergePatchType = "application/merge-patch+json"
+
+// ListCourses returns one page of courses: published ones, plus those the
+// caller owns or co-authors whatever their status, or every course for an
+// admin.
+func (c *Client) ListCourses(ctx context.Context, opts ListOptions) ([]Course, error) {
+	var courses []Course
+	err := c.do(ctx, call{method: "GET", path: "/courses", query: opts.query(), out: &courses, idempotent: true})
+	return courses, err
+}
+
+// Courses iterates over every course, pageSize at a time.
+func (c *Client) Courses(pageSize int) *Iterator[Course] {
+	return newIterator(pageSize, c.ListCourses)
+}
+
+func (c *Client) GetCourse(ctx context.Context, id int) (*Course, error) {
+	var course Course
+	if err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/courses/%d", id), out: &course, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &course, nil
+}
+
+// GetCourseBySlug fetches a course by its slug. A former slug is followed to
+// the course's current one.
+func (c *Client) GetCourseBySlug(ctx context.Context, slug string) (*Course, error) {
+	var course Course
+	if err := c.do(ctx, call{method: "GET", path: "/courses/by-slug/" + url.PathEscape(slug), out: &course, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &course, nil
+}
+
+func (c *Client) CreateCourse(ctx context.Context, in CourseInput) (*Course, error) {
+	var course Course
+	if err := c.do(ctx, call{method: "POST", path: "/courses", body: in, out: &course}); err != nil {
+		return nil, err
+	}
+	return &course, nil
+}
+
+// UpdateCourse replaces every field of the course with in.
+func (c *Client) UpdateCourse(ctx context.Context, id int, in CourseInput) error {
+	return c.do(ctx, call{method: "PUT", path: fmt.Sprintf("/courses/%d", id), body: in, idempotent: true})
+}
+
+// PatchCourse applies a JSON Merge Patch to the course and returns the result.
+// patch is a CoursePatch, or a map when a field has to be cleared with null.
+// A non-zero version makes the update fail with ErrPreconditionFailed if the
+// course has changed since that version was read.
+func (c *Client) PatchCourse(ctx context.Context, id int, patch interface{}, version int) (*Course, error) {
+	var course Course
+	err := c.do(ctx, call{method: "PATCH", path: fmt.Sprintf("/courses/%d", id), body: patch, out: &course,
+		contentType: mergePatchType, ifMatch: version, idempotent: true})
+	if err != nil {
+		return nil, err
+	}
+	return &course, nil
+}
+
+// DeleteCourse soft-deletes the course together with its series. It can be
+// undone with RestoreCourse until the retention period has passed.
+func (c *Client) DeleteCourse(ctx context.Context, id int) error {
+	return c.do(ctx, call{method: "DELETE", path: fmt.Sprintf("/courses/%d", id), idempotent: true})
+}
+
+// RestoreCourse undeletes the course and the series that were deleted with
+// it. Restoring a course that is not deleted returns it unchanged.
+func (c *Client) RestoreCourse(ctx context.Context, id int) (*Course, error) {
+	var course Course
+	if err := c.do(ctx, call{method: "POST", path: fmt.Sprintf("/courses/%d/restore", id), out: &course, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &course, nil
+}
+
+// SubmitCourse sends a draft course for review. Like the other workflow
+// calls it returns the updated course, fails with ErrConflict when the
+// course's status does not allow the step, and honours a non-zero version
+// like PatchCourse.
+func (c *Client) SubmitCourse(ctx context.Context, id, version int) (*Course, error) {
+	return c.courseAction(ctx, id, "submit", nil, version)
+}
+
+// RejectCourse sends a course under review back to draft.
+func (c *Client) RejectCourse(ctx context.Context, id, version int) (*Course, error) {
+	return c.courseAction(ctx, id, "reject", nil, version)
+}
+
+// PublishCourse publishes the course now, or at publishAt when that is in
+// the future.
+func (c *Client) PublishCourse(ctx context.Context, id int, publishAt *time.Time, version int) (*Course, error) {
+	var in interface{}
+	if publishAt != nil {
+		in = PublishInput{PublishAt: *publishAt}
+	}
+	return c.courseAction(ctx, id, "publish", in, version)
+}
+
+// ArchiveCourse takes the course out of the catalogue.
+func (c *Client) ArchiveCourse(ctx context.Context, id, version int) (*Course, error) {
+	return c.courseAction(ctx, id, "archive", nil, version)
+}
+
+func (c *Client) courseAction(ctx context.Context, id int, action string, in interface{}, version int) (*Course, error) {
+	var course Course
+	err := c.do(ctx, call{method: "POST", path: fmt.Sprintf("/courses/%d/%s", id, action), body: in, out: &course, ifMatch: version})
+	if err != nil {
+		return nil, err
+	}
+	return &course, nil
+}
+
+// ListCourseRevisions returns one page of the course's revisions, oldest
+// first.
+func (c *Client) ListCourseRevisions(ctx context.Context, id int, opts ListOptions) ([]Revision, error) {
+	return c.listRevisions(ctx, fmt.Sprintf("/courses/%d/revisions", id), opts)
+}
+
+func (c *Client) GetCourseRevision(ctx context.Context, id, version int) (*Revision, error) {
+	return c.getRevision(ctx, fmt.Sprintf("/courses/%d/revisions/%d", id, version))
+}
+
+// DiffCourse returns how the course's fields changed from one revision to
+// another.
+func (c *Client) DiffCourse(ctx context.Context, id, from, to int) (*Diff, error) {
+	return c.diff(ctx, fmt.Sprintf("/courses/%d/revisions/diff", id), from, to)
+}
+
+// RollbackCourse restores the fields of revision as a new revision. A
+// non-zero version is sent as If-Match like in PatchCourse.
+func (c *Client) RollbackCourse(ctx context.Context, id, revision, version int) (*Course, error) {
+	var course Course
+	err := c.do(ctx, call{method: "POST", path: fmt.Sprintf("/courses/%d/revisions/%d/rollback", id, revision), out: &course, ifMatch: version})
+	if err != nil {
+		return nil, err
+	}
+	return &course, nil
+}
+
+func (c *Client) listRevisions(ctx context.Context, path string, opts ListOptions) ([]Revision, error) {
+	var revisions []Revision
+	err := c.do(ctx, call{method: "GET", path: path, query: opts.query(), out: &revisions, idempotent: true})
+	return revisions, err
+}
+
+func (c *Client) getRevision(ctx context.Context, path string) (*Revision, error) {
+	var revision Revision
+	if err := c.do(ctx, call{method: "GET", path: path, out: &revision, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+func (c *Client) diff(ctx context.Context, path string, from, to int) (*Diff, error) {
+	var diff Diff
+	query := url.Values{"from": {strconv.Itoa(from)}, "to": {strconv.Itoa(to)}}
+	if err := c.do(ctx, call{method: "GET", path: path, query: query, out: &diff, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &diff, nil
+}
+
+// ListSeries returns one page of a course's series.
+func (c *Client) ListSeries(ctx context.Context, courseID int, opts ListOptions) ([]Series, error) {
+	var series []Series
+	err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/courses/%d/series", courseID), query: opts.query(), out: &series, idempotent: true})
+	return series, err
+}
+
+// CourseSeries iterates over every series of a course, pageSize at a time.
+func (c *Client) CourseSeries(courseID, pageSize int) *Iterator[Series] {
+	return newIterator(pageSize, func(ctx context.Context, opts ListOptions) ([]Series, error) {
+		return c.ListSeries(ctx, courseID, opts)
+	})
+}
+
+func (c *Client) GetSeries(ctx context.Context, id int) (*Series, error) {
+	var series Series
+	if err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/series/%d", id), out: &series, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+func (c *Client) CreateSeries(ctx context.Context, courseID int, in SeriesInput) (*Series, error) {
+	var series Series
+	if err := c.do(ctx, call{method: "POST", path: fmt.Sprintf("/courses/%d/series", courseID), body: in, out: &series}); err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+// UpdateSeries replaces every field of the series with in.
+func (c *Client) UpdateSeries(ctx context.Context, id int, in SeriesInput) error {
+	return c.do(ctx, call{method: "PUT", path: fmt.Sprintf("/series/%d", id), body: in, idempotent: true})
+}
+
+// PatchSeries is PatchCourse for a series.
+func (c *Client) PatchSeries(ctx context.Context, id int, patch interface{}, version int) (*Series, error) {
+	var series Series
+	err := c.do(ctx, call{method: "PATCH", path: fmt.Sprintf("/series/%d", id), body: patch, out: &series,
+		contentType: mergePatchType, ifMatch: version, idempotent: true})
+	if err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+func (c *Client) DeleteSeries(ctx context.Context, id int) error {
+	return c.do(ctx, call{method: "DELETE", path: fmt.Sprintf("/series/%d", id), idempotent: true})
+}
+
+// RestoreSeries undeletes a series. It fails with ErrBadRequest while the
+// series' course is itself deleted.
+func (c *Client) RestoreSeries(ctx context.Context, id int) (*Series, error) {
+	var series Series
+	if err := c.do(ctx, call{method: "POST", path: fmt.Sprintf("/series/%d/restore", id), out: &series, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
+
+func (c *Client) ListSeriesRevisions(ctx context.Context, id int, opts ListOptions) ([]Revision, error) {
+	return c.listRevisions(ctx, fmt.Sprintf("/series/%d/revisions", id), opts)
+}
+
+func (c *Client) GetSeriesRevision(ctx context.Context, id, version int) (*Revision, error) {
+	return c.getRevision(ctx, fmt.Sprintf("/series/%d/revisions/%d", id, version))
+}
+
+func (c *Client) DiffSeries(ctx context.Context, id, from, to int) (*Diff, error) {
+	return c.diff(ctx, fmt.Sprintf("/series/%d/revisions/diff", id), from, to)
+}
+
+// RollbackSeries is RollbackCourse for a series.
+func (c *Client) RollbackSeries(ctx context.Context, id, revision, version int) (*Series, error) {
+	var series Series
+	err := c.do(ctx, call{method: "POST", path: fmt.Sprintf("/series/%d/revisions/%d/rollback", id, revision), out: &series, ifMatch: version})
+	if err != nil {
+		return nil, err
+	}
+	return &series, nil
+}
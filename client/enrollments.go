@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"mopcare/problem"
+)
+
+// ListUserEnrollments returns one page of a user's enrollments. A user with
+// no enrollments yields an empty slice, not an error.
+func (c *Client) ListUserEnrollments(ctx context.Context, userID int, opts ListOptions) ([]UserCourseEnrollment, error) {
+	var enrollments []UserCourseEnrollment
+	err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/users/%d/enrollments", userID), query: opts.query(), out: &enrollments, idempotent: true})
+	return enrollments, notFoundIsEmpty(err, problem.CodeEnrollmentNotFound)
+}
+
+// UserEnrollments iterates over every enrollment of a user, pageSize at a time.
+func (c *Client) UserEnrollments(userID, pageSize int) *Iterator[UserCourseEnrollment] {
+	return newIterator(pageSize, func(ctx context.Context, opts ListOptions) ([]UserCourseEnrollment, error) {
+		return c.ListUserEnrollments(ctx, userID, opts)
+	})
+}
+
+// Enroll enrolls in.UserID in in.CourseID. It fails with ErrConflict with
+// Code PREREQUISITES_MISSING while the user has yet to complete any of the
+// course's prerequisites, and with Code ENROLLMENT_NOT_OPEN or
+// ENROLLMENT_CLOSED outside the course's enrollment window. In a full course
+// the enrollment is created with Status "waitlisted".
+func (c *Client) Enroll(ctx context.Context, in EnrollmentInput) (*UserCourseEnrollment, error) {
+	var enrollment UserCourseEnrollment
+	if err := c.do(ctx, call{method: "POST", path: fmt.Sprintf("/users/%d/enrollments", in.UserID), body: in, out: &enrollment}); err != nil {
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+// DeleteEnrollment deletes an enrollment, giving its seat to the first user
+// on the course's waitlist.
+func (c *Client) DeleteEnrollment(ctx context.Context, id int) error {
+	return c.do(ctx, call{method: "DELETE", path: fmt.Sprintf("/enrollments/%d", id), idempotent: true})
+}
+
+// CompleteEnrollment marks an enrollment completed, which only its user or
+// an admin may do. It fails with ErrConflict while required quizzes of the
+// course are not passed or the user is still on the waitlist.
+func (c *Client) CompleteEnrollment(ctx context.Context, id int) (*UserCourseEnrollment, error) {
+	var enrollment UserCourseEnrollment
+	if err := c.do(ctx, call{method: "POST", path: fmt.Sprintf("/enrollments/%d/complete", id), out: &enrollment, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &enrollment, nil
+}
+
+// ListRoster returns one page of the enrollments holding a seat in the
+// course, oldest first.
+func (c *Client) ListRoster(ctx context.Context, courseID int, opts ListOptions) ([]UserCourseEnrollment, error) {
+	var enrollments []UserCourseEnrollment
+	err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/courses/%d/roster", courseID), query: pageQuery(opts), out: &enrollments, idempotent: true})
+	return enrollments, err
+}
+
+// ListWaitlist returns one page of the users waiting for a seat in the
+// course, in the order they will get one.
+func (c *Client) ListWaitlist(ctx context.Context, courseID int, opts ListOptions) ([]WaitlistEntry, error) {
+	var entries []WaitlistEntry
+	err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/courses/%d/waitlist", courseID), query: pageQuery(opts), out: &entries, idempotent: true})
+	return entries, err
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Sentinel errors for errors.Is. Every *Error matches the one for its status
+// class, so callers can branch without knowing individual problem codes.
+var (
+	ErrBadRequest = errors.New("mopcare: bad request")
+	// ErrUnauthorized means the call needs a Config.Token and had none.
+	ErrUnauthorized = errors.New("mopcare: unauthorized")
+	ErrForbidden    = errors.New("mopcare: forbidden")
+	ErrNotFound     = errors.New("mopcare: not found")
+	ErrConflict     = errors.New("mopcare: conflict")
+	ErrUnavailable  = errors.New("mopcare: service unavailable")
+	// ErrPreconditionFailed means a conditional write lost a race: the
+	// resource changed after the version the caller passed was read.
+	ErrPreconditionFailed = errors.New("mopcare: precondition failed")
+)
+
+// Error is a non-2xx API response. Problem holds the decoded RFC 7807 body;
+// its Code is the stable machine-readable error code (see mopcare/problem).
+type Error struct {
+	StatusCode int
+	Problem    Problem
+}
+
+func newError(status int, body []byte) *Error {
+	e := &Error{StatusCode: status}
+	if json.Unmarshal(body, &e.Problem) != nil || e.Problem.Code == "" {
+		e.Problem = Problem{Status: status, Title: http.StatusText(status)}
+	}
+	return e
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("mopcare: %d", e.StatusCode)
+	if e.Problem.Code != "" {
+		msg += " " + e.Problem.Code
+	}
+	if e.Problem.Detail != "" {
+		msg += ": " + e.Problem.Detail
+	}
+	return msg
+}
+
+// Is matches the sentinel for the response's status class.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnprocessableEntity
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrPreconditionFailed:
+		return e.StatusCode == http.StatusPreconditionFailed
+	case ErrUnavailable:
+		return retryable(e.StatusCode)
+	}
+	return false
+}
+
+// Code returns the problem code carried by err, or "" if err is not an API
+// error.
+func Code(err error) string {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Problem.Code
+	}
+	return ""
+}
@@ -0,0 +1,1630 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mopcare/auth"
+	"mopcare/bulk"
+	"mopcare/mergepatch"
+	"mopcare/notify"
+	"mopcare/openapi"
+	"mopcare/paging"
+	"mopcare/problem"
+)
+
+// fakeAPI is an in-memory stand-in for the gateway. It validates bodies
+// against the OpenAPI document like the real gateway does, answers with the
+// same problem documents, and can be told to fail requests to test retries.
+type fakeAPI struct {
+	spec *openapi.Spec
+	url  string
+
+	mu          sync.Mutex
+	nextID      int
+	courses     map[int]Course
+	series      map[int]Series
+	users       map[int]User
+	enrollments map[int]UserCourseEnrollment
+	// revisions holds each course's revisions, oldest first.
+	revisions map[int][]Revision
+	// slugs maps former course slugs to their course.
+	slugs      map[string]int
+	media      map[int]Media
+	blobs      map[int][]byte
+	categories map[int]Category
+	// instructors maps course IDs to their instructors by user ID.
+	instructors map[int]map[int]Instructor
+	// prerequisites maps course IDs to their prerequisites by course ID.
+	prerequisites map[int]map[int]Prerequisite
+	quizzes       map[int]Quiz
+	attempts      map[int]Attempt
+	// certificates are keyed by enrollment ID.
+	certificates map[int]Certificate
+	webhooks     map[int]Webhook
+	cohorts      map[int]Cohort
+	// deliveries are queued but never sent.
+	deliveries map[int]WebhookDelivery
+	// preferences only holds users who changed theirs.
+	preferences map[int]NotificationPreferences
+	// notifications are queued but never sent.
+	notifications map[int]Notification
+	// fail makes the next n requests to "METHOD /path" answer with status.
+	fail map[string]failure
+	hits map[string]int
+}
+
+// fakeAdminToken is the ADMIN_TOKEN the fake accepts.
+const fakeAdminToken = "fake-admin"
+
+type failure struct {
+	n      int
+	status int
+}
+
+func newFake(t *testing.T) (*fakeAPI, *Client) {
+	f := &fakeAPI{
+		spec:          openapi.MustLoad(),
+		nextID:        1,
+		courses:       map[int]Course{},
+		series:        map[int]Series{},
+		users:         map[int]User{},
+		enrollments:   map[int]UserCourseEnrollment{},
+		revisions:     map[int][]Revision{},
+		slugs:         map[string]int{},
+		media:         map[int]Media{},
+		blobs:         map[int][]byte{},
+		categories:    map[int]Category{},
+		instructors:   map[int]map[int]Instructor{},
+		prerequisites: map[int]map[int]Prerequisite{},
+		quizzes:       map[int]Quiz{},
+		attempts:      map[int]Attempt{},
+		certificates:  map[int]Certificate{},
+		webhooks:      map[int]Webhook{},
+		cohorts:       map[int]Cohort{},
+		deliveries:    map[int]WebhookDelivery{},
+		preferences:   map[int]NotificationPreferences{},
+		notifications: map[int]Notification{},
+		fail:          map[string]failure{},
+		hits:          map[string]int{},
+	}
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+	f.url = srv.URL
+	return f, New(Config{BaseURL: srv.URL, RetryBackoff: time.Millisecond})
+}
+
+func (f *fakeAPI) failNext(key string, n, status int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fail[key] = failure{n: n, status: status}
+}
+
+func (f *fakeAPI) hitCount(key string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.hits[key]
+}
+
+func (f *fakeAPI) id() int {
+	id := f.nextID
+	f.nextID++
+	return id
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := r.Method + " " + r.URL.Path
+	f.hits[key]++
+	if fl, ok := f.fail[key]; ok && fl.n > 0 {
+		fl.n--
+		f.fail[key] = fl
+		writeProblem(w, problem.New(fl.status, problem.CodeUpstreamUnavailable, "injected failure"))
+		return
+	}
+
+	body, _ := io.ReadAll(r.Body)
+	if err := f.spec.ValidateRequest(r.Method, r.URL.Path, body); err != nil {
+		writeProblem(w, err.(*problem.Problem))
+		return
+	}
+	page, err := paging.Parse(r.URL.Query().Get("limit"), r.URL.Query().Get("offset"))
+	if err != nil {
+		writeProblem(w, err.(*problem.Problem))
+		return
+	}
+
+	includeDeleted, err := auth.IncludeDeleted(r.URL.Query().Get("include_deleted"), r.Header.Get("Authorization"), fakeAdminToken)
+	if err != nil {
+		writeProblem(w, err.(*problem.Problem))
+		return
+	}
+	visible := func(deletedAt *time.Time) bool { return includeDeleted || deletedAt == nil }
+	// Learners only see published courses; admins see every status unless
+	// they ask for one.
+	admin := auth.IsAdmin(r.Header.Get("Authorization"), fakeAdminToken)
+	status := r.URL.Query().Get("status")
+	if status == "" && !admin {
+		status = "published"
+	} else if status != "published" && !admin {
+		writeProblem(w, problem.Forbidden(problem.CodeForbidden, "Only published courses are visible without an admin token"))
+		return
+	}
+	listed := func(courseID int) bool { return status == "" || f.courses[courseID].Status == status }
+	caller := auth.Identify(r.Header.Get("Authorization"), fakeAdminToken, time.Now())
+
+	seg := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	id := 0
+	if len(seg) > 1 {
+		id, _ = strconv.Atoi(seg[1])
+	}
+	route := r.Method + " /" + seg[0]
+	if len(seg) > 1 {
+		route += "/:id"
+	}
+	if len(seg) > 2 {
+		route += "/" + seg[2]
+	}
+	if len(seg) == 3 && seg[1] == "by-slug" {
+		route = r.Method + " /" + seg[0] + "/by-slug"
+	}
+	if len(seg) == 2 && seg[0] == "courses" && seg[1] == "catalog" {
+		route = r.Method + " /courses/catalog"
+	}
+	if len(seg) == 2 && seg[0] == "webhooks" && seg[1] == "dead-letters" {
+		route = r.Method + " /webhooks/dead-letters"
+	}
+	if len(seg) == 2 && seg[0] == "notifications" && seg[1] == "unsubscribe" {
+		route = r.Method + " /notifications/unsubscribe"
+	}
+	if len(seg) == 2 && (seg[1] == "import" || seg[1] == "export" || seg[1] == "batch") {
+		route = r.Method + " /" + seg[0] + "/" + seg[1]
+	}
+	version := 0
+	if len(seg) > 3 {
+		if seg[3] == "diff" {
+			route += "/diff"
+		} else {
+			route += "/:version"
+			version, _ = strconv.Atoi(seg[3])
+		}
+	}
+	if len(seg) > 4 {
+		route += "/" + seg[4]
+	}
+
+	// Users need a role on the course they write to. Anonymous course writes
+	// are let through so tests that do not care about ownership need no token.
+	if roles, ok := fakeCourseRoles[route]; ok && caller.UserID != 0 && !caller.Admin &&
+		!(route == "DELETE /courses/:id/instructors/:version" && version == caller.UserID) {
+		if role := f.instructors[id][caller.UserID].Role; role == "" || !strings.Contains(roles, role) {
+			writeProblem(w, problem.Forbidden(problem.CodeForbidden, "Requires the "+roles+" role on this course"))
+			return
+		}
+	}
+
+	switch route {
+	case "GET /courses", "GET /courses/catalog":
+		// The plain list also shows users the courses they author.
+		authored := func(courseID int) bool {
+			role := f.instructors[courseID][caller.UserID].Role
+			return route == "GET /courses" && r.URL.Query().Get("status") == "" && caller.UserID != 0 && (role == RoleOwner || role == RoleCoAuthor)
+		}
+		var list []Course
+		for _, c := range sorted(f.courses) {
+			if visible(c.DeletedAt) && (listed(c.ID) || authored(c.ID)) && f.catalogued(c, r.URL.Query()) {
+				list = append(list, c)
+			}
+		}
+		if route == "GET /courses" {
+			writeJSON(w, 200, paging.Slice(list, page))
+			return
+		}
+		writeJSON(w, 200, f.catalog(list, page))
+	case "GET /courses/by-slug":
+		slug := seg[2]
+		id, ok := f.slugs[slug]
+		for _, c := range f.courses {
+			if c.UniqueID == slug {
+				id, ok = c.ID, true
+			}
+		}
+		c := f.courses[id]
+		if !ok || !visible(c.DeletedAt) || !listed(id) {
+			writeProblem(w, problem.NotFound(problem.CodeCourseNotFound, "Course not found"))
+			return
+		}
+		if c.UniqueID != slug {
+			http.Redirect(w, r, "/courses/by-slug/"+c.UniqueID, http.StatusMovedPermanently)
+			return
+		}
+		writeJSON(w, 200, c)
+	case "POST /courses":
+		var in CourseInput
+		json.Unmarshal(body, &in)
+		if in.UniqueID == "" {
+			in.UniqueID = strings.ToLower(strings.Join(strings.Fields(in.Title), "-"))
+		}
+		c := Course{ID: f.id(), Title: in.Title, Content: in.Content, OverviewVideoURL: in.OverviewVideoURL,
+			CoverImageURL: in.CoverImageURL, UniqueID: in.UniqueID, CategoryID: in.CategoryID, Tags: fakeTags(in.Tags), Price: in.Price,
+			CreatedAt: time.Now().UTC(), Version: 1, Status: "draft"}
+		f.courses[c.ID] = c
+		f.revise(c, 0)
+		if caller.UserID != 0 {
+			f.instructors[c.ID] = map[int]Instructor{caller.UserID: {CourseID: c.ID, UserID: caller.UserID, Role: RoleOwner, CreatedAt: c.CreatedAt}}
+		}
+		writeJSON(w, 201, c)
+	case "GET /courses/:id":
+		if c, ok := f.courses[id]; ok && visible(c.DeletedAt) && listed(id) {
+			writeJSON(w, 200, c)
+			return
+		}
+		writeProblem(w, problem.NotFound(problem.CodeCourseNotFound, "Course not found"))
+	case "PUT /courses/:id", "PATCH /courses/:id":
+		c, ok := f.courses[id]
+		if !ok || c.DeletedAt != nil {
+			writeProblem(w, problem.NotFound(problem.CodeCourseNotFound, "Course not found"))
+			return
+		}
+		if !precondition(w, r, c.Version) {
+			return
+		}
+		in := CourseInput{Title: c.Title, Content: c.Content, OverviewVideoURL: c.OverviewVideoURL, CoverImageURL: c.CoverImageURL, UniqueID: c.UniqueID,
+			CategoryID: c.CategoryID, Tags: c.Tags, Price: c.Price, Capacity: c.Capacity, EnrollmentOpensAt: c.EnrollmentOpensAt, EnrollmentClosesAt: c.EnrollmentClosesAt}
+		if r.Method == "PATCH" {
+			body = patched(in, body)
+			in = CourseInput{}
+		}
+		json.Unmarshal(body, &in)
+		if in.UniqueID != c.UniqueID {
+			f.slugs[c.UniqueID] = id
+		}
+		c.Title, c.Content, c.OverviewVideoURL, c.CoverImageURL, c.UniqueID = in.Title, in.Content, in.OverviewVideoURL, in.CoverImageURL, in.UniqueID
+		c.CategoryID, c.Tags, c.Price = in.CategoryID, fakeTags(in.Tags), in.Price
+		c.Capacity, c.EnrollmentOpensAt, c.EnrollmentClosesAt = in.Capacity, in.EnrollmentOpensAt, in.EnrollmentClosesAt
+		c.Version++
+		f.courses[id] = c
+		f.revise(c, 0)
+		if r.Method == "PATCH" {
+			writeJSON(w, 200, c)
+			return
+		}
+		writeJSON(w, 200, Message{Message: "Course updated successfully"})
+	case "DELETE /courses/:id":
+		if c, ok := f.courses[id]; ok && c.DeletedAt == nil {
+			now := time.Now().UTC()
+			c.DeletedAt = &now
+			f.courses[id] = c
+		}
+		writeJSON(w, 200, Message{Message: "Course deleted successfully"})
+	case "POST /courses/:id/restore":
+		c, ok := f.courses[id]
+		if !ok {
+			writeProblem(w, problem.NotFound(problem.CodeCourseNotFound, "Course not found"))
+			return
+		}
+		c.DeletedAt = nil
+		f.courses[id] = c
+		writeJSON(w, 200, c)
+	case "POST /courses/:id/submit", "POST /courses/:id/reject", "POST /courses/:id/publish", "POST /courses/:id/archive":
+		c, ok := f.courses[id]
+		if !ok || c.DeletedAt != nil {
+			writeProblem(w, problem.NotFound(problem.CodeCourseNotFound, "Course not found"))
+			return
+		}
+		if !precondition(w, r, c.Version) {
+			return
+		}
+		from := map[string]string{"submit": "draft", "reject": "in_review", "publish": "draft in_review archived", "archive": "draft in_review published"}[seg[2]]
+		if !strings.Contains(from, c.Status) {
+			writeProblem(w, problem.Conflict(problem.CodeCourseStatusConflict, "Cannot "+seg[2]+" a course that is "+c.Status))
+			return
+		}
+		var in PublishInput
+		json.Unmarshal(body, &in)
+		c.PublishAt = nil
+		switch to := map[string]string{"submit": "in_review", "reject": "draft", "publish": "published", "archive": "archived"}[seg[2]]; {
+		case to == "published" && in.PublishAt.After(time.Now()):
+			c.PublishAt = &in.PublishAt
+		case to == "published":
+			now := time.Now().UTC()
+			c.Status, c.PublishedAt = to, &now
+		default:
+			c.Status = to
+		}
+		c.Version++
+		f.courses[id] = c
+		writeJSON(w, 200, c)
+	case "GET /courses/:id/revisions":
+		if c, ok := f.courses[id]; !ok || !visible(c.DeletedAt) || !listed(id) {
+			writeProblem(w, problem.NotFound(problem.CodeCourseNotFound, "Course not found"))
+			return
+		}
+		writeJSON(w, 200, paging.Slice(f.revisions[id], page))
+	case "GET /courses/:id/revisions/:version":
+		if rev, ok := f.revision(w, id, version); ok {
+			writeJSON(w, 200, rev)
+		}
+	case "GET /courses/:id/revisions/diff":
+		q := r.URL.Query()
+		from, _ := strconv.Atoi(q.Get("from"))
+		to, _ := strconv.Atoi(q.Get("to"))
+		a, ok := f.revision(w, id, from)
+		if !ok {
+			return
+		}
+		b, ok := f.revision(w, id, to)
+		if !ok {
+			return
+		}
+		writeJSON(w, 200, Diff{From: from, To: to, Changes: changes(a.Fields, b.Fields)})
+	case "POST /courses/:id/revisions/:version/rollback":
+		rev, ok := f.revision(w, id, version)
+		if !ok || !precondition(w, r, f.courses[id].Version) {
+			return
+		}
+		c := f.courses[id]
+		c.Title, c.Content, c.OverviewVideoURL, c.CoverImageURL, c.UniqueID = rev.Fields["title"], rev.Fields["content"],
+			rev.Fields["overview_video_url"], rev.Fields["cover_image_url"], rev.Fields["unique_id"]
+		c.Version++
+		f.courses[id] = c
+		f.revise(c, version)
+		writeJSON(w, 200, c)
+	case "GET /courses/:id/series":
+		var list []Series
+		for _, s := range sorted(f.series) {
+			if s.CourseID == id && visible(s.DeletedAt) && listed(id) {
+				list = append(list, s)
+			}
+		}
+		writeJSON(w, 200, paging.Slice(list, page))
+	case "POST /courses/:id/series":
+		if c, ok := f.courses[id]; !ok || c.DeletedAt != nil {
+			writeProblem(w, problem.Unprocessable(problem.CodeCourseNotFound, "Course does not exist"))
+			return
+		}
+		var in SeriesInput
+		json.Unmarshal(body, &in)
+		s := Series{ID: f.id(), CourseID: id, Title: in.Title, Description: in.Description, CreatedAt: time.Now().UTC(), Version: 1}
+		f.series[s.ID] = s
+		writeJSON(w, 201, s)
+	case "GET /series/:id":
+		if s, ok := f.series[id]; ok && listed(s.CourseID) {
+			writeJSON(w, 200, s)
+			return
+		}
+		writeProblem(w, problem.NotFound(problem.CodeSeriesNotFound, "Series not found"))
+	case "PUT /series/:id", "PATCH /series/:id":
+		s, ok := f.series[id]
+		if !ok {
+			writeProblem(w, problem.NotFound(problem.CodeSeriesNotFound, "Series not found"))
+			return
+		}
+		if !precondition(w, r, s.Version) {
+			return
+		}
+		var in SeriesInput
+		if r.Method == "PATCH" {
+			body = patched(SeriesInput{Title: s.Title, Description: s.Description}, body)
+		}
+		json.Unmarshal(body, &in)
+		s.Title, s.Description = in.Title, in.Description
+		s.Version++
+		f.series[id] = s
+		if r.Method == "PATCH" {
+			writeJSON(w, 200, s)
+			return
+		}
+		writeJSON(w, 200, Message{Message: "Series updated successfully"})
+	case "DELETE /series/:id":
+		delete(f.series, id)
+		writeJSON(w, 200, Message{Message: "Series deleted successfully"})
+	case "GET /series/:id/quizzes":
+		list := []Quiz{}
+		for _, q := range sorted(f.quizzes) {
+			if q.SeriesID == id {
+				list = append(list, f.redact(q, caller))
+			}
+		}
+		writeJSON(w, 200, list)
+	case "POST /series/:id/quizzes", "PUT /quizzes/:id":
+		var in QuizInput
+		json.Unmarshal(body, &in)
+		q := Quiz{ID: id, Title: in.Title, PassingScore: in.PassingScore, MaxAttempts: in.MaxAttempts, Required: in.Required,
+			Questions: in.Questions, CreatedAt: time.Now().UTC()}
+		if r.Method == "POST" {
+			if _, ok := f.series[id]; !ok {
+				writeProblem(w, problem.Unprocessable(problem.CodeSeriesNotFound, "Series does not exist"))
+				return
+			}
+			q.ID, q.SeriesID = f.id(), id
+		} else if current, ok := f.quizzes[id]; ok {
+			q.SeriesID = current.SeriesID
+		} else {
+			writeProblem(w, problem.NotFound(problem.CodeQuizNotFound, "Quiz not found"))
+			return
+		}
+		for i := range q.Questions {
+			if q.Questions[i].Type == QuestionTrueFalse {
+				q.Questions[i].Options = []string{"True", "False"}
+			}
+		}
+		f.quizzes[q.ID] = q
+		writeJSON(w, map[string]int{"POST": 201, "PUT": 200}[r.Method], q)
+	case "GET /quizzes/:id":
+		if q, ok := f.quizzes[id]; ok {
+			writeJSON(w, 200, f.redact(q, caller))
+			return
+		}
+		writeProblem(w, problem.NotFound(problem.CodeQuizNotFound, "Quiz not found"))
+	case "DELETE /quizzes/:id":
+		delete(f.quizzes, id)
+		writeJSON(w, 200, Message{Message: "Quiz deleted successfully"})
+	case "GET /quizzes/:id/attempts":
+		list := []Attempt{}
+		for _, a := range sorted(f.attempts) {
+			if a.QuizID == id && (caller.Admin || a.UserID == caller.UserID) {
+				list = append(list, a)
+			}
+		}
+		writeJSON(w, 200, list)
+	case "POST /quizzes/:id/attempts":
+		q, ok := f.quizzes[id]
+		if !ok {
+			writeProblem(w, problem.NotFound(problem.CodeQuizNotFound, "Quiz not found"))
+			return
+		}
+		if caller.UserID == 0 {
+			writeProblem(w, problem.Unauthorized(problem.CodeUnauthorized, "A user token is required"))
+			return
+		}
+		if !f.enrolled(caller.UserID, f.series[q.SeriesID].CourseID) {
+			writeProblem(w, problem.Forbidden(problem.CodeNotEnrolled, "Enroll in the course before attempting its quizzes"))
+			return
+		}
+		used := 0
+		for _, a := range f.attempts {
+			if a.QuizID == id && a.UserID == caller.UserID {
+				used++
+			}
+		}
+		if q.MaxAttempts > 0 && used >= q.MaxAttempts {
+			writeProblem(w, problem.Conflict(problem.CodeQuizAttemptsUsed, "Every attempt at this quiz is used"))
+			return
+		}
+		var in AttemptInput
+		json.Unmarshal(body, &in)
+		a := Attempt{ID: f.id(), QuizID: id, UserID: caller.UserID, Answers: in.Answers, CreatedAt: time.Now().UTC()}
+		right := 0
+		for i, question := range q.Questions {
+			correct := i < len(in.Answers) && question.Answer != nil && in.Answers[i] == *question.Answer
+			if correct {
+				right++
+			}
+			a.Correct = append(a.Correct, correct)
+		}
+		a.Score = right * 100 / len(q.Questions)
+		a.Passed = a.Score >= q.PassingScore
+		f.attempts[a.ID] = a
+		writeJSON(w, 201, a)
+	case "POST /media":
+		sum := sha256.Sum256(body)
+		checksum := hex.EncodeToString(sum[:])
+		for _, m := range f.media {
+			if m.Sha256 == checksum {
+				writeJSON(w, 200, m)
+				return
+			}
+		}
+		contentType := r.Header.Get("Content-Type")
+		m := Media{ID: f.id(), Sha256: checksum, ContentType: contentType, Size: len(body), Kind: strings.Split(contentType, "/")[0],
+			CreatedAt: time.Now().UTC(), URLExpiresAt: time.Now().Add(time.Hour).UTC()}
+		// The fake's links are signed with a constant.
+		m.URL = fmt.Sprintf("/media/%d/content?expires=%d&signature=fake", m.ID, m.URLExpiresAt.Unix())
+		f.media[m.ID], f.blobs[m.ID] = m, body
+		writeJSON(w, 201, m)
+	case "GET /media/:id":
+		if m, ok := f.media[id]; ok {
+			writeJSON(w, 200, m)
+			return
+		}
+		writeProblem(w, problem.NotFound(problem.CodeMediaNotFound, "Media not found"))
+	case "GET /media/:id/content":
+		m, ok := f.media[id]
+		if !ok || r.URL.Query().Get("signature") != "fake" {
+			writeProblem(w, problem.Forbidden(problem.CodeSignatureInvalid, "Download link is not valid"))
+			return
+		}
+		w.Header().Set("Content-Type", m.ContentType)
+		w.Write(f.blobs[id])
+	case "GET /categories":
+		list := []Category{}
+		for _, c := range sorted(f.categories) {
+			list = append(list, c)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+		writeJSON(w, 200, list)
+	case "POST /categories", "PUT /categories/:id":
+		if !admin {
+			writeProblem(w, problem.Forbidden(problem.CodeForbidden, "Only admins can manage categories"))
+			return
+		}
+		var in CategoryInput
+		json.Unmarshal(body, &in)
+		if in.Slug == "" {
+			in.Slug = strings.ToLower(strings.Join(strings.Fields(in.Name), "-"))
+		}
+		c := Category{ID: id, ParentID: in.ParentID, Name: in.Name, Slug: in.Slug, CreatedAt: time.Now().UTC()}
+		if r.Method == "POST" {
+			c.ID = f.id()
+		} else if _, ok := f.categories[id]; !ok {
+			writeProblem(w, problem.NotFound(problem.CodeCategoryNotFound, "Category not found"))
+			return
+		}
+		for _, other := range f.categories {
+			if other.ID != c.ID && other.Slug == c.Slug {
+				writeProblem(w, problem.Conflict(problem.CodeCategorySlugTaken, "Resource conflicts with an existing one"))
+				return
+			}
+		}
+		f.categories[c.ID] = c
+		if r.Method == "POST" {
+			writeJSON(w, 201, c)
+			return
+		}
+		writeJSON(w, 200, c)
+	case "GET /categories/:id":
+		if c, ok := f.categories[id]; ok {
+			writeJSON(w, 200, c)
+			return
+		}
+		writeProblem(w, problem.NotFound(problem.CodeCategoryNotFound, "Category not found"))
+	case "DELETE /categories/:id":
+		if !admin {
+			writeProblem(w, problem.Forbidden(problem.CodeForbidden, "Only admins can manage categories"))
+			return
+		}
+		if _, ok := f.categories[id]; !ok {
+			writeProblem(w, problem.NotFound(problem.CodeCategoryNotFound, "Category not found"))
+			return
+		}
+		for _, c := range f.courses {
+			if c.CategoryID != nil && *c.CategoryID == id {
+				writeProblem(w, problem.Conflict(problem.CodeCategoryInUse, "Category still has subcategories or courses; move them first"))
+				return
+			}
+		}
+		delete(f.categories, id)
+		writeJSON(w, 200, Message{Message: "Category deleted successfully"})
+	case "GET /tags":
+		var list []Course
+		for _, c := range f.courses {
+			if visible(c.DeletedAt) && listed(c.ID) {
+				list = append(list, c)
+			}
+		}
+		writeJSON(w, 200, f.catalog(list, page).Facets.Tags)
+	case "PUT /tags/:id", "DELETE /tags/:id":
+		if !admin {
+			writeProblem(w, problem.Forbidden(problem.CodeForbidden, "Only admins can manage tags"))
+			return
+		}
+		var in TagRenameInput
+		json.Unmarshal(body, &in)
+		rename := TagRename{From: seg[1], To: in.Tag}
+		for _, c := range sorted(f.courses) {
+			var tags []string
+			for _, tag := range c.Tags {
+				if tag != rename.From {
+					tags = append(tags, tag)
+				}
+			}
+			if len(tags) == len(c.Tags) || c.DeletedAt != nil {
+				continue
+			}
+			if rename.To != "" {
+				tags = append(tags, rename.To)
+			}
+			c.Tags = fakeTags(tags)
+			c.Version++
+			f.courses[c.ID] = c
+			f.revise(c, 0)
+			rename.Courses++
+		}
+		if r.Method == "DELETE" {
+			writeJSON(w, 200, Message{Message: "Tag deleted successfully"})
+			return
+		}
+		writeJSON(w, 200, rename)
+	case "GET /courses/:id/instructors":
+		if c, ok := f.courses[id]; !ok || c.DeletedAt != nil {
+			writeProblem(w, problem.NotFound(problem.CodeCourseNotFound, "Course not found"))
+			return
+		}
+		list := []Instructor{}
+		for _, in := range sorted(f.instructors[id]) {
+			list = append(list, in)
+		}
+		writeJSON(w, 200, list)
+	case "PUT /courses/:id/instructors/:version", "DELETE /courses/:id/instructors/:version":
+		if caller.Actor() == "" {
+			writeProblem(w, problem.Unauthorized(problem.CodeUnauthorized, "A user or admin token is required"))
+			return
+		}
+		if c, ok := f.courses[id]; !ok || c.DeletedAt != nil {
+			writeProblem(w, problem.NotFound(problem.CodeCourseNotFound, "Course not found"))
+			return
+		}
+		var in InstructorInput
+		json.Unmarshal(body, &in)
+		current, ok := f.instructors[id][version]
+		if r.Method == "DELETE" && !ok {
+			writeProblem(w, problem.NotFound(problem.CodeInstructorNotFound, "Instructor not found"))
+			return
+		}
+		if current.Role == RoleOwner && in.Role != RoleOwner {
+			owners := 0
+			for _, other := range f.instructors[id] {
+				if other.Role == RoleOwner {
+					owners++
+				}
+			}
+			if owners == 1 {
+				writeProblem(w, problem.Conflict(problem.CodeLastOwner, "The course must keep at least one owner"))
+				return
+			}
+		}
+		if r.Method == "DELETE" {
+			delete(f.instructors[id], version)
+			writeJSON(w, 200, Message{Message: "Instructor removed successfully"})
+			return
+		}
+		if !ok {
+			current = Instructor{CourseID: id, UserID: version, CreatedAt: time.Now().UTC()}
+		}
+		current.Role = in.Role
+		if f.instructors[id] == nil {
+			f.instructors[id] = map[int]Instructor{}
+		}
+		f.instructors[id][version] = current
+		writeJSON(w, 200, current)
+	case "GET /instructors/:id/courses":
+		role := r.URL.Query().Get("role")
+		list := []Course{}
+		for _, c := range sorted(f.courses) {
+			in, ok := f.instructors[c.ID][id]
+			if ok && (role == "" || in.Role == role) && c.DeletedAt == nil && (caller.UserID == id || listed(c.ID)) {
+				list = append(list, c)
+			}
+		}
+		writeJSON(w, 200, paging.Slice(list, page))
+	case "POST /users/:id/token":
+		if !admin {
+			writeProblem(w, problem.Forbidden(problem.CodeForbidden, "Only admins can issue user tokens"))
+			return
+		}
+		if _, ok := f.users[id]; !ok {
+			writeProblem(w, problem.NotFound(problem.CodeUserNotFound, "User not found"))
+			return
+		}
+		expires := time.Now().Add(time.Hour).Truncate(time.Second).UTC()
+		writeJSON(w, 201, UserToken{UserID: id, Token: auth.UserToken(id, expires, fakeAdminToken), ExpiresAt: expires})
+	case "GET /users":
+		var list []User
+		for _, u := range sorted(f.users) {
+			if visible(u.DeletedAt) {
+				list = append(list, u)
+			}
+		}
+		list = paging.Slice(list, page)
+		if len(list) == 0 && page.Offset == 0 {
+			writeProblem(w, problem.NotFound(problem.CodeUserNotFound, "No users found"))
+			return
+		}
+		writeJSON(w, 200, list)
+	case "POST /users":
+		var in UserInput
+		json.Unmarshal(body, &in)
+		for _, u := range f.users {
+			if u.Email == in.Email {
+				writeProblem(w, problem.Conflict(problem.CodeUserEmailTaken, "User with this email already exists"))
+				return
+			}
+		}
+		u := User{ID: f.id(), FirstName: in.FirstName, LastName: in.LastName, Email: in.Email, TotalAmountPaid: in.TotalAmountPaid, CreatedAt: time.Now().UTC()}
+		f.users[u.ID] = u
+		f.notify(u.ID, "user.created", "Welcome to Mopcare")
+		writeJSON(w, 201, u)
+	case "GET /users/:id":
+		if u, ok := f.users[id]; ok && visible(u.DeletedAt) {
+			writeJSON(w, 200, u)
+			return
+		}
+		writeProblem(w, problem.NotFound(problem.CodeUserNotFound, "User not found"))
+	case "GET /users/:id/profile":
+		u, ok := f.users[id]
+		if !ok || u.DeletedAt != nil {
+			writeProblem(w, problem.NotFound(problem.CodeUserNotFound, "User not found"))
+			return
+		}
+		p := UserProfile{FirstName: u.FirstName, LastName: u.LastName, Email: u.Email, TotalAmountPaid: u.TotalAmountPaid}
+		for _, e := range f.enrollments {
+			if e.UserID == id {
+				p.EnrolledCoursesCount++
+			}
+		}
+		writeJSON(w, 200, p)
+	case "DELETE /users/:id":
+		u, ok := f.users[id]
+		if !ok || u.DeletedAt != nil {
+			writeProblem(w, problem.NotFound(problem.CodeUserNotFound, "User not found"))
+			return
+		}
+		now := time.Now().UTC()
+		u.DeletedAt = &now
+		f.users[id] = u
+		writeJSON(w, 200, Message{Message: "User deleted successfully"})
+	case "POST /users/:id/restore":
+		if !admin {
+			writeProblem(w, problem.Forbidden(problem.CodeForbidden, "Only admins can restore users"))
+			return
+		}
+		u, ok := f.users[id]
+		if !ok {
+			writeProblem(w, problem.NotFound(problem.CodeUserNotFound, "User not found"))
+			return
+		}
+		u.DeletedAt = nil
+		f.users[id] = u
+		writeJSON(w, 200, u)
+	case "PUT /users/:id/payment":
+		var in PaymentInput
+		json.Unmarshal(body, &in)
+		u := f.users[id]
+		u.TotalAmountPaid += in.Amount
+		f.users[id] = u
+		writeJSON(w, 200, Message{Message: "Payment updated successfully"})
+	case "GET /users/:id/enrollments":
+		var list []UserCourseEnrollment
+		for _, e := range sorted(f.enrollments) {
+			if e.UserID == id {
+				list = append(list, e)
+			}
+		}
+		if list = paging.Slice(list, page); len(list) == 0 && page.Offset == 0 {
+			writeProblem(w, problem.NotFound(problem.CodeEnrollmentNotFound, "No enrollments found for this user"))
+			return
+		}
+		writeJSON(w, 200, list)
+	case "POST /users/:id/enrollments":
+		var in EnrollmentInput
+		json.Unmarshal(body, &in)
+		if missing := f.missing(in.UserID, in.CourseID); len(missing) > 0 {
+			writeProblem(w, problem.Conflict(problem.CodePrerequisitesMissing, "User has yet to complete the course's prerequisites"))
+			return
+		}
+		c, now := f.courses[in.CourseID], time.Now()
+		switch {
+		case c.EnrollmentOpensAt != nil && now.Before(*c.EnrollmentOpensAt):
+			writeProblem(w, problem.Conflict(problem.CodeEnrollmentNotOpen, "Enrollment in this course is not open yet"))
+			return
+		case c.EnrollmentClosesAt != nil && !now.Before(*c.EnrollmentClosesAt):
+			writeProblem(w, problem.Conflict(problem.CodeEnrollmentClosed, "Enrollment in this course is closed"))
+			return
+		}
+		e := UserCourseEnrollment{ID: f.id(), UserID: in.UserID, CourseID: in.CourseID, Status: in.Status}
+		if c.Capacity != nil && len(f.roster(c.ID)) >= *c.Capacity {
+			e.Status = "waitlisted"
+			f.enrollments[e.ID] = e
+			writeJSON(w, 202, e)
+			return
+		}
+		f.enrollments[e.ID] = e
+		f.queue("enrollment.created", e)
+		if e.Status == "completed" {
+			f.certify(e)
+		}
+		writeJSON(w, 201, e)
+	case "POST /enrollments/:id/complete":
+		e, ok := f.enrollments[id]
+		if !ok {
+			writeProblem(w, problem.NotFound(problem.CodeEnrollmentNotFound, "Enrollment not found"))
+			return
+		}
+		if !caller.Admin && caller.UserID == 0 {
+			writeProblem(w, problem.Unauthorized(problem.CodeUnauthorized, "A user or admin token is required"))
+			return
+		}
+		if !caller.Admin && caller.UserID != e.UserID {
+			writeProblem(w, problem.Forbidden(problem.CodeForbidden, "Enrollments can only be completed by their learner"))
+			return
+		}
+		if e.Status == "waitlisted" {
+			writeProblem(w, problem.Conflict(problem.CodeWaitlisted, "User is still on the waitlist for this course"))
+			return
+		}
+		for _, q := range f.quizzes {
+			if q.Required && f.series[q.SeriesID].CourseID == e.CourseID && !f.passed(e.UserID, q.ID) {
+				writeProblem(w, problem.Conflict(problem.CodeQuizzesNotPassed, "Required quizzes of this course are not passed yet"))
+				return
+			}
+		}
+		e.Status = "completed"
+		f.enrollments[id] = e
+		f.certify(e)
+		writeJSON(w, 200, e)
+	case "GET /users/:id/certificates":
+		if !caller.Admin && caller.UserID == 0 {
+			writeProblem(w, problem.Unauthorized(problem.CodeUnauthorized, "A user or admin token is required"))
+			return
+		}
+		if !caller.Admin && caller.UserID != id {
+			writeProblem(w, problem.Forbidden(problem.CodeForbidden, "Certificates are only shown to their learner"))
+			return
+		}
+		list := []Certificate{}
+		for _, c := range sorted(f.certificates) {
+			if c.UserID == id {
+				list = append(list, c)
+			}
+		}
+		writeJSON(w, 200, paging.Slice(list, page))
+	case "GET /certificates/:id/verify", "GET /certificates/:id/pdf":
+		for _, c := range f.certificates {
+			if c.Code != seg[1] {
+				continue
+			}
+			if seg[2] == "verify" {
+				writeJSON(w, 200, CertificateVerification{Code: c.Code, LearnerName: c.LearnerName, CourseTitle: c.CourseTitle, CompletedAt: c.CompletedAt})
+				return
+			}
+			if !caller.Admin && caller.UserID == 0 {
+				writeProblem(w, problem.Unauthorized(problem.CodeUnauthorized, "A user or admin token is required"))
+				return
+			}
+			if caller.Admin || caller.UserID == c.UserID {
+				w.Header().Set("Content-Type", "application/pdf")
+				w.Write([]byte("%PDF-1.4 " + c.Code))
+				return
+			}
+		}
+		writeProblem(w, problem.NotFound(problem.CodeCertificateNotFound, "Certificate not found"))
+	case "DELETE /enrollments/:id":
+		e, ok := f.enrollments[id]
+		if !ok {
+			writeProblem(w, problem.NotFound(problem.CodeEnrollmentNotFound, "Enrollment not found"))
+			return
+		}
+		delete(f.enrollments, id)
+		delete(f.certificates, id)
+		if waiting := f.waitlist(e.CourseID); len(waiting) > 0 && e.Status != "waitlisted" {
+			next := waiting[0]
+			next.Status = "enrolled"
+			f.enrollments[next.ID] = next
+			f.queue("enrollment.created", next)
+		}
+		writeJSON(w, 200, Message{Message: "Enrollment deleted successfully"})
+	case "GET /webhooks", "POST /webhooks", "GET /webhooks/dead-letters", "GET /webhooks/:id", "PUT /webhooks/:id",
+		"DELETE /webhooks/:id", "GET /webhooks/:id/deliveries", "POST /webhooks/:id/deliveries/:version/redeliver":
+		if !admin {
+			writeProblem(w, problem.Forbidden(problem.CodeForbidden, "Only admins can manage webhooks"))
+			return
+		}
+		if _, ok := f.webhooks[id]; !ok && strings.Contains(route, ":id") {
+			writeProblem(w, problem.NotFound(problem.CodeWebhookNotFound, "Webhook not found"))
+			return
+		}
+		f.serveWebhooks(w, r, route, id, version, body, page)
+	case "GET /users/:id/notification-preferences", "PUT /users/:id/notification-preferences", "GET /users/:id/notifications":
+		if !caller.Admin && caller.UserID == 0 {
+			writeProblem(w, problem.Unauthorized(problem.CodeUnauthorized, "A user or admin token is required"))
+			return
+		}
+		if !caller.Admin && caller.UserID != id {
+			writeProblem(w, problem.Forbidden(problem.CodeForbidden, "Notifications are only shown to their user"))
+			return
+		}
+		f.serveNotifications(w, route, id, body, page)
+	case "GET /notifications/unsubscribe", "POST /notifications/unsubscribe":
+		userID, scope, err := notify.NewLinks(f.url, fakeAdminToken).Parse(r.URL.Query().Get("token"))
+		if err != nil {
+			writeProblem(w, problem.Forbidden(problem.CodeSignatureInvalid, "Unsubscribe link is not valid"))
+			return
+		}
+		f.serveNotifications(w, route, userID, []byte(scope), page)
+	case "POST /users/import", "POST /enrollments/import", "GET /users/export", "GET /enrollments/export", "GET /payments/export":
+		if !admin {
+			writeProblem(w, problem.Forbidden(problem.CodeForbidden, "Only admins can import and export"))
+			return
+		}
+		f.serveBulk(w, r, route, body)
+	case "POST /enrollments/batch", "GET /cohorts", "POST /cohorts", "GET /cohorts/:id", "DELETE /cohorts/:id", "GET /cohorts/:id/enrollments":
+		if !admin {
+			writeProblem(w, problem.Forbidden(problem.CodeForbidden, "Only admins can manage cohorts"))
+			return
+		}
+		if _, ok := f.cohorts[id]; !ok && strings.Contains(route, ":id") {
+			writeProblem(w, problem.NotFound(problem.CodeCohortNotFound, "Cohort not found"))
+			return
+		}
+		f.serveCohorts(w, r, route, id, body, page)
+	case "GET /courses/:id/prerequisites", "PUT /courses/:id/prerequisites/:version", "DELETE /courses/:id/prerequisites/:version":
+		if c, ok := f.courses[id]; !ok || c.DeletedAt != nil {
+			writeProblem(w, problem.NotFound(problem.CodeCourseNotFound, "Course not found"))
+			return
+		}
+		f.servePrerequisites(w, route, id, version)
+	case "GET /courses/:id/roster", "GET /courses/:id/waitlist":
+		if !admin {
+			writeProblem(w, problem.Forbidden(problem.CodeForbidden, "Only admins can view rosters and waitlists"))
+			return
+		}
+		if route == "GET /courses/:id/roster" {
+			writeJSON(w, 200, paging.Slice(f.roster(id), page))
+			return
+		}
+		list := []WaitlistEntry{}
+		for i, e := range f.waitlist(id) {
+			list = append(list, WaitlistEntry{EnrollmentID: e.ID, UserID: e.UserID, CourseID: e.CourseID, CohortID: e.CohortID, Position: i + 1})
+		}
+		writeJSON(w, 200, paging.Slice(list, page))
+	case "GET /users/:id/courses/:version/eligibility":
+		if !caller.Admin && caller.UserID == 0 {
+			writeProblem(w, problem.Unauthorized(problem.CodeUnauthorized, "A user or admin token is required"))
+			return
+		}
+		if !caller.Admin && caller.UserID != id {
+			writeProblem(w, problem.Forbidden(problem.CodeForbidden, "Eligibility is only shown to its user"))
+			return
+		}
+		if _, ok := f.users[id]; !ok {
+			writeProblem(w, problem.NotFound(problem.CodeUserNotFound, "User not found"))
+			return
+		}
+		if c, ok := f.courses[version]; !ok || c.DeletedAt != nil || c.Status != "published" {
+			writeProblem(w, problem.NotFound(problem.CodeCourseNotFound, "Course not found"))
+			return
+		}
+		missing := f.missing(id, version)
+		writeJSON(w, 200, Eligibility{UserID: id, CourseID: version, Eligible: len(missing) == 0, MissingPrerequisites: missing})
+	default:
+		writeProblem(w, problem.NotFound(problem.CodeRouteNotFound, "Service not found"))
+	}
+}
+
+// servePrerequisites answers for the prerequisite routes of an existing
+// course. Writes are anonymous or checked against fakeCourseRoles already.
+func (f *fakeAPI) servePrerequisites(w http.ResponseWriter, route string, courseID, prereqID int) {
+	switch route {
+	case "GET /courses/:id/prerequisites":
+		list := []Prerequisite{}
+		for _, p := range sorted(f.prerequisites[courseID]) {
+			if f.required(p.PrerequisiteID) {
+				list = append(list, p)
+			}
+		}
+		writeJSON(w, 200, list)
+	case "PUT /courses/:id/prerequisites/:version":
+		prereq, ok := f.courses[prereqID]
+		switch {
+		case prereqID == courseID:
+			writeProblem(w, problem.Conflict(problem.CodePrerequisiteCycle, "A course cannot be its own prerequisite"))
+			return
+		case !ok || !f.required(prereqID):
+			writeProblem(w, problem.New(422, problem.CodeCourseNotFound, "Prerequisite course does not exist or is not published"))
+			return
+		case f.requires(prereqID, courseID):
+			writeProblem(w, problem.Conflict(problem.CodePrerequisiteCycle, "Adding the prerequisite would make a cycle"))
+			return
+		}
+		p, ok := f.prerequisites[courseID][prereqID]
+		if !ok {
+			p = Prerequisite{CourseID: courseID, PrerequisiteID: prereqID, CreatedAt: time.Now().UTC()}
+		}
+		p.Title = prereq.Title
+		if f.prerequisites[courseID] == nil {
+			f.prerequisites[courseID] = map[int]Prerequisite{}
+		}
+		f.prerequisites[courseID][prereqID] = p
+		writeJSON(w, 200, p)
+	case "DELETE /courses/:id/prerequisites/:version":
+		if _, ok := f.prerequisites[courseID][prereqID]; !ok {
+			writeProblem(w, problem.NotFound(problem.CodePrerequisiteNotFound, "Course does not require this prerequisite"))
+			return
+		}
+		delete(f.prerequisites[courseID], prereqID)
+		writeJSON(w, 200, Message{Message: "Prerequisite removed successfully"})
+	}
+}
+
+// required reports whether a prerequisite counts: it is published and not
+// deleted.
+func (f *fakeAPI) required(prereqID int) bool {
+	c := f.courses[prereqID]
+	return c.DeletedAt == nil && c.Status == "published"
+}
+
+// requires reports whether course requires other, directly or through its
+// prerequisites.
+func (f *fakeAPI) requires(course, other int) bool {
+	for id := range f.prerequisites[course] {
+		if id == other || f.requires(id, other) {
+			return true
+		}
+	}
+	return false
+}
+
+// missing returns the direct prerequisites of the course the user has not
+// completed.
+func (f *fakeAPI) missing(userID, courseID int) []MissingPrerequisite {
+	list := []MissingPrerequisite{}
+	for _, p := range sorted(f.prerequisites[courseID]) {
+		if !f.required(p.PrerequisiteID) {
+			continue
+		}
+		completed := false
+		for _, e := range f.enrollments {
+			completed = completed || (e.UserID == userID && e.CourseID == p.PrerequisiteID && e.Status == "completed")
+		}
+		if !completed {
+			list = append(list, MissingPrerequisite{CourseID: p.PrerequisiteID, Title: p.Title})
+		}
+	}
+	return list
+}
+
+// roster returns the course's enrollments holding a seat, oldest first.
+func (f *fakeAPI) roster(courseID int) []UserCourseEnrollment {
+	list := []UserCourseEnrollment{}
+	for _, e := range sorted(f.enrollments) {
+		if e.CourseID == courseID && e.Status != "waitlisted" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// waitlist returns the course's waitlisted enrollments, oldest first.
+func (f *fakeAPI) waitlist(courseID int) []UserCourseEnrollment {
+	var list []UserCourseEnrollment
+	for _, e := range sorted(f.enrollments) {
+		if e.CourseID == courseID && e.Status == "waitlisted" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// serveCohorts answers for the cohort routes and batch enrollments. Batch
+// items are only checked for their user and course and for duplicates.
+func (f *fakeAPI) serveCohorts(w http.ResponseWriter, r *http.Request, route string, id int, body []byte, page paging.Page) {
+	switch route {
+	case "GET /cohorts":
+		courseID, _ := strconv.Atoi(r.URL.Query().Get("course_id"))
+		list := []Cohort{}
+		for _, c := range sorted(f.cohorts) {
+			if courseID == 0 || c.CourseID == courseID {
+				list = append(list, f.counted(c))
+			}
+		}
+		writeJSON(w, 200, paging.Slice(list, page))
+	case "POST /cohorts":
+		var in CohortInput
+		json.Unmarshal(body, &in)
+		if _, ok := f.courses[in.CourseID]; !ok {
+			writeProblem(w, problem.Unprocessable(problem.CodeCourseNotFound, "Course does not exist"))
+			return
+		}
+		for _, c := range f.cohorts {
+			if c.CourseID == in.CourseID && c.Name == in.Name {
+				writeProblem(w, problem.Conflict(problem.CodeCohortNameTaken, "Resource conflicts with an existing one"))
+				return
+			}
+		}
+		c := Cohort{ID: f.id(), CourseID: in.CourseID, Name: in.Name, StartsOn: in.StartsOn, CreatedAt: time.Now().UTC()}
+		f.cohorts[c.ID] = c
+		w.Header().Set("Location", fmt.Sprintf("/cohorts/%d", c.ID))
+		writeJSON(w, 201, c)
+	case "GET /cohorts/:id":
+		writeJSON(w, 200, f.counted(f.cohorts[id]))
+	case "DELETE /cohorts/:id":
+		delete(f.cohorts, id)
+		for _, e := range f.enrollments {
+			if e.CohortID == id {
+				e.CohortID = 0
+				f.enrollments[e.ID] = e
+			}
+		}
+		writeJSON(w, 200, Message{Message: "Cohort deleted successfully"})
+	case "GET /cohorts/:id/enrollments":
+		list := []UserCourseEnrollment{}
+		for _, e := range sorted(f.enrollments) {
+			if e.CohortID == id {
+				list = append(list, e)
+			}
+		}
+		writeJSON(w, 200, paging.Slice(list, page))
+	case "POST /enrollments/batch":
+		var in BatchInput
+		json.Unmarshal(body, &in)
+		cohort, ok := f.cohorts[in.CohortID]
+		if in.CohortID != 0 && !ok {
+			writeProblem(w, problem.Unprocessable(problem.CodeCohortNotFound, "Cohort does not exist"))
+			return
+		}
+		if in.Mode == "" {
+			in.Mode = ImportAtomic
+		}
+		result := BatchResult{Mode: in.Mode, Results: []ItemResult{}}
+		seen := map[[2]int]bool{}
+		for _, e := range f.enrollments {
+			seen[[2]int{e.UserID, e.CourseID}] = true
+		}
+		for i, item := range in.Enrollments {
+			if item.CourseID == 0 {
+				item.CourseID = cohort.CourseID
+			}
+			res := ItemResult{Index: i, Status: "created"}
+			_, user := f.users[item.UserID]
+			_, course := f.courses[item.CourseID]
+			var p *problem.Problem
+			switch pair := [2]int{item.UserID, item.CourseID}; {
+			case !user:
+				p = problem.Unprocessable(problem.CodeUserNotFound, "User does not exist")
+			case !course:
+				p = problem.Unprocessable(problem.CodeCourseNotFound, "Course does not exist")
+			case seen[pair]:
+				p = problem.Conflict(problem.CodeEnrollmentDuplicate, "User is already enrolled in this course")
+			default:
+				seen[pair] = true
+				res.Enrollment = UserCourseEnrollment{UserID: item.UserID, CourseID: item.CourseID, Status: item.Status, CohortID: in.CohortID}
+			}
+			if p != nil {
+				res.Status = "failed"
+				res.Error = Problem{Type: p.Type, Title: p.Title, Status: p.Status, Code: p.Code, Detail: p.Detail}
+				result.Failed++
+			}
+			result.Results = append(result.Results, res)
+		}
+		for i, res := range result.Results {
+			switch {
+			case res.Status == "failed":
+			case in.Mode == ImportAtomic && result.Failed > 0:
+				result.Results[i] = ItemResult{Index: i, Status: "skipped"}
+			default:
+				e := res.Enrollment
+				e.ID = f.id()
+				if e.Status == "" {
+					e.Status = "enrolled"
+				}
+				f.enrollments[e.ID] = e
+				result.Results[i].Enrollment = e
+				result.Created++
+			}
+		}
+		writeJSON(w, 200, result)
+	}
+}
+
+// counted is c with the number of its enrollments.
+func (f *fakeAPI) counted(c Cohort) Cohort {
+	for _, e := range f.enrollments {
+		if e.CohortID == c.ID {
+			c.Enrollments++
+		}
+	}
+	return c
+}
+
+// serveBulk answers for the import and export routes. Imports only check
+// that users' emails are unique; exports are always JSON lines.
+func (f *fakeAPI) serveBulk(w http.ResponseWriter, r *http.Request, route string, body []byte) {
+	if strings.HasSuffix(route, "/export") {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		switch route {
+		case "GET /users/export":
+			for _, u := range sorted(f.users) {
+				enc.Encode(u)
+			}
+		case "GET /enrollments/export":
+			for _, e := range sorted(f.enrollments) {
+				enc.Encode(e)
+			}
+		case "GET /payments/export":
+			for _, u := range sorted(f.users) {
+				if u.TotalAmountPaid > 0 {
+					enc.Encode(Payment{ID: u.ID, UserID: u.ID, Email: u.Email, Amount: u.TotalAmountPaid, CreatedAt: u.CreatedAt})
+				}
+			}
+		}
+		return
+	}
+
+	opts, err := bulk.ParseOptions(r.URL.Query())
+	if err != nil {
+		writeProblem(w, err.(*problem.Problem))
+		return
+	}
+	fields, required := []string{"first_name", "last_name", "email"}, 3
+	if route == "POST /enrollments/import" {
+		fields, required = []string{"user_id", "course_id", "status"}, 2
+	}
+	reader, err := bulk.NewReader(bytes.NewReader(body), fields, fields[:required], opts.Mapping)
+	if err != nil {
+		writeProblem(w, err.(*problem.Problem))
+		return
+	}
+	rows, err := reader.ReadAll()
+	if err != nil {
+		writeProblem(w, err.(*problem.Problem))
+		return
+	}
+	report := bulk.NewReport(opts)
+	report.Rows = len(rows)
+	for _, row := range rows {
+		for _, u := range f.users {
+			if route == "POST /users/import" && u.Email == row.Get("email") {
+				report.Fail(row.Number, "email", "User with this email already exists")
+			}
+		}
+	}
+	for _, row := range rows {
+		if !report.Commit() || report.HasFailed(row.Number) {
+			continue
+		}
+		report.Imported++
+		if route == "POST /users/import" {
+			u := User{ID: f.id(), FirstName: row.Get("first_name"), LastName: row.Get("last_name"), Email: row.Get("email"), CreatedAt: time.Now().UTC()}
+			f.users[u.ID] = u
+			continue
+		}
+		userID, _ := strconv.Atoi(row.Get("user_id"))
+		courseID, _ := strconv.Atoi(row.Get("course_id"))
+		e := UserCourseEnrollment{ID: f.id(), UserID: userID, CourseID: courseID, Status: row.Get("status")}
+		if e.Status == "" {
+			e.Status = "enrolled"
+		}
+		f.enrollments[e.ID] = e
+	}
+	writeJSON(w, 200, report)
+}
+
+// serveNotifications answers for the user-service's notification routes;
+// for unsubscribe links, body is the scope of the link.
+func (f *fakeAPI) serveNotifications(w http.ResponseWriter, route string, userID int, body []byte, page paging.Page) {
+	if u, ok := f.users[userID]; !ok || u.DeletedAt != nil {
+		writeProblem(w, problem.NotFound(problem.CodeUserNotFound, "User not found"))
+		return
+	}
+	prefs, ok := f.preferences[userID]
+	if !ok {
+		prefs = NotificationPreferences{UserID: userID, EmailEnabled: true, Locale: "en", Muted: []string{}, UpdatedAt: time.Now().UTC()}
+	}
+	switch route {
+	case "GET /users/:id/notification-preferences":
+		writeJSON(w, 200, prefs)
+	case "PUT /users/:id/notification-preferences":
+		var in PreferencesInput
+		json.Unmarshal(body, &in)
+		if in.EmailEnabled != nil {
+			prefs.EmailEnabled = *in.EmailEnabled
+		}
+		if in.Locale != "" {
+			prefs.Locale = in.Locale
+		}
+		if in.Muted != nil {
+			prefs.Muted = in.Muted
+		}
+		prefs.UpdatedAt = time.Now().UTC()
+		f.preferences[userID] = prefs
+		writeJSON(w, 200, prefs)
+	case "GET /users/:id/notifications":
+		list := []Notification{}
+		for _, n := range sorted(f.notifications) {
+			if n.UserID == userID {
+				list = append([]Notification{n}, list...)
+			}
+		}
+		writeJSON(w, 200, paging.Slice(list, page))
+	case "GET /notifications/unsubscribe", "POST /notifications/unsubscribe":
+		if scope := string(body); scope == notify.ScopeAll {
+			prefs.EmailEnabled = false
+		} else if !slices.Contains(prefs.Muted, scope) {
+			prefs.Muted = append(prefs.Muted, scope)
+		}
+		f.preferences[userID] = prefs
+		writeJSON(w, 200, Message{Message: "You have been unsubscribed"})
+	}
+}
+
+// notify queues an email about eventType to the user unless they turned it
+// off.
+func (f *fakeAPI) notify(userID int, eventType, subject string) {
+	if prefs, ok := f.preferences[userID]; ok && (!prefs.EmailEnabled || slices.Contains(prefs.Muted, eventType)) {
+		return
+	}
+	id, now := f.id(), time.Now().UTC()
+	f.notifications[id] = Notification{ID: id, UserID: userID, EventSource: "user-service", EventID: id, EventType: eventType,
+		Locale: "en", Recipient: f.users[userID].Email, Subject: subject, Status: "pending", NextAttemptAt: &now, CreatedAt: now}
+}
+
+// fakeCourseRoles lists the instructor roles each course write needs.
+var fakeCourseRoles = map[string]string{
+	"PUT /courses/:id":                           "owner or co-author",
+	"PATCH /courses/:id":                         "owner or co-author",
+	"POST /courses/:id/submit":                   "owner or co-author",
+	"POST /courses/:id/reject":                   "owner or reviewer",
+	"POST /courses/:id/publish":                  "owner or reviewer",
+	"DELETE /courses/:id":                        "owner",
+	"POST /courses/:id/restore":                  "owner",
+	"POST /courses/:id/archive":                  "owner",
+	"PUT /courses/:id/instructors/:version":      "owner",
+	"DELETE /courses/:id/instructors/:version":   "owner",
+	"PUT /courses/:id/prerequisites/:version":    "owner or co-author",
+	"DELETE /courses/:id/prerequisites/:version": "owner or co-author",
+}
+
+func (f *fakeAPI) serveWebhooks(w http.ResponseWriter, r *http.Request, route string, id, deliveryID int, body []byte, page paging.Page) {
+	switch route {
+	case "GET /webhooks":
+		writeJSON(w, 200, paging.Slice(sorted(f.webhooks), page))
+	case "POST /webhooks", "PUT /webhooks/:id":
+		var in WebhookInput
+		json.Unmarshal(body, &in)
+		hook, ok := f.webhooks[id]
+		if !ok {
+			hook = Webhook{ID: f.id(), Secret: "whsec_fake", Active: true, CreatedAt: time.Now().UTC()}
+		}
+		hook.URL, hook.EventTypes, hook.UpdatedAt = in.URL, in.EventTypes, time.Now().UTC()
+		if in.Secret != "" {
+			hook.Secret = in.Secret
+		}
+		if in.Active != nil {
+			hook.Active = *in.Active
+		}
+		f.webhooks[hook.ID] = hook
+		if !ok {
+			writeJSON(w, 201, hook)
+			return
+		}
+		writeJSON(w, 200, hook)
+	case "GET /webhooks/:id":
+		writeJSON(w, 200, f.webhooks[id])
+	case "DELETE /webhooks/:id":
+		delete(f.webhooks, id)
+		writeJSON(w, 200, Message{Message: "Webhook deleted successfully"})
+	case "GET /webhooks/:id/deliveries", "GET /webhooks/dead-letters":
+		status := r.URL.Query().Get("status")
+		if route == "GET /webhooks/dead-letters" {
+			status = "dead"
+		}
+		list := []WebhookDelivery{}
+		for _, d := range sorted(f.deliveries) {
+			if (id == 0 || d.WebhookID == id) && (status == "" || d.Status == status) {
+				list = append([]WebhookDelivery{d}, list...)
+			}
+		}
+		writeJSON(w, 200, paging.Slice(list, page))
+	case "POST /webhooks/:id/deliveries/:version/redeliver":
+		d, ok := f.deliveries[deliveryID]
+		if !ok || d.WebhookID != id {
+			writeProblem(w, problem.NotFound(problem.CodeDeliveryNotFound, "Delivery not found"))
+			return
+		}
+		now := time.Now().UTC()
+		d.Status, d.Attempts, d.NextAttemptAt, d.DeliveredAt = "pending", 0, &now, nil
+		f.deliveries[d.ID] = d
+		writeJSON(w, 202, d)
+	}
+}
+
+// queue queues a delivery of an event about v to every active webhook
+// subscribed to eventType.
+func (f *fakeAPI) queue(eventType string, v interface{}) {
+	payload, _ := json.Marshal(v)
+	now := time.Now().UTC()
+	for _, hook := range sorted(f.webhooks) {
+		for _, t := range hook.EventTypes {
+			if hook.Active && t == eventType {
+				id := f.id()
+				f.deliveries[id] = WebhookDelivery{ID: id, WebhookID: hook.ID, EventSource: "enrollment-service", EventID: id, EventType: eventType,
+					Payload: string(payload), Status: "pending", Log: []WebhookAttempt{}, NextAttemptAt: &now, CreatedAt: now}
+			}
+		}
+	}
+}
+
+// certify issues the enrollment's certificate unless it has one.
+func (f *fakeAPI) certify(e UserCourseEnrollment) {
+	if _, ok := f.certificates[e.ID]; ok {
+		return
+	}
+	u := f.users[e.UserID]
+	f.certificates[e.ID] = Certificate{ID: f.id(), Code: fmt.Sprintf("TEST-0000-%04d", e.ID), EnrollmentID: e.ID, UserID: e.UserID,
+		CourseID: e.CourseID, LearnerName: u.FirstName + " " + u.LastName, CourseTitle: f.courses[e.CourseID].Title, CompletedAt: time.Now().UTC()}
+}
+
+// redact hides the quiz's answers from callers who are neither admins nor
+// instructors of its course.
+func (f *fakeAPI) redact(q Quiz, caller auth.Caller) Quiz {
+	if caller.Admin || f.instructors[f.series[q.SeriesID].CourseID][caller.UserID].Role != "" {
+		return q
+	}
+	questions := make([]Question, len(q.Questions))
+	for i, question := range q.Questions {
+		question.Answer = nil
+		questions[i] = question
+	}
+	q.Questions = questions
+	return q
+}
+
+func (f *fakeAPI) enrolled(userID, courseID int) bool {
+	for _, e := range f.enrollments {
+		if e.UserID == userID && e.CourseID == courseID {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *fakeAPI) passed(userID, quizID int) bool {
+	for _, a := range f.attempts {
+		if a.UserID == userID && a.QuizID == quizID && a.Passed {
+			return true
+		}
+	}
+	return false
+}
+
+// revise records the course's fields as of its current version.
+func (f *fakeAPI) revise(c Course, rollbackOf int) {
+	fields := map[string]string{"title": c.Title, "content": c.Content, "overview_video_url": c.OverviewVideoURL,
+		"cover_image_url": c.CoverImageURL, "unique_id": c.UniqueID}
+	var before map[string]string
+	if revs := f.revisions[c.ID]; len(revs) > 0 {
+		before = revs[len(revs)-1].Fields
+	}
+	f.revisions[c.ID] = append(f.revisions[c.ID], Revision{Version: c.Version, CreatedAt: time.Now().UTC(),
+		RollbackOf: rollbackOf, Fields: fields, Changes: changes(before, fields)})
+}
+
+// revision finds a revision of a live course, answering 404 if there is none.
+func (f *fakeAPI) revision(w http.ResponseWriter, courseID, version int) (Revision, bool) {
+	if c, ok := f.courses[courseID]; !ok || c.DeletedAt != nil {
+		writeProblem(w, problem.NotFound(problem.CodeCourseNotFound, "Course not found"))
+		return Revision{}, false
+	}
+	for _, r := range f.revisions[courseID] {
+		if r.Version == version {
+			return r, true
+		}
+	}
+	writeProblem(w, problem.NotFound(problem.CodeRevisionNotFound, "Revision not found"))
+	return Revision{}, false
+}
+
+func changes(from, to map[string]string) map[string]Change {
+	out := map[string]Change{}
+	for k, v := range to {
+		if from[k] != v {
+			out[k] = Change{From: from[k], To: v}
+		}
+	}
+	return out
+}
+
+// precondition answers 412 and returns false when the request's If-Match
+// names a version other than the current one.
+func precondition(w http.ResponseWriter, r *http.Request, current int) bool {
+	tag := r.Header.Get("If-Match")
+	if tag == "" || tag == `"`+strconv.Itoa(current)+`"` {
+		return true
+	}
+	writeProblem(w, problem.New(http.StatusPreconditionFailed, problem.CodePreconditionFailed, "stale version"))
+	return false
+}
+
+// patched merges a merge-patch body into the JSON form of current.
+func patched(current interface{}, patch []byte) []byte {
+	doc, _ := json.Marshal(current)
+	merged, _ := mergepatch.Apply(doc, patch)
+	return merged
+}
+
+// sorted returns the map's values ordered by key, i.e. by ID.
+func sorted[T any](m map[int]T) []T {
+	ids := make([]int, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	out := make([]T, len(ids))
+	for i, id := range ids {
+		out[i] = m[id]
+	}
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeProblem(w http.ResponseWriter, p *problem.Problem) {
+	w.Header().Set("Content-Type", problem.ContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// fakeTags sorts and dedupes tags like the course-service; it does not
+// normalise them.
+func fakeTags(tags []string) []string {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, tag := range tags {
+		if !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// catalogued applies the catalog filters the fake supports: category, which
+// only matches direct members by ID, and tag.
+func (f *fakeAPI) catalogued(c Course, q url.Values) bool {
+	if category := q.Get("category"); category != "" && (c.CategoryID == nil || strconv.Itoa(*c.CategoryID) != category) {
+		return false
+	}
+	for _, tag := range q["tag"] {
+		found := false
+		for _, t := range c.Tags {
+			found = found || t == tag
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// catalog pages matching courses and counts their tags, most used first.
+// Only tag facets are filled in.
+func (f *fakeAPI) catalog(matching []Course, page paging.Page) Catalog {
+	counts := map[string]int{}
+	for _, c := range matching {
+		for _, tag := range c.Tags {
+			counts[tag]++
+		}
+	}
+	catalog := Catalog{Courses: paging.Slice(matching, page), Total: len(matching), Facets: Facets{Tags: []TagCount{}}}
+	for tag, n := range counts {
+		catalog.Facets.Tags = append(catalog.Facets.Tags, TagCount{Tag: tag, Count: n})
+	}
+	sort.Slice(catalog.Facets.Tags, func(i, j int) bool {
+		a, b := catalog.Facets.Tags[i], catalog.Facets.Tags[j]
+		return a.Count > b.Count || (a.Count == b.Count && a.Tag < b.Tag)
+	})
+	return catalog
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"fmt"
+)
+
+// Instructor roles.
+const (
+	RoleOwner    = "owner"
+	RoleCoAuthor = "co-author"
+	RoleReviewer = "reviewer"
+)
+
+// ListInstructors returns the course's instructors ordered by user ID.
+func (c *Client) ListInstructors(ctx context.Context, courseID int) ([]Instructor, error) {
+	var instructors []Instructor
+	err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/courses/%d/instructors", courseID), out: &instructors, idempotent: true})
+	return instructors, err
+}
+
+// SetInstructor adds the user to the course or changes their role. It needs
+// the owner role on the course or an admin Config.Token, and fails with
+// ErrConflict if it would demote the last owner.
+func (c *Client) SetInstructor(ctx context.Context, courseID, userID int, role string) (*Instructor, error) {
+	var instructor Instructor
+	path := fmt.Sprintf("/courses/%d/instructors/%d", courseID, userID)
+	if err := c.do(ctx, call{method: "PUT", path: path, body: InstructorInput{Role: role}, out: &instructor, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &instructor, nil
+}
+
+// RemoveInstructor takes the user off the course. Owners may remove anyone
+// and instructors themselves; the last owner cannot be removed.
+func (c *Client) RemoveInstructor(ctx context.Context, courseID, userID int) error {
+	return c.do(ctx, call{method: "DELETE", path: fmt.Sprintf("/courses/%d/instructors/%d", courseID, userID), idempotent: true})
+}
+
+// ListInstructorCourses returns one page of the courses the user instructs.
+// opts.Role narrows them to one role; courses that are not published are
+// only listed for the user themself or an admin.
+func (c *Client) ListInstructorCourses(ctx context.Context, userID int, opts ListOptions) ([]Course, error) {
+	var courses []Course
+	err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/instructors/%d/courses", userID), query: opts.query(), out: &courses, idempotent: true})
+	return courses, err
+}
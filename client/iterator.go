@@ -0,0 +1,75 @@
+package client
+
+import "context"
+
+// DefaultPageSize is used by iterators created with a page size of zero.
+const DefaultPageSize = 50
+
+// Iterator walks a list endpoint page by page:
+//
+//	it := c.Courses(0)
+//	for it.Next(ctx) {
+//		course := it.Item()
+//	}
+//	if err := it.Err(); err != nil { ... }
+type Iterator[T any] struct {
+	fetch    func(ctx context.Context, opts ListOptions) ([]T, error)
+	pageSize int
+	offset   int
+	buf      []T
+	item     T
+	last     bool
+	err      error
+}
+
+func newIterator[T any](pageSize int, fetch func(context.Context, ListOptions) ([]T, error)) *Iterator[T] {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	return &Iterator[T]{fetch: fetch, pageSize: pageSize}
+}
+
+// Next advances to the next item, fetching another page when the current one
+// is used up. It returns false at the end of the list or on error.
+func (it *Iterator[T]) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+	if len(it.buf) == 0 {
+		if it.last {
+			return false
+		}
+		page, err := it.fetch(ctx, ListOptions{Limit: it.pageSize, Offset: it.offset})
+		if err != nil {
+			it.err = err
+			return false
+		}
+		it.offset += len(page)
+		it.last = len(page) < it.pageSize
+		it.buf = page
+		if len(it.buf) == 0 {
+			return false
+		}
+	}
+	it.item, it.buf = it.buf[0], it.buf[1:]
+	return true
+}
+
+// Item returns the current item.
+func (it *Iterator[T]) Item() T {
+	return it.item
+}
+
+// Err returns the error that stopped iteration, if any.
+func (it *Iterator[T]) Err() error {
+	return it.err
+}
+
+// All drains the iterator into a slice.
+func (it *Iterator[T]) All(ctx context.Context) ([]T, error) {
+	var items []T
+	for it.Next(ctx) {
+		items = append(items, it.Item())
+	}
+	return items, it.Err()
+}
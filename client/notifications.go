@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// GetNotificationPreferences returns what a user is emailed about.
+// Config.Token must belong to that user or be the admin token.
+func (c *Client) GetNotificationPreferences(ctx context.Context, userID int) (*NotificationPreferences, error) {
+	var prefs NotificationPreferences
+	if err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/users/%d/notification-preferences", userID), out: &prefs, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+// UpdateNotificationPreferences changes the preferences in sets: a nil
+// EmailEnabled or Muted and an empty Locale are left as they are.
+func (c *Client) UpdateNotificationPreferences(ctx context.Context, userID int, in PreferencesInput) (*NotificationPreferences, error) {
+	var prefs NotificationPreferences
+	if err := c.do(ctx, call{method: "PUT", path: fmt.Sprintf("/users/%d/notification-preferences", userID), body: in, out: &prefs, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &prefs, nil
+}
+
+// ListNotifications returns one page of the emails queued for a user, newest
+// first, sent or not.
+func (c *Client) ListNotifications(ctx context.Context, userID int, opts ListOptions) ([]Notification, error) {
+	var notifications []Notification
+	err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/users/%d/notifications", userID), query: pageQuery(opts), out: &notifications, idempotent: true})
+	return notifications, err
+}
+
+// Unsubscribe follows an unsubscribe link with the token taken from it. It
+// needs no Config.Token.
+func (c *Client) Unsubscribe(ctx context.Context, token string) error {
+	return c.do(ctx, call{method: "POST", path: "/notifications/unsubscribe", query: url.Values{"token": {token}}, idempotent: true})
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"fmt"
+)
+
+// ListPrerequisites returns the published courses to complete before
+// enrolling in the course, ordered by course ID.
+func (c *Client) ListPrerequisites(ctx context.Context, courseID int) ([]Prerequisite, error) {
+	var prerequisites []Prerequisite
+	err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/courses/%d/prerequisites", courseID), out: &prerequisites, idempotent: true})
+	return prerequisites, err
+}
+
+// AddPrerequisite requires users to complete prereqID before enrolling in the
+// course. It needs the owner or co-author role on the course or an admin
+// Config.Token. It fails with ErrBadRequest unless prereqID is published,
+// and with ErrConflict if prereqID already requires the course, directly or
+// through its own prerequisites.
+func (c *Client) AddPrerequisite(ctx context.Context, courseID, prereqID int) (*Prerequisite, error) {
+	var prerequisite Prerequisite
+	path := fmt.Sprintf("/courses/%d/prerequisites/%d", courseID, prereqID)
+	if err := c.do(ctx, call{method: "PUT", path: path, out: &prerequisite, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &prerequisite, nil
+}
+
+// RemovePrerequisite stops requiring prereqID before enrolling in the course.
+func (c *Client) RemovePrerequisite(ctx context.Context, courseID, prereqID int) error {
+	return c.do(ctx, call{method: "DELETE", path: fmt.Sprintf("/courses/%d/prerequisites/%d", courseID, prereqID), idempotent: true})
+}
+
+// GetEligibility reports whether the user may enroll in a published course
+// and which of its prerequisites they have yet to complete. Enroll fails
+// with ErrConflict while any are missing.
+func (c *Client) GetEligibility(ctx context.Context, userID, courseID int) (*Eligibility, error) {
+	var eligibility Eligibility
+	path := fmt.Sprintf("/users/%d/courses/%d/eligibility", userID, courseID)
+	if err := c.do(ctx, call{method: "GET", path: path, out: &eligibility, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &eligibility, nil
+}
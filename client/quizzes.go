@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"fmt"
+)
+
+// Question types.
+const (
+	QuestionMultipleChoice = "multiple_choice"
+	QuestionTrueFalse      = "true_false"
+)
+
+// ListQuizzes returns the quizzes of a series. Question answers are only
+// filled in for admins and the course's instructors.
+func (c *Client) ListQuizzes(ctx context.Context, seriesID int) ([]Quiz, error) {
+	var quizzes []Quiz
+	err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/series/%d/quizzes", seriesID), out: &quizzes, idempotent: true})
+	return quizzes, err
+}
+
+// CreateQuiz adds a quiz to a series. It needs an owner or co-author of the
+// course or an admin Config.Token.
+func (c *Client) CreateQuiz(ctx context.Context, seriesID int, in QuizInput) (*Quiz, error) {
+	var quiz Quiz
+	if err := c.do(ctx, call{method: "POST", path: fmt.Sprintf("/series/%d/quizzes", seriesID), body: in, out: &quiz}); err != nil {
+		return nil, err
+	}
+	return &quiz, nil
+}
+
+// GetQuiz returns a quiz, with answers hidden like ListQuizzes.
+func (c *Client) GetQuiz(ctx context.Context, id int) (*Quiz, error) {
+	var quiz Quiz
+	if err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/quizzes/%d", id), out: &quiz, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &quiz, nil
+}
+
+// UpdateQuiz replaces a quiz. Attempts already made keep their grades.
+func (c *Client) UpdateQuiz(ctx context.Context, id int, in QuizInput) (*Quiz, error) {
+	var quiz Quiz
+	if err := c.do(ctx, call{method: "PUT", path: fmt.Sprintf("/quizzes/%d", id), body: in, out: &quiz, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &quiz, nil
+}
+
+// DeleteQuiz removes a quiz and every attempt at it.
+func (c *Client) DeleteQuiz(ctx context.Context, id int) error {
+	return c.do(ctx, call{method: "DELETE", path: fmt.Sprintf("/quizzes/%d", id), idempotent: true})
+}
+
+// SubmitAttempt grades answers, one option index per question, as an
+// attempt by the user whose token is Config.Token. The user must be enrolled
+// in the course; once every allowed attempt is used it fails with
+// ErrConflict. Attempts are never retried, since each one counts.
+func (c *Client) SubmitAttempt(ctx context.Context, quizID int, answers []int) (*Attempt, error) {
+	var attempt Attempt
+	path := fmt.Sprintf("/quizzes/%d/attempts", quizID)
+	if err := c.do(ctx, call{method: "POST", path: path, body: AttemptInput{Answers: answers}, out: &attempt}); err != nil {
+		return nil, err
+	}
+	return &attempt, nil
+}
+
+// ListAttempts returns the attempts at a quiz, oldest first: the caller's
+// own, or everyone's for admins and the course's instructors.
+func (c *Client) ListAttempts(ctx context.Context, quizID int) ([]Attempt, error) {
+	var attempts []Attempt
+	err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/quizzes/%d/attempts", quizID), out: &attempts, idempotent: true})
+	return attempts, err
+}
@@ -0,0 +1,703 @@
+// Code generated by go run mopcare/cmd/genclient; DO NOT EDIT.
+
+package client
+
+import "time"
+
+// Attempt mirrors the Attempt schema.
+type Attempt struct {
+	ID int `json:"id"`
+	// The chosen option for each question.
+	Answers []int `json:"answers"`
+	// Whether each answer was right.
+	Correct   []bool    `json:"correct"`
+	CreatedAt time.Time `json:"created_at"`
+	Passed    bool      `json:"passed"`
+	QuizID    int       `json:"quiz_id"`
+	// Percentage of right answers.
+	Score  int `json:"score"`
+	UserID int `json:"user_id"`
+}
+
+// AttemptInput mirrors the AttemptInput schema.
+type AttemptInput struct {
+	// The index of the chosen option for each question, in order.
+	Answers []int `json:"answers"`
+}
+
+// BatchInput mirrors the BatchInput schema.
+type BatchInput struct {
+	// A cohort every enrollment joins
+	CohortID    int         `json:"cohort_id,omitempty"`
+	Enrollments []BatchItem `json:"enrollments"`
+	// atomic, the default, creates nothing unless every item is valid; best_effort creates the valid items
+	Mode string `json:"mode,omitempty"`
+}
+
+// BatchItem mirrors the BatchItem schema.
+type BatchItem struct {
+	// Required unless the batch names a cohort, whose course it defaults to
+	CourseID int `json:"course_id,omitempty"`
+	// Defaults to enrolled
+	Status string `json:"status,omitempty"`
+	UserID int    `json:"user_id"`
+}
+
+// BatchResult mirrors the BatchResult schema.
+type BatchResult struct {
+	Created int    `json:"created"`
+	Failed  int    `json:"failed"`
+	Mode    string `json:"mode"`
+	// One result per item, in order
+	Results []ItemResult `json:"results"`
+}
+
+// Catalog mirrors the Catalog schema.
+type Catalog struct {
+	// The requested page.
+	Courses []Course `json:"courses"`
+	Facets  Facets   `json:"facets"`
+	// Matching courses across all pages.
+	Total int `json:"total"`
+}
+
+// Category mirrors the Category schema.
+type Category struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Name      string    `json:"name"`
+	// Null for a top-level category.
+	ParentID  *int      `json:"parent_id"`
+	Slug      string    `json:"slug"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CategoryCount mirrors the CategoryCount schema.
+type CategoryCount struct {
+	ID int `json:"id"`
+	// Matching courses in the category and the categories below it.
+	Count    int    `json:"count"`
+	Name     string `json:"name"`
+	ParentID *int   `json:"parent_id"`
+	Slug     string `json:"slug"`
+}
+
+// CategoryInput mirrors the CategoryInput schema.
+type CategoryInput struct {
+	Name string `json:"name"`
+	// Parent category; null or omitted for a top-level one.
+	ParentID *int `json:"parent_id,omitempty"`
+	// Empty or omitted generates one from the name.
+	Slug string `json:"slug,omitempty"`
+}
+
+// Certificate mirrors the Certificate schema.
+type Certificate struct {
+	ID int `json:"id"`
+	// Verification code, XXXX-XXXX-XXXX.
+	Code        string    `json:"code"`
+	CompletedAt time.Time `json:"completed_at"`
+	CourseID    int       `json:"course_id"`
+	// The course title when the certificate was issued.
+	CourseTitle  string `json:"course_title"`
+	EnrollmentID int    `json:"enrollment_id"`
+	// The learner's name when the certificate was issued.
+	LearnerName string `json:"learner_name"`
+	UserID      int    `json:"user_id"`
+}
+
+// CertificateVerification mirrors the CertificateVerification schema.
+type CertificateVerification struct {
+	Code        string    `json:"code"`
+	CompletedAt time.Time `json:"completed_at"`
+	CourseTitle string    `json:"course_title"`
+	LearnerName string    `json:"learner_name"`
+}
+
+// Change mirrors the Change schema.
+type Change struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Cohort mirrors the Cohort schema.
+type Cohort struct {
+	ID        int       `json:"id"`
+	CourseID  int       `json:"course_id"`
+	CreatedAt time.Time `json:"created_at"`
+	// How many users are enrolled with the cohort
+	Enrollments int    `json:"enrollments"`
+	Name        string `json:"name"`
+	StartsOn    string `json:"starts_on"`
+}
+
+// CohortInput mirrors the CohortInput schema.
+type CohortInput struct {
+	CourseID int `json:"course_id"`
+	// Unique within the course
+	Name     string `json:"name"`
+	StartsOn string `json:"starts_on"`
+}
+
+// Course mirrors the Course schema.
+type Course struct {
+	ID int `json:"id"`
+	// Most users holding a seat at once; further enrollments are waitlisted. Null for no limit.
+	Capacity *int `json:"capacity"`
+	// Category the course is filed under.
+	CategoryID    *int      `json:"category_id"`
+	Content       string    `json:"content"`
+	CoverImageURL string    `json:"cover_image_url"`
+	CreatedAt     time.Time `json:"created_at"`
+	// Set while the resource is soft-deleted; only visible to admins via include_deleted.
+	DeletedAt *time.Time `json:"deleted_at"`
+	// Total duration of the course's series, in minutes.
+	Duration int `json:"duration"`
+	// Enrollment is refused from this time on; must be after enrollment_opens_at. Null for no closing date.
+	EnrollmentClosesAt *time.Time `json:"enrollment_closes_at"`
+	// Enrollment is refused before this time. Null for no opening date.
+	EnrollmentOpensAt *time.Time `json:"enrollment_opens_at"`
+	// Whether any series of the course is a free preview.
+	HasFreePreview   bool    `json:"has_free_preview"`
+	OverviewVideoURL string  `json:"overview_video_url"`
+	Price            float64 `json:"price"`
+	// When a scheduled publication is due.
+	PublishAt   *time.Time `json:"publish_at"`
+	PublishedAt *time.Time `json:"published_at"`
+	// Workflow state. Learners only see published courses.
+	Status string `json:"status"`
+	// Lowercase tags, sorted.
+	Tags  []string `json:"tags"`
+	Title string   `json:"title"`
+	// URL slug: lowercase letters and digits in hyphen-separated words.
+	UniqueID  string    `json:"unique_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Incremented on every write; sent as the ETag.
+	Version int `json:"version"`
+}
+
+// CourseInput mirrors the CourseInput schema.
+type CourseInput struct {
+	// Most users holding a seat at once; further enrollments are waitlisted. Null for no limit.
+	Capacity *int `json:"capacity,omitempty"`
+	// An existing category, or null for none.
+	CategoryID *int   `json:"category_id,omitempty"`
+	Content    string `json:"content"`
+	// An absolute http(s) URL, or media:<id> of an uploaded image.
+	CoverImageURL string `json:"cover_image_url,omitempty"`
+	// Enrollment is refused from this time on; must be after enrollment_opens_at. Null for no closing date.
+	EnrollmentClosesAt *time.Time `json:"enrollment_closes_at,omitempty"`
+	// Enrollment is refused before this time. Null for no opening date.
+	EnrollmentOpensAt *time.Time `json:"enrollment_opens_at,omitempty"`
+	// An absolute http(s) URL, or media:<id> of an uploaded video.
+	OverviewVideoURL string `json:"overview_video_url,omitempty"`
+	// Defaults to 0.
+	Price float64 `json:"price,omitempty"`
+	// Free-form tags; stored lowercased with whitespace collapsed, deduplicated and sorted.
+	Tags  []string `json:"tags,omitempty"`
+	Title string   `json:"title"`
+	// Custom slug. Omitted or empty generates one from the title, with -2, -3, ... added while it is taken.
+	UniqueID string `json:"unique_id,omitempty"`
+}
+
+// CoursePatch mirrors the CoursePatch schema.
+// JSON Merge Patch (RFC 7396) for a course: omitted fields are kept and null clears an optional field.
+type CoursePatch struct {
+	// Most users holding a seat at once; further enrollments are waitlisted. Null for no limit.
+	Capacity *int `json:"capacity,omitempty"`
+	// An existing category, or null to clear it.
+	CategoryID *int   `json:"category_id,omitempty"`
+	Content    string `json:"content,omitempty"`
+	// An absolute http(s) URL, or media:<id> of an uploaded image.
+	CoverImageURL *string `json:"cover_image_url,omitempty"`
+	// Enrollment is refused from this time on; must be after enrollment_opens_at. Null for no closing date.
+	EnrollmentClosesAt *time.Time `json:"enrollment_closes_at,omitempty"`
+	// Enrollment is refused before this time. Null for no opening date.
+	EnrollmentOpensAt *time.Time `json:"enrollment_opens_at,omitempty"`
+	// An absolute http(s) URL, or media:<id> of an uploaded video.
+	OverviewVideoURL *string `json:"overview_video_url,omitempty"`
+	Price            float64 `json:"price,omitempty"`
+	// Replaces every tag; null clears them.
+	Tags  []string `json:"tags,omitempty"`
+	Title string   `json:"title,omitempty"`
+	// Custom slug; null or empty generates one from the title.
+	UniqueID *string `json:"unique_id,omitempty"`
+}
+
+// Diff mirrors the Diff schema.
+type Diff struct {
+	// Fields whose value differs, by field name. A field that was cleared has an empty to.
+	Changes map[string]Change `json:"changes"`
+	From    int               `json:"from"`
+	To      int               `json:"to"`
+}
+
+// DurationRange mirrors the DurationRange schema.
+// In minutes.
+type DurationRange struct {
+	Max int `json:"max"`
+	Min int `json:"min"`
+}
+
+// Eligibility mirrors the Eligibility schema.
+type Eligibility struct {
+	CourseID int `json:"course_id"`
+	// Whether the user has completed every prerequisite of the course
+	Eligible bool `json:"eligible"`
+	// The prerequisites the user has yet to complete, by ID
+	MissingPrerequisites []MissingPrerequisite `json:"missing_prerequisites"`
+	UserID               int                   `json:"user_id"`
+}
+
+// EnrollmentInput mirrors the EnrollmentInput schema.
+type EnrollmentInput struct {
+	CourseID int    `json:"course_id"`
+	Status   string `json:"status"`
+	UserID   int    `json:"user_id"`
+}
+
+// Facets mirrors the Facets schema.
+type Facets struct {
+	// Categories with at least one match, by name.
+	Categories []CategoryCount `json:"categories"`
+	Duration   DurationRange   `json:"duration"`
+	// Matching courses with a free preview series.
+	FreePreview int        `json:"free_preview"`
+	Price       PriceRange `json:"price"`
+	// Most used first.
+	Tags []TagCount `json:"tags"`
+}
+
+// ImportError mirrors the ImportError schema.
+type ImportError struct {
+	// Field at fault; absent when the row as a whole is
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+	// Row number as in a spreadsheet: the header is row 1
+	Row int `json:"row"`
+}
+
+// ImportReport mirrors the ImportReport schema.
+type ImportReport struct {
+	DryRun bool          `json:"dry_run"`
+	Errors []ImportError `json:"errors"`
+	// Rows with errors
+	Failed int `json:"failed"`
+	// Rows written: none on a dry run, or when an atomic import had invalid rows
+	Imported int    `json:"imported"`
+	Mode     string `json:"mode"`
+	// Data rows read; blank rows are skipped
+	Rows int `json:"rows"`
+}
+
+// Instructor mirrors the Instructor schema.
+type Instructor struct {
+	CourseID  int       `json:"course_id"`
+	CreatedAt time.Time `json:"created_at"`
+	// Owners may do anything to the course; co-authors edit it and its series and submit it for review; reviewers publish it or send it back.
+	Role   string `json:"role"`
+	UserID int    `json:"user_id"`
+}
+
+// InstructorInput mirrors the InstructorInput schema.
+type InstructorInput struct {
+	// Owners may do anything to the course; co-authors edit it and its series and submit it for review; reviewers publish it or send it back.
+	Role string `json:"role"`
+}
+
+// ItemResult mirrors the ItemResult schema.
+type ItemResult struct {
+	Enrollment UserCourseEnrollment `json:"enrollment"`
+	Error      Problem              `json:"error"`
+	// Position of the item in the batch, from 0
+	Index int `json:"index"`
+	// skipped items are valid but were not created because an atomic batch had invalid ones
+	Status string `json:"status"`
+}
+
+// Media mirrors the Media schema.
+type Media struct {
+	ID          int       `json:"id"`
+	ContentType string    `json:"content_type"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Image height in pixels; absent for videos.
+	Height int    `json:"height"`
+	Kind   string `json:"kind"`
+	// Hex SHA-256 of the content. Uploading the same content again returns this media.
+	Sha256 string `json:"sha256"`
+	// Size of the content in bytes.
+	Size int `json:"size"`
+	// Signed link to a thumbnail at most 320 pixels on its longer side; images only.
+	ThumbnailURL string `json:"thumbnail_url"`
+	// Who first uploaded the content; absent for anonymous callers.
+	UploadedBy string `json:"uploaded_by"`
+	// Signed, time-limited link to the content, relative to the API.
+	URL string `json:"url"`
+	// When url and thumbnail_url stop working. Get the media again for fresh links.
+	URLExpiresAt time.Time `json:"url_expires_at"`
+	// Image width in pixels; absent for videos.
+	Width int `json:"width"`
+}
+
+// Message mirrors the Message schema.
+type Message struct {
+	Message string `json:"message"`
+}
+
+// Metrics mirrors the Metrics schema.
+type Metrics struct {
+	Gateway MetricsGateway `json:"gateway"`
+}
+
+// MetricsGateway is the "gateway" object inside Metrics.
+type MetricsGateway struct {
+	CacheHits     int `json:"cache_hits"`
+	CacheMisses   int `json:"cache_misses"`
+	TotalRequests int `json:"total_requests"`
+}
+
+// MissingPrerequisite mirrors the MissingPrerequisite schema.
+type MissingPrerequisite struct {
+	CourseID int    `json:"course_id"`
+	Title    string `json:"title"`
+}
+
+// Notification mirrors the Notification schema.
+type Notification struct {
+	ID       int `json:"id"`
+	Attempts int `json:"attempts"`
+	// Plain text.
+	Body        string    `json:"body"`
+	CreatedAt   time.Time `json:"created_at"`
+	EventID     int       `json:"event_id"`
+	EventSource string    `json:"event_source"`
+	EventType   string    `json:"event_type"`
+	// Why the last attempt failed.
+	LastError string `json:"last_error"`
+	Locale    string `json:"locale"`
+	// Set while pending.
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	// The address the email went to.
+	Recipient string     `json:"recipient"`
+	SentAt    *time.Time `json:"sent_at"`
+	// dead once every attempt failed.
+	Status  string `json:"status"`
+	Subject string `json:"subject"`
+	// Sent as List-Unsubscribe; unsubscribes from this event type.
+	UnsubscribeURL string `json:"unsubscribe_url"`
+	UserID         int    `json:"user_id"`
+}
+
+// NotificationPreferences mirrors the NotificationPreferences schema.
+type NotificationPreferences struct {
+	// Off stops every email.
+	EmailEnabled bool `json:"email_enabled"`
+	// Language emails are written in.
+	Locale string `json:"locale"`
+	// Event types the user is not emailed about.
+	Muted     []string  `json:"muted"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UserID    int       `json:"user_id"`
+}
+
+// Payment mirrors the Payment schema.
+type Payment struct {
+	ID        int       `json:"id"`
+	Amount    float64   `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+	// The paying user's email
+	Email  string `json:"email"`
+	UserID int    `json:"user_id"`
+}
+
+// PaymentInput mirrors the PaymentInput schema.
+type PaymentInput struct {
+	Amount float64 `json:"amount"`
+}
+
+// PreferencesInput mirrors the PreferencesInput schema.
+type PreferencesInput struct {
+	// Kept when left out or null.
+	EmailEnabled *bool `json:"email_enabled"`
+	// Language emails are written in; kept when left out or empty.
+	Locale string `json:"locale"`
+	// Replaces the muted event types; kept when left out or null. An empty list unmutes everything.
+	Muted []string `json:"muted"`
+}
+
+// Prerequisite mirrors the Prerequisite schema.
+type Prerequisite struct {
+	CourseID  int       `json:"course_id"`
+	CreatedAt time.Time `json:"created_at"`
+	// The course to complete first
+	PrerequisiteID int `json:"prerequisite_id"`
+	// The prerequisite's title
+	Title string `json:"title"`
+}
+
+// PriceRange mirrors the PriceRange schema.
+type PriceRange struct {
+	Max float64 `json:"max"`
+	Min float64 `json:"min"`
+}
+
+// Problem mirrors the Problem schema.
+// RFC 7807 problem details.
+type Problem struct {
+	// Stable machine-readable error code.
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+	// Individual validation failures.
+	Errors   []string `json:"errors"`
+	Instance string   `json:"instance"`
+	Status   int      `json:"status"`
+	Title    string   `json:"title"`
+	Type     string   `json:"type"`
+}
+
+// PublishInput mirrors the PublishInput schema.
+type PublishInput struct {
+	// Publish at this time instead of now. A time in the past publishes straight away.
+	PublishAt time.Time `json:"publish_at,omitempty"`
+}
+
+// Question mirrors the Question schema.
+type Question struct {
+	// Index of the correct option. Required when writing a quiz; left out where learners read it.
+	Answer *int `json:"answer,omitempty"`
+	// 2 to 10 options for multiple-choice questions; always "True" and "False" for true/false ones, which may leave them out.
+	Options []string `json:"options,omitempty"`
+	Prompt  string   `json:"prompt"`
+	Type    string   `json:"type"`
+}
+
+// Quiz mirrors the Quiz schema.
+type Quiz struct {
+	ID        int       `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	// Attempts each user gets; 0 means no limit.
+	MaxAttempts int `json:"max_attempts"`
+	// Lowest score, in percent, that passes.
+	PassingScore int        `json:"passing_score"`
+	Questions    []Question `json:"questions"`
+	// Whether enrollments in the course can only be completed once this quiz is passed.
+	Required  bool      `json:"required"`
+	SeriesID  int       `json:"series_id"`
+	Title     string    `json:"title"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// QuizInput mirrors the QuizInput schema.
+type QuizInput struct {
+	// Attempts each user gets; 0, the default, means no limit.
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	// Lowest score, in percent, that passes.
+	PassingScore int        `json:"passing_score"`
+	Questions    []Question `json:"questions"`
+	// Whether enrollments in the course can only be completed once this quiz is passed.
+	Required bool   `json:"required,omitempty"`
+	Title    string `json:"title"`
+}
+
+// Revision mirrors the Revision schema.
+type Revision struct {
+	// Who made the write; absent for anonymous callers.
+	Author string `json:"author"`
+	// How the write changed the fields; on the first revision every non-empty field.
+	Changes   map[string]Change `json:"changes"`
+	CreatedAt time.Time         `json:"created_at"`
+	// The writable fields after the write.
+	Fields map[string]string `json:"fields"`
+	// Set when the write restored the fields of this earlier version.
+	RollbackOf int `json:"rollback_of"`
+	// The version of the course or series this write produced.
+	Version int `json:"version"`
+}
+
+// Series mirrors the Series schema.
+type Series struct {
+	ID        int       `json:"id"`
+	CourseID  int       `json:"course_id"`
+	CreatedAt time.Time `json:"created_at"`
+	// Set while the resource is soft-deleted; only visible to admins via include_deleted.
+	DeletedAt   *time.Time `json:"deleted_at"`
+	Description string     `json:"description"`
+	// In minutes.
+	Duration      int       `json:"duration"`
+	IsFreePreview bool      `json:"is_free_preview"`
+	Title         string    `json:"title"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	// Incremented on every write; sent as the ETag.
+	Version int `json:"version"`
+}
+
+// SeriesInput mirrors the SeriesInput schema.
+type SeriesInput struct {
+	Description string `json:"description,omitempty"`
+	// In minutes; defaults to 0.
+	Duration int `json:"duration,omitempty"`
+	// Whether learners may watch the series without enrolling.
+	IsFreePreview bool   `json:"is_free_preview,omitempty"`
+	Title         string `json:"title"`
+}
+
+// SeriesPatch mirrors the SeriesPatch schema.
+// JSON Merge Patch (RFC 7396) for a series.
+type SeriesPatch struct {
+	Description *string `json:"description,omitempty"`
+	// In minutes.
+	Duration int `json:"duration,omitempty"`
+	// Whether learners may watch the series without enrolling.
+	IsFreePreview bool   `json:"is_free_preview,omitempty"`
+	Title         string `json:"title,omitempty"`
+}
+
+// Status mirrors the Status schema.
+type Status struct {
+	Service string `json:"service"`
+	Status  string `json:"status"`
+	Version string `json:"version"`
+}
+
+// TagCount mirrors the TagCount schema.
+type TagCount struct {
+	Count int    `json:"count"`
+	Tag   string `json:"tag"`
+}
+
+// TagRename mirrors the TagRename schema.
+type TagRename struct {
+	// How many courses were retagged.
+	Courses int    `json:"courses"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+// TagRenameInput mirrors the TagRenameInput schema.
+type TagRenameInput struct {
+	// New name; an existing tag merges the two.
+	Tag string `json:"tag"`
+}
+
+// User mirrors the User schema.
+type User struct {
+	ID                    int       `json:"id"`
+	City                  string    `json:"city"`
+	CompletedCoursesCount int       `json:"completed_courses_count"`
+	CreatedAt             time.Time `json:"created_at"`
+	// Set while the resource is soft-deleted; only visible to admins via include_deleted.
+	DeletedAt       *time.Time `json:"deleted_at"`
+	Email           string     `json:"email"`
+	EnrolledCourses string     `json:"enrolled_courses"`
+	FirstName       string     `json:"first_name"`
+	LastName        string     `json:"last_name"`
+	State           string     `json:"state"`
+	TotalAmountPaid float64    `json:"total_amount_paid"`
+}
+
+// UserCourseEnrollment mirrors the UserCourseEnrollment schema.
+type UserCourseEnrollment struct {
+	ID int `json:"id"`
+	// The cohort the user joined the course with, if any
+	CohortID int `json:"cohort_id"`
+	CourseID int `json:"course_id"`
+	// waitlisted enrollments wait for a seat in a full course and get one, oldest first, as seats free up
+	Status string `json:"status"`
+	UserID int    `json:"user_id"`
+}
+
+// UserInput mirrors the UserInput schema.
+type UserInput struct {
+	Email           string  `json:"email"`
+	FirstName       string  `json:"first_name"`
+	LastName        string  `json:"last_name"`
+	TotalAmountPaid float64 `json:"total_amount_paid,omitempty"`
+}
+
+// UserProfile mirrors the UserProfile schema.
+type UserProfile struct {
+	City                  string  `json:"city"`
+	CompletedCoursesCount int     `json:"completed_courses_count"`
+	Email                 string  `json:"email"`
+	EnrolledCoursesCount  int     `json:"enrolled_courses_count"`
+	FirstName             string  `json:"first_name"`
+	LastName              string  `json:"last_name"`
+	State                 string  `json:"state"`
+	TotalAmountPaid       float64 `json:"total_amount_paid"`
+}
+
+// UserToken mirrors the UserToken schema.
+type UserToken struct {
+	ExpiresAt time.Time `json:"expires_at"`
+	// Send as Authorization: Bearer <token>.
+	Token  string `json:"token"`
+	UserID int    `json:"user_id"`
+}
+
+// WaitlistEntry mirrors the WaitlistEntry schema.
+type WaitlistEntry struct {
+	// The cohort the user asked to join the course with, if any
+	CohortID     int `json:"cohort_id"`
+	CourseID     int `json:"course_id"`
+	EnrollmentID int `json:"enrollment_id"`
+	// Place in the queue, from 1 for the next user to get a seat
+	Position int `json:"position"`
+	UserID   int `json:"user_id"`
+}
+
+// Webhook mirrors the Webhook schema.
+type Webhook struct {
+	ID int `json:"id"`
+	// Inactive webhooks are neither queued nor sent deliveries.
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	EventTypes []string  `json:"event_types"`
+	// Key of the HMAC-SHA256 signature in X-Mopcare-Signature.
+	Secret    string    `json:"secret"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// Where deliveries are POSTed.
+	URL string `json:"url"`
+}
+
+// WebhookAttempt mirrors the WebhookAttempt schema.
+type WebhookAttempt struct {
+	At         time.Time `json:"at"`
+	DurationMs int       `json:"duration_ms"`
+	Error      string    `json:"error"`
+	// 0 when no response arrived.
+	StatusCode int `json:"status_code"`
+}
+
+// WebhookDelivery mirrors the WebhookDelivery schema.
+type WebhookDelivery struct {
+	// Sent as X-Mopcare-Delivery.
+	ID int `json:"id"`
+	// Attempts since the delivery was queued or last redelivered.
+	Attempts    int        `json:"attempts"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DeliveredAt *time.Time `json:"delivered_at"`
+	EventID     int        `json:"event_id"`
+	EventSource string     `json:"event_source"`
+	// Sent as X-Mopcare-Event.
+	EventType string `json:"event_type"`
+	// Every attempt, oldest first.
+	Log []WebhookAttempt `json:"log"`
+	// Set while pending.
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	// The request body: the event as published.
+	Payload string `json:"payload"`
+	// dead once every attempt failed.
+	Status    string `json:"status"`
+	WebhookID int    `json:"webhook_id"`
+}
+
+// WebhookInput mirrors the WebhookInput schema.
+type WebhookInput struct {
+	// Defaults to true on create; kept on update when null or omitted.
+	Active     *bool    `json:"active,omitempty"`
+	EventTypes []string `json:"event_types"`
+	// Generated on create when left out; kept on update when left out.
+	Secret string `json:"secret,omitempty"`
+	// An absolute http or https URL.
+	URL string `json:"url"`
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"mopcare/problem"
+)
+
+// ListUsers returns one page of users. An empty user table is an empty
+// slice, not an error.
+func (c *Client) ListUsers(ctx context.Context, opts ListOptions) ([]User, error) {
+	var users []User
+	err := c.do(ctx, call{method: "GET", path: "/users", query: opts.query(), out: &users, idempotent: true})
+	return users, notFoundIsEmpty(err, problem.CodeUserNotFound)
+}
+
+// Users iterates over every user, pageSize at a time.
+func (c *Client) Users(pageSize int) *Iterator[User] {
+	return newIterator(pageSize, c.ListUsers)
+}
+
+func (c *Client) GetUser(ctx context.Context, id int) (*User, error) {
+	var user User
+	if err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/users/%d", id), out: &user, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// GetUserProfile returns the user with their enrollment counts.
+func (c *Client) GetUserProfile(ctx context.Context, id int) (*UserProfile, error) {
+	var profile UserProfile
+	if err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/users/%d/profile", id), out: &profile, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (c *Client) CreateUser(ctx context.Context, in UserInput) (*User, error) {
+	var user User
+	if err := c.do(ctx, call{method: "POST", path: "/users", body: in, out: &user}); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// DeleteUser soft-deletes the user. It can be undone with RestoreUser until
+// the retention period has passed.
+func (c *Client) DeleteUser(ctx context.Context, id int) error {
+	return c.do(ctx, call{method: "DELETE", path: fmt.Sprintf("/users/%d", id), idempotent: true})
+}
+
+// RestoreUser undeletes the user. It needs the admin token.
+func (c *Client) RestoreUser(ctx context.Context, id int) (*User, error) {
+	var user User
+	if err := c.do(ctx, call{method: "POST", path: fmt.Sprintf("/users/%d/restore", id), out: &user, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// RecordPayment adds amount to the user's total paid. It is never retried:
+// a retry after a lost response would record the payment twice.
+func (c *Client) RecordPayment(ctx context.Context, userID int, amount float64) error {
+	return c.do(ctx, call{method: "PUT", path: fmt.Sprintf("/users/%d/payment", userID), body: PaymentInput{Amount: amount}})
+}
+
+// IssueUserToken mints a token that identifies the user to the course
+// service, for example as an instructor. It needs an admin Config.Token.
+func (c *Client) IssueUserToken(ctx context.Context, id int) (*UserToken, error) {
+	var token UserToken
+	if err := c.do(ctx, call{method: "POST", path: fmt.Sprintf("/users/%d/token", id), out: &token}); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
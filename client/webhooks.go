@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// ListWebhooks returns one page of webhooks, oldest first. Like every
+// webhook call, it needs an admin Config.Token.
+func (c *Client) ListWebhooks(ctx context.Context, opts ListOptions) ([]Webhook, error) {
+	var hooks []Webhook
+	err := c.do(ctx, call{method: "GET", path: "/webhooks", query: pageQuery(opts), out: &hooks, idempotent: true})
+	return hooks, err
+}
+
+// CreateWebhook subscribes in.URL to in.EventTypes. The returned webhook
+// carries the secret its deliveries are signed with, generated unless
+// in.Secret is set.
+func (c *Client) CreateWebhook(ctx context.Context, in WebhookInput) (*Webhook, error) {
+	var hook Webhook
+	if err := c.do(ctx, call{method: "POST", path: "/webhooks", body: in, out: &hook}); err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+func (c *Client) GetWebhook(ctx context.Context, id int) (*Webhook, error) {
+	var hook Webhook
+	if err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/webhooks/%d", id), out: &hook, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// UpdateWebhook replaces the webhook's URL and event types. Its secret and
+// active flag are only changed when in sets them.
+func (c *Client) UpdateWebhook(ctx context.Context, id int, in WebhookInput) (*Webhook, error) {
+	var hook Webhook
+	if err := c.do(ctx, call{method: "PUT", path: fmt.Sprintf("/webhooks/%d", id), body: in, out: &hook, idempotent: true}); err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// DeleteWebhook deletes the webhook together with its deliveries.
+func (c *Client) DeleteWebhook(ctx context.Context, id int) error {
+	return c.do(ctx, call{method: "DELETE", path: fmt.Sprintf("/webhooks/%d", id), idempotent: true})
+}
+
+// ListWebhookDeliveries returns one page of the webhook's deliveries, newest
+// first, each with the log of its attempts. A non-empty status only returns
+// deliveries that are "pending", "delivered" or "dead".
+func (c *Client) ListWebhookDeliveries(ctx context.Context, id int, status string, opts ListOptions) ([]WebhookDelivery, error) {
+	q := pageQuery(opts)
+	if status != "" {
+		q.Set("status", status)
+	}
+	var deliveries []WebhookDelivery
+	err := c.do(ctx, call{method: "GET", path: fmt.Sprintf("/webhooks/%d/deliveries", id), query: q, out: &deliveries, idempotent: true})
+	return deliveries, err
+}
+
+// ListDeadLetters returns one page of the deliveries, of every webhook, that
+// ran out of attempts, newest first.
+func (c *Client) ListDeadLetters(ctx context.Context, opts ListOptions) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	err := c.do(ctx, call{method: "GET", path: "/webhooks/dead-letters", query: pageQuery(opts), out: &deliveries, idempotent: true})
+	return deliveries, err
+}
+
+// RedeliverWebhook queues a delivery to be sent again with a fresh set of
+// attempts. It returns before the delivery is sent.
+func (c *Client) RedeliverWebhook(ctx context.Context, id, deliveryID int) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	path := fmt.Sprintf("/webhooks/%d/deliveries/%d/redeliver", id, deliveryID)
+	if err := c.do(ctx, call{method: "POST", path: path, out: &delivery}); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// pageQuery is opts.query() without the course and user filters, which
+// webhook lists do not take.
+func pageQuery(opts ListOptions) url.Values {
+	return ListOptions{Limit: opts.Limit, Offset: opts.Offset}.query()
+}
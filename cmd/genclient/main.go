@@ -0,0 +1,31 @@
+// Command genclient regenerates the client package's types from the embedded
+// OpenAPI document. Run it through go generate in client/:
+//
+//	go generate ./client
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"mopcare/openapi"
+)
+
+func main() {
+	out := flag.String("o", "types_gen.go", "output file")
+	pkg := flag.String("package", "client", "package name of the generated file")
+	flag.Parse()
+
+	spec, err := openapi.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := spec.GenerateGo(*pkg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,61 @@
+// Command migrate applies or reverts the embedded Mopcare schema migrations.
+//
+//	go run ./cmd/migrate up          apply all pending migrations
+//	go run ./cmd/migrate down [n]    revert the last n migrations (default 1)
+//	go run ./cmd/migrate version     print the applied and expected versions
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	_ "github.com/lib/pq"
+
+	"mopcare/database"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: migrate up | down [n] | version")
+		os.Exit(2)
+	}
+
+	connStr := os.Getenv("SUPABASE_DB_URL")
+	if connStr == "" {
+		log.Fatal("supabase_db_url environment variable is not set")
+	}
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		log.Fatalf("could not open database connection: %v", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	switch os.Args[1] {
+	case "up":
+		err = database.Up(ctx, db)
+	case "down":
+		steps := 1
+		if len(os.Args) > 2 {
+			if steps, err = strconv.Atoi(os.Args[2]); err != nil || steps < 1 {
+				log.Fatalf("invalid step count %q", os.Args[2])
+			}
+		}
+		err = database.Down(ctx, db, steps)
+	case "version":
+		var current, latest int64
+		if current, err = database.CurrentVersion(ctx, db); err == nil {
+			latest, err = database.LatestVersion()
+			fmt.Printf("applied: %d\nexpected: %d\n", current, latest)
+		}
+	default:
+		log.Fatalf("unknown command %q", os.Args[1])
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
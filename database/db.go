@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DB wraps *sql.DB so that every statement runs under the caller's context
+// plus a per-query deadline, and statements slower than a threshold are
+// logged. It deliberately has no context-free Query/QueryRow/Exec methods.
+type DB struct {
+	db      *sql.DB
+	timeout time.Duration
+	slow    time.Duration
+}
+
+// Wrap reads DB_QUERY_TIMEOUT (default 5s) and DB_SLOW_QUERY_THRESHOLD
+// (default 500ms) from the environment. A zero timeout disables the
+// per-query deadline; a zero threshold disables slow-query logging.
+func Wrap(db *sql.DB) *DB {
+	return &DB{
+		db:      db,
+		timeout: durationFromEnv("DB_QUERY_TIMEOUT", 5*time.Second),
+		slow:    durationFromEnv("DB_SLOW_QUERY_THRESHOLD", 500*time.Millisecond),
+	}
+}
+
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		log.Printf("Ignoring invalid %s=%q, using %s", key, raw, fallback)
+		return fallback
+	}
+	return d
+}
+
+// start derives the per-query context and returns a done func that releases
+// it and logs the statement if it ran longer than the slow threshold.
+func (d *DB) start(ctx context.Context, query string) (context.Context, func()) {
+	cancel := func() {}
+	if d.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+	}
+	began := time.Now()
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			cancel()
+			if elapsed := time.Since(began); d.slow > 0 && elapsed >= d.slow {
+				log.Printf("Slow query (%s): %s", elapsed.Round(time.Millisecond), strings.Join(strings.Fields(query), " "))
+			}
+		})
+	}
+}
+
+func (d *DB) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	ctx, done := d.start(ctx, query)
+	rows, err := d.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	return &Rows{Rows: rows, done: done}, nil
+}
+
+func (d *DB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
+	ctx, done := d.start(ctx, query)
+	return &Row{row: d.db.QueryRowContext(ctx, query, args...), done: done}
+}
+
+func (d *DB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, done := d.start(ctx, query)
+	defer done()
+	return d.db.ExecContext(ctx, query, args...)
+}
+
+// BeginTx starts a transaction bound to ctx. Its statements get the same
+// per-query deadline and slow-query logging as the DB's.
+func (d *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	tx, err := d.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{tx: tx, db: d}, nil
+}
+
+func (d *DB) PingContext(ctx context.Context) error {
+	ctx, done := d.start(ctx, "ping")
+	defer done()
+	return d.db.PingContext(ctx)
+}
+
+// Tx wraps *sql.Tx like DB wraps *sql.DB.
+type Tx struct {
+	tx *sql.Tx
+	db *DB
+}
+
+func (t *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*Rows, error) {
+	ctx, done := t.db.start(ctx, query)
+	rows, err := t.tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		done()
+		return nil, err
+	}
+	return &Rows{Rows: rows, done: done}, nil
+}
+
+func (t *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *Row {
+	ctx, done := t.db.start(ctx, query)
+	return &Row{row: t.tx.QueryRowContext(ctx, query, args...), done: done}
+}
+
+func (t *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, done := t.db.start(ctx, query)
+	defer done()
+	return t.tx.ExecContext(ctx, query, args...)
+}
+
+func (t *Tx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *Tx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// Rows releases its query deadline when closed.
+type Rows struct {
+	*sql.Rows
+	done func()
+}
+
+func (r *Rows) Close() error {
+	err := r.Rows.Close()
+	r.done()
+	return err
+}
+
+// Row releases its query deadline once scanned.
+type Row struct {
+	row  *sql.Row
+	done func()
+}
+
+func (r *Row) Scan(dest ...interface{}) error {
+	defer r.done()
+	return r.row.Scan(dest...)
+}
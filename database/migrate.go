@@ -0,0 +1,233 @@
+// Package database owns the shared Postgres schema. Migrations live in
+// database/migrations as NNNN_name.up.sql files (with an optional matching
+// .down.sql) and are embedded into every binary that imports this package.
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// lockKey is the pg_advisory_lock key every migrator takes, so services that
+// start at the same time apply migrations one after another.
+const lockKey int64 = 7_264_227_300
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is one forward step of the schema and its optional reverse.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Migrations returns the embedded migrations ordered by version.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		m := fileName.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("unexpected migration file name %q", entry.Name())
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		body, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		} else if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// LatestVersion is the schema version this build expects.
+func LatestVersion() (int64, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+	if len(migrations) == 0 {
+		return 0, nil
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// CurrentVersion reports the highest applied version, or 0 on an empty database.
+func CurrentVersion(ctx context.Context, db *sql.DB) (int64, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists)
+	if err != nil || !exists {
+		return 0, err
+	}
+	var version sql.NullInt64
+	if err := db.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, err
+	}
+	return version.Int64, nil
+}
+
+// Up applies every pending migration, each in its own transaction, while
+// holding the advisory lock.
+func Up(ctx context.Context, db *sql.DB) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	return withLock(ctx, db, func(conn *sql.Conn) error {
+		if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		)`); err != nil {
+			return fmt.Errorf("could not create schema_migrations: %v", err)
+		}
+
+		var current sql.NullInt64
+		if err := conn.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations").Scan(&current); err != nil {
+			return err
+		}
+		for _, mig := range migrations {
+			if mig.Version <= current.Int64 {
+				continue
+			}
+			if err := apply(ctx, conn, mig.Up,
+				"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", mig.Version, mig.Name); err != nil {
+				return fmt.Errorf("migration %d_%s failed: %v", mig.Version, mig.Name, err)
+			}
+			log.Printf("Applied migration %d_%s", mig.Version, mig.Name)
+		}
+		return nil
+	})
+}
+
+// Down reverts the most recent steps migrations. It refuses to revert a
+// migration that ships without a down script.
+func Down(ctx context.Context, db *sql.DB, steps int) error {
+	migrations, err := Migrations()
+	if err != nil {
+		return err
+	}
+	byVersion := map[int64]Migration{}
+	for _, mig := range migrations {
+		byVersion[mig.Version] = mig
+	}
+
+	return withLock(ctx, db, func(conn *sql.Conn) error {
+		for i := 0; i < steps; i++ {
+			var current sql.NullInt64
+			if err := conn.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations").Scan(&current); err != nil {
+				return err
+			}
+			if !current.Valid {
+				return nil
+			}
+			mig, ok := byVersion[current.Int64]
+			if !ok {
+				return fmt.Errorf("database is at unknown version %d", current.Int64)
+			}
+			if strings.TrimSpace(mig.Down) == "" {
+				return fmt.Errorf("migration %d_%s has no down script", mig.Version, mig.Name)
+			}
+			if err := apply(ctx, conn, mig.Down,
+				"DELETE FROM schema_migrations WHERE version = $1", mig.Version); err != nil {
+				return fmt.Errorf("reverting %d_%s failed: %v", mig.Version, mig.Name, err)
+			}
+			log.Printf("Reverted migration %d_%s", mig.Version, mig.Name)
+		}
+		return nil
+	})
+}
+
+// Prepare is called by every service at startup. Unless DB_AUTO_MIGRATE is
+// "false" it applies pending migrations first; either way it refuses to
+// continue if the schema is not at the version this build was compiled with.
+func Prepare(ctx context.Context, db *sql.DB) error {
+	if os.Getenv("DB_AUTO_MIGRATE") != "false" {
+		if err := Up(ctx, db); err != nil {
+			return err
+		}
+	}
+
+	want, err := LatestVersion()
+	if err != nil {
+		return err
+	}
+	got, err := CurrentVersion(ctx, db)
+	if err != nil {
+		return fmt.Errorf("could not read schema version: %v", err)
+	}
+	if got != want {
+		return fmt.Errorf("database schema is at version %d but this build expects %d", got, want)
+	}
+	return nil
+}
+
+func withLock(ctx context.Context, db *sql.DB, fn func(*sql.Conn) error) error {
+	// Session-level advisory locks belong to a connection, so pin one.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("could not acquire migration lock: %v", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+
+	return fn(conn)
+}
+
+func apply(ctx context.Context, conn *sql.Conn, script, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS user_course_enrollments;
+DROP TABLE IF EXISTS series;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS courses;
@@ -1,17 +1,17 @@
--- Mopcare LMS Database Schema
+-- Mopcare LMS baseline schema.
+-- Uses IF NOT EXISTS so databases created by the old init.sql/schema.sql
+-- scripts are adopted as version 1 without changes.
 
--- First, create the courses table
 CREATE TABLE IF NOT EXISTS courses (
     id SERIAL PRIMARY KEY,
     title VARCHAR(255) NOT NULL,
-    content TEXT NOT NULL,  -- Changed from 'description' to 'content'
+    content TEXT NOT NULL,
     overview_video_url TEXT,
     cover_image_url TEXT,
     unique_id VARCHAR(255) UNIQUE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
--- Then create the series table with the foreign key reference
 CREATE TABLE IF NOT EXISTS series (
     id SERIAL PRIMARY KEY,
     course_id INTEGER NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
@@ -24,7 +24,6 @@ CREATE TABLE IF NOT EXISTS series (
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
--- Users table
 CREATE TABLE IF NOT EXISTS users (
     id SERIAL PRIMARY KEY,
     first_name VARCHAR(100) NOT NULL,
@@ -38,7 +37,6 @@ CREATE TABLE IF NOT EXISTS users (
     created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
 );
 
--- User course enrollments table
 CREATE TABLE IF NOT EXISTS user_course_enrollments (
     id SERIAL PRIMARY KEY,
     user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
@@ -48,8 +46,7 @@ CREATE TABLE IF NOT EXISTS user_course_enrollments (
     UNIQUE(user_id, course_id)
 );
 
--- Indexes
 CREATE INDEX IF NOT EXISTS idx_series_course_id ON series(course_id);
 CREATE INDEX IF NOT EXISTS idx_enrollments_user_id ON user_course_enrollments(user_id);
 CREATE INDEX IF NOT EXISTS idx_enrollments_course_id ON user_course_enrollments(course_id);
-CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
\ No newline at end of file
+CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
@@ -0,0 +1,2 @@
+UPDATE series SET video_url = '' WHERE video_url IS NULL;
+ALTER TABLE series ALTER COLUMN video_url SET NOT NULL;
@@ -0,0 +1,3 @@
+-- The series API never accepted a video URL, so every insert violated the
+-- NOT NULL constraint. Make it optional until series media is modelled.
+ALTER TABLE series ALTER COLUMN video_url DROP NOT NULL;
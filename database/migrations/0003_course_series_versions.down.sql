@@ -0,0 +1,2 @@
+ALTER TABLE series DROP COLUMN version, DROP COLUMN updated_at;
+ALTER TABLE courses DROP COLUMN version, DROP COLUMN updated_at;
@@ -0,0 +1,13 @@
+-- Optimistic locking for course and series edits. Every update bumps version
+-- and updated_at; clients echo the version back in If-Match, and an update
+-- against a stale version is rejected with 412.
+ALTER TABLE courses
+    ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+ALTER TABLE series
+    ADD COLUMN updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+
+UPDATE courses SET updated_at = created_at WHERE created_at IS NOT NULL;
+UPDATE series SET updated_at = created_at WHERE created_at IS NOT NULL;
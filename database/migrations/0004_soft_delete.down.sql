@@ -0,0 +1,9 @@
+-- Rows that are still soft-deleted would reappear as live ones, so remove
+-- them for good before dropping the column.
+DELETE FROM series WHERE deleted_at IS NOT NULL;
+DELETE FROM courses WHERE deleted_at IS NOT NULL;
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE users DROP COLUMN deleted_at;
+ALTER TABLE series DROP COLUMN deleted_at;
+ALTER TABLE courses DROP COLUMN deleted_at;
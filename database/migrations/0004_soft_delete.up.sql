@@ -0,0 +1,12 @@
+-- Soft delete for courses, series and users. Deleting sets deleted_at instead
+-- of removing the row, so enrollments and payment history survive and the
+-- row can be restored; a purge job removes rows once they have been deleted
+-- for longer than the retention period.
+ALTER TABLE courses ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE series ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- The purge job scans by deleted_at; live rows are never in these indexes.
+CREATE INDEX idx_courses_deleted_at ON courses(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_series_deleted_at ON series(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE courses DROP COLUMN published_at, DROP COLUMN publish_at, DROP COLUMN status;
@@ -0,0 +1,18 @@
+-- Editorial workflow for courses: draft -> in_review -> published ->
+-- archived. Only published courses are shown to learners. publish_at holds a
+-- scheduled publication that the course-service's publish job carries out.
+-- Courses that existed before the workflow are already live, so they start
+-- out published; new courses start as drafts.
+ALTER TABLE courses
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'published'
+        CHECK (status IN ('draft', 'in_review', 'published', 'archived')),
+    ADD COLUMN publish_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN published_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE courses SET published_at = COALESCE(created_at, NOW());
+ALTER TABLE courses ALTER COLUMN status SET DEFAULT 'draft';
+
+-- The publish job scans for due schedules; unscheduled courses are never in
+-- this index.
+CREATE INDEX idx_courses_publish_at ON courses(publish_at) WHERE publish_at IS NOT NULL;
+CREATE INDEX idx_courses_status ON courses(status);
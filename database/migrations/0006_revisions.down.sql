@@ -0,0 +1,3 @@
+DROP TABLE series_revisions;
+DROP TABLE course_revisions;
+DROP FUNCTION revisions_are_immutable();
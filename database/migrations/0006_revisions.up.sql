@@ -0,0 +1,37 @@
+-- Content history for courses and series. Every create and every update of
+-- the writable fields appends a row holding the fields as they were after the
+-- write and what changed, keyed by the version the write produced. Rows are
+-- never changed afterwards; they go only when their course or series is
+-- purged.
+CREATE TABLE course_revisions (
+    course_id INTEGER NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    author TEXT,
+    rollback_of INTEGER,
+    fields JSONB NOT NULL,
+    changes JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (course_id, version)
+);
+
+CREATE TABLE series_revisions (
+    series_id INTEGER NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    author TEXT,
+    rollback_of INTEGER,
+    fields JSONB NOT NULL,
+    changes JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (series_id, version)
+);
+
+CREATE FUNCTION revisions_are_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'revisions are immutable';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER course_revisions_immutable BEFORE UPDATE ON course_revisions
+    FOR EACH ROW EXECUTE FUNCTION revisions_are_immutable();
+CREATE TRIGGER series_revisions_immutable BEFORE UPDATE ON series_revisions
+    FOR EACH ROW EXECUTE FUNCTION revisions_are_immutable();
@@ -0,0 +1,3 @@
+-- Generated slugs stay in unique_id; only the history and the NOT NULL go.
+ALTER TABLE courses ALTER COLUMN unique_id DROP NOT NULL;
+DROP TABLE course_slugs;
@@ -0,0 +1,30 @@
+-- unique_id becomes the course's URL slug: always set, lowercase letters,
+-- digits and single hyphens. Courses without one, or with one that is not a
+-- valid slug, get one derived from it or from the title, with "-<id>" added
+-- where two would clash. Slugs a course no longer uses move to course_slugs,
+-- where they stay reserved for that course so that old links redirect.
+CREATE TABLE course_slugs (
+    slug TEXT PRIMARY KEY,
+    course_id INTEGER NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+CREATE INDEX idx_course_slugs_course_id ON course_slugs(course_id);
+
+INSERT INTO course_slugs (slug, course_id)
+SELECT unique_id, id FROM courses
+WHERE unique_id IS NOT NULL AND (unique_id !~ '^[a-z0-9]+(-[a-z0-9]+)*$' OR length(unique_id) > 100);
+
+WITH base AS (
+    SELECT id, COALESCE(NULLIF(trim(BOTH '-' FROM left(regexp_replace(lower(COALESCE(unique_id, title)), '[^a-z0-9]+', '-', 'g'), 80)), ''), 'course') AS slug
+    FROM courses
+    WHERE unique_id IS NULL OR unique_id !~ '^[a-z0-9]+(-[a-z0-9]+)*$' OR length(unique_id) > 100
+), numbered AS (
+    SELECT id, slug, row_number() OVER (PARTITION BY slug ORDER BY id) AS n FROM base
+)
+UPDATE courses SET unique_id = CASE
+        WHEN numbered.n = 1 AND NOT EXISTS (SELECT 1 FROM courses other WHERE other.unique_id = numbered.slug) THEN numbered.slug
+        ELSE numbered.slug || '-' || courses.id
+    END
+FROM numbered WHERE courses.id = numbered.id;
+
+ALTER TABLE courses ALTER COLUMN unique_id SET NOT NULL;
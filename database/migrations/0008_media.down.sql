@@ -0,0 +1,2 @@
+-- Stored blobs are left behind in the media store.
+DROP TABLE media;
@@ -0,0 +1,15 @@
+-- Uploaded media. Blobs live in the media store under their SHA-256, so one
+-- row per distinct content: uploading the same bytes again finds this row
+-- instead of adding another. Courses point at a row with "media:<id>" in
+-- cover_image_url or overview_video_url.
+CREATE TABLE media (
+    id SERIAL PRIMARY KEY,
+    sha256 TEXT NOT NULL UNIQUE,
+    content_type TEXT NOT NULL,
+    size BIGINT NOT NULL CHECK (size > 0),
+    kind TEXT NOT NULL CHECK (kind IN ('image', 'video')),
+    width INTEGER,
+    height INTEGER,
+    uploaded_by TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
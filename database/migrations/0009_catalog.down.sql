@@ -0,0 +1,6 @@
+ALTER TABLE series
+    DROP CONSTRAINT series_duration_check,
+    ALTER COLUMN is_free_preview DROP NOT NULL;
+DROP TABLE course_tags;
+ALTER TABLE courses DROP COLUMN price, DROP COLUMN category_id;
+DROP TABLE categories;
@@ -0,0 +1,35 @@
+-- Catalog browsing: a tree of categories, free-form tags and a price on
+-- courses. A course's duration and free preview are not stored; they are
+-- summed up from the duration (in minutes) and is_free_preview of its live
+-- series, columns the baseline already had.
+CREATE TABLE categories (
+    id SERIAL PRIMARY KEY,
+    parent_id INTEGER REFERENCES categories(id),
+    name VARCHAR(100) NOT NULL,
+    slug VARCHAR(100) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK (parent_id <> id)
+);
+
+CREATE INDEX idx_categories_parent_id ON categories(parent_id);
+
+-- Categories in use cannot be deleted, so no ON DELETE action.
+ALTER TABLE courses
+    ADD COLUMN category_id INTEGER REFERENCES categories(id),
+    ADD COLUMN price NUMERIC(10, 2) NOT NULL DEFAULT 0 CHECK (price >= 0);
+
+CREATE INDEX idx_courses_category_id ON courses(category_id);
+
+CREATE TABLE course_tags (
+    course_id INTEGER NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    tag VARCHAR(50) NOT NULL,
+    PRIMARY KEY (course_id, tag)
+);
+
+CREATE INDEX idx_course_tags_tag ON course_tags(tag);
+
+UPDATE series SET is_free_preview = FALSE WHERE is_free_preview IS NULL;
+ALTER TABLE series
+    ALTER COLUMN is_free_preview SET NOT NULL,
+    ADD CONSTRAINT series_duration_check CHECK (duration >= 0);
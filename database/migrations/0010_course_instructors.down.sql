@@ -0,0 +1 @@
+DROP TABLE course_instructors;
@@ -0,0 +1,13 @@
+-- Who may change a course besides admins. The creator of a course becomes
+-- its owner; owners add co-authors, who edit content, and reviewers, who
+-- publish or send it back. Courses created before this have no instructors
+-- and stay admin-only until an admin adds an owner.
+CREATE TABLE course_instructors (
+    course_id INTEGER NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('owner', 'co-author', 'reviewer')),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (course_id, user_id)
+);
+
+CREATE INDEX idx_course_instructors_user_id ON course_instructors(user_id);
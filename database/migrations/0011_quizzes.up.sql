@@ -0,0 +1,31 @@
+-- Quizzes attached to series. Questions are stored with the quiz as a JSON
+-- array of {type, prompt, options, answer}, answer being the index of the
+-- correct option. Attempts keep the answers given and how each was graded,
+-- so later edits to a quiz do not change past results.
+CREATE TABLE quizzes (
+    id SERIAL PRIMARY KEY,
+    series_id INTEGER NOT NULL REFERENCES series(id) ON DELETE CASCADE,
+    title VARCHAR(255) NOT NULL,
+    passing_score INTEGER NOT NULL CHECK (passing_score BETWEEN 1 AND 100),
+    max_attempts INTEGER NOT NULL DEFAULT 0 CHECK (max_attempts >= 0),
+    required BOOLEAN NOT NULL DEFAULT FALSE,
+    questions JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_quizzes_series_id ON quizzes(series_id);
+
+CREATE TABLE quiz_attempts (
+    id SERIAL PRIMARY KEY,
+    quiz_id INTEGER NOT NULL REFERENCES quizzes(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    answers JSONB NOT NULL,
+    correct JSONB NOT NULL,
+    score INTEGER NOT NULL,
+    passed BOOLEAN NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_quiz_attempts_quiz_user ON quiz_attempts(quiz_id, user_id);
+CREATE INDEX idx_quiz_attempts_user_passed ON quiz_attempts(user_id) WHERE passed;
@@ -0,0 +1,33 @@
+-- Certificates issued when an enrollment is completed. The learner's name and
+-- the course title are copied in when the certificate is issued, so it keeps
+-- saying what it said then even if either is renamed later. Anyone can check
+-- a certificate by its random code; deleting the enrollment revokes it.
+CREATE TABLE certificates (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(14) NOT NULL UNIQUE,
+    enrollment_id INTEGER NOT NULL UNIQUE REFERENCES user_course_enrollments(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    course_id INTEGER NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    learner_name VARCHAR(255) NOT NULL,
+    course_title VARCHAR(255) NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_certificates_user_id ON certificates(user_id);
+
+-- Enrollments completed before certificates existed get one too, dated when
+-- they were created since that is all that is known about them. Hex digits
+-- are a subset of the code alphabet.
+INSERT INTO certificates (code, enrollment_id, user_id, course_id, learner_name, course_title, completed_at)
+SELECT substr(h, 1, 4) || '-' || substr(h, 5, 4) || '-' || substr(h, 9, 4),
+       id, user_id, course_id, learner_name, course_title, completed_at
+FROM (
+    SELECT upper(md5(random()::text || e.id::text)) AS h, e.id, e.user_id, e.course_id,
+           u.first_name || ' ' || u.last_name AS learner_name, c.title AS course_title,
+           COALESCE(e.created_at, NOW()) AS completed_at
+    FROM user_course_enrollments e
+    JOIN users u ON u.id = e.user_id
+    JOIN courses c ON c.id = e.course_id
+    WHERE e.status = 'completed'
+) completed;
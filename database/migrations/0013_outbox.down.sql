@@ -0,0 +1,3 @@
+DROP TABLE enrollment_outbox;
+DROP TABLE course_outbox;
+DROP TABLE user_outbox;
@@ -0,0 +1,34 @@
+-- Transactional outboxes, one per service. A service writes the domain
+-- events a change causes in the same transaction as the change, and its
+-- dispatcher publishes them to the broker afterwards, so an event is sent if
+-- and only if its change committed. Published rows are kept for a while for
+-- debugging and then pruned.
+CREATE TABLE user_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_user_outbox_pending ON user_outbox(id) WHERE published_at IS NULL;
+
+CREATE TABLE course_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_course_outbox_pending ON course_outbox(id) WHERE published_at IS NULL;
+
+CREATE TABLE enrollment_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_enrollment_outbox_pending ON enrollment_outbox(id) WHERE published_at IS NULL;
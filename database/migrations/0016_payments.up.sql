@@ -0,0 +1,14 @@
+-- Every payment on its own, so payments can be exported rather than only
+-- each user's running total. Totals recorded before this migration become a
+-- single payment per user, dated when the user was created.
+CREATE TABLE payments (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount DECIMAL(10,2) NOT NULL CHECK (amount > 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_payments_user_id ON payments(user_id);
+
+INSERT INTO payments (user_id, amount, created_at)
+SELECT id, total_amount_paid, COALESCE(created_at, NOW()) FROM users WHERE total_amount_paid > 0;
@@ -0,0 +1,2 @@
+ALTER TABLE user_course_enrollments DROP COLUMN cohort_id;
+DROP TABLE cohorts;
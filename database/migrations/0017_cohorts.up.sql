@@ -0,0 +1,14 @@
+-- Cohorts are groups of learners who take a course together from a shared
+-- start date. Deleting a cohort keeps its enrollments, outside any cohort.
+CREATE TABLE cohorts (
+    id SERIAL PRIMARY KEY,
+    course_id INTEGER NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    starts_on DATE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (course_id, name)
+);
+
+ALTER TABLE user_course_enrollments ADD COLUMN cohort_id INTEGER REFERENCES cohorts(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_enrollments_cohort_id ON user_course_enrollments(cohort_id);
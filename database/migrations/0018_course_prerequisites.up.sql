@@ -0,0 +1,12 @@
+-- A course's prerequisites are the courses a user must have completed before
+-- enrolling in it. Together they form a directed acyclic graph; the
+-- course-service refuses an edge that would close a cycle.
+CREATE TABLE course_prerequisites (
+    course_id INTEGER NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    prerequisite_id INTEGER NOT NULL REFERENCES courses(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (course_id, prerequisite_id),
+    CHECK (course_id <> prerequisite_id)
+);
+
+CREATE INDEX idx_course_prerequisites_prerequisite_id ON course_prerequisites(prerequisite_id);
@@ -0,0 +1,13 @@
+DROP INDEX idx_enrollments_waitlist;
+
+-- Waitlisted users never had a seat, so they go with the waitlist.
+DELETE FROM user_course_enrollments WHERE status = 'waitlisted';
+ALTER TABLE user_course_enrollments DROP CONSTRAINT user_course_enrollments_status_check;
+ALTER TABLE user_course_enrollments ADD CONSTRAINT user_course_enrollments_status_check
+    CHECK (status IN ('enrolled', 'completed'));
+
+ALTER TABLE courses
+    DROP CONSTRAINT courses_enrollment_window_check,
+    DROP COLUMN enrollment_closes_at,
+    DROP COLUMN enrollment_opens_at,
+    DROP COLUMN capacity;
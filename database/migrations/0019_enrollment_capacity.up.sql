@@ -0,0 +1,16 @@
+-- Courses may limit their seats and the dates users may enroll between;
+-- NULL means no limit. Users who enroll in a full course are waitlisted:
+-- their enrollment has the waitlisted status, takes no seat, and becomes
+-- enrolled, oldest first, as seats free up.
+ALTER TABLE courses
+    ADD COLUMN capacity INTEGER CHECK (capacity > 0),
+    ADD COLUMN enrollment_opens_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN enrollment_closes_at TIMESTAMP WITH TIME ZONE,
+    ADD CONSTRAINT courses_enrollment_window_check CHECK (enrollment_opens_at < enrollment_closes_at);
+
+ALTER TABLE user_course_enrollments DROP CONSTRAINT IF EXISTS user_course_enrollments_status_check;
+ALTER TABLE user_course_enrollments ADD CONSTRAINT user_course_enrollments_status_check
+    CHECK (status IN ('enrolled', 'completed', 'waitlisted'));
+
+-- Promotion reads a course's waitlist in ID order.
+CREATE INDEX idx_enrollments_waitlist ON user_course_enrollments(course_id, id) WHERE status = 'waitlisted';
@@ -0,0 +1,151 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by a Broker used after Close.
+var ErrClosed = errors.New("events: broker closed")
+
+// Handler receives one message published on subject. An error tells the
+// publisher, where the Broker can reach it, that the message was not
+// handled, so that it is published again.
+type Handler func(subject string, data []byte) error
+
+// Broker is a publish/subscribe transport. Subjects are dot-separated tokens
+// as in NATS, and subscription patterns may use "*" for any one token and a
+// trailing ">" for one or more.
+type Broker interface {
+	// Publish returns once the broker has accepted the message, and with
+	// an error if it or any subscriber in this process failed to handle it.
+	Publish(ctx context.Context, subject string, data []byte) error
+	// Subscribe calls handle for every message whose subject matches
+	// pattern until the returned func is called.
+	Subscribe(pattern string, handle Handler) (unsubscribe func(), err error)
+	Close() error
+}
+
+// handleTimeout bounds one call of a SubscribeAll handler.
+const handleTimeout = 10 * time.Second
+
+// SubscribeAll calls handle with every Event broker publishes of one of
+// types until the returned func is called. Its errors fail the handler, so
+// a Dispatcher in the same process publishes the event again; handle must
+// therefore not mind seeing an event twice.
+func SubscribeAll(broker Broker, types []string, handle func(ctx context.Context, e Event) error) (unsubscribe func(), err error) {
+	var unsubscribers []func()
+	unsubscribe = func() {
+		for _, u := range unsubscribers {
+			u()
+		}
+	}
+	for _, eventType := range types {
+		u, err := broker.Subscribe(eventType, func(subject string, data []byte) error {
+			var e Event
+			if err := json.Unmarshal(data, &e); err != nil {
+				return err
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), handleTimeout)
+			defer cancel()
+			return handle(ctx, e)
+		})
+		if err != nil {
+			unsubscribe()
+			return nil, err
+		}
+		unsubscribers = append(unsubscribers, u)
+	}
+	return unsubscribe, nil
+}
+
+// Local is an in-process Broker. Publish delivers to each matching
+// subscriber in turn before returning, and returns what they failed with, so
+// it suits tests and single-process deployments; subscribers in other
+// processes never see its messages.
+type Local struct {
+	mu     sync.RWMutex
+	next   int
+	subs   map[int]subscription
+	closed bool
+}
+
+type subscription struct {
+	pattern string
+	handle  Handler
+}
+
+func NewLocal() *Local {
+	return &Local{subs: map[int]subscription{}}
+}
+
+func (l *Local) Publish(ctx context.Context, subject string, data []byte) error {
+	l.mu.RLock()
+	if l.closed {
+		l.mu.RUnlock()
+		return ErrClosed
+	}
+	var handlers []Handler
+	for id := 0; id < l.next; id++ {
+		if s, ok := l.subs[id]; ok && Match(s.pattern, subject) {
+			handlers = append(handlers, s.handle)
+		}
+	}
+	l.mu.RUnlock()
+	return deliver(handlers, subject, data)
+}
+
+// deliver hands a message to every handler, even after one fails.
+func deliver(handlers []Handler, subject string, data []byte) error {
+	var errs []error
+	for _, handle := range handlers {
+		if err := handle(subject, data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (l *Local) Subscribe(pattern string, handle Handler) (func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil, ErrClosed
+	}
+	id := l.next
+	l.next++
+	l.subs[id] = subscription{pattern, handle}
+	return func() {
+		l.mu.Lock()
+		delete(l.subs, id)
+		l.mu.Unlock()
+	}, nil
+}
+
+func (l *Local) Close() error {
+	l.mu.Lock()
+	l.closed = true
+	l.subs = map[int]subscription{}
+	l.mu.Unlock()
+	return nil
+}
+
+// Match reports whether subject matches a NATS-style pattern.
+func Match(pattern, subject string) bool {
+	p, s := strings.Split(pattern, "."), strings.Split(subject, ".")
+	for i, token := range p {
+		switch {
+		case token == ">" && i == len(p)-1:
+			return len(s) > i
+		case i >= len(s):
+			return false
+		case token != "*" && token != s[i]:
+			return false
+		}
+	}
+	return len(p) == len(s)
+}
@@ -0,0 +1,108 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+
+	"mopcare/database"
+)
+
+// Dispatcher publishes the events in one service's outbox.
+type Dispatcher struct {
+	db     *database.DB
+	outbox string
+	source string
+	broker Broker
+	// Batch is how many events are published per transaction.
+	Batch int
+}
+
+// NewDispatcher publishes outbox to broker, stamping events with source,
+// usually the service's name.
+func NewDispatcher(db *database.DB, outbox, source string, broker Broker) *Dispatcher {
+	return &Dispatcher{db: db, outbox: outbox, source: source, broker: broker, Batch: 100}
+}
+
+// Dispatch publishes pending events oldest first, on subjects named after
+// their type, until none are left. It stops at the first event the broker
+// refuses or a subscriber in this process fails to handle, so events are
+// never published out of order; that event stays unpublished and is
+// published again, to every subscriber, on the next call. Only one replica
+// dispatches an outbox at a time.
+func (d *Dispatcher) Dispatch(ctx context.Context) error {
+	for {
+		n, err := d.dispatchBatch(ctx)
+		if err != nil || n < d.Batch {
+			return err
+		}
+	}
+}
+
+func (d *Dispatcher) dispatchBatch(ctx context.Context) (int, error) {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Another replica holding the lock is already doing the work.
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock(hashtext($1))`, d.outbox).Scan(&locked); err != nil || !locked {
+		return 0, err
+	}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, type, payload, created_at FROM `+d.outbox+`
+		WHERE published_at IS NULL ORDER BY id LIMIT $1`, d.Batch)
+	if err != nil {
+		return 0, err
+	}
+	var pending []Event
+	for rows.Next() {
+		e := Event{Source: d.source}
+		if err := rows.Scan(&e.ID, &e.Type, &e.Payload, &e.OccurredAt); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pending = append(pending, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var sent []int64
+	var publishErr error
+	for _, e := range pending {
+		data, err := json.Marshal(e)
+		if err != nil {
+			publishErr = err
+			break
+		}
+		if publishErr = d.broker.Publish(ctx, e.Type, data); publishErr != nil {
+			break
+		}
+		sent = append(sent, e.ID)
+	}
+	if len(sent) == 0 {
+		return 0, publishErr
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE `+d.outbox+` SET published_at = NOW() WHERE id = ANY($1)`, pq.Array(sent)); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(sent), publishErr
+}
+
+// Prune deletes events published more than retention ago.
+func (d *Dispatcher) Prune(ctx context.Context, retention time.Duration) (int64, error) {
+	res, err := d.db.ExecContext(ctx, `DELETE FROM `+d.outbox+` WHERE published_at < $1`, time.Now().Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,24 @@
+package events
+
+import (
+	"fmt"
+	"os"
+)
+
+// BrokerFromEnv builds the Broker selected by EVENT_BROKER: "local" (the
+// default) for the in-process broker, or "nats" to connect to NATS_URL,
+// which defaults to nats://localhost:4222.
+func BrokerFromEnv() (Broker, error) {
+	switch kind := os.Getenv("EVENT_BROKER"); kind {
+	case "", "local":
+		return NewLocal(), nil
+	case "nats":
+		addr := os.Getenv("NATS_URL")
+		if addr == "" {
+			addr = "nats://localhost:4222"
+		}
+		return DialNATS(addr)
+	default:
+		return nil, fmt.Errorf("events: unknown EVENT_BROKER %q", kind)
+	}
+}
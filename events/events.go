@@ -0,0 +1,107 @@
+// Package events carries domain events between services. A service records
+// the events a change causes in its outbox table, in the same transaction as
+// the change, and a Dispatcher later publishes them to a Broker. Consumers
+// therefore see every committed change and never one that was rolled back,
+// at the cost of the occasional duplicate when a dispatcher fails between
+// publishing and marking an event sent, or publishes an event again because
+// one of its subscribers failed.
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Event types, which double as the subjects events are published on.
+const (
+	TypeUserCreated         = "user.created"
+	TypePaymentRecorded     = "payment.recorded"
+	TypeCourseDeleted       = "course.deleted"
+	TypeEnrollmentCreated   = "enrollment.created"
+	TypeEnrollmentCompleted = "enrollment.completed"
+)
+
+// Outbox tables, one per service.
+const (
+	UserOutbox       = "user_outbox"
+	CourseOutbox     = "course_outbox"
+	EnrollmentOutbox = "enrollment_outbox"
+)
+
+// Payload is the body of a domain event.
+type Payload interface {
+	EventType() string
+}
+
+// UserCreated is recorded when a user signs up.
+type UserCreated struct {
+	UserID    int    `json:"user_id"`
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+// PaymentRecorded is recorded when a payment is added to a user's total.
+type PaymentRecorded struct {
+	UserID          int     `json:"user_id"`
+	Amount          float64 `json:"amount"`
+	TotalAmountPaid float64 `json:"total_amount_paid"`
+}
+
+// CourseDeleted is recorded when a course is soft-deleted.
+type CourseDeleted struct {
+	CourseID int `json:"course_id"`
+}
+
+// EnrollmentCreated is recorded when a user enrolls in a course, with the
+// status the enrollment was created with.
+type EnrollmentCreated struct {
+	EnrollmentID int    `json:"enrollment_id"`
+	UserID       int    `json:"user_id"`
+	CourseID     int    `json:"course_id"`
+	Status       string `json:"status"`
+}
+
+// EnrollmentCompleted is recorded when an enrollment becomes completed,
+// whether by completing it or by creating it completed.
+type EnrollmentCompleted struct {
+	EnrollmentID int `json:"enrollment_id"`
+	UserID       int `json:"user_id"`
+	CourseID     int `json:"course_id"`
+}
+
+func (UserCreated) EventType() string         { return TypeUserCreated }
+func (PaymentRecorded) EventType() string     { return TypePaymentRecorded }
+func (CourseDeleted) EventType() string       { return TypeCourseDeleted }
+func (EnrollmentCreated) EventType() string   { return TypeEnrollmentCreated }
+func (EnrollmentCompleted) EventType() string { return TypeEnrollmentCompleted }
+
+// Event is what is published: a payload plus where and when it happened.
+// ID is only unique per Source, so consumers that must not see an event
+// twice should remember the pair.
+type Event struct {
+	ID         int64           `json:"id"`
+	Source     string          `json:"source"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// Execer is satisfied by *database.Tx, which is what Record is meant to be
+// given.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Record adds p to the outbox table. Pass the transaction making the change
+// so the event commits or rolls back with it.
+func Record(ctx context.Context, tx Execer, outbox string, p Payload) error {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO `+outbox+` (type, payload) VALUES ($1, $2)`, p.EventType(), payload)
+	return err
+}
@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, subject string
+		want             bool
+	}{
+		{"user.created", "user.created", true},
+		{"user.created", "user.deleted", false},
+		{"user.*", "user.created", true},
+		{"*.created", "user.created", true},
+		{"user.*", "user.created.v2", false},
+		{"user.>", "user.created.v2", true},
+		{"user.>", "user", false},
+		{">", "enrollment.completed", true},
+		{"user.created.v2", "user.created", false},
+	} {
+		if got := Match(tc.pattern, tc.subject); got != tc.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tc.pattern, tc.subject, got, tc.want)
+		}
+	}
+}
+
+func TestLocal(t *testing.T) {
+	b := NewLocal()
+	var got []string
+	unsubscribe, err := b.Subscribe("enrollment.*", func(subject string, data []byte) error {
+		got = append(got, subject+" "+string(data))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Subscribe("user.>", func(subject string, data []byte) error {
+		got = append(got, "users saw "+subject)
+		return nil
+	})
+
+	ctx := context.Background()
+	b.Publish(ctx, TypeEnrollmentCompleted, []byte("1"))
+	b.Publish(ctx, TypeUserCreated, []byte("2"))
+	unsubscribe()
+	b.Publish(ctx, TypeEnrollmentCompleted, []byte("3"))
+	if want := []string{"enrollment.completed 1", "users saw user.created"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("delivered %q, want %q", got, want)
+	}
+
+	// A failing subscriber fails Publish, but the others still get the
+	// message.
+	refused := errors.New("refused")
+	b.Subscribe("user.*", func(subject string, data []byte) error { return refused })
+	got = nil
+	if err := b.Publish(ctx, TypeUserCreated, nil); !errors.Is(err, refused) {
+		t.Errorf("Publish to a failing subscriber = %v, want %v", err, refused)
+	}
+	if want := []string{"users saw user.created"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("delivered %q, want %q", got, want)
+	}
+
+	b.Close()
+	if err := b.Publish(ctx, TypeUserCreated, nil); err != ErrClosed {
+		t.Errorf("Publish after Close = %v, want ErrClosed", err)
+	}
+}
+
+func TestSubscribeAll(t *testing.T) {
+	b := NewLocal()
+	var got []int64
+	refused := errors.New("refused")
+	unsubscribe, err := SubscribeAll(b, []string{TypeUserCreated, TypePaymentRecorded}, func(ctx context.Context, e Event) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("handler context has no deadline")
+		}
+		got = append(got, e.ID)
+		if e.ID == 2 {
+			return refused
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	publish := func(id int64, eventType string) error {
+		data, _ := json.Marshal(Event{ID: id, Type: eventType})
+		return b.Publish(ctx, eventType, data)
+	}
+	if err := publish(1, TypeUserCreated); err != nil {
+		t.Errorf("Publish = %v", err)
+	}
+	if err := publish(2, TypePaymentRecorded); !errors.Is(err, refused) {
+		t.Errorf("Publish to a failing handler = %v, want %v", err, refused)
+	}
+	publish(3, TypeCourseDeleted)
+	if err := b.Publish(ctx, TypeUserCreated, []byte("not JSON")); err == nil {
+		t.Error("Publish of a malformed event succeeded")
+	}
+	unsubscribe()
+	publish(4, TypeUserCreated)
+	if want := []int64{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("handled %v, want %v", got, want)
+	}
+}
+
+type execRecorder struct {
+	query string
+	args  []interface{}
+}
+
+func (e *execRecorder) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	e.query, e.args = query, args
+	return nil, nil
+}
+
+func TestRecord(t *testing.T) {
+	var tx execRecorder
+	if err := Record(context.Background(), &tx, CourseOutbox, CourseDeleted{CourseID: 7}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(tx.query, "INSERT INTO course_outbox ") {
+		t.Errorf("query = %q", tx.query)
+	}
+	if tx.args[0] != TypeCourseDeleted || string(tx.args[1].([]byte)) != `{"course_id":7}` {
+		t.Errorf("args = %q", tx.args)
+	}
+}
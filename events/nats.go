@@ -0,0 +1,334 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// natsTimeout bounds dialling and waiting for the server to acknowledge a
+// command when the caller's context has no deadline of its own.
+const natsTimeout = 5 * time.Second
+
+// NATS is a Broker speaking the NATS client protocol over plain TCP, which
+// is enough to publish and subscribe through nats-server or anything
+// compatible with it. Publish waits for the server to answer a PING sent
+// after the message, so a nil error means the server has it. A dropped
+// connection is redialled on next use and its subscriptions renewed. TLS is
+// not supported.
+//
+// The server does not echo a connection's own messages back to it. Publish
+// instead hands them to this connection's matching subscribers itself and
+// returns what they failed with, as Local does, so consumers in the
+// publishing process see every message at least once. Messages from other
+// connections are delivered at most once: the server does not redeliver, so
+// a handler's error is only logged.
+//
+// Handlers run on the goroutine reading the connection, or on the one
+// publishing, so they must return promptly, must be safe to call
+// concurrently and must not publish themselves.
+type NATS struct {
+	addr    string
+	connect string
+
+	mu     sync.Mutex // guards c, next, closed and writes to subs
+	c      *natsConn
+	next   int
+	closed bool
+
+	smu  sync.RWMutex
+	subs map[int]subscription
+}
+
+// natsConn is one connection. Once its reader stops, err is set and the
+// connection is never used again.
+type natsConn struct {
+	conn net.Conn
+	wmu  sync.Mutex // serialises writes and keeps pongs in the order of PINGs
+
+	mu    sync.Mutex
+	pongs []chan error
+	err   error
+}
+
+// DialNATS connects to a nats://[user:password@]host[:port] URL. A user
+// without a password is sent as an auth token.
+func DialNATS(rawURL string) (*NATS, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "nats" || u.Host == "" {
+		return nil, fmt.Errorf("events: invalid NATS URL %q", rawURL)
+	}
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	opts := map[string]interface{}{"verbose": false, "pedantic": false, "echo": false, "name": "mopcare", "lang": "go"}
+	if u.User != nil {
+		if pass, ok := u.User.Password(); ok {
+			opts["user"], opts["pass"] = u.User.Username(), pass
+		} else {
+			opts["auth_token"] = u.User.Username()
+		}
+	}
+	connect, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	n := &NATS{addr: addr, connect: "CONNECT " + string(connect) + "\r\n", subs: map[int]subscription{}}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if _, err := n.conn(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (n *NATS) Publish(ctx context.Context, subject string, data []byte) error {
+	if !validSubject(subject) {
+		return fmt.Errorf("events: invalid subject %q", subject)
+	}
+	n.mu.Lock()
+	c, err := n.conn()
+	n.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := c.flush(ctx, "PUB "+subject+" "+strconv.Itoa(len(data))+"\r\n"+string(data)+"\r\n"); err != nil {
+		return err
+	}
+	var handlers []Handler
+	n.smu.RLock()
+	for _, s := range n.subs {
+		if Match(s.pattern, subject) {
+			handlers = append(handlers, s.handle)
+		}
+	}
+	n.smu.RUnlock()
+	return deliver(handlers, subject, data)
+}
+
+func (n *NATS) Subscribe(pattern string, handle Handler) (func(), error) {
+	if !validSubject(pattern) {
+		return nil, fmt.Errorf("events: invalid subject %q", pattern)
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	c, err := n.conn()
+	if err != nil {
+		return nil, err
+	}
+	id := n.next
+	n.next++
+	// Registered first, as messages may arrive before the server's PONG.
+	n.smu.Lock()
+	n.subs[id] = subscription{pattern, handle}
+	n.smu.Unlock()
+	if err := c.flush(context.Background(), fmt.Sprintf("SUB %s %d\r\n", pattern, id)); err != nil {
+		n.smu.Lock()
+		delete(n.subs, id)
+		n.smu.Unlock()
+		return nil, err
+	}
+	return func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		n.smu.Lock()
+		delete(n.subs, id)
+		n.smu.Unlock()
+		if n.c != nil && n.c.failed() == nil {
+			n.c.write(fmt.Sprintf("UNSUB %d\r\n", id))
+		}
+	}, nil
+}
+
+func (n *NATS) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.closed = true
+	if n.c == nil {
+		return nil
+	}
+	return n.c.conn.Close()
+}
+
+// conn returns the live connection, dialling a new one if the last has
+// failed. n.mu must be held.
+func (n *NATS) conn() (*natsConn, error) {
+	if n.closed {
+		return nil, ErrClosed
+	}
+	if n.c != nil && n.c.failed() == nil {
+		return n.c, nil
+	}
+	conn, err := net.DialTimeout("tcp", n.addr, natsTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("events: dial NATS: %w", err)
+	}
+	r := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(natsTimeout))
+	if line, err := r.ReadString('\n'); err != nil || !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return nil, fmt.Errorf("events: %s did not greet as a NATS server", n.addr)
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	c := &natsConn{conn: conn}
+	go c.read(r, n.handler)
+	hello := n.connect
+	n.smu.RLock()
+	for id, s := range n.subs {
+		hello += fmt.Sprintf("SUB %s %d\r\n", s.pattern, id)
+	}
+	n.smu.RUnlock()
+	if err := c.flush(context.Background(), hello); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	n.c = c
+	return c, nil
+}
+
+func (n *NATS) handler(sid int) Handler {
+	n.smu.RLock()
+	defer n.smu.RUnlock()
+	return n.subs[sid].handle
+}
+
+func (c *natsConn) write(s string) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return c.writeLocked(s)
+}
+
+func (c *natsConn) writeLocked(s string) error {
+	c.conn.SetWriteDeadline(time.Now().Add(natsTimeout))
+	_, err := io.WriteString(c.conn, s)
+	return err
+}
+
+// flush sends cmd followed by a PING and waits for the matching PONG. The
+// server handles commands in order, so the PONG means cmd was processed.
+func (c *natsConn) flush(ctx context.Context, cmd string) error {
+	pong := make(chan error, 1)
+	c.wmu.Lock()
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		c.wmu.Unlock()
+		return c.err
+	}
+	c.pongs = append(c.pongs, pong)
+	c.mu.Unlock()
+	err := c.writeLocked(cmd + "PING\r\n")
+	c.wmu.Unlock()
+	if err != nil {
+		c.fail(err)
+		return err
+	}
+
+	timeout := time.NewTimer(natsTimeout)
+	defer timeout.Stop()
+	select {
+	case err := <-pong:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timeout.C:
+		err := errors.New("events: NATS server did not answer")
+		c.fail(err)
+		return err
+	}
+}
+
+func (c *natsConn) failed() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// fail marks the connection dead and wakes everything waiting on it.
+func (c *natsConn) fail(err error) {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = fmt.Errorf("events: NATS connection lost: %w", err)
+	}
+	for _, pong := range c.pongs {
+		pong <- c.err
+	}
+	c.pongs = nil
+	c.mu.Unlock()
+	c.conn.Close()
+}
+
+// pong answers the oldest waiting flush, with err if the server sent one.
+func (c *natsConn) pong(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.pongs) > 0 {
+		c.pongs[0] <- err
+		c.pongs = c.pongs[1:]
+	}
+}
+
+func (c *natsConn) read(r *bufio.Reader, handler func(sid int) Handler) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			c.fail(err)
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		op, args, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(op) {
+		case "MSG":
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			f := strings.Fields(args)
+			if len(f) < 3 {
+				c.fail(fmt.Errorf("malformed %q", line))
+				return
+			}
+			sid, err1 := strconv.Atoi(f[1])
+			size, err2 := strconv.Atoi(f[len(f)-1])
+			if err1 != nil || err2 != nil || size < 0 {
+				c.fail(fmt.Errorf("malformed %q", line))
+				return
+			}
+			data := make([]byte, size+2)
+			if _, err := io.ReadFull(r, data); err != nil {
+				c.fail(err)
+				return
+			}
+			if handle := handler(sid); handle != nil {
+				if err := handle(f[0], data[:size]); err != nil {
+					log.Printf("events: handling %s: %v", f[0], err)
+				}
+			}
+		case "PING":
+			if err := c.write("PONG\r\n"); err != nil {
+				c.fail(err)
+				return
+			}
+		case "PONG":
+			c.pong(nil)
+		case "-ERR":
+			// The server closes the connection after most errors, which
+			// fails any other waiters.
+			c.pong(fmt.Errorf("events: NATS server: %s", strings.Trim(args, "'")))
+		}
+	}
+}
+
+// validSubject rejects what would break the line-based protocol.
+func validSubject(s string) bool {
+	return s != "" && !strings.ContainsAny(s, " \t\r\n")
+}
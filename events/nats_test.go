@@ -0,0 +1,230 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// natsServer speaks just enough of the server side of the protocol to route
+// PUB to SUB across connections, echoing to the publisher only if it asked.
+type natsServer struct {
+	ln       net.Listener
+	mu       sync.Mutex
+	conn     net.Conn
+	clients  map[net.Conn]*natsClient
+	connects []string
+}
+
+// natsClient is what the server knows of one connection.
+type natsClient struct {
+	subs   map[string]string // sid to pattern
+	noEcho bool
+}
+
+func newNATSServer(t *testing.T) *natsServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &natsServer{ln: ln, clients: map[net.Conn]*natsClient{}}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conn = conn
+			s.clients[conn] = &natsClient{subs: map[string]string{}}
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *natsServer) url(userinfo string) string { return "nats://" + userinfo + s.ln.Addr().String() }
+
+// drop closes the newest connection as a server restart would.
+func (s *natsServer) drop() {
+	s.mu.Lock()
+	s.conn.Close()
+	s.mu.Unlock()
+}
+
+func (s *natsServer) serve(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+	fmt.Fprint(conn, "INFO {\"server_id\":\"test\"}\r\n")
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		f := strings.Fields(line)
+		s.mu.Lock()
+		client := s.clients[conn]
+		switch f[0] {
+		case "CONNECT":
+			options := strings.TrimSpace(strings.TrimPrefix(line, "CONNECT"))
+			s.connects = append(s.connects, options)
+			client.noEcho = strings.Contains(options, `"echo":false`)
+		case "PING":
+			fmt.Fprint(conn, "PONG\r\n")
+		case "SUB":
+			client.subs[f[2]] = f[1]
+		case "UNSUB":
+			delete(client.subs, f[1])
+		case "PUB":
+			size, _ := strconv.Atoi(f[2])
+			data := make([]byte, size+2)
+			io.ReadFull(r, data)
+			for to, c := range s.clients {
+				if to == conn && c.noEcho {
+					continue
+				}
+				for sid, pattern := range c.subs {
+					if Match(pattern, f[1]) {
+						fmt.Fprintf(to, "MSG %s %s %d\r\n%s", f[1], sid, size, data)
+					}
+				}
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+func TestNATS(t *testing.T) {
+	srv := newNATSServer(t)
+	b, err := DialNATS(srv.url("s3cret@"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	if c := srv.connects[0]; !strings.Contains(c, `"auth_token":"s3cret"`) || !strings.Contains(c, `"echo":false`) {
+		t.Errorf("CONNECT %s", c)
+	}
+
+	got := make(chan string, 10)
+	unsubscribe, err := b.Subscribe("enrollment.*", func(subject string, data []byte) error {
+		got <- subject + " " + string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	receive := func(got chan string, want string) {
+		t.Helper()
+		select {
+		case msg := <-got:
+			if msg != want {
+				t.Errorf("received %q, want %q", msg, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("nothing received, want %q", want)
+		}
+	}
+	nothing := func(got chan string) {
+		t.Helper()
+		select {
+		case msg := <-got:
+			t.Errorf("received %q, want nothing", msg)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	// The publisher's own subscribers get the message from Publish, and
+	// only from it.
+	ctx := context.Background()
+	if err := b.Publish(ctx, TypeEnrollmentCompleted, []byte("{\"id\":1}\r\nnot a command")); err != nil {
+		t.Fatal(err)
+	}
+	receive(got, "enrollment.completed {\"id\":1}\r\nnot a command")
+	b.Publish(ctx, TypeUserCreated, []byte("ignored"))
+	nothing(got)
+
+	// After the connection drops, publishing redials and the subscription
+	// carries over.
+	srv.drop()
+	deadline := time.Now().Add(time.Second)
+	for b.Publish(ctx, TypeEnrollmentCompleted, []byte("2")) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("Publish did not recover from a dropped connection")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	receive(got, "enrollment.completed 2")
+
+	// A subscriber of the publisher that fails fails Publish.
+	refused := errors.New("refused")
+	b.Subscribe("user.*", func(subject string, data []byte) error { return refused })
+	if err := b.Publish(ctx, TypeUserCreated, nil); !errors.Is(err, refused) {
+		t.Errorf("Publish to a failing subscriber = %v, want %v", err, refused)
+	}
+
+	// Other connections get the message through the server, and their
+	// failures stay theirs.
+	other, err := DialNATS(srv.url(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	remote := make(chan string, 10)
+	other.Subscribe("enrollment.*", func(subject string, data []byte) error {
+		remote <- subject + " " + string(data)
+		return refused
+	})
+	if err := b.Publish(ctx, TypeEnrollmentCompleted, []byte("3")); err != nil {
+		t.Errorf("Publish to a failing subscriber elsewhere = %v, want nil", err)
+	}
+	receive(got, "enrollment.completed 3")
+	receive(remote, "enrollment.completed 3")
+	if err := other.Publish(ctx, TypeEnrollmentCreated, []byte("4")); !errors.Is(err, refused) {
+		t.Errorf("Publish from the other connection = %v, want %v", err, refused)
+	}
+	receive(remote, "enrollment.created 4")
+	receive(got, "enrollment.created 4")
+
+	unsubscribe()
+	b.Publish(ctx, TypeEnrollmentCompleted, []byte("5"))
+	receive(remote, "enrollment.completed 5")
+	nothing(got)
+}
+
+func TestDialNATSRejects(t *testing.T) {
+	for _, url := range []string{"http://localhost:4222", "nats://", "localhost:4222"} {
+		if _, err := DialNATS(url); err == nil {
+			t.Errorf("DialNATS(%q) succeeded", url)
+		}
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			fmt.Fprint(conn, "HTTP/1.1 400 Bad Request\r\n")
+			conn.Close()
+		}
+	}()
+	if _, err := DialNATS("nats://" + ln.Addr().String()); err == nil || !strings.Contains(err.Error(), "did not greet") {
+		t.Errorf("DialNATS(non-NATS server) = %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+// Package gateway is the Mopcare API gateway: it routes requests to the
+// course, user and enrollment services and exposes health and metrics.
+// Signed media downloads are proxied like any other request, so their links
+// point at the gateway.
+package gateway
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/proxy"
+
+	"mopcare/openapi"
+	"mopcare/problem"
+	"mopcare/server"
+)
+
+type CacheEntry struct {
+	Data      string
+	ExpiresAt time.Time
+}
+
+type Cache struct {
+	store sync.Map
+	ttl   time.Duration
+}
+
+func NewCache() *Cache {
+	return &Cache{ttl: 5 * time.Minute}
+}
+
+func (c *Cache) Get(key string) (string, bool) {
+	if val, ok := c.store.Load(key); ok {
+		entry := val.(CacheEntry)
+		if time.Now().Before(entry.ExpiresAt) {
+			return entry.Data, true
+		}
+		c.store.Delete(key)
+	}
+	return "", false
+}
+
+func (c *Cache) Set(key, data string) {
+	c.store.Store(key, CacheEntry{
+		Data:      data,
+		ExpiresAt: time.Now().Add(c.ttl),
+	})
+}
+
+type Metrics struct {
+	TotalRequests int64
+	CacheHits     int64
+	CacheMisses   int64
+	mu            sync.RWMutex
+}
+
+func (m *Metrics) IncrementRequests() {
+	m.mu.Lock()
+	m.TotalRequests++
+	m.mu.Unlock()
+}
+
+func (m *Metrics) IncrementCacheHits() {
+	m.mu.Lock()
+	m.CacheHits++
+	m.mu.Unlock()
+}
+
+func (m *Metrics) IncrementCacheMisses() {
+	m.mu.Lock()
+	m.CacheMisses++
+	m.mu.Unlock()
+}
+
+// Config holds the upstream service URLs.
+type Config struct {
+	CourseServiceURL     string
+	UserServiceURL       string
+	EnrollmentServiceURL string
+	// Mock serves canned responses instead of proxying, for the Render demo.
+	Mock bool
+}
+
+// ConfigFromEnv reads the *_SERVICE_URL variables, defaulting to the local
+// ports, and switches to mock mode when running on Render.
+func ConfigFromEnv() Config {
+	// For single-service deployment, all services run in the same container
+	cfg := Config{
+		CourseServiceURL:     os.Getenv("COURSE_SERVICE_URL"),
+		UserServiceURL:       os.Getenv("USER_SERVICE_URL"),
+		EnrollmentServiceURL: os.Getenv("ENROLLMENT_SERVICE_URL"),
+		// Auto-detect Render deployment and return mock responses
+		Mock: os.Getenv("RENDER_SERVICE_ID") != "" || os.Getenv("RENDER") != "",
+	}
+	if cfg.CourseServiceURL == "" {
+		cfg.CourseServiceURL = "http://localhost:8081"
+	}
+	if cfg.UserServiceURL == "" {
+		cfg.UserServiceURL = "http://localhost:8082"
+	}
+	if cfg.EnrollmentServiceURL == "" {
+		cfg.EnrollmentServiceURL = "http://localhost:8083"
+	}
+	return cfg
+}
+
+type Gateway struct {
+	cfg     Config
+	cache   *Cache
+	metrics *Metrics
+	spec    *openapi.Spec
+}
+
+// New builds the gateway Fiber app, including /health, /ready, /metrics and
+// /openapi.json. Request bodies are validated against the OpenAPI document
+// before they are proxied.
+func New(cfg Config, ready *server.Readiness, serverCfg server.Config) *fiber.App {
+	g := &Gateway{cfg: cfg, cache: NewCache(), metrics: &Metrics{}, spec: openapi.MustLoad()}
+
+	app := fiber.New(fiber.Config{
+		Prefork:       false, // Disabled for Docker compatibility
+		CaseSensitive: true,
+		StrictRouting: true,
+		ServerHeader:  "Mopcare-Gateway",
+		ErrorHandler:  errorHandler,
+		ReadTimeout:   serverCfg.ReadTimeout,
+		WriteTimeout:  serverCfg.WriteTimeout,
+		IdleTimeout:   serverCfg.IdleTimeout,
+		BodyLimit:     serverCfg.BodyLimit,
+	})
+
+	app.Get("/health", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{
+			"status":  "healthy",
+			"service": "mopcare-api-gateway",
+			"version": "1.0.0",
+		})
+	})
+
+	app.Get("/ready", func(c *fiber.Ctx) error {
+		if !ready.Ready() {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"service": "mopcare-api-gateway", "status": "draining"})
+		}
+		return c.JSON(fiber.Map{"service": "mopcare-api-gateway", "status": "ready"})
+	})
+
+	app.Get("/metrics", func(c *fiber.Ctx) error {
+		g.metrics.mu.RLock()
+		defer g.metrics.mu.RUnlock()
+		return c.JSON(fiber.Map{
+			"gateway": fiber.Map{
+				"total_requests": g.metrics.TotalRequests,
+				"cache_hits":     g.metrics.CacheHits,
+				"cache_misses":   g.metrics.CacheMisses,
+			},
+		})
+	})
+
+	app.Get("/openapi.json", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(openapi.Document())
+	})
+
+	app.Use(g.validateRequest)
+	app.Use(g.proxyHandler)
+	return app
+}
+
+// validateRequest rejects bodies that do not match the documented request
+// schema, so malformed input never reaches a service.
+func (g *Gateway) validateRequest(c *fiber.Ctx) error {
+	if err := g.spec.ValidateRequest(c.Method(), c.Path(), c.Body()); err != nil {
+		return writeError(c, err)
+	}
+	return c.Next()
+}
+
+// seatsPath matches a course's roster and waitlist, which the enrollment
+// service serves although they live under /courses.
+var seatsPath = regexp.MustCompile(`^/courses/\d+/(roster|waitlist)$`)
+
+// route picks the upstream for a path, or "" if no service owns it.
+func (g *Gateway) route(path string) string {
+	switch {
+	case strings.HasPrefix(path, "/courses") && !seatsPath.MatchString(path),
+		strings.HasPrefix(path, "/series") || strings.HasPrefix(path, "/media") ||
+			strings.HasPrefix(path, "/categories") || strings.HasPrefix(path, "/tags") || strings.HasPrefix(path, "/instructors") ||
+			strings.HasPrefix(path, "/quizzes"):
+		return g.cfg.CourseServiceURL
+	case strings.HasPrefix(path, "/users") && !strings.Contains(path, "/enrollments") && !strings.Contains(path, "/certificates") &&
+		!strings.HasSuffix(path, "/eligibility"),
+		strings.HasPrefix(path, "/notifications") || strings.HasPrefix(path, "/payments"):
+		return g.cfg.UserServiceURL
+	case strings.Contains(path, "/enrollments") || strings.Contains(path, "/certificates") || strings.HasPrefix(path, "/webhooks") ||
+		strings.HasPrefix(path, "/cohorts") || strings.HasSuffix(path, "/eligibility") || seatsPath.MatchString(path):
+		return g.cfg.EnrollmentServiceURL
+	}
+	return ""
+}
+
+func (g *Gateway) proxyHandler(c *fiber.Ctx) error {
+	g.metrics.IncrementRequests()
+	path := c.Path()
+	method := c.Method()
+
+	if g.cfg.Mock {
+		return handleMockResponse(c, path, method)
+	}
+
+	targetURL := g.route(path)
+	if targetURL == "" {
+		return writeError(c, problem.NotFound(problem.CodeRouteNotFound, "Service not found"))
+	}
+
+	// OriginalURL keeps the query string (?limit=&offset=), which Path drops.
+	uri := c.OriginalURL()
+	cacheKey := fmt.Sprintf("%s:%s", method, uri)
+	if method == "GET" {
+		if cachedData, found := g.cache.Get(cacheKey); found {
+			g.metrics.IncrementCacheHits()
+			c.Set("X-Cache", "HIT")
+			return c.SendString(cachedData)
+		}
+		g.metrics.IncrementCacheMisses()
+		c.Set("X-Cache", "MISS")
+	}
+
+	if err := proxy.Do(c, targetURL+uri); err != nil {
+		log.Printf("proxy %s %s: %v", method, path, err)
+		return writeError(c, problem.New(fiber.StatusBadGateway, problem.CodeUpstreamUnavailable, "Upstream service is unavailable"))
+	}
+	return nil
+}
+
+func handleMockResponse(c *fiber.Ctx, path, method string) error {
+	if method == "GET" && path == "/courses" {
+		return c.JSON([]fiber.Map{
+			{"id": 1, "title": "Managing Diabetes in Your Golden Years", "content": "Comprehensive guide to diabetes management for seniors", "unique_id": "diabetes-seniors-101"},
+			{"id": 2, "title": "Heart Health After 65", "content": "Essential cardiovascular care for seniors", "unique_id": "heart-health-seniors"},
+		})
+	}
+	if method == "POST" && path == "/courses" {
+		return c.Status(201).JSON(fiber.Map{"id": 3, "title": "New Course", "message": "Course created successfully"})
+	}
+	if method == "GET" && path == "/users" {
+		return c.JSON([]fiber.Map{{"id": 1, "first_name": "Margaret", "last_name": "Johnson", "email": "margaret.johnson@email.com"}})
+	}
+	if method == "POST" && path == "/users" {
+		return c.Status(201).JSON(fiber.Map{"id": 2, "first_name": "New User", "message": "User created successfully"})
+	}
+	return c.Status(200).JSON(fiber.Map{"message": "Mock response for " + method + " " + path})
+}
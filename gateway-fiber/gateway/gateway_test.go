@@ -0,0 +1,154 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"mopcare/server"
+)
+
+func TestRoute(t *testing.T) {
+	g := &Gateway{cfg: Config{CourseServiceURL: "course", UserServiceURL: "user", EnrollmentServiceURL: "enrollment"}}
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"/courses", "course"},
+		{"/courses/1/series", "course"},
+		{"/series/4", "course"},
+		{"/media/3/content", "course"},
+		{"/categories/2", "course"},
+		{"/tags/heart%20health", "course"},
+		{"/instructors/7/courses", "course"},
+		{"/quizzes/5/attempts", "course"},
+		{"/courses/1/instructors/7", "course"},
+		{"/courses/2/prerequisites/1", "course"},
+		{"/courses/by-slug/roster", "course"},
+		{"/courses/by-slug/waitlist", "course"},
+		{"/users/7/token", "user"},
+		{"/users", "user"},
+		{"/users/1/profile", "user"},
+		{"/users/1/notification-preferences", "user"},
+		{"/notifications/unsubscribe", "user"},
+		{"/users/import", "user"},
+		{"/payments/export", "user"},
+		{"/users/1/enrollments", "enrollment"},
+		{"/enrollments/3", "enrollment"},
+		{"/enrollments/export", "enrollment"},
+		{"/enrollments/batch", "enrollment"},
+		{"/cohorts", "enrollment"},
+		{"/cohorts/2", "enrollment"},
+		{"/users/1/certificates", "enrollment"},
+		{"/certificates/ABCD-EFGH-JK12/pdf", "enrollment"},
+		{"/users/1/courses/2/eligibility", "enrollment"},
+		{"/courses/1/roster", "enrollment"},
+		{"/courses/1/waitlist", "enrollment"},
+		{"/webhooks/1/deliveries/2/redeliver", "enrollment"},
+		{"/unknown", ""},
+	}
+	for _, tc := range cases {
+		if got := g.route(tc.path); got != tc.want {
+			t.Errorf("route(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+}
+
+func TestUnknownServiceIsProblem(t *testing.T) {
+	app := New(Config{}, &server.Readiness{}, server.Config{})
+	resp, err := app.Test(httptest.NewRequest("GET", "/unknown", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 404 || !strings.Contains(string(body), `"code":"ROUTE_NOT_FOUND"`) {
+		t.Fatalf("got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestUpstreamDownIsBadGateway(t *testing.T) {
+	app := New(Config{CourseServiceURL: "http://127.0.0.1:1"}, &server.Readiness{}, server.Config{})
+	resp, err := app.Test(httptest.NewRequest("GET", "/courses", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 502 || !strings.Contains(string(body), `"code":"UPSTREAM_UNAVAILABLE"`) {
+		t.Fatalf("got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestMockMode(t *testing.T) {
+	app := New(Config{Mock: true}, &server.Readiness{}, server.Config{})
+	resp, err := app.Test(httptest.NewRequest("GET", "/courses", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || !strings.Contains(string(body), "Heart Health After 65") {
+		t.Fatalf("got %d %s", resp.StatusCode, body)
+	}
+}
+
+func TestServesOpenAPIDocument(t *testing.T) {
+	app := New(Config{}, &server.Readiness{}, server.Config{})
+	resp, err := app.Test(httptest.NewRequest("GET", "/openapi.json", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 || !strings.Contains(string(body), `"openapi": "3.1.0"`) {
+		t.Fatalf("got %d %.80s", resp.StatusCode, body)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("content type %q", ct)
+	}
+}
+
+func TestInvalidBodyIsRejectedBeforeProxying(t *testing.T) {
+	// The upstream is unreachable, so anything other than a 400 means the
+	// request was proxied.
+	app := New(Config{CourseServiceURL: "http://127.0.0.1:1"}, &server.Readiness{}, server.Config{})
+	req := httptest.NewRequest("POST", "/courses", strings.NewReader(`{"title":42}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 400 ||
+		!strings.Contains(string(body), `"code":"VALIDATION_FAILED"`) ||
+		!strings.Contains(string(body), `"title: must be of type string"`) ||
+		!strings.Contains(string(body), `"content: is required"`) {
+		t.Fatalf("got %d %s", resp.StatusCode, body)
+	}
+
+	req = httptest.NewRequest("POST", "/courses", strings.NewReader(`{"title":"Go","content":"Basics"}`))
+	req.Header.Set("Content-Type", "application/json")
+	if resp, err = app.Test(req, -1); err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 502 {
+		t.Fatalf("valid body got %d, want it proxied", resp.StatusCode)
+	}
+}
+
+func TestProxyForwardsQueryString(t *testing.T) {
+	var got string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.RequestURI()
+		w.Write([]byte("[]"))
+	}))
+	defer upstream.Close()
+
+	app := New(Config{CourseServiceURL: upstream.URL}, &server.Readiness{}, server.Config{})
+	resp, err := app.Test(httptest.NewRequest("GET", "/courses?limit=2&offset=4", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 200 || got != "/courses?limit=2&offset=4" {
+		t.Fatalf("got %d, upstream saw %q", resp.StatusCode, got)
+	}
+}
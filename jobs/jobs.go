@@ -0,0 +1,27 @@
+// Package jobs runs periodic background work next to a process's HTTP
+// server, such as purging soft-deleted rows.
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Every calls fn once straight away and then every interval until ctx is
+// cancelled. A failing run is logged and retried on the next tick rather
+// than stopping the loop. Every blocks, so start it in a goroutine.
+func Every(ctx context.Context, name string, interval time.Duration, fn func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := fn(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("job %s: %v", name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
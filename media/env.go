@@ -0,0 +1,52 @@
+package media
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"os"
+)
+
+// StoreFromEnv picks the Store named by MEDIA_STORE. "local", the default,
+// keeps blobs under MEDIA_DIR (default "./media-data"); "s3" uses the bucket
+// described by S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY and
+// S3_SECRET_KEY.
+func StoreFromEnv() (Store, error) {
+	switch kind := os.Getenv("MEDIA_STORE"); kind {
+	case "", "local":
+		dir := os.Getenv("MEDIA_DIR")
+		if dir == "" {
+			dir = "./media-data"
+		}
+		return NewLocal(dir), nil
+	case "s3":
+		cfg := S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		}
+		if cfg.Endpoint == "" || cfg.Bucket == "" {
+			return nil, fmt.Errorf("media: MEDIA_STORE=s3 needs S3_ENDPOINT and S3_BUCKET")
+		}
+		return NewS3(cfg), nil
+	default:
+		return nil, fmt.Errorf("media: unknown MEDIA_STORE %q", kind)
+	}
+}
+
+// SigningKeyFromEnv returns MEDIA_SIGNING_KEY. Without one it makes up a
+// random key, which is fine for a single process but means signed URLs stop
+// working on restart and are not accepted by other replicas.
+func SigningKeyFromEnv() []byte {
+	if key := os.Getenv("MEDIA_SIGNING_KEY"); key != "" {
+		return []byte(key)
+	}
+	log.Println("MEDIA_SIGNING_KEY is not set, signing media URLs with a random key")
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
@@ -0,0 +1,92 @@
+// Package media stores uploaded course media. Blobs go to a Store, keyed by
+// their SHA-256 so identical uploads share one copy; the local filesystem is
+// the default and any S3-compatible service (AWS, MinIO) can stand in for
+// it. Downloads are handed out as HMAC-signed, time-limited URLs and images
+// get a downscaled thumbnail next to the original.
+package media
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// ErrNotFound is returned by a Store for a key it does not hold.
+var ErrNotFound = errors.New("media: not found")
+
+// Store keeps blobs by key. Keys are slash-separated paths of lowercase
+// letters, digits, dots and hyphens.
+type Store interface {
+	// Put stores data under key, replacing what was there.
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get opens the blob under key or fails with ErrNotFound.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes key; deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// Kinds of media, by what a course uses them for.
+const (
+	KindImage = "image"
+	KindVideo = "video"
+)
+
+// types lists the accepted content types and their kind.
+var types = map[string]string{
+	"image/jpeg": KindImage,
+	"image/png":  KindImage,
+	"image/gif":  KindImage,
+	"video/mp4":  KindVideo,
+	"video/webm": KindVideo,
+}
+
+// Kind returns the kind of an accepted content type, or "" for one that is
+// not accepted. Parameters such as "; charset=" are ignored.
+func Kind(contentType string) string {
+	return types[BareType(contentType)]
+}
+
+// Sniff reports whether data looks like contentType, so a client cannot
+// upload one thing labelled as another.
+func Sniff(data []byte, contentType string) bool {
+	return BareType(http.DetectContentType(data)) == BareType(contentType)
+}
+
+// BareType is contentType lowercased and without parameters.
+func BareType(contentType string) string {
+	t, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(t))
+}
+
+// Checksum is the hex SHA-256 of data, which also names its blob.
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// BlobKey and ThumbnailKey are where an upload with the given checksum and
+// its thumbnail are stored.
+func BlobKey(checksum string) string      { return "blobs/" + checksum }
+func ThumbnailKey(checksum string) string { return "thumbnails/" + checksum }
+
+// validKey guards stores against keys that would escape their root.
+func validKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+		for _, r := range part {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '.' || r == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+)
+
+// ThumbnailSize bounds the longer side of a thumbnail, in pixels.
+const ThumbnailSize = 320
+
+// Thumb is a decoded image upload: the original's dimensions and an encoded
+// copy scaled down to fit ThumbnailSize. JPEGs stay JPEGs; PNGs and GIFs
+// become PNGs so transparency survives.
+type Thumb struct {
+	Width       int
+	Height      int
+	Data        []byte
+	ContentType string
+}
+
+// ThumbnailType is the content type of the thumbnail of an image upload.
+func ThumbnailType(contentType string) string {
+	if BareType(contentType) == "image/jpeg" {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+// Thumbnail decodes an image upload and renders its thumbnail. Images that
+// already fit are re-encoded at their own size.
+func Thumbnail(data []byte) (Thumb, error) {
+	var (
+		src image.Image
+		err error
+	)
+	detected := http.DetectContentType(data)
+	switch BareType(detected) {
+	case "image/jpeg":
+		src, err = jpeg.Decode(bytes.NewReader(data))
+	case "image/gif":
+		src, err = gif.Decode(bytes.NewReader(data))
+	default:
+		src, err = png.Decode(bytes.NewReader(data))
+	}
+	format := ThumbnailType(detected)
+	if err != nil {
+		return Thumb{}, err
+	}
+
+	b := src.Bounds()
+	t := Thumb{Width: b.Dx(), Height: b.Dy(), ContentType: format}
+	w, h := fit(t.Width, t.Height, ThumbnailSize)
+	dst := scale(src, w, h)
+
+	var buf bytes.Buffer
+	if format == "image/jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&buf, dst)
+	}
+	t.Data = buf.Bytes()
+	return t, err
+}
+
+// fit scales w x h down, keeping the aspect ratio, until neither side
+// exceeds max.
+func fit(w, h, max int) (int, int) {
+	if w <= max && h <= max {
+		return w, h
+	}
+	if w >= h {
+		return max, clampOne(h * max / w)
+	}
+	return clampOne(w * max / h), max
+}
+
+func clampOne(n int) int {
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// scale resamples src to w x h by averaging the source pixels that fall in
+// each destination pixel, which avoids the aliasing of nearest-neighbour.
+// Colours are averaged premultiplied so transparent pixels do not bleed.
+func scale(src image.Image, w, h int) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := max(b.Min.Y+(y+1)*b.Dy()/h, y0+1)
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := max(b.Min.X+(x+1)*b.Dx()/w, x0+1)
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := color.RGBA64Model.Convert(src.At(sx, sy)).(color.RGBA64)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					bl += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8), G: uint8(g / n >> 8), B: uint8(bl / n >> 8), A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return dst
+}
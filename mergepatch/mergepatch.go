@@ -0,0 +1,65 @@
+// Package mergepatch applies RFC 7396 JSON Merge Patch documents: objects
+// are merged key by key, a null value removes the key, and anything else
+// replaces the target value outright.
+package mergepatch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+)
+
+// ErrNotObject is returned when a patch is valid JSON but not an object.
+// RFC 7396 allows such patches (they replace the whole document), but every
+// Mopcare resource is an object, so callers treat them as malformed.
+var ErrNotObject = errors.New("merge patch must be a JSON object")
+
+// Apply merges patch into doc and returns the result. Numbers are carried
+// through verbatim so large integers do not lose precision.
+func Apply(doc, patch []byte) ([]byte, error) {
+	var p interface{}
+	if err := decode(patch, &p); err != nil {
+		return nil, err
+	}
+	if _, ok := p.(map[string]interface{}); !ok {
+		return nil, ErrNotObject
+	}
+	var target interface{}
+	if len(bytes.TrimSpace(doc)) > 0 {
+		if err := decode(doc, &target); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(merge(target, p))
+}
+
+func merge(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for key, value := range p {
+		if value == nil {
+			delete(t, key)
+			continue
+		}
+		t[key] = merge(t[key], value)
+	}
+	return t
+}
+
+func decode(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("unexpected data after JSON value")
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package mergepatch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// Cases from RFC 7396 appendix A, restricted to object patches.
+func TestApply(t *testing.T) {
+	cases := []struct{ doc, patch, want string }{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"a":1,"e":null}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+		{`{"n":12345678901234567890}`, `{}`, `{"n":12345678901234567890}`},
+	}
+	for _, tc := range cases {
+		got, err := Apply([]byte(tc.doc), []byte(tc.patch))
+		if err != nil {
+			t.Errorf("Apply(%s, %s): %v", tc.doc, tc.patch, err)
+			continue
+		}
+		var g, w interface{}
+		json.Unmarshal(got, &g)
+		json.Unmarshal([]byte(tc.want), &w)
+		if !reflect.DeepEqual(g, w) {
+			t.Errorf("Apply(%s, %s) = %s, want %s", tc.doc, tc.patch, got, tc.want)
+		}
+	}
+}
+
+func TestApplyRejectsBadPatches(t *testing.T) {
+	for _, patch := range []string{`["a"]`, `"x"`, `null`} {
+		if _, err := Apply([]byte(`{}`), []byte(patch)); err != ErrNotObject {
+			t.Errorf("Apply(%s) err = %v, want ErrNotObject", patch, err)
+		}
+	}
+	for _, patch := range []string{``, `{`, `{} {}`} {
+		if _, err := Apply([]byte(`{}`), []byte(patch)); err == nil {
+			t.Errorf("Apply(%q) succeeded", patch)
+		}
+	}
+}
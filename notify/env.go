@@ -0,0 +1,35 @@
+package notify
+
+import (
+	"os"
+	"time"
+)
+
+// SMTPFromEnv builds the SMTP sender described by SMTP_ADDR, which defaults
+// to localhost:1025 where MailHog listens, SMTP_USERNAME, SMTP_PASSWORD and
+// SMTP_FROM.
+func SMTPFromEnv() *SMTP {
+	s := &SMTP{
+		Addr:     os.Getenv("SMTP_ADDR"),
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+		Timeout:  30 * time.Second,
+	}
+	if s.Addr == "" {
+		s.Addr = "localhost:1025"
+	}
+	if s.From == "" {
+		s.From = "Mopcare <no-reply@mopcare.local>"
+	}
+	return s
+}
+
+// BaseURLFromEnv returns PUBLIC_URL, where users reach the gateway, for the
+// links in emails. It defaults to the gateway on localhost.
+func BaseURLFromEnv() string {
+	if u := os.Getenv("PUBLIC_URL"); u != "" {
+		return u
+	}
+	return "http://localhost:9090"
+}
@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"context"
+	"time"
+)
+
+// Mailer sends due notifications. Any number of them may share a Store.
+type Mailer struct {
+	store  Store
+	sender Sender
+	// MaxAttempts is how often a notification is tried before it is dead.
+	MaxAttempts int
+	// Backoff is the wait after the first failed attempt. It doubles after
+	// each further failure, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Batch is how many notifications one claim takes.
+	Batch int
+	// Lease is how long a claimed notification is left to its Mailer. It
+	// must outlast sending one batch.
+	Lease time.Duration
+	now   func() time.Time
+}
+
+// NewMailer tries each notification 8 times over about two hours by
+// default.
+func NewMailer(store Store, sender Sender) *Mailer {
+	return &Mailer{store: store, sender: sender, MaxAttempts: 8, Backoff: time.Minute, MaxBackoff: time.Hour,
+		Batch: 20, Lease: 10 * time.Minute, now: time.Now}
+}
+
+// SendDue sends every notification that is due, one batch at a time and one
+// email at a time. A failed attempt is recorded against its notification
+// rather than returned.
+func (m *Mailer) SendDue(ctx context.Context) error {
+	for {
+		claimed, err := m.store.Claim(ctx, m.now(), m.Batch, m.Lease)
+		if err != nil {
+			return err
+		}
+		for _, n := range claimed {
+			if err := m.send(ctx, n); err != nil {
+				return err
+			}
+		}
+		if len(claimed) < m.Batch {
+			return nil
+		}
+	}
+}
+
+// send makes one attempt at n and records how it went.
+func (m *Mailer) send(ctx context.Context, n Notification) error {
+	err := m.sender.Send(ctx, Message{To: n.Recipient, Subject: n.Subject, Body: n.Body, UnsubscribeURL: n.UnsubscribeURL})
+	at := m.now()
+	if err == nil {
+		return m.store.Record(ctx, n.ID, StatusSent, "", at, nil)
+	}
+	if ctx.Err() != nil {
+		// Shutting down; the lease runs out and the attempt is made again.
+		return ctx.Err()
+	}
+	status, next := StatusDead, (*time.Time)(nil)
+	if attempts := n.Attempts + 1; attempts < m.MaxAttempts {
+		status = StatusPending
+		retry := at.Add(m.backoff(attempts))
+		next = &retry
+	}
+	return m.store.Record(ctx, n.ID, status, err.Error(), at, next)
+}
+
+// backoff is the wait after the attempts-th failed attempt.
+func (m *Mailer) backoff(attempts int) time.Duration {
+	wait := m.Backoff
+	for i := 1; i < attempts && wait < m.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > m.MaxBackoff {
+		wait = m.MaxBackoff
+	}
+	return wait
+}
@@ -0,0 +1,177 @@
+package notify
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"mopcare/paging"
+)
+
+// Memory is an in-memory Store for tests. Setting Err makes every method
+// fail with it, to exercise error paths.
+type Memory struct {
+	mu            sync.Mutex
+	nextID        int64
+	users         map[int]Recipient
+	preferences   map[int]Preferences
+	courses       map[int]string
+	notifications map[int64]Notification
+
+	Err error
+}
+
+func NewMemory() *Memory {
+	return &Memory{nextID: 1, users: map[int]Recipient{}, preferences: map[int]Preferences{},
+		courses: map[int]string{}, notifications: map[int64]Notification{}}
+}
+
+// AddUser registers a live user, as the user-service would.
+func (m *Memory) AddUser(id int, email, firstName, lastName string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users[id] = Recipient{Email: email, FirstName: firstName, LastName: lastName}
+}
+
+// AddCourse registers a live course, as the course-service would.
+func (m *Memory) AddCourse(id int, title string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.courses[id] = title
+}
+
+func (m *Memory) Recipient(ctx context.Context, userID int) (Recipient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return Recipient{}, m.Err
+	}
+	r, ok := m.users[userID]
+	if !ok {
+		return Recipient{}, ErrNotFound
+	}
+	r.Preferences = DefaultPreferences(userID)
+	if p, ok := m.preferences[userID]; ok {
+		r.Preferences = p
+	}
+	return r, nil
+}
+
+func (m *Memory) Preferences(ctx context.Context, userID int) (Preferences, error) {
+	r, err := m.Recipient(ctx, userID)
+	return r.Preferences, err
+}
+
+func (m *Memory) SavePreferences(ctx context.Context, p *Preferences) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	if _, ok := m.users[p.UserID]; !ok {
+		return ErrNotFound
+	}
+	p.UpdatedAt = time.Now()
+	saved := *p
+	saved.Muted = append([]string{}, p.Muted...)
+	m.preferences[p.UserID] = saved
+	return nil
+}
+
+func (m *Memory) CourseTitle(ctx context.Context, courseID int) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return "", m.Err
+	}
+	title, ok := m.courses[courseID]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return title, nil
+}
+
+func (m *Memory) Enqueue(ctx context.Context, n *Notification) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	// Mirrors UNIQUE(user_id, event_source, event_id).
+	for _, queued := range m.notifications {
+		if queued.UserID == n.UserID && queued.EventSource == n.EventSource && queued.EventID == n.EventID {
+			return nil
+		}
+	}
+	now := time.Now()
+	n.ID = m.nextID
+	m.nextID++
+	n.Status, n.Attempts, n.NextAttemptAt, n.CreatedAt = StatusPending, 0, &now, now
+	m.notifications[n.ID] = *n
+	return nil
+}
+
+func (m *Memory) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]Notification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var due []Notification
+	for _, n := range m.notifications {
+		if n.Status == StatusPending && !n.NextAttemptAt.After(now) {
+			due = append(due, n)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(*due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	for i := range due {
+		until := now.Add(lease)
+		due[i].NextAttemptAt = &until
+		m.notifications[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (m *Memory) Record(ctx context.Context, id int64, status, lastError string, at time.Time, next *time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	n, ok := m.notifications[id]
+	if !ok {
+		return ErrNotFound
+	}
+	n.Attempts++
+	n.Status, n.LastError, n.NextAttemptAt, n.SentAt = status, lastError, next, nil
+	if status == StatusSent {
+		n.SentAt = &at
+	}
+	m.notifications[id] = n
+	return nil
+}
+
+func (m *Memory) ListNotifications(ctx context.Context, userID int, page paging.Page) ([]Notification, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var list []Notification
+	for _, n := range m.notifications {
+		if n.UserID == userID {
+			list = append(list, n)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID > list[j].ID })
+	return paging.Slice(list, page), nil
+}
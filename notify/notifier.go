@@ -0,0 +1,87 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"mopcare/events"
+)
+
+// Notifier turns events into queued emails.
+type Notifier struct {
+	store     Store
+	templates *Templates
+	links     *Links
+}
+
+func NewNotifier(store Store, templates *Templates, links *Links) *Notifier {
+	return &Notifier{store: store, templates: templates, links: links}
+}
+
+// Queue queues the email about e for the user it concerns, unless they do
+// not want it or no longer exist. Queuing an event again changes nothing.
+func (n *Notifier) Queue(ctx context.Context, e events.Event) error {
+	// The fields of every payload emails use; each event has some of them.
+	var p struct {
+		UserID          int     `json:"user_id"`
+		CourseID        int     `json:"course_id"`
+		Amount          float64 `json:"amount"`
+		TotalAmountPaid float64 `json:"total_amount_paid"`
+	}
+	if err := json.Unmarshal(e.Payload, &p); err != nil {
+		return err
+	}
+	r, err := n.store.Recipient(ctx, p.UserID)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil || !r.Wants(e.Type) {
+		return err
+	}
+	data := Data{
+		FirstName:         r.FirstName,
+		LastName:          r.LastName,
+		Amount:            p.Amount,
+		TotalAmountPaid:   p.TotalAmountPaid,
+		UnsubscribeURL:    n.links.Unsubscribe(p.UserID, e.Type),
+		UnsubscribeAllURL: n.links.Unsubscribe(p.UserID, ScopeAll),
+	}
+	if p.CourseID != 0 {
+		data.CourseTitle, err = n.store.CourseTitle(ctx, p.CourseID)
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	subject, body, err := n.templates.Render(r.Locale, e.Type, data)
+	if err != nil {
+		return err
+	}
+	return n.store.Enqueue(ctx, &Notification{
+		UserID:         p.UserID,
+		EventSource:    e.Source,
+		EventID:        e.ID,
+		EventType:      e.Type,
+		Locale:         r.Locale,
+		Recipient:      r.Email,
+		Subject:        subject,
+		Body:           body,
+		UnsubscribeURL: data.UnsubscribeURL,
+	})
+}
+
+// Subscribe queues emails for every event broker publishes that users are
+// emailed about. Queuing is idempotent, so every service and replica may
+// subscribe. An event that cannot be queued is published again.
+func (n *Notifier) Subscribe(broker events.Broker) (unsubscribe func(), err error) {
+	return events.SubscribeAll(broker, EventTypes, func(ctx context.Context, e events.Event) error {
+		if err := n.Queue(ctx, e); err != nil {
+			return fmt.Errorf("notify: queueing %s: %w", e.Type, err)
+		}
+		return nil
+	})
+}
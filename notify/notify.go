@@ -0,0 +1,111 @@
+// Package notify emails users about domain events. Each event a user should
+// hear about is rendered from the template for its type and the user's
+// locale and queued; a Mailer sends queued emails over SMTP, retrying with
+// exponential backoff. Users choose which emails they get through their
+// preferences, and every email carries signed unsubscribe links.
+package notify
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"mopcare/events"
+	"mopcare/paging"
+)
+
+// ErrNotFound is returned by a Store for a missing or deleted user, course
+// or notification.
+var ErrNotFound = errors.New("notify: not found")
+
+// EventTypes are the events users are emailed about.
+var EventTypes = []string{events.TypeUserCreated, events.TypeEnrollmentCreated, events.TypeEnrollmentCompleted, events.TypePaymentRecorded}
+
+// Notification statuses.
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusDead    = "dead"
+)
+
+// Preferences are what a user wants to be emailed about. Muted lists the
+// event types they unsubscribed from; EmailEnabled off stops every email.
+type Preferences struct {
+	UserID       int       `json:"user_id"`
+	EmailEnabled bool      `json:"email_enabled"`
+	Locale       string    `json:"locale"`
+	Muted        []string  `json:"muted"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// DefaultPreferences are those of a user who never changed theirs.
+func DefaultPreferences(userID int) Preferences {
+	return Preferences{UserID: userID, EmailEnabled: true, Locale: DefaultLocale, Muted: []string{}}
+}
+
+// Wants reports whether the user should be emailed about eventType.
+func (p Preferences) Wants(eventType string) bool {
+	if !p.EmailEnabled {
+		return false
+	}
+	for _, t := range p.Muted {
+		if t == eventType {
+			return false
+		}
+	}
+	return true
+}
+
+// Recipient is a user to be emailed, with their preferences.
+type Recipient struct {
+	Email     string
+	FirstName string
+	LastName  string
+	Preferences
+}
+
+// Notification is one email on its way to one user.
+type Notification struct {
+	ID             int64  `json:"id"`
+	UserID         int    `json:"user_id"`
+	EventSource    string `json:"event_source"`
+	EventID        int64  `json:"event_id"`
+	EventType      string `json:"event_type"`
+	Locale         string `json:"locale"`
+	Recipient      string `json:"recipient"`
+	Subject        string `json:"subject"`
+	Body           string `json:"body"`
+	UnsubscribeURL string `json:"unsubscribe_url"`
+	Status         string `json:"status"`
+	Attempts       int    `json:"attempts"`
+	LastError      string `json:"last_error"`
+	// NextAttemptAt is only set while the notification is pending.
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	SentAt        *time.Time `json:"sent_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// Store keeps preferences and the outbound queue, and looks up what emails
+// need to know about users and courses.
+type Store interface {
+	// Preferences returns the user's preferences, or the defaults if they
+	// never changed them.
+	Preferences(ctx context.Context, userID int) (Preferences, error)
+	// SavePreferences saves EmailEnabled, Locale and Muted.
+	SavePreferences(ctx context.Context, p *Preferences) error
+	Recipient(ctx context.Context, userID int) (Recipient, error)
+	CourseTitle(ctx context.Context, courseID int) (string, error)
+
+	// Enqueue queues n unless the same event was already queued for the
+	// same user.
+	Enqueue(ctx context.Context, n *Notification) error
+	// Claim returns up to limit pending notifications that are due at now,
+	// and postpones them by lease so that no other Mailer picks them up in
+	// the meantime.
+	Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]Notification, error)
+	// Record counts an attempt made at at and moves the notification to
+	// status, to be tried again at next if it is still pending.
+	Record(ctx context.Context, id int64, status, lastError string, at time.Time, next *time.Time) error
+	// ListNotifications returns the user's notifications, newest first.
+	ListNotifications(ctx context.Context, userID int, page paging.Page) ([]Notification, error)
+}
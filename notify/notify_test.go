@@ -0,0 +1,285 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"mopcare/events"
+	"mopcare/paging"
+)
+
+func TestTemplatesRenderEveryEventInEveryLocale(t *testing.T) {
+	templates, err := LoadTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := Data{FirstName: "Margaret", CourseTitle: "Heart Health", Amount: 12.5, TotalAmountPaid: 40,
+		UnsubscribeURL: "http://x/one", UnsubscribeAllURL: "http://x/all"}
+	subjects := map[string]bool{}
+	for _, locale := range Locales {
+		for _, eventType := range EventTypes {
+			subject, body, err := templates.Render(locale, eventType, data)
+			if err != nil {
+				t.Fatalf("%s %s: %v", locale, eventType, err)
+			}
+			if subject == "" || strings.Contains(subject, "\n") || !strings.Contains(body, "Margaret") ||
+				!strings.Contains(body, "http://x/one") || !strings.Contains(body, "http://x/all") {
+				t.Errorf("%s %s = %q, %q", locale, eventType, subject, body)
+			}
+			subjects[subject] = true
+		}
+	}
+	if len(subjects) != len(Locales)*len(EventTypes) {
+		t.Errorf("subjects = %v, want one per locale and event type", subjects)
+	}
+
+	_, body, _ := templates.Render("en", events.TypePaymentRecorded, data)
+	if !strings.Contains(body, "12.50") || !strings.Contains(body, "40.00") {
+		t.Errorf("payment body = %q", body)
+	}
+	fallback, _, err := templates.Render("fr", events.TypeUserCreated, data)
+	if english, _, _ := templates.Render("en", events.TypeUserCreated, data); err != nil || fallback != english {
+		t.Errorf("Render(fr) = %q, %v, want the English email", fallback, err)
+	}
+}
+
+func TestLinks(t *testing.T) {
+	links := NewLinks("https://mopcare.example/", "key")
+	link := links.Unsubscribe(7, events.TypeEnrollmentCompleted)
+	if !strings.HasPrefix(link, "https://mopcare.example/notifications/unsubscribe?token=7.enrollment.completed.") {
+		t.Fatalf("Unsubscribe = %q", link)
+	}
+	user, scope, err := links.Parse(links.Token(7, events.TypeEnrollmentCompleted))
+	if err != nil || user != 7 || scope != events.TypeEnrollmentCompleted {
+		t.Errorf("Parse = %d, %q, %v", user, scope, err)
+	}
+	token := links.Token(7, ScopeAll)
+	for name, check := range map[string]string{
+		"other key":     NewLinks("", "other").Token(7, ScopeAll),
+		"other user":    "8" + token[1:],
+		"other scope":   strings.Replace(token, ScopeAll, events.TypeUserCreated, 1),
+		"no signature":  "7.all",
+		"empty":         "",
+		"not a user id": NewLinks("", "key").Token(0, ScopeAll),
+	} {
+		if _, _, err := links.Parse(check); err != ErrTokenInvalid {
+			t.Errorf("%s: Parse = %v, want ErrTokenInvalid", name, err)
+		}
+	}
+	if _, _, err := NewLinks("", "").Parse(NewLinks("", "").Token(7, ScopeAll)); err != ErrTokenInvalid {
+		t.Errorf("Parse without a key = %v, want ErrTokenInvalid", err)
+	}
+}
+
+// publish sends an event the way a Dispatcher would.
+func publish(t *testing.T, b events.Broker, id int64, p events.Payload) {
+	t.Helper()
+	payload, _ := json.Marshal(p)
+	data, _ := json.Marshal(events.Event{ID: id, Source: "test", Type: p.EventType(), Payload: payload})
+	if err := b.Publish(context.Background(), p.EventType(), data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNotifierQueuesWhatUsersWant(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+	store.AddUser(1, "margaret@example.com", "Margaret", "Johnson")
+	store.AddUser(2, "carlos@example.com", "Carlos", "Ruiz")
+	store.AddUser(3, "quiet@example.com", "Quiet", "User")
+	store.AddCourse(10, "Heart Health")
+	store.SavePreferences(ctx, &Preferences{UserID: 2, EmailEnabled: true, Locale: "es", Muted: []string{events.TypePaymentRecorded}})
+	store.SavePreferences(ctx, &Preferences{UserID: 3, Locale: "en"})
+	templates, err := LoadTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	broker := events.NewLocal()
+	unsubscribe, err := NewNotifier(store, templates, NewLinks("http://gw", "key")).Subscribe(broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+	publish(t, broker, 1, events.EnrollmentCreated{EnrollmentID: 5, UserID: 1, CourseID: 10, Status: "enrolled"})
+	publish(t, broker, 1, events.EnrollmentCreated{EnrollmentID: 5, UserID: 1, CourseID: 10, Status: "enrolled"}) // redelivered
+	publish(t, broker, 2, events.PaymentRecorded{UserID: 1, Amount: 5, TotalAmountPaid: 5})
+	publish(t, broker, 3, events.EnrollmentCompleted{EnrollmentID: 6, UserID: 2, CourseID: 10})
+	publish(t, broker, 4, events.PaymentRecorded{UserID: 2, Amount: 5, TotalAmountPaid: 5})     // muted
+	publish(t, broker, 5, events.UserCreated{UserID: 3})                                        // email off
+	publish(t, broker, 6, events.UserCreated{UserID: 9})                                        // deleted since
+	publish(t, broker, 7, events.EnrollmentCompleted{EnrollmentID: 8, UserID: 1, CourseID: 11}) // course deleted since
+	publish(t, broker, 8, events.CourseDeleted{CourseID: 10})                                   // not emailed
+
+	var got []string
+	for _, user := range []int{1, 2, 3} {
+		list, _ := store.ListNotifications(ctx, user, paging.Page{})
+		for i := len(list) - 1; i >= 0; i-- {
+			n := list[i]
+			got = append(got, n.Recipient+" "+n.Locale+" "+n.Subject)
+			if !strings.HasPrefix(n.UnsubscribeURL, "http://gw/notifications/unsubscribe?token=") || !strings.Contains(n.Body, n.UnsubscribeURL) {
+				t.Errorf("notification %d: unsubscribe link %q not in %q", n.ID, n.UnsubscribeURL, n.Body)
+			}
+		}
+	}
+	want := []string{
+		"margaret@example.com en You are enrolled in Heart Health",
+		"margaret@example.com en Payment received",
+		"carlos@example.com es Has completado Heart Health",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("queued:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+type fakeSender struct {
+	err  error
+	sent []Message
+}
+
+func (s *fakeSender) Send(ctx context.Context, m Message) error {
+	s.sent = append(s.sent, m)
+	return s.err
+}
+
+func TestMailerRetriesThenGivesUp(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+	store.Enqueue(ctx, &Notification{UserID: 1, EventSource: "test", EventID: 1, Recipient: "a@example.com", Subject: "Hi", UnsubscribeURL: "http://u"})
+	sender := &fakeSender{err: errors.New("450 mailbox busy")}
+	m := NewMailer(store, sender)
+	m.MaxAttempts = 2
+	clock := time.Now()
+	m.now = func() time.Time { return clock }
+	notification := func() Notification {
+		t.Helper()
+		list, _ := store.ListNotifications(ctx, 1, paging.Page{})
+		if len(list) != 1 {
+			t.Fatalf("notifications = %+v", list)
+		}
+		return list[0]
+	}
+
+	if err := m.SendDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := notification(); n.Status != StatusPending || n.Attempts != 1 || n.LastError != "450 mailbox busy" || !n.NextAttemptAt.Equal(clock.Add(time.Minute)) {
+		t.Fatalf("after a failure: %+v", n)
+	}
+	m.SendDue(ctx)
+	if len(sender.sent) != 1 {
+		t.Fatalf("retried %d times before the backoff elapsed", len(sender.sent)-1)
+	}
+	if s := sender.sent[0]; s.To != "a@example.com" || s.Subject != "Hi" || s.UnsubscribeURL != "http://u" {
+		t.Errorf("sent %+v", s)
+	}
+
+	clock = clock.Add(time.Minute)
+	m.SendDue(ctx)
+	if n := notification(); n.Status != StatusDead || n.Attempts != 2 || n.NextAttemptAt != nil {
+		t.Fatalf("after the last attempt: %+v", n)
+	}
+
+	store.Enqueue(ctx, &Notification{UserID: 1, EventSource: "test", EventID: 2, Recipient: "a@example.com"})
+	sender.err = nil
+	m.SendDue(ctx)
+	if list, _ := store.ListNotifications(ctx, 1, paging.Page{}); list[0].Status != StatusSent || list[0].SentAt == nil || list[0].LastError != "" {
+		t.Fatalf("after sending: %+v", list[0])
+	}
+}
+
+func TestBackoffDoublesUpToTheCap(t *testing.T) {
+	m := NewMailer(NewMemory(), nil)
+	var waits []time.Duration
+	for attempts := 1; attempts <= 8; attempts++ {
+		waits = append(waits, m.backoff(attempts))
+	}
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 16 * time.Minute,
+		32 * time.Minute, time.Hour, time.Hour}
+	for i := range want {
+		if waits[i] != want[i] {
+			t.Fatalf("backoff = %v, want %v", waits, want)
+		}
+	}
+}
+
+// smtpSink accepts one message the way MailHog would and returns its
+// envelope and data.
+func smtpSink(t *testing.T) (addr string, received <-chan []string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	out := make(chan []string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		c := textproto.NewConn(conn)
+		c.PrintfLine("220 sink ESMTP")
+		var got []string
+		for {
+			line, err := c.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb := strings.ToUpper(strings.Fields(line + " x")[0]); verb {
+			case "EHLO", "HELO":
+				c.PrintfLine("250 sink")
+			case "MAIL", "RCPT":
+				got = append(got, line)
+				c.PrintfLine("250 ok")
+			case "DATA":
+				c.PrintfLine("354 go ahead")
+				data, _ := io.ReadAll(c.DotReader())
+				got = append(got, string(data))
+				c.PrintfLine("250 queued")
+			case "QUIT":
+				c.PrintfLine("221 bye")
+				out <- got
+				return
+			default:
+				c.PrintfLine("502 unsupported")
+			}
+		}
+	}()
+	return ln.Addr().String(), out
+}
+
+func TestSMTP(t *testing.T) {
+	addr, received := smtpSink(t)
+	s := &SMTP{Addr: addr, From: "Mopcare <no-reply@mopcare.example>", Timeout: 5 * time.Second}
+	err := s.Send(context.Background(), Message{To: "margaret@example.com", Subject: "Has completado «Salud»",
+		Body: "Hola:\n\n.Enhorabuena\n", UnsubscribeURL: "http://gw/notifications/unsubscribe?token=x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := <-received
+	if len(got) != 3 || got[0] != "MAIL FROM:<no-reply@mopcare.example>" || got[1] != "RCPT TO:<margaret@example.com>" {
+		t.Fatalf("envelope = %q", got)
+	}
+	data := got[2]
+	for _, want := range []string{
+		"From: \"Mopcare\" <no-reply@mopcare.example>\n",
+		"To: <margaret@example.com>\n",
+		"Subject: =?utf-8?q?Has_completado_=C2=ABSalud=C2=BB?=\n",
+		"List-Unsubscribe: <http://gw/notifications/unsubscribe?token=x>\n",
+		"List-Unsubscribe-Post: List-Unsubscribe=One-Click\n",
+		"\n\nHola:\n\n.Enhorabuena\n",
+	} {
+		if !strings.Contains(data, want) {
+			t.Errorf("message does not contain %q:\n%s", want, data)
+		}
+	}
+}
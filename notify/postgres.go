@@ -0,0 +1,161 @@
+package notify
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+
+	"mopcare/database"
+	"mopcare/paging"
+)
+
+// Postgres is the production Store. It reads users and courses from the
+// tables of the services that own them.
+type Postgres struct {
+	db *database.DB
+}
+
+func NewPostgres(db *database.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// recipientQuery reads a live user with their preferences, filling in the
+// defaults for users who never saved any.
+const recipientQuery = `SELECT u.email, u.first_name, u.last_name, u.id, COALESCE(p.email_enabled, TRUE),
+	    COALESCE(p.locale, 'en'), COALESCE(p.muted, '{}'), COALESCE(p.updated_at, u.created_at)
+	 FROM users u LEFT JOIN notification_preferences p ON p.user_id = u.id
+	 WHERE u.id = $1 AND u.deleted_at IS NULL`
+
+func (p *Postgres) Recipient(ctx context.Context, userID int) (Recipient, error) {
+	var r Recipient
+	err := p.db.QueryRowContext(ctx, recipientQuery, userID).Scan(&r.Email, &r.FirstName, &r.LastName,
+		&r.UserID, &r.EmailEnabled, &r.Locale, pq.Array(&r.Muted), &r.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Recipient{}, ErrNotFound
+	}
+	return r, err
+}
+
+func (p *Postgres) Preferences(ctx context.Context, userID int) (Preferences, error) {
+	r, err := p.Recipient(ctx, userID)
+	return r.Preferences, err
+}
+
+func (p *Postgres) SavePreferences(ctx context.Context, prefs *Preferences) error {
+	err := p.db.QueryRowContext(ctx,
+		`INSERT INTO notification_preferences (user_id, email_enabled, locale, muted)
+		 SELECT id, $2::boolean, $3::text, $4::text[] FROM users WHERE id = $1 AND deleted_at IS NULL
+		 ON CONFLICT (user_id) DO UPDATE SET email_enabled = EXCLUDED.email_enabled, locale = EXCLUDED.locale,
+		     muted = EXCLUDED.muted, updated_at = NOW()
+		 RETURNING updated_at`,
+		prefs.UserID, prefs.EmailEnabled, prefs.Locale, pq.Array(prefs.Muted),
+	).Scan(&prefs.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (p *Postgres) CourseTitle(ctx context.Context, courseID int) (string, error) {
+	var title string
+	err := p.db.QueryRowContext(ctx, "SELECT title FROM courses WHERE id = $1 AND deleted_at IS NULL", courseID).Scan(&title)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return title, err
+}
+
+func (p *Postgres) Enqueue(ctx context.Context, n *Notification) error {
+	err := p.db.QueryRowContext(ctx,
+		`INSERT INTO notifications (user_id, event_source, event_id, event_type, locale, recipient, subject, body, unsubscribe_url, next_attempt_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+		 ON CONFLICT (user_id, event_source, event_id) DO NOTHING
+		 RETURNING id, status, next_attempt_at, created_at`,
+		n.UserID, n.EventSource, n.EventID, n.EventType, n.Locale, n.Recipient, n.Subject, n.Body, n.UnsubscribeURL,
+	).Scan(&n.ID, &n.Status, &n.NextAttemptAt, &n.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Queued before.
+		return nil
+	}
+	return err
+}
+
+const notificationColumns = `id, user_id, event_source, event_id, event_type, locale, recipient, subject, body, unsubscribe_url,
+	status, attempts, last_error, next_attempt_at, sent_at, created_at`
+
+func scanNotification(row scanner) (Notification, error) {
+	var n Notification
+	err := row.Scan(&n.ID, &n.UserID, &n.EventSource, &n.EventID, &n.EventType, &n.Locale, &n.Recipient, &n.Subject, &n.Body,
+		&n.UnsubscribeURL, &n.Status, &n.Attempts, &n.LastError, &n.NextAttemptAt, &n.SentAt, &n.CreatedAt)
+	return n, err
+}
+
+func (p *Postgres) scanAll(rows *database.Rows, err error) ([]Notification, error) {
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var notifications []Notification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, n)
+	}
+	return notifications, rows.Err()
+}
+
+// Claim skips rows another Mailer has locked, so several can claim at once
+// without waiting for each other.
+func (p *Postgres) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]Notification, error) {
+	return p.scanAll(p.db.QueryContext(ctx,
+		`UPDATE notifications SET next_attempt_at = $2
+		 WHERE id IN (
+		     SELECT id FROM notifications WHERE status = 'pending' AND next_attempt_at <= $1
+		     ORDER BY next_attempt_at, id LIMIT $3
+		     FOR UPDATE SKIP LOCKED
+		 )
+		 RETURNING `+notificationColumns,
+		now, now.Add(lease), limit,
+	))
+}
+
+func (p *Postgres) Record(ctx context.Context, id int64, status, lastError string, at time.Time, next *time.Time) error {
+	result, err := p.db.ExecContext(ctx,
+		`UPDATE notifications SET attempts = attempts + 1, status = $2::text, last_error = $3, next_attempt_at = $5,
+		     sent_at = CASE WHEN $2::text = 'sent' THEN $4::timestamptz END
+		 WHERE id = $1`,
+		id, status, lastError, at, next,
+	)
+	if err != nil {
+		return err
+	}
+	return expectRow(result)
+}
+
+func (p *Postgres) ListNotifications(ctx context.Context, userID int, page paging.Page) ([]Notification, error) {
+	return p.scanAll(p.db.QueryContext(ctx,
+		"SELECT "+notificationColumns+" FROM notifications WHERE user_id = $1 ORDER BY id DESC LIMIT $2 OFFSET $3",
+		userID, page.LimitArg(), page.Offset,
+	))
+}
+
+// expectRow turns an UPDATE that touched nothing into ErrNotFound.
+func expectRow(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
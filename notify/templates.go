@@ -0,0 +1,88 @@
+package notify
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// Locales are the languages emails are written in.
+var Locales = []string{"en", "es"}
+
+// DefaultLocale is used for users who did not choose a locale.
+const DefaultLocale = "en"
+
+// templateFS holds templates/<locale>/<event type>.tmpl, each defining
+// "subject" and "body", and templates/<locale>/footer.tmpl, which bodies
+// end with.
+//
+//go:embed templates
+var templateFS embed.FS
+
+// Data is what templates are executed with. Fields that do not apply to an
+// event type are zero.
+type Data struct {
+	FirstName       string
+	LastName        string
+	CourseTitle     string
+	Amount          float64
+	TotalAmountPaid float64
+	// UnsubscribeURL stops emails of this event type, UnsubscribeAllURL
+	// every email.
+	UnsubscribeURL    string
+	UnsubscribeAllURL string
+}
+
+// Templates renders emails.
+type Templates struct {
+	// sets maps "<locale>/<event type>" to its templates.
+	sets map[string]*template.Template
+}
+
+// LoadTemplates parses the embedded templates. Every locale has a template
+// for every event type.
+func LoadTemplates() (*Templates, error) {
+	t := &Templates{sets: map[string]*template.Template{}}
+	for _, locale := range Locales {
+		for _, eventType := range EventTypes {
+			dir := "templates/" + locale + "/"
+			set, err := template.New("").ParseFS(templateFS, dir+"footer.tmpl", dir+eventType+".tmpl")
+			if err != nil {
+				return nil, fmt.Errorf("notify: %w", err)
+			}
+			t.sets[locale+"/"+eventType] = set
+		}
+	}
+	return t, nil
+}
+
+// Render returns the subject and body of the eventType email in locale,
+// falling back to DefaultLocale for a locale there are no templates for.
+func (t *Templates) Render(locale, eventType string, data Data) (subject, body string, err error) {
+	set, ok := t.sets[locale+"/"+eventType]
+	if !ok {
+		set, ok = t.sets[DefaultLocale+"/"+eventType]
+	}
+	if !ok {
+		return "", "", fmt.Errorf("notify: no template for %s", eventType)
+	}
+	var s, b strings.Builder
+	if err := set.ExecuteTemplate(&s, "subject", data); err != nil {
+		return "", "", err
+	}
+	if err := set.ExecuteTemplate(&b, "body", data); err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(s.String()), b.String(), nil
+}
+
+// KnownLocale reports whether emails can be written in locale.
+func KnownLocale(locale string) bool {
+	for _, l := range Locales {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
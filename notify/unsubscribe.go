@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ScopeAll unsubscribes from every email rather than one event type.
+const ScopeAll = "all"
+
+// ErrTokenInvalid is returned by Links.Parse for a forged or mangled token.
+var ErrTokenInvalid = errors.New("notify: invalid unsubscribe token")
+
+// Links makes and checks unsubscribe links. Their tokens never expire, so a
+// link in an old email keeps working.
+type Links struct {
+	baseURL string
+	key     string
+}
+
+// NewLinks signs tokens with key and points links at baseURL, where the
+// gateway is reached. An empty key yields links nobody accepts.
+func NewLinks(baseURL, key string) *Links {
+	return &Links{baseURL: strings.TrimSuffix(baseURL, "/"), key: key}
+}
+
+// Unsubscribe returns the link that unsubscribes userID from scope, an
+// event type or ScopeAll.
+func (l *Links) Unsubscribe(userID int, scope string) string {
+	return l.baseURL + "/notifications/unsubscribe?token=" + url.QueryEscape(l.Token(userID, scope))
+}
+
+// Token is "<user ID>.<scope>.<signature>". Scopes may contain dots; user
+// IDs and signatures do not.
+func (l *Links) Token(userID int, scope string) string {
+	payload := strconv.Itoa(userID) + "." + scope
+	return payload + "." + l.mac(payload)
+}
+
+// Parse returns who a token unsubscribes from what.
+func (l *Links) Parse(token string) (userID int, scope string, err error) {
+	first, last := strings.Index(token, "."), strings.LastIndex(token, ".")
+	if l.key == "" || first < 0 || last <= first {
+		return 0, "", ErrTokenInvalid
+	}
+	payload := token[:last]
+	if !hmac.Equal([]byte(token[last+1:]), []byte(l.mac(payload))) {
+		return 0, "", ErrTokenInvalid
+	}
+	userID, err = strconv.Atoi(token[:first])
+	if err != nil || userID < 1 {
+		return 0, "", ErrTokenInvalid
+	}
+	return userID, token[first+1 : last], nil
+}
+
+// mac is keyed like user tokens, so its input is prefixed to keep the two
+// apart.
+func (l *Links) mac(payload string) string {
+	m := hmac.New(sha256.New, []byte(l.key))
+	m.Write([]byte("unsubscribe." + payload))
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
@@ -0,0 +1,178 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+)
+
+// GenerateGo renders every component schema as a Go struct in package pkg.
+// The client package's types are produced this way (see cmd/genclient), so
+// they cannot drift from the document. Properties come out alphabetically
+// with "id" first; date-time strings become time.Time; optional properties of
+// closed (additionalProperties: false) input schemas get omitempty.
+func (s *Spec) GenerateGo(pkg string) ([]byte, error) {
+	g := &generator{emitted: map[string]bool{}}
+	fmt.Fprintf(&g.buf, "// Code generated by go run mopcare/cmd/genclient; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&g.buf, "package %s\n\n", pkg)
+
+	names := make([]string, 0, len(s.Components.Schemas))
+	for name := range s.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.structType(name, fmt.Sprintf("%s mirrors the %s schema.", name, name), s.Components.Schemas[name])
+	}
+
+	src := g.buf.Bytes()
+	if g.usesTime {
+		src = bytes.Replace(src, []byte("package "+pkg+"\n"), []byte("package "+pkg+"\n\nimport \"time\"\n"), 1)
+	}
+	out, err := format.Source(src)
+	if err != nil {
+		return nil, fmt.Errorf("formatting generated code: %w", err)
+	}
+	return out, nil
+}
+
+type generator struct {
+	buf      bytes.Buffer
+	pending  []func()
+	emitted  map[string]bool
+	usesTime bool
+}
+
+func (g *generator) structType(name, doc string, sc *Schema) {
+	if g.emitted[name] {
+		return
+	}
+	g.emitted[name] = true
+
+	fmt.Fprintf(&g.buf, "// %s\n", doc)
+	if sc.Description != "" {
+		fmt.Fprintf(&g.buf, "// %s\n", sc.Description)
+	}
+	fmt.Fprintf(&g.buf, "type %s struct {\n", name)
+
+	required := map[string]bool{}
+	for _, r := range sc.Required {
+		required[r] = true
+	}
+	closed := string(bytes.TrimSpace(sc.AdditionalProperties)) == "false"
+	for _, prop := range propertyOrder(sc.Properties) {
+		field := goName(prop)
+		tag := prop
+		if closed && !required[prop] {
+			tag += ",omitempty"
+		}
+		typ := g.goType(name, field, prop, sc.Properties[prop])
+		if desc := sc.Properties[prop].Description; desc != "" {
+			fmt.Fprintf(&g.buf, "\t// %s\n", desc)
+		}
+		fmt.Fprintf(&g.buf, "\t%s %s `json:%q`\n", field, typ, tag)
+	}
+	fmt.Fprintf(&g.buf, "}\n\n")
+
+	// Inline object types are emitted after their parent.
+	pending := g.pending
+	g.pending = nil
+	for _, emit := range pending {
+		emit()
+	}
+}
+
+// goType maps a property schema to a Go type. Inline objects become their
+// own struct named parent+field.
+func (g *generator) goType(parent, field, prop string, sc *Schema) string {
+	if sc.Ref != "" {
+		return strings.TrimPrefix(sc.Ref, "#/components/schemas/")
+	}
+	typ, nullable := "", false
+	for _, t := range sc.Type {
+		if t == "null" {
+			nullable = true
+		} else {
+			typ = t
+		}
+	}
+
+	var out string
+	switch typ {
+	case "integer":
+		out = "int"
+	case "number":
+		out = "float64"
+	case "boolean":
+		out = "bool"
+	case "string":
+		out = "string"
+		if sc.Format == "date-time" {
+			g.usesTime = true
+			out = "time.Time"
+		}
+	case "array":
+		elem := "interface{}"
+		if sc.Items != nil {
+			elem = g.goType(parent, field+"Item", prop, sc.Items)
+		}
+		return "[]" + elem
+	case "object":
+		if len(sc.Properties) == 0 {
+			// additionalProperties: {schema} types the values of a map;
+			// true, false or absent leaves them open.
+			var values Schema
+			if err := json.Unmarshal(sc.AdditionalProperties, &values); err == nil && (values.Ref != "" || len(values.Type) > 0) {
+				return "map[string]" + g.goType(parent, field+"Value", prop, &values)
+			}
+			return "map[string]interface{}"
+		}
+		name := parent + field
+		doc := fmt.Sprintf("%s is the %q object inside %s.", name, prop, parent)
+		g.pending = append(g.pending, func() { g.structType(name, doc, sc) })
+		out = name
+	default:
+		return "interface{}"
+	}
+	if nullable {
+		return "*" + out
+	}
+	return out
+}
+
+func propertyOrder(props map[string]*Schema) []string {
+	names := make([]string, 0, len(props))
+	for name := range props {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if names[i] == "id" || names[j] == "id" {
+			return names[i] == "id"
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// initialisms are the words Go style writes in capitals.
+var initialisms = map[string]string{"id": "ID", "url": "URL", "api": "API", "http": "HTTP", "json": "JSON", "html": "HTML", "pdf": "PDF"}
+
+// goName turns a snake_case property into an exported Go identifier, e.g.
+// "overview_video_url" into "OverviewVideoURL".
+func goName(prop string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(prop, "_") {
+		if word == "" {
+			continue
+		}
+		if upper, ok := initialisms[word]; ok {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
@@ -0,0 +1,259 @@
+// Package openapi embeds the Mopcare OpenAPI 3.1 document and validates
+// request bodies against it. The gateway serves the document at /openapi.json
+// and rejects non-conforming bodies before proxying; each service's tests
+// check that its routes and response structs match the document.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:embed openapi.json
+var document []byte
+
+// Document returns the raw OpenAPI document.
+func Document() []byte {
+	return document
+}
+
+// Spec is the subset of an OpenAPI document the validator needs.
+type Spec struct {
+	Paths      map[string]PathItem `json:"paths"`
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas"`
+	} `json:"components"`
+
+	templates []template
+}
+
+// PathItem maps lower-case HTTP methods to operations. The path-level
+// "parameters" entry is kept out by UnmarshalJSON.
+type PathItem map[string]*Operation
+
+func (p *PathItem) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*p = PathItem{}
+	for key, value := range raw {
+		switch key {
+		case "get", "put", "post", "delete", "patch", "head", "options":
+			var op Operation
+			if err := json.Unmarshal(value, &op); err != nil {
+				return fmt.Errorf("%s: %w", key, err)
+			}
+			(*p)[key] = &op
+		}
+	}
+	return nil
+}
+
+// Operation is one method on one path.
+type Operation struct {
+	OperationID string       `json:"operationId"`
+	RequestBody *RequestBody `json:"requestBody"`
+}
+
+type RequestBody struct {
+	Required bool `json:"required"`
+	Content  map[string]struct {
+		Schema *Schema `json:"schema"`
+	} `json:"content"`
+}
+
+// template is a path split into segments, "" marking a {parameter}.
+type template struct {
+	path     string
+	segments []string
+	literals int
+}
+
+var (
+	loadOnce sync.Once
+	loaded   *Spec
+	loadErr  error
+)
+
+// Load parses the embedded document once and returns it.
+func Load() (*Spec, error) {
+	loadOnce.Do(func() {
+		loaded, loadErr = Parse(document)
+	})
+	return loaded, loadErr
+}
+
+// MustLoad is Load for callers that cannot run without the document.
+func MustLoad() *Spec {
+	spec, err := Load()
+	if err != nil {
+		panic("openapi: " + err.Error())
+	}
+	return spec
+}
+
+// Parse reads an OpenAPI document and resolves every $ref in it.
+func Parse(data []byte) (*Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	for path, item := range spec.Paths {
+		for method, op := range item {
+			if op.RequestBody == nil {
+				continue
+			}
+			for _, media := range op.RequestBody.Content {
+				if err := spec.resolve(media.Schema); err != nil {
+					return nil, fmt.Errorf("%s %s: %w", method, path, err)
+				}
+			}
+		}
+		spec.templates = append(spec.templates, newTemplate(path))
+	}
+	for name, schema := range spec.Components.Schemas {
+		if err := spec.resolve(schema); err != nil {
+			return nil, fmt.Errorf("schema %s: %w", name, err)
+		}
+	}
+	// Prefer the template with the most literal segments, so /courses/by-slug/x
+	// beats /courses/{id}/x when both match.
+	sort.Slice(spec.templates, func(i, j int) bool {
+		if spec.templates[i].literals != spec.templates[j].literals {
+			return spec.templates[i].literals > spec.templates[j].literals
+		}
+		return spec.templates[i].path < spec.templates[j].path
+	})
+	return &spec, nil
+}
+
+func newTemplate(path string) template {
+	t := template{path: path, segments: strings.Split(strings.Trim(path, "/"), "/")}
+	for i, seg := range t.segments {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			t.segments[i] = ""
+		} else {
+			t.literals++
+		}
+	}
+	return t
+}
+
+func (t template) match(segments []string) bool {
+	if len(segments) != len(t.segments) {
+		return false
+	}
+	for i, seg := range t.segments {
+		if seg != "" && seg != segments[i] {
+			return false
+		}
+		if seg == "" && segments[i] == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// resolve links every $ref below s to its component schema.
+func (s *Spec) resolve(schema *Schema) error {
+	if schema == nil || schema.resolved {
+		return nil
+	}
+	schema.resolved = true
+	if schema.Pattern != "" {
+		re, err := regexp.Compile(schema.Pattern)
+		if err != nil {
+			return fmt.Errorf("pattern %q: %w", schema.Pattern, err)
+		}
+		schema.pattern = re
+	}
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		target, ok := s.Components.Schemas[name]
+		if !ok || name == schema.Ref {
+			return fmt.Errorf("unresolvable $ref %q", schema.Ref)
+		}
+		schema.target = target
+		return s.resolve(target)
+	}
+	for _, prop := range schema.Properties {
+		if err := s.resolve(prop); err != nil {
+			return err
+		}
+	}
+	return s.resolve(schema.Items)
+}
+
+// Route returns the documented path template matching a concrete request
+// path, e.g. "/courses/{id}" for "/courses/42".
+func (s *Spec) Route(path string) (string, bool) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for _, t := range s.templates {
+		if t.match(segments) {
+			return t.path, true
+		}
+	}
+	return "", false
+}
+
+// Operation returns the operation for a method and path template.
+func (s *Spec) Operation(method, template string) (*Operation, bool) {
+	op, ok := s.Paths[template][strings.ToLower(method)]
+	return op, ok
+}
+
+// SchemaFieldDiff compares the properties of a component schema with the JSON
+// field names of a struct value and reports fields present on only one side.
+// Service tests use it to keep the document in step with their types.
+func (s *Spec) SchemaFieldDiff(name string, v interface{}) (onlyInSchema, onlyInStruct []string) {
+	schema := s.Components.Schemas[name]
+	fields := map[string]bool{}
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := strings.Split(f.Tag.Get("json"), ",")[0]
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		if tag == "" {
+			tag = f.Name
+		}
+		fields[tag] = true
+	}
+	if schema != nil {
+		for prop := range schema.Properties {
+			if !fields[prop] {
+				onlyInSchema = append(onlyInSchema, prop)
+			}
+			delete(fields, prop)
+		}
+	}
+	for field := range fields {
+		onlyInStruct = append(onlyInStruct, field)
+	}
+	sort.Strings(onlyInSchema)
+	sort.Strings(onlyInStruct)
+	return onlyInSchema, onlyInStruct
+}
+
+// PathTemplate converts a router path such as "/courses/:id/series" into the
+// document's form, "/courses/{id}/series".
+func PathTemplate(route string) string {
+	segments := strings.Split(route, "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") {
+			segments[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,147 @@
+package openapi
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"mopcare/problem"
+)
+
+func TestDocumentParses(t *testing.T) {
+	spec, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Course", "Series", "User", "UserCourseEnrollment", "Problem"} {
+		if spec.Components.Schemas[name] == nil {
+			t.Errorf("schema %s missing", name)
+		}
+	}
+	for path, item := range spec.Paths {
+		for method, op := range item {
+			if op.OperationID == "" {
+				t.Errorf("%s %s has no operationId", method, path)
+			}
+		}
+	}
+}
+
+func TestRoute(t *testing.T) {
+	spec := MustLoad()
+	cases := map[string]string{
+		"/courses":               "/courses",
+		"/courses/42":            "/courses/{id}",
+		"/courses/42/series":     "/courses/{id}/series",
+		"/users/7/enrollments":   "/users/{id}/enrollments",
+		"/enrollments/3":         "/enrollments/{id}",
+		"/enrollments/import":    "/enrollments/import",
+		"/openapi.json":          "/openapi.json",
+		"/nowhere":               "",
+		"/courses/42/series/9/x": "",
+	}
+	for path, want := range cases {
+		got, _ := spec.Route(path)
+		if got != want {
+			t.Errorf("Route(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	spec := MustLoad()
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		code   string
+		errors []string
+	}{
+		{name: "valid course", method: "POST", path: "/courses", body: `{"title":"Go","content":"Basics"}`},
+		{name: "valid enrollment", method: "POST", path: "/users/1/enrollments", body: `{"user_id":1,"course_id":2,"status":"enrolled"}`},
+		{name: "undocumented path", method: "POST", path: "/elsewhere", body: `nonsense`},
+		{name: "no request body", method: "DELETE", path: "/courses/1", body: `nonsense`},
+		{name: "missing body", method: "POST", path: "/courses", code: problem.CodeInvalidBody},
+		{name: "malformed json", method: "POST", path: "/courses", body: `{"title":`, code: problem.CodeInvalidBody},
+		{name: "trailing data", method: "POST", path: "/courses", body: `{} {}`, code: problem.CodeInvalidBody},
+		{
+			name: "missing required", method: "POST", path: "/courses", body: `{"title":""}`,
+			code: problem.CodeValidationFailed, errors: []string{"content: is required", "title: must not be empty"},
+		},
+		{
+			name: "unknown field", method: "PUT", path: "/series/1", body: `{"title":"x","video":"y"}`,
+			code: problem.CodeValidationFailed, errors: []string{"video: is not a recognised field"},
+		},
+		{
+			name: "wrong types", method: "POST", path: "/users/1/enrollments", body: `{"user_id":"1","course_id":1.5,"status":"paused"}`,
+			code: problem.CodeValidationFailed, errors: []string{
+				"course_id: must be of type integer",
+				"status: must be one of \"enrolled\", \"completed\"",
+				"user_id: must be of type integer",
+			},
+		},
+		{
+			name: "bad email and negative total", method: "POST", path: "/users",
+			body: `{"first_name":"A","last_name":"B","email":"nope","total_amount_paid":-1}`,
+			code: problem.CodeValidationFailed, errors: []string{"email: must be a valid email address", "total_amount_paid: must be at least 0"},
+		},
+		{
+			name: "payment not positive", method: "PUT", path: "/users/1/payment", body: `{"amount":0}`,
+			code: problem.CodeValidationFailed, errors: []string{"amount: must be greater than 0"},
+		},
+		{name: "valid merge patch", method: "PATCH", path: "/courses/1", body: `{"cover_image_url":null}`},
+		{
+			name: "merge patch nulls a required field", method: "PATCH", path: "/courses/1", body: `{"title":null,"rating":1}`,
+			code: problem.CodeValidationFailed, errors: []string{"rating: is not a recognised field", "title: must be of type string"},
+		},
+		{
+			name: "not an object", method: "PUT", path: "/users/1/payment", body: `[1]`,
+			code: problem.CodeValidationFailed, errors: []string{"body: must be of type object"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := spec.ValidateRequest(tc.method, tc.path, []byte(tc.body))
+			if tc.code == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var p *problem.Problem
+			if !errors.As(err, &p) {
+				t.Fatalf("got %v, want problem %s", err, tc.code)
+			}
+			if p.Code != tc.code || p.Status != 400 {
+				t.Fatalf("got %d %s, want 400 %s", p.Status, p.Code, tc.code)
+			}
+			if tc.errors != nil && !reflect.DeepEqual(p.Errors, tc.errors) {
+				t.Fatalf("errors = %q, want %q", p.Errors, tc.errors)
+			}
+		})
+	}
+}
+
+func TestSchemaFieldDiff(t *testing.T) {
+	spec := MustLoad()
+	type enrollment struct {
+		ID       int    `json:"id"`
+		UserID   int    `json:"user_id"`
+		CourseID int    `json:"course_id"`
+		CohortID *int   `json:"cohort_id,omitempty"`
+		Extra    string `json:"extra,omitempty"`
+		hidden   int
+	}
+	onlySchema, onlyStruct := spec.SchemaFieldDiff("UserCourseEnrollment", enrollment{})
+	if strings.Join(onlySchema, ",") != "status" || strings.Join(onlyStruct, ",") != "extra" {
+		t.Fatalf("got %v / %v", onlySchema, onlyStruct)
+	}
+}
+
+func TestPathTemplate(t *testing.T) {
+	if got := PathTemplate("/courses/:id/series"); got != "/courses/{id}/series" {
+		t.Fatalf("got %q", got)
+	}
+}
@@ -0,0 +1,293 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"mopcare/problem"
+)
+
+// Schema is the subset of JSON Schema 2020-12 the document uses: types,
+// objects, arrays, enums, numeric and length bounds, patterns and the email
+// and date-time formats. Anything else is accepted without checking.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 types              `json:"type"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	Pattern              string             `json:"pattern"`
+	Format               string             `json:"format"`
+	Description          string             `json:"description"`
+
+	resolved bool
+	target   *Schema
+	pattern  *regexp.Regexp
+}
+
+// types accepts both "type": "string" and the 3.1 form "type": ["string", "null"].
+type types []string
+
+func (t *types) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = types{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*t = many
+	return nil
+}
+
+// ValidateRequest checks body against the request schema documented for
+// method and path, whether documented as application/json or as an RFC 7396
+// application/merge-patch+json. Paths and methods the document does not describe, and
+// operations without a request body, pass through untouched so the upstream
+// can answer them. Failures come back as a 400 problem listing every
+// violation.
+func (s *Spec) ValidateRequest(method, path string, body []byte) error {
+	template, ok := s.Route(path)
+	if !ok {
+		return nil
+	}
+	op, ok := s.Operation(method, template)
+	if !ok || op.RequestBody == nil {
+		return nil
+	}
+	media, ok := op.RequestBody.Content["application/json"]
+	if !ok {
+		media, ok = op.RequestBody.Content["application/merge-patch+json"]
+	}
+	if !ok || media.Schema == nil {
+		return nil
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if op.RequestBody.Required {
+			return problem.BadRequest(problem.CodeInvalidBody, "Request body is required")
+		}
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return problem.BadRequest(problem.CodeInvalidBody, "Request body is not valid JSON")
+	}
+	if dec.More() {
+		return problem.BadRequest(problem.CodeInvalidBody, "Request body must be a single JSON value")
+	}
+
+	if errs := media.Schema.Validate(value); len(errs) > 0 {
+		p := problem.BadRequest(problem.CodeValidationFailed, "Request body does not match the API specification")
+		p.Errors = errs
+		return p
+	}
+	return nil
+}
+
+// Validate checks a value decoded with json.Decoder.UseNumber and returns
+// one message per violation, each prefixed with the offending field.
+func (sc *Schema) Validate(value interface{}) []string {
+	var errs []string
+	sc.validate("", value, &errs)
+	return errs
+}
+
+func (sc *Schema) validate(at string, value interface{}, errs *[]string) {
+	for sc.target != nil {
+		sc = sc.target
+	}
+	fail := func(format string, args ...interface{}) {
+		field := at
+		if field == "" {
+			field = "body"
+		}
+		*errs = append(*errs, field+": "+fmt.Sprintf(format, args...))
+	}
+
+	if len(sc.Type) > 0 && !sc.Type.admits(value) {
+		fail("must be of type %s", strings.Join(sc.Type, " or "))
+		return
+	}
+	if len(sc.Enum) > 0 && !inEnum(sc.Enum, value) {
+		fail("must be one of %s", enumList(sc.Enum))
+	}
+
+	switch v := value.(type) {
+	case string:
+		n := utf8.RuneCountInString(v)
+		if sc.MinLength != nil && n < *sc.MinLength {
+			if *sc.MinLength == 1 {
+				fail("must not be empty")
+			} else {
+				fail("must be at least %d characters", *sc.MinLength)
+			}
+		}
+		if sc.MaxLength != nil && n > *sc.MaxLength {
+			fail("must be at most %d characters", *sc.MaxLength)
+		}
+		if sc.pattern != nil && !sc.pattern.MatchString(v) {
+			fail("must match %s", sc.Pattern)
+		}
+		switch sc.Format {
+		case "email":
+			if addr, err := mail.ParseAddress(v); err != nil || addr.Address != v {
+				fail("must be a valid email address")
+			}
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				fail("must be an RFC 3339 date-time")
+			}
+		}
+	case json.Number:
+		f, _ := strconv.ParseFloat(string(v), 64)
+		if sc.Minimum != nil && f < *sc.Minimum {
+			fail("must be at least %s", formatNumber(*sc.Minimum))
+		}
+		if sc.Maximum != nil && f > *sc.Maximum {
+			fail("must be at most %s", formatNumber(*sc.Maximum))
+		}
+		if sc.ExclusiveMinimum != nil && f <= *sc.ExclusiveMinimum {
+			fail("must be greater than %s", formatNumber(*sc.ExclusiveMinimum))
+		}
+		if sc.ExclusiveMaximum != nil && f >= *sc.ExclusiveMaximum {
+			fail("must be less than %s", formatNumber(*sc.ExclusiveMaximum))
+		}
+	case []interface{}:
+		if sc.MinItems != nil && len(v) < *sc.MinItems {
+			fail("must have at least %d items", *sc.MinItems)
+		}
+		if sc.MaxItems != nil && len(v) > *sc.MaxItems {
+			fail("must have at most %d items", *sc.MaxItems)
+		}
+		if sc.Items != nil {
+			for i, item := range v {
+				sc.Items.validate(fmt.Sprintf("%s[%d]", at, i), item, errs)
+			}
+		}
+	case map[string]interface{}:
+		for _, name := range sc.Required {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, join(at, name)+": is required")
+			}
+		}
+		closed := string(bytes.TrimSpace(sc.AdditionalProperties)) == "false"
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop, ok := sc.Properties[name]
+			switch {
+			case ok:
+				prop.validate(join(at, name), v[name], errs)
+			case closed:
+				*errs = append(*errs, join(at, name)+": is not a recognised field")
+			}
+		}
+	}
+}
+
+func (t types) admits(value interface{}) bool {
+	for _, name := range t {
+		switch v := value.(type) {
+		case nil:
+			if name == "null" {
+				return true
+			}
+		case bool:
+			if name == "boolean" {
+				return true
+			}
+		case string:
+			if name == "string" {
+				return true
+			}
+		case json.Number:
+			if name == "number" {
+				return true
+			}
+			if name == "integer" {
+				f, err := strconv.ParseFloat(string(v), 64)
+				if err == nil && f == math.Trunc(f) {
+					return true
+				}
+			}
+		case []interface{}:
+			if name == "array" {
+				return true
+			}
+		case map[string]interface{}:
+			if name == "object" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	for _, allowed := range enum {
+		switch a := allowed.(type) {
+		case string:
+			if s, ok := value.(string); ok && s == a {
+				return true
+			}
+		case float64:
+			if n, ok := value.(json.Number); ok {
+				if f, err := n.Float64(); err == nil && f == a {
+					return true
+				}
+			}
+		default:
+			if allowed == value {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func enumList(enum []interface{}) string {
+	parts := make([]string, len(enum))
+	for i, v := range enum {
+		raw, _ := json.Marshal(v)
+		parts[i] = string(raw)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func join(at, name string) string {
+	if at == "" {
+		return name
+	}
+	return at + "." + name
+}
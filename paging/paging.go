@@ -0,0 +1,62 @@
+// Package paging parses the limit/offset query parameters accepted by every
+// list endpoint and applies them to queries and in-memory slices.
+package paging
+
+import (
+	"fmt"
+	"strconv"
+
+	"mopcare/problem"
+)
+
+// MaxLimit caps a single page so one request cannot pull a whole table.
+const MaxLimit = 100
+
+// Page is one window of a list. A zero Limit means "no limit", which keeps
+// the unpaginated behaviour for clients that send neither parameter.
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// Parse reads the raw ?limit= and ?offset= values; empty strings take the
+// defaults. Anything else that is not a valid window is a 400 problem.
+func Parse(limit, offset string) (Page, error) {
+	var p Page
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxLimit {
+			return Page{}, problem.BadRequest(problem.CodeInvalidQuery, fmt.Sprintf("limit must be between 1 and %d", MaxLimit))
+		}
+		p.Limit = n
+	}
+	if offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return Page{}, problem.BadRequest(problem.CodeInvalidQuery, "offset must be a non-negative integer")
+		}
+		p.Offset = n
+	}
+	return p, nil
+}
+
+// LimitArg is the value to bind to a "LIMIT $n" placeholder: nil (LIMIT NULL,
+// i.e. all rows) when the page is unlimited.
+func (p Page) LimitArg() interface{} {
+	if p.Limit == 0 {
+		return nil
+	}
+	return p.Limit
+}
+
+// Slice applies p to an already ordered slice, for in-memory repositories.
+func Slice[T any](items []T, p Page) []T {
+	if p.Offset >= len(items) {
+		return nil
+	}
+	items = items[p.Offset:]
+	if p.Limit > 0 && p.Limit < len(items) {
+		items = items[:p.Limit]
+	}
+	return items
+}
@@ -0,0 +1,59 @@
+package paging
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"mopcare/problem"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		limit, offset string
+		want          Page
+		bad           bool
+	}{
+		{"", "", Page{}, false},
+		{"10", "", Page{Limit: 10}, false},
+		{"100", "20", Page{Limit: 100, Offset: 20}, false},
+		{"0", "", Page{}, true},
+		{"101", "", Page{}, true},
+		{"ten", "", Page{}, true},
+		{"", "-1", Page{}, true},
+	}
+	for _, tc := range cases {
+		got, err := Parse(tc.limit, tc.offset)
+		if tc.bad {
+			var p *problem.Problem
+			if !errors.As(err, &p) || p.Code != problem.CodeInvalidQuery {
+				t.Errorf("Parse(%q, %q) err = %v, want INVALID_QUERY", tc.limit, tc.offset, err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("Parse(%q, %q) = %+v, %v; want %+v", tc.limit, tc.offset, got, err, tc.want)
+		}
+	}
+}
+
+func TestSlice(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	cases := []struct {
+		page Page
+		want []int
+	}{
+		{Page{}, []int{1, 2, 3, 4, 5}},
+		{Page{Limit: 2}, []int{1, 2}},
+		{Page{Limit: 2, Offset: 4}, []int{5}},
+		{Page{Offset: 5}, nil},
+	}
+	for _, tc := range cases {
+		if got := Slice(items, tc.page); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Slice(%+v) = %v, want %v", tc.page, got, tc.want)
+		}
+	}
+	if (Page{}).LimitArg() != nil || (Page{Limit: 3}).LimitArg() != 3 {
+		t.Error("LimitArg")
+	}
+}
@@ -0,0 +1,62 @@
+package pdf
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestBytesIsWellFormed(t *testing.T) {
+	p := NewPage(A4Height, A4Width)
+	p.Title = "Certificate"
+	p.Rect(20, 20, A4Height-40, A4Width-40, 2)
+	p.Line(100, 100, 300, 100, 0.5)
+	p.CenteredText(300, HelveticaBold, 24, "Hello (world) \\ Zoë — ✓")
+	doc := p.Bytes()
+
+	if !bytes.HasPrefix(doc, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(doc, []byte("%%EOF\n")) {
+		t.Fatalf("document is not framed as a PDF:\n%s", doc)
+	}
+	for _, want := range []string{
+		`(Hello \(world\) \\ Zo\353 \227 ?) Tj`,
+		"/BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding",
+		"/MediaBox [0 0 841.89 595.28]",
+		"/Title (Certificate)",
+	} {
+		if !bytes.Contains(doc, []byte(want)) {
+			t.Errorf("document lacks %q", want)
+		}
+	}
+
+	// Every xref entry must point at the object it numbers.
+	start, err := strconv.Atoi(string(regexp.MustCompile(`startxref\n(\d+)`).FindSubmatch(doc)[1]))
+	if err != nil || !bytes.HasPrefix(doc[start:], []byte("xref\n")) {
+		t.Fatalf("startxref does not point at the xref table")
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(doc[start:], -1)
+	if len(entries) != 7 {
+		t.Fatalf("xref has %d objects, want 7", len(entries))
+	}
+	for i, e := range entries {
+		offset, _ := strconv.Atoi(string(e[1]))
+		if want := strconv.Itoa(i+1) + " 0 obj\n"; !bytes.HasPrefix(doc[offset:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q", i+1, doc[offset:offset+10])
+		}
+	}
+	if !bytes.Equal(doc, p.Bytes()) {
+		t.Error("rendering the same page twice gave different bytes")
+	}
+}
+
+func TestWidth(t *testing.T) {
+	if got := Width(Helvetica, 10, "Hi"); got != 9.44 {
+		t.Errorf("Width(Helvetica, 10, Hi) = %v, want 9.44", got)
+	}
+	if Width(HelveticaBold, 10, "Hi") <= Width(Helvetica, 10, "Hi") {
+		t.Error("bold text is not wider")
+	}
+	if got := Width(Helvetica, 10, "é"); got != 5.56 {
+		t.Errorf("Width of an accented letter = %v, want 5.56", got)
+	}
+}
@@ -0,0 +1,211 @@
+// Package problem implements the RFC 7807 "problem details" error model shared
+// by the gateway and every service, so clients always receive the same shape
+// and a stable machine-readable code regardless of which process failed.
+package problem
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ContentType is the media type every error response is served with.
+const ContentType = "application/problem+json"
+
+// Stable error codes. Clients switch on these, so never rename one.
+const (
+	CodeBadRequest          = "BAD_REQUEST"
+	CodeInvalidID           = "INVALID_ID"
+	CodeInvalidBody         = "INVALID_BODY"
+	CodeInvalidQuery        = "INVALID_QUERY"
+	CodeValidationFailed    = "VALIDATION_FAILED"
+	CodeUnauthorized        = "UNAUTHORIZED"
+	CodeForbidden           = "FORBIDDEN"
+	CodeNotFound            = "NOT_FOUND"
+	CodeRouteNotFound       = "ROUTE_NOT_FOUND"
+	CodeMethodNotAllowed    = "METHOD_NOT_ALLOWED"
+	CodeConflict            = "CONFLICT"
+	CodePreconditionFailed  = "PRECONDITION_FAILED"
+	CodeReferenceNotFound   = "REFERENCE_NOT_FOUND"
+	CodeInternal            = "INTERNAL_ERROR"
+	CodeUpstreamUnavailable = "UPSTREAM_UNAVAILABLE"
+	CodeTimeout             = "TIMEOUT"
+	CodeClientClosed        = "CLIENT_CLOSED_REQUEST"
+
+	CodeUserNotFound         = "USER_NOT_FOUND"
+	CodeUserEmailTaken       = "USER_EMAIL_TAKEN"
+	CodeCourseNotFound       = "COURSE_NOT_FOUND"
+	CodeCourseUniqueIDTaken  = "COURSE_UNIQUE_ID_TAKEN"
+	CodeCourseStatusConflict = "COURSE_STATUS_CONFLICT"
+	CodeSeriesNotFound       = "SERIES_NOT_FOUND"
+	CodeRevisionNotFound     = "REVISION_NOT_FOUND"
+	CodeMediaNotFound        = "MEDIA_NOT_FOUND"
+	CodeMediaUnsupportedType = "MEDIA_UNSUPPORTED_TYPE"
+	CodeMediaTooLarge        = "MEDIA_TOO_LARGE"
+	CodeSignatureInvalid     = "SIGNATURE_INVALID"
+	CodeCategoryNotFound     = "CATEGORY_NOT_FOUND"
+	CodeCategorySlugTaken    = "CATEGORY_SLUG_TAKEN"
+	CodeCategoryInUse        = "CATEGORY_IN_USE"
+	CodeInstructorNotFound   = "INSTRUCTOR_NOT_FOUND"
+	CodeLastOwner            = "LAST_OWNER"
+	CodeQuizNotFound         = "QUIZ_NOT_FOUND"
+	CodeQuizAttemptsUsed     = "QUIZ_ATTEMPTS_USED"
+	CodeNotEnrolled          = "NOT_ENROLLED"
+	CodeQuizzesNotPassed     = "QUIZZES_NOT_PASSED"
+	CodeCertificateNotFound  = "CERTIFICATE_NOT_FOUND"
+	CodeEnrollmentNotFound   = "ENROLLMENT_NOT_FOUND"
+	CodeEnrollmentDuplicate  = "ENROLLMENT_DUPLICATE"
+	CodeWebhookNotFound      = "WEBHOOK_NOT_FOUND"
+	CodeDeliveryNotFound     = "DELIVERY_NOT_FOUND"
+	CodeCohortNotFound       = "COHORT_NOT_FOUND"
+	CodeCohortNameTaken      = "COHORT_NAME_TAKEN"
+	CodePrerequisiteNotFound = "PREREQUISITE_NOT_FOUND"
+	CodePrerequisiteCycle    = "PREREQUISITE_CYCLE"
+	CodePrerequisitesMissing = "PREREQUISITES_MISSING"
+	CodeEnrollmentNotOpen    = "ENROLLMENT_NOT_OPEN"
+	CodeEnrollmentClosed     = "ENROLLMENT_CLOSED"
+	CodeCourseFull           = "COURSE_FULL"
+	CodeWaitlisted           = "WAITLISTED"
+)
+
+// Problem is a single RFC 7807 problem details document. It doubles as an
+// error so handlers and lower layers can return it directly.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Code     string `json:"code"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Errors lists individual failures when one request breaks several rules.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// New builds a problem for the given status and code. The type URI is derived
+// from the code so each code documents itself at a stable location.
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   "/problems/" + strings.ToLower(strings.ReplaceAll(code, "_", "-")),
+		Title:  http.StatusText(status),
+		Status: status,
+		Code:   code,
+		Detail: detail,
+	}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Code
+	}
+	return fmt.Sprintf("%s: %s", p.Code, p.Detail)
+}
+
+// At returns a copy of p with Instance set to the request path.
+func (p *Problem) At(instance string) *Problem {
+	cp := *p
+	cp.Instance = instance
+	return &cp
+}
+
+// Helpers for the statuses handlers produce most often.
+
+func BadRequest(code, detail string) *Problem {
+	return New(http.StatusBadRequest, code, detail)
+}
+
+func Unauthorized(code, detail string) *Problem {
+	return New(http.StatusUnauthorized, code, detail)
+}
+
+func Forbidden(code, detail string) *Problem {
+	return New(http.StatusForbidden, code, detail)
+}
+
+func NotFound(code, detail string) *Problem {
+	return New(http.StatusNotFound, code, detail)
+}
+
+func Conflict(code, detail string) *Problem {
+	return New(http.StatusConflict, code, detail)
+}
+
+func Unprocessable(code, detail string) *Problem {
+	return New(http.StatusUnprocessableEntity, code, detail)
+}
+
+func Internal() *Problem {
+	return New(http.StatusInternalServerError, CodeInternal, "An unexpected error occurred")
+}
+
+// constraintCodes maps the Postgres constraint names from database/migrations
+// to the specific codes clients expect. Constraints missing from here still
+// map to the generic CONFLICT / REFERENCE_NOT_FOUND codes.
+var constraintCodes = map[string]string{
+	"users_email_key":                               CodeUserEmailTaken,
+	"courses_unique_id_key":                         CodeCourseUniqueIDTaken,
+	"user_course_enrollments_user_id_course_id_key": CodeEnrollmentDuplicate,
+	"user_course_enrollments_user_id_fkey":          CodeUserNotFound,
+	"user_course_enrollments_course_id_fkey":        CodeCourseNotFound,
+	"series_course_id_fkey":                         CodeCourseNotFound,
+	"categories_slug_key":                           CodeCategorySlugTaken,
+	"categories_parent_id_fkey":                     CodeCategoryNotFound,
+	"courses_category_id_fkey":                      CodeCategoryNotFound,
+	"course_instructors_course_id_fkey":             CodeCourseNotFound,
+	"course_instructors_user_id_fkey":               CodeUserNotFound,
+	"quizzes_series_id_fkey":                        CodeSeriesNotFound,
+	"quiz_attempts_quiz_id_fkey":                    CodeQuizNotFound,
+	"quiz_attempts_user_id_fkey":                    CodeUserNotFound,
+	"cohorts_course_id_fkey":                        CodeCourseNotFound,
+	"cohorts_course_id_name_key":                    CodeCohortNameTaken,
+	"user_course_enrollments_cohort_id_fkey":        CodeCohortNotFound,
+	"course_prerequisites_course_id_fkey":           CodeCourseNotFound,
+	"course_prerequisites_prerequisite_id_fkey":     CodeCourseNotFound,
+}
+
+// From converts any error into a problem. Problems pass through untouched,
+// driver errors are mapped by SQLSTATE, and anything else becomes an opaque
+// 500 so no SQL or internal detail ever reaches a client. The second return
+// value reports whether err was unexpected and should be logged.
+func From(err error) (*Problem, bool) {
+	var p *Problem
+	if errors.As(err, &p) {
+		return p, false
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return NotFound(CodeNotFound, "Resource not found"), false
+	}
+	if errors.Is(err, context.Canceled) {
+		// 499 is the de facto status for a client that went away mid-request.
+		return New(499, CodeClientClosed, "Client closed the request"), false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return New(http.StatusGatewayTimeout, CodeTimeout, "The operation timed out"), true
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		code := constraintCodes[pqErr.Constraint]
+		switch pqErr.Code.Name() {
+		case "unique_violation":
+			if code == "" {
+				code = CodeConflict
+			}
+			return Conflict(code, "Resource conflicts with an existing one"), false
+		case "foreign_key_violation":
+			if code == "" {
+				code = CodeReferenceNotFound
+			}
+			return Unprocessable(code, "A referenced resource does not exist"), false
+		case "query_canceled":
+			return New(http.StatusGatewayTimeout, CodeTimeout, "The operation timed out"), true
+		case "check_violation", "not_null_violation", "invalid_text_representation", "string_data_right_truncation":
+			return Unprocessable(CodeValidationFailed, "Request violates a data constraint"), false
+		}
+	}
+	return Internal(), true
+}
@@ -0,0 +1,54 @@
+package problem
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+func TestFrom(t *testing.T) {
+	cases := []struct {
+		name       string
+		err        error
+		status     int
+		code       string
+		unexpected bool
+	}{
+		{"problem passes through", NotFound(CodeUserNotFound, "gone"), 404, CodeUserNotFound, false},
+		{"wrapped problem", fmt.Errorf("ctx: %w", Conflict(CodeConflict, "x")), 409, CodeConflict, false},
+		{"no rows", sql.ErrNoRows, 404, CodeNotFound, false},
+		{"known unique constraint", &pq.Error{Code: "23505", Constraint: "users_email_key"}, 409, CodeUserEmailTaken, false},
+		{"unknown unique constraint", &pq.Error{Code: "23505", Constraint: "other_key"}, 409, CodeConflict, false},
+		{"known foreign key", &pq.Error{Code: "23503", Constraint: "series_course_id_fkey"}, 422, CodeCourseNotFound, false},
+		{"unknown foreign key", &pq.Error{Code: "23503"}, 422, CodeReferenceNotFound, false},
+		{"check violation", &pq.Error{Code: "23514"}, 422, CodeValidationFailed, false},
+		{"query canceled", &pq.Error{Code: "57014"}, http.StatusGatewayTimeout, CodeTimeout, true},
+		{"deadline", context.DeadlineExceeded, http.StatusGatewayTimeout, CodeTimeout, true},
+		{"client gone", context.Canceled, 499, CodeClientClosed, false},
+		{"other pq error", &pq.Error{Code: "42P01", Message: `relation "users" does not exist`}, 500, CodeInternal, true},
+		{"plain error", errors.New("boom"), 500, CodeInternal, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, unexpected := From(tc.err)
+			if p.Status != tc.status || p.Code != tc.code || unexpected != tc.unexpected {
+				t.Fatalf("From(%v) = %d %s %v, want %d %s %v", tc.err, p.Status, p.Code, unexpected, tc.status, tc.code, tc.unexpected)
+			}
+			if p.Status == 500 && p.Detail != Internal().Detail {
+				t.Errorf("internal detail leaked: %q", p.Detail)
+			}
+		})
+	}
+}
+
+func TestNewDerivesType(t *testing.T) {
+	p := New(http.StatusConflict, CodeEnrollmentDuplicate, "")
+	if p.Type != "/problems/enrollment-duplicate" || p.Title != "Conflict" {
+		t.Fatalf("got type %q title %q", p.Type, p.Title)
+	}
+}
@@ -0,0 +1,133 @@
+// Package server holds the process lifecycle shared by the gateway and the
+// services: timeouts and the admin token from the environment, a readiness
+// flag for /ready, and signal-driven graceful shutdown that drains in-flight
+// requests.
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Config controls server timeouts and the shutdown sequence.
+type Config struct {
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	DrainDelay      time.Duration
+	ShutdownTimeout time.Duration
+	// AdminToken is the bearer token that marks a request as coming from an
+	// admin (see mopcare/auth). Empty means nobody is an admin.
+	AdminToken string
+	// UserTokenTTL is how long the user tokens an admin issues stay valid.
+	UserTokenTTL time.Duration
+	// BodyLimit caps request bodies, in bytes. It has to leave room for the
+	// largest media upload.
+	BodyLimit int
+}
+
+// ConfigFromEnv reads HTTP_READ_TIMEOUT, HTTP_WRITE_TIMEOUT, HTTP_IDLE_TIMEOUT,
+// SHUTDOWN_DRAIN_DELAY, SHUTDOWN_TIMEOUT and USER_TOKEN_TTL as Go durations
+// (e.g. "15s"), falling back to defaults for anything unset or invalid,
+// ADMIN_TOKEN, and HTTP_BODY_LIMIT in bytes.
+func ConfigFromEnv() Config {
+	return Config{
+		ReadTimeout:     EnvDuration("HTTP_READ_TIMEOUT", 15*time.Second),
+		WriteTimeout:    EnvDuration("HTTP_WRITE_TIMEOUT", 30*time.Second),
+		IdleTimeout:     EnvDuration("HTTP_IDLE_TIMEOUT", 60*time.Second),
+		DrainDelay:      EnvDuration("SHUTDOWN_DRAIN_DELAY", 2*time.Second),
+		ShutdownTimeout: EnvDuration("SHUTDOWN_TIMEOUT", 20*time.Second),
+		AdminToken:      os.Getenv("ADMIN_TOKEN"),
+		UserTokenTTL:    EnvDuration("USER_TOKEN_TTL", 24*time.Hour),
+		BodyLimit:       EnvInt("HTTP_BODY_LIMIT", 100<<20),
+	}
+}
+
+// EnvDuration reads key as a Go duration, logging and returning fallback if
+// it is unset, malformed or negative.
+func EnvDuration(key string, fallback time.Duration) time.Duration {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		log.Printf("Ignoring invalid %s=%q, using %s", key, raw, fallback)
+		return fallback
+	}
+	return d
+}
+
+// EnvInt reads key as a decimal integer, logging and returning fallback if
+// it is unset, malformed or negative.
+func EnvInt(key string, fallback int) int {
+	raw := os.Getenv(key)
+	if raw == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		log.Printf("Ignoring invalid %s=%q, using %d", key, raw, fallback)
+		return fallback
+	}
+	return n
+}
+
+// Readiness is the flag behind each process's /ready endpoint. It starts
+// ready and is flipped to failing as the first step of shutdown so load
+// balancers stop routing new traffic before the listener closes.
+type Readiness struct {
+	draining atomic.Bool
+}
+
+func (r *Readiness) Ready() bool { return !r.draining.Load() }
+
+func (r *Readiness) Drain() { r.draining.Store(true) }
+
+// Run starts serve and blocks until it fails or SIGINT/SIGTERM arrives. On a
+// signal it marks ready as failing, waits DrainDelay, then calls shutdown with
+// a ShutdownTimeout deadline so in-flight requests can finish. serve returning
+// http.ErrServerClosed is treated as a clean stop.
+func Run(cfg Config, ready *Readiness, serve func() error, shutdown func(context.Context) error) error {
+	errCh := make(chan error, 1)
+	go func() {
+		if err := serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+			return
+		}
+		errCh <- nil
+	}()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	select {
+	case err := <-errCh:
+		return err
+	case sig := <-sigCh:
+		log.Printf("Received %s, draining connections", sig)
+	}
+
+	ready.Drain()
+	time.Sleep(cfg.DrainDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := shutdown(ctx); err != nil {
+		return err
+	}
+	if err := <-errCh; err != nil {
+		return err
+	}
+	log.Println("Shutdown complete")
+	return nil
+}
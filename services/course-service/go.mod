@@ -6,6 +6,7 @@ require (
 	github.com/gofiber/fiber/v2 v2.52.0
 	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.10.9
+	mopcare v0.0.0-00010101000000-000000000000
 )
 
 require (
@@ -21,3 +22,5 @@ require (
 	github.com/valyala/tcplisten v1.0.0 // indirect
 	golang.org/x/sys v0.20.0 // indirect
 )
+
+replace mopcare => ../..
@@ -0,0 +1,213 @@
+package handler
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"course-service/service"
+	"mopcare/auth"
+	"mopcare/problem"
+)
+
+// requireAdmin refuses callers without the admin token.
+func (h *Handler) requireAdmin(c *fiber.Ctx, detail string) error {
+	if !auth.IsAdmin(c.Get(fiber.HeaderAuthorization), h.adminToken) {
+		return problem.Forbidden(problem.CodeForbidden, detail)
+	}
+	return nil
+}
+
+// catalogQuery parses the catalog filters of a course listing: ?category=,
+// any number of ?tag=, ?min_price=, ?max_price=, ?min_duration=,
+// ?max_duration= and ?free_preview=.
+func catalogQuery(c *fiber.Ctx) (service.CatalogQuery, error) {
+	q := service.CatalogQuery{Category: c.Query("category")}
+	for _, tag := range c.Context().QueryArgs().PeekMulti("tag") {
+		q.Tags = append(q.Tags, string(tag))
+	}
+	for _, p := range []struct {
+		name string
+		dst  **float64
+	}{{"min_price", &q.MinPrice}, {"max_price", &q.MaxPrice}} {
+		if raw := c.Query(p.name); raw != "" {
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil || v < 0 {
+				return q, problem.BadRequest(problem.CodeInvalidQuery, p.name+" must be a non-negative number")
+			}
+			*p.dst = &v
+		}
+	}
+	for _, p := range []struct {
+		name string
+		dst  **int
+	}{{"min_duration", &q.MinDuration}, {"max_duration", &q.MaxDuration}} {
+		if raw := c.Query(p.name); raw != "" {
+			v, err := strconv.Atoi(raw)
+			if err != nil || v < 0 {
+				return q, problem.BadRequest(problem.CodeInvalidQuery, p.name+" must be a non-negative number of minutes")
+			}
+			*p.dst = &v
+		}
+	}
+	if raw := c.Query("free_preview"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return q, problem.BadRequest(problem.CodeInvalidQuery, "free_preview must be true or false")
+		}
+		q.FreePreview = &v
+	}
+	return q, nil
+}
+
+// getCatalog is GET /courses with facets: one page of courses, the total
+// and counts per category, tag and free preview over every match.
+func (h *Handler) getCatalog(c *fiber.Ctx) error {
+	page, err := queryPage(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	f, err := h.filter(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	q, err := catalogQuery(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	catalog, err := h.courses.Catalog(c.UserContext(), page, f, q)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(catalog)
+}
+
+func (h *Handler) getCategories(c *fiber.Ctx) error {
+	categories, err := h.courses.ListCategories(c.UserContext())
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(categories)
+}
+
+func (h *Handler) getCategory(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid category ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	category, err := h.courses.GetCategory(c.UserContext(), id)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(category)
+}
+
+func (h *Handler) createCategory(c *fiber.Ctx) error {
+	if err := h.requireAdmin(c, "Only admins can manage categories"); err != nil {
+		return writeError(c, err)
+	}
+	var in service.CategoryInput
+	if err := c.BodyParser(&in); err != nil {
+		return writeError(c, invalidBody())
+	}
+	category, err := h.courses.CreateCategory(c.UserContext(), in)
+	if err != nil {
+		return writeError(c, err)
+	}
+	c.Location("/categories/" + strconv.Itoa(category.ID))
+	return c.Status(fiber.StatusCreated).JSON(category)
+}
+
+func (h *Handler) updateCategory(c *fiber.Ctx) error {
+	if err := h.requireAdmin(c, "Only admins can manage categories"); err != nil {
+		return writeError(c, err)
+	}
+	id, err := paramID(c, "Invalid category ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	var in service.CategoryInput
+	if err := c.BodyParser(&in); err != nil {
+		return writeError(c, invalidBody())
+	}
+	category, err := h.courses.UpdateCategory(c.UserContext(), id, in)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(category)
+}
+
+func (h *Handler) deleteCategory(c *fiber.Ctx) error {
+	if err := h.requireAdmin(c, "Only admins can manage categories"); err != nil {
+		return writeError(c, err)
+	}
+	id, err := paramID(c, "Invalid category ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	if err := h.courses.DeleteCategory(c.UserContext(), id); err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "Category deleted successfully"})
+}
+
+// getTags counts the tags on the courses the caller may see, honouring the
+// same ?status= and ?include_deleted= as GET /courses.
+func (h *Handler) getTags(c *fiber.Ctx) error {
+	f, err := h.filter(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	tags, err := h.courses.ListTags(c.UserContext(), f)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(tags)
+}
+
+// paramTag reads the :tag path parameter, which clients percent-encode.
+func paramTag(c *fiber.Ctx) (string, error) {
+	tag, err := url.PathUnescape(c.Params("tag"))
+	if err != nil {
+		return "", problem.BadRequest(problem.CodeInvalidID, "Invalid tag")
+	}
+	return tag, nil
+}
+
+// renameTag renames or, when the new name is already in use, merges a tag
+// across every course. The body is {"tag": "<new name>"}.
+func (h *Handler) renameTag(c *fiber.Ctx) error {
+	if err := h.requireAdmin(c, "Only admins can manage tags"); err != nil {
+		return writeError(c, err)
+	}
+	tag, err := paramTag(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	var in struct {
+		Tag string `json:"tag"`
+	}
+	if err := c.BodyParser(&in); err != nil {
+		return writeError(c, invalidBody())
+	}
+	rename, err := h.courses.RenameTag(c.UserContext(), tag, in.Tag)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(rename)
+}
+
+func (h *Handler) deleteTag(c *fiber.Ctx) error {
+	if err := h.requireAdmin(c, "Only admins can manage tags"); err != nil {
+		return writeError(c, err)
+	}
+	tag, err := paramTag(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	if _, err := h.courses.DeleteTag(c.UserContext(), tag); err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "Tag deleted successfully"})
+}
@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"course-service/repository"
+	"course-service/service"
+	"mopcare/problem"
+)
+
+// catalogued seeds categories Health (1) > Heart (2) and Nutrition (3), and
+// two published courses: 4 in Heart with a 30 minute free preview (5) and a
+// 45 minute series (6), and 7 in Nutrition with a 60 minute series (8).
+// Course 9 is a draft without a category.
+func catalogued(m *repository.Memory) {
+	ctx := context.Background()
+	health, heart, nutrition := 1, 2, 3
+	m.CreateCategory(ctx, &repository.Category{Name: "Health", Slug: "health"})
+	m.CreateCategory(ctx, &repository.Category{ParentID: &health, Name: "Heart", Slug: "heart"})
+	m.CreateCategory(ctx, &repository.Category{Name: "Nutrition", Slug: "nutrition"})
+	m.CreateCourse(ctx, &repository.Course{Title: "Heart Health After 65", Content: "x", UniqueID: "heart-health-after-65",
+		CategoryID: &heart, Tags: []string{"cardio", "seniors"}, Price: 20}, repository.Edit{})
+	m.CreateSeries(ctx, &repository.Series{CourseID: 4, Title: "Welcome", Duration: 30, IsFreePreview: true}, repository.Edit{})
+	m.CreateSeries(ctx, &repository.Series{CourseID: 4, Title: "Blood pressure", Duration: 45}, repository.Edit{})
+	m.CreateCourse(ctx, &repository.Course{Title: "Managing Diabetes in Your Golden Years", Content: "x", UniqueID: "managing-diabetes",
+		CategoryID: &nutrition, Tags: []string{"diabetes", "seniors"}}, repository.Edit{})
+	m.CreateSeries(ctx, &repository.Series{CourseID: 7, Title: "Basics", Duration: 60}, repository.Edit{})
+	m.CreateCourse(ctx, &repository.Course{Title: "Draft", Content: "x", UniqueID: "draft", Tags: []string{"cardio"}}, repository.Edit{})
+	m.SetStatus(4, repository.StatusPublished)
+	m.SetStatus(7, repository.StatusPublished)
+}
+
+func TestCategories(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "list", method: "GET", path: "/categories", setup: catalogued, status: 200, contains: `"name":"Health"`},
+		{name: "list empty", method: "GET", path: "/categories", status: 200, contains: `[]`},
+		{name: "get", method: "GET", path: "/categories/2", setup: catalogued, status: 200, contains: `"parent_id":1`},
+		{name: "get missing", method: "GET", path: "/categories/9", setup: catalogued, status: 404, code: problem.CodeCategoryNotFound},
+
+		{name: "create without admin", method: "POST", path: "/categories", body: `{"name":"Mobility"}`, status: 403, code: problem.CodeForbidden},
+		{name: "create", method: "POST", path: "/categories", body: `{"name":"Healthy Ageing","parent_id":1}`, setup: catalogued, admin: true,
+			status: 201, contains: `"slug":"healthy-ageing"`},
+		{name: "create missing name", method: "POST", path: "/categories", body: `{"name":" "}`, admin: true, status: 400, code: problem.CodeValidationFailed},
+		{name: "create slug taken", method: "POST", path: "/categories", body: `{"name":"Heart"}`, setup: catalogued, admin: true,
+			status: 409, code: problem.CodeCategorySlugTaken},
+		{name: "create missing parent", method: "POST", path: "/categories", body: `{"name":"x","parent_id":9}`, setup: catalogued, admin: true,
+			status: 422, code: problem.CodeCategoryNotFound},
+
+		{name: "update", method: "PUT", path: "/categories/3", body: `{"name":"Diet","parent_id":1}`, setup: catalogued, admin: true,
+			status: 200, contains: `"name":"Diet","slug":"diet"`},
+		{name: "update below itself", method: "PUT", path: "/categories/1", body: `{"name":"Health","parent_id":2}`, setup: catalogued, admin: true,
+			status: 422, code: problem.CodeValidationFailed},
+		{name: "update missing", method: "PUT", path: "/categories/9", body: `{"name":"x"}`, setup: catalogued, admin: true,
+			status: 404, code: problem.CodeCategoryNotFound},
+
+		{name: "delete with subcategories", method: "DELETE", path: "/categories/1", setup: catalogued, admin: true, status: 409, code: problem.CodeCategoryInUse},
+		{name: "delete with courses", method: "DELETE", path: "/categories/3", setup: catalogued, admin: true, status: 409, code: problem.CodeCategoryInUse},
+		{name: "delete missing", method: "DELETE", path: "/categories/1", admin: true, status: 404, code: problem.CodeCategoryNotFound},
+		{name: "delete unused", method: "DELETE", path: "/categories/1", setup: func(m *repository.Memory) {
+			m.CreateCategory(context.Background(), &repository.Category{Name: "Unused", Slug: "unused"})
+		}, admin: true, status: 200},
+	})
+}
+
+func TestCourseCatalogFields(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "create normalises tags", method: "POST", path: "/courses", admin: true, setup: catalogued,
+			body:   `{"title":"a","content":"b","category_id":2,"tags":["Seniors","  Heart   Health ","seniors"],"price":12.5}`,
+			status: 201, contains: `"category_id":2,"tags":["heart health","seniors"],"price":12.5`},
+		{name: "create missing category", method: "POST", path: "/courses", admin: true, body: `{"title":"a","content":"b","category_id":9}`,
+			status: 422, code: problem.CodeCategoryNotFound},
+		{name: "create negative price", method: "POST", path: "/courses", admin: true, body: `{"title":"a","content":"b","price":-1}`,
+			status: 400, code: problem.CodeValidationFailed},
+		{name: "create tag with comma", method: "POST", path: "/courses", admin: true, body: `{"title":"a","content":"b","tags":["a,b"]}`,
+			status: 400, code: problem.CodeValidationFailed},
+		{name: "read sums series", method: "GET", path: "/courses/4", setup: catalogued, status: 200, contains: `"duration":75,"has_free_preview":true`},
+		{name: "deleted series do not count", method: "GET", path: "/courses/4", setup: func(m *repository.Memory) {
+			catalogued(m)
+			m.DeleteSeries(context.Background(), 5)
+		}, status: 200, contains: `"duration":45,"has_free_preview":false`},
+		{name: "patch clears category", method: "PATCH", path: "/courses/4", admin: true, body: `{"category_id":null,"tags":null}`, setup: catalogued,
+			status: 200, contains: `"category_id":null,"tags":[]`},
+		{name: "series fields", method: "POST", path: "/courses/4/series", admin: true, body: `{"title":"Diet","duration":20,"is_free_preview":true}`,
+			setup: catalogued, status: 201, contains: `"duration":20,"is_free_preview":true`},
+		{name: "series negative duration", method: "PUT", path: "/series/5", admin: true, body: `{"title":"x","duration":-5}`,
+			setup: catalogued, status: 400, code: problem.CodeValidationFailed},
+	})
+}
+
+func TestCourseFilters(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "category includes subcategories", method: "GET", path: "/courses?category=health", setup: catalogued,
+			status: 200, contains: `[{"id":4,`},
+		{name: "category by id", method: "GET", path: "/courses?category=3", setup: catalogued, status: 200, contains: `[{"id":7,`},
+		{name: "unknown category", method: "GET", path: "/courses?category=mobility", setup: catalogued, status: 200, contains: `null`},
+		{name: "every tag", method: "GET", path: "/courses?tag=Seniors&tag=diabetes", setup: catalogued, status: 200, contains: `[{"id":7,`},
+		{name: "price range", method: "GET", path: "/courses?min_price=10&max_price=25", setup: catalogued, status: 200, contains: `[{"id":4,`},
+		{name: "duration", method: "GET", path: "/courses?max_duration=60", setup: catalogued, status: 200, contains: `[{"id":7,`},
+		{name: "free preview", method: "GET", path: "/courses?free_preview=true", setup: catalogued, status: 200, contains: `[{"id":4,`},
+		{name: "drafts stay hidden", method: "GET", path: "/courses?tag=cardio", setup: catalogued, status: 200, contains: `[{"id":4,`},
+		{name: "invalid price", method: "GET", path: "/courses?min_price=-1", status: 400, code: problem.CodeInvalidQuery},
+		{name: "invalid duration", method: "GET", path: "/courses?max_duration=long", status: 400, code: problem.CodeInvalidQuery},
+		{name: "invalid free preview", method: "GET", path: "/courses?free_preview=maybe", status: 400, code: problem.CodeInvalidQuery},
+	})
+}
+
+func TestCatalog(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "facets", method: "GET", path: "/courses/catalog?limit=1", setup: catalogued, status: 200,
+			contains: `"total":2,"facets":{"categories":[` +
+				`{"id":1,"parent_id":null,"name":"Health","slug":"health","count":1},` +
+				`{"id":2,"parent_id":1,"name":"Heart","slug":"heart","count":1},` +
+				`{"id":3,"parent_id":null,"name":"Nutrition","slug":"nutrition","count":1}],` +
+				`"tags":[{"tag":"seniors","count":2},{"tag":"cardio","count":1},{"tag":"diabetes","count":1}],` +
+				`"free_preview":1,"price":{"min":0,"max":20},"duration":{"min":60,"max":75}}`},
+		{name: "facets follow filters", method: "GET", path: "/courses/catalog?tag=cardio", setup: catalogued, status: 200,
+			contains: `"total":1,"facets":{"categories":[{"id":1,`},
+		{name: "admins see drafts", method: "GET", path: "/courses/catalog?tag=cardio", setup: catalogued, admin: true, status: 200,
+			contains: `"total":2`},
+		{name: "nothing matches", method: "GET", path: "/courses/catalog?category=mobility", setup: catalogued, status: 200,
+			contains: `{"courses":[],"total":0,"facets":{"categories":[],"tags":[],"free_preview":0`},
+	})
+}
+
+func TestTags(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "list", method: "GET", path: "/tags", setup: catalogued, status: 200,
+			contains: `[{"tag":"seniors","count":2},{"tag":"cardio","count":1},{"tag":"diabetes","count":1}]`},
+		{name: "list with drafts", method: "GET", path: "/tags", setup: catalogued, admin: true, status: 200, contains: `{"tag":"cardio","count":2}`},
+		{name: "rename without admin", method: "PUT", path: "/tags/cardio", body: `{"tag":"heart"}`, status: 403, code: problem.CodeForbidden},
+		{name: "rename", method: "PUT", path: "/tags/cardio", body: `{"tag":"Heart"}`, setup: catalogued, admin: true,
+			status: 200, contains: `{"from":"cardio","to":"heart","courses":2}`},
+		{name: "rename invalid", method: "PUT", path: "/tags/cardio", body: `{"tag":""}`, setup: catalogued, admin: true,
+			status: 400, code: problem.CodeValidationFailed},
+		{name: "delete", method: "DELETE", path: "/tags/heart%20health", admin: true, status: 200},
+	})
+}
+
+func TestTagChangesAreRevisions(t *testing.T) {
+	repo := repository.NewMemory()
+	catalogued(repo)
+	svc := service.NewCourseService(repo, repo, repo, repo, repo, repo, repo)
+	ctx := asAdmin
+	if r, err := svc.RenameTag(ctx, "seniors", "diabetes"); err != nil || r.Courses != 2 {
+		t.Fatalf("RenameTag = %+v, %v", r, err)
+	}
+	c, _ := repo.GetCourse(ctx, 7, repository.Filter{})
+	if len(c.Tags) != 1 || c.Tags[0] != "diabetes" || c.Version != 2 {
+		t.Fatalf("merged course = %+v", c)
+	}
+	r, err := repo.GetCourseRevision(ctx, 7, 2)
+	if err != nil || r.Changes["tags"] != (repository.Change{From: "diabetes,seniors", To: "diabetes"}) {
+		t.Fatalf("revision = %+v, %v", r, err)
+	}
+
+	if _, err := svc.RollbackCourse(ctx, 7, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	c, _ = repo.GetCourse(ctx, 7, repository.Filter{})
+	if len(c.Tags) != 2 || c.CategoryID == nil || *c.CategoryID != 3 {
+		t.Fatalf("rolled back course = %+v", c)
+	}
+}
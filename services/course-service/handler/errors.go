@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+
+	"mopcare/problem"
+)
+
+// writeError renders err as an RFC 7807 problem. Unexpected errors are logged
+// here and replaced with an opaque 500 so driver messages never leak.
+func writeError(c *fiber.Ctx, err error) error {
+	p, unexpected := problem.From(err)
+	if unexpected {
+		log.Printf("%s %s: %v", c.Method(), c.Path(), err)
+	}
+	return c.Status(p.Status).JSON(p.At(c.Path()), problem.ContentType)
+}
+
+// errorHandler is installed as Fiber's ErrorHandler so router-level failures
+// (unknown routes, wrong methods, oversized bodies) share the problem format.
+func errorHandler(c *fiber.Ctx, err error) error {
+	var fe *fiber.Error
+	if errors.As(err, &fe) {
+		switch fe.Code {
+		case http.StatusNotFound:
+			return writeError(c, problem.NotFound(problem.CodeRouteNotFound, fe.Message))
+		case http.StatusMethodNotAllowed:
+			return writeError(c, problem.New(fe.Code, problem.CodeMethodNotAllowed, fe.Message))
+		}
+		if fe.Code < http.StatusInternalServerError {
+			return writeError(c, problem.New(fe.Code, problem.CodeBadRequest, fe.Message))
+		}
+	}
+	return writeError(c, err)
+}
@@ -0,0 +1,662 @@
+// Package handler exposes the course-service over HTTP.
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"course-service/repository"
+	"course-service/service"
+	"mopcare/auth"
+	"mopcare/paging"
+	"mopcare/problem"
+	"mopcare/server"
+)
+
+type Handler struct {
+	courses    *service.CourseService
+	media      *service.MediaService
+	adminToken string
+}
+
+// NewApp builds the course-service Fiber app, including /health and /ready.
+func NewApp(courses *service.CourseService, media *service.MediaService, ready *server.Readiness, cfg server.Config) *fiber.App {
+	app := fiber.New(fiber.Config{
+		Prefork:      false, // Disabled for Docker compatibility
+		ServerHeader: "Course-Service",
+		ErrorHandler: errorHandler,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+		BodyLimit:    cfg.BodyLimit,
+	})
+
+	app.Get("/health", func(c *fiber.Ctx) error {
+		return c.JSON(fiber.Map{"service": "course-service", "status": "running"})
+	})
+	app.Get("/ready", func(c *fiber.Ctx) error {
+		if !ready.Ready() {
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{"service": "course-service", "status": "draining"})
+		}
+		return c.JSON(fiber.Map{"service": "course-service", "status": "ready"})
+	})
+
+	h := &Handler{courses: courses, media: media, adminToken: cfg.AdminToken}
+	app.Use(h.identify)
+	app.Post("/courses", h.createCourse)
+	app.Get("/courses", h.getCourses)
+	app.Get("/courses/catalog", h.getCatalog)
+	app.Get("/courses/by-slug/:slug", h.getCourseBySlug)
+	app.Get("/courses/:id", h.getCourse)
+	app.Put("/courses/:id", h.updateCourse)
+	app.Patch("/courses/:id", h.patchCourse)
+	app.Delete("/courses/:id", h.deleteCourse)
+	app.Post("/courses/:id/restore", h.restoreCourse)
+	app.Post("/courses/:id/submit", h.courseAction(h.courses.SubmitCourse))
+	app.Post("/courses/:id/reject", h.courseAction(h.courses.RejectCourse))
+	app.Post("/courses/:id/publish", h.publishCourse)
+	app.Post("/courses/:id/archive", h.courseAction(h.courses.ArchiveCourse))
+	app.Get("/courses/:id/revisions", h.getCourseRevisions)
+	app.Get("/courses/:id/revisions/diff", h.diffCourse)
+	app.Get("/courses/:id/revisions/:version", h.getCourseRevision)
+	app.Post("/courses/:id/revisions/:version/rollback", h.rollbackCourse)
+	app.Get("/courses/:id/instructors", h.getInstructors)
+	app.Put("/courses/:id/instructors/:user_id", h.setInstructor)
+	app.Delete("/courses/:id/instructors/:user_id", h.removeInstructor)
+	app.Get("/courses/:id/prerequisites", h.getPrerequisites)
+	app.Put("/courses/:id/prerequisites/:prerequisite_id", h.addPrerequisite)
+	app.Delete("/courses/:id/prerequisites/:prerequisite_id", h.removePrerequisite)
+	app.Get("/instructors/:id/courses", h.getInstructorCourses)
+
+	app.Get("/courses/:id/series", h.getSeriesForCourse)
+	app.Get("/series/:id", h.getSeriesByID)
+	app.Post("/courses/:id/series", h.createSeriesForCourse)
+	app.Put("/series/:id", h.updateSeries)
+	app.Patch("/series/:id", h.patchSeries)
+	app.Delete("/series/:id", h.deleteSeries)
+	app.Post("/series/:id/restore", h.restoreSeries)
+	app.Get("/series/:id/revisions", h.getSeriesRevisions)
+	app.Get("/series/:id/revisions/diff", h.diffSeries)
+	app.Get("/series/:id/revisions/:version", h.getSeriesRevision)
+	app.Post("/series/:id/revisions/:version/rollback", h.rollbackSeries)
+	app.Get("/series/:id/quizzes", h.getQuizzes)
+	app.Post("/series/:id/quizzes", h.createQuiz)
+	app.Get("/quizzes/:id", h.getQuiz)
+	app.Put("/quizzes/:id", h.updateQuiz)
+	app.Delete("/quizzes/:id", h.deleteQuiz)
+	app.Get("/quizzes/:id/attempts", h.getAttempts)
+	app.Post("/quizzes/:id/attempts", h.submitAttempt)
+
+	app.Post("/media", h.uploadMedia)
+	app.Get("/media/:id", h.getMedia)
+	app.Get("/media/:id/content", h.downloadMedia(false))
+	app.Get("/media/:id/thumbnail", h.downloadMedia(true))
+
+	app.Get("/categories", h.getCategories)
+	app.Post("/categories", h.createCategory)
+	app.Get("/categories/:id", h.getCategory)
+	app.Put("/categories/:id", h.updateCategory)
+	app.Delete("/categories/:id", h.deleteCategory)
+	app.Get("/tags", h.getTags)
+	app.Put("/tags/:tag", h.renameTag)
+	app.Delete("/tags/:tag", h.deleteTag)
+	return app
+}
+
+// identify records who is calling on the request context, for the service's
+// permission checks and so the revisions it writes name their author.
+// Callers without an admin or user token are anonymous.
+func (h *Handler) identify(c *fiber.Ctx) error {
+	caller := auth.Identify(c.Get(fiber.HeaderAuthorization), h.adminToken, time.Now())
+	c.SetUserContext(auth.WithCaller(c.UserContext(), caller))
+	return c.Next()
+}
+
+// paramID parses the :id path parameter into a 400 problem on failure.
+func paramID(c *fiber.Ctx, detail string) (int, error) {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return 0, problem.BadRequest(problem.CodeInvalidID, detail)
+	}
+	return id, nil
+}
+
+// paramVersion parses the :version path parameter of a revision route.
+func paramVersion(c *fiber.Ctx) (int, error) {
+	version, err := strconv.Atoi(c.Params("version"))
+	if err != nil || version < 1 {
+		return 0, problem.BadRequest(problem.CodeInvalidID, "Invalid revision version")
+	}
+	return version, nil
+}
+
+// queryDiff parses the ?from= and ?to= revision versions of a diff.
+func queryDiff(c *fiber.Ctx) (from, to int, err error) {
+	from, errFrom := strconv.Atoi(c.Query("from"))
+	to, errTo := strconv.Atoi(c.Query("to"))
+	if errFrom != nil || errTo != nil || from < 1 || to < 1 {
+		return 0, 0, problem.BadRequest(problem.CodeInvalidQuery, "from and to must be revision versions")
+	}
+	return from, to, nil
+}
+
+// queryPage parses the ?limit= and ?offset= list parameters.
+func queryPage(c *fiber.Ctx) (paging.Page, error) {
+	return paging.Parse(c.Query("limit"), c.Query("offset"))
+}
+
+// filter decides what course and series reads may return to the caller.
+// Learners only see published courses. Admins see every status, can narrow
+// to one with ?status=, and may ask for deleted rows with ?include_deleted=.
+// The service widens the filter for a course's own instructors.
+func (h *Handler) filter(c *fiber.Ctx) (repository.Filter, error) {
+	return h.statusFilter(c, auth.IsAdmin(c.Get(fiber.HeaderAuthorization), h.adminToken))
+}
+
+// statusFilter is filter, with every status visible if allStatuses is set.
+func (h *Handler) statusFilter(c *fiber.Ctx, allStatuses bool) (repository.Filter, error) {
+	authorization := c.Get(fiber.HeaderAuthorization)
+	deleted, err := auth.IncludeDeleted(c.Query("include_deleted"), authorization, h.adminToken)
+	if err != nil {
+		return repository.Filter{}, err
+	}
+	status := c.Query("status")
+	switch status {
+	case "", repository.StatusDraft, repository.StatusInReview, repository.StatusPublished, repository.StatusArchived:
+	default:
+		return repository.Filter{}, problem.BadRequest(problem.CodeInvalidQuery, "status must be draft, in_review, published or archived")
+	}
+	if !allStatuses {
+		if status != "" && status != repository.StatusPublished {
+			return repository.Filter{}, problem.Forbidden(problem.CodeForbidden, "Only published courses are visible without an admin token")
+		}
+		status = repository.StatusPublished
+	}
+	return repository.Filter{IncludeDeleted: deleted, Status: status}, nil
+}
+
+// ifMatch parses the If-Match header into the version it names. Versions
+// travel as ETags like "3"; a missing header or "*" means no precondition.
+func ifMatch(c *fiber.Ctx) (int, error) {
+	tag := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if tag == "" || tag == "*" {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(tag, "W/"), `"`))
+	if err != nil || version < 1 {
+		return 0, problem.New(http.StatusPreconditionFailed, problem.CodePreconditionFailed, "If-Match must be an ETag returned by this API")
+	}
+	return version, nil
+}
+
+func setETag(c *fiber.Ctx, version int) {
+	c.Set(fiber.HeaderETag, `"`+strconv.Itoa(version)+`"`)
+}
+
+func invalidBody() error {
+	return problem.BadRequest(problem.CodeInvalidBody, "Invalid request body")
+}
+
+func (h *Handler) createCourse(c *fiber.Ctx) error {
+	var in service.CourseInput
+	if err := c.BodyParser(&in); err != nil {
+		return writeError(c, invalidBody())
+	}
+	course, err := h.courses.CreateCourse(c.UserContext(), in)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.Status(201).JSON(course)
+}
+
+// getCourses lists published courses, or every course with the admin token.
+// Unless ?status= asks for published ones only, a user token also lists the
+// courses the user owns or co-authors, whatever their status.
+func (h *Handler) getCourses(c *fiber.Ctx) error {
+	page, err := queryPage(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	f, err := h.filter(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	if caller := auth.CallerOf(c.UserContext()); c.Query("status") == "" && caller.UserID != 0 && !caller.Admin {
+		f.Author = caller.UserID
+	}
+	q, err := catalogQuery(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	courses, err := h.courses.ListCourses(c.UserContext(), page, f, q)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(courses)
+}
+
+func (h *Handler) getCourse(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid course ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	f, err := h.filter(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	course, err := h.courses.GetCourse(c.UserContext(), id, f)
+	if err != nil {
+		return writeError(c, err)
+	}
+	setETag(c, course.Version)
+	return c.JSON(course)
+}
+
+// getCourseBySlug answers a former slug with a 301 to the current one, so
+// links to a renamed course keep working.
+func (h *Handler) getCourseBySlug(c *fiber.Ctx) error {
+	f, err := h.filter(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	course, moved, err := h.courses.GetCourseBySlug(c.UserContext(), c.Params("slug"), f)
+	if err != nil {
+		return writeError(c, err)
+	}
+	if moved {
+		location := "/courses/by-slug/" + course.UniqueID
+		if query := c.Request().URI().QueryString(); len(query) > 0 {
+			location += "?" + string(query)
+		}
+		return c.Redirect(location, fiber.StatusMovedPermanently)
+	}
+	setETag(c, course.Version)
+	return c.JSON(course)
+}
+
+func (h *Handler) updateCourse(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid course ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	version, err := ifMatch(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	var in service.CourseInput
+	if err := c.BodyParser(&in); err != nil {
+		return writeError(c, invalidBody())
+	}
+	course, err := h.courses.UpdateCourse(c.UserContext(), id, in, version)
+	if err != nil {
+		return writeError(c, err)
+	}
+	setETag(c, course.Version)
+	return c.JSON(fiber.Map{"message": "Course updated successfully"})
+}
+
+func (h *Handler) patchCourse(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid course ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	version, err := ifMatch(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	course, err := h.courses.PatchCourse(c.UserContext(), id, c.Body(), version)
+	if err != nil {
+		return writeError(c, err)
+	}
+	setETag(c, course.Version)
+	return c.JSON(course)
+}
+
+func (h *Handler) deleteCourse(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid course ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	if err := h.courses.DeleteCourse(c.UserContext(), id); err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "Course deleted successfully"})
+}
+
+func (h *Handler) restoreCourse(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid course ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	course, err := h.courses.RestoreCourse(c.UserContext(), id)
+	if err != nil {
+		return writeError(c, err)
+	}
+	setETag(c, course.Version)
+	return c.JSON(course)
+}
+
+// courseAction serves a workflow action that takes no body.
+func (h *Handler) courseAction(action func(ctx context.Context, id, ifMatch int) (repository.Course, error)) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := paramID(c, "Invalid course ID")
+		if err != nil {
+			return writeError(c, err)
+		}
+		version, err := ifMatch(c)
+		if err != nil {
+			return writeError(c, err)
+		}
+		course, err := action(c.UserContext(), id, version)
+		if err != nil {
+			return writeError(c, err)
+		}
+		setETag(c, course.Version)
+		return c.JSON(course)
+	}
+}
+
+func (h *Handler) publishCourse(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid course ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	version, err := ifMatch(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	var in service.PublishInput
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&in); err != nil {
+			return writeError(c, invalidBody())
+		}
+	}
+	course, err := h.courses.PublishCourse(c.UserContext(), id, in.PublishAt, version)
+	if err != nil {
+		return writeError(c, err)
+	}
+	setETag(c, course.Version)
+	return c.JSON(course)
+}
+
+func (h *Handler) getCourseRevisions(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid course ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	page, err := queryPage(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	f, err := h.filter(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	revisions, err := h.courses.ListCourseRevisions(c.UserContext(), id, page, f)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(revisions)
+}
+
+func (h *Handler) getCourseRevision(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid course ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	version, err := paramVersion(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	f, err := h.filter(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	revision, err := h.courses.GetCourseRevision(c.UserContext(), id, version, f)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(revision)
+}
+
+func (h *Handler) diffCourse(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid course ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	from, to, err := queryDiff(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	f, err := h.filter(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	diff, err := h.courses.DiffCourse(c.UserContext(), id, from, to, f)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(diff)
+}
+
+func (h *Handler) rollbackCourse(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid course ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	version, err := paramVersion(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	match, err := ifMatch(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	course, err := h.courses.RollbackCourse(c.UserContext(), id, version, match)
+	if err != nil {
+		return writeError(c, err)
+	}
+	setETag(c, course.Version)
+	return c.JSON(course)
+}
+
+func (h *Handler) getSeriesForCourse(c *fiber.Ctx) error {
+	courseID, err := paramID(c, "Invalid course ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	page, err := queryPage(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	f, err := h.filter(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	seriesList, err := h.courses.ListSeries(c.UserContext(), courseID, page, f)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(seriesList)
+}
+
+func (h *Handler) getSeriesByID(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid series ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	f, err := h.filter(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	s, err := h.courses.GetSeries(c.UserContext(), id, f)
+	if err != nil {
+		return writeError(c, err)
+	}
+	setETag(c, s.Version)
+	return c.JSON(s)
+}
+
+func (h *Handler) createSeriesForCourse(c *fiber.Ctx) error {
+	courseID, err := paramID(c, "Invalid course ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	var in service.SeriesInput
+	if err := c.BodyParser(&in); err != nil {
+		return writeError(c, invalidBody())
+	}
+	series, err := h.courses.CreateSeries(c.UserContext(), courseID, in)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.Status(201).JSON(series)
+}
+
+func (h *Handler) updateSeries(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid series ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	version, err := ifMatch(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	var in service.SeriesInput
+	if err := c.BodyParser(&in); err != nil {
+		return writeError(c, invalidBody())
+	}
+	series, err := h.courses.UpdateSeries(c.UserContext(), id, in, version)
+	if err != nil {
+		return writeError(c, err)
+	}
+	setETag(c, series.Version)
+	return c.JSON(fiber.Map{"message": "Series updated successfully"})
+}
+
+func (h *Handler) patchSeries(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid series ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	version, err := ifMatch(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	series, err := h.courses.PatchSeries(c.UserContext(), id, c.Body(), version)
+	if err != nil {
+		return writeError(c, err)
+	}
+	setETag(c, series.Version)
+	return c.JSON(series)
+}
+
+func (h *Handler) deleteSeries(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid series ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	if err := h.courses.DeleteSeries(c.UserContext(), id); err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "Series deleted successfully"})
+}
+
+func (h *Handler) restoreSeries(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid series ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	series, err := h.courses.RestoreSeries(c.UserContext(), id)
+	if err != nil {
+		return writeError(c, err)
+	}
+	setETag(c, series.Version)
+	return c.JSON(series)
+}
+
+func (h *Handler) getSeriesRevisions(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid series ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	page, err := queryPage(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	f, err := h.filter(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	revisions, err := h.courses.ListSeriesRevisions(c.UserContext(), id, page, f)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(revisions)
+}
+
+func (h *Handler) getSeriesRevision(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid series ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	version, err := paramVersion(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	f, err := h.filter(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	revision, err := h.courses.GetSeriesRevision(c.UserContext(), id, version, f)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(revision)
+}
+
+func (h *Handler) diffSeries(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid series ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	from, to, err := queryDiff(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	f, err := h.filter(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	diff, err := h.courses.DiffSeries(c.UserContext(), id, from, to, f)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(diff)
+}
+
+func (h *Handler) rollbackSeries(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid series ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	version, err := paramVersion(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	match, err := ifMatch(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	series, err := h.courses.RollbackSeries(c.UserContext(), id, version, match)
+	if err != nil {
+		return writeError(c, err)
+	}
+	setETag(c, series.Version)
+	return c.JSON(series)
+}
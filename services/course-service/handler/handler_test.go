@@ -0,0 +1,534 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"course-service/repository"
+	"course-service/service"
+	"mopcare/auth"
+	"mopcare/events"
+	"mopcare/media"
+	"mopcare/problem"
+	"mopcare/server"
+)
+
+type testCase struct {
+	name     string
+	method   string
+	path     string
+	body     string
+	setup    func(*repository.Memory)
+	ifMatch  string // sent as the If-Match header when set
+	admin    bool   // send the admin token
+	status   int
+	code     string // expected problem code; empty for success responses
+	contains string // substring expected in a success body
+	etag     string // expected ETag header when set
+	user     int    // send a token for this user
+}
+
+// seeded creates published course 1 with series 2.
+func seeded(m *repository.Memory) {
+	ctx := context.Background()
+	m.CreateCourse(ctx, &repository.Course{Title: "Heart Health After 65", Content: "Essential cardiovascular care", UniqueID: "heart-health-after-65"}, repository.Edit{})
+	m.CreateSeries(ctx, &repository.Series{CourseID: 1, Title: "Blood pressure", Description: "Basics"}, repository.Edit{})
+	m.SetStatus(1, repository.StatusPublished)
+}
+
+// inStatus is seeded with course 1 moved to status.
+func inStatus(status string) func(*repository.Memory) {
+	return func(m *repository.Memory) {
+		seeded(m)
+		m.SetStatus(1, status)
+	}
+}
+
+// deleted is seeded with course 1 (and so series 2) soft-deleted.
+func deleted(m *repository.Memory) {
+	seeded(m)
+	m.DeleteCourse(context.Background(), 1)
+}
+
+const adminToken = "admin-secret"
+
+// asAdmin is the context the handlers give the service on admin requests.
+var asAdmin = auth.WithCaller(context.Background(), auth.Caller{Admin: true})
+
+// newApp serves repo with media kept in a temporary directory. Uploads are
+// capped at 64 KiB for images and 1 KiB for videos.
+func newApp(t *testing.T, repo *repository.Memory) *fiber.App {
+	t.Helper()
+	return newAppWithSigner(t, repo, media.NewSigner([]byte("test-key"), time.Hour))
+}
+
+func newAppWithSigner(t *testing.T, repo *repository.Memory, signer *media.Signer) *fiber.App {
+	t.Helper()
+	mediaService := service.NewMediaService(repo, media.NewLocal(t.TempDir()), signer, service.MediaLimits{Image: 64 << 10, Video: 1 << 10})
+	return NewApp(service.NewCourseService(repo, repo, repo, repo, repo, repo, repo), mediaService, &server.Readiness{}, server.Config{AdminToken: adminToken})
+}
+
+func failWith(err error) func(*repository.Memory) {
+	return func(m *repository.Memory) {
+		seeded(m)
+		m.Err = err
+	}
+}
+
+func runCases(t *testing.T, cases []testCase) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := repository.NewMemory()
+			if tc.setup != nil {
+				tc.setup(repo)
+			}
+			app := newApp(t, repo)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.method == "PATCH" {
+				req.Header.Set("Content-Type", "application/merge-patch+json")
+			}
+			if tc.admin {
+				req.Header.Set("Authorization", "Bearer "+adminToken)
+			}
+			if tc.user != 0 {
+				req.Header.Set("Authorization", "Bearer "+auth.UserToken(tc.user, time.Now().Add(time.Hour), adminToken))
+			}
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+
+			if resp.StatusCode != tc.status {
+				t.Fatalf("status = %d, want %d (body %s)", resp.StatusCode, tc.status, body)
+			}
+			if tc.code != "" {
+				if ct := resp.Header.Get("Content-Type"); ct != problem.ContentType {
+					t.Errorf("Content-Type = %q, want %q", ct, problem.ContentType)
+				}
+				var p problem.Problem
+				if err := json.Unmarshal(body, &p); err != nil {
+					t.Fatalf("decoding problem: %v", err)
+				}
+				if p.Code != tc.code {
+					t.Errorf("code = %q, want %q", p.Code, tc.code)
+				}
+			}
+			if tc.contains != "" && !strings.Contains(string(body), tc.contains) {
+				t.Errorf("body %s does not contain %q", body, tc.contains)
+			}
+			if tc.etag != "" && resp.Header.Get("ETag") != tc.etag {
+				t.Errorf("ETag = %q, want %q", resp.Header.Get("ETag"), tc.etag)
+			}
+		})
+	}
+}
+
+var errDB = errors.New("connection reset")
+
+func TestCourses(t *testing.T) {
+	const valid = `{"title":"Managing Diabetes","content":"A guide"}`
+	runCases(t, []testCase{
+		{name: "create", method: "POST", path: "/courses", admin: true, body: valid, status: 201, contains: `"id":1`},
+		{name: "create malformed body", method: "POST", path: "/courses", admin: true, body: `{`, status: 400, code: problem.CodeInvalidBody},
+		{name: "create missing content", method: "POST", path: "/courses", admin: true, body: `{"title":"x"}`, status: 400, code: problem.CodeValidationFailed},
+		{name: "create duplicate unique_id", method: "POST", path: "/courses", admin: true, body: `{"title":"a","content":"b","unique_id":"dup"}`,
+			setup: func(m *repository.Memory) {
+				m.CreateCourse(context.Background(), &repository.Course{Title: "a", Content: "b", UniqueID: "dup"}, repository.Edit{})
+			}, status: 409, code: problem.CodeCourseUniqueIDTaken},
+		{name: "create repository error", method: "POST", path: "/courses", admin: true, body: valid, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+
+		{name: "list", method: "GET", path: "/courses", setup: seeded, status: 200, contains: "Heart Health"},
+		{name: "list repository error", method: "GET", path: "/courses", setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+		{name: "list page", method: "GET", path: "/courses?limit=1", setup: func(m *repository.Memory) {
+			seeded(m)
+			m.CreateCourse(context.Background(), &repository.Course{Title: "Second", Content: "x"}, repository.Edit{})
+		}, status: 200, contains: `[{"id":1,`},
+		{name: "list invalid limit", method: "GET", path: "/courses?limit=1000", status: 400, code: problem.CodeInvalidQuery},
+
+		{name: "get", method: "GET", path: "/courses/1", setup: seeded, status: 200, contains: `"title":"Heart Health After 65"`, etag: `"1"`},
+		{name: "get invalid id", method: "GET", path: "/courses/x", status: 400, code: problem.CodeInvalidID},
+		{name: "get missing", method: "GET", path: "/courses/9", status: 404, code: problem.CodeCourseNotFound},
+		{name: "get repository error", method: "GET", path: "/courses/1", setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+
+		{name: "update", method: "PUT", path: "/courses/1", admin: true, body: valid, setup: seeded, status: 200, contains: "updated", etag: `"2"`},
+		{name: "update invalid id", method: "PUT", path: "/courses/x", admin: true, body: valid, status: 400, code: problem.CodeInvalidID},
+		{name: "update malformed body", method: "PUT", path: "/courses/1", admin: true, body: `{`, setup: seeded, status: 400, code: problem.CodeInvalidBody},
+		{name: "update missing content", method: "PUT", path: "/courses/1", admin: true, body: `{"title":"x"}`, setup: seeded, status: 400, code: problem.CodeValidationFailed},
+		{name: "update missing", method: "PUT", path: "/courses/9", admin: true, body: valid, status: 404, code: problem.CodeCourseNotFound},
+		{name: "update current version", method: "PUT", path: "/courses/1", admin: true, body: valid, ifMatch: `"1"`, setup: seeded, status: 200, etag: `"2"`},
+		{name: "update stale version", method: "PUT", path: "/courses/1", admin: true, body: valid, ifMatch: `"3"`, setup: seeded, status: 412, code: problem.CodePreconditionFailed},
+		{name: "update unparseable if-match", method: "PUT", path: "/courses/1", admin: true, body: valid, ifMatch: `abc`, setup: seeded, status: 412, code: problem.CodePreconditionFailed},
+		{name: "update repository error", method: "PUT", path: "/courses/1", admin: true, body: valid, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+
+		{name: "patch keeps other fields", method: "PATCH", path: "/courses/1", admin: true, body: `{"cover_image_url":"https://cdn.example.com/c.png"}`, setup: seeded, status: 200,
+			contains: `"title":"Heart Health After 65","content":"Essential cardiovascular care","overview_video_url":"","cover_image_url":"https://cdn.example.com/c.png"`, etag: `"2"`},
+		{name: "patch null clears optional field", method: "PATCH", path: "/courses/1", admin: true, body: `{"cover_image_url":null}`, setup: func(m *repository.Memory) {
+			m.CreateCourse(context.Background(), &repository.Course{Title: "a", Content: "b", CoverImageURL: "https://cdn.example.com/c.png", UniqueID: "u-1"}, repository.Edit{})
+		}, status: 200, contains: `"cover_image_url":"",`},
+		{name: "patch null required field", method: "PATCH", path: "/courses/1", admin: true, body: `{"title":null}`, setup: seeded, status: 400, code: problem.CodeValidationFailed},
+		{name: "patch unknown field", method: "PATCH", path: "/courses/1", admin: true, body: `{"rating":5}`, setup: seeded, status: 400, code: problem.CodeValidationFailed},
+		{name: "patch wrong type", method: "PATCH", path: "/courses/1", admin: true, body: `{"title":5}`, setup: seeded, status: 400, code: problem.CodeValidationFailed},
+		{name: "patch not an object", method: "PATCH", path: "/courses/1", admin: true, body: `[1]`, setup: seeded, status: 400, code: problem.CodeInvalidBody},
+		{name: "patch malformed body", method: "PATCH", path: "/courses/1", admin: true, body: `{`, setup: seeded, status: 400, code: problem.CodeInvalidBody},
+		{name: "patch missing", method: "PATCH", path: "/courses/9", admin: true, body: `{}`, status: 404, code: problem.CodeCourseNotFound},
+		{name: "patch current version", method: "PATCH", path: "/courses/1", admin: true, body: `{"title":"New"}`, ifMatch: `W/"1"`, setup: seeded, status: 200, contains: `"version":2`, etag: `"2"`},
+		{name: "patch stale version", method: "PATCH", path: "/courses/1", admin: true, body: `{"title":"New"}`, ifMatch: `"2"`, setup: seeded, status: 412, code: problem.CodePreconditionFailed},
+		{name: "patch repository error", method: "PATCH", path: "/courses/1", admin: true, body: `{}`, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+		{name: "patch capacity and window", method: "PATCH", path: "/courses/1", admin: true, setup: seeded, status: 200,
+			body:     `{"capacity":20,"enrollment_opens_at":"2027-01-01T00:00:00Z","enrollment_closes_at":"2027-02-01T00:00:00Z"}`,
+			contains: `"capacity":20,"enrollment_opens_at":"2027-01-01T00:00:00Z","enrollment_closes_at":"2027-02-01T00:00:00Z"`},
+		{name: "patch capacity below one", method: "PATCH", path: "/courses/1", admin: true, body: `{"capacity":0}`, setup: seeded, status: 400, code: problem.CodeValidationFailed},
+		{name: "patch window closing before it opens", method: "PATCH", path: "/courses/1", admin: true, setup: seeded, status: 400, code: problem.CodeValidationFailed,
+			body: `{"enrollment_opens_at":"2027-02-01T00:00:00Z","enrollment_closes_at":"2027-01-01T00:00:00Z"}`},
+
+		{name: "delete", method: "DELETE", path: "/courses/1", admin: true, setup: seeded, status: 200, contains: "deleted"},
+		{name: "delete invalid id", method: "DELETE", path: "/courses/x", admin: true, status: 400, code: problem.CodeInvalidID},
+		{name: "delete repository error", method: "DELETE", path: "/courses/1", admin: true, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+
+		{name: "deleted is hidden", method: "GET", path: "/courses/1", setup: deleted, status: 404, code: problem.CodeCourseNotFound},
+		{name: "deleted is listed for admins", method: "GET", path: "/courses?include_deleted=true", admin: true, setup: deleted, status: 200, contains: `"deleted_at":`},
+		{name: "deleted is shown to admins", method: "GET", path: "/courses/1?include_deleted=true", admin: true, setup: deleted, status: 200, contains: `"deleted_at":`},
+		{name: "include deleted needs admin", method: "GET", path: "/courses?include_deleted=true", setup: deleted, status: 403, code: problem.CodeForbidden},
+		{name: "include deleted invalid", method: "GET", path: "/courses?include_deleted=yes", admin: true, status: 400, code: problem.CodeInvalidQuery},
+		{name: "update deleted", method: "PUT", path: "/courses/1", admin: true, body: valid, setup: deleted, status: 404, code: problem.CodeCourseNotFound},
+		{name: "patch deleted", method: "PATCH", path: "/courses/1", admin: true, body: `{}`, setup: deleted, status: 404, code: problem.CodeCourseNotFound},
+
+		{name: "restore", method: "POST", path: "/courses/1/restore", admin: true, setup: deleted, status: 200, contains: `"version":3`, etag: `"3"`},
+		{name: "restore live course", method: "POST", path: "/courses/1/restore", admin: true, setup: seeded, status: 200, contains: `"version":1`},
+		{name: "restore missing", method: "POST", path: "/courses/9/restore", admin: true, status: 404, code: problem.CodeCourseNotFound},
+		{name: "restore invalid id", method: "POST", path: "/courses/x/restore", admin: true, status: 400, code: problem.CodeInvalidID},
+		{name: "restore repository error", method: "POST", path: "/courses/1/restore", admin: true, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
+
+// renamed is seeded with course 1 moved from its generated slug to a custom
+// one, and course 2 holding "heart-health-after-65-2".
+func renamed(m *repository.Memory) {
+	seeded(m)
+	ctx := context.Background()
+	m.UpdateCourse(ctx, &repository.Course{ID: 1, Title: "Heart Health After 65", Content: "Essential cardiovascular care", UniqueID: "heart-health"}, repository.Edit{})
+	m.CreateCourse(ctx, &repository.Course{Title: "Heart Health After 65", Content: "Part two", UniqueID: "heart-health-after-65-2"}, repository.Edit{})
+	m.SetStatus(3, repository.StatusPublished)
+}
+
+func TestSlugs(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "generated from title", method: "POST", path: "/courses", admin: true, body: `{"title":"Managing Diabetes: A Guide!","content":"x"}`, status: 201, contains: `"unique_id":"managing-diabetes-a-guide"`},
+		{name: "generated with suffix", method: "POST", path: "/courses", admin: true, body: `{"title":"Heart health after 65","content":"x"}`, setup: seeded, status: 201, contains: `"unique_id":"heart-health-after-65-2"`},
+		{name: "suffix skips taken ones", method: "POST", path: "/courses", admin: true, body: `{"title":"Heart health after 65","content":"x"}`, setup: renamed, status: 201, contains: `"unique_id":"heart-health-after-65-3"`},
+		{name: "title without letters", method: "POST", path: "/courses", admin: true, body: `{"title":"???","content":"x"}`, status: 201, contains: `"unique_id":"course"`},
+		{name: "custom slug", method: "POST", path: "/courses", admin: true, body: `{"title":"a","content":"b","unique_id":"intro-101"}`, status: 201, contains: `"unique_id":"intro-101"`},
+		{name: "custom slug uppercase", method: "POST", path: "/courses", admin: true, body: `{"title":"a","content":"b","unique_id":"Intro"}`, status: 400, code: problem.CodeValidationFailed},
+		{name: "custom slug double hyphen", method: "POST", path: "/courses", admin: true, body: `{"title":"a","content":"b","unique_id":"a--b"}`, status: 400, code: problem.CodeValidationFailed},
+		{name: "custom slug too long", method: "POST", path: "/courses", admin: true, body: `{"title":"a","content":"b","unique_id":"` + strings.Repeat("a", 101) + `"}`, status: 400, code: problem.CodeValidationFailed},
+		{name: "former slug is reserved", method: "POST", path: "/courses", admin: true, body: `{"title":"a","content":"b","unique_id":"heart-health-after-65"}`, setup: renamed, status: 409, code: problem.CodeCourseUniqueIDTaken},
+		{name: "own former slug can be reclaimed", method: "PATCH", path: "/courses/1", admin: true, body: `{"unique_id":"heart-health-after-65"}`, setup: renamed, status: 200, contains: `"unique_id":"heart-health-after-65"`},
+		{name: "patch null regenerates", method: "PATCH", path: "/courses/1", admin: true, body: `{"title":"Blood Pressure","unique_id":null}`, setup: seeded, status: 200, contains: `"unique_id":"blood-pressure"`},
+
+		{name: "by slug", method: "GET", path: "/courses/by-slug/heart-health-after-65", setup: seeded, status: 200, contains: `"id":1`, etag: `"1"`},
+		{name: "by former slug redirects", method: "GET", path: "/courses/by-slug/heart-health-after-65", setup: renamed, status: 301},
+		{name: "by slug missing", method: "GET", path: "/courses/by-slug/nope", setup: seeded, status: 404, code: problem.CodeCourseNotFound},
+		{name: "by slug of draft", method: "GET", path: "/courses/by-slug/heart-health-after-65", setup: inStatus(repository.StatusDraft), status: 404, code: problem.CodeCourseNotFound},
+		{name: "by slug of deleted for admins", method: "GET", path: "/courses/by-slug/heart-health-after-65?include_deleted=true", admin: true, setup: deleted, status: 200, contains: `"deleted_at":`},
+		{name: "by slug repository error", method: "GET", path: "/courses/by-slug/x", setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
+
+func TestFormerSlugRedirect(t *testing.T) {
+	repo := repository.NewMemory()
+	renamed(repo)
+	app := newApp(t, repo)
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/courses/by-slug/heart-health-after-65?include_deleted=false", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 301 || resp.Header.Get("Location") != "/courses/by-slug/heart-health?include_deleted=false" {
+		t.Fatalf("redirect = %d to %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+}
+
+func TestSeries(t *testing.T) {
+	const valid = `{"title":"Diet","description":"What to eat"}`
+	runCases(t, []testCase{
+		{name: "list for course", method: "GET", path: "/courses/1/series", setup: seeded, status: 200, contains: "Blood pressure"},
+		{name: "list invalid id", method: "GET", path: "/courses/x/series", status: 400, code: problem.CodeInvalidID},
+		{name: "list offset", method: "GET", path: "/courses/1/series?offset=1", setup: seeded, status: 200, contains: `null`},
+		{name: "list invalid offset", method: "GET", path: "/courses/1/series?offset=x", status: 400, code: problem.CodeInvalidQuery},
+		{name: "list repository error", method: "GET", path: "/courses/1/series", setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+
+		{name: "get", method: "GET", path: "/series/2", setup: seeded, status: 200, contains: `"course_id":1`},
+		{name: "get invalid id", method: "GET", path: "/series/x", status: 400, code: problem.CodeInvalidID},
+		{name: "get missing", method: "GET", path: "/series/9", status: 404, code: problem.CodeSeriesNotFound},
+		{name: "get repository error", method: "GET", path: "/series/2", setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+
+		{name: "create", method: "POST", path: "/courses/1/series", admin: true, body: valid, setup: seeded, status: 201, contains: `"title":"Diet"`},
+		{name: "create invalid id", method: "POST", path: "/courses/x/series", admin: true, body: valid, status: 400, code: problem.CodeInvalidID},
+		{name: "create malformed body", method: "POST", path: "/courses/1/series", admin: true, body: `{`, setup: seeded, status: 400, code: problem.CodeInvalidBody},
+		{name: "create missing title", method: "POST", path: "/courses/1/series", admin: true, body: `{}`, setup: seeded, status: 400, code: problem.CodeValidationFailed},
+		{name: "create unknown course", method: "POST", path: "/courses/9/series", admin: true, body: valid, status: 422, code: problem.CodeCourseNotFound},
+		{name: "create in deleted course", method: "POST", path: "/courses/1/series", admin: true, body: valid, setup: deleted, status: 422, code: problem.CodeCourseNotFound},
+		{name: "create repository error", method: "POST", path: "/courses/1/series", admin: true, body: valid, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+
+		{name: "update", method: "PUT", path: "/series/2", admin: true, body: valid, setup: seeded, status: 200, contains: "updated", etag: `"2"`},
+		{name: "update invalid id", method: "PUT", path: "/series/x", admin: true, body: valid, status: 400, code: problem.CodeInvalidID},
+		{name: "update malformed body", method: "PUT", path: "/series/2", admin: true, body: `{`, setup: seeded, status: 400, code: problem.CodeInvalidBody},
+		{name: "update missing title", method: "PUT", path: "/series/2", admin: true, body: `{}`, setup: seeded, status: 400, code: problem.CodeValidationFailed},
+		{name: "update missing", method: "PUT", path: "/series/9", admin: true, body: valid, status: 404, code: problem.CodeSeriesNotFound},
+		{name: "update stale version", method: "PUT", path: "/series/2", admin: true, body: valid, ifMatch: `"7"`, setup: seeded, status: 412, code: problem.CodePreconditionFailed},
+
+		{name: "patch", method: "PATCH", path: "/series/2", admin: true, body: `{"description":null}`, setup: seeded, status: 200,
+			contains: `"title":"Blood pressure","description":""`, etag: `"2"`},
+		{name: "patch empty title", method: "PATCH", path: "/series/2", admin: true, body: `{"title":""}`, setup: seeded, status: 400, code: problem.CodeValidationFailed},
+		{name: "patch missing", method: "PATCH", path: "/series/9", admin: true, body: `{}`, status: 404, code: problem.CodeSeriesNotFound},
+		{name: "patch stale version", method: "PATCH", path: "/series/2", admin: true, body: `{}`, ifMatch: `"2"`, setup: seeded, status: 412, code: problem.CodePreconditionFailed},
+		{name: "update repository error", method: "PUT", path: "/series/2", admin: true, body: valid, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+
+		{name: "delete", method: "DELETE", path: "/series/2", admin: true, setup: seeded, status: 200, contains: "deleted"},
+		{name: "delete invalid id", method: "DELETE", path: "/series/x", admin: true, status: 400, code: problem.CodeInvalidID},
+		{name: "delete repository error", method: "DELETE", path: "/series/2", admin: true, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+
+		{name: "deleted with course", method: "GET", path: "/series/2", setup: deleted, status: 404, code: problem.CodeSeriesNotFound},
+		{name: "deleted shown to admins", method: "GET", path: "/series/2?include_deleted=true", admin: true, setup: deleted, status: 200, contains: `"deleted_at":`},
+		{name: "list deleted for admins", method: "GET", path: "/courses/1/series?include_deleted=true", admin: true, setup: deleted, status: 200, contains: `"deleted_at":`},
+		{name: "list deleted needs admin", method: "GET", path: "/courses/1/series?include_deleted=1", setup: deleted, status: 403, code: problem.CodeForbidden},
+		{name: "restore", method: "POST", path: "/series/2/restore", admin: true, setup: func(m *repository.Memory) {
+			seeded(m)
+			m.DeleteSeries(context.Background(), 2)
+		}, status: 200, contains: `"title":"Blood pressure"`},
+		{name: "restore with deleted course", method: "POST", path: "/series/2/restore", admin: true, setup: deleted, status: 422, code: problem.CodeCourseNotFound},
+		{name: "restore missing", method: "POST", path: "/series/9/restore", admin: true, status: 404, code: problem.CodeSeriesNotFound},
+	})
+}
+
+func TestWorkflow(t *testing.T) {
+	const later = `{"publish_at":"2099-01-01T00:00:00Z"}`
+	draft, inReview, archived := inStatus(repository.StatusDraft), inStatus(repository.StatusInReview), inStatus(repository.StatusArchived)
+	runCases(t, []testCase{
+		{name: "created as draft", method: "POST", path: "/courses", admin: true, body: `{"title":"a","content":"b"}`, status: 201, contains: `"status":"draft"`},
+		{name: "draft hidden", method: "GET", path: "/courses/1", setup: draft, status: 404, code: problem.CodeCourseNotFound},
+		{name: "draft not listed", method: "GET", path: "/courses", setup: draft, status: 200, contains: `null`},
+		{name: "draft series hidden", method: "GET", path: "/series/2", setup: draft, status: 404, code: problem.CodeSeriesNotFound},
+		{name: "draft series not listed", method: "GET", path: "/courses/1/series", setup: draft, status: 200, contains: `null`},
+		{name: "archived hidden", method: "GET", path: "/courses/1", setup: archived, status: 404, code: problem.CodeCourseNotFound},
+		{name: "draft shown to admins", method: "GET", path: "/courses/1", admin: true, setup: draft, status: 200, contains: `"status":"draft"`},
+		{name: "admins filter by status", method: "GET", path: "/courses?status=draft", admin: true, setup: draft, status: 200, contains: "Heart Health"},
+		{name: "status filter excludes others", method: "GET", path: "/courses?status=archived", admin: true, setup: draft, status: 200, contains: `null`},
+		{name: "status filter needs admin", method: "GET", path: "/courses?status=draft", setup: draft, status: 403, code: problem.CodeForbidden},
+		{name: "published filter is public", method: "GET", path: "/courses?status=published", setup: seeded, status: 200, contains: "Heart Health"},
+		{name: "unknown status", method: "GET", path: "/courses?status=live", setup: seeded, status: 400, code: problem.CodeInvalidQuery},
+
+		{name: "submit", method: "POST", path: "/courses/1/submit", admin: true, setup: draft, status: 200, contains: `"status":"in_review"`, etag: `"2"`},
+		{name: "submit published", method: "POST", path: "/courses/1/submit", admin: true, setup: seeded, status: 409, code: problem.CodeCourseStatusConflict},
+		{name: "reject", method: "POST", path: "/courses/1/reject", admin: true, setup: inReview, status: 200, contains: `"status":"draft"`},
+		{name: "reject draft", method: "POST", path: "/courses/1/reject", admin: true, setup: draft, status: 409, code: problem.CodeCourseStatusConflict},
+		{name: "publish now", method: "POST", path: "/courses/1/publish", admin: true, setup: inReview, status: 200, contains: `"published_at":`},
+		{name: "publish straight from draft", method: "POST", path: "/courses/1/publish", admin: true, body: `{}`, setup: draft, status: 200, contains: `"status":"published"`},
+		{name: "publish in the past is now", method: "POST", path: "/courses/1/publish", admin: true, body: `{"publish_at":"2000-01-01T00:00:00Z"}`, setup: draft, status: 200, contains: `"status":"published"`},
+		{name: "schedule", method: "POST", path: "/courses/1/publish", admin: true, body: later, setup: inReview, status: 200, contains: `"publish_at":"2099-01-01T00:00:00Z"`},
+		{name: "scheduled stays unpublished", method: "POST", path: "/courses/1/publish", admin: true, body: later, setup: draft, status: 200, contains: `"status":"draft"`},
+		{name: "publish published", method: "POST", path: "/courses/1/publish", admin: true, setup: seeded, status: 409, code: problem.CodeCourseStatusConflict},
+		{name: "publish malformed body", method: "POST", path: "/courses/1/publish", admin: true, body: `{`, setup: draft, status: 400, code: problem.CodeInvalidBody},
+		{name: "publish stale version", method: "POST", path: "/courses/1/publish", admin: true, ifMatch: `"4"`, setup: draft, status: 412, code: problem.CodePreconditionFailed},
+		{name: "republish archived", method: "POST", path: "/courses/1/publish", admin: true, setup: archived, status: 200, contains: `"status":"published"`},
+		{name: "archive", method: "POST", path: "/courses/1/archive", admin: true, ifMatch: `"1"`, setup: seeded, status: 200, contains: `"status":"archived"`, etag: `"2"`},
+		{name: "archive archived", method: "POST", path: "/courses/1/archive", admin: true, setup: archived, status: 409, code: problem.CodeCourseStatusConflict},
+		{name: "action on missing course", method: "POST", path: "/courses/9/submit", admin: true, status: 404, code: problem.CodeCourseNotFound},
+		{name: "action on deleted course", method: "POST", path: "/courses/1/archive", admin: true, setup: deleted, status: 404, code: problem.CodeCourseNotFound},
+		{name: "action invalid id", method: "POST", path: "/courses/x/archive", admin: true, status: 400, code: problem.CodeInvalidID},
+		{name: "action repository error", method: "POST", path: "/courses/1/submit", admin: true, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
+
+func TestScheduledPublishing(t *testing.T) {
+	ctx := asAdmin
+	repo := repository.NewMemory()
+	seeded(repo)
+	repo.SetStatus(1, repository.StatusInReview)
+	svc := service.NewCourseService(repo, repo, repo, repo, repo, repo, repo)
+
+	at := time.Now().Add(50 * time.Millisecond)
+	if _, err := svc.PublishCourse(ctx, 1, &at, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := svc.PublishDue(ctx); err != nil || n != 0 {
+		t.Fatalf("PublishDue before the schedule = %d, %v", n, err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	if n, err := svc.PublishDue(ctx); err != nil || n != 1 {
+		t.Fatalf("PublishDue after the schedule = %d, %v", n, err)
+	}
+	course, err := svc.GetCourse(ctx, 1, repository.Filter{Status: repository.StatusPublished})
+	if err != nil || course.PublishAt != nil || course.PublishedAt == nil || !course.PublishedAt.Equal(at) {
+		t.Fatalf("course after PublishDue = %+v, %v", course, err)
+	}
+}
+
+func TestActionsCancelSchedule(t *testing.T) {
+	ctx := asAdmin
+	repo := repository.NewMemory()
+	seeded(repo)
+	repo.SetStatus(1, repository.StatusDraft)
+	svc := service.NewCourseService(repo, repo, repo, repo, repo, repo, repo)
+
+	at := time.Now().Add(time.Hour)
+	if _, err := svc.PublishCourse(ctx, 1, &at, 0); err != nil {
+		t.Fatal(err)
+	}
+	course, err := svc.SubmitCourse(ctx, 1, 0)
+	if err != nil || course.PublishAt != nil {
+		t.Fatalf("SubmitCourse = %+v, %v; want the schedule cancelled", course, err)
+	}
+}
+
+// edited is seeded with the content of course 1 and the title of series 2
+// changed once, so both are at version 2.
+func edited(m *repository.Memory) {
+	seeded(m)
+	ctx := context.Background()
+	m.UpdateCourse(ctx, &repository.Course{ID: 1, Title: "Heart Health After 65", Content: "Updated care", UniqueID: "heart-health-after-65"}, repository.Edit{})
+	m.UpdateSeries(ctx, &repository.Series{ID: 2, Title: "Blood pressure, revised", Description: "Basics"}, repository.Edit{})
+}
+
+func TestRevisions(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "create records revision 1", method: "GET", path: "/courses/1/revisions", setup: seeded, status: 200, contains: `"changes":{"content":{"from":"","to":"Essential cardiovascular care"}`},
+		{name: "update records changes", method: "GET", path: "/courses/1/revisions", setup: edited, status: 200, contains: `"changes":{"content":{"from":"Essential cardiovascular care","to":"Updated care"}}`},
+		{name: "paged", method: "GET", path: "/courses/1/revisions?offset=1", setup: edited, status: 200, contains: `[{"version":2,`},
+		{name: "draft course revisions hidden", method: "GET", path: "/courses/1/revisions", setup: inStatus(repository.StatusDraft), status: 404, code: problem.CodeCourseNotFound},
+		{name: "draft course revisions shown to admins", method: "GET", path: "/courses/1/revisions", admin: true, setup: inStatus(repository.StatusDraft), status: 200, contains: `"version":1`},
+		{name: "get revision", method: "GET", path: "/courses/1/revisions/1", setup: edited, status: 200, contains: `"content":"Essential cardiovascular care"`},
+		{name: "missing revision", method: "GET", path: "/courses/1/revisions/7", setup: edited, status: 404, code: problem.CodeRevisionNotFound},
+		{name: "invalid revision", method: "GET", path: "/courses/1/revisions/0", setup: edited, status: 400, code: problem.CodeInvalidID},
+		{name: "diff backwards", method: "GET", path: "/courses/1/revisions/diff?from=2&to=1", setup: edited, status: 200, contains: `"changes":{"content":{"from":"Updated care","to":"Essential cardiovascular care"}}`},
+		{name: "diff needs from and to", method: "GET", path: "/courses/1/revisions/diff?from=1", setup: edited, status: 400, code: problem.CodeInvalidQuery},
+		{name: "diff missing revision", method: "GET", path: "/courses/1/revisions/diff?from=1&to=5", setup: edited, status: 404, code: problem.CodeRevisionNotFound},
+		{name: "rollback", method: "POST", path: "/courses/1/revisions/1/rollback", admin: true, ifMatch: `"2"`, setup: edited, status: 200, contains: `"content":"Essential cardiovascular care"`, etag: `"3"`},
+		{name: "rollback keeps capacity", method: "POST", path: "/courses/1/revisions/1/rollback", admin: true, setup: func(m *repository.Memory) {
+			seeded(m)
+			seats := 5
+			m.UpdateCourse(context.Background(), &repository.Course{ID: 1, Title: "Heart Health After 65", Content: "Updated care", Capacity: &seats}, repository.Edit{})
+		}, status: 200, contains: `"capacity":5`},
+		{name: "rollback stale version", method: "POST", path: "/courses/1/revisions/1/rollback", admin: true, ifMatch: `"1"`, setup: edited, status: 412, code: problem.CodePreconditionFailed},
+		{name: "rollback missing revision", method: "POST", path: "/courses/1/revisions/9/rollback", admin: true, setup: edited, status: 404, code: problem.CodeRevisionNotFound},
+		{name: "rollback deleted course", method: "POST", path: "/courses/1/revisions/1/rollback", admin: true, setup: deleted, status: 404, code: problem.CodeCourseNotFound},
+		{name: "revisions repository error", method: "GET", path: "/courses/1/revisions", setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+
+		{name: "series revisions", method: "GET", path: "/series/2/revisions", setup: edited, status: 200, contains: `"title":{"from":"Blood pressure","to":"Blood pressure, revised"}`},
+		{name: "series diff", method: "GET", path: "/series/2/revisions/diff?from=1&to=2", setup: edited, status: 200, contains: `"changes":{"title":`},
+		{name: "series revision", method: "GET", path: "/series/2/revisions/2", setup: edited, status: 200, contains: `"version":2`},
+		{name: "series rollback", method: "POST", path: "/series/2/revisions/1/rollback", admin: true, setup: edited, status: 200, contains: `"title":"Blood pressure"`, etag: `"3"`},
+		{name: "missing series revisions", method: "GET", path: "/series/9/revisions", status: 404, code: problem.CodeSeriesNotFound},
+	})
+}
+
+func TestRevisionsRecordAuthorAndRollback(t *testing.T) {
+	repo := repository.NewMemory()
+	edited(repo)
+	app := newApp(t, repo)
+
+	req := httptest.NewRequest("POST", "/courses/1/revisions/1/rollback", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	if resp, err := app.Test(req, -1); err != nil || resp.StatusCode != 200 {
+		t.Fatalf("rollback = %v, %v", resp, err)
+	}
+	revision, err := repo.GetCourseRevision(context.Background(), 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if revision.Author != "admin" || revision.RollbackOf != 1 || revision.Changes["content"].To != "Essential cardiovascular care" {
+		t.Fatalf("rollback revision = %+v", revision)
+	}
+	if first, _ := repo.GetCourseRevision(context.Background(), 1, 1); first.Author != "" {
+		t.Fatalf("anonymous revision has author %q", first.Author)
+	}
+}
+
+func TestDeleteCourseCascadesSeries(t *testing.T) {
+	repo := repository.NewMemory()
+	seeded(repo)
+	svc := service.NewCourseService(repo, repo, repo, repo, repo, repo, repo)
+
+	if err := svc.DeleteCourse(asAdmin, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.GetSeries(asAdmin, 2, repository.Filter{}); err == nil {
+		t.Fatal("series survived its course being deleted")
+	}
+	repo.DeleteCourse(asAdmin, 1)
+	if got, want := repo.Events(), []events.Payload{events.CourseDeleted{CourseID: 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %+v, want %+v", got, want)
+	}
+}
+
+func TestRestoreCourseOnlyRestoresSeriesDeletedWithIt(t *testing.T) {
+	ctx := asAdmin
+	repo := repository.NewMemory()
+	seeded(repo)
+	repo.CreateSeries(ctx, &repository.Series{CourseID: 1, Title: "Removed earlier"}, repository.Edit{})
+	svc := service.NewCourseService(repo, repo, repo, repo, repo, repo, repo)
+
+	if err := svc.DeleteSeries(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond) // distinct deletion timestamps
+	if err := svc.DeleteCourse(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.RestoreCourse(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.GetSeries(ctx, 2, repository.Filter{}); err != nil {
+		t.Fatalf("series deleted with the course was not restored: %v", err)
+	}
+	if _, err := svc.GetSeries(ctx, 3, repository.Filter{}); err == nil {
+		t.Fatal("series deleted on its own was restored with the course")
+	}
+}
+
+func TestPurgeDeletedHonoursRetention(t *testing.T) {
+	ctx := asAdmin
+	repo := repository.NewMemory()
+	seeded(repo)
+	repo.CreateCourse(ctx, &repository.Course{Title: "Recent", Content: "x"}, repository.Edit{})
+	svc := service.NewCourseService(repo, repo, repo, repo, repo, repo, repo)
+	svc.DeleteCourse(ctx, 1)
+	svc.DeleteCourse(ctx, 3)
+	repo.Backdate(1, 48*time.Hour)
+	repo.Backdate(2, 48*time.Hour)
+
+	n, err := svc.PurgeDeleted(ctx, 24*time.Hour)
+	if err != nil || n != 2 {
+		t.Fatalf("PurgeDeleted = %d, %v; want 2 rows", n, err)
+	}
+	if _, err := svc.GetCourse(ctx, 1, repository.Filter{IncludeDeleted: true}); err == nil {
+		t.Fatal("course past retention was not purged")
+	}
+	if _, err := svc.GetCourse(ctx, 3, repository.Filter{IncludeDeleted: true}); err != nil {
+		t.Fatalf("course within retention was purged: %v", err)
+	}
+}
+
+func TestRouterErrors(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "unknown route", method: "GET", path: "/nope", status: 404, code: problem.CodeRouteNotFound},
+		{name: "health", method: "GET", path: "/health", status: 200, contains: "course-service"},
+		{name: "ready", method: "GET", path: "/ready", status: 200, contains: "ready"},
+	})
+}
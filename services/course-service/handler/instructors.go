@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"course-service/repository"
+	"course-service/service"
+	"mopcare/auth"
+	"mopcare/problem"
+)
+
+// paramUserID parses the :user_id path parameter of an instructor route.
+func paramUserID(c *fiber.Ctx) (int, error) {
+	id, err := strconv.Atoi(c.Params("user_id"))
+	if err != nil {
+		return 0, problem.BadRequest(problem.CodeInvalidID, "Invalid user ID")
+	}
+	return id, nil
+}
+
+func (h *Handler) getInstructors(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid course ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	f, err := h.filter(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	instructors, err := h.courses.ListInstructors(c.UserContext(), id, f)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(instructors)
+}
+
+// setInstructor adds a user to the course or changes their role. The body is
+// {"role": "owner" | "co-author" | "reviewer"}.
+func (h *Handler) setInstructor(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid course ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	userID, err := paramUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	var in service.InstructorInput
+	if err := c.BodyParser(&in); err != nil {
+		return writeError(c, invalidBody())
+	}
+	instructor, err := h.courses.SetInstructor(c.UserContext(), id, userID, in)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(instructor)
+}
+
+func (h *Handler) removeInstructor(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid course ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	userID, err := paramUserID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	if err := h.courses.RemoveInstructor(c.UserContext(), id, userID); err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "Instructor removed successfully"})
+}
+
+// getInstructorCourses lists the courses a user instructs, narrowed to one
+// role by ?role=. Like GET /courses it shows others only published courses;
+// the instructor themself sees every status.
+func (h *Handler) getInstructorCourses(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid user ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	page, err := queryPage(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	caller := auth.CallerOf(c.UserContext())
+	f, err := h.statusFilter(c, caller.Admin || caller.UserID == id)
+	if err != nil {
+		return writeError(c, err)
+	}
+	role := c.Query("role")
+	switch role {
+	case "", repository.RoleOwner, repository.RoleCoAuthor, repository.RoleReviewer:
+	default:
+		return writeError(c, problem.BadRequest(problem.CodeInvalidQuery, "role must be owner, co-author or reviewer"))
+	}
+	courses, err := h.courses.ListInstructorCourses(c.UserContext(), id, role, page, f)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(courses)
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"course-service/repository"
+	"mopcare/auth"
+	"mopcare/problem"
+)
+
+// Users 10, 11 and 12 are the owner, co-author and reviewer of course 1;
+// user 13 has no role on it.
+const (
+	owner    = 10
+	coAuthor = 11
+	reviewer = 12
+	stranger = 13
+)
+
+// instructed is seeded with owner, coAuthor and reviewer on course 1.
+func instructed(m *repository.Memory) {
+	seeded(m)
+	ctx := context.Background()
+	for user, role := range map[int]string{owner: repository.RoleOwner, coAuthor: repository.RoleCoAuthor, reviewer: repository.RoleReviewer} {
+		m.SetInstructor(ctx, &repository.Instructor{CourseID: 1, UserID: user, Role: role})
+	}
+}
+
+// instructedIn is instructed with course 1 moved to status.
+func instructedIn(status string) func(*repository.Memory) {
+	return func(m *repository.Memory) {
+		instructed(m)
+		m.SetStatus(1, status)
+	}
+}
+
+func TestCoursePermissions(t *testing.T) {
+	course := `{"title":"Heart Health After 65","content":"Revised"}`
+	runCases(t, []testCase{
+		{name: "anonymous create", method: "POST", path: "/courses", body: course, status: 401, code: problem.CodeUnauthorized},
+		{name: "user create", method: "POST", path: "/courses", body: course, user: stranger, status: 201},
+
+		{name: "co-author updates", method: "PUT", path: "/courses/1", body: course, setup: instructed, user: coAuthor, status: 200},
+		{name: "reviewer cannot update", method: "PUT", path: "/courses/1", body: course, setup: instructed, user: reviewer,
+			status: 403, code: problem.CodeForbidden},
+		{name: "stranger cannot patch", method: "PATCH", path: "/courses/1", body: `{"content":"x"}`, setup: instructed, user: stranger,
+			status: 403, code: problem.CodeForbidden},
+		{name: "anonymous cannot patch", method: "PATCH", path: "/courses/1", body: `{"content":"x"}`, setup: instructed,
+			status: 401, code: problem.CodeUnauthorized},
+		{name: "course without instructors is admin only", method: "PUT", path: "/courses/1", body: course, setup: seeded, user: owner,
+			status: 403, code: problem.CodeForbidden},
+		{name: "co-author rolls back", method: "POST", path: "/courses/1/revisions/1/rollback", setup: instructed, user: coAuthor, status: 200},
+		{name: "co-author cannot delete", method: "DELETE", path: "/courses/1", setup: instructed, user: coAuthor, status: 403, code: problem.CodeForbidden},
+		{name: "owner deletes", method: "DELETE", path: "/courses/1", setup: instructed, user: owner, status: 200},
+		{name: "owner restores", method: "POST", path: "/courses/1/restore", setup: func(m *repository.Memory) {
+			instructed(m)
+			m.DeleteCourse(context.Background(), 1)
+		}, user: owner, status: 200},
+
+		{name: "co-author submits", method: "POST", path: "/courses/1/submit", setup: instructedIn(repository.StatusDraft), user: coAuthor,
+			status: 200, contains: `"status":"in_review"`},
+		{name: "co-author cannot publish", method: "POST", path: "/courses/1/publish", setup: instructedIn(repository.StatusInReview), user: coAuthor,
+			status: 403, code: problem.CodeForbidden},
+		{name: "reviewer publishes", method: "POST", path: "/courses/1/publish", setup: instructedIn(repository.StatusInReview), user: reviewer,
+			status: 200, contains: `"status":"published"`},
+		{name: "reviewer rejects", method: "POST", path: "/courses/1/reject", setup: instructedIn(repository.StatusInReview), user: reviewer,
+			status: 200, contains: `"status":"draft"`},
+		{name: "reviewer cannot archive", method: "POST", path: "/courses/1/archive", setup: instructed, user: reviewer, status: 403, code: problem.CodeForbidden},
+
+		{name: "co-author adds series", method: "POST", path: "/courses/1/series", body: `{"title":"Diet"}`, setup: instructed, user: coAuthor, status: 201},
+		{name: "stranger cannot add series", method: "POST", path: "/courses/1/series", body: `{"title":"Diet"}`, setup: instructed, user: stranger,
+			status: 403, code: problem.CodeForbidden},
+		{name: "co-author patches series", method: "PATCH", path: "/series/2", body: `{"title":"Diet"}`, setup: instructed, user: coAuthor, status: 200},
+		{name: "reviewer cannot delete series", method: "DELETE", path: "/series/2", setup: instructed, user: reviewer, status: 403, code: problem.CodeForbidden},
+		{name: "user updates missing series", method: "PUT", path: "/series/9", body: `{"title":"x"}`, setup: instructed, user: coAuthor,
+			status: 404, code: problem.CodeSeriesNotFound},
+		{name: "anonymous cannot delete series", method: "DELETE", path: "/series/2", setup: instructed, status: 401, code: problem.CodeUnauthorized},
+	})
+}
+
+func TestInstructorsSeeUnpublishedCourses(t *testing.T) {
+	draft := instructedIn(repository.StatusDraft)
+	runCases(t, []testCase{
+		{name: "instructor reads draft", method: "GET", path: "/courses/1", setup: draft, user: reviewer, status: 200, contains: `"status":"draft"`},
+		{name: "stranger cannot", method: "GET", path: "/courses/1", setup: draft, user: stranger, status: 404, code: problem.CodeCourseNotFound},
+		{name: "instructor lists series", method: "GET", path: "/courses/1/series", setup: draft, user: coAuthor, status: 200, contains: `"title":"Blood pressure"`},
+		{name: "instructor reads series", method: "GET", path: "/series/2", setup: draft, user: owner, status: 200, contains: `"title":"Blood pressure"`},
+		{name: "stranger cannot read series", method: "GET", path: "/series/2", setup: draft, user: stranger, status: 404, code: problem.CodeSeriesNotFound},
+		{name: "instructor reads revisions", method: "GET", path: "/courses/1/revisions", setup: draft, user: owner, status: 200, contains: `"version":1`},
+
+		{name: "owner lists draft", method: "GET", path: "/courses", setup: draft, user: owner, status: 200, contains: `[{"id":1,`},
+		{name: "co-author lists draft", method: "GET", path: "/courses", setup: draft, user: coAuthor, status: 200, contains: `"status":"draft"`},
+		{name: "reviewer does not list draft", method: "GET", path: "/courses", setup: draft, user: reviewer, status: 200, contains: `null`},
+		{name: "stranger does not list draft", method: "GET", path: "/courses", setup: draft, user: stranger, status: 200, contains: `null`},
+		{name: "published only on request", method: "GET", path: "/courses?status=published", setup: draft, user: owner, status: 200, contains: `null`},
+	})
+}
+
+func TestInstructors(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "list", method: "GET", path: "/courses/1/instructors", setup: instructed, status: 200,
+			contains: `[{"course_id":1,"user_id":10,"role":"owner",`},
+		{name: "list hidden course", method: "GET", path: "/courses/1/instructors", setup: instructedIn(repository.StatusDraft),
+			status: 404, code: problem.CodeCourseNotFound},
+		{name: "list none", method: "GET", path: "/courses/1/instructors", setup: seeded, status: 200, contains: `[]`},
+
+		{name: "owner adds", method: "PUT", path: "/courses/1/instructors/13", body: `{"role":"reviewer"}`, setup: instructed, user: owner,
+			status: 200, contains: `"user_id":13,"role":"reviewer"`},
+		{name: "admin adds first owner", method: "PUT", path: "/courses/1/instructors/10", body: `{"role":"owner"}`, setup: seeded, admin: true,
+			status: 200, contains: `"role":"owner"`},
+		{name: "co-author cannot add", method: "PUT", path: "/courses/1/instructors/13", body: `{"role":"reviewer"}`, setup: instructed, user: coAuthor,
+			status: 403, code: problem.CodeForbidden},
+		{name: "invalid role", method: "PUT", path: "/courses/1/instructors/13", body: `{"role":"editor"}`, setup: instructed, user: owner,
+			status: 400, code: problem.CodeValidationFailed},
+		{name: "invalid user", method: "PUT", path: "/courses/1/instructors/x", body: `{"role":"owner"}`, setup: instructed, user: owner,
+			status: 400, code: problem.CodeInvalidID},
+		{name: "missing course", method: "PUT", path: "/courses/9/instructors/13", body: `{"role":"owner"}`, admin: true,
+			status: 404, code: problem.CodeCourseNotFound},
+		{name: "demote last owner", method: "PUT", path: "/courses/1/instructors/10", body: `{"role":"co-author"}`, setup: instructed, user: owner,
+			status: 409, code: problem.CodeLastOwner},
+		{name: "promote then demote", method: "PUT", path: "/courses/1/instructors/10", body: `{"role":"reviewer"}`, setup: func(m *repository.Memory) {
+			instructed(m)
+			m.SetInstructor(context.Background(), &repository.Instructor{CourseID: 1, UserID: coAuthor, Role: repository.RoleOwner})
+		}, user: owner, status: 200, contains: `"role":"reviewer"`},
+
+		{name: "remove last owner", method: "DELETE", path: "/courses/1/instructors/10", setup: instructed, admin: true, status: 409, code: problem.CodeLastOwner},
+		{name: "remove", method: "DELETE", path: "/courses/1/instructors/11", setup: instructed, user: owner, status: 200},
+		{name: "leave", method: "DELETE", path: "/courses/1/instructors/12", setup: instructed, user: reviewer, status: 200},
+		{name: "cannot remove others", method: "DELETE", path: "/courses/1/instructors/11", setup: instructed, user: reviewer, status: 403, code: problem.CodeForbidden},
+		{name: "remove missing", method: "DELETE", path: "/courses/1/instructors/13", setup: instructed, user: owner, status: 404, code: problem.CodeInstructorNotFound},
+		{name: "instructor repository error", method: "GET", path: "/courses/1/instructors", setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
+
+func TestInstructorCourses(t *testing.T) {
+	// Course 3 is a draft owned by owner, who is only a co-author of course 4.
+	catalogue := func(m *repository.Memory) {
+		instructed(m)
+		ctx := context.Background()
+		m.CreateCourse(ctx, &repository.Course{Title: "Draft", Content: "x", UniqueID: "draft"}, repository.Edit{Owner: owner})
+		m.CreateCourse(ctx, &repository.Course{Title: "Shared", Content: "x", UniqueID: "shared"}, repository.Edit{Owner: stranger})
+		m.SetInstructor(ctx, &repository.Instructor{CourseID: 4, UserID: owner, Role: repository.RoleCoAuthor})
+		m.SetStatus(4, repository.StatusPublished)
+	}
+	runCases(t, []testCase{
+		{name: "published to others", method: "GET", path: "/instructors/10/courses", setup: catalogue, status: 200,
+			contains: `[{"id":1,`},
+		{name: "everything to themself", method: "GET", path: "/instructors/10/courses?status=draft", setup: catalogue, user: owner, status: 200,
+			contains: `[{"id":3,`},
+		{name: "by role", method: "GET", path: "/instructors/10/courses?role=co-author", setup: catalogue, status: 200,
+			contains: `[{"id":4,`},
+		{name: "none", method: "GET", path: "/instructors/99/courses", setup: catalogue, status: 200, contains: `[]`},
+		{name: "drafts of others", method: "GET", path: "/instructors/10/courses?status=draft", setup: catalogue, user: stranger,
+			status: 403, code: problem.CodeForbidden},
+		{name: "invalid role", method: "GET", path: "/instructors/10/courses?role=editor", status: 400, code: problem.CodeInvalidQuery},
+	})
+}
+
+func TestCreatorOwnsCourse(t *testing.T) {
+	repo := repository.NewMemory()
+	app := newApp(t, repo)
+
+	req := httptest.NewRequest("POST", "/courses", strings.NewReader(`{"title":"Eye care","content":"x"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+auth.UserToken(owner, time.Now().Add(time.Hour), adminToken))
+	if resp, err := app.Test(req, -1); err != nil || resp.StatusCode != 201 {
+		t.Fatalf("create = %v, %v", resp, err)
+	}
+	ctx := context.Background()
+	if role, err := repo.InstructorRole(ctx, 1, owner); err != nil || role != repository.RoleOwner {
+		t.Fatalf("creator's role = %q, %v", role, err)
+	}
+	if revision, err := repo.GetCourseRevision(ctx, 1, 1); err != nil || revision.Author != "user:10" {
+		t.Fatalf("revision = %+v, %v", revision, err)
+	}
+}
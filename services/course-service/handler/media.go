@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// uploadMedia takes the raw bytes of an image or video as the request body,
+// typed by Content-Type. A first upload answers 201; content uploaded before
+// answers 200 with the existing media.
+func (h *Handler) uploadMedia(c *fiber.Ctx) error {
+	m, created, err := h.media.Upload(c.UserContext(), c.Body(), c.Get(fiber.HeaderContentType))
+	if err != nil {
+		return writeError(c, err)
+	}
+	if !created {
+		return c.JSON(m)
+	}
+	c.Location("/media/" + strconv.Itoa(m.ID))
+	return c.Status(fiber.StatusCreated).JSON(m)
+}
+
+func (h *Handler) getMedia(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid media ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	m, err := h.media.GetMedia(c.UserContext(), id)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(m)
+}
+
+// downloadMedia serves the content or thumbnail of a media through the
+// signed link from getMedia. Content never changes under an ID, so clients
+// may cache it for as long as the link is valid.
+func (h *Handler) downloadMedia(thumbnail bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id, err := paramID(c, "Invalid media ID")
+		if err != nil {
+			return writeError(c, err)
+		}
+		d, err := h.media.Open(c.UserContext(), id, thumbnail, c.Query("expires"), c.Query("signature"))
+		if err != nil {
+			return writeError(c, err)
+		}
+		etag := d.Checksum
+		if thumbnail {
+			etag += "-thumbnail"
+		}
+		maxAge := int(time.Until(d.ExpiresAt).Seconds())
+		c.Set(fiber.HeaderContentType, d.ContentType)
+		c.Set(fiber.HeaderETag, `"`+etag+`"`)
+		c.Set(fiber.HeaderCacheControl, "private, max-age="+strconv.Itoa(max(maxAge, 0)))
+		c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+		return c.SendStream(d.Body, int(d.Size))
+	}
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"course-service/repository"
+	"course-service/service"
+	"mopcare/media"
+	"mopcare/problem"
+)
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 640, 480))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// testMP4 is an ISO media header that content sniffing takes for MP4,
+// padded to size bytes.
+func testMP4(size int) []byte {
+	header := []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom")
+	return append(header, make([]byte, size-len(header))...)
+}
+
+func send(t *testing.T, app *fiber.App, method, path, contentType string, body []byte) (*http.Response, []byte) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	return resp, data
+}
+
+func decodeMedia(t *testing.T, body []byte) service.Media {
+	t.Helper()
+	var m service.Media
+	if err := json.Unmarshal(body, &m); err != nil {
+		t.Fatalf("decoding media %s: %v", body, err)
+	}
+	return m
+}
+
+func TestMediaUpload(t *testing.T) {
+	img := testPNG(t)
+	notPNG := append([]byte("\x89PNG\r\n\x1a\n"), "truncated"...)
+	cases := []struct {
+		name        string
+		contentType string
+		body        []byte
+		status      int
+		code        string
+	}{
+		{"unsupported type", "text/html", []byte("<html></html>"), 415, problem.CodeMediaUnsupportedType},
+		{"mislabelled content", "image/jpeg", img, 415, problem.CodeMediaUnsupportedType},
+		{"empty", "image/png", nil, 400, problem.CodeValidationFailed},
+		{"video too large", "video/mp4", testMP4(2 << 10), 413, problem.CodeMediaTooLarge},
+		{"undecodable image", "image/png", notPNG, 422, problem.CodeValidationFailed},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, body := send(t, newApp(t, repository.NewMemory()), "POST", "/media", tc.contentType, tc.body)
+			var p problem.Problem
+			json.Unmarshal(body, &p)
+			if resp.StatusCode != tc.status || p.Code != tc.code {
+				t.Fatalf("upload = %d %s, want %d %s", resp.StatusCode, body, tc.status, tc.code)
+			}
+		})
+	}
+}
+
+func TestMediaRoundTrip(t *testing.T) {
+	repo := repository.NewMemory()
+	app := newApp(t, repo)
+	img := testPNG(t)
+
+	resp, body := send(t, app, "POST", "/media", "image/png", img)
+	if resp.StatusCode != 201 || resp.Header.Get("Location") != "/media/1" {
+		t.Fatalf("upload = %d %q %s", resp.StatusCode, resp.Header.Get("Location"), body)
+	}
+	m := decodeMedia(t, body)
+	if m.Kind != media.KindImage || m.Width != 640 || m.Height != 480 || m.Checksum != media.Checksum(img) || m.ThumbnailURL == "" {
+		t.Fatalf("media = %+v", m)
+	}
+
+	resp, body = send(t, app, "POST", "/media", "image/png", img)
+	if resp.StatusCode != 200 || decodeMedia(t, body).ID != m.ID {
+		t.Fatalf("second upload of the same content = %d %s", resp.StatusCode, body)
+	}
+
+	resp, body = send(t, app, "GET", m.URL, "", nil)
+	if resp.StatusCode != 200 || !bytes.Equal(body, img) || resp.Header.Get("Content-Type") != "image/png" {
+		t.Fatalf("download = %d %q, %d bytes", resp.StatusCode, resp.Header.Get("Content-Type"), len(body))
+	}
+	resp, body = send(t, app, "GET", m.ThumbnailURL, "", nil)
+	if resp.StatusCode != 200 {
+		t.Fatalf("thumbnail = %d %s", resp.StatusCode, body)
+	}
+	thumb, err := png.Decode(bytes.NewReader(body))
+	if err != nil || thumb.Bounds().Dx() != media.ThumbnailSize {
+		t.Fatalf("thumbnail decodes to %v, %v", thumb, err)
+	}
+
+	resp, body = send(t, app, "POST", "/media", "video/mp4", testMP4(512))
+	if resp.StatusCode != 201 {
+		t.Fatalf("video upload = %d %s", resp.StatusCode, body)
+	}
+	if video := decodeMedia(t, body); video.ThumbnailURL != "" {
+		t.Fatalf("video has a thumbnail: %+v", video)
+	}
+
+	resp, _ = send(t, app, "GET", "/media/9", "", nil)
+	if resp.StatusCode != 404 {
+		t.Fatalf("missing media = %d", resp.StatusCode)
+	}
+}
+
+func TestMediaLinksAreSigned(t *testing.T) {
+	repo := repository.NewMemory()
+	app := newApp(t, repo)
+	_, body := send(t, app, "POST", "/media", "image/png", testPNG(t))
+	m := decodeMedia(t, body)
+
+	expired := newAppWithSigner(t, repo, media.NewSigner([]byte("test-key"), -time.Minute))
+	_, body = send(t, expired, "GET", "/media/1", "", nil)
+	stale := decodeMedia(t, body)
+
+	for name, path := range map[string]string{
+		"unsigned":         "/media/1/content",
+		"tampered":         strings.Replace(m.URL, "signature=", "signature=x", 1),
+		"other media":      strings.Replace(m.URL, "/media/1/", "/media/2/", 1),
+		"content as thumb": strings.Replace(m.URL, "/content", "/thumbnail", 1),
+		"expired":          stale.URL,
+	} {
+		resp, body := send(t, app, "GET", path, "", nil)
+		if resp.StatusCode != 403 || !strings.Contains(string(body), problem.CodeSignatureInvalid) {
+			t.Errorf("%s link = %d %s, want 403", name, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestCourseMediaURLs(t *testing.T) {
+	upload := func(m *repository.Memory) {
+		m.CreateMedia(context.Background(), &repository.Media{Checksum: "a", ContentType: "image/png", Size: 1, Kind: media.KindImage})
+		m.CreateMedia(context.Background(), &repository.Media{Checksum: "b", ContentType: "video/mp4", Size: 1, Kind: media.KindVideo})
+	}
+	legacy := func(m *repository.Memory) {
+		seeded(m)
+		m.UpdateCourse(context.Background(), &repository.Course{ID: 1, Title: "Heart Health After 65", Content: "x", CoverImageURL: "cover.png", UniqueID: "heart-health-after-65"}, repository.Edit{})
+	}
+	runCases(t, []testCase{
+		{name: "uploaded cover", method: "POST", path: "/courses", admin: true, body: `{"title":"a","content":"b","cover_image_url":"media:1","overview_video_url":"media:2"}`,
+			setup: upload, status: 201, contains: `"overview_video_url":"media:2","cover_image_url":"media:1"`},
+		{name: "external cover", method: "POST", path: "/courses", admin: true, body: `{"title":"a","content":"b","cover_image_url":"https://cdn.example.com/a.png"}`, status: 201},
+		{name: "relative cover", method: "POST", path: "/courses", admin: true, body: `{"title":"a","content":"b","cover_image_url":"a.png"}`, status: 400, code: problem.CodeValidationFailed},
+		{name: "malformed media reference", method: "POST", path: "/courses", admin: true, body: `{"title":"a","content":"b","cover_image_url":"media:x"}`, status: 400, code: problem.CodeValidationFailed},
+		{name: "missing media", method: "POST", path: "/courses", admin: true, body: `{"title":"a","content":"b","cover_image_url":"media:9"}`, status: 422, code: problem.CodeMediaNotFound},
+		{name: "video as cover", method: "PUT", path: "/courses/1", admin: true, body: `{"title":"a","content":"b","cover_image_url":"media:4"}`,
+			setup: func(m *repository.Memory) { seeded(m); upload(m) }, status: 422, code: problem.CodeValidationFailed},
+		{name: "patch keeps legacy url", method: "PATCH", path: "/courses/1", admin: true, body: `{"content":"y"}`, setup: legacy, status: 200, contains: `"cover_image_url":"cover.png"`},
+		{name: "patch checks changed url", method: "PATCH", path: "/courses/1", admin: true, body: `{"cover_image_url":"other.png"}`, setup: legacy, status: 400, code: problem.CodeValidationFailed},
+	})
+}
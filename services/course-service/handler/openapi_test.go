@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"testing"
+
+	"course-service/repository"
+	"course-service/service"
+	"mopcare/openapi"
+)
+
+// TestRoutesAreDocumented keeps the OpenAPI document in step with the router.
+func TestRoutesAreDocumented(t *testing.T) {
+	spec := openapi.MustLoad()
+	repo := repository.NewMemory()
+	app := newApp(t, repo)
+	for _, route := range app.GetRoutes(true) {
+		if route.Method == "HEAD" {
+			// Fiber registers HEAD alongside every GET.
+			continue
+		}
+		path := openapi.PathTemplate(route.Path)
+		if _, ok := spec.Operation(route.Method, path); !ok {
+			t.Errorf("%s %s is not in the OpenAPI document", route.Method, path)
+		}
+	}
+}
+
+func TestSchemasMatchTypes(t *testing.T) {
+	spec := openapi.MustLoad()
+	for name, v := range map[string]interface{}{
+		"Course":          repository.Course{},
+		"Series":          repository.Series{},
+		"CourseInput":     service.CourseInput{},
+		"SeriesInput":     service.SeriesInput{},
+		"PublishInput":    service.PublishInput{},
+		"Revision":        repository.Revision{},
+		"Change":          repository.Change{},
+		"Diff":            service.Diff{},
+		"Media":           service.Media{},
+		"Category":        repository.Category{},
+		"CategoryInput":   service.CategoryInput{},
+		"TagCount":        repository.TagCount{},
+		"TagRename":       service.TagRename{},
+		"Catalog":         service.Catalog{},
+		"Facets":          service.Facets{},
+		"CategoryCount":   service.CategoryCount{},
+		"PriceRange":      service.PriceRange{},
+		"DurationRange":   service.DurationRange{},
+		"Instructor":      repository.Instructor{},
+		"Prerequisite":    repository.Prerequisite{},
+		"InstructorInput": service.InstructorInput{},
+		"Quiz":            repository.Quiz{},
+		"Question":        repository.Question{},
+		"QuizInput":       service.QuizInput{},
+		"Attempt":         repository.Attempt{},
+		"AttemptInput":    service.AttemptInput{},
+	} {
+		onlySchema, onlyStruct := spec.SchemaFieldDiff(name, v)
+		if len(onlySchema) > 0 || len(onlyStruct) > 0 {
+			t.Errorf("%s: only in document %v, only in Go type %v", name, onlySchema, onlyStruct)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+
+	"mopcare/problem"
+)
+
+// paramPrerequisiteID parses the :prerequisite_id path parameter.
+func paramPrerequisiteID(c *fiber.Ctx) (int, error) {
+	id, err := strconv.Atoi(c.Params("prerequisite_id"))
+	if err != nil {
+		return 0, problem.BadRequest(problem.CodeInvalidID, "Invalid prerequisite ID")
+	}
+	return id, nil
+}
+
+func (h *Handler) getPrerequisites(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid course ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	f, err := h.filter(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	prerequisites, err := h.courses.ListPrerequisites(c.UserContext(), id, f)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(prerequisites)
+}
+
+// addPrerequisite takes no body; adding a prerequisite the course already
+// requires changes nothing.
+func (h *Handler) addPrerequisite(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid course ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	prerequisiteID, err := paramPrerequisiteID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	prerequisite, err := h.courses.AddPrerequisite(c.UserContext(), id, prerequisiteID)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(prerequisite)
+}
+
+func (h *Handler) removePrerequisite(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid course ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	prerequisiteID, err := paramPrerequisiteID(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	if err := h.courses.RemovePrerequisite(c.UserContext(), id, prerequisiteID); err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "Prerequisite removed successfully"})
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"course-service/repository"
+	"mopcare/problem"
+)
+
+// chained is instructed with published courses 3 and 4, where course 1
+// requires course 3 and course 3 requires course 4.
+func chained(m *repository.Memory) {
+	instructed(m)
+	ctx := context.Background()
+	m.CreateCourse(ctx, &repository.Course{Title: "Cardiology Basics", Content: "C", UniqueID: "cardiology-basics"}, repository.Edit{})
+	m.CreateCourse(ctx, &repository.Course{Title: "Anatomy", Content: "C", UniqueID: "anatomy"}, repository.Edit{})
+	m.SetStatus(3, repository.StatusPublished)
+	m.SetStatus(4, repository.StatusPublished)
+	m.AddPrerequisite(ctx, &repository.Prerequisite{CourseID: 1, PrerequisiteID: 3})
+	m.AddPrerequisite(ctx, &repository.Prerequisite{CourseID: 3, PrerequisiteID: 4})
+}
+
+func TestPrerequisites(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "list", method: "GET", path: "/courses/1/prerequisites", setup: chained, status: 200,
+			contains: `[{"course_id":1,"prerequisite_id":3,"title":"Cardiology Basics",`},
+		{name: "list without any", method: "GET", path: "/courses/1/prerequisites", setup: seeded, status: 200, contains: `[]`},
+		{name: "list leaves deleted courses out", method: "GET", path: "/courses/1/prerequisites", setup: func(m *repository.Memory) {
+			chained(m)
+			m.DeleteCourse(context.Background(), 3)
+		}, status: 200, contains: `[]`},
+		{name: "list leaves unpublished courses out", method: "GET", path: "/courses/1/prerequisites", setup: func(m *repository.Memory) {
+			chained(m)
+			m.SetStatus(3, repository.StatusArchived)
+		}, status: 200, contains: `[]`},
+		{name: "list of a missing course", method: "GET", path: "/courses/9/prerequisites", status: 404, code: problem.CodeCourseNotFound},
+
+		{name: "co-author adds", method: "PUT", path: "/courses/1/prerequisites/4", setup: chained, user: coAuthor,
+			status: 200, contains: `"prerequisite_id":4,"title":"Anatomy"`},
+		{name: "adding again changes nothing", method: "PUT", path: "/courses/1/prerequisites/3", setup: chained, admin: true,
+			status: 200, contains: `"prerequisite_id":3`},
+		{name: "direct cycle", method: "PUT", path: "/courses/3/prerequisites/1", setup: chained, admin: true,
+			status: 409, code: problem.CodePrerequisiteCycle},
+		{name: "cycle through a prerequisite", method: "PUT", path: "/courses/4/prerequisites/1", setup: chained, admin: true,
+			status: 409, code: problem.CodePrerequisiteCycle},
+		{name: "own prerequisite", method: "PUT", path: "/courses/1/prerequisites/1", setup: chained, admin: true,
+			status: 409, code: problem.CodePrerequisiteCycle},
+		{name: "missing prerequisite", method: "PUT", path: "/courses/1/prerequisites/9", setup: chained, admin: true,
+			status: 422, code: problem.CodeCourseNotFound},
+		{name: "deleted prerequisite", method: "PUT", path: "/courses/1/prerequisites/4", setup: func(m *repository.Memory) {
+			chained(m)
+			m.DeleteCourse(context.Background(), 4)
+		}, admin: true, status: 422, code: problem.CodeCourseNotFound},
+		{name: "draft prerequisite", method: "PUT", path: "/courses/1/prerequisites/4", setup: func(m *repository.Memory) {
+			chained(m)
+			m.SetStatus(4, repository.StatusDraft)
+		}, user: coAuthor, status: 422, code: problem.CodeCourseNotFound},
+		{name: "add to a missing course", method: "PUT", path: "/courses/9/prerequisites/1", setup: chained, admin: true,
+			status: 404, code: problem.CodeCourseNotFound},
+		{name: "reviewer cannot add", method: "PUT", path: "/courses/1/prerequisites/4", setup: chained, user: reviewer,
+			status: 403, code: problem.CodeForbidden},
+		{name: "anonymous cannot add", method: "PUT", path: "/courses/1/prerequisites/4", setup: chained,
+			status: 401, code: problem.CodeUnauthorized},
+		{name: "invalid prerequisite ID", method: "PUT", path: "/courses/1/prerequisites/x", admin: true,
+			status: 400, code: problem.CodeInvalidID},
+
+		{name: "owner removes", method: "DELETE", path: "/courses/1/prerequisites/3", setup: chained, user: owner,
+			status: 200, contains: "removed"},
+		{name: "remove one not required", method: "DELETE", path: "/courses/1/prerequisites/4", setup: chained, admin: true,
+			status: 404, code: problem.CodePrerequisiteNotFound},
+		{name: "stranger cannot remove", method: "DELETE", path: "/courses/1/prerequisites/3", setup: chained, user: stranger,
+			status: 403, code: problem.CodeForbidden},
+		{name: "repository error", method: "GET", path: "/courses/1/prerequisites", setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+
+	"course-service/service"
+)
+
+func (h *Handler) getQuizzes(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid series ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	f, err := h.filter(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	quizzes, err := h.courses.ListQuizzes(c.UserContext(), id, f)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(quizzes)
+}
+
+func (h *Handler) createQuiz(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid series ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	var in service.QuizInput
+	if err := c.BodyParser(&in); err != nil {
+		return writeError(c, invalidBody())
+	}
+	quiz, err := h.courses.CreateQuiz(c.UserContext(), id, in)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.Status(201).JSON(quiz)
+}
+
+func (h *Handler) getQuiz(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid quiz ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	f, err := h.filter(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	quiz, err := h.courses.GetQuiz(c.UserContext(), id, f)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(quiz)
+}
+
+func (h *Handler) updateQuiz(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid quiz ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	var in service.QuizInput
+	if err := c.BodyParser(&in); err != nil {
+		return writeError(c, invalidBody())
+	}
+	quiz, err := h.courses.UpdateQuiz(c.UserContext(), id, in)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(quiz)
+}
+
+func (h *Handler) deleteQuiz(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid quiz ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	if err := h.courses.DeleteQuiz(c.UserContext(), id); err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(fiber.Map{"message": "Quiz deleted successfully"})
+}
+
+// getAttempts lists the caller's attempts at a quiz, or everyone's for
+// admins and the course's instructors.
+func (h *Handler) getAttempts(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid quiz ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	f, err := h.filter(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	attempts, err := h.courses.ListAttempts(c.UserContext(), id, f)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.JSON(attempts)
+}
+
+// submitAttempt grades {"answers": [...]} and answers with the recorded
+// attempt.
+func (h *Handler) submitAttempt(c *fiber.Ctx) error {
+	id, err := paramID(c, "Invalid quiz ID")
+	if err != nil {
+		return writeError(c, err)
+	}
+	f, err := h.filter(c)
+	if err != nil {
+		return writeError(c, err)
+	}
+	var in service.AttemptInput
+	if err := c.BodyParser(&in); err != nil {
+		return writeError(c, invalidBody())
+	}
+	attempt, err := h.courses.SubmitAttempt(c.UserContext(), id, in, f)
+	if err != nil {
+		return writeError(c, err)
+	}
+	return c.Status(201).JSON(attempt)
+}
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"course-service/repository"
+	"mopcare/problem"
+)
+
+// learner is enrolled in course 1 but has no role on it.
+const learner = 20
+
+// quizzed is instructed with learner enrolled and quiz 3 on series 2: two
+// questions, 50% to pass, two attempts each.
+func quizzed(m *repository.Memory) {
+	instructed(m)
+	m.Enroll(learner, 1, "enrolled")
+	first, second := 1, 0
+	m.CreateQuiz(context.Background(), &repository.Quiz{SeriesID: 2, Title: "Check-up", PassingScore: 50, MaxAttempts: 2, Required: true,
+		Questions: []repository.Question{
+			{Type: repository.QuestionMultipleChoice, Prompt: "Normal systolic pressure?", Options: []string{"90", "120", "180"}, Answer: &first},
+			{Type: repository.QuestionTrueFalse, Prompt: "Salt raises blood pressure.", Options: []string{"True", "False"}, Answer: &second},
+		}})
+}
+
+// attempted is quizzed after learner used one attempt.
+func attempted(m *repository.Memory) {
+	quizzed(m)
+	m.CreateAttempt(context.Background(), &repository.Attempt{QuizID: 3, UserID: learner, Answers: []int{0, 0}, Correct: []bool{false, true}, Score: 50, Passed: true}, 0)
+}
+
+func TestQuizzes(t *testing.T) {
+	quiz := `{"title":"Check-up","passing_score":80,"questions":[{"type":"true_false","prompt":"Salt raises blood pressure.","answer":0}]}`
+	runCases(t, []testCase{
+		{name: "co-author creates", method: "POST", path: "/series/2/quizzes", body: quiz, setup: instructed, user: coAuthor,
+			status: 201, contains: `"options":["True","False"],"answer":0`},
+		{name: "anonymous cannot create", method: "POST", path: "/series/2/quizzes", body: quiz, setup: instructed, status: 401, code: problem.CodeUnauthorized},
+		{name: "reviewer cannot create", method: "POST", path: "/series/2/quizzes", body: quiz, setup: instructed, user: reviewer,
+			status: 403, code: problem.CodeForbidden},
+		{name: "missing series", method: "POST", path: "/series/9/quizzes", body: quiz, admin: true, status: 422, code: problem.CodeSeriesNotFound},
+		{name: "no questions", method: "POST", path: "/series/2/quizzes", body: `{"title":"x","passing_score":80,"questions":[]}`, setup: seeded, admin: true,
+			status: 400, code: problem.CodeValidationFailed},
+		{name: "passing score out of range", method: "POST", path: "/series/2/quizzes",
+			body: `{"title":"x","passing_score":120,"questions":[{"type":"true_false","prompt":"p","answer":0}]}`, setup: seeded, admin: true,
+			status: 400, code: problem.CodeValidationFailed, contains: "passing_score"},
+		{name: "answer out of range", method: "POST", path: "/series/2/quizzes",
+			body: `{"title":"x","passing_score":80,"questions":[{"type":"multiple_choice","prompt":"p","options":["a","b"],"answer":2}]}`, setup: seeded, admin: true,
+			status: 400, code: problem.CodeValidationFailed, contains: "questions[0]: answer"},
+		{name: "too few options", method: "POST", path: "/series/2/quizzes",
+			body: `{"title":"x","passing_score":80,"questions":[{"type":"multiple_choice","prompt":"p","options":["a"],"answer":0}]}`, setup: seeded, admin: true,
+			status: 400, code: problem.CodeValidationFailed},
+
+		{name: "learner reads without answers", method: "GET", path: "/quizzes/3", setup: quizzed, user: learner,
+			status: 200, contains: `"options":["90","120","180"]}`},
+		{name: "instructor reads answers", method: "GET", path: "/series/2/quizzes", setup: quizzed, user: reviewer,
+			status: 200, contains: `"answer":1`},
+		{name: "missing quiz", method: "GET", path: "/quizzes/9", setup: quizzed, status: 404, code: problem.CodeQuizNotFound},
+		{name: "quiz of a draft course", method: "GET", path: "/quizzes/3", setup: func(m *repository.Memory) {
+			quizzed(m)
+			m.SetStatus(1, repository.StatusDraft)
+		}, user: learner, status: 404, code: problem.CodeQuizNotFound},
+		{name: "co-author updates", method: "PUT", path: "/quizzes/3", body: quiz, setup: quizzed, user: coAuthor,
+			status: 200, contains: `"passing_score":80`},
+		{name: "learner cannot update", method: "PUT", path: "/quizzes/3", body: quiz, setup: quizzed, user: learner,
+			status: 403, code: problem.CodeForbidden},
+		{name: "owner deletes", method: "DELETE", path: "/quizzes/3", setup: quizzed, user: owner, status: 200},
+		{name: "delete missing", method: "DELETE", path: "/quizzes/9", setup: quizzed, admin: true, status: 404, code: problem.CodeQuizNotFound},
+	})
+}
+
+func TestQuizAttempts(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "pass", method: "POST", path: "/quizzes/3/attempts", body: `{"answers":[1,0]}`, setup: quizzed, user: learner,
+			status: 201, contains: `"correct":[true,true],"score":100,"passed":true`},
+		{name: "fail", method: "POST", path: "/quizzes/3/attempts", body: `{"answers":[2,1]}`, setup: quizzed, user: learner,
+			status: 201, contains: `"score":0,"passed":false`},
+		{name: "attempts used", method: "POST", path: "/quizzes/3/attempts", body: `{"answers":[1,0]}`, setup: func(m *repository.Memory) {
+			attempted(m)
+			m.CreateAttempt(context.Background(), &repository.Attempt{QuizID: 3, UserID: learner, Answers: []int{0, 1}, Correct: []bool{false, false}}, 0)
+		}, user: learner, status: 409, code: problem.CodeQuizAttemptsUsed},
+		{name: "not enrolled", method: "POST", path: "/quizzes/3/attempts", body: `{"answers":[1,0]}`, setup: quizzed, user: stranger,
+			status: 403, code: problem.CodeNotEnrolled},
+		{name: "waitlisted", method: "POST", path: "/quizzes/3/attempts", body: `{"answers":[1,0]}`,
+			setup: func(m *repository.Memory) { quizzed(m); m.Enroll(stranger, 1, "waitlisted") }, user: stranger,
+			status: 403, code: problem.CodeNotEnrolled},
+		{name: "anonymous", method: "POST", path: "/quizzes/3/attempts", body: `{"answers":[1,0]}`, setup: quizzed,
+			status: 401, code: problem.CodeUnauthorized},
+		{name: "admin token", method: "POST", path: "/quizzes/3/attempts", body: `{"answers":[1,0]}`, setup: quizzed, admin: true,
+			status: 403, code: problem.CodeForbidden},
+		{name: "too few answers", method: "POST", path: "/quizzes/3/attempts", body: `{"answers":[1]}`, setup: quizzed, user: learner,
+			status: 400, code: problem.CodeValidationFailed},
+		{name: "answer out of range", method: "POST", path: "/quizzes/3/attempts", body: `{"answers":[1,2]}`, setup: quizzed, user: learner,
+			status: 400, code: problem.CodeValidationFailed, contains: "answers[1]"},
+		{name: "deleted series", method: "POST", path: "/quizzes/3/attempts", body: `{"answers":[1,0]}`, setup: func(m *repository.Memory) {
+			quizzed(m)
+			m.DeleteSeries(context.Background(), 2)
+		}, user: learner, status: 404, code: problem.CodeQuizNotFound},
+
+		{name: "learner lists own", method: "GET", path: "/quizzes/3/attempts", setup: attempted, user: learner,
+			status: 200, contains: `"user_id":20`},
+		{name: "other learner sees none", method: "GET", path: "/quizzes/3/attempts", setup: attempted, user: stranger,
+			status: 200, contains: `[]`},
+		{name: "instructor lists all", method: "GET", path: "/quizzes/3/attempts", setup: attempted, user: reviewer,
+			status: 200, contains: `"user_id":20`},
+		{name: "anonymous cannot list", method: "GET", path: "/quizzes/3/attempts", setup: attempted, status: 401, code: problem.CodeUnauthorized},
+	})
+}
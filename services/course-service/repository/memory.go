@@ -0,0 +1,1133 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"mopcare/events"
+	"mopcare/paging"
+	"mopcare/problem"
+)
+
+// Memory is an in-memory CourseRepository, SeriesRepository,
+// MediaRepository, CategoryRepository, InstructorRepository, QuizRepository
+// and PrerequisiteRepository for tests. Enrollments belong to the enrollment-service, so
+// tests register them with Enroll.
+// Setting Err makes every method fail with it, to exercise error paths.
+type Memory struct {
+	mu      sync.Mutex
+	nextID  int
+	courses map[int]Course
+	series  map[int]Series
+	// Revisions by course or series ID, oldest first.
+	courseRevisions map[int][]Revision
+	seriesRevisions map[int][]Revision
+	// slugs maps former course slugs to their course.
+	slugs      map[string]int
+	media      map[int]Media
+	categories map[int]Category
+	// instructors maps course IDs to their instructors by user ID.
+	instructors map[int]map[int]Instructor
+	// prerequisites maps course IDs to their prerequisites by ID.
+	prerequisites map[int]map[int]Prerequisite
+	quizzes       map[int]Quiz
+	attempts      map[int]Attempt
+	// enrollments maps {user ID, course ID} pairs to the enrollment's status.
+	enrollments map[[2]int]string
+	events      []events.Payload
+
+	Err error
+}
+
+func NewMemory() *Memory {
+	return &Memory{
+		nextID:          1,
+		courses:         map[int]Course{},
+		series:          map[int]Series{},
+		courseRevisions: map[int][]Revision{},
+		seriesRevisions: map[int][]Revision{},
+		slugs:           map[string]int{},
+		media:           map[int]Media{},
+		categories:      map[int]Category{},
+		instructors:     map[int]map[int]Instructor{},
+		prerequisites:   map[int]map[int]Prerequisite{},
+		quizzes:         map[int]Quiz{},
+		attempts:        map[int]Attempt{},
+		enrollments:     map[[2]int]string{},
+	}
+}
+
+// Events returns the events recorded so far, as Postgres would have written
+// them to the outbox.
+func (m *Memory) Events() []events.Payload {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]events.Payload(nil), m.events...)
+}
+
+func newRevision(version int, e Edit, from, to map[string]string) Revision {
+	return Revision{
+		Version:    version,
+		Author:     e.Author,
+		CreatedAt:  time.Now(),
+		RollbackOf: e.RollbackOf,
+		Fields:     to,
+		Changes:    Changes(from, to),
+	}
+}
+
+func findRevision(revisions []Revision, version int) (Revision, error) {
+	for _, r := range revisions {
+		if r.Version == version {
+			return r, nil
+		}
+	}
+	return Revision{}, ErrNotFound
+}
+
+// matches reports whether a summarised course passes f.
+func (f Filter) matches(c Course) bool {
+	if !(f.IncludeDeleted || c.DeletedAt == nil) || !(f.Status == "" || c.Status == f.Status) {
+		return false
+	}
+	if f.CategoryIDs != nil && (c.CategoryID == nil || !containsInt(f.CategoryIDs, *c.CategoryID)) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !containsString(c.Tags, tag) {
+			return false
+		}
+	}
+	return (f.MinPrice == nil || c.Price >= *f.MinPrice) && (f.MaxPrice == nil || c.Price <= *f.MaxPrice) &&
+		(f.MinDuration == nil || c.Duration >= *f.MinDuration) && (f.MaxDuration == nil || c.Duration <= *f.MaxDuration) &&
+		(f.FreePreview == nil || c.HasFreePreview == *f.FreePreview)
+}
+
+func containsInt(ids []int, id int) bool {
+	for _, x := range ids {
+		if x == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// summarise fills in what Postgres computes when reading a course: its
+// duration and free preview from its live series. Tags are copied so callers
+// cannot alter the stored ones.
+func (m *Memory) summarise(c Course) Course {
+	c.Tags = append([]string{}, c.Tags...)
+	c.Duration, c.HasFreePreview = 0, false
+	for _, s := range m.series {
+		if s.CourseID == c.ID && s.DeletedAt == nil {
+			c.Duration += s.Duration
+			c.HasFreePreview = c.HasFreePreview || s.IsFreePreview
+		}
+	}
+	return c
+}
+
+func (m *Memory) ListCourses(ctx context.Context, page paging.Page, f Filter) ([]Course, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return paging.Slice(m.matching(f), page), nil
+}
+
+// matching returns the summarised courses passing f, ordered by ID.
+func (m *Memory) matching(f Filter) []Course {
+	var courses []Course
+	for _, c := range m.courses {
+		if f.Instructor != 0 && !m.instructs(c.ID, f.Instructor, f.InstructorRole) {
+			continue
+		}
+		g := f
+		if f.Author != 0 && (m.instructs(c.ID, f.Author, RoleOwner) || m.instructs(c.ID, f.Author, RoleCoAuthor)) {
+			g.Status = ""
+		}
+		if c = m.summarise(c); g.matches(c) {
+			courses = append(courses, c)
+		}
+	}
+	sort.Slice(courses, func(i, j int) bool { return courses[i].ID < courses[j].ID })
+	return courses
+}
+
+func (m *Memory) CourseFacets(ctx context.Context, f Filter) (Facets, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return Facets{}, m.Err
+	}
+	facets := Facets{Categories: map[int]int{}}
+	tags := map[string]int{}
+	for i, c := range m.matching(f) {
+		if i == 0 {
+			facets.MinPrice, facets.MaxPrice, facets.MinDuration, facets.MaxDuration = c.Price, c.Price, c.Duration, c.Duration
+		}
+		facets.Total++
+		if c.HasFreePreview {
+			facets.FreePreview++
+		}
+		facets.MinPrice, facets.MaxPrice = min(facets.MinPrice, c.Price), max(facets.MaxPrice, c.Price)
+		facets.MinDuration, facets.MaxDuration = min(facets.MinDuration, c.Duration), max(facets.MaxDuration, c.Duration)
+		if c.CategoryID != nil {
+			facets.Categories[*c.CategoryID]++
+		}
+		for _, tag := range c.Tags {
+			tags[tag]++
+		}
+	}
+	for tag, n := range tags {
+		facets.Tags = append(facets.Tags, TagCount{Tag: tag, Count: n})
+	}
+	sort.Slice(facets.Tags, func(i, j int) bool {
+		a, b := facets.Tags[i], facets.Tags[j]
+		return a.Count > b.Count || (a.Count == b.Count && a.Tag < b.Tag)
+	})
+	return facets, nil
+}
+
+func (m *Memory) GetCourse(ctx context.Context, id int, f Filter) (Course, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return Course{}, m.Err
+	}
+	c, ok := m.courses[id]
+	if !ok {
+		return Course{}, ErrNotFound
+	}
+	if c = m.summarise(c); !f.matches(c) {
+		return Course{}, ErrNotFound
+	}
+	return c, nil
+}
+
+func (m *Memory) CreateCourse(ctx context.Context, c *Course, e Edit) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	if err := m.checkUniqueID(c); err != nil {
+		return err
+	}
+	c.ID = m.nextID
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = c.CreatedAt
+	c.Version = 1
+	c.Status = StatusDraft
+	c.Tags = append([]string{}, c.Tags...)
+	m.nextID++
+	m.courses[c.ID] = *c
+	m.courseRevisions[c.ID] = []Revision{newRevision(c.Version, e, nil, c.fields())}
+	if e.Owner != 0 {
+		m.instructors[c.ID] = map[int]Instructor{e.Owner: {CourseID: c.ID, UserID: e.Owner, Role: RoleOwner, CreatedAt: c.CreatedAt}}
+	}
+	return nil
+}
+
+// checkUniqueID mirrors the courses_unique_id_key constraint, which deleted
+// courses still hold, and the reservation of former slugs.
+func (m *Memory) checkUniqueID(c *Course) error {
+	if c.UniqueID == "" {
+		return nil
+	}
+	if owner, ok := m.slugs[c.UniqueID]; ok && owner != c.ID {
+		return slugTaken()
+	}
+	for _, other := range m.courses {
+		if other.ID != c.ID && other.UniqueID == c.UniqueID {
+			return slugTaken()
+		}
+	}
+	return nil
+}
+
+func (m *Memory) UpdateCourse(ctx context.Context, c *Course, e Edit) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	existing, ok := m.courses[c.ID]
+	if !ok || existing.DeletedAt != nil {
+		return ErrNotFound
+	}
+	if c.Version != 0 && c.Version != existing.Version {
+		return ErrVersionConflict
+	}
+	if err := m.checkUniqueID(c); err != nil {
+		return err
+	}
+	c.CreatedAt = existing.CreatedAt
+	c.UpdatedAt = time.Now()
+	c.Version = existing.Version + 1
+	c.DeletedAt, c.Status, c.PublishAt, c.PublishedAt = existing.DeletedAt, existing.Status, existing.PublishAt, existing.PublishedAt
+	m.courses[c.ID] = *c
+	*c = m.summarise(*c)
+	if existing.UniqueID != c.UniqueID {
+		delete(m.slugs, c.UniqueID)
+		if existing.UniqueID != "" {
+			m.slugs[existing.UniqueID] = c.ID
+		}
+	}
+	m.courseRevisions[c.ID] = append(m.courseRevisions[c.ID], newRevision(c.Version, e, existing.fields(), c.fields()))
+	return nil
+}
+
+func (m *Memory) UpdateStatus(ctx context.Context, c *Course) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	existing, ok := m.courses[c.ID]
+	if !ok || existing.DeletedAt != nil {
+		return ErrNotFound
+	}
+	if c.Version != 0 && c.Version != existing.Version {
+		return ErrVersionConflict
+	}
+	existing.Status, existing.PublishAt, existing.PublishedAt = c.Status, c.PublishAt, c.PublishedAt
+	existing.UpdatedAt = time.Now()
+	existing.Version++
+	m.courses[c.ID] = existing
+	*c = m.summarise(existing)
+	return nil
+}
+
+func (m *Memory) PublishDue(ctx context.Context, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	var n int64
+	for id, c := range m.courses {
+		if c.PublishAt != nil && !c.PublishAt.After(now) && c.DeletedAt == nil {
+			c.Status, c.PublishedAt, c.PublishAt = StatusPublished, c.PublishAt, nil
+			c.UpdatedAt = time.Now()
+			c.Version++
+			m.courses[id] = c
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *Memory) DeleteCourse(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	c, ok := m.courses[id]
+	if !ok || c.DeletedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	m.courses[id] = markDeleted(c, &now)
+	for sid, s := range m.series {
+		if s.CourseID == id && s.DeletedAt == nil {
+			m.series[sid] = markSeriesDeleted(s, &now)
+		}
+	}
+	m.events = append(m.events, events.CourseDeleted{CourseID: id})
+	return nil
+}
+
+func (m *Memory) RestoreCourse(ctx context.Context, id int) (Course, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return Course{}, m.Err
+	}
+	c, ok := m.courses[id]
+	if !ok {
+		return Course{}, ErrNotFound
+	}
+	if c.DeletedAt == nil {
+		return m.summarise(c), nil
+	}
+	for sid, s := range m.series {
+		if s.CourseID == id && s.DeletedAt != nil && s.DeletedAt.Equal(*c.DeletedAt) {
+			m.series[sid] = markSeriesDeleted(s, nil)
+		}
+	}
+	c = markDeleted(c, nil)
+	m.courses[id] = c
+	return m.summarise(c), nil
+}
+
+func (m *Memory) PurgeCourses(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	var n int64
+	for id, c := range m.courses {
+		if c.DeletedAt != nil && c.DeletedAt.Before(before) {
+			delete(m.courses, id)
+			delete(m.courseRevisions, id)
+			delete(m.instructors, id)
+			delete(m.prerequisites, id)
+			for _, prerequisites := range m.prerequisites {
+				delete(prerequisites, id)
+			}
+			for slug, owner := range m.slugs {
+				if owner == id {
+					delete(m.slugs, slug)
+				}
+			}
+			n++
+			// Mirrors ON DELETE CASCADE on series.course_id.
+			for sid, s := range m.series {
+				if s.CourseID == id {
+					delete(m.series, sid)
+					delete(m.seriesRevisions, sid)
+					m.dropQuizzes(sid)
+				}
+			}
+		}
+	}
+	return n, nil
+}
+
+func (m *Memory) ListCourseRevisions(ctx context.Context, courseID int, page paging.Page) ([]Revision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return paging.Slice(append([]Revision(nil), m.courseRevisions[courseID]...), page), nil
+}
+
+func (m *Memory) GetCourseRevision(ctx context.Context, courseID, version int) (Revision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return Revision{}, m.Err
+	}
+	return findRevision(m.courseRevisions[courseID], version)
+}
+
+func (m *Memory) FindSlug(ctx context.Context, slug string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	for _, c := range m.courses {
+		if c.UniqueID == slug {
+			return c.ID, nil
+		}
+	}
+	if id, ok := m.slugs[slug]; ok {
+		return id, nil
+	}
+	return 0, ErrNotFound
+}
+
+func (m *Memory) TakenSlugs(ctx context.Context, base string, courseID int) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	related := func(slug string) bool { return slug == base || strings.HasPrefix(slug, base+"-") }
+	var slugs []string
+	for _, c := range m.courses {
+		if c.ID != courseID && related(c.UniqueID) {
+			slugs = append(slugs, c.UniqueID)
+		}
+	}
+	for slug, owner := range m.slugs {
+		if owner != courseID && related(slug) {
+			slugs = append(slugs, slug)
+		}
+	}
+	return slugs, nil
+}
+
+func markDeleted(c Course, at *time.Time) Course {
+	c.DeletedAt = at
+	c.UpdatedAt = time.Now()
+	c.Version++
+	return c
+}
+
+func markSeriesDeleted(s Series, at *time.Time) Series {
+	s.DeletedAt = at
+	s.UpdatedAt = time.Now()
+	s.Version++
+	return s
+}
+
+// seriesMatches reports whether a series passes f; Status is checked
+// against its course.
+func (m *Memory) seriesMatches(s Series, f Filter) bool {
+	return (f.IncludeDeleted || s.DeletedAt == nil) && (f.Status == "" || m.courses[s.CourseID].Status == f.Status)
+}
+
+func (m *Memory) ListSeries(ctx context.Context, courseID int, page paging.Page, f Filter) ([]Series, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var seriesList []Series
+	for _, s := range m.series {
+		if s.CourseID == courseID && m.seriesMatches(s, f) {
+			seriesList = append(seriesList, s)
+		}
+	}
+	sort.Slice(seriesList, func(i, j int) bool { return seriesList[i].ID < seriesList[j].ID })
+	return paging.Slice(seriesList, page), nil
+}
+
+func (m *Memory) GetSeries(ctx context.Context, id int, f Filter) (Series, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return Series{}, m.Err
+	}
+	s, ok := m.series[id]
+	if !ok || !m.seriesMatches(s, f) {
+		return Series{}, ErrNotFound
+	}
+	return s, nil
+}
+
+func (m *Memory) CreateSeries(ctx context.Context, s *Series, e Edit) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	if c, ok := m.courses[s.CourseID]; !ok || c.DeletedAt != nil {
+		return ErrCourseNotFound
+	}
+	s.ID = m.nextID
+	s.CreatedAt = time.Now()
+	s.UpdatedAt = s.CreatedAt
+	s.Version = 1
+	m.nextID++
+	m.series[s.ID] = *s
+	m.seriesRevisions[s.ID] = []Revision{newRevision(s.Version, e, nil, s.fields())}
+	return nil
+}
+
+func (m *Memory) UpdateSeries(ctx context.Context, s *Series, e Edit) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	existing, ok := m.series[s.ID]
+	if !ok || existing.DeletedAt != nil {
+		return ErrNotFound
+	}
+	if s.Version != 0 && s.Version != existing.Version {
+		return ErrVersionConflict
+	}
+	from := existing.fields()
+	existing.Title = s.Title
+	existing.Description = s.Description
+	existing.Duration, existing.IsFreePreview = s.Duration, s.IsFreePreview
+	existing.UpdatedAt = time.Now()
+	existing.Version++
+	m.series[s.ID] = existing
+	m.seriesRevisions[s.ID] = append(m.seriesRevisions[s.ID], newRevision(existing.Version, e, from, existing.fields()))
+	*s = existing
+	return nil
+}
+
+func (m *Memory) DeleteSeries(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	if s, ok := m.series[id]; ok && s.DeletedAt == nil {
+		now := time.Now()
+		m.series[id] = markSeriesDeleted(s, &now)
+	}
+	return nil
+}
+
+func (m *Memory) RestoreSeries(ctx context.Context, id int) (Series, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return Series{}, m.Err
+	}
+	s, ok := m.series[id]
+	if !ok {
+		return Series{}, ErrNotFound
+	}
+	if s.DeletedAt == nil {
+		return s, nil
+	}
+	if c := m.courses[s.CourseID]; c.DeletedAt != nil {
+		return Series{}, ErrCourseNotFound
+	}
+	s = markSeriesDeleted(s, nil)
+	m.series[id] = s
+	return s, nil
+}
+
+func (m *Memory) PurgeSeries(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	var n int64
+	for id, s := range m.series {
+		if s.DeletedAt != nil && s.DeletedAt.Before(before) {
+			delete(m.series, id)
+			delete(m.seriesRevisions, id)
+			m.dropQuizzes(id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *Memory) ListSeriesRevisions(ctx context.Context, seriesID int, page paging.Page) ([]Revision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return paging.Slice(append([]Revision(nil), m.seriesRevisions[seriesID]...), page), nil
+}
+
+func (m *Memory) GetSeriesRevision(ctx context.Context, seriesID, version int) (Revision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return Revision{}, m.Err
+	}
+	return findRevision(m.seriesRevisions[seriesID], version)
+}
+
+// Backdate moves the deletion time of a deleted course or series back by d,
+// so tests can exercise the purge retention without waiting.
+func (m *Memory) Backdate(id int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok := m.courses[id]; ok && c.DeletedAt != nil {
+		at := c.DeletedAt.Add(-d)
+		c.DeletedAt = &at
+		m.courses[id] = c
+	}
+	if s, ok := m.series[id]; ok && s.DeletedAt != nil {
+		at := s.DeletedAt.Add(-d)
+		s.DeletedAt = &at
+		m.series[id] = s
+	}
+}
+
+// SetStatus moves a course straight to status without bumping its version,
+// so tests can seed courses in any workflow state.
+func (m *Memory) SetStatus(id int, status string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if c, ok := m.courses[id]; ok {
+		c.Status = status
+		m.courses[id] = c
+	}
+}
+
+func (m *Memory) CreateMedia(ctx context.Context, md *Media) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return false, m.Err
+	}
+	for _, existing := range m.media {
+		if existing.Checksum == md.Checksum {
+			*md = existing
+			return false, nil
+		}
+	}
+	md.ID = m.nextID
+	m.nextID++
+	md.CreatedAt = time.Now()
+	m.media[md.ID] = *md
+	return true, nil
+}
+
+func (m *Memory) GetMedia(ctx context.Context, id int) (Media, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return Media{}, m.Err
+	}
+	md, ok := m.media[id]
+	if !ok {
+		return Media{}, ErrNotFound
+	}
+	return md, nil
+}
+
+func (m *Memory) FindMedia(ctx context.Context, checksum string) (Media, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return Media{}, m.Err
+	}
+	for _, md := range m.media {
+		if md.Checksum == checksum {
+			return md, nil
+		}
+	}
+	return Media{}, ErrNotFound
+}
+
+// categorySlugTaken mirrors what the categories_slug_key constraint maps to.
+func categorySlugTaken() error {
+	return problem.Conflict(problem.CodeCategorySlugTaken, "Resource conflicts with an existing one")
+}
+
+// checkCategory mirrors categories_slug_key and categories_parent_id_fkey.
+func (m *Memory) checkCategory(c *Category) error {
+	for _, other := range m.categories {
+		if other.ID != c.ID && other.Slug == c.Slug {
+			return categorySlugTaken()
+		}
+	}
+	if c.ParentID == nil {
+		return nil
+	}
+	if _, ok := m.categories[*c.ParentID]; !ok {
+		return problem.Unprocessable(problem.CodeCategoryNotFound, "A referenced resource does not exist")
+	}
+	return nil
+}
+
+func (m *Memory) ListCategories(ctx context.Context) ([]Category, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var categories []Category
+	for _, c := range m.categories {
+		categories = append(categories, c)
+	}
+	sort.Slice(categories, func(i, j int) bool {
+		a, b := categories[i], categories[j]
+		return a.Name < b.Name || (a.Name == b.Name && a.ID < b.ID)
+	})
+	return categories, nil
+}
+
+func (m *Memory) GetCategory(ctx context.Context, id int) (Category, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return Category{}, m.Err
+	}
+	c, ok := m.categories[id]
+	if !ok {
+		return Category{}, ErrNotFound
+	}
+	return c, nil
+}
+
+func (m *Memory) CreateCategory(ctx context.Context, c *Category) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	if err := m.checkCategory(c); err != nil {
+		return err
+	}
+	c.ID = m.nextID
+	m.nextID++
+	c.CreatedAt = time.Now()
+	c.UpdatedAt = c.CreatedAt
+	m.categories[c.ID] = *c
+	return nil
+}
+
+func (m *Memory) UpdateCategory(ctx context.Context, c *Category) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	existing, ok := m.categories[c.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := m.checkCategory(c); err != nil {
+		return err
+	}
+	c.CreatedAt = existing.CreatedAt
+	c.UpdatedAt = time.Now()
+	m.categories[c.ID] = *c
+	return nil
+}
+
+func (m *Memory) DeleteCategory(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	if _, ok := m.categories[id]; !ok {
+		return ErrNotFound
+	}
+	for _, c := range m.categories {
+		if c.ParentID != nil && *c.ParentID == id {
+			return ErrCategoryInUse
+		}
+	}
+	for _, c := range m.courses {
+		if c.CategoryID != nil && *c.CategoryID == id {
+			return ErrCategoryInUse
+		}
+	}
+	delete(m.categories, id)
+	return nil
+}
+
+// instructs reports whether the user has role on the course, or any role
+// if role is empty.
+func (m *Memory) instructs(courseID, userID int, role string) bool {
+	in, ok := m.instructors[courseID][userID]
+	return ok && (role == "" || in.Role == role)
+}
+
+func (m *Memory) ListPrerequisites(ctx context.Context, courseID int) ([]Prerequisite, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var prerequisites []Prerequisite
+	for id, pr := range m.prerequisites[courseID] {
+		if c := m.courses[id]; c.DeletedAt == nil && c.Status == StatusPublished {
+			pr.Title = c.Title
+			prerequisites = append(prerequisites, pr)
+		}
+	}
+	sort.Slice(prerequisites, func(i, j int) bool { return prerequisites[i].PrerequisiteID < prerequisites[j].PrerequisiteID })
+	return prerequisites, nil
+}
+
+func (m *Memory) AddPrerequisite(ctx context.Context, pr *Prerequisite) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	_, course := m.courses[pr.CourseID]
+	_, prerequisite := m.courses[pr.PrerequisiteID]
+	if !course || !prerequisite {
+		// Mirrors the course_prerequisites foreign keys.
+		return problem.Unprocessable(problem.CodeCourseNotFound, "Referenced resource does not exist")
+	}
+	if m.requires(pr.PrerequisiteID, pr.CourseID, map[int]bool{}) {
+		return ErrPrerequisiteCycle
+	}
+	existing, ok := m.prerequisites[pr.CourseID][pr.PrerequisiteID]
+	if !ok {
+		existing = Prerequisite{CourseID: pr.CourseID, PrerequisiteID: pr.PrerequisiteID, CreatedAt: time.Now()}
+	}
+	if m.prerequisites[pr.CourseID] == nil {
+		m.prerequisites[pr.CourseID] = map[int]Prerequisite{}
+	}
+	m.prerequisites[pr.CourseID][pr.PrerequisiteID] = existing
+	*pr = existing
+	pr.Title = m.courses[pr.PrerequisiteID].Title
+	return nil
+}
+
+// requires reports whether the course requires target, directly or through
+// its prerequisites, deleted ones included.
+func (m *Memory) requires(courseID, target int, seen map[int]bool) bool {
+	for id := range m.prerequisites[courseID] {
+		if id == target || (!seen[id] && m.requires(id, target, seen)) {
+			return true
+		}
+		seen[id] = true
+	}
+	return false
+}
+
+func (m *Memory) RemovePrerequisite(ctx context.Context, courseID, prerequisiteID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	if _, ok := m.prerequisites[courseID][prerequisiteID]; !ok {
+		return ErrNotFound
+	}
+	delete(m.prerequisites[courseID], prerequisiteID)
+	return nil
+}
+
+func (m *Memory) ListInstructors(ctx context.Context, courseID int) ([]Instructor, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var instructors []Instructor
+	for _, in := range m.instructors[courseID] {
+		instructors = append(instructors, in)
+	}
+	sort.Slice(instructors, func(i, j int) bool { return instructors[i].UserID < instructors[j].UserID })
+	return instructors, nil
+}
+
+func (m *Memory) InstructorRole(ctx context.Context, courseID, userID int) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return "", m.Err
+	}
+	return m.instructors[courseID][userID].Role, nil
+}
+
+func (m *Memory) SetInstructor(ctx context.Context, in *Instructor) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	if _, ok := m.courses[in.CourseID]; !ok {
+		// Mirrors the course_instructors_course_id_fkey constraint.
+		return problem.Unprocessable(problem.CodeCourseNotFound, "Referenced resource does not exist")
+	}
+	existing, ok := m.instructors[in.CourseID][in.UserID]
+	if ok && in.Role != RoleOwner && m.lastOwner(in.CourseID, in.UserID) {
+		return ErrLastOwner
+	}
+	in.CreatedAt = time.Now()
+	if ok {
+		in.CreatedAt = existing.CreatedAt
+	}
+	if m.instructors[in.CourseID] == nil {
+		m.instructors[in.CourseID] = map[int]Instructor{}
+	}
+	m.instructors[in.CourseID][in.UserID] = *in
+	return nil
+}
+
+func (m *Memory) RemoveInstructor(ctx context.Context, courseID, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	if _, ok := m.instructors[courseID][userID]; !ok {
+		return ErrNotFound
+	}
+	if m.lastOwner(courseID, userID) {
+		return ErrLastOwner
+	}
+	delete(m.instructors[courseID], userID)
+	return nil
+}
+
+// lastOwner reports whether the user is the course's only owner.
+func (m *Memory) lastOwner(courseID, userID int) bool {
+	for _, in := range m.instructors[courseID] {
+		if in.Role == RoleOwner && in.UserID != userID {
+			return false
+		}
+	}
+	return m.instructors[courseID][userID].Role == RoleOwner
+}
+
+// Enroll registers the user's enrollment in the course with status.
+func (m *Memory) Enroll(userID, courseID int, status string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enrollments[[2]int{userID, courseID}] = status
+}
+
+// dropQuizzes mirrors ON DELETE CASCADE on quizzes.series_id and
+// quiz_attempts.quiz_id.
+func (m *Memory) dropQuizzes(seriesID int) {
+	for id, q := range m.quizzes {
+		if q.SeriesID == seriesID {
+			m.dropQuiz(id)
+		}
+	}
+}
+
+func (m *Memory) dropQuiz(id int) {
+	delete(m.quizzes, id)
+	for aid, a := range m.attempts {
+		if a.QuizID == id {
+			delete(m.attempts, aid)
+		}
+	}
+}
+
+// copyQuiz keeps callers from mutating stored questions through shared
+// slices and answer pointers.
+func copyQuiz(q Quiz) Quiz {
+	questions := make([]Question, len(q.Questions))
+	for i, question := range q.Questions {
+		question.Options = append([]string(nil), question.Options...)
+		if question.Answer != nil {
+			answer := *question.Answer
+			question.Answer = &answer
+		}
+		questions[i] = question
+	}
+	q.Questions = questions
+	return q
+}
+
+func (m *Memory) ListQuizzes(ctx context.Context, seriesID int) ([]Quiz, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var quizzes []Quiz
+	for _, q := range m.quizzes {
+		if q.SeriesID == seriesID {
+			quizzes = append(quizzes, copyQuiz(q))
+		}
+	}
+	sort.Slice(quizzes, func(i, j int) bool { return quizzes[i].ID < quizzes[j].ID })
+	return quizzes, nil
+}
+
+func (m *Memory) GetQuiz(ctx context.Context, id int) (Quiz, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return Quiz{}, m.Err
+	}
+	q, ok := m.quizzes[id]
+	if !ok {
+		return Quiz{}, ErrNotFound
+	}
+	return copyQuiz(q), nil
+}
+
+func (m *Memory) CreateQuiz(ctx context.Context, q *Quiz) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	if _, ok := m.series[q.SeriesID]; !ok {
+		// Mirrors the quizzes_series_id_fkey constraint.
+		return problem.Unprocessable(problem.CodeSeriesNotFound, "Referenced resource does not exist")
+	}
+	q.ID = m.nextID
+	m.nextID++
+	q.CreatedAt = time.Now()
+	q.UpdatedAt = q.CreatedAt
+	m.quizzes[q.ID] = copyQuiz(*q)
+	return nil
+}
+
+func (m *Memory) UpdateQuiz(ctx context.Context, q *Quiz) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	current, ok := m.quizzes[q.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	q.SeriesID, q.CreatedAt, q.UpdatedAt = current.SeriesID, current.CreatedAt, time.Now()
+	m.quizzes[q.ID] = copyQuiz(*q)
+	return nil
+}
+
+func (m *Memory) DeleteQuiz(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	if _, ok := m.quizzes[id]; !ok {
+		return ErrNotFound
+	}
+	m.dropQuiz(id)
+	return nil
+}
+
+func (m *Memory) CreateAttempt(ctx context.Context, a *Attempt, maxAttempts int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	if _, ok := m.quizzes[a.QuizID]; !ok {
+		return ErrNotFound
+	}
+	used := 0
+	for _, other := range m.attempts {
+		if other.QuizID == a.QuizID && other.UserID == a.UserID {
+			used++
+		}
+	}
+	if maxAttempts > 0 && used >= maxAttempts {
+		return ErrAttemptsUsed
+	}
+	a.ID = m.nextID
+	m.nextID++
+	a.CreatedAt = time.Now()
+	stored := *a
+	stored.Answers = append([]int(nil), a.Answers...)
+	stored.Correct = append([]bool(nil), a.Correct...)
+	m.attempts[a.ID] = stored
+	return nil
+}
+
+func (m *Memory) ListAttempts(ctx context.Context, quizID, userID int) ([]Attempt, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var attempts []Attempt
+	for _, a := range m.attempts {
+		if a.QuizID == quizID && (userID == 0 || a.UserID == userID) {
+			attempts = append(attempts, a)
+		}
+	}
+	sort.Slice(attempts, func(i, j int) bool { return attempts[i].ID < attempts[j].ID })
+	return attempts, nil
+}
+
+func (m *Memory) Enrolled(ctx context.Context, userID, courseID int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status, ok := m.enrollments[[2]int{userID, courseID}]
+	return ok && status != "waitlisted", m.Err
+}
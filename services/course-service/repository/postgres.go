@@ -0,0 +1,1081 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"mopcare/database"
+	"mopcare/events"
+	"mopcare/paging"
+)
+
+// Postgres is the production CourseRepository, SeriesRepository,
+// MediaRepository, CategoryRepository, InstructorRepository and
+// QuizRepository.
+type Postgres struct {
+	db *database.DB
+}
+
+func NewPostgres(db *database.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+// courseDuration and courseFreePreview sum up the live series of the course
+// in the enclosing query.
+const (
+	courseDuration    = "(SELECT COALESCE(SUM(duration), 0) FROM series WHERE series.course_id = courses.id AND series.deleted_at IS NULL)"
+	courseFreePreview = "EXISTS (SELECT 1 FROM series WHERE series.course_id = courses.id AND series.deleted_at IS NULL AND series.is_free_preview)"
+)
+
+// Optional URL columns are stored as NULL when empty.
+const courseColumns = "id, title, content, COALESCE(overview_video_url, ''), COALESCE(cover_image_url, ''), unique_id, " +
+	"category_id, ARRAY(SELECT tag FROM course_tags WHERE course_tags.course_id = courses.id ORDER BY tag), price, " +
+	courseDuration + ", " + courseFreePreview + ", " +
+	"created_at, updated_at, version, deleted_at, status, publish_at, published_at, " +
+	"capacity, enrollment_opens_at, enrollment_closes_at"
+
+func scanCourse(row interface{ Scan(...interface{}) error }, c *Course) error {
+	return row.Scan(&c.ID, &c.Title, &c.Content, &c.OverviewVideoURL, &c.CoverImageURL, &c.UniqueID,
+		&c.CategoryID, pq.Array(&c.Tags), &c.Price, &c.Duration, &c.HasFreePreview,
+		&c.CreatedAt, &c.UpdatedAt, &c.Version, &c.DeletedAt, &c.Status, &c.PublishAt, &c.PublishedAt,
+		&c.Capacity, &c.EnrollmentOpensAt, &c.EnrollmentClosesAt)
+}
+
+const seriesColumns = "id, course_id, title, description, duration, is_free_preview, created_at, updated_at, version, deleted_at"
+
+func scanSeries(row interface{ Scan(...interface{}) error }, s *Series) error {
+	return row.Scan(&s.ID, &s.CourseID, &s.Title, &s.Description, &s.Duration, &s.IsFreePreview, &s.CreatedAt, &s.UpdatedAt, &s.Version, &s.DeletedAt)
+}
+
+// courseConditions renders f as a WHERE clause over courses and returns it
+// with its arguments, to which callers append their own.
+func courseConditions(f Filter) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	conds = append(conds, "("+arg(f.IncludeDeleted)+" OR courses.deleted_at IS NULL)")
+	switch {
+	case f.Status != "" && f.Author != 0:
+		conds = append(conds, "(courses.status = "+arg(f.Status)+
+			" OR EXISTS(SELECT 1 FROM course_instructors WHERE course_instructors.course_id = courses.id AND user_id = "+
+			arg(f.Author)+" AND role = ANY("+arg(pq.Array([]string{RoleOwner, RoleCoAuthor}))+")))")
+	case f.Status != "":
+		conds = append(conds, "courses.status = "+arg(f.Status))
+	}
+	if f.CategoryIDs != nil {
+		conds = append(conds, "courses.category_id = ANY("+arg(pq.Array(f.CategoryIDs))+")")
+	}
+	if len(f.Tags) > 0 {
+		conds = append(conds, "(SELECT COUNT(*) FROM course_tags WHERE course_tags.course_id = courses.id AND course_tags.tag = ANY("+
+			arg(pq.Array(f.Tags))+")) = "+arg(len(f.Tags)))
+	}
+	if f.MinPrice != nil {
+		conds = append(conds, "courses.price >= "+arg(*f.MinPrice))
+	}
+	if f.MaxPrice != nil {
+		conds = append(conds, "courses.price <= "+arg(*f.MaxPrice))
+	}
+	if f.MinDuration != nil {
+		conds = append(conds, courseDuration+" >= "+arg(*f.MinDuration))
+	}
+	if f.MaxDuration != nil {
+		conds = append(conds, courseDuration+" <= "+arg(*f.MaxDuration))
+	}
+	if f.FreePreview != nil {
+		conds = append(conds, courseFreePreview+" = "+arg(*f.FreePreview))
+	}
+	if f.Instructor != 0 {
+		conds = append(conds, "EXISTS(SELECT 1 FROM course_instructors WHERE course_instructors.course_id = courses.id AND user_id = "+
+			arg(f.Instructor)+" AND ("+arg(f.InstructorRole)+" = '' OR role = "+arg(f.InstructorRole)+"))")
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+func (p *Postgres) ListCourses(ctx context.Context, page paging.Page, f Filter) ([]Course, error) {
+	where, args := courseConditions(f)
+	n := len(args)
+	rows, err := p.db.QueryContext(ctx,
+		"SELECT "+courseColumns+" FROM courses WHERE "+where+" ORDER BY id LIMIT $"+strconv.Itoa(n+1)+" OFFSET $"+strconv.Itoa(n+2),
+		append(args, page.LimitArg(), page.Offset)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var courses []Course
+	for rows.Next() {
+		var course Course
+		if err := scanCourse(rows, &course); err != nil {
+			return nil, err
+		}
+		courses = append(courses, course)
+	}
+	return courses, rows.Err()
+}
+
+func (p *Postgres) CourseFacets(ctx context.Context, f Filter) (Facets, error) {
+	where, args := courseConditions(f)
+	facets := Facets{Categories: map[int]int{}}
+	err := p.db.QueryRowContext(ctx,
+		`SELECT COUNT(*), COUNT(*) FILTER (WHERE free_preview),
+		        COALESCE(MIN(price), 0), COALESCE(MAX(price), 0), COALESCE(MIN(duration), 0), COALESCE(MAX(duration), 0)
+		 FROM (SELECT price, `+courseDuration+` AS duration, `+courseFreePreview+` AS free_preview
+		       FROM courses WHERE `+where+`) matching`,
+		args...,
+	).Scan(&facets.Total, &facets.FreePreview, &facets.MinPrice, &facets.MaxPrice, &facets.MinDuration, &facets.MaxDuration)
+	if err != nil {
+		return Facets{}, err
+	}
+
+	rows, err := p.db.QueryContext(ctx,
+		"SELECT category_id, COUNT(*) FROM courses WHERE "+where+" AND category_id IS NOT NULL GROUP BY category_id", args...)
+	if err != nil {
+		return Facets{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, count int
+		if err := rows.Scan(&id, &count); err != nil {
+			return Facets{}, err
+		}
+		facets.Categories[id] = count
+	}
+	if err := rows.Err(); err != nil {
+		return Facets{}, err
+	}
+
+	tags, err := p.db.QueryContext(ctx,
+		"SELECT tag, COUNT(*) FROM course_tags JOIN courses ON courses.id = course_tags.course_id WHERE "+where+
+			" GROUP BY tag ORDER BY COUNT(*) DESC, tag", args...)
+	if err != nil {
+		return Facets{}, err
+	}
+	defer tags.Close()
+	for tags.Next() {
+		var t TagCount
+		if err := tags.Scan(&t.Tag, &t.Count); err != nil {
+			return Facets{}, err
+		}
+		facets.Tags = append(facets.Tags, t)
+	}
+	return facets, tags.Err()
+}
+
+func (p *Postgres) GetCourse(ctx context.Context, id int, f Filter) (Course, error) {
+	var course Course
+	err := scanCourse(p.db.QueryRowContext(ctx,
+		"SELECT "+courseColumns+" FROM courses WHERE id = $1 AND ($2 OR deleted_at IS NULL) AND ($3 = '' OR status = $3)",
+		id, f.IncludeDeleted, f.Status,
+	), &course)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Course{}, ErrNotFound
+	}
+	return course, err
+}
+
+func (p *Postgres) CreateCourse(ctx context.Context, c *Course, e Edit) error {
+	return p.inTx(ctx, func(tx *database.Tx) error {
+		if err := claimSlug(ctx, tx, 0, "", c.UniqueID); err != nil {
+			return err
+		}
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO courses (title, content, overview_video_url, cover_image_url, unique_id, category_id, price,
+			                      capacity, enrollment_opens_at, enrollment_closes_at)
+			 VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, $6, $7, $8, $9, $10) RETURNING id, created_at, updated_at, version, status`,
+			c.Title, c.Content, c.OverviewVideoURL, c.CoverImageURL, c.UniqueID, c.CategoryID, c.Price,
+			c.Capacity, c.EnrollmentOpensAt, c.EnrollmentClosesAt,
+		).Scan(&c.ID, &c.CreatedAt, &c.UpdatedAt, &c.Version, &c.Status)
+		if err != nil {
+			return err
+		}
+		if err := replaceTags(ctx, tx, c.ID, c.Tags); err != nil {
+			return err
+		}
+		if e.Owner != 0 {
+			_, err := tx.ExecContext(ctx,
+				"INSERT INTO course_instructors (course_id, user_id, role) VALUES ($1, $2, $3)", c.ID, e.Owner, RoleOwner)
+			if err != nil {
+				return err
+			}
+		}
+		return insertRevision(ctx, tx, "course_revisions", "course_id", c.ID, c.Version, e, nil, c.fields())
+	})
+}
+
+// UpdateCourse locks the row first so the revision's changes are computed
+// against exactly the version being replaced.
+func (p *Postgres) UpdateCourse(ctx context.Context, c *Course, e Edit) error {
+	return p.inTx(ctx, func(tx *database.Tx) error {
+		var old Course
+		err := scanCourse(tx.QueryRowContext(ctx,
+			"SELECT "+courseColumns+" FROM courses WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", c.ID,
+		), &old)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if c.Version != 0 && c.Version != old.Version {
+			return ErrVersionConflict
+		}
+		if err := claimSlug(ctx, tx, c.ID, old.UniqueID, c.UniqueID); err != nil {
+			return err
+		}
+		// Tags go first so the RETURNING below reads the new ones.
+		if err := replaceTags(ctx, tx, c.ID, c.Tags); err != nil {
+			return err
+		}
+		err = scanCourse(tx.QueryRowContext(ctx,
+			`UPDATE courses SET title = $1, content = $2, overview_video_url = NULLIF($3, ''), cover_image_url = NULLIF($4, ''), unique_id = $5,
+			        category_id = $6, price = $7, capacity = $8, enrollment_opens_at = $9, enrollment_closes_at = $10,
+			        version = version + 1, updated_at = NOW()
+			 WHERE id = $11
+			 RETURNING `+courseColumns,
+			c.Title, c.Content, c.OverviewVideoURL, c.CoverImageURL, c.UniqueID, c.CategoryID, c.Price,
+			c.Capacity, c.EnrollmentOpensAt, c.EnrollmentClosesAt, c.ID,
+		), c)
+		if err != nil {
+			return err
+		}
+		return insertRevision(ctx, tx, "course_revisions", "course_id", c.ID, c.Version, e, old.fields(), c.fields())
+	})
+}
+
+func (p *Postgres) UpdateStatus(ctx context.Context, c *Course) error {
+	err := scanCourse(p.db.QueryRowContext(ctx,
+		`UPDATE courses SET status = $1, publish_at = $2, published_at = $3, version = version + 1, updated_at = NOW()
+		 WHERE id = $4 AND deleted_at IS NULL AND ($5 = 0 OR version = $5)
+		 RETURNING `+courseColumns,
+		c.Status, c.PublishAt, c.PublishedAt, c.ID, c.Version,
+	), c)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p.missingOrStale(ctx, "courses", c.ID)
+	}
+	return err
+}
+
+// PublishDue records the scheduled time, not the time the job got round to
+// it, as published_at.
+func (p *Postgres) PublishDue(ctx context.Context, now time.Time) (int64, error) {
+	result, err := p.db.ExecContext(ctx,
+		`UPDATE courses SET status = 'published', published_at = publish_at, publish_at = NULL, version = version + 1, updated_at = NOW()
+		 WHERE publish_at <= $1 AND deleted_at IS NULL`,
+		now,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// missingOrStale explains an UPDATE that matched no row: either the row is
+// gone (or deleted) or its version moved on.
+func (p *Postgres) missingOrStale(ctx context.Context, table string, id int) error {
+	var exists bool
+	if err := p.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM "+table+" WHERE id = $1 AND deleted_at IS NULL)", id).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return ErrVersionConflict
+	}
+	return ErrNotFound
+}
+
+// claimSlug moves a course from slug from to slug to: to must not be a former
+// slug of another course, and from becomes a former slug of this one. A
+// course can go back to one of its own former slugs. Clashes with the current
+// slug of another course are left to courses_unique_id_key.
+func claimSlug(ctx context.Context, tx *database.Tx, courseID int, from, to string) error {
+	if from == to {
+		return nil
+	}
+	var owner int
+	err := tx.QueryRowContext(ctx, "DELETE FROM course_slugs WHERE slug = $1 RETURNING course_id", to).Scan(&owner)
+	if err == nil && owner != courseID {
+		return slugTaken()
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if from == "" {
+		return nil
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO course_slugs (slug, course_id) VALUES ($1, $2)", from, courseID)
+	return err
+}
+
+func (p *Postgres) FindSlug(ctx context.Context, slug string) (int, error) {
+	var id int
+	err := p.db.QueryRowContext(ctx,
+		"SELECT id FROM courses WHERE unique_id = $1 UNION ALL SELECT course_id FROM course_slugs WHERE slug = $1 LIMIT 1", slug,
+	).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return id, err
+}
+
+func (p *Postgres) TakenSlugs(ctx context.Context, base string, courseID int) ([]string, error) {
+	rows, err := p.db.QueryContext(ctx,
+		`SELECT unique_id FROM courses WHERE id <> $2 AND (unique_id = $1 OR unique_id LIKE $1 || '-%')
+		 UNION SELECT slug FROM course_slugs WHERE course_id <> $2 AND (slug = $1 OR slug LIKE $1 || '-%')`,
+		base, courseID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var slugs []string
+	for rows.Next() {
+		var slug string
+		if err := rows.Scan(&slug); err != nil {
+			return nil, err
+		}
+		slugs = append(slugs, slug)
+	}
+	return slugs, rows.Err()
+}
+
+func replaceTags(ctx context.Context, tx *database.Tx, courseID int, tags []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM course_tags WHERE course_id = $1", courseID); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO course_tags (course_id, tag) SELECT $1, unnest($2::text[])", courseID, pq.Array(tags))
+	return err
+}
+
+// inTx runs fn in a transaction, committing only if it succeeds.
+func (p *Postgres) inTx(ctx context.Context, fn func(*database.Tx) error) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertRevision(ctx context.Context, tx *database.Tx, table, key string, id, version int, e Edit, from, to map[string]string) error {
+	fields, err := json.Marshal(to)
+	if err != nil {
+		return err
+	}
+	changes, err := json.Marshal(Changes(from, to))
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO "+table+" ("+key+", version, author, rollback_of, fields, changes) VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, 0), $5, $6)",
+		id, version, e.Author, e.RollbackOf, fields, changes,
+	)
+	return err
+}
+
+const revisionColumns = "version, COALESCE(author, ''), created_at, COALESCE(rollback_of, 0), fields, changes"
+
+func scanRevision(row interface{ Scan(...interface{}) error }, r *Revision) error {
+	var fields, changes []byte
+	if err := row.Scan(&r.Version, &r.Author, &r.CreatedAt, &r.RollbackOf, &fields, &changes); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(fields, &r.Fields); err != nil {
+		return err
+	}
+	return json.Unmarshal(changes, &r.Changes)
+}
+
+func (p *Postgres) listRevisions(ctx context.Context, table, key string, id int, page paging.Page) ([]Revision, error) {
+	rows, err := p.db.QueryContext(ctx,
+		"SELECT "+revisionColumns+" FROM "+table+" WHERE "+key+" = $1 ORDER BY version LIMIT $2 OFFSET $3",
+		id, page.LimitArg(), page.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []Revision
+	for rows.Next() {
+		var r Revision
+		if err := scanRevision(rows, &r); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, r)
+	}
+	return revisions, rows.Err()
+}
+
+func (p *Postgres) getRevision(ctx context.Context, table, key string, id, version int) (Revision, error) {
+	var r Revision
+	err := scanRevision(p.db.QueryRowContext(ctx,
+		"SELECT "+revisionColumns+" FROM "+table+" WHERE "+key+" = $1 AND version = $2", id, version,
+	), &r)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Revision{}, ErrNotFound
+	}
+	return r, err
+}
+
+func (p *Postgres) ListCourseRevisions(ctx context.Context, courseID int, page paging.Page) ([]Revision, error) {
+	return p.listRevisions(ctx, "course_revisions", "course_id", courseID, page)
+}
+
+func (p *Postgres) GetCourseRevision(ctx context.Context, courseID, version int) (Revision, error) {
+	return p.getRevision(ctx, "course_revisions", "course_id", courseID, version)
+}
+
+// DeleteCourse stamps the course and its live series with the same NOW(), so
+// RestoreCourse can tell which series went with it.
+func (p *Postgres) DeleteCourse(ctx context.Context, id int) error {
+	return p.inTx(ctx, func(tx *database.Tx) error {
+		var deleted bool
+		err := tx.QueryRowContext(ctx,
+			`WITH course AS (
+			     UPDATE courses SET deleted_at = NOW(), version = version + 1, updated_at = NOW()
+			     WHERE id = $1 AND deleted_at IS NULL
+			     RETURNING id, deleted_at
+			 ), series_deleted AS (
+			     UPDATE series SET deleted_at = course.deleted_at, version = series.version + 1, updated_at = NOW()
+			     FROM course WHERE series.course_id = course.id AND series.deleted_at IS NULL
+			 )
+			 SELECT EXISTS (SELECT 1 FROM course)`,
+			id,
+		).Scan(&deleted)
+		if err != nil || !deleted {
+			return err
+		}
+		return events.Record(ctx, tx, events.CourseOutbox, events.CourseDeleted{CourseID: id})
+	})
+}
+
+func (p *Postgres) RestoreCourse(ctx context.Context, id int) (Course, error) {
+	var course Course
+	err := scanCourse(p.db.QueryRowContext(ctx,
+		`WITH deleted AS (
+		     SELECT id AS course_id, deleted_at AS stamp FROM courses WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE
+		 ), series_restored AS (
+		     UPDATE series SET deleted_at = NULL, version = series.version + 1, updated_at = NOW()
+		     FROM deleted WHERE series.course_id = deleted.course_id AND series.deleted_at = deleted.stamp
+		 )
+		 UPDATE courses SET deleted_at = NULL, version = version + 1, updated_at = NOW()
+		 FROM deleted WHERE courses.id = deleted.course_id
+		 RETURNING `+courseColumns,
+		id,
+	), &course)
+	if errors.Is(err, sql.ErrNoRows) {
+		return p.GetCourse(ctx, id, Filter{})
+	}
+	return course, err
+}
+
+func (p *Postgres) PurgeCourses(ctx context.Context, before time.Time) (int64, error) {
+	return p.purge(ctx, "courses", before)
+}
+
+func (p *Postgres) purge(ctx context.Context, table string, before time.Time) (int64, error) {
+	result, err := p.db.ExecContext(ctx, "DELETE FROM "+table+" WHERE deleted_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// seriesCourseStatus restricts a series query to series whose course has
+// the status in the given parameter, or to any series when it is empty.
+func seriesCourseStatus(param string) string {
+	return "(" + param + " = '' OR EXISTS (SELECT 1 FROM courses WHERE courses.id = series.course_id AND courses.status = " + param + "))"
+}
+
+func (p *Postgres) ListSeries(ctx context.Context, courseID int, page paging.Page, f Filter) ([]Series, error) {
+	rows, err := p.db.QueryContext(ctx,
+		"SELECT "+seriesColumns+" FROM series WHERE course_id = $1 AND ($2 OR deleted_at IS NULL) AND "+seriesCourseStatus("$3")+" ORDER BY id LIMIT $4 OFFSET $5",
+		courseID, f.IncludeDeleted, f.Status, page.LimitArg(), page.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var seriesList []Series
+	for rows.Next() {
+		var s Series
+		if err := scanSeries(rows, &s); err != nil {
+			return nil, err
+		}
+		seriesList = append(seriesList, s)
+	}
+	return seriesList, rows.Err()
+}
+
+func (p *Postgres) GetSeries(ctx context.Context, id int, f Filter) (Series, error) {
+	var s Series
+	err := scanSeries(p.db.QueryRowContext(ctx,
+		"SELECT "+seriesColumns+" FROM series WHERE id = $1 AND ($2 OR deleted_at IS NULL) AND "+seriesCourseStatus("$3"),
+		id, f.IncludeDeleted, f.Status,
+	), &s)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Series{}, ErrNotFound
+	}
+	return s, err
+}
+
+// CreateSeries inserts through a SELECT so that a deleted course, which the
+// foreign key still accepts, is refused like a missing one.
+func (p *Postgres) CreateSeries(ctx context.Context, s *Series, e Edit) error {
+	return p.inTx(ctx, func(tx *database.Tx) error {
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO series (course_id, title, description, duration, is_free_preview)
+			 SELECT id, $2, $3, $4, $5 FROM courses WHERE id = $1 AND deleted_at IS NULL
+			 RETURNING id, created_at, updated_at, version`,
+			s.CourseID, s.Title, s.Description, s.Duration, s.IsFreePreview,
+		).Scan(&s.ID, &s.CreatedAt, &s.UpdatedAt, &s.Version)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCourseNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return insertRevision(ctx, tx, "series_revisions", "series_id", s.ID, s.Version, e, nil, s.fields())
+	})
+}
+
+func (p *Postgres) UpdateSeries(ctx context.Context, s *Series, e Edit) error {
+	return p.inTx(ctx, func(tx *database.Tx) error {
+		var old Series
+		err := scanSeries(tx.QueryRowContext(ctx,
+			"SELECT "+seriesColumns+" FROM series WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", s.ID,
+		), &old)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if s.Version != 0 && s.Version != old.Version {
+			return ErrVersionConflict
+		}
+		err = scanSeries(tx.QueryRowContext(ctx,
+			`UPDATE series SET title = $1, description = $2, duration = $3, is_free_preview = $4, version = version + 1, updated_at = NOW()
+			 WHERE id = $5
+			 RETURNING `+seriesColumns,
+			s.Title, s.Description, s.Duration, s.IsFreePreview, s.ID,
+		), s)
+		if err != nil {
+			return err
+		}
+		return insertRevision(ctx, tx, "series_revisions", "series_id", s.ID, s.Version, e, old.fields(), s.fields())
+	})
+}
+
+func (p *Postgres) ListSeriesRevisions(ctx context.Context, seriesID int, page paging.Page) ([]Revision, error) {
+	return p.listRevisions(ctx, "series_revisions", "series_id", seriesID, page)
+}
+
+func (p *Postgres) GetSeriesRevision(ctx context.Context, seriesID, version int) (Revision, error) {
+	return p.getRevision(ctx, "series_revisions", "series_id", seriesID, version)
+}
+
+func (p *Postgres) DeleteSeries(ctx context.Context, id int) error {
+	_, err := p.db.ExecContext(ctx,
+		"UPDATE series SET deleted_at = NOW(), version = version + 1, updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL", id)
+	return err
+}
+
+func (p *Postgres) RestoreSeries(ctx context.Context, id int) (Series, error) {
+	var s Series
+	err := scanSeries(p.db.QueryRowContext(ctx,
+		`UPDATE series SET deleted_at = NULL, version = version + 1, updated_at = NOW()
+		 WHERE id = $1 AND deleted_at IS NOT NULL
+		   AND EXISTS (SELECT 1 FROM courses WHERE courses.id = series.course_id AND courses.deleted_at IS NULL)
+		 RETURNING `+seriesColumns,
+		id,
+	), &s)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return s, err
+	}
+	// Nothing restored: the series is live already, missing, or stuck
+	// behind its deleted course.
+	if s, err = p.GetSeries(ctx, id, Filter{IncludeDeleted: true}); err != nil {
+		return Series{}, err
+	}
+	if s.DeletedAt != nil {
+		return Series{}, ErrCourseNotFound
+	}
+	return s, nil
+}
+
+func (p *Postgres) PurgeSeries(ctx context.Context, before time.Time) (int64, error) {
+	return p.purge(ctx, "series", before)
+}
+
+// Width and height are stored as NULL when unknown.
+const mediaColumns = "id, sha256, content_type, size, kind, COALESCE(width, 0), COALESCE(height, 0), COALESCE(uploaded_by, ''), created_at"
+
+func scanMedia(row interface{ Scan(...interface{}) error }, m *Media) error {
+	return row.Scan(&m.ID, &m.Checksum, &m.ContentType, &m.Size, &m.Kind, &m.Width, &m.Height, &m.UploadedBy, &m.CreatedAt)
+}
+
+// CreateMedia leaves a concurrent upload of the same content to the unique
+// sha256 constraint: the loser inserts nothing and reads the winner's row.
+func (p *Postgres) CreateMedia(ctx context.Context, m *Media) (bool, error) {
+	err := scanMedia(p.db.QueryRowContext(ctx,
+		`INSERT INTO media (sha256, content_type, size, kind, width, height, uploaded_by)
+		 VALUES ($1, $2, $3, $4, NULLIF($5, 0), NULLIF($6, 0), NULLIF($7, ''))
+		 ON CONFLICT (sha256) DO NOTHING RETURNING `+mediaColumns,
+		m.Checksum, m.ContentType, m.Size, m.Kind, m.Width, m.Height, m.UploadedBy,
+	), m)
+	if errors.Is(err, sql.ErrNoRows) {
+		existing, err := p.FindMedia(ctx, m.Checksum)
+		if err != nil {
+			return false, err
+		}
+		*m = existing
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (p *Postgres) GetMedia(ctx context.Context, id int) (Media, error) {
+	var m Media
+	err := scanMedia(p.db.QueryRowContext(ctx, "SELECT "+mediaColumns+" FROM media WHERE id = $1", id), &m)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Media{}, ErrNotFound
+	}
+	return m, err
+}
+
+func (p *Postgres) FindMedia(ctx context.Context, checksum string) (Media, error) {
+	var m Media
+	err := scanMedia(p.db.QueryRowContext(ctx, "SELECT "+mediaColumns+" FROM media WHERE sha256 = $1", checksum), &m)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Media{}, ErrNotFound
+	}
+	return m, err
+}
+
+const categoryColumns = "id, parent_id, name, slug, created_at, updated_at"
+
+func scanCategory(row interface{ Scan(...interface{}) error }, c *Category) error {
+	return row.Scan(&c.ID, &c.ParentID, &c.Name, &c.Slug, &c.CreatedAt, &c.UpdatedAt)
+}
+
+func (p *Postgres) ListCategories(ctx context.Context) ([]Category, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT "+categoryColumns+" FROM categories ORDER BY name, id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []Category
+	for rows.Next() {
+		var c Category
+		if err := scanCategory(rows, &c); err != nil {
+			return nil, err
+		}
+		categories = append(categories, c)
+	}
+	return categories, rows.Err()
+}
+
+func (p *Postgres) GetCategory(ctx context.Context, id int) (Category, error) {
+	var c Category
+	err := scanCategory(p.db.QueryRowContext(ctx, "SELECT "+categoryColumns+" FROM categories WHERE id = $1", id), &c)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Category{}, ErrNotFound
+	}
+	return c, err
+}
+
+func (p *Postgres) CreateCategory(ctx context.Context, c *Category) error {
+	return scanCategory(p.db.QueryRowContext(ctx,
+		"INSERT INTO categories (parent_id, name, slug) VALUES ($1, $2, $3) RETURNING "+categoryColumns,
+		c.ParentID, c.Name, c.Slug,
+	), c)
+}
+
+func (p *Postgres) UpdateCategory(ctx context.Context, c *Category) error {
+	err := scanCategory(p.db.QueryRowContext(ctx,
+		"UPDATE categories SET parent_id = $1, name = $2, slug = $3, updated_at = NOW() WHERE id = $4 RETURNING "+categoryColumns,
+		c.ParentID, c.Name, c.Slug, c.ID,
+	), c)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// DeleteCategory checks for dependants itself rather than leaving it to the
+// foreign keys, whose violations callers would see as a missing category.
+func (p *Postgres) DeleteCategory(ctx context.Context, id int) error {
+	return p.inTx(ctx, func(tx *database.Tx) error {
+		err := tx.QueryRowContext(ctx, "SELECT id FROM categories WHERE id = $1 FOR UPDATE", id).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		var inUse bool
+		err = tx.QueryRowContext(ctx,
+			"SELECT EXISTS(SELECT 1 FROM categories WHERE parent_id = $1) OR EXISTS(SELECT 1 FROM courses WHERE category_id = $1)", id,
+		).Scan(&inUse)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return ErrCategoryInUse
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM categories WHERE id = $1", id)
+		return err
+	})
+}
+
+const instructorColumns = "course_id, user_id, role, created_at"
+
+func scanInstructor(row interface{ Scan(...interface{}) error }, in *Instructor) error {
+	return row.Scan(&in.CourseID, &in.UserID, &in.Role, &in.CreatedAt)
+}
+
+func (p *Postgres) ListInstructors(ctx context.Context, courseID int) ([]Instructor, error) {
+	rows, err := p.db.QueryContext(ctx,
+		"SELECT "+instructorColumns+" FROM course_instructors WHERE course_id = $1 ORDER BY user_id", courseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var instructors []Instructor
+	for rows.Next() {
+		var in Instructor
+		if err := scanInstructor(rows, &in); err != nil {
+			return nil, err
+		}
+		instructors = append(instructors, in)
+	}
+	return instructors, rows.Err()
+}
+
+func (p *Postgres) InstructorRole(ctx context.Context, courseID, userID int) (string, error) {
+	var role string
+	err := p.db.QueryRowContext(ctx,
+		"SELECT role FROM course_instructors WHERE course_id = $1 AND user_id = $2", courseID, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
+}
+
+// lockOwners locks every instructor row of the course, so that concurrent
+// demotions cannot both see another owner remaining, and reports whether the
+// user is the only owner.
+func lockOwners(ctx context.Context, tx *database.Tx, courseID, userID int) (bool, error) {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT user_id, role FROM course_instructors WHERE course_id = $1 FOR UPDATE", courseID)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	owner, others := false, false
+	for rows.Next() {
+		var id int
+		var role string
+		if err := rows.Scan(&id, &role); err != nil {
+			return false, err
+		}
+		if role == RoleOwner {
+			owner = owner || id == userID
+			others = others || id != userID
+		}
+	}
+	return owner && !others, rows.Err()
+}
+
+func (p *Postgres) SetInstructor(ctx context.Context, in *Instructor) error {
+	return p.inTx(ctx, func(tx *database.Tx) error {
+		last, err := lockOwners(ctx, tx, in.CourseID, in.UserID)
+		if err != nil {
+			return err
+		}
+		if last && in.Role != RoleOwner {
+			return ErrLastOwner
+		}
+		return scanInstructor(tx.QueryRowContext(ctx,
+			`INSERT INTO course_instructors (course_id, user_id, role) VALUES ($1, $2, $3)
+			 ON CONFLICT (course_id, user_id) DO UPDATE SET role = EXCLUDED.role
+			 RETURNING `+instructorColumns,
+			in.CourseID, in.UserID, in.Role,
+		), in)
+	})
+}
+
+func (p *Postgres) RemoveInstructor(ctx context.Context, courseID, userID int) error {
+	return p.inTx(ctx, func(tx *database.Tx) error {
+		last, err := lockOwners(ctx, tx, courseID, userID)
+		if err != nil {
+			return err
+		}
+		if last {
+			return ErrLastOwner
+		}
+		result, err := tx.ExecContext(ctx,
+			"DELETE FROM course_instructors WHERE course_id = $1 AND user_id = $2", courseID, userID)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		if err == nil && n == 0 {
+			return ErrNotFound
+		}
+		return err
+	})
+}
+
+const prerequisiteColumns = "p.course_id, p.prerequisite_id, c.title, p.created_at"
+
+func scanPrerequisite(row interface{ Scan(...interface{}) error }, pr *Prerequisite) error {
+	return row.Scan(&pr.CourseID, &pr.PrerequisiteID, &pr.Title, &pr.CreatedAt)
+}
+
+func (p *Postgres) ListPrerequisites(ctx context.Context, courseID int) ([]Prerequisite, error) {
+	rows, err := p.db.QueryContext(ctx,
+		`SELECT `+prerequisiteColumns+` FROM course_prerequisites p
+		 JOIN courses c ON c.id = p.prerequisite_id AND c.deleted_at IS NULL AND c.status = 'published'
+		 WHERE p.course_id = $1 ORDER BY p.prerequisite_id`, courseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var prerequisites []Prerequisite
+	for rows.Next() {
+		var pr Prerequisite
+		if err := scanPrerequisite(rows, &pr); err != nil {
+			return nil, err
+		}
+		prerequisites = append(prerequisites, pr)
+	}
+	return prerequisites, rows.Err()
+}
+
+// AddPrerequisite walks everything the prerequisite requires with a
+// recursive query. Writers take turns on the table, so two edges added at
+// once cannot close a cycle that neither saw.
+func (p *Postgres) AddPrerequisite(ctx context.Context, pr *Prerequisite) error {
+	return p.inTx(ctx, func(tx *database.Tx) error {
+		if _, err := tx.ExecContext(ctx, "LOCK TABLE course_prerequisites IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+			return err
+		}
+		var cycle bool
+		err := tx.QueryRowContext(ctx,
+			`WITH RECURSIVE required(id) AS (
+			     SELECT prerequisite_id FROM course_prerequisites WHERE course_id = $1
+			     UNION
+			     SELECT p.prerequisite_id FROM course_prerequisites p JOIN required r ON p.course_id = r.id
+			 )
+			 SELECT EXISTS(SELECT 1 FROM required WHERE id = $2)`,
+			pr.PrerequisiteID, pr.CourseID,
+		).Scan(&cycle)
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrPrerequisiteCycle
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO course_prerequisites (course_id, prerequisite_id) VALUES ($1, $2)
+			 ON CONFLICT (course_id, prerequisite_id) DO NOTHING`,
+			pr.CourseID, pr.PrerequisiteID)
+		if err != nil {
+			return err
+		}
+		return scanPrerequisite(tx.QueryRowContext(ctx,
+			`SELECT `+prerequisiteColumns+` FROM course_prerequisites p JOIN courses c ON c.id = p.prerequisite_id
+			 WHERE p.course_id = $1 AND p.prerequisite_id = $2`,
+			pr.CourseID, pr.PrerequisiteID,
+		), pr)
+	})
+}
+
+func (p *Postgres) RemovePrerequisite(ctx context.Context, courseID, prerequisiteID int) error {
+	result, err := p.db.ExecContext(ctx,
+		"DELETE FROM course_prerequisites WHERE course_id = $1 AND prerequisite_id = $2", courseID, prerequisiteID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+const quizColumns = "id, series_id, title, passing_score, max_attempts, required, questions, created_at, updated_at"
+
+func scanQuiz(row interface{ Scan(...interface{}) error }, q *Quiz) error {
+	var questions []byte
+	if err := row.Scan(&q.ID, &q.SeriesID, &q.Title, &q.PassingScore, &q.MaxAttempts, &q.Required, &questions, &q.CreatedAt, &q.UpdatedAt); err != nil {
+		return err
+	}
+	return json.Unmarshal(questions, &q.Questions)
+}
+
+func (p *Postgres) ListQuizzes(ctx context.Context, seriesID int) ([]Quiz, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT "+quizColumns+" FROM quizzes WHERE series_id = $1 ORDER BY id", seriesID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var quizzes []Quiz
+	for rows.Next() {
+		var q Quiz
+		if err := scanQuiz(rows, &q); err != nil {
+			return nil, err
+		}
+		quizzes = append(quizzes, q)
+	}
+	return quizzes, rows.Err()
+}
+
+func (p *Postgres) GetQuiz(ctx context.Context, id int) (Quiz, error) {
+	var q Quiz
+	err := scanQuiz(p.db.QueryRowContext(ctx, "SELECT "+quizColumns+" FROM quizzes WHERE id = $1", id), &q)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Quiz{}, ErrNotFound
+	}
+	return q, err
+}
+
+func (p *Postgres) CreateQuiz(ctx context.Context, q *Quiz) error {
+	questions, err := json.Marshal(q.Questions)
+	if err != nil {
+		return err
+	}
+	return scanQuiz(p.db.QueryRowContext(ctx,
+		`INSERT INTO quizzes (series_id, title, passing_score, max_attempts, required, questions)
+		 VALUES ($1, $2, $3, $4, $5, $6) RETURNING `+quizColumns,
+		q.SeriesID, q.Title, q.PassingScore, q.MaxAttempts, q.Required, questions,
+	), q)
+}
+
+func (p *Postgres) UpdateQuiz(ctx context.Context, q *Quiz) error {
+	questions, err := json.Marshal(q.Questions)
+	if err != nil {
+		return err
+	}
+	err = scanQuiz(p.db.QueryRowContext(ctx,
+		`UPDATE quizzes SET title = $1, passing_score = $2, max_attempts = $3, required = $4, questions = $5, updated_at = NOW()
+		 WHERE id = $6 RETURNING `+quizColumns,
+		q.Title, q.PassingScore, q.MaxAttempts, q.Required, questions, q.ID,
+	), q)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (p *Postgres) DeleteQuiz(ctx context.Context, id int) error {
+	result, err := p.db.ExecContext(ctx, "DELETE FROM quizzes WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err == nil && n == 0 {
+		return ErrNotFound
+	}
+	return err
+}
+
+const attemptColumns = "id, quiz_id, user_id, answers, correct, score, passed, created_at"
+
+func scanAttempt(row interface{ Scan(...interface{}) error }, a *Attempt) error {
+	var answers, correct []byte
+	if err := row.Scan(&a.ID, &a.QuizID, &a.UserID, &answers, &correct, &a.Score, &a.Passed, &a.CreatedAt); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(answers, &a.Answers); err != nil {
+		return err
+	}
+	return json.Unmarshal(correct, &a.Correct)
+}
+
+// CreateAttempt locks the quiz row so that concurrent attempts by the same
+// user cannot both slip under the limit.
+func (p *Postgres) CreateAttempt(ctx context.Context, a *Attempt, maxAttempts int) error {
+	answers, err := json.Marshal(a.Answers)
+	if err != nil {
+		return err
+	}
+	correct, err := json.Marshal(a.Correct)
+	if err != nil {
+		return err
+	}
+	return p.inTx(ctx, func(tx *database.Tx) error {
+		var id, used int
+		err := tx.QueryRowContext(ctx, "SELECT id FROM quizzes WHERE id = $1 FOR UPDATE", a.QuizID).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		err = tx.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM quiz_attempts WHERE quiz_id = $1 AND user_id = $2", a.QuizID, a.UserID).Scan(&used)
+		if err != nil {
+			return err
+		}
+		if maxAttempts > 0 && used >= maxAttempts {
+			return ErrAttemptsUsed
+		}
+		return scanAttempt(tx.QueryRowContext(ctx,
+			`INSERT INTO quiz_attempts (quiz_id, user_id, answers, correct, score, passed)
+			 VALUES ($1, $2, $3, $4, $5, $6) RETURNING `+attemptColumns,
+			a.QuizID, a.UserID, answers, correct, a.Score, a.Passed,
+		), a)
+	})
+}
+
+func (p *Postgres) ListAttempts(ctx context.Context, quizID, userID int) ([]Attempt, error) {
+	rows, err := p.db.QueryContext(ctx,
+		"SELECT "+attemptColumns+" FROM quiz_attempts WHERE quiz_id = $1 AND ($2 = 0 OR user_id = $2) ORDER BY id",
+		quizID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []Attempt
+	for rows.Next() {
+		var a Attempt
+		if err := scanAttempt(rows, &a); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+func (p *Postgres) Enrolled(ctx context.Context, userID, courseID int) (bool, error) {
+	var enrolled bool
+	err := p.db.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM user_course_enrollments WHERE user_id = $1 AND course_id = $2 AND status <> 'waitlisted')", userID, courseID,
+	).Scan(&enrolled)
+	return enrolled, err
+}
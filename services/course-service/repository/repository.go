@@ -0,0 +1,481 @@
+// Package repository is the persistence layer of the course-service.
+// Handlers and business logic depend only on the CourseRepository,
+// SeriesRepository, MediaRepository, CategoryRepository,
+// InstructorRepository, QuizRepository and PrerequisiteRepository interfaces
+// so they can be exercised against the in-memory implementation in tests.
+package repository
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"mopcare/paging"
+	"mopcare/problem"
+)
+
+var (
+	// ErrNotFound is returned when the addressed row does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrVersionConflict is returned when an update names a version that is
+	// no longer current.
+	ErrVersionConflict = errors.New("version conflict")
+	// ErrCourseNotFound is returned when a series is created in or restored
+	// into a course that does not exist or is deleted.
+	ErrCourseNotFound = errors.New("course not found")
+	// ErrCategoryInUse is returned when deleting a category that still has
+	// subcategories or courses.
+	ErrCategoryInUse = errors.New("category in use")
+	// ErrLastOwner is returned when a change would leave a course that has
+	// an owner without one.
+	ErrLastOwner = errors.New("last owner")
+	// ErrAttemptsUsed is returned when a user has no quiz attempts left.
+	ErrAttemptsUsed = errors.New("attempts used")
+	// ErrPrerequisiteCycle is returned when a prerequisite would end up
+	// requiring the course it is added to.
+	ErrPrerequisiteCycle = errors.New("prerequisite cycle")
+)
+
+// slugTaken is the problem for a slug held by another course. It matches what
+// the courses_unique_id_key constraint maps to, so callers see one error
+// whichever check caught the clash.
+func slugTaken() error {
+	return problem.Conflict(problem.CodeCourseUniqueIDTaken, "Resource conflicts with an existing one")
+}
+
+// Course statuses. A course moves draft -> in_review -> published ->
+// archived; learners only ever see published ones.
+const (
+	StatusDraft     = "draft"
+	StatusInReview  = "in_review"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// Filter narrows course and series reads. The zero value matches every live
+// row whatever its status, which is what writes that re-read a row want.
+type Filter struct {
+	// IncludeDeleted adds soft-deleted rows.
+	IncludeDeleted bool
+	// Status, when set, only matches courses in that status, and series
+	// whose course is in it.
+	Status string
+	// Author, when set, also lets courses the user owns or co-authors
+	// through Status, whatever theirs. Only ListCourses and CourseFacets
+	// heed it.
+	Author int
+
+	// The remaining fields only narrow ListCourses and CourseFacets.
+
+	// CategoryIDs, when non-nil, only matches courses in one of the
+	// categories; an empty slice matches none.
+	CategoryIDs []int
+	// Tags only matches courses carrying every one of them.
+	Tags                     []string
+	MinPrice, MaxPrice       *float64
+	MinDuration, MaxDuration *int
+	// FreePreview, when set, only matches courses that do or do not have a
+	// free preview series.
+	FreePreview *bool
+	// Instructor, when set, only matches courses the user is an instructor
+	// of, with InstructorRole if that is set too.
+	Instructor     int
+	InstructorRole string
+}
+
+// Course and Series carry a Version that starts at 1 and is bumped by every
+// update; it is the ETag clients send back in If-Match.
+//
+// Deleting only sets DeletedAt. Deleted rows are invisible to reads unless
+// Filter.IncludeDeleted is set, cannot be updated, and are removed for good
+// by the Purge methods.
+//
+// Status, PublishAt and PublishedAt are only changed by UpdateStatus and
+// PublishDue; UpdateCourse leaves them alone.
+//
+// UniqueID is the course's URL slug. When a write changes it, the old slug is
+// kept as a former slug of the course: it still finds the course, and no
+// other course may take it.
+//
+// Tags are kept sorted. Duration and HasFreePreview are read-only: they sum
+// up the course's live series.
+//
+// Capacity and the enrollment window are enforced by the enrollment-service.
+// Like the status they are not part of a revision.
+type Course struct {
+	ID               int      `json:"id"`
+	Title            string   `json:"title"`
+	Content          string   `json:"content"`
+	OverviewVideoURL string   `json:"overview_video_url"`
+	CoverImageURL    string   `json:"cover_image_url"`
+	UniqueID         string   `json:"unique_id"`
+	CategoryID       *int     `json:"category_id"`
+	Tags             []string `json:"tags"`
+	Price            float64  `json:"price"`
+	// Capacity is how many users may hold a seat, or nil for no limit.
+	Capacity *int `json:"capacity"`
+	// EnrollmentOpensAt and EnrollmentClosesAt bound when users may enroll;
+	// nil leaves that end open.
+	EnrollmentOpensAt  *time.Time `json:"enrollment_opens_at"`
+	EnrollmentClosesAt *time.Time `json:"enrollment_closes_at"`
+	// Duration is the total of the series durations, in minutes.
+	Duration       int        `json:"duration"`
+	HasFreePreview bool       `json:"has_free_preview"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	Version        int        `json:"version"`
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+	Status         string     `json:"status"`
+	// PublishAt is when a scheduled publication is due.
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+}
+
+type Series struct {
+	ID          int    `json:"id"`
+	CourseID    int    `json:"course_id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// Duration is in minutes.
+	Duration      int        `json:"duration"`
+	IsFreePreview bool       `json:"is_free_preview"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Version       int        `json:"version"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Revision is the immutable record of one content write to a course or
+// series: its writable fields as they were after the write, and how they
+// differ from before it. Revisions are keyed by the Version the write
+// produced, so status changes and deletes, which bump the version without
+// touching content, leave gaps in the numbering.
+type Revision struct {
+	Version int `json:"version"`
+	// Author is who made the write, or empty for anonymous callers.
+	Author    string    `json:"author,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	// RollbackOf is the version whose content this write restored.
+	RollbackOf int               `json:"rollback_of,omitempty"`
+	Fields     map[string]string `json:"fields"`
+	Changes    map[string]Change `json:"changes"`
+}
+
+// Change is the old and new value of one field.
+type Change struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Changes lists the fields whose values differ between two field sets. A
+// field missing on one side counts as empty.
+func Changes(from, to map[string]string) map[string]Change {
+	changes := map[string]Change{}
+	for k, v := range to {
+		if from[k] != v {
+			changes[k] = Change{From: from[k], To: v}
+		}
+	}
+	for k, v := range from {
+		if _, ok := to[k]; !ok && v != "" {
+			changes[k] = Change{From: v}
+		}
+	}
+	return changes
+}
+
+// Edit says who made a content write, for the revision it records.
+type Edit struct {
+	Author     string
+	RollbackOf int
+	// Owner is the user CreateCourse makes the new course's owner, if any.
+	Owner int
+}
+
+// Instructor roles. Owners may do anything to their course, co-authors edit
+// its content and series, and reviewers publish it or send it back.
+const (
+	RoleOwner    = "owner"
+	RoleCoAuthor = "co-author"
+	RoleReviewer = "reviewer"
+)
+
+// Instructor is a user's role on a course.
+type Instructor struct {
+	CourseID  int       `json:"course_id"`
+	UserID    int       `json:"user_id"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Prerequisite is a course that must be completed before enrolling in
+// another. Title is the prerequisite's.
+type Prerequisite struct {
+	CourseID       int       `json:"course_id"`
+	PrerequisiteID int       `json:"prerequisite_id"`
+	Title          string    `json:"title"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// Question types. True/false questions always have the options "True" and
+// "False".
+const (
+	QuestionMultipleChoice = "multiple_choice"
+	QuestionTrueFalse      = "true_false"
+)
+
+// Quiz is a set of questions on a series. An attempt passes with a score of
+// at least PassingScore percent; MaxAttempts limits how many attempts each
+// user gets, zero meaning no limit. A course's enrollments can only be
+// completed once every Required quiz on its live series is passed.
+type Quiz struct {
+	ID           int        `json:"id"`
+	SeriesID     int        `json:"series_id"`
+	Title        string     `json:"title"`
+	PassingScore int        `json:"passing_score"`
+	MaxAttempts  int        `json:"max_attempts"`
+	Required     bool       `json:"required"`
+	Questions    []Question `json:"questions"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Question is one question of a quiz. Answer is the index of the correct
+// option; it is nil where learners read the quiz.
+type Question struct {
+	Type    string   `json:"type"`
+	Prompt  string   `json:"prompt"`
+	Options []string `json:"options"`
+	Answer  *int     `json:"answer,omitempty"`
+}
+
+// Attempt is one graded submission of a quiz. Answers holds the chosen
+// option per question and Correct whether each was right; Score is the
+// percentage of correct answers.
+type Attempt struct {
+	ID        int       `json:"id"`
+	QuizID    int       `json:"quiz_id"`
+	UserID    int       `json:"user_id"`
+	Answers   []int     `json:"answers"`
+	Correct   []bool    `json:"correct"`
+	Score     int       `json:"score"`
+	Passed    bool      `json:"passed"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Media describes an uploaded blob. The bytes themselves are in the media
+// store under Checksum; Width and Height are only known for images.
+type Media struct {
+	ID          int    `json:"id"`
+	Checksum    string `json:"sha256"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Kind        string `json:"kind"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+	// UploadedBy is who uploaded the content first, or empty for anonymous
+	// callers.
+	UploadedBy string    `json:"uploaded_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// Category is a node in the catalog tree; top-level categories have no
+// ParentID.
+type Category struct {
+	ID        int       `json:"id"`
+	ParentID  *int      `json:"parent_id"`
+	Name      string    `json:"name"`
+	Slug      string    `json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TagCount is how many courses carry a tag.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// Facets summarises the courses matching a filter. Categories counts courses
+// by the category they are directly in; Tags is ordered by descending count,
+// then tag. The ranges are zero when nothing matches.
+type Facets struct {
+	Total                    int
+	Categories               map[int]int
+	Tags                     []TagCount
+	FreePreview              int
+	MinPrice, MaxPrice       float64
+	MinDuration, MaxDuration int
+}
+
+// The fields of a revision leave out category, tags and price while they are
+// unset, so revisions recorded before those existed do not show them as
+// changed.
+func (c Course) fields() map[string]string {
+	f := map[string]string{
+		"title":              c.Title,
+		"content":            c.Content,
+		"overview_video_url": c.OverviewVideoURL,
+		"cover_image_url":    c.CoverImageURL,
+		"unique_id":          c.UniqueID,
+	}
+	if c.CategoryID != nil {
+		f["category_id"] = strconv.Itoa(*c.CategoryID)
+	}
+	if len(c.Tags) > 0 {
+		f["tags"] = strings.Join(c.Tags, ",")
+	}
+	if c.Price != 0 {
+		f["price"] = strconv.FormatFloat(c.Price, 'f', 2, 64)
+	}
+	return f
+}
+
+func (s Series) fields() map[string]string {
+	f := map[string]string{"title": s.Title, "description": s.Description}
+	if s.Duration != 0 {
+		f["duration"] = strconv.Itoa(s.Duration)
+	}
+	if s.IsFreePreview {
+		f["is_free_preview"] = "true"
+	}
+	return f
+}
+
+type CourseRepository interface {
+	ListCourses(ctx context.Context, page paging.Page, f Filter) ([]Course, error)
+	// CourseFacets summarises every course matching f.
+	CourseFacets(ctx context.Context, f Filter) (Facets, error)
+	GetCourse(ctx context.Context, id int, f Filter) (Course, error)
+	// CreateCourse inserts c as a draft and fills in its ID, timestamps,
+	// Version and Status. Like UpdateCourse it records a revision.
+	CreateCourse(ctx context.Context, c *Course, e Edit) error
+	// UpdateCourse overwrites the writable fields of c.ID and records the
+	// revision in the same transaction. A non-zero c.Version must match the
+	// stored one or ErrVersionConflict is returned. On success c is refreshed
+	// from the stored row.
+	UpdateCourse(ctx context.Context, c *Course, e Edit) error
+	// UpdateStatus writes c.Status, c.PublishAt and c.PublishedAt with the
+	// same version check and refresh as UpdateCourse.
+	UpdateStatus(ctx context.Context, c *Course) error
+	// PublishDue publishes every live course whose PublishAt is not after
+	// now and returns how many.
+	PublishDue(ctx context.Context, now time.Time) (int64, error)
+	// DeleteCourse soft-deletes the course and, with the same timestamp,
+	// every live series in it.
+	DeleteCourse(ctx context.Context, id int) error
+	// RestoreCourse undeletes the course together with the series deleted
+	// along with it; series deleted on their own stay deleted. Restoring a
+	// live course returns it unchanged.
+	RestoreCourse(ctx context.Context, id int) (Course, error)
+	// PurgeCourses permanently removes courses deleted before the cutoff,
+	// cascading to their series and enrollments, and returns how many.
+	PurgeCourses(ctx context.Context, before time.Time) (int64, error)
+	// ListCourseRevisions returns one page of a course's revisions, oldest
+	// first.
+	ListCourseRevisions(ctx context.Context, courseID int, page paging.Page) ([]Revision, error)
+	GetCourseRevision(ctx context.Context, courseID, version int) (Revision, error)
+	// FindSlug returns the ID of the course whose current or former slug is
+	// slug, deleted courses included, or ErrNotFound.
+	FindSlug(ctx context.Context, slug string) (int, error)
+	// TakenSlugs returns the current and former slugs of courses other than
+	// courseID that are base or start with base followed by a hyphen.
+	TakenSlugs(ctx context.Context, base string, courseID int) ([]string, error)
+}
+
+type SeriesRepository interface {
+	ListSeries(ctx context.Context, courseID int, page paging.Page, f Filter) ([]Series, error)
+	GetSeries(ctx context.Context, id int, f Filter) (Series, error)
+	// CreateSeries inserts s and fills in its ID, timestamps and Version. It
+	// returns ErrCourseNotFound unless s.CourseID is a live course.
+	CreateSeries(ctx context.Context, s *Series, e Edit) error
+	// UpdateSeries overwrites the writable fields of s.ID, with the same
+	// revision, version check and refresh as UpdateCourse.
+	UpdateSeries(ctx context.Context, s *Series, e Edit) error
+	DeleteSeries(ctx context.Context, id int) error
+	// RestoreSeries undeletes the series. It returns ErrCourseNotFound while
+	// the series' course is itself deleted.
+	RestoreSeries(ctx context.Context, id int) (Series, error)
+	// PurgeSeries permanently removes series deleted before the cutoff.
+	PurgeSeries(ctx context.Context, before time.Time) (int64, error)
+	ListSeriesRevisions(ctx context.Context, seriesID int, page paging.Page) ([]Revision, error)
+	GetSeriesRevision(ctx context.Context, seriesID, version int) (Revision, error)
+}
+
+type MediaRepository interface {
+	// CreateMedia inserts m and fills in its ID and CreatedAt. If media with
+	// the same checksum already exists, m is overwritten with that row instead
+	// and false is returned.
+	CreateMedia(ctx context.Context, m *Media) (bool, error)
+	GetMedia(ctx context.Context, id int) (Media, error)
+	// FindMedia returns the media with the given checksum, or ErrNotFound.
+	FindMedia(ctx context.Context, checksum string) (Media, error)
+}
+
+type CategoryRepository interface {
+	// ListCategories returns every category ordered by name.
+	ListCategories(ctx context.Context) ([]Category, error)
+	GetCategory(ctx context.Context, id int) (Category, error)
+	// CreateCategory inserts c and fills in its ID and timestamps.
+	CreateCategory(ctx context.Context, c *Category) error
+	// UpdateCategory overwrites the parent, name and slug of c.ID and
+	// refreshes c from the stored row.
+	UpdateCategory(ctx context.Context, c *Category) error
+	// DeleteCategory removes the category, or returns ErrCategoryInUse while
+	// it has subcategories or courses, deleted courses included.
+	DeleteCategory(ctx context.Context, id int) error
+}
+
+type InstructorRepository interface {
+	// ListInstructors returns the instructors of a course ordered by user ID.
+	ListInstructors(ctx context.Context, courseID int) ([]Instructor, error)
+	// InstructorRole returns the user's role on the course, or "" if they
+	// have none.
+	InstructorRole(ctx context.Context, courseID, userID int) (string, error)
+	// SetInstructor adds the instructor or changes their role, and refreshes
+	// in from the stored row. Demoting the only owner returns ErrLastOwner.
+	SetInstructor(ctx context.Context, in *Instructor) error
+	// RemoveInstructor returns ErrNotFound if the user is not an instructor
+	// of the course, and ErrLastOwner if they are its only owner.
+	RemoveInstructor(ctx context.Context, courseID, userID int) error
+}
+
+type PrerequisiteRepository interface {
+	// ListPrerequisites returns the prerequisites of a course ordered by
+	// ID, leaving out deleted courses, which are not required.
+	ListPrerequisites(ctx context.Context, courseID int) ([]Prerequisite, error)
+	// AddPrerequisite makes p.PrerequisiteID a prerequisite of p.CourseID,
+	// or leaves it one, and refreshes p from the stored row. It returns
+	// ErrPrerequisiteCycle if the prerequisite already requires the course,
+	// directly or through its own prerequisites.
+	AddPrerequisite(ctx context.Context, p *Prerequisite) error
+	// RemovePrerequisite returns ErrNotFound if the course does not require
+	// the prerequisite.
+	RemovePrerequisite(ctx context.Context, courseID, prerequisiteID int) error
+}
+
+type QuizRepository interface {
+	// ListQuizzes returns the quizzes of a series ordered by ID.
+	ListQuizzes(ctx context.Context, seriesID int) ([]Quiz, error)
+	GetQuiz(ctx context.Context, id int) (Quiz, error)
+	// CreateQuiz inserts q and fills in its ID and timestamps.
+	CreateQuiz(ctx context.Context, q *Quiz) error
+	// UpdateQuiz overwrites everything but the series of q.ID and refreshes q
+	// from the stored row.
+	UpdateQuiz(ctx context.Context, q *Quiz) error
+	// DeleteQuiz removes the quiz and its attempts.
+	DeleteQuiz(ctx context.Context, id int) error
+	// CreateAttempt inserts a and fills in its ID and CreatedAt, unless the
+	// user already has maxAttempts attempts at the quiz, in which case it
+	// returns ErrAttemptsUsed. A maxAttempts of zero means no limit; a quiz
+	// that does not exist is ErrNotFound.
+	CreateAttempt(ctx context.Context, a *Attempt, maxAttempts int) error
+	// ListAttempts returns the attempts at a quiz, oldest first, only the
+	// user's if userID is not zero.
+	ListAttempts(ctx context.Context, quizID, userID int) ([]Attempt, error)
+	// Enrolled reports whether the user is enrolled in the course with a
+	// seat; waiting on its waitlist does not count.
+	Enrolled(ctx context.Context, userID, courseID int) (bool, error)
+}
@@ -0,0 +1,415 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"course-service/repository"
+	"mopcare/paging"
+	"mopcare/problem"
+)
+
+const (
+	// maxPrice is the largest value courses.price, a NUMERIC(10, 2), holds.
+	maxPrice     = 99999999.99
+	maxTags      = 20
+	maxTagLength = 50
+)
+
+// normalizeTag lowercases a tag and collapses its whitespace, so "Heart
+// Health" and " heart  health" are the same tag.
+func normalizeTag(tag string) string {
+	return strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+}
+
+// normalizeTags normalises, sorts and dedupes tags. Tags are stored joined
+// by commas in revisions, so they cannot contain one.
+func normalizeTags(tags []string) ([]string, error) {
+	seen := map[string]bool{}
+	out := []string{}
+	for _, tag := range tags {
+		tag = normalizeTag(tag)
+		if tag == "" || len(tag) > maxTagLength || strings.Contains(tag, ",") {
+			return nil, problem.BadRequest(problem.CodeValidationFailed,
+				fmt.Sprintf("Tags must be 1 to %d characters without commas", maxTagLength))
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	if len(out) > maxTags {
+		return nil, problem.BadRequest(problem.CodeValidationFailed, fmt.Sprintf("A course has at most %d tags", maxTags))
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+func (s *CourseService) checkCategory(ctx context.Context, id *int) error {
+	if id == nil {
+		return nil
+	}
+	_, err := s.categories.GetCategory(ctx, *id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return problem.Unprocessable(problem.CodeCategoryNotFound, "category_id refers to a category that does not exist")
+	}
+	return err
+}
+
+// CatalogQuery narrows course listings beyond what the caller may see.
+// Category is an ID or slug and also matches the categories below it; Tags
+// must all be present. The ranges are inclusive and in minutes for duration.
+type CatalogQuery struct {
+	Category                 string
+	Tags                     []string
+	MinPrice, MaxPrice       *float64
+	MinDuration, MaxDuration *int
+	FreePreview              *bool
+}
+
+// catalogFilter adds q to f. An unknown category matches no course rather
+// than failing, like an unknown tag.
+func (s *CourseService) catalogFilter(ctx context.Context, f repository.Filter, q CatalogQuery) (repository.Filter, error) {
+	if q.Category != "" {
+		categories, err := s.categories.ListCategories(ctx)
+		if err != nil {
+			return f, err
+		}
+		f.CategoryIDs = []int{}
+		if root, ok := findCategory(categories, q.Category); ok {
+			f.CategoryIDs = subtree(categories, root.ID)
+		}
+	}
+	f.Tags = nil
+	for _, tag := range q.Tags {
+		f.Tags = append(f.Tags, normalizeTag(tag))
+	}
+	f.MinPrice, f.MaxPrice = q.MinPrice, q.MaxPrice
+	f.MinDuration, f.MaxDuration = q.MinDuration, q.MaxDuration
+	f.FreePreview = q.FreePreview
+	return f, nil
+}
+
+// findCategory looks a category up by ID or, failing that, by slug.
+func findCategory(categories []repository.Category, ref string) (repository.Category, bool) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		for _, c := range categories {
+			if c.ID == id {
+				return c, true
+			}
+		}
+	}
+	for _, c := range categories {
+		if c.Slug == ref {
+			return c, true
+		}
+	}
+	return repository.Category{}, false
+}
+
+// subtree returns root and every category below it.
+func subtree(categories []repository.Category, root int) []int {
+	ids := []int{root}
+	for i := 0; i < len(ids); i++ {
+		for _, c := range categories {
+			if c.ParentID != nil && *c.ParentID == ids[i] {
+				ids = append(ids, c.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// Catalog is one page of matching courses together with facets over all of
+// them, for browsing UIs that show counts next to each filter.
+type Catalog struct {
+	Courses []repository.Course `json:"courses"`
+	Total   int                 `json:"total"`
+	Facets  Facets              `json:"facets"`
+}
+
+// Facets summarises the courses matching a catalog query. A category counts
+// the courses in it and in the categories below it; categories without any
+// are left out.
+type Facets struct {
+	Categories  []CategoryCount       `json:"categories"`
+	Tags        []repository.TagCount `json:"tags"`
+	FreePreview int                   `json:"free_preview"`
+	Price       PriceRange            `json:"price"`
+	Duration    DurationRange         `json:"duration"`
+}
+
+type CategoryCount struct {
+	ID       int    `json:"id"`
+	ParentID *int   `json:"parent_id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	Count    int    `json:"count"`
+}
+
+type PriceRange struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// DurationRange is in minutes.
+type DurationRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// Catalog lists one page of the courses f and q let through, with facets.
+func (s *CourseService) Catalog(ctx context.Context, page paging.Page, f repository.Filter, q CatalogQuery) (Catalog, error) {
+	f, err := s.catalogFilter(ctx, f, q)
+	if err != nil {
+		return Catalog{}, err
+	}
+	courses, err := s.courses.ListCourses(ctx, page, f)
+	if err != nil {
+		return Catalog{}, err
+	}
+	counts, err := s.courses.CourseFacets(ctx, f)
+	if err != nil {
+		return Catalog{}, err
+	}
+	categories, err := s.categories.ListCategories(ctx)
+	if err != nil {
+		return Catalog{}, err
+	}
+	catalog := Catalog{
+		Courses: courses,
+		Total:   counts.Total,
+		Facets: Facets{
+			Categories:  []CategoryCount{},
+			Tags:        counts.Tags,
+			FreePreview: counts.FreePreview,
+			Price:       PriceRange{Min: counts.MinPrice, Max: counts.MaxPrice},
+			Duration:    DurationRange{Min: counts.MinDuration, Max: counts.MaxDuration},
+		},
+	}
+	if catalog.Courses == nil {
+		catalog.Courses = []repository.Course{}
+	}
+	if catalog.Facets.Tags == nil {
+		catalog.Facets.Tags = []repository.TagCount{}
+	}
+	// categories is ordered by name, and so are the facets.
+	for _, c := range categories {
+		n := 0
+		for _, id := range subtree(categories, c.ID) {
+			n += counts.Categories[id]
+		}
+		if n > 0 {
+			catalog.Facets.Categories = append(catalog.Facets.Categories,
+				CategoryCount{ID: c.ID, ParentID: c.ParentID, Name: c.Name, Slug: c.Slug, Count: n})
+		}
+	}
+	return catalog, nil
+}
+
+// ListTags counts the tags on the courses f lets through, most used first.
+func (s *CourseService) ListTags(ctx context.Context, f repository.Filter) ([]repository.TagCount, error) {
+	facets, err := s.courses.CourseFacets(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	if facets.Tags == nil {
+		return []repository.TagCount{}, nil
+	}
+	return facets.Tags, nil
+}
+
+// TagRename reports a rename: the normalised tags and how many courses
+// changed.
+type TagRename struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Courses int    `json:"courses"`
+}
+
+// RenameTag replaces tag with to on every live course carrying it, merging
+// it into to where a course has both. Each course is updated like any other
+// edit, so it gets a new version and revision.
+func (s *CourseService) RenameTag(ctx context.Context, tag, to string) (TagRename, error) {
+	if _, err := normalizeTags([]string{to}); err != nil {
+		return TagRename{}, err
+	}
+	r := TagRename{From: normalizeTag(tag), To: normalizeTag(to)}
+	var err error
+	r.Courses, err = s.retag(ctx, r.From, func(tags []string) []string {
+		return append(removeTag(tags, r.From), r.To)
+	})
+	return r, err
+}
+
+// DeleteTag removes tag from every live course carrying it and returns how
+// many courses changed.
+func (s *CourseService) DeleteTag(ctx context.Context, tag string) (int, error) {
+	tag = normalizeTag(tag)
+	return s.retag(ctx, tag, func(tags []string) []string {
+		return removeTag(tags, tag)
+	})
+}
+
+func removeTag(tags []string, tag string) []string {
+	var out []string
+	for _, t := range tags {
+		if t != tag {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// retag rewrites the tags of each course carrying tag. A course edited
+// concurrently is re-read and retried like in PatchCourse.
+func (s *CourseService) retag(ctx context.Context, tag string, rewrite func([]string) []string) (int, error) {
+	courses, err := s.courses.ListCourses(ctx, paging.Page{}, repository.Filter{Tags: []string{tag}})
+	if err != nil {
+		return 0, err
+	}
+	changed := 0
+	for _, c := range courses {
+		for attempt := 1; ; attempt++ {
+			course := courseInput(c).course(c.ID)
+			course.Tags, _ = normalizeTags(rewrite(c.Tags))
+			course.Version = c.Version
+			err = s.updateCourse(ctx, &course, edit(ctx))
+			if errors.Is(err, repository.ErrVersionConflict) && attempt < patchAttempts {
+				if c, err = s.courses.GetCourse(ctx, c.ID, repository.Filter{}); err != nil {
+					break
+				}
+				continue
+			}
+			break
+		}
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			// Deleted since it was listed.
+		case err != nil:
+			return changed, writeError(err, problem.CodeCourseNotFound, "Course")
+		default:
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// CategoryInput is the writable part of a category. An empty Slug is
+// generated from the name.
+type CategoryInput struct {
+	ParentID *int   `json:"parent_id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+}
+
+func (in CategoryInput) validate() error {
+	if strings.TrimSpace(in.Name) == "" || len(in.Name) > 100 {
+		return problem.BadRequest(problem.CodeValidationFailed, "Name is required and at most 100 characters")
+	}
+	if in.Slug != "" && !validSlug(in.Slug) {
+		return problem.BadRequest(problem.CodeValidationFailed, "slug must be lowercase letters and digits in hyphen-separated words, at most 100 characters")
+	}
+	return nil
+}
+
+func (in CategoryInput) category(id int) repository.Category {
+	c := repository.Category{ID: id, ParentID: in.ParentID, Name: strings.TrimSpace(in.Name), Slug: in.Slug}
+	if c.Slug == "" {
+		c.Slug = slugify(c.Name)
+	}
+	return c
+}
+
+func (s *CourseService) ListCategories(ctx context.Context) ([]repository.Category, error) {
+	categories, err := s.categories.ListCategories(ctx)
+	if categories == nil && err == nil {
+		categories = []repository.Category{}
+	}
+	return categories, err
+}
+
+func (s *CourseService) GetCategory(ctx context.Context, id int) (repository.Category, error) {
+	c, err := s.categories.GetCategory(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return c, problem.NotFound(problem.CodeCategoryNotFound, "Category not found")
+	}
+	return c, err
+}
+
+func (s *CourseService) CreateCategory(ctx context.Context, in CategoryInput) (repository.Category, error) {
+	if err := in.validate(); err != nil {
+		return repository.Category{}, err
+	}
+	if err := s.checkParent(ctx, 0, in.ParentID); err != nil {
+		return repository.Category{}, err
+	}
+	c := in.category(0)
+	if err := s.categories.CreateCategory(ctx, &c); err != nil {
+		return repository.Category{}, err
+	}
+	return c, nil
+}
+
+// UpdateCategory replaces the category's parent, name and slug. Moving a
+// category below itself or one of its descendants is refused.
+func (s *CourseService) UpdateCategory(ctx context.Context, id int, in CategoryInput) (repository.Category, error) {
+	if err := in.validate(); err != nil {
+		return repository.Category{}, err
+	}
+	if _, err := s.GetCategory(ctx, id); err != nil {
+		return repository.Category{}, err
+	}
+	if err := s.checkParent(ctx, id, in.ParentID); err != nil {
+		return repository.Category{}, err
+	}
+	c := in.category(id)
+	err := s.categories.UpdateCategory(ctx, &c)
+	if errors.Is(err, repository.ErrNotFound) {
+		return repository.Category{}, problem.NotFound(problem.CodeCategoryNotFound, "Category not found")
+	}
+	if err != nil {
+		return repository.Category{}, err
+	}
+	return c, nil
+}
+
+// checkParent checks that parent exists and, for an existing category id,
+// is not id or below it.
+func (s *CourseService) checkParent(ctx context.Context, id int, parent *int) error {
+	if parent == nil {
+		return nil
+	}
+	categories, err := s.categories.ListCategories(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := findCategory(categories, strconv.Itoa(*parent)); !ok {
+		return problem.Unprocessable(problem.CodeCategoryNotFound, "parent_id refers to a category that does not exist")
+	}
+	if id != 0 {
+		for _, below := range subtree(categories, id) {
+			if below == *parent {
+				return problem.Unprocessable(problem.CodeValidationFailed, "A category cannot be moved below itself")
+			}
+		}
+	}
+	return nil
+}
+
+// DeleteCategory removes a category that has no subcategories and no
+// courses, deleted courses included.
+func (s *CourseService) DeleteCategory(ctx context.Context, id int) error {
+	err := s.categories.DeleteCategory(ctx, id)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return problem.NotFound(problem.CodeCategoryNotFound, "Category not found")
+	case errors.Is(err, repository.ErrCategoryInUse):
+		return problem.Conflict(problem.CodeCategoryInUse, "Category still has subcategories or courses; move them first")
+	}
+	return err
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"course-service/repository"
+	"mopcare/auth"
+	"mopcare/paging"
+	"mopcare/problem"
+)
+
+// Who may change a course besides admins, who may do anything. Owners may
+// also do anything; co-authors edit the course and its series and submit it
+// for review, and reviewers publish it or send it back.
+var (
+	editors   = []string{repository.RoleOwner, repository.RoleCoAuthor}
+	reviewers = []string{repository.RoleOwner, repository.RoleReviewer}
+	owners    = []string{repository.RoleOwner}
+)
+
+// identified returns the caller on ctx, or a 401 for anonymous callers.
+func identified(ctx context.Context) (auth.Caller, error) {
+	caller := auth.CallerOf(ctx)
+	if !caller.Admin && caller.UserID == 0 {
+		return caller, problem.Unauthorized(problem.CodeUnauthorized, "A user or admin token is required")
+	}
+	return caller, nil
+}
+
+// authorize lets admins through, and users holding one of roles on the
+// course.
+func (s *CourseService) authorize(ctx context.Context, courseID int, roles []string) error {
+	caller, err := identified(ctx)
+	if err != nil || caller.Admin {
+		return err
+	}
+	return s.checkRole(ctx, courseID, caller.UserID, roles)
+}
+
+// authorizeSeries is authorize for the course of a series, deleted or not.
+func (s *CourseService) authorizeSeries(ctx context.Context, seriesID int, roles []string) error {
+	caller, err := identified(ctx)
+	if err != nil || caller.Admin {
+		return err
+	}
+	series, err := s.GetSeries(ctx, seriesID, repository.Filter{IncludeDeleted: true})
+	if err != nil {
+		return err
+	}
+	return s.checkRole(ctx, series.CourseID, caller.UserID, roles)
+}
+
+func (s *CourseService) checkRole(ctx context.Context, courseID, userID int, roles []string) error {
+	role, err := s.instructors.InstructorRole(ctx, courseID, userID)
+	if err != nil {
+		return err
+	}
+	for _, r := range roles {
+		if role == r {
+			return nil
+		}
+	}
+	return problem.Forbidden(problem.CodeForbidden, "Requires the "+strings.Join(roles, " or ")+" role on this course")
+}
+
+// visible widens f for the course's instructors, who see it whatever its
+// status.
+func (s *CourseService) visible(ctx context.Context, courseID int, f repository.Filter) (repository.Filter, error) {
+	caller := auth.CallerOf(ctx)
+	if f.Status == "" || caller.UserID == 0 {
+		return f, nil
+	}
+	role, err := s.instructors.InstructorRole(ctx, courseID, caller.UserID)
+	if role != "" {
+		f.Status = ""
+	}
+	return f, err
+}
+
+// InstructorInput is the body of a request adding an instructor or changing
+// their role.
+type InstructorInput struct {
+	Role string `json:"role"`
+}
+
+func (in InstructorInput) validate() error {
+	switch in.Role {
+	case repository.RoleOwner, repository.RoleCoAuthor, repository.RoleReviewer:
+		return nil
+	}
+	return problem.BadRequest(problem.CodeValidationFailed, "role must be owner, co-author or reviewer")
+}
+
+// ListInstructors returns the instructors of a course f lets through.
+func (s *CourseService) ListInstructors(ctx context.Context, courseID int, f repository.Filter) ([]repository.Instructor, error) {
+	if _, err := s.GetCourse(ctx, courseID, f); err != nil {
+		return nil, err
+	}
+	instructors, err := s.instructors.ListInstructors(ctx, courseID)
+	if instructors == nil {
+		instructors = []repository.Instructor{}
+	}
+	return instructors, err
+}
+
+// SetInstructor gives a user a role on the course. Only owners and admins
+// manage instructors, and a course that has an owner always keeps one.
+func (s *CourseService) SetInstructor(ctx context.Context, courseID, userID int, in InstructorInput) (repository.Instructor, error) {
+	if err := s.authorize(ctx, courseID, owners); err != nil {
+		return repository.Instructor{}, err
+	}
+	if err := in.validate(); err != nil {
+		return repository.Instructor{}, err
+	}
+	if _, err := s.GetCourse(ctx, courseID, repository.Filter{}); err != nil {
+		return repository.Instructor{}, err
+	}
+	instructor := repository.Instructor{CourseID: courseID, UserID: userID, Role: in.Role}
+	if err := s.instructors.SetInstructor(ctx, &instructor); err != nil {
+		return repository.Instructor{}, instructorError(err)
+	}
+	return instructor, nil
+}
+
+// RemoveInstructor takes a user's role on the course away. Besides owners
+// and admins, instructors may remove themselves.
+func (s *CourseService) RemoveInstructor(ctx context.Context, courseID, userID int) error {
+	if caller := auth.CallerOf(ctx); caller.UserID == 0 || caller.UserID != userID {
+		if err := s.authorize(ctx, courseID, owners); err != nil {
+			return err
+		}
+	}
+	return instructorError(s.instructors.RemoveInstructor(ctx, courseID, userID))
+}
+
+func instructorError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return problem.NotFound(problem.CodeInstructorNotFound, "Instructor not found")
+	case errors.Is(err, repository.ErrLastOwner):
+		return problem.Conflict(problem.CodeLastOwner, "The course must keep at least one owner")
+	}
+	return err
+}
+
+// ListInstructorCourses returns one page of the courses f lets through that
+// the user instructs, with role if that is set.
+func (s *CourseService) ListInstructorCourses(ctx context.Context, userID int, role string, page paging.Page, f repository.Filter) ([]repository.Course, error) {
+	f.Instructor, f.InstructorRole = userID, role
+	courses, err := s.courses.ListCourses(ctx, page, f)
+	if courses == nil {
+		courses = []repository.Course{}
+	}
+	return courses, err
+}
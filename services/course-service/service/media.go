@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"course-service/repository"
+	"mopcare/auth"
+	"mopcare/media"
+	"mopcare/problem"
+)
+
+// MediaLimits caps upload sizes by kind, in bytes.
+type MediaLimits struct {
+	Image int64
+	Video int64
+}
+
+// MediaService stores uploads and hands out signed links to them.
+type MediaService struct {
+	media  repository.MediaRepository
+	store  media.Store
+	signer *media.Signer
+	limits MediaLimits
+}
+
+func NewMediaService(repo repository.MediaRepository, store media.Store, signer *media.Signer, limits MediaLimits) *MediaService {
+	return &MediaService{media: repo, store: store, signer: signer, limits: limits}
+}
+
+// Media is an upload as the API returns it: what is stored about it plus
+// signed links to its content and, for images, its thumbnail. The links
+// stop working at URLExpiresAt; fetch the media again for fresh ones.
+type Media struct {
+	ID           int       `json:"id"`
+	Checksum     string    `json:"sha256"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`
+	Kind         string    `json:"kind"`
+	Width        int       `json:"width,omitempty"`
+	Height       int       `json:"height,omitempty"`
+	UploadedBy   string    `json:"uploaded_by,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+	URLExpiresAt time.Time `json:"url_expires_at"`
+}
+
+// Download is an open blob ready to be sent. Size is -1 when unknown.
+type Download struct {
+	Body        io.ReadCloser
+	ContentType string
+	Size        int64
+	Checksum    string
+	ExpiresAt   time.Time
+}
+
+func contentPath(id int) string   { return fmt.Sprintf("/media/%d/content", id) }
+func thumbnailPath(id int) string { return fmt.Sprintf("/media/%d/thumbnail", id) }
+
+func (s *MediaService) view(m repository.Media) Media {
+	now := time.Now()
+	v := Media{
+		ID:          m.ID,
+		Checksum:    m.Checksum,
+		ContentType: m.ContentType,
+		Size:        m.Size,
+		Kind:        m.Kind,
+		Width:       m.Width,
+		Height:      m.Height,
+		UploadedBy:  m.UploadedBy,
+		CreatedAt:   m.CreatedAt,
+	}
+	v.URL, v.URLExpiresAt = s.signer.Sign(contentPath(m.ID), now)
+	if m.Kind == media.KindImage {
+		v.ThumbnailURL, _ = s.signer.Sign(thumbnailPath(m.ID), now)
+	}
+	return v
+}
+
+// Upload stores data as contentType and reports whether it was new. Content
+// that was uploaded before is not stored again; the existing media is
+// returned instead.
+func (s *MediaService) Upload(ctx context.Context, data []byte, contentType string) (Media, bool, error) {
+	kind := media.Kind(contentType)
+	if kind == "" {
+		return Media{}, false, problem.New(http.StatusUnsupportedMediaType, problem.CodeMediaUnsupportedType,
+			"Content-Type must be image/jpeg, image/png, image/gif, video/mp4 or video/webm")
+	}
+	if len(data) == 0 {
+		return Media{}, false, problem.BadRequest(problem.CodeValidationFailed, "Upload is empty")
+	}
+	limit := s.limits.Image
+	if kind == media.KindVideo {
+		limit = s.limits.Video
+	}
+	if int64(len(data)) > limit {
+		return Media{}, false, problem.New(http.StatusRequestEntityTooLarge, problem.CodeMediaTooLarge,
+			fmt.Sprintf("%s uploads are limited to %d bytes", kind, limit))
+	}
+	if !media.Sniff(data, contentType) {
+		return Media{}, false, problem.New(http.StatusUnsupportedMediaType, problem.CodeMediaUnsupportedType,
+			"Content does not match its Content-Type")
+	}
+
+	sum := media.Checksum(data)
+	existing, err := s.media.FindMedia(ctx, sum)
+	if err == nil {
+		return s.view(existing), false, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return Media{}, false, err
+	}
+
+	m := repository.Media{
+		Checksum:    sum,
+		ContentType: media.BareType(contentType),
+		Size:        int64(len(data)),
+		Kind:        kind,
+		UploadedBy:  auth.Actor(ctx),
+	}
+	// Blobs go in before the row that points at them. Both are keyed by the
+	// checksum, so a concurrent upload of the same content only rewrites the
+	// same bytes.
+	if kind == media.KindImage {
+		thumb, err := media.Thumbnail(data)
+		if err != nil {
+			return Media{}, false, problem.Unprocessable(problem.CodeValidationFailed, "Image could not be decoded")
+		}
+		m.Width, m.Height = thumb.Width, thumb.Height
+		if err := s.store.Put(ctx, media.ThumbnailKey(sum), thumb.Data, thumb.ContentType); err != nil {
+			return Media{}, false, err
+		}
+	}
+	if err := s.store.Put(ctx, media.BlobKey(sum), data, m.ContentType); err != nil {
+		return Media{}, false, err
+	}
+	created, err := s.media.CreateMedia(ctx, &m)
+	if err != nil {
+		return Media{}, false, err
+	}
+	return s.view(m), created, nil
+}
+
+func (s *MediaService) GetMedia(ctx context.Context, id int) (Media, error) {
+	m, err := s.get(ctx, id)
+	if err != nil {
+		return Media{}, err
+	}
+	return s.view(m), nil
+}
+
+func (s *MediaService) get(ctx context.Context, id int) (repository.Media, error) {
+	m, err := s.media.GetMedia(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return m, problem.NotFound(problem.CodeMediaNotFound, "Media not found")
+	}
+	return m, err
+}
+
+// Open checks a signed link to the content or thumbnail of media id and
+// opens the blob it names.
+func (s *MediaService) Open(ctx context.Context, id int, thumbnail bool, expires, signature string) (Download, error) {
+	path := contentPath(id)
+	if thumbnail {
+		path = thumbnailPath(id)
+	}
+	switch err := s.signer.Verify(path, expires, signature, time.Now()); {
+	case errors.Is(err, media.ErrSignatureExpired):
+		return Download{}, problem.Forbidden(problem.CodeSignatureInvalid, "Download link has expired")
+	case err != nil:
+		return Download{}, problem.Forbidden(problem.CodeSignatureInvalid, "Download link is not valid")
+	}
+	m, err := s.get(ctx, id)
+	if err != nil {
+		return Download{}, err
+	}
+	unix, _ := strconv.ParseInt(expires, 10, 64)
+	d := Download{ContentType: m.ContentType, Size: m.Size, Checksum: m.Checksum, ExpiresAt: time.Unix(unix, 0)}
+	key := media.BlobKey(m.Checksum)
+	if thumbnail {
+		if m.Kind != media.KindImage {
+			return Download{}, problem.NotFound(problem.CodeMediaNotFound, "Only images have thumbnails")
+		}
+		key, d.ContentType, d.Size = media.ThumbnailKey(m.Checksum), media.ThumbnailType(m.ContentType), -1
+	}
+	d.Body, err = s.store.Get(ctx, key)
+	if err != nil {
+		return Download{}, fmt.Errorf("media %d: %w", id, err)
+	}
+	return d, nil
+}
+
+// checkMediaURLs validates the cover image and overview video of a course
+// write. Each is empty, an absolute http(s) URL, or media:<id> naming an
+// upload of the right kind. Values equal to those in current are not
+// rechecked, so patching other fields of a course keeps working whatever
+// was stored before these rules existed.
+func (s *CourseService) checkMediaURLs(ctx context.Context, in, current CourseInput) error {
+	for _, f := range []struct{ field, value, current, kind string }{
+		{"cover_image_url", in.CoverImageURL, current.CoverImageURL, media.KindImage},
+		{"overview_video_url", in.OverviewVideoURL, current.OverviewVideoURL, media.KindVideo},
+	} {
+		if f.value == "" || f.value == f.current {
+			continue
+		}
+		if err := s.checkMediaURL(ctx, f.field, f.value, f.kind); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *CourseService) checkMediaURL(ctx context.Context, field, value, kind string) error {
+	ref, isRef := strings.CutPrefix(value, "media:")
+	if !isRef {
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return problem.BadRequest(problem.CodeValidationFailed, field+" must be an http(s) URL or media:<id>")
+		}
+		return nil
+	}
+	id, err := strconv.Atoi(ref)
+	if err != nil || id < 1 {
+		return problem.BadRequest(problem.CodeValidationFailed, field+" must be an http(s) URL or media:<id>")
+	}
+	m, err := s.media.GetMedia(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return problem.Unprocessable(problem.CodeMediaNotFound, field+" refers to media that does not exist")
+	}
+	if err != nil {
+		return err
+	}
+	if m.Kind != kind {
+		return problem.Unprocessable(problem.CodeValidationFailed, field+" must refer to an uploaded "+kind)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"course-service/repository"
+	"mopcare/problem"
+)
+
+// ListPrerequisites returns the published prerequisites of a course f lets
+// through; only those are required.
+func (s *CourseService) ListPrerequisites(ctx context.Context, courseID int, f repository.Filter) ([]repository.Prerequisite, error) {
+	if _, err := s.GetCourse(ctx, courseID, f); err != nil {
+		return nil, err
+	}
+	prerequisites, err := s.prerequisites.ListPrerequisites(ctx, courseID)
+	if prerequisites == nil {
+		prerequisites = []repository.Prerequisite{}
+	}
+	return prerequisites, err
+}
+
+// AddPrerequisite requires users to complete one course before enrolling in
+// another. Owners, co-authors and admins manage a course's prerequisites,
+// which must be published, so learners can take them, and may not loop back
+// to the course. A prerequisite unpublished later stops being required until
+// it is published again.
+func (s *CourseService) AddPrerequisite(ctx context.Context, courseID, prerequisiteID int) (repository.Prerequisite, error) {
+	if err := s.authorize(ctx, courseID, editors); err != nil {
+		return repository.Prerequisite{}, err
+	}
+	if _, err := s.GetCourse(ctx, courseID, repository.Filter{}); err != nil {
+		return repository.Prerequisite{}, err
+	}
+	if courseID == prerequisiteID {
+		return repository.Prerequisite{}, problem.Conflict(problem.CodePrerequisiteCycle, "A course cannot be its own prerequisite")
+	}
+	_, err := s.courses.GetCourse(ctx, prerequisiteID, repository.Filter{Status: repository.StatusPublished})
+	if errors.Is(err, repository.ErrNotFound) {
+		return repository.Prerequisite{}, problem.Unprocessable(problem.CodeCourseNotFound, "Prerequisite course does not exist or is not published")
+	} else if err != nil {
+		return repository.Prerequisite{}, err
+	}
+	p := repository.Prerequisite{CourseID: courseID, PrerequisiteID: prerequisiteID}
+	err = s.prerequisites.AddPrerequisite(ctx, &p)
+	if errors.Is(err, repository.ErrPrerequisiteCycle) {
+		return repository.Prerequisite{}, problem.Conflict(problem.CodePrerequisiteCycle,
+			fmt.Sprintf("Course %d already requires course %d, directly or through its prerequisites", prerequisiteID, courseID))
+	}
+	if err != nil {
+		return repository.Prerequisite{}, err
+	}
+	return p, nil
+}
+
+func (s *CourseService) RemovePrerequisite(ctx context.Context, courseID, prerequisiteID int) error {
+	if err := s.authorize(ctx, courseID, editors); err != nil {
+		return err
+	}
+	err := s.prerequisites.RemovePrerequisite(ctx, courseID, prerequisiteID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return problem.NotFound(problem.CodePrerequisiteNotFound, "Course does not require this prerequisite")
+	}
+	return err
+}
@@ -0,0 +1,345 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"course-service/repository"
+	"mopcare/auth"
+	"mopcare/problem"
+)
+
+const (
+	maxQuestions = 100
+	maxOptions   = 10
+)
+
+// trueFalseOptions are the options of every true/false question.
+var trueFalseOptions = []string{"True", "False"}
+
+// QuizInput is the writable part of a quiz. True/false questions may leave
+// their options out.
+type QuizInput struct {
+	Title        string                `json:"title"`
+	PassingScore int                   `json:"passing_score"`
+	MaxAttempts  int                   `json:"max_attempts"`
+	Required     bool                  `json:"required"`
+	Questions    []repository.Question `json:"questions"`
+}
+
+// AttemptInput is a learner's answers to a quiz: the index of the chosen
+// option for each question, in order.
+type AttemptInput struct {
+	Answers []int `json:"answers"`
+}
+
+func (in QuizInput) validate() error {
+	if strings.TrimSpace(in.Title) == "" {
+		return problem.BadRequest(problem.CodeValidationFailed, "Title is required")
+	}
+	if in.PassingScore < 1 || in.PassingScore > 100 {
+		return problem.BadRequest(problem.CodeValidationFailed, "passing_score must be between 1 and 100")
+	}
+	if in.MaxAttempts < 0 {
+		return problem.BadRequest(problem.CodeValidationFailed, "max_attempts must not be negative")
+	}
+	if len(in.Questions) == 0 || len(in.Questions) > maxQuestions {
+		return problem.BadRequest(problem.CodeValidationFailed, fmt.Sprintf("A quiz has 1 to %d questions", maxQuestions))
+	}
+	for i, q := range in.Questions {
+		if err := validateQuestion(q); err != nil {
+			return problem.BadRequest(problem.CodeValidationFailed, fmt.Sprintf("questions[%d]: %s", i, err))
+		}
+	}
+	return nil
+}
+
+func validateQuestion(q repository.Question) error {
+	if strings.TrimSpace(q.Prompt) == "" {
+		return errors.New("prompt is required")
+	}
+	options := q.Options
+	switch q.Type {
+	case repository.QuestionMultipleChoice:
+		if len(options) < 2 || len(options) > maxOptions {
+			return fmt.Errorf("a multiple-choice question has 2 to %d options", maxOptions)
+		}
+		for _, option := range options {
+			if strings.TrimSpace(option) == "" {
+				return errors.New("options must not be empty")
+			}
+		}
+	case repository.QuestionTrueFalse:
+		if len(options) != 0 && strings.Join(options, ",") != strings.Join(trueFalseOptions, ",") {
+			return errors.New(`the options of a true/false question are "True" and "False"`)
+		}
+		options = trueFalseOptions
+	default:
+		return errors.New("type must be multiple_choice or true_false")
+	}
+	if q.Answer == nil || *q.Answer < 0 || *q.Answer >= len(options) {
+		return errors.New("answer must be the index of one of the options")
+	}
+	return nil
+}
+
+// quiz assumes in has been validated.
+func (in QuizInput) quiz(id int) repository.Quiz {
+	questions := make([]repository.Question, len(in.Questions))
+	for i, q := range in.Questions {
+		if q.Type == repository.QuestionTrueFalse {
+			q.Options = append([]string(nil), trueFalseOptions...)
+		}
+		answer := *q.Answer
+		q.Answer = &answer
+		questions[i] = q
+	}
+	return repository.Quiz{
+		ID:           id,
+		Title:        strings.TrimSpace(in.Title),
+		PassingScore: in.PassingScore,
+		MaxAttempts:  in.MaxAttempts,
+		Required:     in.Required,
+		Questions:    questions,
+	}
+}
+
+// grade scores answers against the quiz; answers must have one valid option
+// per question.
+func grade(q repository.Quiz, answers []int) repository.Attempt {
+	a := repository.Attempt{QuizID: q.ID, Answers: answers, Correct: make([]bool, len(answers))}
+	right := 0
+	for i, question := range q.Questions {
+		if a.Correct[i] = answers[i] == *question.Answer; a.Correct[i] {
+			right++
+		}
+	}
+	a.Score = right * 100 / len(q.Questions)
+	a.Passed = a.Score >= q.PassingScore
+	return a
+}
+
+// marker reports whether the caller sees answers and every attempt at the
+// quizzes of a course: admins and the course's instructors.
+func (s *CourseService) marker(ctx context.Context, courseID int) (bool, error) {
+	caller := auth.CallerOf(ctx)
+	if caller.Admin {
+		return true, nil
+	}
+	if caller.UserID == 0 {
+		return false, nil
+	}
+	role, err := s.instructors.InstructorRole(ctx, courseID, caller.UserID)
+	return role != "", err
+}
+
+// redact hides the answers from callers who are not markers of the course.
+func (s *CourseService) redact(ctx context.Context, courseID int, quizzes []repository.Quiz) ([]repository.Quiz, error) {
+	marker, err := s.marker(ctx, courseID)
+	if err != nil || marker {
+		return quizzes, err
+	}
+	for i := range quizzes {
+		for j := range quizzes[i].Questions {
+			quizzes[i].Questions[j].Answer = nil
+		}
+	}
+	return quizzes, nil
+}
+
+// quizSeries returns the quiz and its series, treating a quiz whose series f
+// does not let through as missing.
+func (s *CourseService) quizSeries(ctx context.Context, id int, f repository.Filter) (repository.Quiz, repository.Series, error) {
+	quiz, err := s.quizzes.GetQuiz(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return quiz, repository.Series{}, quizNotFound()
+	}
+	if err != nil {
+		return quiz, repository.Series{}, err
+	}
+	series, err := s.GetSeries(ctx, quiz.SeriesID, f)
+	var p *problem.Problem
+	if errors.As(err, &p) && p.Code == problem.CodeSeriesNotFound {
+		return quiz, series, quizNotFound()
+	}
+	return quiz, series, err
+}
+
+func quizNotFound() error {
+	return problem.NotFound(problem.CodeQuizNotFound, "Quiz not found")
+}
+
+// ListQuizzes returns the quizzes of a series f lets through. Only admins
+// and the course's instructors see the answers.
+func (s *CourseService) ListQuizzes(ctx context.Context, seriesID int, f repository.Filter) ([]repository.Quiz, error) {
+	series, err := s.GetSeries(ctx, seriesID, f)
+	if err != nil {
+		return nil, err
+	}
+	quizzes, err := s.quizzes.ListQuizzes(ctx, seriesID)
+	if err != nil {
+		return nil, err
+	}
+	if quizzes == nil {
+		quizzes = []repository.Quiz{}
+	}
+	return s.redact(ctx, series.CourseID, quizzes)
+}
+
+// GetQuiz returns a quiz whose series f lets through, with answers hidden
+// like ListQuizzes.
+func (s *CourseService) GetQuiz(ctx context.Context, id int, f repository.Filter) (repository.Quiz, error) {
+	quiz, series, err := s.quizSeries(ctx, id, f)
+	if err != nil {
+		return repository.Quiz{}, err
+	}
+	quizzes, err := s.redact(ctx, series.CourseID, []repository.Quiz{quiz})
+	if err != nil {
+		return repository.Quiz{}, err
+	}
+	return quizzes[0], nil
+}
+
+func (s *CourseService) CreateQuiz(ctx context.Context, seriesID int, in QuizInput) (repository.Quiz, error) {
+	if err := s.authorizeSeries(ctx, seriesID, editors); err != nil {
+		return repository.Quiz{}, err
+	}
+	if err := in.validate(); err != nil {
+		return repository.Quiz{}, err
+	}
+	_, err := s.series.GetSeries(ctx, seriesID, repository.Filter{})
+	if errors.Is(err, repository.ErrNotFound) {
+		return repository.Quiz{}, problem.Unprocessable(problem.CodeSeriesNotFound, "Series does not exist")
+	}
+	if err != nil {
+		return repository.Quiz{}, err
+	}
+	quiz := in.quiz(0)
+	quiz.SeriesID = seriesID
+	if err := s.quizzes.CreateQuiz(ctx, &quiz); err != nil {
+		return repository.Quiz{}, err
+	}
+	return quiz, nil
+}
+
+// UpdateQuiz replaces the quiz. Attempts already made keep their grades.
+func (s *CourseService) UpdateQuiz(ctx context.Context, id int, in QuizInput) (repository.Quiz, error) {
+	current, err := s.quizzes.GetQuiz(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return repository.Quiz{}, quizNotFound()
+	}
+	if err != nil {
+		return repository.Quiz{}, err
+	}
+	if err := s.authorizeSeries(ctx, current.SeriesID, editors); err != nil {
+		return repository.Quiz{}, err
+	}
+	if err := in.validate(); err != nil {
+		return repository.Quiz{}, err
+	}
+	quiz := in.quiz(id)
+	err = s.quizzes.UpdateQuiz(ctx, &quiz)
+	if errors.Is(err, repository.ErrNotFound) {
+		return repository.Quiz{}, quizNotFound()
+	}
+	if err != nil {
+		return repository.Quiz{}, err
+	}
+	return quiz, nil
+}
+
+// DeleteQuiz removes the quiz along with every attempt at it.
+func (s *CourseService) DeleteQuiz(ctx context.Context, id int) error {
+	quiz, err := s.quizzes.GetQuiz(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return quizNotFound()
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.authorizeSeries(ctx, quiz.SeriesID, editors); err != nil {
+		return err
+	}
+	err = s.quizzes.DeleteQuiz(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return quizNotFound()
+	}
+	return err
+}
+
+// SubmitAttempt grades the caller's answers to a quiz f lets through and
+// records the attempt. Only users enrolled in the course may attempt its
+// quizzes, each at most MaxAttempts times.
+func (s *CourseService) SubmitAttempt(ctx context.Context, quizID int, in AttemptInput, f repository.Filter) (repository.Attempt, error) {
+	caller, err := identified(ctx)
+	if err != nil {
+		return repository.Attempt{}, err
+	}
+	if caller.UserID == 0 {
+		return repository.Attempt{}, problem.Forbidden(problem.CodeForbidden, "Quiz attempts are made with a user token")
+	}
+	// Quizzes on deleted series take no attempts.
+	f.IncludeDeleted = false
+	quiz, series, err := s.quizSeries(ctx, quizID, f)
+	if err != nil {
+		return repository.Attempt{}, err
+	}
+	enrolled, err := s.quizzes.Enrolled(ctx, caller.UserID, series.CourseID)
+	if err != nil {
+		return repository.Attempt{}, err
+	}
+	if !enrolled {
+		return repository.Attempt{}, problem.Forbidden(problem.CodeNotEnrolled, "Enroll in the course before attempting its quizzes")
+	}
+	if len(in.Answers) != len(quiz.Questions) {
+		return repository.Attempt{}, problem.BadRequest(problem.CodeValidationFailed,
+			fmt.Sprintf("answers must have one entry for each of the %d questions", len(quiz.Questions)))
+	}
+	for i, answer := range in.Answers {
+		if answer < 0 || answer >= len(quiz.Questions[i].Options) {
+			return repository.Attempt{}, problem.BadRequest(problem.CodeValidationFailed,
+				fmt.Sprintf("answers[%d] must be the index of one of the question's options", i))
+		}
+	}
+	attempt := grade(quiz, in.Answers)
+	attempt.UserID = caller.UserID
+	err = s.quizzes.CreateAttempt(ctx, &attempt, quiz.MaxAttempts)
+	switch {
+	case errors.Is(err, repository.ErrAttemptsUsed):
+		return repository.Attempt{}, problem.Conflict(problem.CodeQuizAttemptsUsed,
+			fmt.Sprintf("All %d attempts at this quiz are used", quiz.MaxAttempts))
+	case errors.Is(err, repository.ErrNotFound):
+		return repository.Attempt{}, quizNotFound()
+	case err != nil:
+		return repository.Attempt{}, err
+	}
+	return attempt, nil
+}
+
+// ListAttempts returns the attempts at a quiz f lets through: every one for
+// admins and the course's instructors, the caller's own for anyone else.
+func (s *CourseService) ListAttempts(ctx context.Context, quizID int, f repository.Filter) ([]repository.Attempt, error) {
+	caller, err := identified(ctx)
+	if err != nil {
+		return nil, err
+	}
+	_, series, err := s.quizSeries(ctx, quizID, f)
+	if err != nil {
+		return nil, err
+	}
+	marker, err := s.marker(ctx, series.CourseID)
+	if err != nil {
+		return nil, err
+	}
+	userID := caller.UserID
+	if marker {
+		userID = 0
+	}
+	attempts, err := s.quizzes.ListAttempts(ctx, quizID, userID)
+	if attempts == nil {
+		attempts = []repository.Attempt{}
+	}
+	return attempts, err
+}
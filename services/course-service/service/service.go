@@ -0,0 +1,725 @@
+// Package service holds the course-service business rules. It speaks in
+// repository types and returns *problem.Problem for every expected failure.
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"course-service/repository"
+	"mopcare/auth"
+	"mopcare/mergepatch"
+	"mopcare/paging"
+	"mopcare/problem"
+)
+
+// patchAttempts bounds how often an unconditional PATCH re-reads and retries
+// when a concurrent write bumps the version between its read and write.
+const patchAttempts = 3
+
+type CourseService struct {
+	courses       repository.CourseRepository
+	series        repository.SeriesRepository
+	media         repository.MediaRepository
+	categories    repository.CategoryRepository
+	instructors   repository.InstructorRepository
+	quizzes       repository.QuizRepository
+	prerequisites repository.PrerequisiteRepository
+}
+
+func NewCourseService(courses repository.CourseRepository, series repository.SeriesRepository, media repository.MediaRepository, categories repository.CategoryRepository, instructors repository.InstructorRepository, quizzes repository.QuizRepository, prerequisites repository.PrerequisiteRepository) *CourseService {
+	return &CourseService{courses: courses, series: series, media: media, categories: categories, instructors: instructors, quizzes: quizzes, prerequisites: prerequisites}
+}
+
+// CourseInput is the writable part of a course. An empty UniqueID is
+// generated from the title. OverviewVideoURL and CoverImageURL are checked
+// by checkMediaURLs. Tags are normalised by normalizeTags.
+type CourseInput struct {
+	Title            string   `json:"title"`
+	Content          string   `json:"content"`
+	OverviewVideoURL string   `json:"overview_video_url"`
+	CoverImageURL    string   `json:"cover_image_url"`
+	UniqueID         string   `json:"unique_id"`
+	CategoryID       *int     `json:"category_id"`
+	Tags             []string `json:"tags"`
+	Price            float64  `json:"price"`
+	// Capacity and the enrollment window are optional; nil means no limit.
+	Capacity           *int       `json:"capacity"`
+	EnrollmentOpensAt  *time.Time `json:"enrollment_opens_at"`
+	EnrollmentClosesAt *time.Time `json:"enrollment_closes_at"`
+}
+
+// SeriesInput is the writable part of a series.
+type SeriesInput struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	// Duration is in minutes.
+	Duration      int  `json:"duration"`
+	IsFreePreview bool `json:"is_free_preview"`
+}
+
+// PublishInput is the optional body of a publish request.
+type PublishInput struct {
+	// PublishAt schedules publication; omitted or past means now.
+	PublishAt *time.Time `json:"publish_at"`
+}
+
+// Diff is how the fields of a course or series changed between two of its
+// revisions.
+type Diff struct {
+	From    int                          `json:"from"`
+	To      int                          `json:"to"`
+	Changes map[string]repository.Change `json:"changes"`
+}
+
+// edit attributes a write to the actor the handler put on ctx.
+func edit(ctx context.Context) repository.Edit {
+	return repository.Edit{Author: auth.Actor(ctx)}
+}
+
+func (in CourseInput) validate() error {
+	if in.Title == "" || in.Content == "" {
+		return problem.BadRequest(problem.CodeValidationFailed, "Title and content are required")
+	}
+	if in.UniqueID != "" && !validSlug(in.UniqueID) {
+		return problem.BadRequest(problem.CodeValidationFailed, "unique_id must be lowercase letters and digits in hyphen-separated words, at most 100 characters")
+	}
+	if in.Price < 0 || in.Price > maxPrice {
+		return problem.BadRequest(problem.CodeValidationFailed, "price must be between 0 and 99999999.99")
+	}
+	if in.Capacity != nil && *in.Capacity < 1 {
+		return problem.BadRequest(problem.CodeValidationFailed, "capacity must be at least 1, or null for no limit")
+	}
+	if in.EnrollmentOpensAt != nil && in.EnrollmentClosesAt != nil && !in.EnrollmentOpensAt.Before(*in.EnrollmentClosesAt) {
+		return problem.BadRequest(problem.CodeValidationFailed, "enrollment_opens_at must be before enrollment_closes_at")
+	}
+	_, err := normalizeTags(in.Tags)
+	return err
+}
+
+func (in SeriesInput) validate() error {
+	if in.Title == "" {
+		return problem.BadRequest(problem.CodeValidationFailed, "Title is required")
+	}
+	if in.Duration < 0 {
+		return problem.BadRequest(problem.CodeValidationFailed, "duration must not be negative")
+	}
+	return nil
+}
+
+// checkCourse validates a course write: the input itself, then what it
+// refers to. Values equal to those in current are not rechecked.
+func (s *CourseService) checkCourse(ctx context.Context, in, current CourseInput) error {
+	if err := in.validate(); err != nil {
+		return err
+	}
+	if err := s.checkMediaURLs(ctx, in, current); err != nil {
+		return err
+	}
+	return s.checkCategory(ctx, in.CategoryID)
+}
+
+func courseInput(c repository.Course) CourseInput {
+	return CourseInput{
+		Title:              c.Title,
+		Content:            c.Content,
+		OverviewVideoURL:   c.OverviewVideoURL,
+		CoverImageURL:      c.CoverImageURL,
+		UniqueID:           c.UniqueID,
+		CategoryID:         c.CategoryID,
+		Tags:               c.Tags,
+		Price:              c.Price,
+		Capacity:           c.Capacity,
+		EnrollmentOpensAt:  c.EnrollmentOpensAt,
+		EnrollmentClosesAt: c.EnrollmentClosesAt,
+	}
+}
+
+// course assumes in has been validated.
+func (in CourseInput) course(id int) repository.Course {
+	tags, _ := normalizeTags(in.Tags)
+	return repository.Course{
+		ID:                 id,
+		Title:              in.Title,
+		Content:            in.Content,
+		OverviewVideoURL:   in.OverviewVideoURL,
+		CoverImageURL:      in.CoverImageURL,
+		UniqueID:           in.UniqueID,
+		CategoryID:         in.CategoryID,
+		Tags:               tags,
+		Price:              in.Price,
+		Capacity:           in.Capacity,
+		EnrollmentOpensAt:  in.EnrollmentOpensAt,
+		EnrollmentClosesAt: in.EnrollmentClosesAt,
+	}
+}
+
+func seriesInput(s repository.Series) SeriesInput {
+	return SeriesInput{Title: s.Title, Description: s.Description, Duration: s.Duration, IsFreePreview: s.IsFreePreview}
+}
+
+func (in SeriesInput) series(id int) repository.Series {
+	return repository.Series{ID: id, Title: in.Title, Description: in.Description, Duration: in.Duration, IsFreePreview: in.IsFreePreview}
+}
+
+// ListCourses returns one page of the courses f and q let through. Handlers
+// decide f from the caller: learners only ever get published courses.
+func (s *CourseService) ListCourses(ctx context.Context, page paging.Page, f repository.Filter, q CatalogQuery) ([]repository.Course, error) {
+	f, err := s.catalogFilter(ctx, f, q)
+	if err != nil {
+		return nil, err
+	}
+	return s.courses.ListCourses(ctx, page, f)
+}
+
+// GetCourse returns the course if f lets it through, or if the caller is one
+// of its instructors.
+func (s *CourseService) GetCourse(ctx context.Context, id int, f repository.Filter) (repository.Course, error) {
+	f, err := s.visible(ctx, id, f)
+	if err != nil {
+		return repository.Course{}, err
+	}
+	course, err := s.courses.GetCourse(ctx, id, f)
+	if errors.Is(err, repository.ErrNotFound) {
+		return course, problem.NotFound(problem.CodeCourseNotFound, "Course not found")
+	}
+	return course, err
+}
+
+// CreateCourse makes the calling user the new course's owner. Courses an
+// admin creates start without instructors.
+func (s *CourseService) CreateCourse(ctx context.Context, in CourseInput) (repository.Course, error) {
+	caller, err := identified(ctx)
+	if err != nil {
+		return repository.Course{}, err
+	}
+	if err := s.checkCourse(ctx, in, CourseInput{}); err != nil {
+		return repository.Course{}, err
+	}
+	course := in.course(0)
+	e := edit(ctx)
+	e.Owner = caller.UserID
+	err = s.saveCourse(ctx, &course, func(c *repository.Course) error {
+		return s.courses.CreateCourse(ctx, c, e)
+	})
+	if err != nil {
+		return repository.Course{}, err
+	}
+	return course, nil
+}
+
+// UpdateCourse replaces every writable field of the course. ifMatch is the
+// version the caller last saw, or 0 to overwrite whatever is stored.
+func (s *CourseService) UpdateCourse(ctx context.Context, id int, in CourseInput, ifMatch int) (repository.Course, error) {
+	if err := s.authorize(ctx, id, editors); err != nil {
+		return repository.Course{}, err
+	}
+	if err := s.checkCourse(ctx, in, CourseInput{}); err != nil {
+		return repository.Course{}, err
+	}
+	course := in.course(id)
+	course.Version = ifMatch
+	if err := s.updateCourse(ctx, &course, edit(ctx)); err != nil {
+		return repository.Course{}, writeError(err, problem.CodeCourseNotFound, "Course")
+	}
+	return course, nil
+}
+
+// updateCourse is UpdateCourse on the repository, through saveCourse.
+func (s *CourseService) updateCourse(ctx context.Context, course *repository.Course, e repository.Edit) error {
+	return s.saveCourse(ctx, course, func(c *repository.Course) error {
+		return s.courses.UpdateCourse(ctx, c, e)
+	})
+}
+
+// GetCourseBySlug finds a course by its current or a former slug, and
+// reports whether slug is a former one that callers should be redirected
+// away from.
+func (s *CourseService) GetCourseBySlug(ctx context.Context, slug string, f repository.Filter) (repository.Course, bool, error) {
+	id, err := s.courses.FindSlug(ctx, slug)
+	if errors.Is(err, repository.ErrNotFound) {
+		return repository.Course{}, false, problem.NotFound(problem.CodeCourseNotFound, "Course not found")
+	}
+	if err != nil {
+		return repository.Course{}, false, err
+	}
+	course, err := s.GetCourse(ctx, id, f)
+	return course, err == nil && course.UniqueID != slug, err
+}
+
+// PatchCourse applies an RFC 7396 merge patch to the course's writable
+// fields: absent fields keep their value and null clears an optional one.
+// With ifMatch 0 the read-modify-write is retried on concurrent edits, so
+// the patch always lands on the latest version.
+func (s *CourseService) PatchCourse(ctx context.Context, id int, patch []byte, ifMatch int) (repository.Course, error) {
+	if err := s.authorize(ctx, id, editors); err != nil {
+		return repository.Course{}, err
+	}
+	for attempt := 1; ; attempt++ {
+		current, err := s.GetCourse(ctx, id, repository.Filter{})
+		if err != nil {
+			return repository.Course{}, err
+		}
+		if ifMatch != 0 && ifMatch != current.Version {
+			return repository.Course{}, stale("Course")
+		}
+		var in CourseInput
+		if err := applyPatch(courseInput(current), patch, &in); err != nil {
+			return repository.Course{}, err
+		}
+		if err := s.checkCourse(ctx, in, courseInput(current)); err != nil {
+			return repository.Course{}, err
+		}
+		course := in.course(id)
+		course.Version = current.Version
+		err = s.updateCourse(ctx, &course, edit(ctx))
+		if errors.Is(err, repository.ErrVersionConflict) && ifMatch == 0 && attempt < patchAttempts {
+			continue
+		}
+		if err != nil {
+			return repository.Course{}, writeError(err, problem.CodeCourseNotFound, "Course")
+		}
+		return course, nil
+	}
+}
+
+// ListCourseRevisions returns one page of the course's revisions, oldest
+// first, if f lets the course through.
+func (s *CourseService) ListCourseRevisions(ctx context.Context, id int, page paging.Page, f repository.Filter) ([]repository.Revision, error) {
+	if _, err := s.GetCourse(ctx, id, f); err != nil {
+		return nil, err
+	}
+	return s.courses.ListCourseRevisions(ctx, id, page)
+}
+
+func (s *CourseService) GetCourseRevision(ctx context.Context, id, version int, f repository.Filter) (repository.Revision, error) {
+	if _, err := s.GetCourse(ctx, id, f); err != nil {
+		return repository.Revision{}, err
+	}
+	return revisionError(s.courses.GetCourseRevision(ctx, id, version))
+}
+
+// DiffCourse compares the course's fields at two revisions. from may be
+// later than to, which shows the edits undone.
+func (s *CourseService) DiffCourse(ctx context.Context, id, from, to int, f repository.Filter) (Diff, error) {
+	if _, err := s.GetCourse(ctx, id, f); err != nil {
+		return Diff{}, err
+	}
+	return diff(ctx, id, from, to, s.courses.GetCourseRevision)
+}
+
+// RollbackCourse writes the fields of an earlier revision back as a new
+// revision, with the same ifMatch semantics as UpdateCourse. Status,
+// schedule, capacity and enrollment window are not part of a revision and
+// stay as they are. Media URLs are restored as recorded, without
+// checkMediaURLs; a category deleted since is dropped.
+func (s *CourseService) RollbackCourse(ctx context.Context, id, version, ifMatch int) (repository.Course, error) {
+	if err := s.authorize(ctx, id, editors); err != nil {
+		return repository.Course{}, err
+	}
+	current, err := s.GetCourse(ctx, id, repository.Filter{})
+	if err != nil {
+		return repository.Course{}, err
+	}
+	revision, err := revisionError(s.courses.GetCourseRevision(ctx, id, version))
+	if err != nil {
+		return repository.Course{}, err
+	}
+	course := repository.Course{
+		ID:               id,
+		Title:            revision.Fields["title"],
+		Content:          revision.Fields["content"],
+		OverviewVideoURL: revision.Fields["overview_video_url"],
+		CoverImageURL:    revision.Fields["cover_image_url"],
+		UniqueID:         revision.Fields["unique_id"],
+		Tags:             []string{},
+		Version:          ifMatch,
+		// Not part of a revision.
+		Capacity:           current.Capacity,
+		EnrollmentOpensAt:  current.EnrollmentOpensAt,
+		EnrollmentClosesAt: current.EnrollmentClosesAt,
+	}
+	if !validSlug(course.UniqueID) {
+		// Revisions from before slugs may hold none or a malformed one.
+		course.UniqueID = ""
+	}
+	if tags := revision.Fields["tags"]; tags != "" {
+		course.Tags = strings.Split(tags, ",")
+	}
+	course.Price, _ = strconv.ParseFloat(revision.Fields["price"], 64)
+	if id, err := strconv.Atoi(revision.Fields["category_id"]); err == nil {
+		switch _, err := s.categories.GetCategory(ctx, id); {
+		case err == nil:
+			course.CategoryID = &id
+		case !errors.Is(err, repository.ErrNotFound):
+			return repository.Course{}, err
+		}
+	}
+	e := edit(ctx)
+	e.RollbackOf = version
+	if err := s.updateCourse(ctx, &course, e); err != nil {
+		return repository.Course{}, writeError(err, problem.CodeCourseNotFound, "Course")
+	}
+	return course, nil
+}
+
+// DeleteCourse soft-deletes the course and its series; enrollments are kept
+// until the course is purged.
+func (s *CourseService) DeleteCourse(ctx context.Context, id int) error {
+	if err := s.authorize(ctx, id, owners); err != nil {
+		return err
+	}
+	return s.courses.DeleteCourse(ctx, id)
+}
+
+// RestoreCourse undeletes the course and the series deleted with it.
+func (s *CourseService) RestoreCourse(ctx context.Context, id int) (repository.Course, error) {
+	if err := s.authorize(ctx, id, owners); err != nil {
+		return repository.Course{}, err
+	}
+	course, err := s.courses.RestoreCourse(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return course, problem.NotFound(problem.CodeCourseNotFound, "Course not found")
+	}
+	return course, err
+}
+
+// SubmitCourse sends a draft for review.
+func (s *CourseService) SubmitCourse(ctx context.Context, id, ifMatch int) (repository.Course, error) {
+	return s.transition(ctx, id, ifMatch, "submit", editors, func(c *repository.Course) bool {
+		if c.Status != repository.StatusDraft {
+			return false
+		}
+		c.Status = repository.StatusInReview
+		return true
+	})
+}
+
+// RejectCourse sends a course under review back to draft.
+func (s *CourseService) RejectCourse(ctx context.Context, id, ifMatch int) (repository.Course, error) {
+	return s.transition(ctx, id, ifMatch, "reject", reviewers, func(c *repository.Course) bool {
+		if c.Status != repository.StatusInReview {
+			return false
+		}
+		c.Status = repository.StatusDraft
+		return true
+	})
+}
+
+// PublishCourse publishes a course that is not yet published. A publishAt in
+// the future only schedules it: the status stays as it is until the publish
+// job runs PublishDue at or after that time.
+func (s *CourseService) PublishCourse(ctx context.Context, id int, publishAt *time.Time, ifMatch int) (repository.Course, error) {
+	return s.transition(ctx, id, ifMatch, "publish", reviewers, func(c *repository.Course) bool {
+		if c.Status == repository.StatusPublished {
+			return false
+		}
+		if now := time.Now(); publishAt != nil && publishAt.After(now) {
+			c.PublishAt = publishAt
+		} else {
+			c.Status, c.PublishedAt = repository.StatusPublished, &now
+		}
+		return true
+	})
+}
+
+// ArchiveCourse takes a course out of the catalogue. Learners who are
+// enrolled keep their enrollments; nobody new can enroll.
+func (s *CourseService) ArchiveCourse(ctx context.Context, id, ifMatch int) (repository.Course, error) {
+	return s.transition(ctx, id, ifMatch, "archive", owners, func(c *repository.Course) bool {
+		if c.Status == repository.StatusArchived {
+			return false
+		}
+		c.Status = repository.StatusArchived
+		return true
+	})
+}
+
+// PublishDue publishes the courses whose scheduled time has come.
+func (s *CourseService) PublishDue(ctx context.Context) (int64, error) {
+	return s.courses.PublishDue(ctx, time.Now())
+}
+
+// transition moves a course through the workflow. apply changes the status
+// of a copy of the current course and reports false if action is not allowed
+// from its status. Every action cancels a pending schedule unless apply sets
+// a new one. Concurrent changes are retried like in PatchCourse. Besides
+// admins, only instructors with one of roles may take the action.
+func (s *CourseService) transition(ctx context.Context, id, ifMatch int, action string, roles []string, apply func(*repository.Course) bool) (repository.Course, error) {
+	if err := s.authorize(ctx, id, roles); err != nil {
+		return repository.Course{}, err
+	}
+	for attempt := 1; ; attempt++ {
+		course, err := s.GetCourse(ctx, id, repository.Filter{})
+		if err != nil {
+			return repository.Course{}, err
+		}
+		if ifMatch != 0 && ifMatch != course.Version {
+			return repository.Course{}, stale("Course")
+		}
+		from := course.Status
+		course.PublishAt = nil
+		if !apply(&course) {
+			return repository.Course{}, problem.Conflict(problem.CodeCourseStatusConflict,
+				fmt.Sprintf("Cannot %s a course that is %s", action, from))
+		}
+		err = s.courses.UpdateStatus(ctx, &course)
+		if errors.Is(err, repository.ErrVersionConflict) && ifMatch == 0 && attempt < patchAttempts {
+			continue
+		}
+		if err != nil {
+			return repository.Course{}, writeError(err, problem.CodeCourseNotFound, "Course")
+		}
+		return course, nil
+	}
+}
+
+// ListSeries returns one page of the course's series, widening f for the
+// course's instructors like GetCourse.
+func (s *CourseService) ListSeries(ctx context.Context, courseID int, page paging.Page, f repository.Filter) ([]repository.Series, error) {
+	f, err := s.visible(ctx, courseID, f)
+	if err != nil {
+		return nil, err
+	}
+	return s.series.ListSeries(ctx, courseID, page, f)
+}
+
+func (s *CourseService) GetSeries(ctx context.Context, id int, f repository.Filter) (repository.Series, error) {
+	series, err := s.series.GetSeries(ctx, id, f)
+	if errors.Is(err, repository.ErrNotFound) && f.Status != "" && auth.CallerOf(ctx).UserID != 0 {
+		series, err = s.instructorSeries(ctx, id, f)
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return series, problem.NotFound(problem.CodeSeriesNotFound, "Series not found")
+	}
+	return series, err
+}
+
+// instructorSeries is GetSeries for a series whose course's status f does
+// not let through, which the course's instructors see nevertheless.
+func (s *CourseService) instructorSeries(ctx context.Context, id int, f repository.Filter) (repository.Series, error) {
+	series, err := s.series.GetSeries(ctx, id, repository.Filter{IncludeDeleted: f.IncludeDeleted})
+	if err != nil {
+		return series, err
+	}
+	if f, err = s.visible(ctx, series.CourseID, f); err != nil {
+		return repository.Series{}, err
+	}
+	if f.Status != "" {
+		return repository.Series{}, repository.ErrNotFound
+	}
+	return series, nil
+}
+
+func (s *CourseService) CreateSeries(ctx context.Context, courseID int, in SeriesInput) (repository.Series, error) {
+	if err := s.authorize(ctx, courseID, editors); err != nil {
+		return repository.Series{}, err
+	}
+	if err := in.validate(); err != nil {
+		return repository.Series{}, err
+	}
+	series := in.series(0)
+	series.CourseID = courseID
+	err := s.series.CreateSeries(ctx, &series, edit(ctx))
+	if errors.Is(err, repository.ErrCourseNotFound) {
+		return repository.Series{}, problem.Unprocessable(problem.CodeCourseNotFound, "Course does not exist")
+	}
+	if err != nil {
+		return repository.Series{}, err
+	}
+	return series, nil
+}
+
+// UpdateSeries replaces every writable field of the series, with the same
+// ifMatch semantics as UpdateCourse.
+func (s *CourseService) UpdateSeries(ctx context.Context, id int, in SeriesInput, ifMatch int) (repository.Series, error) {
+	if err := s.authorizeSeries(ctx, id, editors); err != nil {
+		return repository.Series{}, err
+	}
+	if err := in.validate(); err != nil {
+		return repository.Series{}, err
+	}
+	series := in.series(id)
+	series.Version = ifMatch
+	if err := s.series.UpdateSeries(ctx, &series, edit(ctx)); err != nil {
+		return repository.Series{}, writeError(err, problem.CodeSeriesNotFound, "Series")
+	}
+	return series, nil
+}
+
+// PatchSeries is PatchCourse for a series.
+func (s *CourseService) PatchSeries(ctx context.Context, id int, patch []byte, ifMatch int) (repository.Series, error) {
+	if err := s.authorizeSeries(ctx, id, editors); err != nil {
+		return repository.Series{}, err
+	}
+	for attempt := 1; ; attempt++ {
+		current, err := s.GetSeries(ctx, id, repository.Filter{})
+		if err != nil {
+			return repository.Series{}, err
+		}
+		if ifMatch != 0 && ifMatch != current.Version {
+			return repository.Series{}, stale("Series")
+		}
+		var in SeriesInput
+		if err := applyPatch(seriesInput(current), patch, &in); err != nil {
+			return repository.Series{}, err
+		}
+		if err := in.validate(); err != nil {
+			return repository.Series{}, err
+		}
+		series := in.series(id)
+		series.Version = current.Version
+		err = s.series.UpdateSeries(ctx, &series, edit(ctx))
+		if errors.Is(err, repository.ErrVersionConflict) && ifMatch == 0 && attempt < patchAttempts {
+			continue
+		}
+		if err != nil {
+			return repository.Series{}, writeError(err, problem.CodeSeriesNotFound, "Series")
+		}
+		return series, nil
+	}
+}
+
+func (s *CourseService) ListSeriesRevisions(ctx context.Context, id int, page paging.Page, f repository.Filter) ([]repository.Revision, error) {
+	if _, err := s.GetSeries(ctx, id, f); err != nil {
+		return nil, err
+	}
+	return s.series.ListSeriesRevisions(ctx, id, page)
+}
+
+func (s *CourseService) GetSeriesRevision(ctx context.Context, id, version int, f repository.Filter) (repository.Revision, error) {
+	if _, err := s.GetSeries(ctx, id, f); err != nil {
+		return repository.Revision{}, err
+	}
+	return revisionError(s.series.GetSeriesRevision(ctx, id, version))
+}
+
+func (s *CourseService) DiffSeries(ctx context.Context, id, from, to int, f repository.Filter) (Diff, error) {
+	if _, err := s.GetSeries(ctx, id, f); err != nil {
+		return Diff{}, err
+	}
+	return diff(ctx, id, from, to, s.series.GetSeriesRevision)
+}
+
+// RollbackSeries is RollbackCourse for a series.
+func (s *CourseService) RollbackSeries(ctx context.Context, id, version, ifMatch int) (repository.Series, error) {
+	if err := s.authorizeSeries(ctx, id, editors); err != nil {
+		return repository.Series{}, err
+	}
+	if _, err := s.GetSeries(ctx, id, repository.Filter{}); err != nil {
+		return repository.Series{}, err
+	}
+	revision, err := revisionError(s.series.GetSeriesRevision(ctx, id, version))
+	if err != nil {
+		return repository.Series{}, err
+	}
+	series := repository.Series{
+		ID:            id,
+		Title:         revision.Fields["title"],
+		Description:   revision.Fields["description"],
+		IsFreePreview: revision.Fields["is_free_preview"] == "true",
+		Version:       ifMatch,
+	}
+	series.Duration, _ = strconv.Atoi(revision.Fields["duration"])
+	e := edit(ctx)
+	e.RollbackOf = version
+	if err := s.series.UpdateSeries(ctx, &series, e); err != nil {
+		return repository.Series{}, writeError(err, problem.CodeSeriesNotFound, "Series")
+	}
+	return series, nil
+}
+
+func (s *CourseService) DeleteSeries(ctx context.Context, id int) error {
+	if err := s.authorizeSeries(ctx, id, editors); err != nil {
+		return err
+	}
+	return s.series.DeleteSeries(ctx, id)
+}
+
+// RestoreSeries undeletes a series whose course is live.
+func (s *CourseService) RestoreSeries(ctx context.Context, id int) (repository.Series, error) {
+	if err := s.authorizeSeries(ctx, id, editors); err != nil {
+		return repository.Series{}, err
+	}
+	series, err := s.series.RestoreSeries(ctx, id)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return series, problem.NotFound(problem.CodeSeriesNotFound, "Series not found")
+	case errors.Is(err, repository.ErrCourseNotFound):
+		return series, problem.Unprocessable(problem.CodeCourseNotFound, "The series' course is deleted; restore the course first")
+	}
+	return series, err
+}
+
+// PurgeDeleted permanently removes series and courses that were deleted
+// more than retention ago and returns how many rows went.
+func (s *CourseService) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+	before := time.Now().Add(-retention)
+	series, err := s.series.PurgeSeries(ctx, before)
+	if err != nil {
+		return series, err
+	}
+	courses, err := s.courses.PurgeCourses(ctx, before)
+	return series + courses, err
+}
+
+// applyPatch merges an RFC 7396 patch into the JSON form of current and
+// decodes the result into out, rejecting fields out does not have.
+func applyPatch(current interface{}, patch []byte, out interface{}) error {
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	merged, err := mergepatch.Apply(doc, patch)
+	if err != nil {
+		return problem.BadRequest(problem.CodeInvalidBody, "Body must be a JSON merge patch object")
+	}
+	dec := json.NewDecoder(bytes.NewReader(merged))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(out); err != nil {
+		return problem.BadRequest(problem.CodeValidationFailed, "Patch contains an unknown field or a value of the wrong type")
+	}
+	return nil
+}
+
+func revisionError(r repository.Revision, err error) (repository.Revision, error) {
+	if errors.Is(err, repository.ErrNotFound) {
+		return r, problem.NotFound(problem.CodeRevisionNotFound, "Revision not found")
+	}
+	return r, err
+}
+
+func diff(ctx context.Context, id, from, to int, get func(context.Context, int, int) (repository.Revision, error)) (Diff, error) {
+	a, err := revisionError(get(ctx, id, from))
+	if err != nil {
+		return Diff{}, err
+	}
+	b, err := revisionError(get(ctx, id, to))
+	if err != nil {
+		return Diff{}, err
+	}
+	return Diff{From: from, To: to, Changes: repository.Changes(a.Fields, b.Fields)}, nil
+}
+
+// writeError maps the repository's update failures to problems.
+func writeError(err error, notFoundCode, resource string) error {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return problem.NotFound(notFoundCode, resource+" not found")
+	case errors.Is(err, repository.ErrVersionConflict):
+		return stale(resource)
+	}
+	return err
+}
+
+func stale(resource string) error {
+	return problem.New(http.StatusPreconditionFailed, problem.CodePreconditionFailed,
+		resource+" has been modified since the version in If-Match; fetch it again and retry")
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"course-service/repository"
+	"mopcare/problem"
+)
+
+// A slug is lowercase ASCII letters and digits in hyphen-separated words. It
+// is what unique_id holds and what GET /courses/by-slug/:slug looks up.
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+const (
+	maxSlugLength = 100
+	// maxSlugBase leaves room for a collision suffix within maxSlugLength.
+	maxSlugBase = 80
+)
+
+func validSlug(slug string) bool {
+	return len(slug) <= maxSlugLength && slugPattern.MatchString(slug)
+}
+
+// slugify turns a title into a slug base: runs of anything but ASCII letters
+// and digits become one hyphen. A title with nothing left gives "course".
+func slugify(title string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(title) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+		if b.Len() >= maxSlugBase {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return "course"
+	}
+	return strings.TrimRight(b.String()[:min(b.Len(), maxSlugBase)], "-")
+}
+
+// freeSlug picks the first of base, base-2, base-3, ... that no other course
+// holds now or held before.
+func (s *CourseService) freeSlug(ctx context.Context, courseID int, title string) (string, error) {
+	base := slugify(title)
+	taken, err := s.courses.TakenSlugs(ctx, base, courseID)
+	if err != nil {
+		return "", err
+	}
+	used := make(map[string]bool, len(taken))
+	for _, slug := range taken {
+		used[slug] = true
+	}
+	slug := base
+	for n := 2; used[slug]; n++ {
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+	return slug, nil
+}
+
+// saveCourse runs write for c, first generating c's slug from its title if
+// it has none. A generated slug that a concurrent write claimed first is
+// replaced by the next free one and the write retried.
+func (s *CourseService) saveCourse(ctx context.Context, c *repository.Course, write func(*repository.Course) error) error {
+	generate := c.UniqueID == ""
+	for attempt := 1; ; attempt++ {
+		if generate {
+			slug, err := s.freeSlug(ctx, c.ID, c.Title)
+			if err != nil {
+				return err
+			}
+			c.UniqueID = slug
+		}
+		err := write(c)
+		if generate && attempt < patchAttempts && slugClash(err) {
+			continue
+		}
+		return err
+	}
+}
+
+// slugClash reports whether err is the unique_id conflict, however the
+// repository produced it.
+func slugClash(err error) bool {
+	if err == nil || errors.Is(err, repository.ErrNotFound) || errors.Is(err, repository.ErrVersionConflict) {
+		return false
+	}
+	p, _ := problem.From(err)
+	return p.Code == problem.CodeCourseUniqueIDTaken
+}
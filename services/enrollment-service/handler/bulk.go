@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"mopcare/bulk"
+	"mopcare/problem"
+)
+
+func (h *Handler) importEnrollments(c *gin.Context) {
+	opts, err := bulk.ParseOptions(c.Request.URL.Query())
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, bulk.MaxBytes)
+	report, err := h.enrollments.Import(h.context(c), body, opts)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *Handler) exportEnrollments(c *gin.Context) {
+	format, err := bulk.ParseFormat(c.Query("format"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	courseID := 0
+	if raw := c.Query("course_id"); raw != "" {
+		if courseID, err = strconv.Atoi(raw); err != nil || courseID < 1 {
+			writeError(c, problem.BadRequest(problem.CodeInvalidQuery, "course_id must be a positive integer"))
+			return
+		}
+	}
+	resp := bulk.NewResponse(c.Writer, format, "enrollments")
+	err = h.enrollments.Export(h.context(c), resp, format, courseID)
+	switch {
+	case err == nil:
+		resp.Close()
+	case !resp.Started():
+		writeError(c, err)
+	default:
+		// The status is already sent, so the truncated download is left for
+		// the client to notice.
+		log.Printf("%s %s: export ended early: %v", c.Request.Method, c.Request.URL.Path, err)
+		c.Abort()
+	}
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"enrollment-service/repository"
+	"mopcare/paging"
+	"mopcare/problem"
+	"mopcare/webhooks"
+)
+
+// importable is seeded, with user 2 known by email and a second course 11.
+func importable(m *repository.Memory) {
+	seeded(m)
+	m.AddUser(2)
+	m.SetUserEmail(2, "grace@example.com")
+	m.AddCourse(11)
+}
+
+func TestImportEnrollments(t *testing.T) {
+	csv := "user_id,email,course_id,status\n1,,10,\n,grace@example.com,11,completed\n"
+	runCases(t, []testCase{
+		{name: "imports", method: "POST", path: "/enrollments/import", body: csv, admin: true, setup: importable, status: 200, contains: `"imported":2`},
+		{name: "dry run", method: "POST", path: "/enrollments/import?dry_run=true", body: csv, admin: true, setup: importable, status: 200, contains: `"imported":0`},
+		{name: "mapped columns", method: "POST", path: "/enrollments/import?map=user_id:Learner&map=course_id:Course",
+			body: "Learner,Course\n1,10\n", admin: true, setup: importable, status: 200, contains: `"imported":1`},
+		{name: "unknown email", method: "POST", path: "/enrollments/import", body: "email,course_id\nnobody@example.com,10\n", admin: true,
+			setup: importable, status: 200, contains: `{"row":2,"field":"email","message":"No user has this email"}`},
+		{name: "no user", method: "POST", path: "/enrollments/import", body: "user_id,course_id\n,10\n", admin: true,
+			setup: importable, status: 200, contains: `{"row":2,"field":"user_id","message":"user_id or email is required"}`},
+		{name: "unknown course", method: "POST", path: "/enrollments/import", body: "user_id,course_id\n1,99\n", admin: true,
+			setup: importable, status: 200, contains: `{"row":2,"field":"course_id","message":"Course does not exist"}`},
+		{name: "invalid status", method: "POST", path: "/enrollments/import", body: "user_id,course_id,status\n1,10,dropped\n", admin: true,
+			setup: importable, status: 200, contains: `"field":"status"`},
+		{name: "already enrolled", method: "POST", path: "/enrollments/import", body: "user_id,course_id\n1,10\n", admin: true,
+			setup: enrolled, status: 200, contains: `"message":"User is already enrolled in this course"`},
+		{name: "same enrollment twice", method: "POST", path: "/enrollments/import", body: "user_id,course_id\n1,10\n1,10\n", admin: true,
+			setup: importable, status: 200, contains: `{"row":3,"message":"Row 2 enrolls the same user in the same course"}`},
+		{name: "completed with quizzes pending", method: "POST", path: "/enrollments/import", body: "user_id,course_id,status\n1,10,completed\n", admin: true,
+			setup: func(m *repository.Memory) { seeded(m); m.AddQuiz(10, 5) }, status: 200, contains: `"message":"1 required quizzes of this course are not passed yet"`},
+		{name: "atomic import with an invalid row", method: "POST", path: "/enrollments/import", body: "user_id,course_id\n1,10\n1,99\n", admin: true,
+			setup: importable, status: 200, contains: `"imported":0,"failed":1`},
+		{name: "best effort import with an invalid row", method: "POST", path: "/enrollments/import?mode=best_effort", body: "user_id,course_id\n1,10\n1,99\n",
+			admin: true, setup: importable, status: 200, contains: `"imported":1,"failed":1`},
+		{name: "no course column", method: "POST", path: "/enrollments/import", body: "user_id\n1\n", admin: true, status: 400, code: problem.CodeValidationFailed},
+		{name: "needs admin", method: "POST", path: "/enrollments/import", body: csv, user: 1, status: 403, code: problem.CodeForbidden},
+		{name: "repository error", method: "POST", path: "/enrollments/import", body: csv, admin: true, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
+
+func TestImportCertifiesCompletedRows(t *testing.T) {
+	repo := repository.NewMemory()
+	importable(repo)
+	req := httptest.NewRequest("POST", "/enrollments/import", strings.NewReader("email,course_id,status\ngrace@example.com,11,completed\n"))
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	rec := httptest.NewRecorder()
+	newRouter(repo, webhooks.NewMemory()).ServeHTTP(rec, req)
+
+	if rec.Code != 200 {
+		t.Fatalf("status = %d (body %s)", rec.Code, rec.Body)
+	}
+	certificates, err := repo.ListCertificates(context.Background(), 2, paging.Page{})
+	if err != nil || len(certificates) != 1 || certificates[0].CourseID != 11 {
+		t.Errorf("certificates = %+v, %v; want one for course 11", certificates, err)
+	}
+}
+
+func TestExportEnrollments(t *testing.T) {
+	twoCourses := func(m *repository.Memory) {
+		enrolled(m)
+		m.AddCourse(11)
+		m.Create(context.Background(), &repository.UserCourseEnrollment{UserID: 1, CourseID: 11, Status: "completed"})
+	}
+	runCases(t, []testCase{
+		{name: "csv", method: "GET", path: "/enrollments/export", admin: true, setup: twoCourses, status: 200,
+			contains: "id,user_id,course_id,status,cohort_id\n1,1,10,enrolled,\n2,1,11,completed,\n"},
+		{name: "one course", method: "GET", path: "/enrollments/export?course_id=11", admin: true, setup: twoCourses, status: 200,
+			contains: "id,user_id,course_id,status,cohort_id\n2,1,11,completed,\n"},
+		{name: "json lines", method: "GET", path: "/enrollments/export?format=jsonl", admin: true, setup: enrolled, status: 200,
+			contains: `{"id":1,"user_id":1,"course_id":10,"status":"enrolled"}`},
+		{name: "invalid course", method: "GET", path: "/enrollments/export?course_id=x", admin: true, status: 400, code: problem.CodeInvalidQuery},
+		{name: "unknown format", method: "GET", path: "/enrollments/export?format=xml", admin: true, status: 400, code: problem.CodeInvalidQuery},
+		{name: "needs admin", method: "GET", path: "/enrollments/export", user: 1, status: 403, code: problem.CodeForbidden},
+		{name: "repository error", method: "GET", path: "/enrollments/export", admin: true, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
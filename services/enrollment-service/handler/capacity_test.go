@@ -0,0 +1,144 @@
+package handler
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"enrollment-service/repository"
+	"mopcare/events"
+	"mopcare/problem"
+	"mopcare/webhooks"
+)
+
+// full is importable, with course 10 limited to one seat, which user 1 holds.
+func full(m *repository.Memory) {
+	importable(m)
+	m.AddUser(3)
+	one := 1
+	m.LimitCourse(10, repository.Limits{Capacity: &one})
+	m.Create(context.Background(), &repository.UserCourseEnrollment{UserID: 1, CourseID: 10, Status: "enrolled"})
+}
+
+// waiting is full, with users 2 and 3 on the waitlist of course 10.
+func waiting(m *repository.Memory) {
+	full(m)
+	m.Create(context.Background(), &repository.UserCourseEnrollment{UserID: 2, CourseID: 10, Status: "waitlisted"})
+	m.Create(context.Background(), &repository.UserCourseEnrollment{UserID: 3, CourseID: 10, Status: "waitlisted"})
+}
+
+// windowed is seeded, with enrollment in course 10 open between from and to.
+func windowed(from, to time.Duration) func(*repository.Memory) {
+	return func(m *repository.Memory) {
+		seeded(m)
+		opens, closes := time.Now().Add(from), time.Now().Add(to)
+		m.LimitCourse(10, repository.Limits{OpensAt: &opens, ClosesAt: &closes})
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "seat free", method: "POST", path: "/users/2/enrollments", admin: true,
+			setup: func(m *repository.Memory) {
+				importable(m)
+				two := 2
+				m.LimitCourse(10, repository.Limits{Capacity: &two})
+			},
+			body: `{"user_id":2,"course_id":10,"status":"enrolled"}`, status: 201, contains: `"status":"enrolled"`},
+		{name: "course full", method: "POST", path: "/users/2/enrollments", admin: true, setup: full,
+			body: `{"user_id":2,"course_id":10,"status":"enrolled"}`, status: 202, contains: `"status":"waitlisted"`},
+		{name: "completed into a full course", method: "POST", path: "/users/2/enrollments", admin: true, setup: full,
+			body: `{"user_id":2,"course_id":10,"status":"completed"}`, status: 409, code: problem.CodeCourseFull},
+		{name: "already waitlisted", method: "POST", path: "/users/2/enrollments", admin: true, setup: waiting,
+			body: `{"user_id":2,"course_id":10,"status":"enrolled"}`, status: 409, code: problem.CodeEnrollmentDuplicate},
+		{name: "complete while waitlisted", method: "POST", path: "/enrollments/2/complete", admin: true, setup: waiting,
+			status: 409, code: problem.CodeWaitlisted},
+		{name: "not open yet", method: "POST", path: "/users/1/enrollments", admin: true, setup: windowed(time.Hour, 2*time.Hour),
+			body: `{"user_id":1,"course_id":10,"status":"enrolled"}`, status: 409, code: problem.CodeEnrollmentNotOpen},
+		{name: "closed", method: "POST", path: "/users/1/enrollments", admin: true, setup: windowed(-2*time.Hour, -time.Hour),
+			body: `{"user_id":1,"course_id":10,"status":"enrolled"}`, status: 409, code: problem.CodeEnrollmentClosed},
+		{name: "open", method: "POST", path: "/users/1/enrollments", admin: true, setup: windowed(-time.Hour, time.Hour),
+			body: `{"user_id":1,"course_id":10,"status":"enrolled"}`, status: 201, contains: `"status":"enrolled"`},
+
+		{name: "batch fills the last seat and waitlists the rest", method: "POST", path: "/enrollments/batch", admin: true,
+			setup:  func(m *repository.Memory) { full(m); two := 2; m.LimitCourse(10, repository.Limits{Capacity: &two}) },
+			body:   `{"enrollments":[{"user_id":2,"course_id":10},{"user_id":3,"course_id":10}]}`,
+			status: 200, contains: `"enrollment":{"id":3,"user_id":3,"course_id":10,"status":"waitlisted"}`},
+		{name: "batch into a closed course", method: "POST", path: "/enrollments/batch", admin: true, setup: windowed(-2*time.Hour, -time.Hour),
+			body: `{"enrollments":[{"user_id":1,"course_id":10}]}`, status: 200, contains: `"code":"ENROLLMENT_CLOSED"`},
+		{name: "import waitlists", method: "POST", path: "/enrollments/import", admin: true, setup: full,
+			body: "user_id,course_id\n2,10\n", status: 200, contains: `"imported":1`},
+		{name: "import into a closed course", method: "POST", path: "/enrollments/import", admin: true, setup: windowed(-2*time.Hour, -time.Hour),
+			body: "user_id,course_id\n1,10\n", status: 200, contains: `{"row":2,"field":"course_id","message":"Enrollment in this course closed at `},
+		{name: "dry run into a course not open yet", method: "POST", path: "/enrollments/import?dry_run=true", admin: true,
+			setup: windowed(time.Hour, 2*time.Hour), body: "user_id,course_id\n1,10\n", status: 200,
+			contains: `"failed":1,"errors":[{"row":2,"field":"course_id","message":"Enrollment in this course opens at `},
+
+		{name: "roster", method: "GET", path: "/courses/10/roster", admin: true, setup: waiting,
+			status: 200, contains: `[{"id":1,"user_id":1,"course_id":10,"status":"enrolled"}]`},
+		{name: "waitlist", method: "GET", path: "/courses/10/waitlist", admin: true, setup: waiting, status: 200,
+			contains: `[{"enrollment_id":2,"user_id":2,"course_id":10,"position":1},{"enrollment_id":3,"user_id":3,"course_id":10,"position":2}]`},
+		{name: "waitlist page", method: "GET", path: "/courses/10/waitlist?offset=1", admin: true, setup: waiting,
+			status: 200, contains: `[{"enrollment_id":3,"user_id":3,"course_id":10,"position":2}]`},
+		{name: "waitlist skips deleted users", method: "GET", path: "/courses/10/waitlist", admin: true,
+			setup: func(m *repository.Memory) { waiting(m); m.DeleteUser(2) }, status: 200, contains: `[{"enrollment_id":3,"user_id":3,"course_id":10,"position":1}]`},
+		{name: "empty waitlist", method: "GET", path: "/courses/10/waitlist", admin: true, setup: full, status: 200, contains: `[]`},
+		{name: "roster needs admin", method: "GET", path: "/courses/10/roster", user: 1, setup: full, status: 403, code: problem.CodeForbidden},
+		{name: "waitlist needs admin", method: "GET", path: "/courses/10/waitlist", user: 1, setup: full, status: 403, code: problem.CodeForbidden},
+		{name: "roster of an invalid course", method: "GET", path: "/courses/x/roster", admin: true, status: 400, code: problem.CodeInvalidID},
+		{name: "roster repository error", method: "GET", path: "/courses/10/roster", admin: true, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
+
+func TestDeletingPromotesFirstWaitlisted(t *testing.T) {
+	m := repository.NewMemory()
+	waiting(m)
+	router := newRouter(m, webhooks.NewMemory())
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("DELETE", "/enrollments/1", nil))
+	if rec.Code != 200 {
+		t.Fatalf("status = %d (body %s)", rec.Code, rec.Body)
+	}
+
+	second, err := m.Get(context.Background(), 2)
+	if err != nil || second.Status != "enrolled" {
+		t.Errorf("enrollment 2 = %+v, %v; want the first waitlisted promoted", second, err)
+	}
+	third, err := m.Get(context.Background(), 3)
+	if err != nil || third.Status != "waitlisted" {
+		t.Errorf("enrollment 3 = %+v, %v; want it still waitlisted", third, err)
+	}
+	want := []events.Payload{
+		events.EnrollmentCreated{EnrollmentID: 1, UserID: 1, CourseID: 10, Status: "enrolled"},
+		events.EnrollmentCreated{EnrollmentID: 2, UserID: 2, CourseID: 10, Status: "enrolled"},
+	}
+	if got := m.Events(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %+v, want enrollment announced on promotion only: %+v", got, want)
+	}
+}
+
+func TestRaisingCapacityPromotesOnNextEnrollment(t *testing.T) {
+	m := repository.NewMemory()
+	waiting(m)
+	m.AddUser(4)
+	three := 3
+	m.LimitCourse(10, repository.Limits{Capacity: &three})
+	body := `{"user_id":4,"course_id":10,"status":"enrolled"}`
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/users/4/enrollments", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	newRouter(m, webhooks.NewMemory()).ServeHTTP(rec, req)
+
+	// Users 2 and 3 take the two new seats before user 4 asks for one.
+	if rec.Code != 202 {
+		t.Fatalf("status = %d, want 202 (body %s)", rec.Code, rec.Body)
+	}
+	for _, id := range []int{2, 3} {
+		if e, _ := m.Get(context.Background(), id); e.Status != "enrolled" {
+			t.Errorf("enrollment %d = %+v, want it promoted", id, e)
+		}
+	}
+}
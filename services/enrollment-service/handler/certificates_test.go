@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"enrollment-service/repository"
+	"mopcare/auth"
+	"mopcare/events"
+	"mopcare/paging"
+	"mopcare/problem"
+	"mopcare/webhooks"
+)
+
+const code = "ABCD-EFGH-JK12"
+
+// certified is enrolled with enrollment 1 completed and certified as code.
+func certified(m *repository.Memory) {
+	enrolled(m)
+	m.NameUser(1, "Margaret Johnson")
+	m.TitleCourse(10, "Heart Health (Level 2)")
+	m.Complete(context.Background(), 1)
+	m.IssueCertificate(context.Background(), 1, code)
+}
+
+func TestCompletingIssuesCertificate(t *testing.T) {
+	m := repository.NewMemory()
+	enrolled(m)
+	m.AddCourse(11)
+	router := newRouter(m, webhooks.NewMemory())
+	for _, req := range []struct{ method, path, body string }{
+		{"POST", "/enrollments/1/complete", ""},
+		{"POST", "/enrollments/1/complete", ""},
+		{"POST", "/users/1/enrollments", `{"user_id":1,"course_id":11,"status":"completed"}`},
+	} {
+		rec := httptest.NewRecorder()
+		r := httptest.NewRequest(req.method, req.path, strings.NewReader(req.body))
+		r.Header.Set("Authorization", "Bearer "+adminToken)
+		router.ServeHTTP(rec, r)
+		if rec.Code >= 300 {
+			t.Fatalf("%s %s = %d: %s", req.method, req.path, rec.Code, rec.Body)
+		}
+	}
+
+	certificates, err := m.ListCertificates(context.Background(), 1, paging.Page{})
+	if err != nil || len(certificates) != 2 {
+		t.Fatalf("certificates = %+v, %v, want one per completed enrollment", certificates, err)
+	}
+	c := certificates[0]
+	if c.EnrollmentID != 1 || c.LearnerName != "User 1" || c.CourseTitle != "Course 10" || len(c.Code) != len(code) {
+		t.Errorf("certificate = %+v", c)
+	}
+
+	second := certificates[1].EnrollmentID
+	want := []events.Payload{
+		events.EnrollmentCreated{EnrollmentID: 1, UserID: 1, CourseID: 10, Status: "enrolled"},
+		events.EnrollmentCompleted{EnrollmentID: 1, UserID: 1, CourseID: 10},
+		events.EnrollmentCreated{EnrollmentID: second, UserID: 1, CourseID: 11, Status: "completed"},
+		events.EnrollmentCompleted{EnrollmentID: second, UserID: 1, CourseID: 11},
+	}
+	if got := m.Events(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %+v, want completion announced once per enrollment: %+v", got, want)
+	}
+}
+
+func TestCertificates(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "verify", method: "GET", path: "/certificates/" + code + "/verify", setup: certified, status: 200,
+			contains: `"learner_name":"Margaret Johnson","course_title":"Heart Health (Level 2)"`},
+		{name: "verify as typed", method: "GET", path: "/certificates/abcd%20efgh%20jk12/verify", setup: certified, status: 200, contains: code},
+		{name: "verify unknown", method: "GET", path: "/certificates/ABCD-EFGH-JK13/verify", setup: certified, status: 404, code: problem.CodeCertificateNotFound},
+		{name: "verify revoked", method: "GET", path: "/certificates/" + code + "/verify", setup: func(m *repository.Memory) {
+			certified(m)
+			m.Delete(context.Background(), 1)
+		}, status: 404, code: problem.CodeCertificateNotFound},
+		{name: "verify error", method: "GET", path: "/certificates/" + code + "/verify", setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+
+		{name: "list own", method: "GET", path: "/users/1/certificates", setup: certified, user: 1, status: 200, contains: `"code":"` + code + `"`},
+		{name: "list as admin", method: "GET", path: "/users/1/certificates", setup: certified, admin: true, status: 200, contains: `"enrollment_id":1`},
+		{name: "list none", method: "GET", path: "/users/1/certificates", setup: enrolled, user: 1, status: 200, contains: `[]`},
+		{name: "list anonymous", method: "GET", path: "/users/1/certificates", setup: certified, status: 401, code: problem.CodeUnauthorized},
+		{name: "list someone else's", method: "GET", path: "/users/1/certificates", setup: certified, user: 2, status: 403, code: problem.CodeForbidden},
+		{name: "list invalid id", method: "GET", path: "/users/x/certificates", user: 1, status: 400, code: problem.CodeInvalidID},
+		{name: "list invalid page", method: "GET", path: "/users/1/certificates?limit=0", user: 1, status: 400, code: problem.CodeInvalidQuery},
+
+		{name: "download own", method: "GET", path: "/certificates/" + code + "/pdf", setup: certified, user: 1, status: 200, contains: "%PDF-1.4"},
+		{name: "download as admin", method: "GET", path: "/certificates/" + code + "/pdf", setup: certified, admin: true, status: 200, contains: "(Margaret Johnson)"},
+		{name: "download someone else's", method: "GET", path: "/certificates/" + code + "/pdf", setup: certified, user: 2,
+			status: 404, code: problem.CodeCertificateNotFound},
+		{name: "download anonymous", method: "GET", path: "/certificates/" + code + "/pdf", setup: certified, status: 401, code: problem.CodeUnauthorized},
+		{name: "download unknown", method: "GET", path: "/certificates/ABCD-EFGH-JK13/pdf", user: 1, status: 404, code: problem.CodeCertificateNotFound},
+	})
+}
+
+func TestCertificatePDF(t *testing.T) {
+	m := repository.NewMemory()
+	certified(m)
+	req := httptest.NewRequest("GET", "/certificates/"+code+"/pdf", nil)
+	req.Header.Set("Authorization", "Bearer "+auth.UserToken(1, time.Now().Add(time.Hour), adminToken))
+	rec := httptest.NewRecorder()
+	newRouter(m, webhooks.NewMemory()).ServeHTTP(rec, req)
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/pdf" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); cd != `attachment; filename="certificate-`+code+`.pdf"` {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	body := rec.Body.String()
+	for _, want := range []string{"(Margaret Johnson)", `(Heart Health \(Level 2\))`, "(on " + time.Now().UTC().Format("January 2, 2006") + ")", code} {
+		if !strings.Contains(body, want) {
+			t.Errorf("certificate lacks %q", want)
+		}
+	}
+}
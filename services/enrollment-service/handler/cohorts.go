@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"enrollment-service/service"
+	"mopcare/paging"
+	"mopcare/problem"
+)
+
+// batchEnroll answers 200 with a result for every item, whether or not any
+// was created.
+func (h *Handler) batchEnroll(c *gin.Context) {
+	var in service.BatchInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, problem.BadRequest(problem.CodeInvalidBody, "Invalid request body"))
+		return
+	}
+	result, err := h.enrollments.EnrollBatch(h.context(c), in)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) getCohorts(c *gin.Context) {
+	page, err := paging.Parse(c.Query("limit"), c.Query("offset"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	courseID := 0
+	if raw := c.Query("course_id"); raw != "" {
+		if courseID, err = strconv.Atoi(raw); err != nil || courseID < 1 {
+			writeError(c, problem.BadRequest(problem.CodeInvalidQuery, "course_id must be a positive integer"))
+			return
+		}
+	}
+	cohorts, err := h.enrollments.ListCohorts(h.context(c), courseID, page)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, cohorts)
+}
+
+func (h *Handler) createCohort(c *gin.Context) {
+	var in service.CohortInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, problem.BadRequest(problem.CodeInvalidBody, "Invalid request body"))
+		return
+	}
+	cohort, err := h.enrollments.CreateCohort(h.context(c), in)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Header("Location", "/cohorts/"+strconv.Itoa(cohort.ID))
+	c.JSON(http.StatusCreated, cohort)
+}
+
+func (h *Handler) getCohort(c *gin.Context) {
+	id, ok := paramID(c, "Invalid cohort ID")
+	if !ok {
+		return
+	}
+	cohort, err := h.enrollments.Cohort(h.context(c), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, cohort)
+}
+
+func (h *Handler) deleteCohort(c *gin.Context) {
+	id, ok := paramID(c, "Invalid cohort ID")
+	if !ok {
+		return
+	}
+	if err := h.enrollments.DeleteCohort(h.context(c), id); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Cohort deleted successfully"})
+}
+
+func (h *Handler) getCohortEnrollments(c *gin.Context) {
+	id, ok := paramID(c, "Invalid cohort ID")
+	if !ok {
+		return
+	}
+	page, err := paging.Parse(c.Query("limit"), c.Query("offset"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	enrollments, err := h.enrollments.CohortEnrollments(h.context(c), id, page)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, enrollments)
+}
+
+func (h *Handler) getRoster(c *gin.Context) {
+	id, ok := paramID(c, "Invalid course ID")
+	if !ok {
+		return
+	}
+	page, err := paging.Parse(c.Query("limit"), c.Query("offset"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	enrollments, err := h.enrollments.Roster(h.context(c), id, page)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, enrollments)
+}
+
+func (h *Handler) getWaitlist(c *gin.Context) {
+	id, ok := paramID(c, "Invalid course ID")
+	if !ok {
+		return
+	}
+	page, err := paging.Parse(c.Query("limit"), c.Query("offset"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	entries, err := h.enrollments.Waitlist(h.context(c), id, page)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
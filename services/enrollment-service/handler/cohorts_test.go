@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"enrollment-service/repository"
+	"mopcare/problem"
+)
+
+// cohorted is importable, with cohort 1 "Spring" of course 10.
+func cohorted(m *repository.Memory) {
+	importable(m)
+	m.CreateCohort(context.Background(), &repository.Cohort{CourseID: 10, Name: "Spring", StartsOn: "2027-03-01"})
+}
+
+// joined is cohorted, with user 1 enrolled in the cohort.
+func joined(m *repository.Memory) {
+	cohorted(m)
+	cohort := 1
+	m.Create(context.Background(), &repository.UserCourseEnrollment{UserID: 1, CourseID: 10, Status: "enrolled", CohortID: &cohort})
+}
+
+func TestBatchEnroll(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "creates", method: "POST", path: "/enrollments/batch", admin: true, setup: importable,
+			body:   `{"enrollments":[{"user_id":1,"course_id":10},{"user_id":2,"course_id":11,"status":"completed"}]}`,
+			status: 200, contains: `"created":2,"failed":0`},
+		{name: "into a cohort", method: "POST", path: "/enrollments/batch", admin: true, setup: cohorted,
+			body:   `{"cohort_id":1,"enrollments":[{"user_id":1},{"user_id":2}]}`,
+			status: 200, contains: `"enrollment":{"id":3,"user_id":2,"course_id":10,"status":"enrolled","cohort_id":1}`},
+		{name: "atomic batch with an invalid item", method: "POST", path: "/enrollments/batch", admin: true, setup: importable,
+			body:   `{"enrollments":[{"user_id":1,"course_id":10},{"user_id":9,"course_id":10}]}`,
+			status: 200, contains: `"created":0,"failed":1,"results":[{"index":0,"status":"skipped"},{"index":1,"status":"failed","error":{`},
+		{name: "best effort batch with an invalid item", method: "POST", path: "/enrollments/batch", admin: true, setup: importable,
+			body:   `{"mode":"best_effort","enrollments":[{"user_id":1,"course_id":10},{"user_id":1,"course_id":99}]}`,
+			status: 200, contains: `"created":1,"failed":1`},
+		{name: "already enrolled", method: "POST", path: "/enrollments/batch", admin: true, setup: enrolled,
+			body: `{"enrollments":[{"user_id":1,"course_id":10}]}`, status: 200, contains: `"code":"ENROLLMENT_DUPLICATE"`},
+		{name: "same pair twice", method: "POST", path: "/enrollments/batch", admin: true, setup: importable,
+			body:   `{"enrollments":[{"user_id":1,"course_id":10},{"user_id":1,"course_id":10}]}`,
+			status: 200, contains: `"detail":"Item 0 enrolls the same user in the same course"`},
+		{name: "quizzes pending", method: "POST", path: "/enrollments/batch", admin: true, setup: func(m *repository.Memory) { seeded(m); m.AddQuiz(10, 5) },
+			body: `{"enrollments":[{"user_id":1,"course_id":10,"status":"completed"}]}`, status: 200, contains: `"code":"QUIZZES_NOT_PASSED"`},
+		{name: "another course than the cohort's", method: "POST", path: "/enrollments/batch", admin: true, setup: cohorted,
+			body: `{"cohort_id":1,"enrollments":[{"user_id":1,"course_id":11}]}`, status: 200, contains: `"detail":"Course ID must be the cohort's course"`},
+		{name: "unknown cohort", method: "POST", path: "/enrollments/batch", admin: true, setup: importable,
+			body: `{"cohort_id":9,"enrollments":[{"user_id":1}]}`, status: 422, code: problem.CodeCohortNotFound},
+		{name: "empty", method: "POST", path: "/enrollments/batch", admin: true, body: `{"enrollments":[]}`, status: 400, code: problem.CodeValidationFailed},
+		{name: "unknown mode", method: "POST", path: "/enrollments/batch", admin: true,
+			body: `{"mode":"some","enrollments":[{"user_id":1,"course_id":10}]}`, status: 400, code: problem.CodeValidationFailed},
+		{name: "invalid body", method: "POST", path: "/enrollments/batch", admin: true, body: `{`, status: 400, code: problem.CodeInvalidBody},
+		{name: "needs admin", method: "POST", path: "/enrollments/batch", user: 1,
+			body: `{"enrollments":[{"user_id":1,"course_id":10}]}`, status: 403, code: problem.CodeForbidden},
+		{name: "repository error", method: "POST", path: "/enrollments/batch", admin: true, setup: failWith(errDB),
+			body: `{"enrollments":[{"user_id":1,"course_id":10}]}`, status: 500, code: problem.CodeInternal},
+	})
+}
+
+func TestCohorts(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "create", method: "POST", path: "/cohorts", admin: true, setup: seeded,
+			body: `{"course_id":10,"name":" Spring ","starts_on":"2027-03-01"}`, status: 201, contains: `"name":"Spring","starts_on":"2027-03-01","enrollments":0`},
+		{name: "name taken", method: "POST", path: "/cohorts", admin: true, setup: cohorted,
+			body: `{"course_id":10,"name":"Spring","starts_on":"2027-09-01"}`, status: 409, code: problem.CodeCohortNameTaken},
+		{name: "invalid date", method: "POST", path: "/cohorts", admin: true, setup: seeded,
+			body: `{"course_id":10,"name":"Spring","starts_on":"March"}`, status: 400, code: problem.CodeValidationFailed},
+		{name: "unknown course", method: "POST", path: "/cohorts", admin: true, setup: seeded,
+			body: `{"course_id":99,"name":"Spring","starts_on":"2027-03-01"}`, status: 422, code: problem.CodeCourseNotFound},
+		{name: "create needs admin", method: "POST", path: "/cohorts", user: 1, setup: seeded,
+			body: `{"course_id":10,"name":"Spring","starts_on":"2027-03-01"}`, status: 403, code: problem.CodeForbidden},
+
+		{name: "get", method: "GET", path: "/cohorts/1", admin: true, setup: joined, status: 200, contains: `"enrollments":1`},
+		{name: "get missing", method: "GET", path: "/cohorts/9", admin: true, status: 404, code: problem.CodeCohortNotFound},
+		{name: "list", method: "GET", path: "/cohorts?course_id=10", admin: true, setup: cohorted, status: 200, contains: `[{"id":1,`},
+		{name: "list another course", method: "GET", path: "/cohorts?course_id=11", admin: true, setup: cohorted, status: 200, contains: `[]`},
+		{name: "list invalid course", method: "GET", path: "/cohorts?course_id=x", admin: true, status: 400, code: problem.CodeInvalidQuery},
+		{name: "members", method: "GET", path: "/cohorts/1/enrollments", admin: true, setup: joined, status: 200, contains: `"cohort_id":1`},
+		{name: "members of a missing cohort", method: "GET", path: "/cohorts/9/enrollments", admin: true, status: 404, code: problem.CodeCohortNotFound},
+		{name: "delete", method: "DELETE", path: "/cohorts/1", admin: true, setup: joined, status: 200, contains: "deleted"},
+		{name: "delete missing", method: "DELETE", path: "/cohorts/9", admin: true, status: 404, code: problem.CodeCohortNotFound},
+		{name: "delete needs admin", method: "DELETE", path: "/cohorts/1", setup: cohorted, status: 403, code: problem.CodeForbidden},
+	})
+}
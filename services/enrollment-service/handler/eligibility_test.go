@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"enrollment-service/repository"
+	"mopcare/problem"
+)
+
+// gated is seeded with courses 11 and 12, titled, which course 10 requires;
+// user 1 has completed course 11.
+func gated(m *repository.Memory) {
+	seeded(m)
+	m.AddCourse(11)
+	m.AddCourse(12)
+	m.TitleCourse(11, "Cardiology Basics")
+	m.TitleCourse(12, "Anatomy")
+	m.AddPrerequisite(10, 12)
+	m.AddPrerequisite(10, 11)
+	m.Create(context.Background(), &repository.UserCourseEnrollment{UserID: 1, CourseID: 11, Status: "completed"})
+}
+
+func TestEligibility(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "missing a prerequisite", method: "GET", path: "/users/1/courses/10/eligibility", user: 1, setup: gated, status: 200,
+			contains: `{"user_id":1,"course_id":10,"eligible":false,"missing_prerequisites":[{"course_id":12,"title":"Anatomy"}]}`},
+		{name: "eligible", method: "GET", path: "/users/1/courses/11/eligibility", admin: true, setup: gated, status: 200,
+			contains: `"eligible":true,"missing_prerequisites":[]`},
+		{name: "deleted or unpublished prerequisites are not required", method: "GET", path: "/users/1/courses/10/eligibility", user: 1, setup: func(m *repository.Memory) {
+			gated(m)
+			m.DeleteCourse(12)
+		}, status: 200, contains: `"eligible":true`},
+		{name: "missing user", method: "GET", path: "/users/9/courses/10/eligibility", admin: true, setup: gated, status: 404, code: problem.CodeUserNotFound},
+		{name: "missing course", method: "GET", path: "/users/1/courses/99/eligibility", user: 1, setup: gated, status: 404, code: problem.CodeCourseNotFound},
+		{name: "another user", method: "GET", path: "/users/1/courses/10/eligibility", user: 2, setup: gated, status: 403, code: problem.CodeForbidden},
+		{name: "anonymous", method: "GET", path: "/users/1/courses/10/eligibility", setup: gated, status: 401, code: problem.CodeUnauthorized},
+		{name: "invalid course ID", method: "GET", path: "/users/1/courses/x/eligibility", user: 1, status: 400, code: problem.CodeInvalidID},
+		{name: "repository error", method: "GET", path: "/users/1/courses/10/eligibility", user: 1, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+
+		{name: "enrolling without prerequisites", method: "POST", path: "/users/1/enrollments", setup: gated,
+			body: `{"user_id":1,"course_id":10,"status":"enrolled"}`, status: 409, code: problem.CodePrerequisitesMissing},
+		{name: "enrolling with prerequisites", method: "POST", path: "/users/1/enrollments", setup: func(m *repository.Memory) {
+			gated(m)
+			m.Create(context.Background(), &repository.UserCourseEnrollment{UserID: 1, CourseID: 12, Status: "completed"})
+		}, body: `{"user_id":1,"course_id":10,"status":"enrolled"}`, status: 201},
+		{name: "importing without prerequisites", method: "POST", path: "/enrollments/import", admin: true, setup: gated,
+			body: "user_id,course_id\n1,10\n", status: 200, contains: `"message":"User has yet to complete the course's prerequisites: Anatomy"`},
+		{name: "batch without prerequisites", method: "POST", path: "/enrollments/batch", admin: true, setup: gated,
+			body: `{"enrollments":[{"user_id":1,"course_id":10}]}`, status: 200, contains: `"errors":["Course 12 (Anatomy) is not completed"]`},
+	})
+}
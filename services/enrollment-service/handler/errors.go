@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+
+	"github.com/gin-gonic/gin"
+
+	"mopcare/problem"
+)
+
+// writeError renders err as an RFC 7807 problem and aborts the chain.
+// Unexpected errors are logged here and replaced with an opaque 500 so
+// driver messages never leak to clients.
+func writeError(c *gin.Context, err error) {
+	p, unexpected := problem.From(err)
+	if unexpected {
+		log.Printf("%s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+	}
+	body, _ := json.Marshal(p.At(c.Request.URL.Path))
+	c.Abort()
+	c.Data(p.Status, problem.ContentType, body)
+}
+
+func routeNotFound(c *gin.Context) {
+	writeError(c, problem.NotFound(problem.CodeRouteNotFound, "Route not found"))
+}
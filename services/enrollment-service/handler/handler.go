@@ -0,0 +1,213 @@
+// Package handler exposes the enrollment-service over HTTP.
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"enrollment-service/repository"
+	"enrollment-service/service"
+	"mopcare/auth"
+	"mopcare/paging"
+	"mopcare/problem"
+	"mopcare/server"
+)
+
+type Handler struct {
+	enrollments *service.EnrollmentService
+	webhooks    *service.WebhookService
+	adminToken  string
+}
+
+// NewRouter builds the enrollment-service router, including /health and /ready.
+func NewRouter(enrollments *service.EnrollmentService, hooks *service.WebhookService, ready *server.Readiness, cfg server.Config) *gin.Engine {
+	router := gin.Default()
+	router.SetTrustedProxies([]string{"127.0.0.1"})
+	router.NoRoute(routeNotFound)
+
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"service": "enrollment-service", "status": "running"})
+	})
+	router.GET("/ready", func(c *gin.Context) {
+		if !ready.Ready() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"service": "enrollment-service", "status": "draining"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"service": "enrollment-service", "status": "ready"})
+	})
+
+	h := &Handler{enrollments: enrollments, webhooks: hooks, adminToken: cfg.AdminToken}
+	router.GET("/users/:id/enrollments", h.getUserEnrollments)
+	router.POST("/users/:id/enrollments", h.createUserEnrollment)
+	router.GET("/users/:id/courses/:courseId/eligibility", h.getEligibility)
+	router.POST("/enrollments/batch", h.batchEnroll)
+	router.POST("/enrollments/import", h.importEnrollments)
+	router.GET("/enrollments/export", h.exportEnrollments)
+	router.POST("/enrollments/:id/complete", h.completeEnrollment)
+	router.DELETE("/enrollments/:id", h.deleteUserEnrollment)
+	router.GET("/users/:id/certificates", h.getUserCertificates)
+	router.GET("/certificates/:code/verify", h.verifyCertificate)
+	router.GET("/certificates/:code/pdf", h.downloadCertificate)
+	router.GET("/courses/:id/roster", h.getRoster)
+	router.GET("/courses/:id/waitlist", h.getWaitlist)
+	router.GET("/cohorts", h.getCohorts)
+	router.POST("/cohorts", h.createCohort)
+	router.GET("/cohorts/:id", h.getCohort)
+	router.DELETE("/cohorts/:id", h.deleteCohort)
+	router.GET("/cohorts/:id/enrollments", h.getCohortEnrollments)
+	router.GET("/webhooks", h.getWebhooks)
+	router.POST("/webhooks", h.createWebhook)
+	router.GET("/webhooks/dead-letters", h.getDeadLetters)
+	router.GET("/webhooks/:id", h.getWebhook)
+	router.PUT("/webhooks/:id", h.updateWebhook)
+	router.DELETE("/webhooks/:id", h.deleteWebhook)
+	router.GET("/webhooks/:id/deliveries", h.getWebhookDeliveries)
+	router.POST("/webhooks/:id/deliveries/:deliveryId/redeliver", h.redeliverWebhook)
+	return router
+}
+
+// context returns the request context carrying the caller its bearer token
+// identifies.
+func (h *Handler) context(c *gin.Context) context.Context {
+	caller := auth.Identify(c.GetHeader("Authorization"), h.adminToken, time.Now())
+	return auth.WithCaller(c.Request.Context(), caller)
+}
+
+// paramID parses the :id path parameter, writing a 400 problem on failure.
+func paramID(c *gin.Context, detail string) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		writeError(c, problem.BadRequest(problem.CodeInvalidID, detail))
+		return 0, false
+	}
+	return id, true
+}
+
+func (h *Handler) getUserEnrollments(c *gin.Context) {
+	id, ok := paramID(c, "Invalid user ID")
+	if !ok {
+		return
+	}
+	page, err := paging.Parse(c.Query("limit"), c.Query("offset"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	enrollments, err := h.enrollments.ListForUser(c.Request.Context(), id, page)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, enrollments)
+}
+
+func (h *Handler) createUserEnrollment(c *gin.Context) {
+	id, ok := paramID(c, "Invalid user ID")
+	if !ok {
+		return
+	}
+	var enrollment repository.UserCourseEnrollment
+	if err := c.ShouldBindJSON(&enrollment); err != nil {
+		writeError(c, problem.BadRequest(problem.CodeInvalidBody, "Invalid request body"))
+		return
+	}
+	if err := h.enrollments.Enroll(c.Request.Context(), id, &enrollment); err != nil {
+		writeError(c, err)
+		return
+	}
+	// A waitlisted enrollment is accepted but holds no seat yet.
+	if enrollment.Status == "waitlisted" {
+		c.JSON(http.StatusAccepted, enrollment)
+		return
+	}
+	c.JSON(http.StatusCreated, enrollment)
+}
+
+// completeEnrollment marks the enrollment completed, for its user or an
+// admin, which needs every required quiz of the course to be passed.
+func (h *Handler) completeEnrollment(c *gin.Context) {
+	id, ok := paramID(c, "Invalid enrollment ID")
+	if !ok {
+		return
+	}
+	enrollment, err := h.enrollments.Complete(h.context(c), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+func (h *Handler) deleteUserEnrollment(c *gin.Context) {
+	id, ok := paramID(c, "Invalid enrollment ID")
+	if !ok {
+		return
+	}
+	if err := h.enrollments.Delete(c.Request.Context(), id); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Enrollment deleted successfully"})
+}
+
+func (h *Handler) getUserCertificates(c *gin.Context) {
+	id, ok := paramID(c, "Invalid user ID")
+	if !ok {
+		return
+	}
+	page, err := paging.Parse(c.Query("limit"), c.Query("offset"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	certificates, err := h.enrollments.ListCertificates(h.context(c), id, page)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, certificates)
+}
+
+func (h *Handler) getEligibility(c *gin.Context) {
+	id, ok := paramID(c, "Invalid user ID")
+	if !ok {
+		return
+	}
+	courseID, err := strconv.Atoi(c.Param("courseId"))
+	if err != nil {
+		writeError(c, problem.BadRequest(problem.CodeInvalidID, "Invalid course ID"))
+		return
+	}
+	eligibility, err := h.enrollments.Eligibility(h.context(c), id, courseID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, eligibility)
+}
+
+// verifyCertificate is public: the code is all anyone needs to check a
+// certificate.
+func (h *Handler) verifyCertificate(c *gin.Context) {
+	verification, err := h.enrollments.Verify(c.Request.Context(), c.Param("code"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, verification)
+}
+
+func (h *Handler) downloadCertificate(c *gin.Context) {
+	certificate, body, err := h.enrollments.CertificatePDF(h.context(c), c.Param("code"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="certificate-%s.pdf"`, certificate.Code))
+	c.Data(http.StatusOK, "application/pdf", body)
+}
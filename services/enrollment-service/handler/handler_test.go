@@ -0,0 +1,210 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"enrollment-service/repository"
+	"enrollment-service/service"
+	"mopcare/auth"
+	"mopcare/problem"
+	"mopcare/server"
+	"mopcare/webhooks"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	os.Exit(m.Run())
+}
+
+type testCase struct {
+	name     string
+	method   string
+	path     string
+	body     string
+	setup    func(*repository.Memory)
+	hooks    func(*webhooks.Memory)
+	status   int
+	code     string // expected problem code; empty for success responses
+	contains string // substring expected in a success body
+	admin    bool   // send the admin token
+	user     int    // send a token for this user
+}
+
+const adminToken = "admin-secret"
+
+// newRouter serves repo and hooks the way main does.
+func newRouter(repo *repository.Memory, hooks *webhooks.Memory) *gin.Engine {
+	return NewRouter(service.NewEnrollmentService(repo, repo, repo), service.NewWebhookService(hooks), &server.Readiness{}, server.Config{AdminToken: adminToken})
+}
+
+// seeded registers user 1 and course 10, with no enrollments.
+func seeded(m *repository.Memory) {
+	m.AddUser(1)
+	m.AddCourse(10)
+}
+
+func enrolled(m *repository.Memory) {
+	seeded(m)
+	m.Create(context.Background(), &repository.UserCourseEnrollment{UserID: 1, CourseID: 10, Status: "enrolled"})
+}
+
+// withQuiz is enrolled, with a required quiz 5 on course 10.
+func withQuiz(m *repository.Memory) {
+	enrolled(m)
+	m.AddQuiz(10, 5)
+}
+
+func failWith(err error) func(*repository.Memory) {
+	return func(m *repository.Memory) {
+		seeded(m)
+		m.Err = err
+	}
+}
+
+func runCases(t *testing.T, cases []testCase) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := repository.NewMemory()
+			if tc.setup != nil {
+				tc.setup(repo)
+			}
+			hooks := webhooks.NewMemory()
+			if tc.hooks != nil {
+				tc.hooks(hooks)
+			}
+			router := newRouter(repo, hooks)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.admin {
+				req.Header.Set("Authorization", "Bearer "+adminToken)
+			}
+			if tc.user != 0 {
+				req.Header.Set("Authorization", "Bearer "+auth.UserToken(tc.user, time.Now().Add(time.Hour), adminToken))
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tc.status, rec.Body)
+			}
+			if tc.code != "" {
+				if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+					t.Errorf("Content-Type = %q, want %q", ct, problem.ContentType)
+				}
+				var p problem.Problem
+				if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+					t.Fatalf("decoding problem: %v", err)
+				}
+				if p.Code != tc.code {
+					t.Errorf("code = %q, want %q", p.Code, tc.code)
+				}
+			}
+			if tc.contains != "" && !strings.Contains(rec.Body.String(), tc.contains) {
+				t.Errorf("body %s does not contain %q", rec.Body, tc.contains)
+			}
+		})
+	}
+}
+
+var errDB = errors.New("connection reset")
+
+func TestGetUserEnrollments(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "lists enrollments", method: "GET", path: "/users/1/enrollments", setup: enrolled, status: 200, contains: `"course_id":10`},
+		{name: "invalid id", method: "GET", path: "/users/x/enrollments", status: 400, code: problem.CodeInvalidID},
+		{name: "none", method: "GET", path: "/users/1/enrollments", setup: seeded, status: 404, code: problem.CodeEnrollmentNotFound},
+		{name: "course deleted", method: "GET", path: "/users/1/enrollments", setup: func(m *repository.Memory) {
+			enrolled(m)
+			m.DeleteCourse(10)
+		}, status: 404, code: problem.CodeEnrollmentNotFound},
+		{name: "past the last page", method: "GET", path: "/users/1/enrollments?limit=10&offset=1", setup: enrolled, status: 200, contains: `[]`},
+		{name: "invalid offset", method: "GET", path: "/users/1/enrollments?offset=-1", status: 400, code: problem.CodeInvalidQuery},
+		{name: "repository error", method: "GET", path: "/users/1/enrollments", setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
+
+func TestCreateUserEnrollment(t *testing.T) {
+	const valid = `{"user_id":1,"course_id":10,"status":"enrolled"}`
+	runCases(t, []testCase{
+		{name: "created", method: "POST", path: "/users/1/enrollments", body: valid, setup: seeded, status: 201, contains: `"id":1`},
+		{name: "invalid id", method: "POST", path: "/users/x/enrollments", body: valid, status: 400, code: problem.CodeInvalidID},
+		{name: "malformed body", method: "POST", path: "/users/1/enrollments", body: `{`, status: 400, code: problem.CodeInvalidBody},
+		{name: "missing fields", method: "POST", path: "/users/1/enrollments", body: `{"user_id":1}`, status: 400, code: problem.CodeValidationFailed},
+		{name: "user mismatch", method: "POST", path: "/users/2/enrollments", body: valid, status: 400, code: problem.CodeValidationFailed},
+		{name: "bad status", method: "POST", path: "/users/1/enrollments", body: `{"user_id":1,"course_id":10,"status":"paused"}`, status: 400, code: problem.CodeValidationFailed},
+		{name: "unknown user", method: "POST", path: "/users/1/enrollments", body: valid, setup: func(m *repository.Memory) { m.AddCourse(10) }, status: 422, code: problem.CodeUserNotFound},
+		{name: "unknown course", method: "POST", path: "/users/1/enrollments", body: valid, setup: func(m *repository.Memory) { m.AddUser(1) }, status: 422, code: problem.CodeCourseNotFound},
+		{name: "deleted user", method: "POST", path: "/users/1/enrollments", body: valid, setup: func(m *repository.Memory) {
+			seeded(m)
+			m.DeleteUser(1)
+		}, status: 422, code: problem.CodeUserNotFound},
+		{name: "duplicate", method: "POST", path: "/users/1/enrollments", body: valid, setup: enrolled, status: 409, code: problem.CodeEnrollmentDuplicate},
+		{name: "completed with quizzes to pass", method: "POST", path: "/users/1/enrollments", body: `{"user_id":1,"course_id":10,"status":"completed"}`, setup: func(m *repository.Memory) {
+			seeded(m)
+			m.AddQuiz(10, 5)
+		}, status: 409, code: problem.CodeQuizzesNotPassed},
+		{name: "completed with quizzes passed", method: "POST", path: "/users/1/enrollments", body: `{"user_id":1,"course_id":10,"status":"completed"}`, setup: func(m *repository.Memory) {
+			seeded(m)
+			m.AddQuiz(10, 5)
+			m.PassQuiz(1, 5)
+		}, status: 201, contains: `"status":"completed"`},
+		{name: "repository error", method: "POST", path: "/users/1/enrollments", body: valid, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
+
+func TestCompleteEnrollment(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "no quizzes", method: "POST", path: "/enrollments/1/complete", user: 1, setup: enrolled, status: 200, contains: `"status":"completed"`},
+		{name: "quiz not passed", method: "POST", path: "/enrollments/1/complete", user: 1, setup: withQuiz, status: 409, code: problem.CodeQuizzesNotPassed},
+		{name: "quiz passed by someone else", method: "POST", path: "/enrollments/1/complete", user: 1, setup: func(m *repository.Memory) {
+			withQuiz(m)
+			m.PassQuiz(2, 5)
+		}, status: 409, code: problem.CodeQuizzesNotPassed},
+		{name: "quiz passed", method: "POST", path: "/enrollments/1/complete", user: 1, setup: func(m *repository.Memory) {
+			withQuiz(m)
+			m.PassQuiz(1, 5)
+		}, status: 200, contains: `"status":"completed"`},
+		{name: "quiz on another course", method: "POST", path: "/enrollments/1/complete", user: 1, setup: func(m *repository.Memory) {
+			enrolled(m)
+			m.AddQuiz(11, 5)
+		}, status: 200, contains: `"status":"completed"`},
+		{name: "already completed", method: "POST", path: "/enrollments/1/complete", user: 1, setup: func(m *repository.Memory) {
+			withQuiz(m)
+			m.Complete(context.Background(), 1)
+		}, status: 200, contains: `"status":"completed"`},
+		{name: "by an admin", method: "POST", path: "/enrollments/1/complete", admin: true, setup: enrolled, status: 200, contains: `"status":"completed"`},
+		{name: "by another user", method: "POST", path: "/enrollments/1/complete", user: 2, setup: enrolled, status: 403, code: problem.CodeForbidden},
+		{name: "anonymous", method: "POST", path: "/enrollments/1/complete", setup: enrolled, status: 401, code: problem.CodeUnauthorized},
+		{name: "invalid id", method: "POST", path: "/enrollments/x/complete", status: 400, code: problem.CodeInvalidID},
+		{name: "missing", method: "POST", path: "/enrollments/9/complete", user: 1, status: 404, code: problem.CodeEnrollmentNotFound},
+		{name: "repository error", method: "POST", path: "/enrollments/1/complete", user: 1, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
+
+func TestDeleteUserEnrollment(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "deleted", method: "DELETE", path: "/enrollments/1", setup: enrolled, status: 200, contains: "deleted"},
+		{name: "invalid id", method: "DELETE", path: "/enrollments/x", status: 400, code: problem.CodeInvalidID},
+		{name: "missing", method: "DELETE", path: "/enrollments/9", status: 404, code: problem.CodeEnrollmentNotFound},
+		{name: "repository error", method: "DELETE", path: "/enrollments/1", setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
+
+func TestUnknownRoute(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "unknown route", method: "GET", path: "/nope", status: 404, code: problem.CodeRouteNotFound},
+	})
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"testing"
+
+	"enrollment-service/repository"
+	"enrollment-service/service"
+	"mopcare/bulk"
+	"mopcare/openapi"
+	"mopcare/webhooks"
+)
+
+// TestRoutesAreDocumented keeps the OpenAPI document in step with the router.
+func TestRoutesAreDocumented(t *testing.T) {
+	spec := openapi.MustLoad()
+	router := newRouter(repository.NewMemory(), webhooks.NewMemory())
+	for _, route := range router.Routes() {
+		path := openapi.PathTemplate(route.Path)
+		if _, ok := spec.Operation(route.Method, path); !ok {
+			t.Errorf("%s %s is not in the OpenAPI document", route.Method, path)
+		}
+	}
+}
+
+func TestSchemasMatchTypes(t *testing.T) {
+	spec := openapi.MustLoad()
+	for name, v := range map[string]interface{}{
+		"UserCourseEnrollment":    repository.UserCourseEnrollment{},
+		"WaitlistEntry":           repository.WaitlistEntry{},
+		"Certificate":             repository.Certificate{},
+		"CertificateVerification": service.Verification{},
+		"Webhook":                 webhooks.Webhook{},
+		"WebhookInput":            service.WebhookInput{},
+		"WebhookDelivery":         webhooks.Delivery{},
+		"WebhookAttempt":          webhooks.Attempt{},
+		"ImportReport":            bulk.Report{},
+		"BatchInput":              service.BatchInput{},
+		"BatchItem":               service.BatchItem{},
+		"BatchResult":             service.BatchResult{},
+		"ItemResult":              service.ItemResult{},
+		"Cohort":                  repository.Cohort{},
+		"CohortInput":             service.CohortInput{},
+		"Eligibility":             service.Eligibility{},
+		"MissingPrerequisite":     repository.Prerequisite{},
+	} {
+		onlySchema, onlyStruct := spec.SchemaFieldDiff(name, v)
+		if len(onlySchema) > 0 || len(onlyStruct) > 0 {
+			t.Errorf("%s: only in document %v, only in Go type %v", name, onlySchema, onlyStruct)
+		}
+	}
+}
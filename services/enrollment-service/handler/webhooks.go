@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"enrollment-service/service"
+	"mopcare/paging"
+	"mopcare/problem"
+)
+
+func (h *Handler) getWebhooks(c *gin.Context) {
+	page, err := paging.Parse(c.Query("limit"), c.Query("offset"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	hooks, err := h.webhooks.List(h.context(c), page)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, hooks)
+}
+
+func (h *Handler) createWebhook(c *gin.Context) {
+	var in service.WebhookInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, problem.BadRequest(problem.CodeInvalidBody, "Invalid request body"))
+		return
+	}
+	w, err := h.webhooks.Create(h.context(c), in)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.Header("Location", "/webhooks/"+strconv.Itoa(w.ID))
+	c.JSON(http.StatusCreated, w)
+}
+
+func (h *Handler) getWebhook(c *gin.Context) {
+	id, ok := paramID(c, "Invalid webhook ID")
+	if !ok {
+		return
+	}
+	w, err := h.webhooks.Get(h.context(c), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, w)
+}
+
+func (h *Handler) updateWebhook(c *gin.Context) {
+	id, ok := paramID(c, "Invalid webhook ID")
+	if !ok {
+		return
+	}
+	var in service.WebhookInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, problem.BadRequest(problem.CodeInvalidBody, "Invalid request body"))
+		return
+	}
+	w, err := h.webhooks.Update(h.context(c), id, in)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, w)
+}
+
+func (h *Handler) deleteWebhook(c *gin.Context) {
+	id, ok := paramID(c, "Invalid webhook ID")
+	if !ok {
+		return
+	}
+	if err := h.webhooks.Delete(h.context(c), id); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted successfully"})
+}
+
+// getWebhookDeliveries is the delivery log of one webhook, with every
+// attempt at each delivery.
+func (h *Handler) getWebhookDeliveries(c *gin.Context) {
+	id, ok := paramID(c, "Invalid webhook ID")
+	if !ok {
+		return
+	}
+	page, err := paging.Parse(c.Query("limit"), c.Query("offset"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	deliveries, err := h.webhooks.Deliveries(h.context(c), id, c.Query("status"), page)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+func (h *Handler) getDeadLetters(c *gin.Context) {
+	page, err := paging.Parse(c.Query("limit"), c.Query("offset"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	deliveries, err := h.webhooks.DeadLetters(h.context(c), page)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// redeliverWebhook answers 202: the delivery is queued, not yet sent.
+func (h *Handler) redeliverWebhook(c *gin.Context) {
+	id, ok := paramID(c, "Invalid webhook ID")
+	if !ok {
+		return
+	}
+	deliveryID, err := strconv.ParseInt(c.Param("deliveryId"), 10, 64)
+	if err != nil {
+		writeError(c, problem.BadRequest(problem.CodeInvalidID, "Invalid delivery ID"))
+		return
+	}
+	d, err := h.webhooks.Redeliver(h.context(c), id, deliveryID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, d)
+}
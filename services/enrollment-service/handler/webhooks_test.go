@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"mopcare/events"
+	"mopcare/problem"
+	"mopcare/webhooks"
+)
+
+// subscribed has webhook 1 subscribed to completions, with delivery 2 of
+// event 7 dead after one failed attempt.
+func subscribed(m *webhooks.Memory) {
+	ctx := context.Background()
+	m.CreateWebhook(ctx, &webhooks.Webhook{URL: "https://clinic.example/hooks", EventTypes: []string{events.TypeEnrollmentCompleted}, Secret: "0123456789abcdef", Active: true})
+	m.Enqueue(ctx, events.Event{ID: 7, Source: "enrollment-service", Type: events.TypeEnrollmentCompleted}, []byte(`{"id":7}`))
+	m.Record(ctx, 2, webhooks.Attempt{At: time.Now(), StatusCode: 500}, webhooks.StatusDead, nil)
+}
+
+func hooksFailWith(err error) func(*webhooks.Memory) {
+	return func(m *webhooks.Memory) {
+		subscribed(m)
+		m.Err = err
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	const valid = `{"url":"https://clinic.example/hooks","event_types":["enrollment.created","payment.recorded"]}`
+	runCases(t, []testCase{
+		{name: "created", method: "POST", path: "/webhooks", body: valid, admin: true, status: 201, contains: `"secret":"whsec_`},
+		{name: "created inactive", method: "POST", path: "/webhooks", body: `{"url":"http://clinic.example","event_types":["enrollment.completed"],"active":false}`, admin: true, status: 201, contains: `"active":false`},
+		{name: "create as a user", method: "POST", path: "/webhooks", body: valid, user: 1, status: 403, code: problem.CodeForbidden},
+		{name: "create anonymously", method: "POST", path: "/webhooks", body: valid, status: 403, code: problem.CodeForbidden},
+		{name: "malformed body", method: "POST", path: "/webhooks", body: `{`, admin: true, status: 400, code: problem.CodeInvalidBody},
+		{name: "relative url", method: "POST", path: "/webhooks", body: `{"url":"/hooks","event_types":["enrollment.created"]}`, admin: true, status: 400, code: problem.CodeValidationFailed},
+		{name: "ftp url", method: "POST", path: "/webhooks", body: `{"url":"ftp://clinic.example","event_types":["enrollment.created"]}`, admin: true, status: 400, code: problem.CodeValidationFailed},
+		{name: "no event types", method: "POST", path: "/webhooks", body: `{"url":"https://clinic.example"}`, admin: true, status: 400, code: problem.CodeValidationFailed},
+		{name: "unknown event type", method: "POST", path: "/webhooks", body: `{"url":"https://clinic.example","event_types":["course.deleted"]}`, admin: true, status: 400, code: problem.CodeValidationFailed},
+		{name: "short secret", method: "POST", path: "/webhooks", body: `{"url":"https://clinic.example","event_types":["enrollment.created"],"secret":"short"}`, admin: true, status: 400, code: problem.CodeValidationFailed},
+
+		{name: "listed", method: "GET", path: "/webhooks", hooks: subscribed, admin: true, status: 200, contains: `"url":"https://clinic.example/hooks"`},
+		{name: "list empty", method: "GET", path: "/webhooks", admin: true, status: 200, contains: `[]`},
+		{name: "list as a user", method: "GET", path: "/webhooks", hooks: subscribed, user: 1, status: 403, code: problem.CodeForbidden},
+		{name: "list invalid limit", method: "GET", path: "/webhooks?limit=x", admin: true, status: 400, code: problem.CodeInvalidQuery},
+		{name: "got", method: "GET", path: "/webhooks/1", hooks: subscribed, admin: true, status: 200, contains: `"event_types":["enrollment.completed"]`},
+		{name: "get missing", method: "GET", path: "/webhooks/9", admin: true, status: 404, code: problem.CodeWebhookNotFound},
+		{name: "get invalid id", method: "GET", path: "/webhooks/x", admin: true, status: 400, code: problem.CodeInvalidID},
+
+		{name: "updated keeping the secret", method: "PUT", path: "/webhooks/1", body: `{"url":"https://clinic.example/v2","event_types":["payment.recorded"],"active":false}`, hooks: subscribed, admin: true, status: 200, contains: `"secret":"0123456789abcdef"`},
+		{name: "update missing", method: "PUT", path: "/webhooks/9", body: valid, admin: true, status: 404, code: problem.CodeWebhookNotFound},
+		{name: "update invalid", method: "PUT", path: "/webhooks/1", body: `{"url":"https://clinic.example","event_types":[]}`, hooks: subscribed, admin: true, status: 400, code: problem.CodeValidationFailed},
+		{name: "update as a user", method: "PUT", path: "/webhooks/1", body: valid, hooks: subscribed, user: 1, status: 403, code: problem.CodeForbidden},
+
+		{name: "deleted", method: "DELETE", path: "/webhooks/1", hooks: subscribed, admin: true, status: 200, contains: "deleted"},
+		{name: "delete missing", method: "DELETE", path: "/webhooks/9", admin: true, status: 404, code: problem.CodeWebhookNotFound},
+		{name: "delete as a user", method: "DELETE", path: "/webhooks/1", hooks: subscribed, user: 1, status: 403, code: problem.CodeForbidden},
+
+		{name: "store error", method: "GET", path: "/webhooks", hooks: hooksFailWith(errDB), admin: true, status: 500, code: problem.CodeInternal},
+	})
+}
+
+func TestWebhookDeliveries(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "log", method: "GET", path: "/webhooks/1/deliveries", hooks: subscribed, admin: true, status: 200, contains: `"status_code":500`},
+		{name: "by status", method: "GET", path: "/webhooks/1/deliveries?status=pending", hooks: subscribed, admin: true, status: 200, contains: `[]`},
+		{name: "unknown status", method: "GET", path: "/webhooks/1/deliveries?status=lost", hooks: subscribed, admin: true, status: 400, code: problem.CodeInvalidQuery},
+		{name: "of a missing webhook", method: "GET", path: "/webhooks/9/deliveries", admin: true, status: 404, code: problem.CodeWebhookNotFound},
+		{name: "log as a user", method: "GET", path: "/webhooks/1/deliveries", hooks: subscribed, user: 1, status: 403, code: problem.CodeForbidden},
+		{name: "dead letters", method: "GET", path: "/webhooks/dead-letters", hooks: subscribed, admin: true, status: 200, contains: `"status":"dead"`},
+		{name: "dead letters as a user", method: "GET", path: "/webhooks/dead-letters", user: 1, status: 403, code: problem.CodeForbidden},
+
+		{name: "redelivered", method: "POST", path: "/webhooks/1/deliveries/2/redeliver", hooks: subscribed, admin: true, status: 202, contains: `"status":"pending"`},
+		{name: "redeliver another webhook's delivery", method: "POST", path: "/webhooks/3/deliveries/2/redeliver", hooks: func(m *webhooks.Memory) {
+			subscribed(m)
+			m.CreateWebhook(context.Background(), &webhooks.Webhook{URL: "https://other.example", EventTypes: webhooks.EventTypes, Active: true})
+		}, admin: true, status: 404, code: problem.CodeDeliveryNotFound},
+		{name: "redeliver missing", method: "POST", path: "/webhooks/1/deliveries/9/redeliver", hooks: subscribed, admin: true, status: 404, code: problem.CodeDeliveryNotFound},
+		{name: "redeliver invalid id", method: "POST", path: "/webhooks/1/deliveries/x/redeliver", admin: true, status: 400, code: problem.CodeInvalidID},
+		{name: "redeliver as a user", method: "POST", path: "/webhooks/1/deliveries/2/redeliver", hooks: subscribed, user: 1, status: 403, code: problem.CodeForbidden},
+	})
+}
+
+// TestRedeliverResetsAttempts checks what a redelivered dead letter looks
+// like to the deliverer.
+func TestRedeliverResetsAttempts(t *testing.T) {
+	hooks := webhooks.NewMemory()
+	subscribed(hooks)
+	req := httptest.NewRequest("POST", "/webhooks/1/deliveries/2/redeliver", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	rec := httptest.NewRecorder()
+	newRouter(nil, hooks).ServeHTTP(rec, req)
+
+	var d webhooks.Delivery
+	if err := json.Unmarshal(rec.Body.Bytes(), &d); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body, err)
+	}
+	if d.Status != webhooks.StatusPending || d.Attempts != 0 || d.NextAttemptAt == nil || len(d.Log) != 1 || !strings.Contains(d.Payload, `"id":7`) {
+		t.Errorf("redelivered = %+v", d)
+	}
+}
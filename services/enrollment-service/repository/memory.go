@@ -0,0 +1,646 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"mopcare/events"
+	"mopcare/paging"
+	"mopcare/problem"
+)
+
+// Memory is an in-memory EnrollmentRepository, CertificateRepository and
+// CohortRepository for tests. Users, courses and quizzes belong to other services, so tests
+// register the IDs that should exist and can soft-delete them again; users
+// are named "User <id>" and courses titled "Course <id>" unless a test says
+// otherwise.
+// Setting Err makes every method fail with it, to exercise error paths.
+type Memory struct {
+	mu          sync.Mutex
+	nextID      int
+	users       map[int]bool // ID -> live
+	courses     map[int]bool // ID -> live and published
+	enrollments map[int]UserCourseEnrollment
+	quizzes     map[int]int     // required quiz ID -> course ID
+	passed      map[[2]int]bool // {user ID, quiz ID}
+	names       map[int]string  // user ID -> full name
+	emails      map[string]int  // email -> user ID
+	titles      map[int]string  // course ID -> title
+	// prerequisites maps course IDs to the IDs of their prerequisites.
+	prerequisites map[int][]int
+	limits        map[int]Limits // course ID -> limits, if any
+	// certificates are keyed by enrollment ID.
+	certificates map[int]Certificate
+	cohorts      map[int]Cohort
+	events       []events.Payload
+
+	Err error
+}
+
+func NewMemory() *Memory {
+	return &Memory{nextID: 1, users: map[int]bool{}, courses: map[int]bool{}, enrollments: map[int]UserCourseEnrollment{},
+		quizzes: map[int]int{}, passed: map[[2]int]bool{}, names: map[int]string{}, emails: map[string]int{}, titles: map[int]string{},
+		certificates: map[int]Certificate{}, cohorts: map[int]Cohort{}, prerequisites: map[int][]int{}, limits: map[int]Limits{}}
+}
+
+// Events returns the events recorded so far, as Postgres would have written
+// them to the outbox.
+func (m *Memory) Events() []events.Payload {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]events.Payload(nil), m.events...)
+}
+
+func (m *Memory) AddUser(id int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users[id] = true
+}
+
+func (m *Memory) AddCourse(id int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.courses[id] = true
+}
+
+// NameUser sets the full name certificates give the user.
+func (m *Memory) NameUser(id int, name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.names[id] = name
+}
+
+// SetUserEmail gives the user an email imports can find them by.
+func (m *Memory) SetUserEmail(id int, email string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.emails[email] = id
+}
+
+// TitleCourse sets the title certificates give the course.
+func (m *Memory) TitleCourse(id int, title string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.titles[id] = title
+}
+
+// AddQuiz registers a required quiz on the course.
+func (m *Memory) AddQuiz(courseID, quizID int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.quizzes[quizID] = courseID
+}
+
+// AddPrerequisite makes one course a prerequisite of another.
+func (m *Memory) AddPrerequisite(courseID, prerequisiteID int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prerequisites[courseID] = append(m.prerequisites[courseID], prerequisiteID)
+}
+
+// LimitCourse sets the course's capacity and enrollment window.
+func (m *Memory) LimitCourse(id int, l Limits) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.limits[id] = l
+}
+
+// PassQuiz records that the user passed the quiz.
+func (m *Memory) PassQuiz(userID, quizID int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.passed[[2]int{userID, quizID}] = true
+}
+
+// DeleteUser soft-deletes a registered user, hiding their enrollments.
+func (m *Memory) DeleteUser(id int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.users[id] = false
+}
+
+// DeleteCourse soft-deletes or unpublishes a registered course, hiding its
+// enrollments.
+func (m *Memory) DeleteCourse(id int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.courses[id] = false
+}
+
+func (m *Memory) ListByUser(ctx context.Context, userID int, page paging.Page) ([]UserCourseEnrollment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var enrollments []UserCourseEnrollment
+	for _, e := range m.enrollments {
+		if e.UserID == userID && m.users[e.UserID] && m.courses[e.CourseID] {
+			enrollments = append(enrollments, e)
+		}
+	}
+	sort.Slice(enrollments, func(i, j int) bool { return enrollments[i].ID < enrollments[j].ID })
+	return paging.Slice(enrollments, page), nil
+}
+
+func (m *Memory) UserExists(ctx context.Context, userID int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.users[userID], m.Err
+}
+
+func (m *Memory) CourseExists(ctx context.Context, courseID int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.courses[courseID], m.Err
+}
+
+func (m *Memory) CourseLimits(ctx context.Context, courseID int) (Limits, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return Limits{}, m.Err
+	}
+	if _, ok := m.courses[courseID]; !ok {
+		return Limits{}, ErrNotFound
+	}
+	return m.limits[courseID], nil
+}
+
+func (m *Memory) Exists(ctx context.Context, userID, courseID int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.enrolled(userID, courseID), m.Err
+}
+
+func (m *Memory) enrolled(userID, courseID int) bool {
+	for _, e := range m.enrollments {
+		if e.UserID == userID && e.CourseID == courseID {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Memory) UserIDByEmail(ctx context.Context, email string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	id, ok := m.emails[email]
+	if !ok || !m.users[id] {
+		return 0, ErrNotFound
+	}
+	return id, nil
+}
+
+func (m *Memory) Create(ctx context.Context, e *UserCourseEnrollment) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	if err := m.check(e); err != nil {
+		return err
+	}
+	m.create(e)
+	return nil
+}
+
+// check mirrors the foreign keys and UNIQUE(user_id, course_id).
+func (m *Memory) check(e *UserCourseEnrollment) error {
+	if !m.users[e.UserID] {
+		return problem.Unprocessable(problem.CodeUserNotFound, "User does not exist")
+	}
+	if !m.courses[e.CourseID] {
+		return problem.Unprocessable(problem.CodeCourseNotFound, "Course does not exist")
+	}
+	if m.enrolled(e.UserID, e.CourseID) {
+		return problem.Conflict(problem.CodeEnrollmentDuplicate, "User is already enrolled in this course")
+	}
+	if e.CohortID != nil {
+		if _, ok := m.cohorts[*e.CohortID]; !ok {
+			return problem.Unprocessable(problem.CodeCohortNotFound, "A referenced resource does not exist")
+		}
+	}
+	return nil
+}
+
+func (m *Memory) create(e *UserCourseEnrollment) {
+	e.ID = m.nextID
+	m.nextID++
+	m.enrollments[e.ID] = *e
+	if e.Status == "waitlisted" {
+		// Announced when promoted.
+		return
+	}
+	m.events = append(m.events, events.EnrollmentCreated{EnrollmentID: e.ID, UserID: e.UserID, CourseID: e.CourseID, Status: e.Status})
+	if e.Status == "completed" {
+		m.events = append(m.events, events.EnrollmentCompleted{EnrollmentID: e.ID, UserID: e.UserID, CourseID: e.CourseID})
+	}
+}
+
+func (m *Memory) CreateBatch(ctx context.Context, enrollments []*UserCourseEnrollment, check func(BatchFacts) []bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	facts := BatchFacts{Users: map[int]bool{}, Courses: map[int]bool{}, Enrolled: map[[2]int]bool{}, PendingQuizzes: map[[2]int]int{},
+		MissingPrerequisites: map[[2]int][]Prerequisite{}, Now: time.Now(), Limits: map[int]Limits{}, Seats: map[int]int{}}
+	for _, e := range enrollments {
+		if _, seen := facts.Limits[e.CourseID]; seen || !m.courses[e.CourseID] {
+			continue
+		}
+		m.promote(e.CourseID)
+		l := m.limits[e.CourseID]
+		facts.Limits[e.CourseID] = l
+		if l.Capacity != nil {
+			facts.Seats[e.CourseID] = max(*l.Capacity-m.seated(e.CourseID), 0)
+		}
+	}
+	for _, e := range enrollments {
+		pair := [2]int{e.UserID, e.CourseID}
+		facts.Users[e.UserID] = m.users[e.UserID]
+		facts.Courses[e.CourseID] = m.courses[e.CourseID]
+		facts.Enrolled[pair] = m.enrolled(e.UserID, e.CourseID)
+		if e.Status == "completed" {
+			facts.PendingQuizzes[pair] = m.pending(e.UserID, e.CourseID)
+		}
+		facts.MissingPrerequisites[pair] = m.missing(e.UserID, e.CourseID)
+	}
+	accept := check(facts)
+	var created []*UserCourseEnrollment
+	for i, e := range enrollments {
+		if !accept[i] {
+			continue
+		}
+		if err := m.check(e); err != nil {
+			return err
+		}
+		created = append(created, e)
+	}
+	for _, e := range created {
+		m.create(e)
+	}
+	return nil
+}
+
+// EachEnrollment copies the enrollments first, so fn may call back into m.
+func (m *Memory) EachEnrollment(ctx context.Context, courseID int, fn func(UserCourseEnrollment) error) error {
+	m.mu.Lock()
+	if m.Err != nil {
+		m.mu.Unlock()
+		return m.Err
+	}
+	var enrollments []UserCourseEnrollment
+	for _, e := range m.enrollments {
+		if courseID == 0 || e.CourseID == courseID {
+			enrollments = append(enrollments, e)
+		}
+	}
+	m.mu.Unlock()
+	sort.Slice(enrollments, func(i, j int) bool { return enrollments[i].ID < enrollments[j].ID })
+	for _, e := range enrollments {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Delete(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	e, ok := m.enrollments[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(m.enrollments, id)
+	delete(m.certificates, id)
+	if m.courses[e.CourseID] {
+		m.promote(e.CourseID)
+	}
+	return nil
+}
+
+// seated counts the enrollments holding a seat in the course.
+func (m *Memory) seated(courseID int) int {
+	n := 0
+	for _, e := range m.enrollments {
+		if e.CourseID == courseID && e.Status != "waitlisted" {
+			n++
+		}
+	}
+	return n
+}
+
+// waitlist returns the course's waitlisted enrollments of live users, oldest
+// first.
+func (m *Memory) waitlist(courseID int) []UserCourseEnrollment {
+	var waiting []UserCourseEnrollment
+	for _, e := range m.enrollments {
+		if e.CourseID == courseID && e.Status == "waitlisted" && m.users[e.UserID] {
+			waiting = append(waiting, e)
+		}
+	}
+	sort.Slice(waiting, func(i, j int) bool { return waiting[i].ID < waiting[j].ID })
+	return waiting
+}
+
+// promote mirrors Postgres's promote for one course.
+func (m *Memory) promote(courseID int) {
+	waiting := m.waitlist(courseID)
+	if c := m.limits[courseID].Capacity; c != nil {
+		waiting = waiting[:min(max(*c-m.seated(courseID), 0), len(waiting))]
+	}
+	for _, e := range waiting {
+		e.Status = "enrolled"
+		m.enrollments[e.ID] = e
+		m.events = append(m.events, events.EnrollmentCreated{EnrollmentID: e.ID, UserID: e.UserID, CourseID: e.CourseID, Status: e.Status})
+	}
+}
+
+func (m *Memory) ListRoster(ctx context.Context, courseID int, page paging.Page) ([]UserCourseEnrollment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var enrollments []UserCourseEnrollment
+	for _, e := range m.enrollments {
+		if e.CourseID == courseID && e.Status != "waitlisted" && m.users[e.UserID] {
+			enrollments = append(enrollments, e)
+		}
+	}
+	sort.Slice(enrollments, func(i, j int) bool { return enrollments[i].ID < enrollments[j].ID })
+	return paging.Slice(enrollments, page), nil
+}
+
+func (m *Memory) ListWaitlist(ctx context.Context, courseID int, page paging.Page) ([]WaitlistEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var entries []WaitlistEntry
+	for i, e := range m.waitlist(courseID) {
+		entries = append(entries, WaitlistEntry{EnrollmentID: e.ID, UserID: e.UserID, CourseID: e.CourseID, CohortID: e.CohortID, Position: i + 1})
+	}
+	return paging.Slice(entries, page), nil
+}
+
+func (m *Memory) Get(ctx context.Context, id int) (UserCourseEnrollment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return UserCourseEnrollment{}, m.Err
+	}
+	e, ok := m.enrollments[id]
+	if !ok {
+		return UserCourseEnrollment{}, ErrNotFound
+	}
+	return e, nil
+}
+
+func (m *Memory) Complete(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	e, ok := m.enrollments[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if e.Status != "completed" {
+		m.events = append(m.events, events.EnrollmentCompleted{EnrollmentID: id, UserID: e.UserID, CourseID: e.CourseID})
+	}
+	e.Status = "completed"
+	m.enrollments[id] = e
+	return nil
+}
+
+func (m *Memory) PendingQuizzes(ctx context.Context, userID, courseID int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	return m.pending(userID, courseID), nil
+}
+
+func (m *Memory) pending(userID, courseID int) int {
+	pending := 0
+	for quizID, course := range m.quizzes {
+		if course == courseID && !m.passed[[2]int{userID, quizID}] {
+			pending++
+		}
+	}
+	return pending
+}
+
+func (m *Memory) MissingPrerequisites(ctx context.Context, userID, courseID int) ([]Prerequisite, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	return m.missing(userID, courseID), nil
+}
+
+func (m *Memory) missing(userID, courseID int) []Prerequisite {
+	var missing []Prerequisite
+	for _, id := range m.prerequisites[courseID] {
+		if !m.courses[id] || m.completed(userID, id) {
+			continue
+		}
+		title := m.titles[id]
+		if title == "" {
+			title = fmt.Sprintf("Course %d", id)
+		}
+		missing = append(missing, Prerequisite{CourseID: id, Title: title})
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i].CourseID < missing[j].CourseID })
+	return missing
+}
+
+func (m *Memory) completed(userID, courseID int) bool {
+	for _, e := range m.enrollments {
+		if e.UserID == userID && e.CourseID == courseID && e.Status == "completed" {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Memory) IssueCertificate(ctx context.Context, enrollmentID int, code string) (Certificate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return Certificate{}, m.Err
+	}
+	if c, ok := m.certificates[enrollmentID]; ok {
+		return c, nil
+	}
+	e, ok := m.enrollments[enrollmentID]
+	if !ok {
+		return Certificate{}, ErrNotFound
+	}
+	for _, other := range m.certificates {
+		if other.Code == code {
+			// Mirrors certificates_code_key.
+			return Certificate{}, problem.Conflict(problem.CodeConflict, "Resource conflicts with an existing one")
+		}
+	}
+	c := Certificate{ID: m.nextID, Code: code, EnrollmentID: e.ID, UserID: e.UserID, CourseID: e.CourseID,
+		LearnerName: m.names[e.UserID], CourseTitle: m.titles[e.CourseID], CompletedAt: time.Now()}
+	m.nextID++
+	if c.LearnerName == "" {
+		c.LearnerName = fmt.Sprintf("User %d", e.UserID)
+	}
+	if c.CourseTitle == "" {
+		c.CourseTitle = fmt.Sprintf("Course %d", e.CourseID)
+	}
+	m.certificates[enrollmentID] = c
+	return c, nil
+}
+
+func (m *Memory) CertificateByCode(ctx context.Context, code string) (Certificate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return Certificate{}, m.Err
+	}
+	for _, c := range m.certificates {
+		if c.Code == code {
+			return c, nil
+		}
+	}
+	return Certificate{}, ErrNotFound
+}
+
+func (m *Memory) ListCertificates(ctx context.Context, userID int, page paging.Page) ([]Certificate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var certificates []Certificate
+	for _, c := range m.certificates {
+		if c.UserID == userID {
+			certificates = append(certificates, c)
+		}
+	}
+	sort.Slice(certificates, func(i, j int) bool { return certificates[i].ID < certificates[j].ID })
+	return paging.Slice(certificates, page), nil
+}
+
+func (m *Memory) CreateCohort(ctx context.Context, c *Cohort) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	// Mirrors the course foreign key and UNIQUE(course_id, name).
+	if _, ok := m.courses[c.CourseID]; !ok {
+		return problem.Unprocessable(problem.CodeCourseNotFound, "A referenced resource does not exist")
+	}
+	for _, other := range m.cohorts {
+		if other.CourseID == c.CourseID && other.Name == c.Name {
+			return problem.Conflict(problem.CodeCohortNameTaken, "Resource conflicts with an existing one")
+		}
+	}
+	c.ID = m.nextID
+	m.nextID++
+	c.CreatedAt = time.Now()
+	m.cohorts[c.ID] = *c
+	return nil
+}
+
+func (m *Memory) GetCohort(ctx context.Context, id int) (Cohort, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return Cohort{}, m.Err
+	}
+	c, ok := m.cohorts[id]
+	if !ok {
+		return Cohort{}, ErrNotFound
+	}
+	return m.counted(c), nil
+}
+
+func (m *Memory) counted(c Cohort) Cohort {
+	c.Enrollments = 0
+	for _, e := range m.enrollments {
+		if e.CohortID != nil && *e.CohortID == c.ID {
+			c.Enrollments++
+		}
+	}
+	return c
+}
+
+func (m *Memory) ListCohorts(ctx context.Context, courseID int, page paging.Page) ([]Cohort, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var cohorts []Cohort
+	for _, c := range m.cohorts {
+		if courseID == 0 || c.CourseID == courseID {
+			cohorts = append(cohorts, m.counted(c))
+		}
+	}
+	sort.Slice(cohorts, func(i, j int) bool {
+		if cohorts[i].StartsOn != cohorts[j].StartsOn {
+			return cohorts[i].StartsOn < cohorts[j].StartsOn
+		}
+		return cohorts[i].ID < cohorts[j].ID
+	})
+	return paging.Slice(cohorts, page), nil
+}
+
+func (m *Memory) DeleteCohort(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	if _, ok := m.cohorts[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.cohorts, id)
+	// Mirrors ON DELETE SET NULL on user_course_enrollments.cohort_id.
+	for eid, e := range m.enrollments {
+		if e.CohortID != nil && *e.CohortID == id {
+			e.CohortID = nil
+			m.enrollments[eid] = e
+		}
+	}
+	return nil
+}
+
+func (m *Memory) ListCohortEnrollments(ctx context.Context, cohortID int, page paging.Page) ([]UserCourseEnrollment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var enrollments []UserCourseEnrollment
+	for _, e := range m.enrollments {
+		if e.CohortID != nil && *e.CohortID == cohortID && m.users[e.UserID] && m.courses[e.CourseID] {
+			enrollments = append(enrollments, e)
+		}
+	}
+	sort.Slice(enrollments, func(i, j int) bool { return enrollments[i].ID < enrollments[j].ID })
+	return paging.Slice(enrollments, page), nil
+}
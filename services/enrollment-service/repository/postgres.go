@@ -0,0 +1,697 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"mopcare/database"
+	"mopcare/events"
+	"mopcare/paging"
+)
+
+// Postgres is the production EnrollmentRepository, CertificateRepository and
+// CohortRepository.
+type Postgres struct {
+	db *database.DB
+}
+
+func NewPostgres(db *database.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+// ListByUser hides enrollments whose user or course is soft-deleted; they
+// come back if the row is restored.
+func (p *Postgres) ListByUser(ctx context.Context, userID int, page paging.Page) ([]UserCourseEnrollment, error) {
+	rows, err := p.db.QueryContext(ctx,
+		`SELECT e.id, e.user_id, e.course_id, e.status, e.cohort_id FROM user_course_enrollments e
+		 JOIN users u ON u.id = e.user_id AND u.deleted_at IS NULL
+		 JOIN courses c ON c.id = e.course_id AND c.deleted_at IS NULL
+		 WHERE e.user_id = $1 ORDER BY e.id LIMIT $2 OFFSET $3`,
+		userID, page.LimitArg(), page.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanEnrollments(rows)
+}
+
+const enrollmentColumns = "id, user_id, course_id, status, cohort_id"
+
+func scanEnrollment(row interface{ Scan(...interface{}) error }) (UserCourseEnrollment, error) {
+	var e UserCourseEnrollment
+	err := row.Scan(&e.ID, &e.UserID, &e.CourseID, &e.Status, &e.CohortID)
+	return e, err
+}
+
+func scanEnrollments(rows *database.Rows) ([]UserCourseEnrollment, error) {
+	defer rows.Close()
+	var enrollments []UserCourseEnrollment
+	for rows.Next() {
+		e, err := scanEnrollment(rows)
+		if err != nil {
+			return nil, err
+		}
+		enrollments = append(enrollments, e)
+	}
+	return enrollments, rows.Err()
+}
+
+func (p *Postgres) UserExists(ctx context.Context, userID int) (bool, error) {
+	return p.exists(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)", userID)
+}
+
+func (p *Postgres) CourseExists(ctx context.Context, courseID int) (bool, error) {
+	return p.exists(ctx, "SELECT EXISTS(SELECT 1 FROM courses WHERE id = $1 AND deleted_at IS NULL AND status = 'published')", courseID)
+}
+
+func (p *Postgres) CourseLimits(ctx context.Context, courseID int) (Limits, error) {
+	var l Limits
+	err := p.db.QueryRowContext(ctx,
+		"SELECT capacity, enrollment_opens_at, enrollment_closes_at FROM courses WHERE id = $1", courseID,
+	).Scan(&l.Capacity, &l.OpensAt, &l.ClosesAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Limits{}, ErrNotFound
+	}
+	return l, err
+}
+
+func (p *Postgres) Exists(ctx context.Context, userID, courseID int) (bool, error) {
+	return p.exists(ctx, "SELECT EXISTS(SELECT 1 FROM user_course_enrollments WHERE user_id = $1 AND course_id = $2)", userID, courseID)
+}
+
+func (p *Postgres) exists(ctx context.Context, query string, args ...interface{}) (bool, error) {
+	var exists bool
+	err := p.db.QueryRowContext(ctx, query, args...).Scan(&exists)
+	return exists, err
+}
+
+func (p *Postgres) UserIDByEmail(ctx context.Context, email string) (int, error) {
+	var id int
+	err := p.db.QueryRowContext(ctx, "SELECT id FROM users WHERE email = $1 AND deleted_at IS NULL", email).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	return id, err
+}
+
+func (p *Postgres) Create(ctx context.Context, e *UserCourseEnrollment) error {
+	return p.inTx(ctx, func(tx *database.Tx) error {
+		return create(ctx, tx, e)
+	})
+}
+
+func create(ctx context.Context, tx *database.Tx, e *UserCourseEnrollment) error {
+	err := tx.QueryRowContext(ctx,
+		"INSERT INTO user_course_enrollments (user_id, course_id, status, cohort_id) VALUES ($1, $2, $3, $4) RETURNING id",
+		e.UserID, e.CourseID, e.Status, e.CohortID,
+	).Scan(&e.ID)
+	if err != nil {
+		return err
+	}
+	return recordCreated(ctx, tx, e)
+}
+
+// recordCreated announces a new enrollment, unless it is waitlisted: that
+// one is announced when promote gives it a seat.
+func recordCreated(ctx context.Context, tx *database.Tx, e *UserCourseEnrollment) error {
+	if e.Status == "waitlisted" {
+		return nil
+	}
+	err := events.Record(ctx, tx, events.EnrollmentOutbox, events.EnrollmentCreated{EnrollmentID: e.ID, UserID: e.UserID, CourseID: e.CourseID, Status: e.Status})
+	if err != nil || e.Status != "completed" {
+		return err
+	}
+	return events.Record(ctx, tx, events.EnrollmentOutbox, events.EnrollmentCompleted{EnrollmentID: e.ID, UserID: e.UserID, CourseID: e.CourseID})
+}
+
+// CreateBatch reads its facts with one query each for the whole batch. It
+// locks the users it found with FOR SHARE, so none of them is deleted before
+// the batch commits, and the courses with lockCourses. Before counting
+// seats it promotes waitlisted users into any that freed up, for instance
+// because a course's capacity was raised, so they keep their place ahead of
+// the batch.
+func (p *Postgres) CreateBatch(ctx context.Context, enrollments []*UserCourseEnrollment, check func(BatchFacts) []bool) error {
+	return p.inTx(ctx, func(tx *database.Tx) error {
+		var userIDs, courseIDs, completedUsers, completedCourses []int64
+		for _, e := range enrollments {
+			userIDs = append(userIDs, int64(e.UserID))
+			courseIDs = append(courseIDs, int64(e.CourseID))
+			if e.Status == "completed" {
+				completedUsers = append(completedUsers, int64(e.UserID))
+				completedCourses = append(completedCourses, int64(e.CourseID))
+			}
+		}
+		facts := BatchFacts{Users: map[int]bool{}, Courses: map[int]bool{}, Enrolled: map[[2]int]bool{}, PendingQuizzes: map[[2]int]int{},
+			MissingPrerequisites: map[[2]int][]Prerequisite{}, Seats: map[int]int{}}
+		err := queryIDs(ctx, tx, facts.Users,
+			"SELECT id FROM users WHERE id = ANY($1) AND deleted_at IS NULL FOR SHARE", pq.Array(userIDs))
+		if err != nil {
+			return err
+		}
+		facts.Limits, facts.Now, err = lockCourses(ctx, tx, courseIDs)
+		if err != nil {
+			return err
+		}
+		var live []int64
+		for id := range facts.Limits {
+			facts.Courses[id] = true
+			live = append(live, int64(id))
+		}
+		if err := promote(ctx, tx, live); err != nil {
+			return err
+		}
+		err = queryCounts(ctx, tx, facts.Seats,
+			`SELECT c.id, GREATEST(c.capacity - COUNT(e.id), 0) FROM courses c
+			 LEFT JOIN user_course_enrollments e ON e.course_id = c.id AND e.status <> 'waitlisted'
+			 WHERE c.id = ANY($1) AND c.capacity IS NOT NULL
+			 GROUP BY c.id`,
+			pq.Array(live))
+		if err != nil {
+			return err
+		}
+		err = queryPairs(ctx, tx, func(pair [2]int, _ int) { facts.Enrolled[pair] = true },
+			`SELECT e.user_id, e.course_id, 0 FROM user_course_enrollments e
+			 JOIN unnest($1::int[], $2::int[]) AS p(user_id, course_id) ON e.user_id = p.user_id AND e.course_id = p.course_id`,
+			pq.Array(userIDs), pq.Array(courseIDs))
+		if err != nil {
+			return err
+		}
+		err = queryPairs(ctx, tx, func(pair [2]int, n int) { facts.PendingQuizzes[pair] = n },
+			`SELECT p.user_id, p.course_id, COUNT(*) FROM unnest($1::int[], $2::int[]) AS p(user_id, course_id)
+			 JOIN series s ON s.course_id = p.course_id AND s.deleted_at IS NULL
+			 JOIN quizzes q ON q.series_id = s.id AND q.required
+			 WHERE NOT EXISTS (SELECT 1 FROM quiz_attempts a WHERE a.quiz_id = q.id AND a.user_id = p.user_id AND a.passed)
+			 GROUP BY p.user_id, p.course_id`,
+			pq.Array(completedUsers), pq.Array(completedCourses))
+		if err != nil {
+			return err
+		}
+		rows, err := tx.QueryContext(ctx,
+			fmt.Sprintf(missingPrerequisites, "unnest($1::int[], $2::int[]) AS b(user_id, course_id)"), pq.Array(userIDs), pq.Array(courseIDs))
+		if err != nil {
+			return err
+		}
+		err = scanMissing(rows, facts.MissingPrerequisites)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+
+		accept := check(facts)
+		var created []*UserCourseEnrollment
+		var users, courses, cohorts []int64
+		var statuses []string
+		for i, e := range enrollments {
+			if !accept[i] {
+				continue
+			}
+			created = append(created, e)
+			users = append(users, int64(e.UserID))
+			courses = append(courses, int64(e.CourseID))
+			statuses = append(statuses, e.Status)
+			cohort := int64(0)
+			if e.CohortID != nil {
+				cohort = int64(*e.CohortID)
+			}
+			cohorts = append(cohorts, cohort)
+		}
+		if len(created) == 0 {
+			return nil
+		}
+		ids := map[[2]int]int{}
+		err = queryPairs(ctx, tx, func(pair [2]int, id int) { ids[pair] = id },
+			`INSERT INTO user_course_enrollments (user_id, course_id, status, cohort_id)
+			 SELECT user_id, course_id, status, NULLIF(cohort_id, 0)
+			 FROM unnest($1::int[], $2::int[], $3::varchar[], $4::int[]) AS t(user_id, course_id, status, cohort_id)
+			 RETURNING user_id, course_id, id`,
+			pq.Array(users), pq.Array(courses), pq.Array(statuses), pq.Array(cohorts))
+		if err != nil {
+			return err
+		}
+		for _, e := range created {
+			e.ID = ids[[2]int{e.UserID, e.CourseID}]
+			if err := recordCreated(ctx, tx, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// lockCourses reads the limits of the live, published courses among ids, and
+// when it read them. It locks the courses FOR NO KEY UPDATE, so transactions
+// taking or freeing seats in a course go one at a time, while the FOR KEY
+// SHARE locks of foreign keys to the course do not wait.
+func lockCourses(ctx context.Context, tx *database.Tx, ids []int64) (map[int]Limits, time.Time, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, capacity, enrollment_opens_at, enrollment_closes_at, NOW() FROM courses
+		 WHERE id = ANY($1) AND deleted_at IS NULL AND status = 'published'
+		 ORDER BY id FOR NO KEY UPDATE`,
+		pq.Array(ids))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer rows.Close()
+	limits := map[int]Limits{}
+	var now time.Time
+	for rows.Next() {
+		var id int
+		var l Limits
+		if err := rows.Scan(&id, &l.Capacity, &l.OpensAt, &l.ClosesAt, &now); err != nil {
+			return nil, time.Time{}, err
+		}
+		limits[id] = l
+	}
+	return limits, now, rows.Err()
+}
+
+// promote gives the free seats of the courses to their waitlists, oldest
+// first, and announces the enrollments it promotes. A course without a
+// capacity promotes its whole waitlist. The courses must be locked.
+func promote(ctx context.Context, tx *database.Tx, courseIDs []int64) error {
+	rows, err := tx.QueryContext(ctx,
+		`WITH free AS (
+		   SELECT c.id AS course_id, c.capacity - (SELECT COUNT(*) FROM user_course_enrollments e
+		                                           WHERE e.course_id = c.id AND e.status <> 'waitlisted') AS seats
+		   FROM courses c WHERE c.id = ANY($1)
+		 ), queue AS (
+		   SELECT e.id, e.course_id, ROW_NUMBER() OVER (PARTITION BY e.course_id ORDER BY e.id) AS position
+		   FROM user_course_enrollments e
+		   JOIN users u ON u.id = e.user_id AND u.deleted_at IS NULL
+		   WHERE e.course_id = ANY($1) AND e.status = 'waitlisted'
+		 )
+		 UPDATE user_course_enrollments e SET status = 'enrolled'
+		 FROM queue JOIN free ON free.course_id = queue.course_id
+		 WHERE e.id = queue.id AND (free.seats IS NULL OR queue.position <= free.seats)
+		 RETURNING e.id, e.user_id, e.course_id`,
+		pq.Array(courseIDs))
+	if err != nil {
+		return err
+	}
+	var promoted []*UserCourseEnrollment
+	for rows.Next() {
+		e := &UserCourseEnrollment{Status: "enrolled"}
+		if err := rows.Scan(&e.ID, &e.UserID, &e.CourseID); err != nil {
+			rows.Close()
+			return err
+		}
+		promoted = append(promoted, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, e := range promoted {
+		if err := recordCreated(ctx, tx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func queryIDs(ctx context.Context, tx *database.Tx, into map[int]bool, query string, args ...interface{}) error {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		into[id] = true
+	}
+	return rows.Err()
+}
+
+// queryCounts reads the number following each ID the query returns.
+func queryCounts(ctx context.Context, tx *database.Tx, into map[int]int, query string, args ...interface{}) error {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id, n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return err
+		}
+		into[id] = n
+	}
+	return rows.Err()
+}
+
+// queryPairs calls fn with each {user ID, course ID} pair the query returns
+// and the number that follows it.
+func queryPairs(ctx context.Context, tx *database.Tx, fn func(pair [2]int, n int), query string, args ...interface{}) error {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var pair [2]int
+		var n int
+		if err := rows.Scan(&pair[0], &pair[1], &n); err != nil {
+			return err
+		}
+		fn(pair, n)
+	}
+	return rows.Err()
+}
+
+func (p *Postgres) EachEnrollment(ctx context.Context, courseID int, fn func(UserCourseEnrollment) error) error {
+	rows, err := p.db.QueryContext(ctx,
+		"SELECT "+enrollmentColumns+" FROM user_course_enrollments WHERE ($1 = 0 OR course_id = $1) ORDER BY id", courseID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		e, err := scanEnrollment(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (p *Postgres) Get(ctx context.Context, id int) (UserCourseEnrollment, error) {
+	e, err := scanEnrollment(p.db.QueryRowContext(ctx, "SELECT "+enrollmentColumns+" FROM user_course_enrollments WHERE id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return UserCourseEnrollment{}, ErrNotFound
+	}
+	return e, err
+}
+
+// Complete only records EnrollmentCompleted when the status changes, so an
+// enrollment is announced as completed once however often it is completed.
+func (p *Postgres) Complete(ctx context.Context, id int) error {
+	return p.inTx(ctx, func(tx *database.Tx) error {
+		var e UserCourseEnrollment
+		err := tx.QueryRowContext(ctx,
+			"SELECT user_id, course_id, status FROM user_course_enrollments WHERE id = $1 FOR UPDATE", id,
+		).Scan(&e.UserID, &e.CourseID, &e.Status)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil || e.Status == "completed" {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "UPDATE user_course_enrollments SET status = 'completed' WHERE id = $1", id); err != nil {
+			return err
+		}
+		return events.Record(ctx, tx, events.EnrollmentOutbox, events.EnrollmentCompleted{EnrollmentID: id, UserID: e.UserID, CourseID: e.CourseID})
+	})
+}
+
+// PendingQuizzes ignores quizzes on deleted series, which learners cannot
+// see, let alone pass.
+func (p *Postgres) PendingQuizzes(ctx context.Context, userID, courseID int) (int, error) {
+	var pending int
+	err := p.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM quizzes q
+		 JOIN series s ON s.id = q.series_id AND s.deleted_at IS NULL
+		 WHERE s.course_id = $2 AND q.required
+		 AND NOT EXISTS (SELECT 1 FROM quiz_attempts a WHERE a.quiz_id = q.id AND a.user_id = $1 AND a.passed)`,
+		userID, courseID,
+	).Scan(&pending)
+	return pending, err
+}
+
+// missingPrerequisites selects, for each {user_id, course_id} row of b, the
+// prerequisites of the course that are published, and so not deleted, and
+// that the user has not completed.
+const missingPrerequisites = `SELECT b.user_id, b.course_id, c.id, c.title FROM %s
+	 JOIN course_prerequisites p ON p.course_id = b.course_id
+	 JOIN courses c ON c.id = p.prerequisite_id AND c.deleted_at IS NULL AND c.status = 'published'
+	 WHERE NOT EXISTS (SELECT 1 FROM user_course_enrollments e
+	                   WHERE e.user_id = b.user_id AND e.course_id = p.prerequisite_id AND e.status = 'completed')
+	 ORDER BY c.id`
+
+func (p *Postgres) MissingPrerequisites(ctx context.Context, userID, courseID int) ([]Prerequisite, error) {
+	rows, err := p.db.QueryContext(ctx,
+		fmt.Sprintf(missingPrerequisites, "(SELECT $1::int AS user_id, $2::int AS course_id) b"), userID, courseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	missing := map[[2]int][]Prerequisite{}
+	err = scanMissing(rows, missing)
+	return missing[[2]int{userID, courseID}], err
+}
+
+// scanMissing adds the prerequisite in the last two columns of each row to
+// the {user ID, course ID} pair in the first two.
+func scanMissing(rows interface {
+	Next() bool
+	Scan(...interface{}) error
+	Err() error
+}, into map[[2]int][]Prerequisite) error {
+	for rows.Next() {
+		var pair [2]int
+		var pr Prerequisite
+		if err := rows.Scan(&pair[0], &pair[1], &pr.CourseID, &pr.Title); err != nil {
+			return err
+		}
+		into[pair] = append(into[pair], pr)
+	}
+	return rows.Err()
+}
+
+// Delete locks the course as CreateBatch does before freeing the seat, so
+// the waitlist gets it rather than a batch counting seats at the same time.
+// The waitlists of deleted and unpublished courses wait for them to return.
+func (p *Postgres) Delete(ctx context.Context, id int) error {
+	return p.inTx(ctx, func(tx *database.Tx) error {
+		var courseID int64
+		err := tx.QueryRowContext(ctx, "SELECT course_id FROM user_course_enrollments WHERE id = $1", id).Scan(&courseID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		limits, _, err := lockCourses(ctx, tx, []int64{courseID})
+		if err != nil {
+			return err
+		}
+		result, err := tx.ExecContext(ctx, "DELETE FROM user_course_enrollments WHERE id = $1", id)
+		if err != nil {
+			return err
+		}
+		if err := expectRow(result); err != nil || len(limits) == 0 {
+			return err
+		}
+		return promote(ctx, tx, []int64{courseID})
+	})
+}
+
+func (p *Postgres) ListRoster(ctx context.Context, courseID int, page paging.Page) ([]UserCourseEnrollment, error) {
+	rows, err := p.db.QueryContext(ctx,
+		`SELECT e.id, e.user_id, e.course_id, e.status, e.cohort_id FROM user_course_enrollments e
+		 JOIN users u ON u.id = e.user_id AND u.deleted_at IS NULL
+		 WHERE e.course_id = $1 AND e.status <> 'waitlisted' ORDER BY e.id LIMIT $2 OFFSET $3`,
+		courseID, page.LimitArg(), page.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanEnrollments(rows)
+}
+
+// ListWaitlist numbers the whole waitlist before paging it, so positions
+// carry on from one page to the next.
+func (p *Postgres) ListWaitlist(ctx context.Context, courseID int, page paging.Page) ([]WaitlistEntry, error) {
+	rows, err := p.db.QueryContext(ctx,
+		`SELECT e.id, e.user_id, e.course_id, e.cohort_id, ROW_NUMBER() OVER (ORDER BY e.id) FROM user_course_enrollments e
+		 JOIN users u ON u.id = e.user_id AND u.deleted_at IS NULL
+		 WHERE e.course_id = $1 AND e.status = 'waitlisted' ORDER BY e.id LIMIT $2 OFFSET $3`,
+		courseID, page.LimitArg(), page.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []WaitlistEntry
+	for rows.Next() {
+		var w WaitlistEntry
+		if err := rows.Scan(&w.EnrollmentID, &w.UserID, &w.CourseID, &w.CohortID, &w.Position); err != nil {
+			return nil, err
+		}
+		entries = append(entries, w)
+	}
+	return entries, rows.Err()
+}
+
+const cohortColumns = `c.id, c.course_id, c.name, c.starts_on::text,
+	(SELECT COUNT(*) FROM user_course_enrollments e WHERE e.cohort_id = c.id), c.created_at`
+
+func scanCohort(row interface{ Scan(...interface{}) error }) (Cohort, error) {
+	var c Cohort
+	err := row.Scan(&c.ID, &c.CourseID, &c.Name, &c.StartsOn, &c.Enrollments, &c.CreatedAt)
+	return c, err
+}
+
+func (p *Postgres) CreateCohort(ctx context.Context, c *Cohort) error {
+	return p.db.QueryRowContext(ctx,
+		"INSERT INTO cohorts (course_id, name, starts_on) VALUES ($1, $2, $3) RETURNING id, created_at",
+		c.CourseID, c.Name, c.StartsOn,
+	).Scan(&c.ID, &c.CreatedAt)
+}
+
+func (p *Postgres) GetCohort(ctx context.Context, id int) (Cohort, error) {
+	c, err := scanCohort(p.db.QueryRowContext(ctx, "SELECT "+cohortColumns+" FROM cohorts c WHERE c.id = $1", id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Cohort{}, ErrNotFound
+	}
+	return c, err
+}
+
+func (p *Postgres) ListCohorts(ctx context.Context, courseID int, page paging.Page) ([]Cohort, error) {
+	rows, err := p.db.QueryContext(ctx,
+		"SELECT "+cohortColumns+" FROM cohorts c WHERE ($1 = 0 OR c.course_id = $1) ORDER BY c.starts_on, c.id LIMIT $2 OFFSET $3",
+		courseID, page.LimitArg(), page.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cohorts []Cohort
+	for rows.Next() {
+		c, err := scanCohort(rows)
+		if err != nil {
+			return nil, err
+		}
+		cohorts = append(cohorts, c)
+	}
+	return cohorts, rows.Err()
+}
+
+func (p *Postgres) DeleteCohort(ctx context.Context, id int) error {
+	result, err := p.db.ExecContext(ctx, "DELETE FROM cohorts WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	return expectRow(result)
+}
+
+// ListCohortEnrollments hides enrollments whose user or course is
+// soft-deleted, as ListByUser does.
+func (p *Postgres) ListCohortEnrollments(ctx context.Context, cohortID int, page paging.Page) ([]UserCourseEnrollment, error) {
+	rows, err := p.db.QueryContext(ctx,
+		`SELECT e.id, e.user_id, e.course_id, e.status, e.cohort_id FROM user_course_enrollments e
+		 JOIN users u ON u.id = e.user_id AND u.deleted_at IS NULL
+		 JOIN courses c ON c.id = e.course_id AND c.deleted_at IS NULL
+		 WHERE e.cohort_id = $1 ORDER BY e.id LIMIT $2 OFFSET $3`,
+		cohortID, page.LimitArg(), page.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanEnrollments(rows)
+}
+
+const certificateColumns = "id, code, enrollment_id, user_id, course_id, learner_name, course_title, completed_at"
+
+func scanCertificate(row interface{ Scan(...interface{}) error }) (Certificate, error) {
+	var c Certificate
+	err := row.Scan(&c.ID, &c.Code, &c.EnrollmentID, &c.UserID, &c.CourseID, &c.LearnerName, &c.CourseTitle, &c.CompletedAt)
+	return c, err
+}
+
+// IssueCertificate inserts unless the enrollment is certified already and
+// reads back whichever certificate it has. The statement cannot see a row it
+// inserted itself, so at most one of its two halves returns anything. Nor
+// can it see one a concurrent issuance committed after it began, which its
+// insert still conflicts with, so finding nothing is checked again with a
+// fresh snapshot.
+func (p *Postgres) IssueCertificate(ctx context.Context, enrollmentID int, code string) (Certificate, error) {
+	c, err := scanCertificate(p.db.QueryRowContext(ctx,
+		`WITH issued AS (
+		   INSERT INTO certificates (code, enrollment_id, user_id, course_id, learner_name, course_title, completed_at)
+		   SELECT $2, e.id, e.user_id, e.course_id, u.first_name || ' ' || u.last_name, c.title, NOW()
+		   FROM user_course_enrollments e
+		   JOIN users u ON u.id = e.user_id
+		   JOIN courses c ON c.id = e.course_id
+		   WHERE e.id = $1
+		   ON CONFLICT (enrollment_id) DO NOTHING
+		   RETURNING `+certificateColumns+`
+		 )
+		 SELECT `+certificateColumns+` FROM issued
+		 UNION ALL SELECT `+certificateColumns+` FROM certificates WHERE enrollment_id = $1
+		 LIMIT 1`,
+		enrollmentID, code,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		c, err = scanCertificate(p.db.QueryRowContext(ctx, "SELECT "+certificateColumns+" FROM certificates WHERE enrollment_id = $1", enrollmentID))
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return Certificate{}, ErrNotFound
+	}
+	return c, err
+}
+
+func (p *Postgres) CertificateByCode(ctx context.Context, code string) (Certificate, error) {
+	c, err := scanCertificate(p.db.QueryRowContext(ctx, "SELECT "+certificateColumns+" FROM certificates WHERE code = $1", code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return Certificate{}, ErrNotFound
+	}
+	return c, err
+}
+
+func (p *Postgres) ListCertificates(ctx context.Context, userID int, page paging.Page) ([]Certificate, error) {
+	rows, err := p.db.QueryContext(ctx,
+		"SELECT "+certificateColumns+" FROM certificates WHERE user_id = $1 ORDER BY id LIMIT $2 OFFSET $3",
+		userID, page.LimitArg(), page.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var certificates []Certificate
+	for rows.Next() {
+		c, err := scanCertificate(rows)
+		if err != nil {
+			return nil, err
+		}
+		certificates = append(certificates, c)
+	}
+	return certificates, rows.Err()
+}
+
+func (p *Postgres) inTx(ctx context.Context, fn func(*database.Tx) error) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// expectRow turns an UPDATE/DELETE that touched nothing into ErrNotFound.
+func expectRow(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// Package repository is the persistence layer of the enrollment-service.
+// Handlers and business logic depend only on the EnrollmentRepository,
+// CertificateRepository and CohortRepository interfaces so they can be
+// exercised against the in-memory implementation.
+//
+// An enrollment is enrolled, completed or waitlisted. Waitlisted users wait
+// for a seat in a full course: their enrollment takes none, and becomes
+// enrolled, oldest first, once one frees up. Only then is it announced with
+// EnrollmentCreated.
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"mopcare/paging"
+)
+
+// ErrNotFound is returned when the addressed row does not exist.
+var ErrNotFound = errors.New("not found")
+
+type UserCourseEnrollment struct {
+	ID       int    `json:"id"`
+	UserID   int    `json:"user_id"`
+	CourseID int    `json:"course_id"`
+	Status   string `json:"status"`
+	// CohortID is the cohort the user takes the course with, if any.
+	CohortID *int `json:"cohort_id,omitempty"`
+}
+
+// BatchFacts are what a batch of enrollments is checked against. They only
+// cover the users, courses and pairs in the batch.
+type BatchFacts struct {
+	// Users holds the live users and Courses the live, published courses.
+	Users   map[int]bool
+	Courses map[int]bool
+	// Enrolled holds the {user ID, course ID} pairs enrolled already.
+	Enrolled map[[2]int]bool
+	// PendingQuizzes counts, for each pair being created completed, the
+	// required quizzes of the course the user has not passed.
+	PendingQuizzes map[[2]int]int
+	// MissingPrerequisites holds, for each pair, the prerequisites of the
+	// course the user has not completed.
+	MissingPrerequisites map[[2]int][]Prerequisite
+	// Now is when the facts were read, for the enrollment windows in Limits.
+	Now time.Time
+	// Limits holds the limits of each of the Courses.
+	Limits map[int]Limits
+	// Seats holds the free seats of each course with a capacity, after the
+	// waitlist took those that freed up. A check that accepts an enrollment
+	// taking a seat should take it from here.
+	Seats map[int]int
+}
+
+// Limits are the capacity and enrollment window the course-service keeps for
+// a course; nil means no limit.
+type Limits struct {
+	Capacity *int
+	OpensAt  *time.Time
+	ClosesAt *time.Time
+}
+
+// WaitlistEntry is a waitlisted enrollment and its place in the course's
+// waitlist, counting from 1.
+type WaitlistEntry struct {
+	EnrollmentID int  `json:"enrollment_id"`
+	UserID       int  `json:"user_id"`
+	CourseID     int  `json:"course_id"`
+	CohortID     *int `json:"cohort_id,omitempty"`
+	Position     int  `json:"position"`
+}
+
+// Prerequisite is a course that must be completed before enrolling in
+// another, which the course-service manages.
+type Prerequisite struct {
+	CourseID int    `json:"course_id"`
+	Title    string `json:"title"`
+}
+
+type EnrollmentRepository interface {
+	ListByUser(ctx context.Context, userID int, page paging.Page) ([]UserCourseEnrollment, error)
+	// UserExists and CourseExists report whether the row exists and is not
+	// soft-deleted. CourseExists also requires the course to be published,
+	// so drafts and archived courses cannot be enrolled in.
+	UserExists(ctx context.Context, userID int) (bool, error)
+	// UserIDByEmail finds the live user with the email, or fails with
+	// ErrNotFound.
+	UserIDByEmail(ctx context.Context, email string) (int, error)
+	CourseExists(ctx context.Context, courseID int) (bool, error)
+	// CourseLimits returns the course's capacity and enrollment window as
+	// they are now, without locking it; CreateBatch reads them again.
+	CourseLimits(ctx context.Context, courseID int) (Limits, error)
+	Exists(ctx context.Context, userID, courseID int) (bool, error)
+	// Create inserts e as it is and fills in its ID, without the checks of
+	// CreateBatch.
+	Create(ctx context.Context, e *UserCourseEnrollment) error
+	// CreateBatch reads the facts about enrollments, hands them to check and
+	// creates the enrollments check accepts, all in one transaction so the
+	// facts still hold when the rows are written. check returns whether to
+	// create each enrollment and may change their status; those created get
+	// their ID filled in. Enrollments in the same course are checked one
+	// batch at a time, so their seats are counted right.
+	CreateBatch(ctx context.Context, enrollments []*UserCourseEnrollment, check func(BatchFacts) []bool) error
+	// EachEnrollment calls fn with every enrollment in the course, or in any
+	// course if courseID is 0, in ID order, and stops at the first error fn
+	// returns. Unlike ListByUser it includes enrollments of deleted users and
+	// courses.
+	EachEnrollment(ctx context.Context, courseID int, fn func(UserCourseEnrollment) error) error
+	Get(ctx context.Context, id int) (UserCourseEnrollment, error)
+	// Complete marks the enrollment completed.
+	Complete(ctx context.Context, id int) error
+	// Delete deletes the enrollment and gives any seat it frees to the
+	// course's waitlist.
+	Delete(ctx context.Context, id int) error
+	// ListRoster returns one page of the enrollments holding a seat in the
+	// course, and ListWaitlist one of its waitlist, oldest first. Both hide
+	// soft-deleted users, who are not promoted either.
+	ListRoster(ctx context.Context, courseID int, page paging.Page) ([]UserCourseEnrollment, error)
+	ListWaitlist(ctx context.Context, courseID int, page paging.Page) ([]WaitlistEntry, error)
+	// PendingQuizzes counts the required quizzes on the course's live series
+	// that the user has not passed yet.
+	PendingQuizzes(ctx context.Context, userID, courseID int) (int, error)
+	// MissingPrerequisites returns the course's prerequisites the user has
+	// not completed, ordered by ID. Deleted prerequisites are not required.
+	MissingPrerequisites(ctx context.Context, userID, courseID int) ([]Prerequisite, error)
+}
+
+// Cohort is a group of users taking a course together from StartsOn, a
+// YYYY-MM-DD date. Enrollments counts its members.
+type Cohort struct {
+	ID          int       `json:"id"`
+	CourseID    int       `json:"course_id"`
+	Name        string    `json:"name"`
+	StartsOn    string    `json:"starts_on"`
+	Enrollments int       `json:"enrollments"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type CohortRepository interface {
+	// CreateCohort inserts c and fills in its ID and CreatedAt.
+	CreateCohort(ctx context.Context, c *Cohort) error
+	GetCohort(ctx context.Context, id int) (Cohort, error)
+	// ListCohorts returns one page of the course's cohorts, or of every
+	// cohort if courseID is 0, by start date.
+	ListCohorts(ctx context.Context, courseID int, page paging.Page) ([]Cohort, error)
+	// DeleteCohort deletes the cohort; its enrollments stay, outside any
+	// cohort.
+	DeleteCohort(ctx context.Context, id int) error
+	ListCohortEnrollments(ctx context.Context, cohortID int, page paging.Page) ([]UserCourseEnrollment, error)
+}
+
+// Certificate records that a learner completed a course. LearnerName and
+// CourseTitle are as they were when it was issued.
+type Certificate struct {
+	ID           int       `json:"id"`
+	Code         string    `json:"code"`
+	EnrollmentID int       `json:"enrollment_id"`
+	UserID       int       `json:"user_id"`
+	CourseID     int       `json:"course_id"`
+	LearnerName  string    `json:"learner_name"`
+	CourseTitle  string    `json:"course_title"`
+	CompletedAt  time.Time `json:"completed_at"`
+}
+
+type CertificateRepository interface {
+	// IssueCertificate certifies the completed enrollment under code, taking
+	// the learner's name and the course title as they are now. An enrollment
+	// that already has a certificate keeps it, and that one is returned. It
+	// fails with ErrNotFound if the enrollment does not exist.
+	IssueCertificate(ctx context.Context, enrollmentID int, code string) (Certificate, error)
+	CertificateByCode(ctx context.Context, code string) (Certificate, error)
+	// ListCertificates returns one page of the user's certificates, oldest
+	// first.
+	ListCertificates(ctx context.Context, userID int, page paging.Page) ([]Certificate, error)
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"enrollment-service/repository"
+	"mopcare/auth"
+	"mopcare/bulk"
+	"mopcare/problem"
+)
+
+// MaxBatch is the most enrollments one batch may create.
+const MaxBatch = 1000
+
+// Batch item statuses.
+const (
+	ItemCreated = "created"
+	ItemFailed  = "failed"
+	// ItemSkipped is a valid item that was not created because an atomic
+	// batch had invalid ones.
+	ItemSkipped = "skipped"
+)
+
+// BatchInput enrolls many users at once. With a cohort, every enrollment
+// joins it, and items without a course are for the cohort's course.
+type BatchInput struct {
+	CohortID    int         `json:"cohort_id"`
+	Mode        string      `json:"mode"`
+	Enrollments []BatchItem `json:"enrollments"`
+}
+
+// BatchItem is one enrollment of a batch. Status defaults to enrolled.
+type BatchItem struct {
+	UserID   int    `json:"user_id"`
+	CourseID int    `json:"course_id"`
+	Status   string `json:"status"`
+}
+
+// BatchResult reports what became of each item of a batch, in order.
+type BatchResult struct {
+	Mode    string       `json:"mode"`
+	Created int          `json:"created"`
+	Failed  int          `json:"failed"`
+	Results []ItemResult `json:"results"`
+}
+
+type ItemResult struct {
+	Index      int                              `json:"index"`
+	Status     string                           `json:"status"`
+	Enrollment *repository.UserCourseEnrollment `json:"enrollment,omitempty"`
+	Error      *problem.Problem                 `json:"error,omitempty"`
+}
+
+// EnrollBatch checks and creates every enrollment of in within one
+// transaction, checking each as Enroll does. An atomic batch, the default,
+// creates nothing unless every item is valid; a best-effort one creates the
+// valid items.
+func (s *EnrollmentService) EnrollBatch(ctx context.Context, in BatchInput) (*BatchResult, error) {
+	if !auth.CallerOf(ctx).Admin {
+		return nil, problem.Forbidden(problem.CodeForbidden, "Only admins can enroll users in batches")
+	}
+	if in.Mode == "" {
+		in.Mode = bulk.ModeAtomic
+	}
+	if in.Mode != bulk.ModeAtomic && in.Mode != bulk.ModeBestEffort {
+		return nil, problem.BadRequest(problem.CodeValidationFailed, "Mode must be 'atomic' or 'best_effort'")
+	}
+	if len(in.Enrollments) == 0 || len(in.Enrollments) > MaxBatch {
+		return nil, problem.BadRequest(problem.CodeValidationFailed, fmt.Sprintf("A batch must have between 1 and %d enrollments", MaxBatch))
+	}
+	var cohort *repository.Cohort
+	if in.CohortID != 0 {
+		c, err := s.cohorts.GetCohort(ctx, in.CohortID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, problem.Unprocessable(problem.CodeCohortNotFound, "Cohort does not exist")
+		}
+		if err != nil {
+			return nil, err
+		}
+		cohort = &c
+	}
+
+	result := &BatchResult{Mode: in.Mode, Results: make([]ItemResult, len(in.Enrollments))}
+	enrollments := make([]*repository.UserCourseEnrollment, len(in.Enrollments))
+	first := map[[2]int]int{} // {user ID, course ID} -> first item with them
+	for i, item := range in.Enrollments {
+		e := &repository.UserCourseEnrollment{UserID: item.UserID, CourseID: item.CourseID, Status: item.Status}
+		if e.Status == "" {
+			e.Status = "enrolled"
+		}
+		if cohort != nil {
+			if e.CourseID == 0 {
+				e.CourseID = cohort.CourseID
+			}
+			e.CohortID = &cohort.ID
+		}
+		enrollments[i] = e
+		result.Results[i] = ItemResult{Index: i}
+		switch other, seen := first[[2]int{e.UserID, e.CourseID}]; {
+		case e.UserID == 0 || e.CourseID == 0:
+			result.fail(i, problem.BadRequest(problem.CodeValidationFailed, "User ID and Course ID are required"))
+		case e.Status != "enrolled" && e.Status != "completed":
+			result.fail(i, problem.BadRequest(problem.CodeValidationFailed, "Status must be 'enrolled' or 'completed'"))
+		case cohort != nil && e.CourseID != cohort.CourseID:
+			result.fail(i, problem.BadRequest(problem.CodeValidationFailed, "Course ID must be the cohort's course"))
+		case seen:
+			result.fail(i, problem.Conflict(problem.CodeEnrollmentDuplicate, fmt.Sprintf("Item %d enrolls the same user in the same course", other)))
+		default:
+			first[[2]int{e.UserID, e.CourseID}] = i
+		}
+	}
+
+	err := s.repo.CreateBatch(ctx, enrollments, func(facts repository.BatchFacts) []bool {
+		for i, e := range enrollments {
+			if result.Results[i].Error != nil {
+				continue
+			}
+			if err := checkEnrollment(facts, e); err != nil {
+				result.fail(i, err.(*problem.Problem))
+			}
+		}
+		accept := make([]bool, len(enrollments))
+		for i := range enrollments {
+			accept[i] = result.Results[i].Error == nil && (in.Mode == bulk.ModeBestEffort || result.Failed == 0)
+		}
+		return accept
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, e := range enrollments {
+		switch r := &result.Results[i]; {
+		case r.Error != nil:
+		case e.ID == 0:
+			r.Status = ItemSkipped
+		default:
+			r.Status, r.Enrollment = ItemCreated, e
+			result.Created++
+			if e.Status == "completed" {
+				if err := s.certify(ctx, e.ID); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+func (r *BatchResult) fail(i int, p *problem.Problem) {
+	r.Results[i].Status, r.Results[i].Error = ItemFailed, p
+	r.Failed++
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"enrollment-service/repository"
+	"mopcare/auth"
+	"mopcare/bulk"
+	"mopcare/problem"
+)
+
+// importFields are the columns of an enrollment import. Each row names its
+// user by user_id or, failing that, by email; status defaults to enrolled.
+var importFields = []string{"user_id", "email", "course_id", "status"}
+
+var enrollmentColumns = []string{"id", "user_id", "course_id", "status", "cohort_id"}
+
+// Import enrolls users in courses from the rows of a CSV. Rows are checked
+// as POST /users/{id}/enrollments checks an enrollment, and no two rows may
+// enroll the same user in the same course. Rows imported completed are
+// certified, as enrollments created completed are, and rows for a full
+// course are waitlisted. Seats are only counted as the rows are written, so
+// a dry run does not tell which rows would be waitlisted.
+func (s *EnrollmentService) Import(ctx context.Context, csv io.Reader, opts bulk.Options) (*bulk.Report, error) {
+	if !auth.CallerOf(ctx).Admin {
+		return nil, problem.Forbidden(problem.CodeForbidden, "Only admins can import enrollments")
+	}
+	reader, err := bulk.NewReader(csv, importFields, []string{"course_id"}, opts.Mapping)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	report := bulk.NewReport(opts)
+	report.Rows = len(rows)
+	var enrollments []*repository.UserCourseEnrollment
+	var numbers []int
+	pairs := map[[2]int]int{} // {user ID, course ID} -> first row with them
+	for _, row := range rows {
+		e, err := s.importRow(ctx, row, report)
+		if err != nil {
+			return nil, err
+		}
+		if e == nil {
+			continue
+		}
+		if first, ok := pairs[[2]int{e.UserID, e.CourseID}]; ok {
+			report.Fail(row.Number, "", fmt.Sprintf("Row %d enrolls the same user in the same course", first))
+			continue
+		}
+		pairs[[2]int{e.UserID, e.CourseID}] = row.Number
+		enrollments = append(enrollments, e)
+		numbers = append(numbers, row.Number)
+	}
+
+	if !report.Commit() {
+		return report, nil
+	}
+	err = s.repo.CreateBatch(ctx, enrollments, func(facts repository.BatchFacts) []bool {
+		accept := make([]bool, len(enrollments))
+		for i, e := range enrollments {
+			if err := checkEnrollment(facts, e); err != nil {
+				report.FailWith(numbers[i], "", err)
+				continue
+			}
+			accept[i] = true
+		}
+		if !report.Commit() {
+			return make([]bool, len(enrollments))
+		}
+		return accept
+	})
+	if err != nil {
+		return nil, err
+	}
+	var created []*repository.UserCourseEnrollment
+	for _, e := range enrollments {
+		if e.ID != 0 {
+			created = append(created, e)
+		}
+	}
+	report.Imported = len(created)
+	return report, s.certifyImported(ctx, created)
+}
+
+// importRow validates one row, recording what is wrong with it in report.
+// It returns nil for a row that failed, and an error only if checking the
+// row did.
+func (s *EnrollmentService) importRow(ctx context.Context, row bulk.Row, report *bulk.Report) (*repository.UserCourseEnrollment, error) {
+	e := &repository.UserCourseEnrollment{Status: row.Get("status")}
+	if e.Status == "" {
+		e.Status = "enrolled"
+	}
+	if e.Status != "enrolled" && e.Status != "completed" {
+		report.Fail(row.Number, "status", "Status must be 'enrolled' or 'completed'")
+	}
+	courseID, err := strconv.Atoi(row.Get("course_id"))
+	if err != nil || courseID < 1 {
+		report.Fail(row.Number, "course_id", "course_id must be a positive integer")
+	}
+	e.CourseID = courseID
+
+	switch raw, email := row.Get("user_id"), row.Get("email"); {
+	case raw != "":
+		id, err := strconv.Atoi(raw)
+		if err != nil || id < 1 {
+			report.Fail(row.Number, "user_id", "user_id must be a positive integer")
+		}
+		e.UserID = id
+	case email != "":
+		id, err := s.repo.UserIDByEmail(ctx, email)
+		if errors.Is(err, repository.ErrNotFound) {
+			report.Fail(row.Number, "email", "No user has this email")
+		} else if err != nil {
+			return nil, err
+		}
+		e.UserID = id
+	default:
+		report.Fail(row.Number, "user_id", "user_id or email is required")
+	}
+	if report.HasFailed(row.Number) {
+		return nil, nil
+	}
+
+	exists, err := s.repo.UserExists(ctx, e.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		report.Fail(row.Number, "user_id", "User does not exist")
+	}
+	exists, err = s.repo.CourseExists(ctx, e.CourseID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		report.Fail(row.Number, "course_id", "Course does not exist")
+	}
+	if report.HasFailed(row.Number) {
+		return nil, nil
+	}
+	limits, err := s.repo.CourseLimits(ctx, e.CourseID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkWindow(limits, time.Now()); err != nil {
+		report.FailWith(row.Number, "course_id", err)
+		return nil, nil
+	}
+	exists, err = s.repo.Exists(ctx, e.UserID, e.CourseID)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		report.Fail(row.Number, "", "User is already enrolled in this course")
+		return nil, nil
+	}
+	missing, err := s.repo.MissingPrerequisites(ctx, e.UserID, e.CourseID)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		report.FailWith(row.Number, "course_id", prerequisitesMissing(missing))
+		return nil, nil
+	}
+	if e.Status == "completed" {
+		if err := s.checkQuizzes(ctx, e.UserID, e.CourseID); err != nil {
+			if _, ok := err.(*problem.Problem); !ok {
+				return nil, err
+			}
+			report.FailWith(row.Number, "status", err)
+			return nil, nil
+		}
+	}
+	return e, nil
+}
+
+func (s *EnrollmentService) certifyImported(ctx context.Context, enrollments []*repository.UserCourseEnrollment) error {
+	for _, e := range enrollments {
+		if e.Status != "completed" {
+			continue
+		}
+		if err := s.certify(ctx, e.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Export writes the enrollments in the course, or in every course if
+// courseID is 0, to out.
+func (s *EnrollmentService) Export(ctx context.Context, out io.Writer, format bulk.Format, courseID int) error {
+	if !auth.CallerOf(ctx).Admin {
+		return problem.Forbidden(problem.CodeForbidden, "Only admins can export enrollments")
+	}
+	w := bulk.NewWriter(out, format, enrollmentColumns...)
+	err := s.repo.EachEnrollment(ctx, courseID, func(e repository.UserCourseEnrollment) error {
+		cohort := ""
+		if e.CohortID != nil {
+			cohort = strconv.Itoa(*e.CohortID)
+		}
+		return w.Write(e, strconv.Itoa(e.ID), strconv.Itoa(e.UserID), strconv.Itoa(e.CourseID), e.Status, cohort)
+	})
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"enrollment-service/repository"
+	"mopcare/auth"
+	"mopcare/paging"
+	"mopcare/pdf"
+	"mopcare/problem"
+)
+
+// codeAlphabet is Crockford's base32, which leaves out I, L, O and U so a
+// code read off paper is hard to get wrong.
+const codeAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// Verification is what anyone holding a certificate's code may learn about
+// it.
+type Verification struct {
+	Code        string    `json:"code"`
+	LearnerName string    `json:"learner_name"`
+	CourseTitle string    `json:"course_title"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// newCode returns 60 random bits as XXXX-XXXX-XXXX.
+func newCode() (string, error) {
+	raw := make([]byte, 12)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	code := make([]byte, 0, 14)
+	for i, b := range raw {
+		if i > 0 && i%4 == 0 {
+			code = append(code, '-')
+		}
+		code = append(code, codeAlphabet[b%32])
+	}
+	return string(code), nil
+}
+
+// normalizeCode accepts a code typed in lower case, with spaces, or without
+// its dashes.
+func normalizeCode(code string) string {
+	code = strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(code))
+	if len(code) != 12 {
+		return code
+	}
+	return code[:4] + "-" + code[4:8] + "-" + code[8:]
+}
+
+// certify issues the enrollment's certificate unless it has one already.
+func (s *EnrollmentService) certify(ctx context.Context, enrollmentID int) error {
+	code, err := newCode()
+	if err != nil {
+		return err
+	}
+	_, err = s.certificates.IssueCertificate(ctx, enrollmentID, code)
+	if errors.Is(err, repository.ErrNotFound) {
+		return problem.NotFound(problem.CodeEnrollmentNotFound, "Enrollment not found")
+	}
+	return err
+}
+
+// learner lets admins and the user themself through, and forbids others
+// what.
+func learner(ctx context.Context, userID int, what string) error {
+	caller := auth.CallerOf(ctx)
+	switch {
+	case caller.Admin || (caller.UserID != 0 && caller.UserID == userID):
+		return nil
+	case caller.UserID == 0:
+		return problem.Unauthorized(problem.CodeUnauthorized, "A user or admin token is required")
+	}
+	return problem.Forbidden(problem.CodeForbidden, what)
+}
+
+func certificateNotFound() error {
+	return problem.NotFound(problem.CodeCertificateNotFound, "Certificate not found")
+}
+
+// Verify looks a certificate up by its code. Anyone may do so.
+func (s *EnrollmentService) Verify(ctx context.Context, code string) (Verification, error) {
+	c, err := s.certificates.CertificateByCode(ctx, normalizeCode(code))
+	if errors.Is(err, repository.ErrNotFound) {
+		return Verification{}, certificateNotFound()
+	}
+	if err != nil {
+		return Verification{}, err
+	}
+	return Verification{Code: c.Code, LearnerName: c.LearnerName, CourseTitle: c.CourseTitle, CompletedAt: c.CompletedAt}, nil
+}
+
+// ListCertificates returns one page of the user's certificates to the user
+// themself or an admin.
+func (s *EnrollmentService) ListCertificates(ctx context.Context, userID int, page paging.Page) ([]repository.Certificate, error) {
+	if err := learner(ctx, userID, "Certificates are only shown to their learner"); err != nil {
+		return nil, err
+	}
+	certificates, err := s.certificates.ListCertificates(ctx, userID, page)
+	if certificates == nil {
+		certificates = []repository.Certificate{}
+	}
+	return certificates, err
+}
+
+// CertificatePDF renders the certificate for its learner or an admin. Other
+// callers are told it does not exist rather than that it is not theirs.
+func (s *EnrollmentService) CertificatePDF(ctx context.Context, code string) (repository.Certificate, []byte, error) {
+	c, err := s.certificates.CertificateByCode(ctx, normalizeCode(code))
+	if errors.Is(err, repository.ErrNotFound) {
+		return c, nil, certificateNotFound()
+	}
+	if err != nil {
+		return c, nil, err
+	}
+	if err := learner(ctx, c.UserID, "Certificates are only shown to their learner"); err != nil {
+		var p *problem.Problem
+		if errors.As(err, &p) && p.Code == problem.CodeForbidden {
+			return c, nil, certificateNotFound()
+		}
+		return c, nil, err
+	}
+	return c, renderCertificate(c), nil
+}
+
+// renderCertificate lays the certificate out on a landscape A4 page.
+func renderCertificate(c repository.Certificate) []byte {
+	page := pdf.NewPage(pdf.A4Height, pdf.A4Width)
+	page.Title = "Certificate of Completion - " + c.CourseTitle
+	page.Rect(24, 24, page.Width-48, page.Height-48, 3)
+	page.Rect(32, 32, page.Width-64, page.Height-64, 0.75)
+
+	page.CenteredText(450, pdf.HelveticaBold, 34, "Certificate of Completion")
+	page.Line(page.Width/2-120, 432, page.Width/2+120, 432, 0.75)
+	page.CenteredText(385, pdf.Helvetica, 14, "This certifies that")
+	page.CenteredText(340, pdf.HelveticaBold, fit(pdf.HelveticaBold, 28, c.LearnerName), c.LearnerName)
+	page.CenteredText(295, pdf.Helvetica, 14, "has completed the course")
+	page.CenteredText(250, pdf.HelveticaBold, fit(pdf.HelveticaBold, 22, c.CourseTitle), c.CourseTitle)
+	page.CenteredText(205, pdf.Helvetica, 14, "on "+c.CompletedAt.UTC().Format("January 2, 2006"))
+
+	page.CenteredText(70, pdf.Helvetica, 10, fmt.Sprintf("Verification code %s - check it at /certificates/%s/verify", c.Code, c.Code))
+	return page.Bytes()
+}
+
+// fit shrinks size until s fits between the page borders.
+func fit(font pdf.Font, size float64, s string) float64 {
+	const room = pdf.A4Height - 120
+	for size > 8 && pdf.Width(font, size, s) > room {
+		size--
+	}
+	return size
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"enrollment-service/repository"
+	"mopcare/auth"
+	"mopcare/paging"
+	"mopcare/problem"
+)
+
+// CohortInput creates a cohort of a course starting on StartsOn, a
+// YYYY-MM-DD date.
+type CohortInput struct {
+	CourseID int    `json:"course_id"`
+	Name     string `json:"name"`
+	StartsOn string `json:"starts_on"`
+}
+
+func (in *CohortInput) validate() error {
+	in.Name = strings.TrimSpace(in.Name)
+	if in.CourseID == 0 || in.Name == "" || in.StartsOn == "" {
+		return problem.BadRequest(problem.CodeValidationFailed, "Course ID, name and start date are required")
+	}
+	if len(in.Name) > 100 {
+		return problem.BadRequest(problem.CodeValidationFailed, "Name must be at most 100 characters")
+	}
+	if _, err := time.Parse("2006-01-02", in.StartsOn); err != nil {
+		return problem.BadRequest(problem.CodeValidationFailed, "Start date must be a date like 2027-03-01")
+	}
+	return nil
+}
+
+func cohortAdmin(ctx context.Context) error {
+	if !auth.CallerOf(ctx).Admin {
+		return problem.Forbidden(problem.CodeForbidden, "Only admins can manage cohorts")
+	}
+	return nil
+}
+
+func cohortNotFound(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return problem.NotFound(problem.CodeCohortNotFound, "Cohort not found")
+	}
+	return err
+}
+
+// CreateCohort starts a cohort of a published course. Cohort names are
+// unique within their course.
+func (s *EnrollmentService) CreateCohort(ctx context.Context, in CohortInput) (repository.Cohort, error) {
+	if err := cohortAdmin(ctx); err != nil {
+		return repository.Cohort{}, err
+	}
+	if err := in.validate(); err != nil {
+		return repository.Cohort{}, err
+	}
+	exists, err := s.repo.CourseExists(ctx, in.CourseID)
+	if err != nil {
+		return repository.Cohort{}, err
+	}
+	if !exists {
+		return repository.Cohort{}, problem.Unprocessable(problem.CodeCourseNotFound, "Course does not exist")
+	}
+	c := repository.Cohort{CourseID: in.CourseID, Name: in.Name, StartsOn: in.StartsOn}
+	if err := s.cohorts.CreateCohort(ctx, &c); err != nil {
+		return repository.Cohort{}, err
+	}
+	return c, nil
+}
+
+func (s *EnrollmentService) Cohort(ctx context.Context, id int) (repository.Cohort, error) {
+	if err := cohortAdmin(ctx); err != nil {
+		return repository.Cohort{}, err
+	}
+	c, err := s.cohorts.GetCohort(ctx, id)
+	return c, cohortNotFound(err)
+}
+
+// ListCohorts returns one page of the course's cohorts, or of every cohort
+// if courseID is 0, by start date.
+func (s *EnrollmentService) ListCohorts(ctx context.Context, courseID int, page paging.Page) ([]repository.Cohort, error) {
+	if err := cohortAdmin(ctx); err != nil {
+		return nil, err
+	}
+	cohorts, err := s.cohorts.ListCohorts(ctx, courseID, page)
+	if cohorts == nil && err == nil {
+		cohorts = []repository.Cohort{}
+	}
+	return cohorts, err
+}
+
+// DeleteCohort deletes a cohort but not its enrollments, which stay outside
+// any cohort.
+func (s *EnrollmentService) DeleteCohort(ctx context.Context, id int) error {
+	if err := cohortAdmin(ctx); err != nil {
+		return err
+	}
+	return cohortNotFound(s.cohorts.DeleteCohort(ctx, id))
+}
+
+// CohortEnrollments returns one page of the cohort's enrollments.
+func (s *EnrollmentService) CohortEnrollments(ctx context.Context, id int, page paging.Page) ([]repository.UserCourseEnrollment, error) {
+	if err := cohortAdmin(ctx); err != nil {
+		return nil, err
+	}
+	if _, err := s.cohorts.GetCohort(ctx, id); err != nil {
+		return nil, cohortNotFound(err)
+	}
+	enrollments, err := s.cohorts.ListCohortEnrollments(ctx, id, page)
+	if enrollments == nil && err == nil {
+		enrollments = []repository.UserCourseEnrollment{}
+	}
+	return enrollments, err
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+
+	"enrollment-service/repository"
+	"mopcare/problem"
+)
+
+// Eligibility is whether a user may enroll in a course as far as its
+// prerequisites go.
+type Eligibility struct {
+	UserID   int  `json:"user_id"`
+	CourseID int  `json:"course_id"`
+	Eligible bool `json:"eligible"`
+	// Missing lists the prerequisites the user has yet to complete.
+	Missing []repository.Prerequisite `json:"missing_prerequisites"`
+}
+
+// Eligibility tells the user themself or an admin which prerequisites of a
+// published course the user still has to complete before enrolling in it.
+func (s *EnrollmentService) Eligibility(ctx context.Context, userID, courseID int) (Eligibility, error) {
+	if err := learner(ctx, userID, "Eligibility is only shown to its user"); err != nil {
+		return Eligibility{}, err
+	}
+	exists, err := s.repo.UserExists(ctx, userID)
+	if err != nil {
+		return Eligibility{}, err
+	}
+	if !exists {
+		return Eligibility{}, problem.NotFound(problem.CodeUserNotFound, "User not found")
+	}
+	exists, err = s.repo.CourseExists(ctx, courseID)
+	if err != nil {
+		return Eligibility{}, err
+	}
+	if !exists {
+		return Eligibility{}, problem.NotFound(problem.CodeCourseNotFound, "Course not found")
+	}
+	missing, err := s.repo.MissingPrerequisites(ctx, userID, courseID)
+	if err != nil {
+		return Eligibility{}, err
+	}
+	if missing == nil {
+		missing = []repository.Prerequisite{}
+	}
+	return Eligibility{UserID: userID, CourseID: courseID, Eligible: len(missing) == 0, Missing: missing}, nil
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+
+	"enrollment-service/repository"
+	"mopcare/auth"
+	"mopcare/paging"
+	"mopcare/problem"
+)
+
+func rosterAdmin(ctx context.Context) error {
+	if !auth.CallerOf(ctx).Admin {
+		return problem.Forbidden(problem.CodeForbidden, "Only admins can view rosters and waitlists")
+	}
+	return nil
+}
+
+// Roster returns one page of the enrollments holding a seat in the course.
+func (s *EnrollmentService) Roster(ctx context.Context, courseID int, page paging.Page) ([]repository.UserCourseEnrollment, error) {
+	if err := rosterAdmin(ctx); err != nil {
+		return nil, err
+	}
+	enrollments, err := s.repo.ListRoster(ctx, courseID, page)
+	if enrollments == nil && err == nil {
+		enrollments = []repository.UserCourseEnrollment{}
+	}
+	return enrollments, err
+}
+
+// Waitlist returns one page of the users waiting for a seat in the course,
+// in the order they will get one.
+func (s *EnrollmentService) Waitlist(ctx context.Context, courseID int, page paging.Page) ([]repository.WaitlistEntry, error) {
+	if err := rosterAdmin(ctx); err != nil {
+		return nil, err
+	}
+	entries, err := s.repo.ListWaitlist(ctx, courseID, page)
+	if entries == nil && err == nil {
+		entries = []repository.WaitlistEntry{}
+	}
+	return entries, err
+}
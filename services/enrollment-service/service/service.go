@@ -0,0 +1,202 @@
+// Package service holds the enrollment-service business rules. It speaks in
+// repository types and returns *problem.Problem for every expected failure.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"enrollment-service/repository"
+	"mopcare/paging"
+	"mopcare/problem"
+)
+
+type EnrollmentService struct {
+	repo         repository.EnrollmentRepository
+	certificates repository.CertificateRepository
+	cohorts      repository.CohortRepository
+}
+
+func NewEnrollmentService(repo repository.EnrollmentRepository, certificates repository.CertificateRepository, cohorts repository.CohortRepository) *EnrollmentService {
+	return &EnrollmentService{repo: repo, certificates: certificates, cohorts: cohorts}
+}
+
+// ListForUser returns one page of a user's enrollments. Only an empty first
+// page is a 404; a page past the end is simply empty.
+func (s *EnrollmentService) ListForUser(ctx context.Context, userID int, page paging.Page) ([]repository.UserCourseEnrollment, error) {
+	enrollments, err := s.repo.ListByUser(ctx, userID, page)
+	if err != nil {
+		return nil, err
+	}
+	if len(enrollments) == 0 && page.Offset == 0 {
+		return nil, problem.NotFound(problem.CodeEnrollmentNotFound, "No enrollments found for this user")
+	}
+	if enrollments == nil {
+		enrollments = []repository.UserCourseEnrollment{}
+	}
+	return enrollments, nil
+}
+
+// Enroll validates e against the user in the URL and creates it, checking
+// and writing in one transaction. An enrollment created completed is
+// certified straight away; one in a full course is waitlisted.
+func (s *EnrollmentService) Enroll(ctx context.Context, userID int, e *repository.UserCourseEnrollment) error {
+	if e.UserID == 0 || e.CourseID == 0 || e.Status == "" {
+		return problem.BadRequest(problem.CodeValidationFailed, "User ID, Course ID, and Status are required")
+	}
+	if e.UserID != userID {
+		return problem.BadRequest(problem.CodeValidationFailed, "User ID in body must match URL parameter")
+	}
+	if e.Status != "enrolled" && e.Status != "completed" {
+		return problem.BadRequest(problem.CodeValidationFailed, "Status must be 'enrolled' or 'completed'")
+	}
+
+	// Cohorts are joined through batches.
+	e.CohortID = nil
+
+	var failure error
+	err := s.repo.CreateBatch(ctx, []*repository.UserCourseEnrollment{e}, func(facts repository.BatchFacts) []bool {
+		failure = checkEnrollment(facts, e)
+		return []bool{failure == nil}
+	})
+	if err != nil {
+		return err
+	}
+	if failure != nil {
+		return failure
+	}
+	if e.Status == "completed" {
+		return s.certify(ctx, e.ID)
+	}
+	return nil
+}
+
+// checkEnrollment checks e against the facts read for it: its user and
+// course must exist, it must not exist already, the course must be open for
+// enrollment, its user must have completed the course's prerequisites, and
+// if it is created completed its user must have passed the course's required
+// quizzes. It then takes a seat for e.
+func checkEnrollment(facts repository.BatchFacts, e *repository.UserCourseEnrollment) error {
+	pair := [2]int{e.UserID, e.CourseID}
+	switch {
+	case !facts.Users[e.UserID]:
+		return problem.Unprocessable(problem.CodeUserNotFound, "User does not exist")
+	case !facts.Courses[e.CourseID]:
+		return problem.Unprocessable(problem.CodeCourseNotFound, "Course does not exist")
+	case facts.Enrolled[pair]:
+		return problem.Conflict(problem.CodeEnrollmentDuplicate, "User is already enrolled in this course")
+	}
+	if err := checkWindow(facts.Limits[e.CourseID], facts.Now); err != nil {
+		return err
+	}
+	switch {
+	case len(facts.MissingPrerequisites[pair]) > 0:
+		return prerequisitesMissing(facts.MissingPrerequisites[pair])
+	case e.Status == "completed" && facts.PendingQuizzes[pair] > 0:
+		return quizzesPending(facts.PendingQuizzes[pair])
+	}
+	return takeSeat(facts, e)
+}
+
+// checkWindow fails unless the course is open for enrollment at now.
+func checkWindow(limits repository.Limits, now time.Time) error {
+	switch {
+	case limits.OpensAt != nil && now.Before(*limits.OpensAt):
+		return problem.Conflict(problem.CodeEnrollmentNotOpen, "Enrollment in this course opens at "+limits.OpensAt.UTC().Format(time.RFC3339))
+	case limits.ClosesAt != nil && !now.Before(*limits.ClosesAt):
+		return problem.Conflict(problem.CodeEnrollmentClosed, "Enrollment in this course closed at "+limits.ClosesAt.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// takeSeat takes a free seat for e if its course has a capacity. Without
+// one e is waitlisted, unless it is created completed, which cannot wait.
+func takeSeat(facts repository.BatchFacts, e *repository.UserCourseEnrollment) error {
+	switch seats, limited := facts.Seats[e.CourseID]; {
+	case !limited:
+	case seats > 0:
+		facts.Seats[e.CourseID]--
+	case e.Status == "completed":
+		return problem.Conflict(problem.CodeCourseFull, "Course is full; only enrollments that are not completed can be waitlisted")
+	default:
+		e.Status = "waitlisted"
+	}
+	return nil
+}
+
+// Complete marks an enrollment completed once the user has passed every
+// required quiz of the course, and issues its certificate. Only the user
+// themself or an admin may complete it. Completing it again only issues the
+// certificate if it is still missing.
+func (s *EnrollmentService) Complete(ctx context.Context, id int) (repository.UserCourseEnrollment, error) {
+	e, err := s.repo.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return e, problem.NotFound(problem.CodeEnrollmentNotFound, "Enrollment not found")
+	}
+	if err != nil {
+		return e, err
+	}
+	if err := learner(ctx, e.UserID, "Enrollments can only be completed by their learner"); err != nil {
+		return e, err
+	}
+	if e.Status == "completed" {
+		return e, s.certify(ctx, id)
+	}
+	if e.Status == "waitlisted" {
+		return e, problem.Conflict(problem.CodeWaitlisted, "User is still on the waitlist for this course")
+	}
+	if err := s.checkQuizzes(ctx, e.UserID, e.CourseID); err != nil {
+		return e, err
+	}
+	err = s.repo.Complete(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return e, problem.NotFound(problem.CodeEnrollmentNotFound, "Enrollment not found")
+	}
+	if err != nil {
+		return e, err
+	}
+	e.Status = "completed"
+	return e, s.certify(ctx, id)
+}
+
+func (s *EnrollmentService) checkQuizzes(ctx context.Context, userID, courseID int) error {
+	pending, err := s.repo.PendingQuizzes(ctx, userID, courseID)
+	if err != nil {
+		return err
+	}
+	if pending > 0 {
+		return quizzesPending(pending)
+	}
+	return nil
+}
+
+func quizzesPending(n int) error {
+	return problem.Conflict(problem.CodeQuizzesNotPassed, fmt.Sprintf("%d required quizzes of this course are not passed yet", n))
+}
+
+// prerequisitesMissing names the missing prerequisites in its detail, and
+// lists them with their IDs in its errors.
+func prerequisitesMissing(missing []repository.Prerequisite) error {
+	titles := make([]string, len(missing))
+	errs := make([]string, len(missing))
+	for i, m := range missing {
+		titles[i] = m.Title
+		errs[i] = fmt.Sprintf("Course %d (%s) is not completed", m.CourseID, m.Title)
+	}
+	p := problem.Conflict(problem.CodePrerequisitesMissing, "User has yet to complete the course's prerequisites: "+strings.Join(titles, ", "))
+	p.Errors = errs
+	return p
+}
+
+// Delete deletes an enrollment; the seat it frees goes to the first user on
+// the course's waitlist.
+func (s *EnrollmentService) Delete(ctx context.Context, id int) error {
+	err := s.repo.Delete(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return problem.NotFound(problem.CodeEnrollmentNotFound, "Enrollment not found")
+	}
+	return err
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"mopcare/auth"
+	"mopcare/paging"
+	"mopcare/problem"
+	"mopcare/webhooks"
+)
+
+const minSecretLength = 16
+
+// WebhookInput is the writable part of a webhook. A webhook created without
+// a secret is given one; one updated without a secret keeps its own. A new
+// webhook is active unless Active says otherwise.
+type WebhookInput struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret"`
+	Active     *bool    `json:"active"`
+}
+
+func (in WebhookInput) validate() error {
+	u, err := url.Parse(in.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return problem.BadRequest(problem.CodeValidationFailed, "url must be an absolute http or https URL")
+	}
+	if len(in.EventTypes) == 0 {
+		return problem.BadRequest(problem.CodeValidationFailed, "event_types must name at least one event type")
+	}
+	for _, t := range in.EventTypes {
+		if !known(t) {
+			return problem.BadRequest(problem.CodeValidationFailed,
+				fmt.Sprintf("Unknown event type %q; webhooks can subscribe to %s", t, strings.Join(webhooks.EventTypes, ", ")))
+		}
+	}
+	if in.Secret != "" && len(in.Secret) < minSecretLength {
+		return problem.BadRequest(problem.CodeValidationFailed, fmt.Sprintf("secret must be at least %d characters", minSecretLength))
+	}
+	return nil
+}
+
+func known(eventType string) bool {
+	for _, t := range webhooks.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// newSecret returns 192 random bits, prefixed so a leaked one is easy to
+// recognise.
+func newSecret() (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(raw), nil
+}
+
+// WebhookService manages webhook subscriptions and their deliveries. All of
+// it is for admins only.
+type WebhookService struct {
+	store webhooks.Store
+}
+
+func NewWebhookService(store webhooks.Store) *WebhookService {
+	return &WebhookService{store: store}
+}
+
+func admin(ctx context.Context) error {
+	if !auth.CallerOf(ctx).Admin {
+		return problem.Forbidden(problem.CodeForbidden, "Only admins can manage webhooks")
+	}
+	return nil
+}
+
+func webhookNotFound() error {
+	return problem.NotFound(problem.CodeWebhookNotFound, "Webhook not found")
+}
+
+// List returns one page of webhooks.
+func (s *WebhookService) List(ctx context.Context, page paging.Page) ([]webhooks.Webhook, error) {
+	if err := admin(ctx); err != nil {
+		return nil, err
+	}
+	hooks, err := s.store.ListWebhooks(ctx, page)
+	if hooks == nil {
+		hooks = []webhooks.Webhook{}
+	}
+	return hooks, err
+}
+
+func (s *WebhookService) Create(ctx context.Context, in WebhookInput) (webhooks.Webhook, error) {
+	if err := admin(ctx); err != nil {
+		return webhooks.Webhook{}, err
+	}
+	if err := in.validate(); err != nil {
+		return webhooks.Webhook{}, err
+	}
+	w := webhooks.Webhook{URL: in.URL, EventTypes: in.EventTypes, Secret: in.Secret, Active: in.Active == nil || *in.Active}
+	if w.Secret == "" {
+		secret, err := newSecret()
+		if err != nil {
+			return webhooks.Webhook{}, err
+		}
+		w.Secret = secret
+	}
+	err := s.store.CreateWebhook(ctx, &w)
+	return w, err
+}
+
+func (s *WebhookService) Get(ctx context.Context, id int) (webhooks.Webhook, error) {
+	if err := admin(ctx); err != nil {
+		return webhooks.Webhook{}, err
+	}
+	w, err := s.store.GetWebhook(ctx, id)
+	if errors.Is(err, webhooks.ErrNotFound) {
+		return webhooks.Webhook{}, webhookNotFound()
+	}
+	return w, err
+}
+
+// Update replaces the webhook's URL and event types, and its secret and
+// active flag when given.
+func (s *WebhookService) Update(ctx context.Context, id int, in WebhookInput) (webhooks.Webhook, error) {
+	w, err := s.Get(ctx, id)
+	if err != nil {
+		return webhooks.Webhook{}, err
+	}
+	if err := in.validate(); err != nil {
+		return webhooks.Webhook{}, err
+	}
+	w.URL, w.EventTypes = in.URL, in.EventTypes
+	if in.Secret != "" {
+		w.Secret = in.Secret
+	}
+	if in.Active != nil {
+		w.Active = *in.Active
+	}
+	err = s.store.UpdateWebhook(ctx, &w)
+	if errors.Is(err, webhooks.ErrNotFound) {
+		return webhooks.Webhook{}, webhookNotFound()
+	}
+	return w, err
+}
+
+// Delete removes the webhook together with its deliveries.
+func (s *WebhookService) Delete(ctx context.Context, id int) error {
+	if err := admin(ctx); err != nil {
+		return err
+	}
+	err := s.store.DeleteWebhook(ctx, id)
+	if errors.Is(err, webhooks.ErrNotFound) {
+		return webhookNotFound()
+	}
+	return err
+}
+
+// Deliveries returns one page of the webhook's deliveries, newest first,
+// optionally only those with status.
+func (s *WebhookService) Deliveries(ctx context.Context, id int, status string, page paging.Page) ([]webhooks.Delivery, error) {
+	if _, err := s.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	switch status {
+	case "", webhooks.StatusPending, webhooks.StatusDelivered, webhooks.StatusDead:
+	default:
+		return nil, problem.BadRequest(problem.CodeInvalidQuery, "status must be pending, delivered or dead")
+	}
+	return s.list(ctx, id, status, page)
+}
+
+// DeadLetters returns one page of the deliveries of every webhook that ran
+// out of attempts, newest first.
+func (s *WebhookService) DeadLetters(ctx context.Context, page paging.Page) ([]webhooks.Delivery, error) {
+	if err := admin(ctx); err != nil {
+		return nil, err
+	}
+	return s.list(ctx, 0, webhooks.StatusDead, page)
+}
+
+func (s *WebhookService) list(ctx context.Context, id int, status string, page paging.Page) ([]webhooks.Delivery, error) {
+	deliveries, err := s.store.ListDeliveries(ctx, id, status, page)
+	if deliveries == nil {
+		deliveries = []webhooks.Delivery{}
+	}
+	return deliveries, err
+}
+
+// Redeliver queues a delivery of the webhook to be sent again straight
+// away, with a fresh set of attempts, whatever its status.
+func (s *WebhookService) Redeliver(ctx context.Context, id int, deliveryID int64) (webhooks.Delivery, error) {
+	if err := admin(ctx); err != nil {
+		return webhooks.Delivery{}, err
+	}
+	d, err := s.store.Redeliver(ctx, id, deliveryID, time.Now())
+	if errors.Is(err, webhooks.ErrNotFound) {
+		return webhooks.Delivery{}, problem.NotFound(problem.CodeDeliveryNotFound, "Delivery not found")
+	}
+	return d, err
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"mopcare/bulk"
+)
+
+func (h *Handler) importUsers(c *gin.Context) {
+	opts, err := bulk.ParseOptions(c.Request.URL.Query())
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, bulk.MaxBytes)
+	report, err := h.users.Import(h.context(c), body, opts)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *Handler) exportUsers(c *gin.Context) {
+	format, err := bulk.ParseFormat(c.Query("format"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	deleted, err := h.includeDeleted(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	resp := bulk.NewResponse(c.Writer, format, "users")
+	exported(c, resp, h.users.Export(h.context(c), resp, format, deleted))
+}
+
+func (h *Handler) exportPayments(c *gin.Context) {
+	format, err := bulk.ParseFormat(c.Query("format"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	resp := bulk.NewResponse(c.Writer, format, "payments")
+	exported(c, resp, h.users.ExportPayments(h.context(c), resp, format))
+}
+
+// exported finishes an export. An error before the first row is answered
+// with a problem; after it the status is already sent, so the error is only
+// logged and the truncated download is left for the client to notice.
+func exported(c *gin.Context, resp *bulk.Response, err error) {
+	switch {
+	case err == nil:
+		resp.Close()
+	case !resp.Started():
+		writeError(c, err)
+	default:
+		log.Printf("%s %s: export ended early: %v", c.Request.Method, c.Request.URL.Path, err)
+		c.Abort()
+	}
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"mopcare/notify"
+	"mopcare/problem"
+	"mopcare/server"
+	"user-service/repository"
+)
+
+const importCSV = "First Name,Last Name,Email\nAda,Lovelace,ada@example.com\nGrace,Hopper,grace@example.com\n"
+
+func TestImportUsers(t *testing.T) {
+	invalid := "first_name,last_name,email\nAda,Lovelace,ada@example.com\n,Hopper,grace@example.com\n"
+	runCases(t, []testCase{
+		{name: "imports", method: "POST", path: "/users/import", body: importCSV, admin: true, status: 200, contains: `"imported":2`},
+		{name: "dry run", method: "POST", path: "/users/import?dry_run=true", body: importCSV, admin: true, status: 200, contains: `"imported":0`},
+		{name: "mapped columns", method: "POST", path: "/users/import?map=email:E-mail",
+			body: "first_name,last_name,E-mail\nAda,Lovelace,ada@example.com\n", admin: true, status: 200, contains: `"imported":1`},
+		{name: "atomic import with an invalid row", method: "POST", path: "/users/import", body: invalid, admin: true, status: 200,
+			contains: `"imported":0,"failed":1,"errors":[{"row":3,"field":"first_name","message":"first_name is required"}]`},
+		{name: "best effort import with an invalid row", method: "POST", path: "/users/import?mode=best_effort", body: invalid, admin: true,
+			status: 200, contains: `"imported":1,"failed":1`},
+		{name: "email taken", method: "POST", path: "/users/import", body: importCSV, admin: true,
+			setup: func(m *repository.Memory) { seedUser(m, "grace@example.com") }, status: 200, contains: `{"row":3,"field":"email","message":"User with this email already exists"}`},
+		{name: "email repeated", method: "POST", path: "/users/import", body: importCSV + "Ada,King,ada@example.com\n", admin: true,
+			status: 200, contains: `{"row":4,"field":"email","message":"Row 2 has the same email"}`},
+		{name: "missing column", method: "POST", path: "/users/import", body: "first_name,last_name\nAda,Lovelace\n", admin: true,
+			status: 400, code: problem.CodeValidationFailed},
+		{name: "empty body", method: "POST", path: "/users/import", admin: true, status: 400, code: problem.CodeInvalidBody},
+		{name: "unknown mode", method: "POST", path: "/users/import?mode=some", body: importCSV, admin: true, status: 400, code: problem.CodeInvalidQuery},
+		{name: "needs admin", method: "POST", path: "/users/import", body: importCSV, user: 1, status: 403, code: problem.CodeForbidden},
+		{name: "repository error", method: "POST", path: "/users/import", body: importCSV, admin: true, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
+
+func paid(m *repository.Memory) {
+	seedUser(m, "a@example.com")
+	m.AddPayment(context.Background(), 1, 49.5)
+}
+
+func TestExportUsers(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "csv", method: "GET", path: "/users/export", admin: true, setup: paid, status: 200,
+			contains: "id,first_name,last_name,email,total_amount_paid,state,city,created_at,deleted_at\n1,Margaret,Johnson,a@example.com,49.50,"},
+		{name: "json lines", method: "GET", path: "/users/export?format=jsonl", admin: true, setup: paid, status: 200, contains: `"email":"a@example.com"`},
+		{name: "no users", method: "GET", path: "/users/export", admin: true, status: 200, contains: "id,first_name"},
+		{name: "deleted", method: "GET", path: "/users/export?include_deleted=true", admin: true, setup: deletedUser, status: 200, contains: "Z\n"},
+		{name: "unknown format", method: "GET", path: "/users/export?format=xlsx", admin: true, status: 400, code: problem.CodeInvalidQuery},
+		{name: "needs admin", method: "GET", path: "/users/export", user: 1, status: 403, code: problem.CodeForbidden},
+		{name: "repository error", method: "GET", path: "/users/export", admin: true, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+
+		{name: "payments", method: "GET", path: "/payments/export", admin: true, setup: paid, status: 200,
+			contains: "id,user_id,email,amount,created_at\n1,1,a@example.com,49.50,"},
+		{name: "payments as json lines", method: "GET", path: "/payments/export?format=jsonl", admin: true, setup: paid, status: 200, contains: `"amount":49.5`},
+		{name: "payments need admin", method: "GET", path: "/payments/export", status: 403, code: problem.CodeForbidden},
+	})
+}
+
+func TestExportIsADownload(t *testing.T) {
+	router := newRouter(repository.NewMemory(), notify.NewMemory(), &server.Readiness{})
+	req := httptest.NewRequest("GET", "/users/export?format=jsonl", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	if rec.Code != 200 || rec.Body.Len() != 0 {
+		t.Fatalf("status = %d, body %q; want 200 and no rows", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cd := rec.Header().Get("Content-Disposition"); cd != `attachment; filename="users.jsonl"` {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+}
@@ -0,0 +1,217 @@
+// Package handler exposes the user-service over HTTP.
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"mopcare/auth"
+	"mopcare/paging"
+	"mopcare/problem"
+	"mopcare/server"
+	"user-service/repository"
+	"user-service/service"
+)
+
+type Handler struct {
+	users         *service.UserService
+	notifications *service.NotificationService
+	adminToken    string
+	tokenTTL      time.Duration
+}
+
+// NewRouter builds the user-service router, including /health and /ready.
+func NewRouter(users *service.UserService, notifications *service.NotificationService, ready *server.Readiness, cfg server.Config) *gin.Engine {
+	router := gin.Default()
+	router.SetTrustedProxies([]string{"127.0.0.1"})
+	router.NoRoute(routeNotFound)
+
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"service": "user-service", "status": "running"})
+	})
+	router.GET("/ready", func(c *gin.Context) {
+		if !ready.Ready() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"service": "user-service", "status": "draining"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"service": "user-service", "status": "ready"})
+	})
+
+	h := &Handler{users: users, notifications: notifications, adminToken: cfg.AdminToken, tokenTTL: cfg.UserTokenTTL}
+	router.GET("/users", h.getUsers)
+	router.GET("/users/:id", h.getUser)
+	router.POST("/users/import", h.importUsers)
+	router.GET("/users/export", h.exportUsers)
+	router.GET("/payments/export", h.exportPayments)
+	router.POST("/users", h.createUser)
+	router.DELETE("/users/:id", h.deleteUser)
+	router.POST("/users/:id/restore", h.restoreUser)
+	router.POST("/users/:id/token", h.issueToken)
+	router.GET("/users/:id/profile", h.getUserProfile)
+	router.PUT("/users/:id/payment", h.updateUserPayment)
+	router.GET("/users/:id/notification-preferences", h.getPreferences)
+	router.PUT("/users/:id/notification-preferences", h.updatePreferences)
+	router.GET("/users/:id/notifications", h.getNotifications)
+	// Mail clients may follow the link or, for one-click unsubscribe, post
+	// to it.
+	router.GET("/notifications/unsubscribe", h.unsubscribe)
+	router.POST("/notifications/unsubscribe", h.unsubscribe)
+	return router
+}
+
+// context returns the request context carrying the caller its bearer token
+// identifies.
+func (h *Handler) context(c *gin.Context) context.Context {
+	caller := auth.Identify(c.GetHeader("Authorization"), h.adminToken, time.Now())
+	return auth.WithCaller(c.Request.Context(), caller)
+}
+
+// paramID parses the :id path parameter, writing a 400 problem on failure.
+func paramID(c *gin.Context, detail string) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		writeError(c, problem.BadRequest(problem.CodeInvalidID, detail))
+		return 0, false
+	}
+	return id, true
+}
+
+// includeDeleted parses ?include_deleted=, which only admins may set.
+func (h *Handler) includeDeleted(c *gin.Context) (bool, error) {
+	return auth.IncludeDeleted(c.Query("include_deleted"), c.GetHeader("Authorization"), h.adminToken)
+}
+
+func (h *Handler) getUsers(c *gin.Context) {
+	page, err := paging.Parse(c.Query("limit"), c.Query("offset"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	deleted, err := h.includeDeleted(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	users, err := h.users.List(c.Request.Context(), page, deleted)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+func (h *Handler) getUser(c *gin.Context) {
+	id, ok := paramID(c, "Invalid user ID")
+	if !ok {
+		return
+	}
+	deleted, err := h.includeDeleted(c)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	user, err := h.users.Get(c.Request.Context(), id, deleted)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *Handler) createUser(c *gin.Context) {
+	var user repository.User
+	if err := c.ShouldBindJSON(&user); err != nil {
+		writeError(c, problem.BadRequest(problem.CodeInvalidBody, "Invalid request body"))
+		return
+	}
+	if err := h.users.Create(c.Request.Context(), &user); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, user)
+}
+
+func (h *Handler) deleteUser(c *gin.Context) {
+	id, ok := paramID(c, "Invalid user ID")
+	if !ok {
+		return
+	}
+	if err := h.users.Delete(c.Request.Context(), id); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+}
+
+// restoreUser undoes an admin's deletion of a user, so only admins may.
+func (h *Handler) restoreUser(c *gin.Context) {
+	if !auth.IsAdmin(c.GetHeader("Authorization"), h.adminToken) {
+		writeError(c, problem.Forbidden(problem.CodeForbidden, "Only admins can restore users"))
+		return
+	}
+	id, ok := paramID(c, "Invalid user ID")
+	if !ok {
+		return
+	}
+	user, err := h.users.Restore(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// issueToken hands an admin a token that identifies the user to the other
+// services, for instance as the instructor of a course.
+func (h *Handler) issueToken(c *gin.Context) {
+	if !auth.IsAdmin(c.GetHeader("Authorization"), h.adminToken) {
+		writeError(c, problem.Forbidden(problem.CodeForbidden, "Only admins can issue user tokens"))
+		return
+	}
+	id, ok := paramID(c, "Invalid user ID")
+	if !ok {
+		return
+	}
+	token, err := h.users.IssueToken(c.Request.Context(), id, h.adminToken, time.Now().Add(h.tokenTTL))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, token)
+}
+
+func (h *Handler) getUserProfile(c *gin.Context) {
+	id, ok := paramID(c, "Invalid user ID")
+	if !ok {
+		return
+	}
+	profile, err := h.users.Profile(c.Request.Context(), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, profile)
+}
+
+func (h *Handler) updateUserPayment(c *gin.Context) {
+	id, ok := paramID(c, "Invalid user ID")
+	if !ok {
+		return
+	}
+	var payment struct {
+		Amount float64 `json:"amount"`
+	}
+	if err := c.ShouldBindJSON(&payment); err != nil {
+		writeError(c, problem.BadRequest(problem.CodeInvalidBody, "Invalid request body"))
+		return
+	}
+	if err := h.users.RecordPayment(c.Request.Context(), id, payment.Amount); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Payment updated successfully"})
+}
@@ -0,0 +1,321 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"mopcare/auth"
+	"mopcare/events"
+	"mopcare/notify"
+	"mopcare/problem"
+	"mopcare/server"
+	"user-service/repository"
+	"user-service/service"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+	os.Exit(m.Run())
+}
+
+type testCase struct {
+	name     string
+	method   string
+	path     string
+	body     string
+	setup    func(*repository.Memory)
+	notes    func(*notify.Memory)
+	admin    bool // send the admin token
+	user     int  // send a token for this user
+	status   int
+	code     string // expected problem code; empty for success responses
+	contains string // substring expected in a success body
+}
+
+func seedUser(m *repository.Memory, email string) {
+	m.Create(context.Background(), &repository.User{FirstName: "Margaret", LastName: "Johnson", Email: email})
+}
+
+// deletedUser seeds user 1 and soft-deletes them.
+func deletedUser(m *repository.Memory) {
+	seedUser(m, "a@example.com")
+	m.Delete(context.Background(), 1)
+}
+
+const adminToken = "admin-secret"
+
+var links = notify.NewLinks("http://localhost:9090", adminToken)
+
+// newRouter serves repo and notes the way main does.
+func newRouter(repo *repository.Memory, notes *notify.Memory, ready *server.Readiness) *gin.Engine {
+	return NewRouter(service.NewUserService(repo), service.NewNotificationService(notes, links), ready,
+		server.Config{AdminToken: adminToken, UserTokenTTL: time.Hour})
+}
+
+func failWith(err error) func(*repository.Memory) {
+	return func(m *repository.Memory) { m.Err = err }
+}
+
+func runCases(t *testing.T, cases []testCase) {
+	t.Helper()
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := repository.NewMemory()
+			if tc.setup != nil {
+				tc.setup(repo)
+			}
+			notes := notify.NewMemory()
+			if tc.notes != nil {
+				tc.notes(notes)
+			}
+			router := newRouter(repo, notes, &server.Readiness{})
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.admin {
+				req.Header.Set("Authorization", "Bearer "+adminToken)
+			}
+			if tc.user != 0 {
+				req.Header.Set("Authorization", "Bearer "+auth.UserToken(tc.user, time.Now().Add(time.Hour), adminToken))
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.status {
+				t.Fatalf("status = %d, want %d (body %s)", rec.Code, tc.status, rec.Body)
+			}
+			if tc.code != "" {
+				if ct := rec.Header().Get("Content-Type"); ct != problem.ContentType {
+					t.Errorf("Content-Type = %q, want %q", ct, problem.ContentType)
+				}
+				var p problem.Problem
+				if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+					t.Fatalf("decoding problem: %v", err)
+				}
+				if p.Code != tc.code {
+					t.Errorf("code = %q, want %q", p.Code, tc.code)
+				}
+			}
+			if tc.contains != "" && !strings.Contains(rec.Body.String(), tc.contains) {
+				t.Errorf("body %s does not contain %q", rec.Body, tc.contains)
+			}
+		})
+	}
+}
+
+var errDB = errors.New("connection reset")
+
+func TestListUsers(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "lists users", method: "GET", path: "/users", setup: func(m *repository.Memory) { seedUser(m, "a@example.com") }, status: 200, contains: `"email":"a@example.com"`},
+		{name: "empty", method: "GET", path: "/users", status: 404, code: problem.CodeUserNotFound},
+		{name: "second page", method: "GET", path: "/users?limit=1&offset=1", setup: func(m *repository.Memory) {
+			seedUser(m, "a@example.com")
+			seedUser(m, "b@example.com")
+		}, status: 200, contains: `[{"id":2,`},
+		{name: "past the last page", method: "GET", path: "/users?offset=5", setup: func(m *repository.Memory) { seedUser(m, "a@example.com") }, status: 200, contains: `[]`},
+		{name: "invalid limit", method: "GET", path: "/users?limit=0", status: 400, code: problem.CodeInvalidQuery},
+		{name: "deleted are hidden", method: "GET", path: "/users", setup: deletedUser, status: 404, code: problem.CodeUserNotFound},
+		{name: "deleted are listed for admins", method: "GET", path: "/users?include_deleted=true", admin: true, setup: deletedUser, status: 200, contains: `"deleted_at":`},
+		{name: "include deleted needs admin", method: "GET", path: "/users?include_deleted=true", setup: deletedUser, status: 403, code: problem.CodeForbidden},
+		{name: "repository error", method: "GET", path: "/users", setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
+
+func TestGetUser(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "found", method: "GET", path: "/users/1", setup: func(m *repository.Memory) { seedUser(m, "a@example.com") }, status: 200, contains: `"id":1`},
+		{name: "invalid id", method: "GET", path: "/users/abc", status: 400, code: problem.CodeInvalidID},
+		{name: "missing", method: "GET", path: "/users/9", status: 404, code: problem.CodeUserNotFound},
+		{name: "deleted", method: "GET", path: "/users/1", setup: deletedUser, status: 404, code: problem.CodeUserNotFound},
+		{name: "deleted for admins", method: "GET", path: "/users/1?include_deleted=true", admin: true, setup: deletedUser, status: 200, contains: `"deleted_at":`},
+		{name: "repository error", method: "GET", path: "/users/1", setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
+
+func TestCreateUser(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "created", method: "POST", path: "/users", body: `{"first_name":"Ada","last_name":"Lovelace","email":"ada@example.com"}`, status: 201, contains: `"id":1`},
+		{name: "malformed body", method: "POST", path: "/users", body: `{`, status: 400, code: problem.CodeInvalidBody},
+		{name: "missing fields", method: "POST", path: "/users", body: `{"first_name":"Ada"}`, status: 400, code: problem.CodeValidationFailed},
+		{name: "email taken", method: "POST", path: "/users", body: `{"first_name":"Ada","last_name":"Lovelace","email":"a@example.com"}`, setup: func(m *repository.Memory) { seedUser(m, "a@example.com") }, status: 409, code: problem.CodeUserEmailTaken},
+		{name: "repository error", method: "POST", path: "/users", body: `{"first_name":"Ada","last_name":"Lovelace","email":"ada@example.com"}`, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
+
+func TestDeleteUser(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "deleted", method: "DELETE", path: "/users/1", setup: func(m *repository.Memory) { seedUser(m, "a@example.com") }, status: 200, contains: "deleted"},
+		{name: "invalid id", method: "DELETE", path: "/users/x", status: 400, code: problem.CodeInvalidID},
+		{name: "missing", method: "DELETE", path: "/users/9", status: 404, code: problem.CodeUserNotFound},
+		{name: "already deleted", method: "DELETE", path: "/users/1", setup: deletedUser, status: 404, code: problem.CodeUserNotFound},
+		{name: "repository error", method: "DELETE", path: "/users/1", setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
+
+func TestRestoreUser(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "restored", method: "POST", path: "/users/1/restore", admin: true, setup: deletedUser, status: 200, contains: `"email":"a@example.com"`},
+		{name: "not deleted", method: "POST", path: "/users/1/restore", admin: true, setup: func(m *repository.Memory) { seedUser(m, "a@example.com") }, status: 200, contains: `"id":1`},
+		{name: "needs admin", method: "POST", path: "/users/1/restore", setup: deletedUser, status: 403, code: problem.CodeForbidden},
+		{name: "not by the user", method: "POST", path: "/users/1/restore", user: 1, setup: deletedUser, status: 403, code: problem.CodeForbidden},
+		{name: "invalid id", method: "POST", path: "/users/x/restore", admin: true, status: 400, code: problem.CodeInvalidID},
+		{name: "missing", method: "POST", path: "/users/9/restore", admin: true, status: 404, code: problem.CodeUserNotFound},
+		{name: "repository error", method: "POST", path: "/users/1/restore", admin: true, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
+
+func TestDeleteKeepsHistoryUntilPurged(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemory()
+	seedUser(repo, "a@example.com")
+	repo.AddEnrollment(1, "completed")
+	svc := service.NewUserService(repo)
+
+	if err := svc.Delete(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := svc.PurgeDeleted(ctx, time.Hour); err != nil || n != 0 {
+		t.Fatalf("PurgeDeleted within retention = %d, %v", n, err)
+	}
+	if _, err := svc.Restore(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := svc.Profile(ctx, 1); err != nil || p.CompletedCoursesCount != 1 {
+		t.Fatalf("profile after restore = %+v, %v", p, err)
+	}
+
+	svc.Delete(ctx, 1)
+	repo.Backdate(1, 2*time.Hour)
+	if n, err := svc.PurgeDeleted(ctx, time.Hour); err != nil || n != 1 {
+		t.Fatalf("PurgeDeleted past retention = %d, %v", n, err)
+	}
+	if _, err := svc.Restore(ctx, 1); err == nil {
+		t.Fatal("purged user could be restored")
+	}
+}
+
+func TestIssueToken(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "issued", method: "POST", path: "/users/1/token", admin: true, setup: func(m *repository.Memory) { seedUser(m, "a@example.com") },
+			status: 201, contains: `"user_id":1,"token":"user.1.`},
+		{name: "needs admin", method: "POST", path: "/users/1/token", setup: func(m *repository.Memory) { seedUser(m, "a@example.com") },
+			status: 403, code: problem.CodeForbidden},
+		{name: "invalid id", method: "POST", path: "/users/x/token", admin: true, status: 400, code: problem.CodeInvalidID},
+		{name: "deleted", method: "POST", path: "/users/1/token", admin: true, setup: deletedUser, status: 404, code: problem.CodeUserNotFound},
+	})
+
+	repo := repository.NewMemory()
+	seedUser(repo, "a@example.com")
+	token, err := service.NewUserService(repo).IssueToken(context.Background(), 1, adminToken, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caller := auth.Identify("Bearer "+token.Token, adminToken, time.Now()); caller.UserID != 1 {
+		t.Fatalf("token identifies %+v, want user 1", caller)
+	}
+}
+
+func TestUserProfile(t *testing.T) {
+	withEnrollments := func(m *repository.Memory) {
+		seedUser(m, "a@example.com")
+		m.AddEnrollment(1, "enrolled")
+		m.AddEnrollment(1, "completed")
+	}
+	runCases(t, []testCase{
+		{name: "counts enrollments", method: "GET", path: "/users/1/profile", setup: withEnrollments, status: 200, contains: `"enrolled_courses_count":2,"completed_courses_count":1`},
+		{name: "waitlisted enrollments do not count", method: "GET", path: "/users/1/profile",
+			setup:  func(m *repository.Memory) { withEnrollments(m); m.AddEnrollment(1, "waitlisted") },
+			status: 200, contains: `"enrolled_courses_count":2,"completed_courses_count":1`},
+		{name: "invalid id", method: "GET", path: "/users/x/profile", status: 400, code: problem.CodeInvalidID},
+		{name: "missing", method: "GET", path: "/users/9/profile", status: 404, code: problem.CodeUserNotFound},
+		{name: "repository error", method: "GET", path: "/users/1/profile", setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
+
+func TestUpdateUserPayment(t *testing.T) {
+	seeded := func(m *repository.Memory) { seedUser(m, "a@example.com") }
+	runCases(t, []testCase{
+		{name: "recorded", method: "PUT", path: "/users/1/payment", body: `{"amount":25.5}`, setup: seeded, status: 200, contains: "Payment updated"},
+		{name: "invalid id", method: "PUT", path: "/users/x/payment", body: `{"amount":1}`, status: 400, code: problem.CodeInvalidID},
+		{name: "malformed body", method: "PUT", path: "/users/1/payment", body: `nope`, setup: seeded, status: 400, code: problem.CodeInvalidBody},
+		{name: "non-positive amount", method: "PUT", path: "/users/1/payment", body: `{"amount":0}`, setup: seeded, status: 400, code: problem.CodeValidationFailed},
+		{name: "missing", method: "PUT", path: "/users/9/payment", body: `{"amount":1}`, status: 404, code: problem.CodeUserNotFound},
+		{name: "deleted", method: "PUT", path: "/users/1/payment", body: `{"amount":1}`, setup: deletedUser, status: 404, code: problem.CodeUserNotFound},
+		{name: "repository error", method: "PUT", path: "/users/1/payment", body: `{"amount":1}`, setup: failWith(errDB), status: 500, code: problem.CodeInternal},
+	})
+}
+
+func TestPaymentAccumulates(t *testing.T) {
+	repo := repository.NewMemory()
+	seedUser(repo, "a@example.com")
+	router := newRouter(repo, notify.NewMemory(), &server.Readiness{})
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("PUT", "/users/1/payment", strings.NewReader(`{"amount":10}`))
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	user, _ := repo.Get(context.Background(), 1, false)
+	if user.TotalAmountPaid != 20 {
+		t.Errorf("total_amount_paid = %v, want 20", user.TotalAmountPaid)
+	}
+}
+
+func TestChangesRecordEvents(t *testing.T) {
+	repo := repository.NewMemory()
+	router := newRouter(repo, notify.NewMemory(), &server.Readiness{})
+	for _, req := range []struct{ method, path, body string }{
+		{"POST", "/users", `{"first_name":"Margaret","last_name":"Johnson","email":"a@example.com"}`},
+		{"POST", "/users", `{"first_name":"Margaret","last_name":"Johnson","email":"a@example.com"}`},
+		{"PUT", "/users/1/payment", `{"amount":10}`},
+		{"PUT", "/users/9/payment", `{"amount":10}`},
+	} {
+		r := httptest.NewRequest(req.method, req.path, strings.NewReader(req.body))
+		r.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	want := []events.Payload{
+		events.UserCreated{UserID: 1, Email: "a@example.com", FirstName: "Margaret", LastName: "Johnson"},
+		events.PaymentRecorded{UserID: 1, Amount: 10, TotalAmountPaid: 10},
+	}
+	if got := repo.Events(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %+v, want %+v (failed changes record nothing)", got, want)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	ready := &server.Readiness{}
+	router := newRouter(repository.NewMemory(), notify.NewMemory(), ready)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ready status = %d, want 200", rec.Code)
+	}
+
+	ready.Drain()
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest("GET", "/ready", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("draining status = %d, want 503", rec.Code)
+	}
+}
+
+func TestUnknownRoute(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "unknown route", method: "GET", path: "/nope", status: 404, code: problem.CodeRouteNotFound},
+	})
+}
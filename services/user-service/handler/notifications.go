@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"mopcare/paging"
+	"mopcare/problem"
+	"user-service/service"
+)
+
+func (h *Handler) getPreferences(c *gin.Context) {
+	id, ok := paramID(c, "Invalid user ID")
+	if !ok {
+		return
+	}
+	p, err := h.notifications.Preferences(h.context(c), id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+func (h *Handler) updatePreferences(c *gin.Context) {
+	id, ok := paramID(c, "Invalid user ID")
+	if !ok {
+		return
+	}
+	var in service.PreferencesInput
+	if err := c.ShouldBindJSON(&in); err != nil {
+		writeError(c, problem.BadRequest(problem.CodeInvalidBody, "Invalid request body"))
+		return
+	}
+	p, err := h.notifications.UpdatePreferences(h.context(c), id, in)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+func (h *Handler) getNotifications(c *gin.Context) {
+	id, ok := paramID(c, "Invalid user ID")
+	if !ok {
+		return
+	}
+	page, err := paging.Parse(c.Query("limit"), c.Query("offset"))
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	notifications, err := h.notifications.Notifications(h.context(c), id, page)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, notifications)
+}
+
+func (h *Handler) unsubscribe(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		writeError(c, problem.BadRequest(problem.CodeInvalidQuery, "token is required"))
+		return
+	}
+	if _, err := h.notifications.Unsubscribe(c.Request.Context(), token); err != nil {
+		writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "You have been unsubscribed"})
+}
@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"mopcare/events"
+	"mopcare/notify"
+	"mopcare/problem"
+	"mopcare/server"
+	"user-service/repository"
+)
+
+// noted registers user 1 with the notification store.
+func noted(m *notify.Memory) {
+	m.AddUser(1, "a@example.com", "Margaret", "Johnson")
+}
+
+// notified is noted, with one email queued for user 1.
+func notified(m *notify.Memory) {
+	noted(m)
+	m.Enqueue(context.Background(), &notify.Notification{UserID: 1, EventSource: "user-service", EventID: 7,
+		EventType: events.TypeUserCreated, Recipient: "a@example.com", Subject: "Welcome to Mopcare"})
+}
+
+func notesFailWith(err error) func(*notify.Memory) {
+	return func(m *notify.Memory) {
+		noted(m)
+		m.Err = err
+	}
+}
+
+func TestNotificationPreferences(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "defaults", method: "GET", path: "/users/1/notification-preferences", notes: noted, user: 1, status: 200, contains: `"email_enabled":true`},
+		{name: "admin", method: "GET", path: "/users/1/notification-preferences", notes: noted, admin: true, status: 200, contains: `"locale":"en"`},
+		{name: "anonymous", method: "GET", path: "/users/1/notification-preferences", notes: noted, status: 401, code: problem.CodeUnauthorized},
+		{name: "another user", method: "GET", path: "/users/1/notification-preferences", notes: noted, user: 2, status: 403, code: problem.CodeForbidden},
+		{name: "unknown user", method: "GET", path: "/users/9/notification-preferences", notes: noted, admin: true, status: 404, code: problem.CodeUserNotFound},
+		{name: "invalid id", method: "GET", path: "/users/abc/notification-preferences", admin: true, status: 400, code: problem.CodeInvalidID},
+		{name: "store error", method: "GET", path: "/users/1/notification-preferences", notes: notesFailWith(errDB), admin: true, status: 500, code: problem.CodeInternal},
+
+		{name: "update", method: "PUT", path: "/users/1/notification-preferences", body: `{"locale":"es","muted":["payment.recorded"]}`,
+			notes: noted, user: 1, status: 200, contains: `"muted":["payment.recorded"]`},
+		{name: "update keeps what is left out", method: "PUT", path: "/users/1/notification-preferences", body: `{"locale":"es"}`,
+			notes: noted, user: 1, status: 200, contains: `"email_enabled":true`},
+		{name: "turn email off", method: "PUT", path: "/users/1/notification-preferences", body: `{"email_enabled":false}`,
+			notes: noted, user: 1, status: 200, contains: `"email_enabled":false`},
+		{name: "unknown locale", method: "PUT", path: "/users/1/notification-preferences", body: `{"locale":"xx"}`,
+			notes: noted, user: 1, status: 400, code: problem.CodeValidationFailed},
+		{name: "unknown event type", method: "PUT", path: "/users/1/notification-preferences", body: `{"muted":["course.published"]}`,
+			notes: noted, user: 1, status: 400, code: problem.CodeValidationFailed},
+		{name: "update another user", method: "PUT", path: "/users/1/notification-preferences", body: `{}`,
+			notes: noted, user: 2, status: 403, code: problem.CodeForbidden},
+		{name: "invalid body", method: "PUT", path: "/users/1/notification-preferences", body: `{`,
+			notes: noted, user: 1, status: 400, code: problem.CodeInvalidBody},
+	})
+}
+
+func TestNotifications(t *testing.T) {
+	runCases(t, []testCase{
+		{name: "list", method: "GET", path: "/users/1/notifications", notes: notified, user: 1, status: 200, contains: `"subject":"Welcome to Mopcare"`},
+		{name: "empty", method: "GET", path: "/users/1/notifications", notes: noted, user: 1, status: 200, contains: `[]`},
+		{name: "another user", method: "GET", path: "/users/1/notifications", notes: notified, user: 2, status: 403, code: problem.CodeForbidden},
+		{name: "unknown user", method: "GET", path: "/users/9/notifications", notes: noted, admin: true, status: 404, code: problem.CodeUserNotFound},
+		{name: "invalid paging", method: "GET", path: "/users/1/notifications?limit=0", notes: noted, user: 1, status: 400, code: problem.CodeInvalidQuery},
+	})
+}
+
+func TestUnsubscribe(t *testing.T) {
+	one := url.QueryEscape(links.Token(1, events.TypeEnrollmentCreated))
+	all := url.QueryEscape(links.Token(1, notify.ScopeAll))
+	runCases(t, []testCase{
+		{name: "one event type", method: "GET", path: "/notifications/unsubscribe?token=" + one, notes: noted, status: 200, contains: "unsubscribed"},
+		{name: "one-click post", method: "POST", path: "/notifications/unsubscribe?token=" + all, notes: noted, status: 200, contains: "unsubscribed"},
+		{name: "forged", method: "GET", path: "/notifications/unsubscribe?token=1.all.forged", notes: noted, status: 403, code: problem.CodeSignatureInvalid},
+		{name: "missing token", method: "GET", path: "/notifications/unsubscribe", status: 400, code: problem.CodeInvalidQuery},
+		{name: "deleted user", method: "GET", path: "/notifications/unsubscribe?token=" + one, status: 404, code: problem.CodeUserNotFound},
+	})
+}
+
+// TestUnsubscribeLinks follows the links of an email and checks the user
+// stops getting what they unsubscribed from, and only that.
+func TestUnsubscribeLinks(t *testing.T) {
+	notes := notify.NewMemory()
+	noted(notes)
+	router := newRouter(repository.NewMemory(), notes, &server.Readiness{})
+	follow := func(link string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", strings.TrimPrefix(link, "http://localhost:9090"), nil))
+		if rec.Code != 200 {
+			t.Fatalf("following %s: status = %d (body %s)", link, rec.Code, rec.Body)
+		}
+	}
+
+	follow(links.Unsubscribe(1, events.TypeEnrollmentCreated))
+	follow(links.Unsubscribe(1, events.TypeEnrollmentCreated))
+	p, _ := notes.Preferences(context.Background(), 1)
+	if p.Wants(events.TypeEnrollmentCreated) || !p.Wants(events.TypePaymentRecorded) || len(p.Muted) != 1 {
+		t.Errorf("after unsubscribing from one type twice: %+v", p)
+	}
+
+	follow(links.Unsubscribe(1, notify.ScopeAll))
+	if p, _ := notes.Preferences(context.Background(), 1); p.Wants(events.TypePaymentRecorded) {
+		t.Errorf("after unsubscribing from all: %+v", p)
+	}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"testing"
+
+	"mopcare/bulk"
+	"mopcare/notify"
+	"mopcare/openapi"
+	"mopcare/server"
+	"user-service/repository"
+	"user-service/service"
+)
+
+// TestRoutesAreDocumented keeps the OpenAPI document in step with the router.
+func TestRoutesAreDocumented(t *testing.T) {
+	spec := openapi.MustLoad()
+	router := newRouter(repository.NewMemory(), notify.NewMemory(), &server.Readiness{})
+	for _, route := range router.Routes() {
+		path := openapi.PathTemplate(route.Path)
+		if _, ok := spec.Operation(route.Method, path); !ok {
+			t.Errorf("%s %s is not in the OpenAPI document", route.Method, path)
+		}
+	}
+}
+
+func TestSchemasMatchTypes(t *testing.T) {
+	spec := openapi.MustLoad()
+	for name, v := range map[string]interface{}{
+		"User":        repository.User{},
+		"UserProfile": service.Profile{},
+		"UserToken":   service.Token{},
+
+		"NotificationPreferences": notify.Preferences{},
+		"PreferencesInput":        service.PreferencesInput{},
+		"Notification":            notify.Notification{},
+
+		"Payment":      repository.Payment{},
+		"ImportReport": bulk.Report{},
+		"ImportError":  bulk.RowError{},
+	} {
+		onlySchema, onlyStruct := spec.SchemaFieldDiff(name, v)
+		if len(onlySchema) > 0 || len(onlyStruct) > 0 {
+			t.Errorf("%s: only in document %v, only in Go type %v", name, onlySchema, onlyStruct)
+		}
+	}
+}
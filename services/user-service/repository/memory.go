@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"mopcare/events"
+	"mopcare/paging"
+	"mopcare/problem"
+)
+
+// Memory is an in-memory UserRepository for tests. Setting Err makes every
+// method fail with it, to exercise error paths.
+type Memory struct {
+	mu          sync.Mutex
+	nextID      int
+	users       map[int]User
+	enrollments map[int][]string // user ID -> enrollment statuses
+	payments    []Payment
+	events      []events.Payload
+
+	Err error
+}
+
+func NewMemory() *Memory {
+	return &Memory{nextID: 1, users: map[int]User{}, enrollments: map[int][]string{}}
+}
+
+// AddEnrollment records an enrollment owned by the enrollment-service so
+// profile counts can be tested.
+func (m *Memory) AddEnrollment(userID int, status string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enrollments[userID] = append(m.enrollments[userID], status)
+}
+
+// Events returns the events recorded so far, as Postgres would have written
+// them to the outbox.
+func (m *Memory) Events() []events.Payload {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]events.Payload(nil), m.events...)
+}
+
+func (m *Memory) List(ctx context.Context, page paging.Page, includeDeleted bool) ([]User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var users []User
+	for _, u := range m.users {
+		if includeDeleted || u.DeletedAt == nil {
+			users = append(users, u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return paging.Slice(users, page), nil
+}
+
+func (m *Memory) Get(ctx context.Context, id int, includeDeleted bool) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return User{}, m.Err
+	}
+	u, ok := m.users[id]
+	if !ok || (u.DeletedAt != nil && !includeDeleted) {
+		return User{}, ErrNotFound
+	}
+	return u, nil
+}
+
+func (m *Memory) EmailExists(ctx context.Context, email string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return false, m.Err
+	}
+	return m.emailTaken(email), nil
+}
+
+func (m *Memory) emailTaken(email string) bool {
+	for _, u := range m.users {
+		if u.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Memory) Create(ctx context.Context, u *User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	return m.create(u)
+}
+
+func (m *Memory) CreateMany(ctx context.Context, users []*User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	// Checked up front, as nothing is created if the transaction fails.
+	emails := map[string]bool{}
+	for _, u := range users {
+		if m.emailTaken(u.Email) || emails[u.Email] {
+			return problem.Conflict(problem.CodeUserEmailTaken, "User with this email already exists")
+		}
+		emails[u.Email] = true
+	}
+	for _, u := range users {
+		m.create(u)
+	}
+	return nil
+}
+
+func (m *Memory) create(u *User) error {
+	// Mirrors the users_email_key constraint.
+	if m.emailTaken(u.Email) {
+		return problem.Conflict(problem.CodeUserEmailTaken, "User with this email already exists")
+	}
+	u.ID = m.nextID
+	u.CreatedAt = time.Now()
+	m.nextID++
+	m.users[u.ID] = *u
+	if u.TotalAmountPaid > 0 {
+		m.pay(u.ID, u.TotalAmountPaid)
+	}
+	m.events = append(m.events, events.UserCreated{UserID: u.ID, Email: u.Email, FirstName: u.FirstName, LastName: u.LastName})
+	return nil
+}
+
+func (m *Memory) pay(userID int, amount float64) {
+	m.payments = append(m.payments, Payment{ID: int64(len(m.payments) + 1), UserID: userID, Amount: amount, CreatedAt: time.Now()})
+}
+
+// EachUser copies the users first, so fn may call back into m.
+func (m *Memory) EachUser(ctx context.Context, includeDeleted bool, fn func(User) error) error {
+	users, err := m.List(ctx, paging.Page{}, includeDeleted)
+	if err != nil {
+		return err
+	}
+	for _, u := range users {
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Delete(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	u, ok := m.users[id]
+	if !ok || u.DeletedAt != nil {
+		return ErrNotFound
+	}
+	now := time.Now()
+	u.DeletedAt = &now
+	m.users[id] = u
+	return nil
+}
+
+func (m *Memory) Restore(ctx context.Context, id int) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return User{}, m.Err
+	}
+	u, ok := m.users[id]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	u.DeletedAt = nil
+	m.users[id] = u
+	return u, nil
+}
+
+func (m *Memory) Purge(ctx context.Context, before time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return 0, m.Err
+	}
+	var n int64
+	for id, u := range m.users {
+		if u.DeletedAt != nil && u.DeletedAt.Before(before) {
+			delete(m.users, id)
+			// Mirrors ON DELETE CASCADE on user_course_enrollments.user_id.
+			delete(m.enrollments, id)
+			n++
+		}
+	}
+	// And on payments.user_id.
+	payments := m.payments[:0]
+	for _, p := range m.payments {
+		if _, ok := m.users[p.UserID]; ok {
+			payments = append(payments, p)
+		}
+	}
+	m.payments = payments
+	return n, nil
+}
+
+// Backdate moves a deleted user's deletion time back by d, so tests can
+// exercise the purge retention without waiting.
+func (m *Memory) Backdate(id int, d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.users[id]; ok && u.DeletedAt != nil {
+		at := u.DeletedAt.Add(-d)
+		u.DeletedAt = &at
+		m.users[id] = u
+	}
+}
+
+func (m *Memory) AddPayment(ctx context.Context, id int, amount float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	u, ok := m.users[id]
+	if !ok || u.DeletedAt != nil {
+		return ErrNotFound
+	}
+	u.TotalAmountPaid += amount
+	m.users[id] = u
+	m.pay(id, amount)
+	m.events = append(m.events, events.PaymentRecorded{UserID: id, Amount: amount, TotalAmountPaid: u.TotalAmountPaid})
+	return nil
+}
+
+func (m *Memory) EnrollmentCounts(ctx context.Context, userID int) (int64, int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return 0, 0, m.Err
+	}
+	var enrolled, completed int64
+	for _, status := range m.enrollments[userID] {
+		// A waitlisted user holds no seat yet.
+		if status != "waitlisted" {
+			enrolled++
+		}
+		if status == "completed" {
+			completed++
+		}
+	}
+	return enrolled, completed, nil
+}
+
+func (m *Memory) EachPayment(ctx context.Context, fn func(Payment) error) error {
+	m.mu.Lock()
+	if m.Err != nil {
+		m.mu.Unlock()
+		return m.Err
+	}
+	payments := make([]Payment, len(m.payments))
+	for i, p := range m.payments {
+		p.Email = m.users[p.UserID].Email
+		payments[i] = p
+	}
+	m.mu.Unlock()
+	for _, p := range payments {
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"mopcare/database"
+	"mopcare/events"
+	"mopcare/paging"
+)
+
+// Postgres is the production UserRepository.
+type Postgres struct {
+	db *database.DB
+}
+
+func NewPostgres(db *database.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+func (p *Postgres) List(ctx context.Context, page paging.Page, includeDeleted bool) ([]User, error) {
+	rows, err := p.db.QueryContext(ctx,
+		"SELECT id, first_name, last_name, email, total_amount_paid, created_at, deleted_at FROM users WHERE ($1 OR deleted_at IS NULL) ORDER BY id LIMIT $2 OFFSET $3",
+		includeDeleted, page.LimitArg(), page.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var user User
+		if err := rows.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.TotalAmountPaid, &user.CreatedAt, &user.DeletedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+const userColumns = "id, first_name, last_name, email, total_amount_paid, COALESCE(state, ''), COALESCE(city, ''), created_at, deleted_at"
+
+func scanUser(row *database.Row) (User, error) {
+	var user User
+	err := row.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.TotalAmountPaid, &user.State, &user.City, &user.CreatedAt, &user.DeletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return User{}, ErrNotFound
+	}
+	return user, err
+}
+
+func (p *Postgres) Get(ctx context.Context, id int, includeDeleted bool) (User, error) {
+	return scanUser(p.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE id = $1 AND ($2 OR deleted_at IS NULL)", id, includeDeleted))
+}
+
+func (p *Postgres) EmailExists(ctx context.Context, email string) (bool, error) {
+	var exists bool
+	err := p.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)", email).Scan(&exists)
+	return exists, err
+}
+
+func (p *Postgres) Create(ctx context.Context, u *User) error {
+	return p.inTx(ctx, func(tx *database.Tx) error {
+		return create(ctx, tx, u)
+	})
+}
+
+func (p *Postgres) CreateMany(ctx context.Context, users []*User) error {
+	return p.inTx(ctx, func(tx *database.Tx) error {
+		for _, u := range users {
+			if err := create(ctx, tx, u); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// create also records an opening total_amount_paid as the user's first
+// payment.
+func create(ctx context.Context, tx *database.Tx, u *User) error {
+	err := tx.QueryRowContext(ctx,
+		"INSERT INTO users (first_name, last_name, email, total_amount_paid) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		u.FirstName, u.LastName, u.Email, u.TotalAmountPaid,
+	).Scan(&u.ID, &u.CreatedAt)
+	if err != nil {
+		return err
+	}
+	if u.TotalAmountPaid > 0 {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO payments (user_id, amount) VALUES ($1, $2)", u.ID, u.TotalAmountPaid); err != nil {
+			return err
+		}
+	}
+	return events.Record(ctx, tx, events.UserOutbox, events.UserCreated{UserID: u.ID, Email: u.Email, FirstName: u.FirstName, LastName: u.LastName})
+}
+
+func (p *Postgres) EachUser(ctx context.Context, includeDeleted bool, fn func(User) error) error {
+	rows, err := p.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users WHERE ($1 OR deleted_at IS NULL) ORDER BY id", includeDeleted)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var user User
+		if err := rows.Scan(&user.ID, &user.FirstName, &user.LastName, &user.Email, &user.TotalAmountPaid, &user.State, &user.City, &user.CreatedAt, &user.DeletedAt); err != nil {
+			return err
+		}
+		if err := fn(user); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (p *Postgres) Delete(ctx context.Context, id int) error {
+	result, err := p.db.ExecContext(ctx, "UPDATE users SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL", id)
+	if err != nil {
+		return err
+	}
+	return expectRow(result)
+}
+
+// Restore clears deleted_at; a user who is not deleted is returned as is.
+func (p *Postgres) Restore(ctx context.Context, id int) (User, error) {
+	user, err := scanUser(p.db.QueryRowContext(ctx,
+		"UPDATE users SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL RETURNING "+userColumns, id))
+	if errors.Is(err, ErrNotFound) {
+		return p.Get(ctx, id, false)
+	}
+	return user, err
+}
+
+func (p *Postgres) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result, err := p.db.ExecContext(ctx, "DELETE FROM users WHERE deleted_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+func (p *Postgres) AddPayment(ctx context.Context, id int, amount float64) error {
+	return p.inTx(ctx, func(tx *database.Tx) error {
+		var total float64
+		err := tx.QueryRowContext(ctx,
+			"UPDATE users SET total_amount_paid = total_amount_paid + $1 WHERE id = $2 AND deleted_at IS NULL RETURNING total_amount_paid",
+			amount, id,
+		).Scan(&total)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO payments (user_id, amount) VALUES ($1, $2)", id, amount); err != nil {
+			return err
+		}
+		return events.Record(ctx, tx, events.UserOutbox, events.PaymentRecorded{UserID: id, Amount: amount, TotalAmountPaid: total})
+	})
+}
+
+func (p *Postgres) EachPayment(ctx context.Context, fn func(Payment) error) error {
+	rows, err := p.db.QueryContext(ctx,
+		"SELECT p.id, p.user_id, u.email, p.amount, p.created_at FROM payments p JOIN users u ON u.id = p.user_id ORDER BY p.id")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var payment Payment
+		if err := rows.Scan(&payment.ID, &payment.UserID, &payment.Email, &payment.Amount, &payment.CreatedAt); err != nil {
+			return err
+		}
+		if err := fn(payment); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (p *Postgres) EnrollmentCounts(ctx context.Context, userID int) (int64, int64, error) {
+	var enrolled, completed int64
+	err := p.db.QueryRowContext(ctx,
+		`SELECT COUNT(*), COUNT(*) FILTER (WHERE e.status = 'completed')
+		 FROM user_course_enrollments e JOIN courses c ON c.id = e.course_id AND c.deleted_at IS NULL
+		 WHERE e.user_id = $1 AND e.status <> 'waitlisted'`,
+		userID,
+	).Scan(&enrolled, &completed)
+	return enrolled, completed, err
+}
+
+func (p *Postgres) inTx(ctx context.Context, fn func(*database.Tx) error) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// expectRow turns an UPDATE/DELETE that touched nothing into ErrNotFound.
+func expectRow(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
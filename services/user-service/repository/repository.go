@@ -0,0 +1,66 @@
+// Package repository is the persistence layer of the user-service. Handlers
+// and business logic depend only on the UserRepository interface so they can
+// be exercised against the in-memory implementation in tests.
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"mopcare/paging"
+)
+
+// ErrNotFound is returned when the addressed row does not exist.
+var ErrNotFound = errors.New("not found")
+
+// User rows are soft-deleted: DeletedAt is set instead of removing the row,
+// so payment and enrollment history survive until the row is purged.
+type User struct {
+	ID                    int        `json:"id"`
+	FirstName             string     `json:"first_name"`
+	LastName              string     `json:"last_name"`
+	Email                 string     `json:"email"`
+	TotalAmountPaid       float64    `json:"total_amount_paid"`
+	CreatedAt             time.Time  `json:"created_at"`
+	EnrolledCourses       string     `json:"enrolled_courses,omitempty"`
+	CompletedCoursesCount int64      `json:"completed_courses_count,omitempty"`
+	State                 string     `json:"state,omitempty"`
+	City                  string     `json:"city,omitempty"`
+	DeletedAt             *time.Time `json:"deleted_at,omitempty"`
+}
+
+// Payment is one payment a user made. Email is the user's.
+type Payment struct {
+	ID        int64     `json:"id"`
+	UserID    int       `json:"user_id"`
+	Email     string    `json:"email"`
+	Amount    float64   `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type UserRepository interface {
+	// List and Get skip deleted users unless includeDeleted is set.
+	List(ctx context.Context, page paging.Page, includeDeleted bool) ([]User, error)
+	Get(ctx context.Context, id int, includeDeleted bool) (User, error)
+	// EmailExists also counts deleted users, which keep their address.
+	EmailExists(ctx context.Context, email string) (bool, error)
+	// Create inserts u and fills in its ID and CreatedAt.
+	Create(ctx context.Context, u *User) error
+	// CreateMany creates every user in one transaction, or none of them.
+	CreateMany(ctx context.Context, users []*User) error
+	// EachUser calls fn with every user in ID order, skipping deleted ones
+	// unless includeDeleted is set, and stops at the first error fn returns.
+	EachUser(ctx context.Context, includeDeleted bool, fn func(User) error) error
+	// Delete soft-deletes a live user; Restore undeletes one and returns it.
+	Delete(ctx context.Context, id int) error
+	Restore(ctx context.Context, id int) (User, error)
+	// Purge permanently removes users deleted before the cutoff, cascading
+	// to their enrollments, and returns how many.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+	AddPayment(ctx context.Context, id int, amount float64) error
+	// EachPayment calls fn with every payment, deleted users' included, in
+	// ID order, and stops at the first error fn returns.
+	EachPayment(ctx context.Context, fn func(Payment) error) error
+	EnrollmentCounts(ctx context.Context, userID int) (enrolled, completed int64, err error)
+}
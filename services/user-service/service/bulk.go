@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"mopcare/auth"
+	"mopcare/bulk"
+	"mopcare/problem"
+	"user-service/repository"
+)
+
+// importFields are the columns of a user import, all of them required.
+var importFields = []string{"first_name", "last_name", "email"}
+
+// maxLengths mirror the column sizes of the users table.
+var maxLengths = map[string]int{"first_name": 100, "last_name": 100, "email": 255}
+
+var userColumns = []string{"id", "first_name", "last_name", "email", "total_amount_paid", "state", "city", "created_at", "deleted_at"}
+
+var paymentColumns = []string{"id", "user_id", "email", "amount", "created_at"}
+
+func admin(ctx context.Context, what string) error {
+	if !auth.CallerOf(ctx).Admin {
+		return problem.Forbidden(problem.CodeForbidden, "Only admins can "+what)
+	}
+	return nil
+}
+
+// Import creates a user for each row of a CSV. Rows are checked as
+// POST /users checks a user, and no two rows may share an email.
+func (s *UserService) Import(ctx context.Context, csv io.Reader, opts bulk.Options) (*bulk.Report, error) {
+	if err := admin(ctx, "import users"); err != nil {
+		return nil, err
+	}
+	reader, err := bulk.NewReader(csv, importFields, importFields, opts.Mapping)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	report := bulk.NewReport(opts)
+	report.Rows = len(rows)
+	var users []*repository.User
+	var numbers []int
+	emails := map[string]int{} // email -> first row with it
+	for _, row := range rows {
+		for _, field := range importFields {
+			if value := row.Get(field); value == "" {
+				report.Fail(row.Number, field, field+" is required")
+			} else if len(value) > maxLengths[field] {
+				report.Fail(row.Number, field, fmt.Sprintf("%s must be at most %d characters", field, maxLengths[field]))
+			}
+		}
+		email := row.Get("email")
+		if first, ok := emails[email]; ok && email != "" {
+			report.Fail(row.Number, "email", fmt.Sprintf("Row %d has the same email", first))
+		} else if email != "" {
+			emails[email] = row.Number
+			exists, err := s.repo.EmailExists(ctx, email)
+			if err != nil {
+				return nil, err
+			}
+			if exists {
+				report.Fail(row.Number, "email", "User with this email already exists")
+			}
+		}
+		if !report.HasFailed(row.Number) {
+			users = append(users, &repository.User{FirstName: row.Get("first_name"), LastName: row.Get("last_name"), Email: email})
+			numbers = append(numbers, row.Number)
+		}
+	}
+
+	if !report.Commit() {
+		return report, nil
+	}
+	if opts.Mode == bulk.ModeAtomic {
+		if err := s.repo.CreateMany(ctx, users); err != nil {
+			return nil, err
+		}
+		report.Imported = len(users)
+		return report, nil
+	}
+	for i, u := range users {
+		if err := s.repo.Create(ctx, u); err != nil {
+			if err := rowError(report, numbers[i], err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		report.Imported++
+	}
+	return report, nil
+}
+
+// rowError records a failed write against its row, unless it is not the
+// row's fault, in which case it is returned to end the import.
+func rowError(report *bulk.Report, row int, err error) error {
+	p, unexpected := problem.From(err)
+	if unexpected || p.Status >= 499 {
+		return err
+	}
+	report.Fail(row, "", p.Detail)
+	return nil
+}
+
+// Export writes every user to out, the deleted ones only if includeDeleted
+// is set.
+func (s *UserService) Export(ctx context.Context, out io.Writer, format bulk.Format, includeDeleted bool) error {
+	if err := admin(ctx, "export users"); err != nil {
+		return err
+	}
+	w := bulk.NewWriter(out, format, userColumns...)
+	err := s.repo.EachUser(ctx, includeDeleted, func(u repository.User) error {
+		deletedAt := ""
+		if u.DeletedAt != nil {
+			deletedAt = timestamp(*u.DeletedAt)
+		}
+		return w.Write(u, strconv.Itoa(u.ID), bulk.Cell(u.FirstName), bulk.Cell(u.LastName), bulk.Cell(u.Email), money(u.TotalAmountPaid),
+			bulk.Cell(u.State), bulk.Cell(u.City), timestamp(u.CreatedAt), deletedAt)
+	})
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// ExportPayments writes every payment to out, oldest first.
+func (s *UserService) ExportPayments(ctx context.Context, out io.Writer, format bulk.Format) error {
+	if err := admin(ctx, "export payments"); err != nil {
+		return err
+	}
+	w := bulk.NewWriter(out, format, paymentColumns...)
+	err := s.repo.EachPayment(ctx, func(p repository.Payment) error {
+		return w.Write(p, strconv.FormatInt(p.ID, 10), strconv.Itoa(p.UserID), bulk.Cell(p.Email), money(p.Amount), timestamp(p.CreatedAt))
+	})
+	if err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func money(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
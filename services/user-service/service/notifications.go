@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"mopcare/auth"
+	"mopcare/notify"
+	"mopcare/paging"
+	"mopcare/problem"
+)
+
+// PreferencesInput changes a user's notification preferences. Fields left
+// out keep their current value; an empty muted list unmutes everything.
+type PreferencesInput struct {
+	EmailEnabled *bool    `json:"email_enabled"`
+	Locale       string   `json:"locale"`
+	Muted        []string `json:"muted"`
+}
+
+func (in PreferencesInput) validate() error {
+	if in.Locale != "" && !notify.KnownLocale(in.Locale) {
+		return problem.BadRequest(problem.CodeValidationFailed,
+			fmt.Sprintf("Unknown locale %q; emails are written in %s", in.Locale, strings.Join(notify.Locales, ", ")))
+	}
+	for _, t := range in.Muted {
+		if !notifiable(t) {
+			return problem.BadRequest(problem.CodeValidationFailed,
+				fmt.Sprintf("Unknown event type %q; users are emailed about %s", t, strings.Join(notify.EventTypes, ", ")))
+		}
+	}
+	return nil
+}
+
+func notifiable(eventType string) bool {
+	for _, t := range notify.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// NotificationService manages what users are emailed about and shows them
+// what they were sent.
+type NotificationService struct {
+	store notify.Store
+	links *notify.Links
+}
+
+func NewNotificationService(store notify.Store, links *notify.Links) *NotificationService {
+	return &NotificationService{store: store, links: links}
+}
+
+// self lets admins and the user themself through.
+func self(ctx context.Context, userID int) error {
+	caller := auth.CallerOf(ctx)
+	switch {
+	case caller.Admin || (caller.UserID != 0 && caller.UserID == userID):
+		return nil
+	case caller.UserID == 0:
+		return problem.Unauthorized(problem.CodeUnauthorized, "A user or admin token is required")
+	}
+	return problem.Forbidden(problem.CodeForbidden, "Notifications are only shown to their user")
+}
+
+func (s *NotificationService) notFound(err error) error {
+	if errors.Is(err, notify.ErrNotFound) {
+		return problem.NotFound(problem.CodeUserNotFound, "User not found")
+	}
+	return err
+}
+
+func (s *NotificationService) Preferences(ctx context.Context, userID int) (notify.Preferences, error) {
+	if err := self(ctx, userID); err != nil {
+		return notify.Preferences{}, err
+	}
+	p, err := s.store.Preferences(ctx, userID)
+	return p, s.notFound(err)
+}
+
+func (s *NotificationService) UpdatePreferences(ctx context.Context, userID int, in PreferencesInput) (notify.Preferences, error) {
+	if err := self(ctx, userID); err != nil {
+		return notify.Preferences{}, err
+	}
+	if err := in.validate(); err != nil {
+		return notify.Preferences{}, err
+	}
+	p, err := s.store.Preferences(ctx, userID)
+	if err != nil {
+		return notify.Preferences{}, s.notFound(err)
+	}
+	if in.EmailEnabled != nil {
+		p.EmailEnabled = *in.EmailEnabled
+	}
+	if in.Locale != "" {
+		p.Locale = in.Locale
+	}
+	if in.Muted != nil {
+		p.Muted = in.Muted
+	}
+	if err := s.store.SavePreferences(ctx, &p); err != nil {
+		return notify.Preferences{}, s.notFound(err)
+	}
+	return p, nil
+}
+
+// Unsubscribe follows an unsubscribe link. It needs no token other than the
+// link's own, and following it twice does no harm.
+func (s *NotificationService) Unsubscribe(ctx context.Context, token string) (notify.Preferences, error) {
+	userID, scope, err := s.links.Parse(token)
+	if err != nil {
+		return notify.Preferences{}, problem.Forbidden(problem.CodeSignatureInvalid, "Unsubscribe link is not valid")
+	}
+	p, err := s.store.Preferences(ctx, userID)
+	if err != nil {
+		return notify.Preferences{}, s.notFound(err)
+	}
+	switch {
+	case scope == notify.ScopeAll:
+		p.EmailEnabled = false
+	case !muted(p, scope):
+		p.Muted = append(p.Muted, scope)
+	}
+	if err := s.store.SavePreferences(ctx, &p); err != nil {
+		return notify.Preferences{}, s.notFound(err)
+	}
+	return p, nil
+}
+
+func muted(p notify.Preferences, eventType string) bool {
+	for _, t := range p.Muted {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Notifications returns one page of the emails queued for the user, newest
+// first.
+func (s *NotificationService) Notifications(ctx context.Context, userID int, page paging.Page) ([]notify.Notification, error) {
+	if err := self(ctx, userID); err != nil {
+		return nil, err
+	}
+	if _, err := s.store.Preferences(ctx, userID); err != nil {
+		return nil, s.notFound(err)
+	}
+	notifications, err := s.store.ListNotifications(ctx, userID, page)
+	if notifications == nil && err == nil {
+		notifications = []notify.Notification{}
+	}
+	return notifications, err
+}
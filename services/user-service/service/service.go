@@ -0,0 +1,138 @@
+// Package service holds the user-service business rules. It speaks in
+// repository types and returns *problem.Problem for every expected failure.
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"mopcare/auth"
+	"mopcare/paging"
+	"mopcare/problem"
+	"user-service/repository"
+)
+
+type UserService struct {
+	repo repository.UserRepository
+}
+
+func NewUserService(repo repository.UserRepository) *UserService {
+	return &UserService{repo: repo}
+}
+
+// Profile is the aggregated view served by GET /users/:id/profile.
+type Profile struct {
+	FirstName             string  `json:"first_name"`
+	LastName              string  `json:"last_name"`
+	Email                 string  `json:"email"`
+	TotalAmountPaid       float64 `json:"total_amount_paid"`
+	EnrolledCoursesCount  int64   `json:"enrolled_courses_count"`
+	CompletedCoursesCount int64   `json:"completed_courses_count"`
+	State                 string  `json:"state,omitempty"`
+	City                  string  `json:"city,omitempty"`
+}
+
+// List returns one page of users, with deleted ones only if includeDeleted
+// is set. Only an empty first page is a 404; a page past the end is simply
+// empty.
+func (s *UserService) List(ctx context.Context, page paging.Page, includeDeleted bool) ([]repository.User, error) {
+	users, err := s.repo.List(ctx, page, includeDeleted)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 && page.Offset == 0 {
+		return nil, problem.NotFound(problem.CodeUserNotFound, "No users found")
+	}
+	if users == nil {
+		users = []repository.User{}
+	}
+	return users, nil
+}
+
+func (s *UserService) Get(ctx context.Context, id int, includeDeleted bool) (repository.User, error) {
+	user, err := s.repo.Get(ctx, id, includeDeleted)
+	return user, notFound(err)
+}
+
+func (s *UserService) Create(ctx context.Context, user *repository.User) error {
+	if user.FirstName == "" || user.LastName == "" || user.Email == "" {
+		return problem.BadRequest(problem.CodeValidationFailed, "First name, last name, and email are required")
+	}
+
+	exists, err := s.repo.EmailExists(ctx, user.Email)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return problem.Conflict(problem.CodeUserEmailTaken, "User with this email already exists")
+	}
+	return s.repo.Create(ctx, user)
+}
+
+// Delete soft-deletes the user, keeping their enrollments and payment
+// history until the row is purged.
+func (s *UserService) Delete(ctx context.Context, id int) error {
+	return notFound(s.repo.Delete(ctx, id))
+}
+
+func (s *UserService) Restore(ctx context.Context, id int) (repository.User, error) {
+	user, err := s.repo.Restore(ctx, id)
+	return user, notFound(err)
+}
+
+// PurgeDeleted permanently removes users deleted more than retention ago.
+func (s *UserService) PurgeDeleted(ctx context.Context, retention time.Duration) (int64, error) {
+	return s.repo.Purge(ctx, time.Now().Add(-retention))
+}
+
+// Token identifies a user to every service until ExpiresAt.
+type Token struct {
+	UserID    int       `json:"user_id"`
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// IssueToken signs a token for a live user with key, the admin token.
+func (s *UserService) IssueToken(ctx context.Context, id int, key string, expires time.Time) (Token, error) {
+	if _, err := s.repo.Get(ctx, id, false); err != nil {
+		return Token{}, notFound(err)
+	}
+	expires = expires.Truncate(time.Second)
+	return Token{UserID: id, Token: auth.UserToken(id, expires, key), ExpiresAt: expires}, nil
+}
+
+func (s *UserService) Profile(ctx context.Context, id int) (Profile, error) {
+	user, err := s.repo.Get(ctx, id, false)
+	if err != nil {
+		return Profile{}, notFound(err)
+	}
+	enrolled, completed, err := s.repo.EnrollmentCounts(ctx, id)
+	if err != nil {
+		return Profile{}, err
+	}
+	return Profile{
+		FirstName:             user.FirstName,
+		LastName:              user.LastName,
+		Email:                 user.Email,
+		TotalAmountPaid:       user.TotalAmountPaid,
+		EnrolledCoursesCount:  enrolled,
+		CompletedCoursesCount: completed,
+		State:                 user.State,
+		City:                  user.City,
+	}, nil
+}
+
+func (s *UserService) RecordPayment(ctx context.Context, id int, amount float64) error {
+	if amount <= 0 {
+		return problem.BadRequest(problem.CodeValidationFailed, "Amount must be positive")
+	}
+	return notFound(s.repo.AddPayment(ctx, id, amount))
+}
+
+func notFound(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return problem.NotFound(problem.CodeUserNotFound, "User not found")
+	}
+	return err
+}
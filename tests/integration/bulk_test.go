@@ -0,0 +1,66 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"mopcare/client"
+)
+
+// TestImportAndExport imports users and their enrollments from spreadsheets
+// through the gateway and exports them, with a payment, again.
+func TestImportAndExport(t *testing.T) {
+	s := Start(t)
+	admin := client.New(client.Config{BaseURL: s.GatewayURL, Token: AdminToken})
+	ctx := context.Background()
+
+	users := []byte("First Name,Last Name,Email Address\nAda,Lovelace,ada@example.com\nGrace,,grace@example.com\n")
+	mapping := map[string]string{"email": "Email Address"}
+	report, err := admin.ImportUsers(ctx, users, client.ImportOptions{Mapping: mapping})
+	if err != nil || report.Imported != 0 || report.Failed != 1 || report.Errors[0].Row != 3 || report.Errors[0].Field != "last_name" {
+		t.Fatalf("atomic import with an invalid row = %+v, %v", report, err)
+	}
+	report, err = admin.ImportUsers(ctx, users, client.ImportOptions{Mapping: mapping, Mode: client.ImportBestEffort})
+	if err != nil || report.Imported != 1 || report.Failed != 1 {
+		t.Fatalf("best effort import = %+v, %v", report, err)
+	}
+
+	course, err := admin.CreateCourse(ctx, client.CourseInput{Title: "Heart Health", Content: "C"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.PublishCourse(ctx, course.ID, nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	enrollments := []byte(fmt.Sprintf("email,course_id,status\nada@example.com,%d,completed\nnobody@example.com,%d,\n", course.ID, course.ID))
+	report, err = admin.ImportEnrollments(ctx, enrollments, client.ImportOptions{Mode: client.ImportBestEffort})
+	if err != nil || report.Imported != 1 || report.Failed != 1 || report.Errors[0].Message != "No user has this email" {
+		t.Fatalf("ImportEnrollments = %+v, %v", report, err)
+	}
+
+	exported, err := admin.ExportUsers(ctx, client.ExportCSV, false)
+	if err != nil || !strings.HasPrefix(string(exported), "id,first_name,") || !strings.Contains(string(exported), ",Ada,Lovelace,ada@example.com,0.00,") {
+		t.Fatalf("ExportUsers = %s, %v", exported, err)
+	}
+	ada := strings.Split(strings.Split(string(exported), "\n")[1], ",")[0]
+	exported, err = admin.ExportEnrollments(ctx, client.ExportJSONLines, course.ID)
+	if err != nil || !strings.Contains(string(exported), `"user_id":`+ada) || !strings.Contains(string(exported), `"status":"completed"`) {
+		t.Fatalf("ExportEnrollments = %s, %v", exported, err)
+	}
+	id, _ := strconv.Atoi(ada)
+	certificates, err := admin.ListCertificates(ctx, id, client.ListOptions{})
+	if err != nil || len(certificates) != 1 {
+		t.Fatalf("certificates of the imported completion = %+v, %v", certificates, err)
+	}
+
+	if err := admin.RecordPayment(ctx, id, 12.5); err != nil {
+		t.Fatal(err)
+	}
+	exported, err = admin.ExportPayments(ctx, client.ExportCSV)
+	if err != nil || !strings.Contains(string(exported), ",ada@example.com,12.50,") {
+		t.Fatalf("ExportPayments = %s, %v", exported, err)
+	}
+}
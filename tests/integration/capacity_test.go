@@ -0,0 +1,90 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"mopcare/client"
+	"mopcare/problem"
+)
+
+// TestCapacity enrolls more users at once than a course has seats and checks
+// that only as many get one, and that the rest get theirs in order as seats
+// free up.
+func TestCapacity(t *testing.T) {
+	s := Start(t)
+	admin := client.New(client.Config{BaseURL: s.GatewayURL, Token: AdminToken})
+	ctx := context.Background()
+
+	three := 3
+	course, err := admin.CreateCourse(ctx, client.CourseInput{Title: "Live Cardio", Content: "C", Capacity: &three})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.PublishCourse(ctx, course.ID, nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	var users []int
+	for i := 0; i < 10; i++ {
+		user, err := admin.CreateUser(ctx, client.UserInput{FirstName: "U", LastName: fmt.Sprint(i), Email: fmt.Sprintf("u%d@example.com", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, user.ID)
+	}
+
+	enrollments := make([]*client.UserCourseEnrollment, len(users))
+	errs := make([]error, len(users))
+	var wg sync.WaitGroup
+	for i, id := range users {
+		wg.Add(1)
+		go func(i, id int) {
+			defer wg.Done()
+			enrollments[i], errs[i] = admin.Enroll(ctx, client.EnrollmentInput{UserID: id, CourseID: course.ID, Status: "enrolled"})
+		}(i, id)
+	}
+	wg.Wait()
+	seated := 0
+	for i, e := range enrollments {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		if e.Status == "enrolled" {
+			seated++
+		}
+	}
+	if seated != 3 {
+		t.Fatalf("%d enrollments got a seat, want 3", seated)
+	}
+
+	roster, err := admin.ListRoster(ctx, course.ID, client.ListOptions{})
+	if err != nil || len(roster) != 3 {
+		t.Fatalf("ListRoster = %+v, %v", roster, err)
+	}
+	waitlist, err := admin.ListWaitlist(ctx, course.ID, client.ListOptions{})
+	if err != nil || len(waitlist) != 7 || waitlist[0].Position != 1 {
+		t.Fatalf("ListWaitlist = %+v, %v", waitlist, err)
+	}
+	if err := admin.DeleteEnrollment(ctx, roster[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	roster, err = admin.ListRoster(ctx, course.ID, client.ListOptions{})
+	if err != nil || len(roster) != 3 || roster[2].ID != waitlist[0].EnrollmentID {
+		t.Fatalf("ListRoster after a seat freed = %+v, %v; want enrollment %d promoted", roster, err, waitlist[0].EnrollmentID)
+	}
+
+	closed := time.Now().Add(-time.Hour)
+	if _, err := admin.PatchCourse(ctx, course.ID, client.CoursePatch{EnrollmentClosesAt: &closed}, 0); err != nil {
+		t.Fatal(err)
+	}
+	user, err := admin.CreateUser(ctx, client.UserInput{FirstName: "Late", LastName: "Comer", Email: "late@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.Enroll(ctx, client.EnrollmentInput{UserID: user.ID, CourseID: course.ID, Status: "enrolled"}); client.Code(err) != problem.CodeEnrollmentClosed {
+		t.Fatalf("Enroll after closing = %v, want ENROLLMENT_CLOSED", err)
+	}
+}
@@ -0,0 +1,57 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	enrollmentRepository "enrollment-service/repository"
+	"mopcare/client"
+	"mopcare/database"
+)
+
+// TestConcurrentCertificates issues a certificate for one enrollment many
+// times at once and checks that every caller gets the one that was issued.
+func TestConcurrentCertificates(t *testing.T) {
+	s := Start(t)
+	admin := client.New(client.Config{BaseURL: s.GatewayURL, Token: AdminToken})
+	ctx := context.Background()
+
+	course, err := admin.CreateCourse(ctx, client.CourseInput{Title: "Heart Health", Content: "C"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.PublishCourse(ctx, course.ID, nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	user, err := admin.CreateUser(ctx, client.UserInput{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enrollment, err := admin.Enroll(ctx, client.EnrollmentInput{UserID: user.ID, CourseID: course.ID, Status: "enrolled"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := enrollmentRepository.NewPostgres(database.Wrap(s.DB))
+	certificates := make([]enrollmentRepository.Certificate, 20)
+	errs := make([]error, len(certificates))
+	var wg sync.WaitGroup
+	for i := range certificates {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			certificates[i], errs[i] = repo.IssueCertificate(ctx, enrollment.ID, fmt.Sprintf("RACE-%04d", i))
+		}(i)
+	}
+	wg.Wait()
+	for i, c := range certificates {
+		if errs[i] != nil {
+			t.Fatalf("issuance %d: %v", i, errs[i])
+		}
+		if c.ID != certificates[0].ID || c.Code != certificates[0].Code {
+			t.Errorf("issuance %d got %+v, want %+v", i, c, certificates[0])
+		}
+	}
+}
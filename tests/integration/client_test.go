@@ -0,0 +1,393 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+	"sync"
+	"testing"
+
+	"mopcare/client"
+	"mopcare/media"
+	"mopcare/problem"
+)
+
+// TestClientAgainstStack drives the real services through the gateway with
+// the Go client, so the SDK, the OpenAPI document and the handlers are
+// checked against each other.
+func TestClientAgainstStack(t *testing.T) {
+	s := Start(t)
+	c := client.New(client.Config{BaseURL: s.GatewayURL})
+	admin := client.New(client.Config{BaseURL: s.GatewayURL, Token: AdminToken})
+	ctx := context.Background()
+
+	user, err := c.CreateUser(ctx, client.UserInput{FirstName: "Margaret", LastName: "Johnson", Email: "margaret@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		course, err := admin.CreateCourse(ctx, client.CourseInput{Title: "Course", Content: "Content"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := admin.PublishCourse(ctx, course.ID, nil, 0); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.Enroll(ctx, client.EnrollmentInput{UserID: user.ID, CourseID: course.ID, Status: "enrolled"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	courses, err := c.Courses(2).All(ctx)
+	if err != nil || len(courses) != 5 {
+		t.Fatalf("Courses = %d, %v", len(courses), err)
+	}
+	enrollments, err := c.UserEnrollments(user.ID, 2).All(ctx)
+	if err != nil || len(enrollments) != 5 {
+		t.Fatalf("UserEnrollments = %d, %v", len(enrollments), err)
+	}
+	if err := c.RecordPayment(ctx, user.ID, 25); err != nil {
+		t.Fatal(err)
+	}
+	profile, err := c.GetUserProfile(ctx, user.ID)
+	if err != nil || profile.EnrolledCoursesCount != 5 || profile.TotalAmountPaid != 25 {
+		t.Fatalf("profile = %+v, %v", profile, err)
+	}
+
+	_, err = c.Enroll(ctx, client.EnrollmentInput{UserID: user.ID, CourseID: courses[0].ID, Status: "enrolled"})
+	if !errors.Is(err, client.ErrConflict) || client.Code(err) != problem.CodeEnrollmentDuplicate {
+		t.Fatalf("duplicate enrollment = %v", err)
+	}
+}
+
+// TestConcurrentEdits checks optimistic locking end to end: two writers that
+// read the same version cannot both win, while unconditional patches to
+// different fields are merged rather than lost.
+func TestConcurrentEdits(t *testing.T) {
+	s := Start(t)
+	// The course stays a draft, which only admins can read back.
+	c := client.New(client.Config{BaseURL: s.GatewayURL, Token: AdminToken})
+	ctx := context.Background()
+
+	course, err := c.CreateCourse(ctx, client.CourseInput{Title: "Falls prevention", Content: "Home safety"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.PatchCourse(ctx, course.ID, client.CoursePatch{Title: "Falls prevention at home"}, course.Version); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.PatchCourse(ctx, course.ID, client.CoursePatch{Content: "Stale edit"}, course.Version)
+	if !errors.Is(err, client.ErrPreconditionFailed) || client.Code(err) != problem.CodePreconditionFailed {
+		t.Fatalf("second writer = %v, want ErrPreconditionFailed", err)
+	}
+
+	video, cover := "https://cdn.example.com/intro.mp4", "https://cdn.example.com/cover.png"
+	patches := []client.CoursePatch{{OverviewVideoURL: &video}, {CoverImageURL: &cover}}
+	var wg sync.WaitGroup
+	for _, patch := range patches {
+		wg.Add(1)
+		go func(patch client.CoursePatch) {
+			defer wg.Done()
+			if _, err := c.PatchCourse(ctx, course.ID, patch, 0); err != nil {
+				t.Error(err)
+			}
+		}(patch)
+	}
+	wg.Wait()
+
+	got, err := c.GetCourse(ctx, course.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.OverviewVideoURL != video || got.CoverImageURL != cover || got.Content != "Home safety" || got.Version != 4 {
+		t.Fatalf("course after concurrent patches = %+v", got)
+	}
+}
+
+// TestRevisionHistory checks that revisions are written in the same
+// transaction as the edits and that a rollback lands as a new revision.
+func TestRevisionHistory(t *testing.T) {
+	s := Start(t)
+	c := client.New(client.Config{BaseURL: s.GatewayURL, Token: AdminToken})
+	ctx := context.Background()
+
+	course, err := c.CreateCourse(ctx, client.CourseInput{Title: "Sleep", Content: "First draft"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited, err := c.PatchCourse(ctx, course.ID, client.CoursePatch{Content: "Second draft"}, course.Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.PublishCourse(ctx, course.ID, nil, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := c.RollbackCourse(ctx, course.ID, course.Version, 0)
+	if err != nil || restored.Content != "First draft" || restored.Status != "published" {
+		t.Fatalf("RollbackCourse = %+v, %v", restored, err)
+	}
+	revisions, err := c.ListCourseRevisions(ctx, course.ID, client.ListOptions{})
+	if err != nil || len(revisions) != 3 {
+		t.Fatalf("ListCourseRevisions = %+v, %v", revisions, err)
+	}
+	last := revisions[2]
+	if last.Version != restored.Version || last.RollbackOf != course.Version || last.Author != "admin" {
+		t.Fatalf("rollback revision = %+v", last)
+	}
+	diff, err := c.DiffCourse(ctx, course.ID, edited.Version, last.Version)
+	if err != nil || diff.Changes["content"] != (client.Change{From: "Second draft", To: "First draft"}) {
+		t.Fatalf("DiffCourse = %+v, %v", diff, err)
+	}
+}
+
+// TestSlugRedirects checks that a renamed course is still found under its
+// old slug, through the gateway's proxy of the 301.
+func TestSlugRedirects(t *testing.T) {
+	s := Start(t)
+	c := client.New(client.Config{BaseURL: s.GatewayURL, Token: AdminToken})
+	ctx := context.Background()
+
+	course, err := c.CreateCourse(ctx, client.CourseInput{Title: "Hearing Loss", Content: "Aids"})
+	if err != nil || course.UniqueID != "hearing-loss" {
+		t.Fatalf("CreateCourse = %+v, %v", course, err)
+	}
+	slug := "hearing"
+	if _, err := c.PatchCourse(ctx, course.ID, client.CoursePatch{UniqueID: &slug}, 0); err != nil {
+		t.Fatal(err)
+	}
+	got, err := c.GetCourseBySlug(ctx, "hearing-loss")
+	if err != nil || got.ID != course.ID || got.UniqueID != slug {
+		t.Fatalf("GetCourseBySlug of the former slug = %+v, %v", got, err)
+	}
+	other, err := c.CreateCourse(ctx, client.CourseInput{Title: "Other", Content: "x", UniqueID: "hearing-loss"})
+	if !errors.Is(err, client.ErrConflict) {
+		t.Fatalf("CreateCourse with a former slug = %+v, %v; want ErrConflict", other, err)
+	}
+}
+
+// TestMediaThroughGateway uploads a cover image, points a course at it and
+// downloads it and its thumbnail through the signed links, all via the
+// gateway.
+func TestMediaThroughGateway(t *testing.T) {
+	s := Start(t)
+	c := client.New(client.Config{BaseURL: s.GatewayURL, Token: AdminToken})
+	ctx := context.Background()
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 800, 600))); err != nil {
+		t.Fatal(err)
+	}
+	m, err := c.UploadMedia(ctx, img.Bytes(), "image/png")
+	if err != nil || m.Width != 800 || m.ThumbnailURL == "" {
+		t.Fatalf("UploadMedia = %+v, %v", m, err)
+	}
+	if again, err := c.UploadMedia(ctx, img.Bytes(), "image/png"); err != nil || again.ID != m.ID {
+		t.Fatalf("second UploadMedia = %+v, %v; want media %d", again, err, m.ID)
+	}
+
+	cover := fmt.Sprintf("media:%d", m.ID)
+	if _, err := c.CreateCourse(ctx, client.CourseInput{Title: "Eye care", Content: "x", CoverImageURL: cover}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.CreateCourse(ctx, client.CourseInput{Title: "Hearing", Content: "x", OverviewVideoURL: cover})
+	if client.Code(err) != problem.CodeValidationFailed {
+		t.Fatalf("image as overview video = %v, want VALIDATION_FAILED", err)
+	}
+
+	data, err := c.DownloadMedia(ctx, m.URL)
+	if err != nil || !bytes.Equal(data, img.Bytes()) {
+		t.Fatalf("DownloadMedia = %d bytes, %v", len(data), err)
+	}
+	thumb, err := c.DownloadMedia(ctx, m.ThumbnailURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded, err := png.Decode(bytes.NewReader(thumb)); err != nil || decoded.Bounds().Dx() != media.ThumbnailSize {
+		t.Fatalf("thumbnail = %v, %v", decoded, err)
+	}
+}
+
+func TestCatalogThroughGateway(t *testing.T) {
+	s := Start(t)
+	c := client.New(client.Config{BaseURL: s.GatewayURL, Token: AdminToken})
+	ctx := context.Background()
+
+	health, err := c.CreateCategory(ctx, client.CategoryInput{Name: "Health"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	heart, err := c.CreateCategory(ctx, client.CategoryInput{Name: "Heart", ParentID: &health.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	course, err := c.CreateCourse(ctx, client.CourseInput{Title: "Heart Health After 65", Content: "x",
+		CategoryID: &heart.ID, Tags: []string{"Seniors", "cardio"}, Price: 20})
+	if err != nil || len(course.Tags) != 2 || course.Tags[0] != "cardio" {
+		t.Fatalf("CreateCourse = %+v, %v", course, err)
+	}
+	if _, err := c.CreateSeries(ctx, course.ID, client.SeriesInput{Title: "Welcome", Duration: 30, IsFreePreview: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateCourse(ctx, client.CourseInput{Title: "Managing Diabetes", Content: "x", Tags: []string{"seniors"}}); err != nil {
+		t.Fatal(err)
+	}
+	missing := heart.ID + 100
+	_, err = c.CreateCourse(ctx, client.CourseInput{Title: "Lost", Content: "x", CategoryID: &missing})
+	if client.Code(err) != problem.CodeCategoryNotFound {
+		t.Fatalf("unknown category = %v, want CATEGORY_NOT_FOUND", err)
+	}
+
+	catalog, err := c.BrowseCourses(ctx, client.ListOptions{Category: health.Slug, Tags: []string{"seniors"}})
+	if err != nil || catalog.Total != 1 || catalog.Courses[0].Duration != 30 || !catalog.Courses[0].HasFreePreview {
+		t.Fatalf("BrowseCourses = %+v, %v", catalog, err)
+	}
+	if len(catalog.Facets.Categories) != 2 || catalog.Facets.Price.Max != 20 || catalog.Facets.FreePreview != 1 {
+		t.Fatalf("facets = %+v", catalog.Facets)
+	}
+
+	if r, err := c.RenameTag(ctx, "seniors", "older adults"); err != nil || r.Courses != 2 {
+		t.Fatalf("RenameTag = %+v, %v", r, err)
+	}
+	if err := c.DeleteCategory(ctx, health.ID); client.Code(err) != problem.CodeCategoryInUse {
+		t.Fatalf("DeleteCategory in use = %v, want CATEGORY_IN_USE", err)
+	}
+}
+
+// TestInstructorsThroughGateway checks that user tokens minted by the user
+// service are honoured by the course service behind the gateway.
+func TestInstructorsThroughGateway(t *testing.T) {
+	s := Start(t)
+	admin := client.New(client.Config{BaseURL: s.GatewayURL, Token: AdminToken})
+	ctx := context.Background()
+
+	as := map[string]*client.Client{}
+	ids := map[string]int{}
+	for _, name := range []string{"owner", "coauthor", "stranger"} {
+		user, err := admin.CreateUser(ctx, client.UserInput{FirstName: name, LastName: "Instructor", Email: name + "@example.com"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := admin.IssueUserToken(ctx, user.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = user.ID
+		as[name] = client.New(client.Config{BaseURL: s.GatewayURL, Token: token.Token})
+	}
+
+	if _, err := client.New(client.Config{BaseURL: s.GatewayURL}).CreateCourse(ctx, client.CourseInput{Title: "T", Content: "x"}); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("anonymous CreateCourse = %v, want ErrUnauthorized", err)
+	}
+	course, err := as["owner"].CreateCourse(ctx, client.CourseInput{Title: "Heart Health", Content: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := as["owner"].SetInstructor(ctx, course.ID, ids["coauthor"], client.RoleCoAuthor); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := as["coauthor"].CreateSeries(ctx, course.ID, client.SeriesInput{Title: "Welcome"}); err != nil {
+		t.Fatalf("CreateSeries by a co-author: %v", err)
+	}
+	if err := as["stranger"].UpdateCourse(ctx, course.ID, client.CourseInput{Title: "Mine", Content: "x"}); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("UpdateCourse by a stranger = %v, want ErrForbidden", err)
+	}
+	if err := as["coauthor"].DeleteCourse(ctx, course.ID); !errors.Is(err, client.ErrForbidden) {
+		t.Fatalf("DeleteCourse by a co-author = %v, want ErrForbidden", err)
+	}
+	if err := as["owner"].RemoveInstructor(ctx, course.ID, ids["owner"]); client.Code(err) != problem.CodeLastOwner {
+		t.Fatalf("removing the last owner = %v, want LAST_OWNER", err)
+	}
+
+	// The draft is listed for its co-author but not for anyone else.
+	if mine, err := as["coauthor"].ListInstructorCourses(ctx, ids["coauthor"], client.ListOptions{}); err != nil || len(mine) != 1 {
+		t.Fatalf("own ListInstructorCourses = %+v, %v", mine, err)
+	}
+	if theirs, err := as["stranger"].ListInstructorCourses(ctx, ids["coauthor"], client.ListOptions{}); err != nil || len(theirs) != 0 {
+		t.Fatalf("public ListInstructorCourses = %+v, %v", theirs, err)
+	}
+	if mine, err := as["coauthor"].ListCourses(ctx, client.ListOptions{}); err != nil || len(mine) != 1 || mine[0].Status != "draft" {
+		t.Fatalf("ListCourses by a co-author = %+v, %v", mine, err)
+	}
+	if theirs, err := as["stranger"].ListCourses(ctx, client.ListOptions{}); err != nil || len(theirs) != 0 {
+		t.Fatalf("ListCourses by a stranger = %+v, %v", theirs, err)
+	}
+}
+
+// TestQuizzesGateCompletion checks that the enrollment service only lets an
+// enrollment be completed once its learner passed the course's required
+// quizzes in the course service, and certifies it when it is.
+func TestQuizzesGateCompletion(t *testing.T) {
+	s := Start(t)
+	admin := client.New(client.Config{BaseURL: s.GatewayURL, Token: AdminToken})
+	ctx := context.Background()
+
+	user, err := admin.CreateUser(ctx, client.UserInput{FirstName: "Margaret", LastName: "Johnson", Email: "margaret@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := admin.IssueUserToken(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	learner := client.New(client.Config{BaseURL: s.GatewayURL, Token: token.Token})
+	course, err := admin.CreateCourse(ctx, client.CourseInput{Title: "Heart Health", Content: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	series, err := admin.CreateSeries(ctx, course.ID, client.SeriesInput{Title: "Blood pressure"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.PublishCourse(ctx, course.ID, nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	answer := 1
+	quiz, err := admin.CreateQuiz(ctx, series.ID, client.QuizInput{Title: "Check-up", PassingScore: 100, Required: true,
+		Questions: []client.Question{{Type: client.QuestionMultipleChoice, Prompt: "Normal systolic pressure?", Options: []string{"90", "120"}, Answer: &answer}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	enrollment, err := learner.Enroll(ctx, client.EnrollmentInput{UserID: user.ID, CourseID: course.ID, Status: "enrolled"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := learner.CompleteEnrollment(ctx, enrollment.ID); client.Code(err) != problem.CodeQuizzesNotPassed {
+		t.Fatalf("CompleteEnrollment before passing = %v, want QUIZZES_NOT_PASSED", err)
+	}
+	if a, err := learner.SubmitAttempt(ctx, quiz.ID, []int{0}); err != nil || a.Passed {
+		t.Fatalf("wrong attempt = %+v, %v", a, err)
+	}
+	if _, err := learner.CompleteEnrollment(ctx, enrollment.ID); client.Code(err) != problem.CodeQuizzesNotPassed {
+		t.Fatalf("CompleteEnrollment after failing = %v, want QUIZZES_NOT_PASSED", err)
+	}
+	if a, err := learner.SubmitAttempt(ctx, quiz.ID, []int{1}); err != nil || !a.Passed {
+		t.Fatalf("right attempt = %+v, %v", a, err)
+	}
+	if e, err := learner.CompleteEnrollment(ctx, enrollment.ID); err != nil || e.Status != "completed" {
+		t.Fatalf("CompleteEnrollment = %+v, %v", e, err)
+	}
+
+	// Completing issued a certificate, named as the learner and course were
+	// at the time, which anyone can verify and its learner can download.
+	certificates, err := learner.ListCertificates(ctx, user.ID, client.ListOptions{})
+	if err != nil || len(certificates) != 1 || certificates[0].LearnerName != "Margaret Johnson" || certificates[0].CourseTitle != "Heart Health" {
+		t.Fatalf("ListCertificates = %+v, %v", certificates, err)
+	}
+	code := certificates[0].Code
+	anonymous := client.New(client.Config{BaseURL: s.GatewayURL})
+	if v, err := anonymous.VerifyCertificate(ctx, strings.ToLower(code)); err != nil || v.Code != code {
+		t.Fatalf("VerifyCertificate = %+v, %v", v, err)
+	}
+	if data, err := learner.DownloadCertificate(ctx, code); err != nil || !bytes.HasPrefix(data, []byte("%PDF-1.4")) {
+		t.Fatalf("DownloadCertificate = %.20q, %v", data, err)
+	}
+	if _, err := anonymous.DownloadCertificate(ctx, code); !errors.Is(err, client.ErrUnauthorized) {
+		t.Fatalf("anonymous DownloadCertificate = %v, want ErrUnauthorized", err)
+	}
+}
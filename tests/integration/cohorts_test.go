@@ -0,0 +1,63 @@
+package integration
+
+import (
+	"context"
+	"testing"
+
+	"mopcare/client"
+)
+
+// TestCohortsAndBatches enrolls users in a course together, as a cohort,
+// through the gateway in one batch.
+func TestCohortsAndBatches(t *testing.T) {
+	s := Start(t)
+	admin := client.New(client.Config{BaseURL: s.GatewayURL, Token: AdminToken})
+	ctx := context.Background()
+
+	course, err := admin.CreateCourse(ctx, client.CourseInput{Title: "Heart Health", Content: "C"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.PublishCourse(ctx, course.ID, nil, 0); err != nil {
+		t.Fatal(err)
+	}
+	var items []client.BatchItem
+	for _, email := range []string{"ada@example.com", "grace@example.com"} {
+		u, err := admin.CreateUser(ctx, client.UserInput{FirstName: "First", LastName: "Last", Email: email})
+		if err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, client.BatchItem{UserID: u.ID})
+	}
+	cohort, err := admin.CreateCohort(ctx, client.CohortInput{CourseID: course.ID, Name: "Spring", StartsOn: "2027-03-01"})
+	if err != nil || cohort.StartsOn != "2027-03-01" {
+		t.Fatalf("CreateCohort = %+v, %v", cohort, err)
+	}
+
+	batch := client.BatchInput{CohortID: cohort.ID, Enrollments: append(items, client.BatchItem{UserID: 999999})}
+	result, err := admin.EnrollBatch(ctx, batch)
+	if err != nil || result.Created != 0 || result.Failed != 1 || result.Results[2].Error.Code != "USER_NOT_FOUND" {
+		t.Fatalf("atomic batch with an unknown user = %+v, %v", result, err)
+	}
+	batch.Enrollments = items
+	if result, err = admin.EnrollBatch(ctx, batch); err != nil || result.Created != 2 {
+		t.Fatalf("EnrollBatch = %+v, %v", result, err)
+	}
+	if result, err = admin.EnrollBatch(ctx, batch); err != nil || result.Created != 0 || result.Results[0].Error.Code != "ENROLLMENT_DUPLICATE" {
+		t.Fatalf("enrolling the cohort again = %+v, %v", result, err)
+	}
+
+	enrollments, err := admin.ListCohortEnrollments(ctx, cohort.ID, client.ListOptions{})
+	if err != nil || len(enrollments) != 2 || enrollments[0].CourseID != course.ID || enrollments[0].CohortID != cohort.ID {
+		t.Fatalf("ListCohortEnrollments = %+v, %v", enrollments, err)
+	}
+	if cohort, err = admin.GetCohort(ctx, cohort.ID); err != nil || cohort.Enrollments != 2 {
+		t.Fatalf("GetCohort = %+v, %v", cohort, err)
+	}
+	if err := admin.DeleteCohort(ctx, cohort.ID); err != nil {
+		t.Fatal(err)
+	}
+	if cohorts, err := admin.ListCohorts(ctx, course.ID, client.ListOptions{}); err != nil || len(cohorts) != 0 {
+		t.Fatalf("ListCohorts after deleting = %+v, %v", cohorts, err)
+	}
+}
@@ -0,0 +1,203 @@
+package integration
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"mopcare/events"
+)
+
+func TestMain(m *testing.M) {
+	code := m.Run()
+	StopPostgres()
+	os.Exit(code)
+}
+
+type idResponse struct {
+	ID int `json:"id"`
+}
+
+type profile struct {
+	EnrolledCoursesCount  int64 `json:"enrolled_courses_count"`
+	CompletedCoursesCount int64 `json:"completed_courses_count"`
+}
+
+func TestEnrollmentFlow(t *testing.T) {
+	s := Start(t)
+	admin := s.As(AdminToken)
+
+	var user, course, series, enrollment idResponse
+	s.Expect(t, 201, "POST", "/users", map[string]interface{}{
+		"first_name": "Margaret", "last_name": "Johnson", "email": "margaret@example.com",
+	}, &user)
+	admin.Expect(t, 201, "POST", "/courses", map[string]interface{}{
+		"title": "Heart Health After 65", "content": "Essential cardiovascular care",
+	}, &course)
+	admin.Expect(t, 201, "POST", fmt.Sprintf("/courses/%d/series", course.ID), map[string]interface{}{
+		"title": "Blood pressure", "description": "Basics",
+	}, &series)
+
+	// New courses are drafts: hidden from learners and closed to enrollment
+	// until they are published.
+	s.Expect(t, 404, "GET", fmt.Sprintf("/courses/%d", course.ID), nil, nil)
+	s.Expect(t, 422, "POST", fmt.Sprintf("/users/%d/enrollments", user.ID), map[string]interface{}{
+		"user_id": user.ID, "course_id": course.ID, "status": "enrolled",
+	}, nil)
+	admin.Expect(t, 200, "POST", fmt.Sprintf("/courses/%d/publish", course.ID), nil, nil)
+
+	// /users/:id/enrollments must be routed to the enrollment-service even
+	// though it starts with /users.
+	s.Expect(t, 201, "POST", fmt.Sprintf("/users/%d/enrollments", user.ID), map[string]interface{}{
+		"user_id": user.ID, "course_id": course.ID, "status": "enrolled",
+	}, &enrollment)
+
+	var p profile
+	s.Expect(t, 200, "GET", fmt.Sprintf("/users/%d/profile", user.ID), nil, &p)
+	if p.EnrolledCoursesCount != 1 || p.CompletedCoursesCount != 0 {
+		t.Fatalf("profile counts = %+v, want 1 enrolled, 0 completed", p)
+	}
+
+	raw := s.Expect(t, 409, "POST", fmt.Sprintf("/users/%d/enrollments", user.ID), map[string]interface{}{
+		"user_id": user.ID, "course_id": course.ID, "status": "completed",
+	}, nil)
+	if !strings.Contains(string(raw), "ENROLLMENT_DUPLICATE") {
+		t.Fatalf("duplicate enrollment body = %s", raw)
+	}
+
+	var list []idResponse
+	s.Expect(t, 200, "GET", fmt.Sprintf("/users/%d/enrollments", user.ID), nil, &list)
+	if len(list) != 1 || list[0].ID != enrollment.ID {
+		t.Fatalf("enrollments = %+v", list)
+	}
+
+	s.Expect(t, 200, "DELETE", fmt.Sprintf("/enrollments/%d", enrollment.ID), nil, nil)
+	s.Expect(t, 200, "GET", fmt.Sprintf("/users/%d/profile", user.ID), nil, &p)
+	if p.EnrolledCoursesCount != 0 {
+		t.Fatalf("enrolled count after delete = %d", p.EnrolledCoursesCount)
+	}
+}
+
+func TestConstraintErrorsAreProblems(t *testing.T) {
+	s := Start(t)
+	admin := s.As(AdminToken)
+
+	body := map[string]interface{}{"first_name": "Ada", "last_name": "Lovelace", "email": "ada@example.com"}
+	s.Expect(t, 201, "POST", "/users", body, nil)
+	raw := s.Expect(t, 409, "POST", "/users", body, nil)
+	if !strings.Contains(string(raw), "USER_EMAIL_TAKEN") || strings.Contains(string(raw), "pq:") {
+		t.Fatalf("email conflict body = %s", raw)
+	}
+
+	raw = admin.Expect(t, 422, "POST", "/courses/999/series", map[string]interface{}{"title": "Orphan"}, nil)
+	if !strings.Contains(string(raw), "COURSE_NOT_FOUND") {
+		t.Fatalf("orphan series body = %s", raw)
+	}
+
+	// Courses without a unique_id get distinct slugs generated from the title.
+	for _, want := range []string{"untitled", "untitled-2"} {
+		var course struct {
+			UniqueID string `json:"unique_id"`
+		}
+		admin.Expect(t, 201, "POST", "/courses", map[string]interface{}{"title": "Untitled", "content": "x"}, &course)
+		if course.UniqueID != want {
+			t.Fatalf("generated slug = %q, want %q", course.UniqueID, want)
+		}
+	}
+}
+
+// TestDeletingCourseHidesEnrollments checks that a soft-deleted course drops
+// out of its students' enrollments and comes back with them when restored.
+func TestDeletingCourseHidesEnrollments(t *testing.T) {
+	s := Start(t)
+	admin := s.As(AdminToken)
+
+	var user, course idResponse
+	s.Expect(t, 201, "POST", "/users", map[string]interface{}{"first_name": "A", "last_name": "B", "email": "ab@example.com"}, &user)
+	admin.Expect(t, 201, "POST", "/courses", map[string]interface{}{"title": "T", "content": "C"}, &course)
+	admin.Expect(t, 200, "POST", fmt.Sprintf("/courses/%d/publish", course.ID), nil, nil)
+	s.Expect(t, 201, "POST", fmt.Sprintf("/users/%d/enrollments", user.ID), map[string]interface{}{
+		"user_id": user.ID, "course_id": course.ID, "status": "completed",
+	}, nil)
+
+	admin.Expect(t, 200, "DELETE", fmt.Sprintf("/courses/%d", course.ID), nil, nil)
+	s.Expect(t, 404, "GET", fmt.Sprintf("/users/%d/enrollments", user.ID), nil, nil)
+	s.Expect(t, 403, "GET", fmt.Sprintf("/courses/%d?include_deleted=true", course.ID), nil, nil)
+
+	admin.Expect(t, 200, "POST", fmt.Sprintf("/courses/%d/restore", course.ID), nil, nil)
+	s.Expect(t, 200, "GET", fmt.Sprintf("/users/%d/enrollments", user.ID), nil, nil)
+}
+
+func TestGatewayValidatesAgainstOpenAPI(t *testing.T) {
+	s := Start(t)
+
+	s.Expect(t, 200, "GET", "/openapi.json", nil, nil)
+	raw := s.Expect(t, 400, "POST", "/users", map[string]interface{}{"first_name": "A", "email": "not-an-email"}, nil)
+	if !strings.Contains(string(raw), "VALIDATION_FAILED") || !strings.Contains(string(raw), "last_name: is required") {
+		t.Fatalf("validation body = %s", raw)
+	}
+	var users []interface{}
+	if status, _ := s.Call(t, "GET", "/users", nil, &users); status != 404 {
+		t.Fatalf("invalid user reached the service: GET /users = %d", status)
+	}
+}
+
+// TestOutboxPublishesEvents checks that committed changes reach the broker
+// once each, in order, and that rejected ones never do.
+func TestOutboxPublishesEvents(t *testing.T) {
+	s := Start(t)
+	admin := s.As(AdminToken)
+
+	var received []events.Event
+	s.Events.Subscribe(">", func(subject string, data []byte) error {
+		var e events.Event
+		if err := json.Unmarshal(data, &e); err != nil || e.Type != subject {
+			t.Errorf("message on %s = %s (%v)", subject, data, err)
+		}
+		received = append(received, e)
+		return nil
+	})
+
+	var user, course, enrollment idResponse
+	body := map[string]interface{}{"first_name": "Ada", "last_name": "Lovelace", "email": "ada@example.com"}
+	s.Expect(t, 201, "POST", "/users", body, &user)
+	s.Expect(t, 409, "POST", "/users", body, nil)
+	s.Expect(t, 200, "PUT", fmt.Sprintf("/users/%d/payment", user.ID), map[string]interface{}{"amount": 12.5}, nil)
+	admin.Expect(t, 201, "POST", "/courses", map[string]interface{}{"title": "T", "content": "C"}, &course)
+	admin.Expect(t, 200, "POST", fmt.Sprintf("/courses/%d/publish", course.ID), nil, nil)
+	s.Expect(t, 201, "POST", fmt.Sprintf("/users/%d/enrollments", user.ID), map[string]interface{}{
+		"user_id": user.ID, "course_id": course.ID, "status": "enrolled",
+	}, &enrollment)
+	for i := 0; i < 2; i++ {
+		admin.Expect(t, 200, "POST", fmt.Sprintf("/enrollments/%d/complete", enrollment.ID), nil, nil)
+	}
+	admin.Expect(t, 200, "DELETE", fmt.Sprintf("/courses/%d", course.ID), nil, nil)
+
+	s.Dispatch(t)
+	s.Dispatch(t)
+	var got []string
+	for _, e := range received {
+		// Postgres reorders the keys of JSONB; re-encoding sorts them.
+		var payload map[string]interface{}
+		json.Unmarshal(e.Payload, &payload)
+		sorted, _ := json.Marshal(payload)
+		got = append(got, e.Source+" "+e.Type+" "+string(sorted))
+	}
+	want := []string{
+		fmt.Sprintf(`user-service user.created {"email":"ada@example.com","first_name":"Ada","last_name":"Lovelace","user_id":%d}`, user.ID),
+		fmt.Sprintf(`user-service payment.recorded {"amount":12.5,"total_amount_paid":12.5,"user_id":%d}`, user.ID),
+		fmt.Sprintf(`course-service course.deleted {"course_id":%d}`, course.ID),
+		fmt.Sprintf(`enrollment-service enrollment.created {"course_id":%d,"enrollment_id":%d,"status":"enrolled","user_id":%d}`, course.ID, enrollment.ID, user.ID),
+		fmt.Sprintf(`enrollment-service enrollment.completed {"course_id":%d,"enrollment_id":%d,"user_id":%d}`, course.ID, enrollment.ID, user.ID),
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("events:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	var pending int
+	if err := s.DB.QueryRow(`SELECT COUNT(*) FROM user_outbox WHERE published_at IS NULL`).Scan(&pending); err != nil || pending != 0 {
+		t.Fatalf("unpublished user events = %d, %v", pending, err)
+	}
+}
@@ -0,0 +1,264 @@
+// Package integration boots Postgres, the three services and the gateway in
+// one process so cross-service flows can be tested end to end.
+package integration
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gofiber/fiber/v2"
+
+	courseHandler "course-service/handler"
+	courseRepository "course-service/repository"
+	courseService "course-service/service"
+	enrollmentHandler "enrollment-service/handler"
+	enrollmentRepository "enrollment-service/repository"
+	enrollmentService "enrollment-service/service"
+	"gateway-fiber/gateway"
+	"mopcare/database"
+	"mopcare/events"
+	"mopcare/media"
+	"mopcare/notify"
+	"mopcare/server"
+	"mopcare/webhooks"
+	userHandler "user-service/handler"
+	userRepository "user-service/repository"
+	userService "user-service/service"
+)
+
+// Stack is one running copy of the whole system on random local ports.
+type Stack struct {
+	DB            *sql.DB
+	GatewayURL    string
+	CourseURL     string
+	UserURL       string
+	EnrollmentURL string
+	// Token, when set, is sent as the bearer token by Call and Expect.
+	Token string
+	// Events receives what Dispatch publishes from the outboxes.
+	Events      *events.Local
+	dispatchers []*events.Dispatcher
+	// Webhooks sends the deliveries queued from Events.
+	Webhooks *webhooks.Deliverer
+	// Mail sends the emails queued from Events to Mailbox.
+	Mail    *notify.Mailer
+	Mailbox *Mailbox
+}
+
+// PublicURL is what links in emails start with; tests swap it for
+// GatewayURL to follow them.
+const PublicURL = "http://mopcare.test"
+
+// Mailbox is a notify.Sender that keeps what it is sent.
+type Mailbox struct {
+	mu       sync.Mutex
+	messages []notify.Message
+}
+
+func (m *Mailbox) Send(ctx context.Context, msg notify.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+// Messages returns what was sent so far, oldest first.
+func (m *Mailbox) Messages() []notify.Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]notify.Message(nil), m.messages...)
+}
+
+// AdminToken is the ADMIN_TOKEN every service in a Stack is started with.
+const AdminToken = "integration-admin"
+
+var dbCounter int64
+
+// Start creates a fresh, fully migrated database and boots every service and
+// the gateway against it. Everything is torn down when t finishes. The test
+// is skipped if no Postgres can be started.
+func Start(t *testing.T) *Stack {
+	t.Helper()
+	adminURL, err := Postgres()
+	if err != nil {
+		t.Skip(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+
+	dsn := createDatabase(t, adminURL)
+	sqlDB, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := database.Up(context.Background(), sqlDB); err != nil {
+		t.Fatalf("migrating: %v", err)
+	}
+	db := database.Wrap(sqlDB)
+	ready := &server.Readiness{}
+	cfg := server.Config{AdminToken: AdminToken, BodyLimit: 16 << 20, UserTokenTTL: time.Hour}
+
+	courses := courseRepository.NewPostgres(db)
+	mediaService := courseService.NewMediaService(courses, media.NewLocal(t.TempDir()),
+		media.NewSigner([]byte("integration"), time.Hour), courseService.MediaLimits{Image: 1 << 20, Video: 8 << 20})
+	s := &Stack{DB: sqlDB, Events: events.NewLocal()}
+	for _, outbox := range []struct{ table, source string }{
+		{events.UserOutbox, "user-service"}, {events.CourseOutbox, "course-service"}, {events.EnrollmentOutbox, "enrollment-service"},
+	} {
+		s.dispatchers = append(s.dispatchers, events.NewDispatcher(db, outbox.table, outbox.source, s.Events))
+	}
+	s.CourseURL = serveFiber(t, courseHandler.NewApp(courseService.NewCourseService(courses, courses, courses, courses, courses, courses, courses), mediaService, ready, cfg))
+	notes := notify.NewPostgres(db)
+	links := notify.NewLinks(PublicURL, AdminToken)
+	s.UserURL = serveHTTP(t, userHandler.NewRouter(userService.NewUserService(userRepository.NewPostgres(db)),
+		userService.NewNotificationService(notes, links), ready, cfg))
+	templates, err := notify.LoadTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unsubscribeNotes, err := notify.NewNotifier(notes, templates, links).Subscribe(s.Events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(unsubscribeNotes)
+	s.Mailbox = &Mailbox{}
+	s.Mail = notify.NewMailer(notes, s.Mailbox)
+	hooks := webhooks.NewPostgres(db)
+	unsubscribe, err := webhooks.Subscribe(s.Events, hooks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(unsubscribe)
+	s.Webhooks = webhooks.NewDeliverer(hooks, nil)
+	enrollments := enrollmentRepository.NewPostgres(db)
+	s.EnrollmentURL = serveHTTP(t, enrollmentHandler.NewRouter(enrollmentService.NewEnrollmentService(enrollments, enrollments, enrollments),
+		enrollmentService.NewWebhookService(hooks), ready, cfg))
+	s.GatewayURL = serveFiber(t, gateway.New(gateway.Config{
+		CourseServiceURL:     s.CourseURL,
+		UserServiceURL:       s.UserURL,
+		EnrollmentServiceURL: s.EnrollmentURL,
+	}, ready, cfg))
+	return s
+}
+
+// Dispatch publishes every pending outbox event to s.Events, which the
+// services' background jobs would otherwise do.
+func (s *Stack) Dispatch(t *testing.T) {
+	t.Helper()
+	for _, d := range s.dispatchers {
+		if err := d.Dispatch(context.Background()); err != nil {
+			t.Fatalf("dispatching events: %v", err)
+		}
+	}
+}
+
+// createDatabase makes an empty database for one Stack and drops it afterwards.
+func createDatabase(t *testing.T, adminURL string) string {
+	t.Helper()
+	admin, err := sql.Open("postgres", adminURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := fmt.Sprintf("mopcare_it_%d", atomic.AddInt64(&dbCounter, 1))
+	if _, err := admin.Exec("DROP DATABASE IF EXISTS " + name); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.Exec("CREATE DATABASE " + name); err != nil {
+		t.Fatalf("creating test database: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP DATABASE IF EXISTS " + name + " WITH (FORCE)")
+		admin.Close()
+	})
+
+	dsn, err := withDatabase(adminURL, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dsn
+}
+
+func serveHTTP(t *testing.T, handler http.Handler) string {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func serveFiber(t *testing.T, app *fiber.App) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(ln)
+	t.Cleanup(func() { app.Shutdown() })
+	return "http://" + ln.Addr().String()
+}
+
+// As returns a copy of s whose calls carry token.
+func (s *Stack) As(token string) *Stack {
+	as := *s
+	as.Token = token
+	return &as
+}
+
+// Call sends a JSON request through the gateway and decodes a JSON response
+// into out when out is non-nil. It returns the status code and raw body.
+func (s *Stack) Call(t *testing.T, method, path string, body, out interface{}) (int, []byte) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequest(method, s.GatewayURL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != nil && resp.StatusCode < 300 {
+		if err := json.Unmarshal(raw, out); err != nil {
+			t.Fatalf("%s %s: decoding %s: %v", method, path, raw, err)
+		}
+	}
+	return resp.StatusCode, raw
+}
+
+// Expect is Call that fails the test unless the status matches.
+func (s *Stack) Expect(t *testing.T, status int, method, path string, body, out interface{}) []byte {
+	t.Helper()
+	got, raw := s.Call(t, method, path, body, out)
+	if got != status {
+		t.Fatalf("%s %s = %d, want %d: %s", method, path, got, status, raw)
+	}
+	return raw
+}
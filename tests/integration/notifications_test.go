@@ -0,0 +1,69 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"mopcare/notify"
+)
+
+// TestNotificationsEmailUsers follows events from the outboxes to the
+// user's inbox, in the language they chose, until they unsubscribe through
+// the link in an email.
+func TestNotificationsEmailUsers(t *testing.T) {
+	s := Start(t)
+	admin := s.As(AdminToken)
+	send := func() []notify.Message {
+		t.Helper()
+		s.Dispatch(t)
+		if err := s.Mail.SendDue(context.Background()); err != nil {
+			t.Fatalf("sending notifications: %v", err)
+		}
+		return s.Mailbox.Messages()
+	}
+
+	var user, course, other idResponse
+	s.Expect(t, 201, "POST", "/users", map[string]interface{}{"first_name": "Ada", "last_name": "Lovelace", "email": "ada@example.com"}, &user)
+	if sent := send(); len(sent) != 1 || sent[0].To != "ada@example.com" || sent[0].Subject != "Welcome to Mopcare, Ada" {
+		t.Fatalf("after signing up: %+v", sent)
+	}
+
+	var token struct {
+		Token string `json:"token"`
+	}
+	admin.Expect(t, 201, "POST", fmt.Sprintf("/users/%d/token", user.ID), nil, &token)
+	ada := s.As(token.Token)
+	s.Expect(t, 401, "PUT", fmt.Sprintf("/users/%d/notification-preferences", user.ID), map[string]interface{}{"locale": "es"}, nil)
+	ada.Expect(t, 200, "PUT", fmt.Sprintf("/users/%d/notification-preferences", user.ID), map[string]interface{}{"locale": "es"}, nil)
+
+	admin.Expect(t, 201, "POST", "/courses", map[string]interface{}{"title": "Heart Health", "content": "C"}, &course)
+	admin.Expect(t, 200, "POST", fmt.Sprintf("/courses/%d/publish", course.ID), nil, nil)
+	s.Expect(t, 201, "POST", fmt.Sprintf("/users/%d/enrollments", user.ID), map[string]interface{}{
+		"user_id": user.ID, "course_id": course.ID, "status": "enrolled",
+	}, nil)
+	sent := send()
+	if len(sent) != 2 || sent[1].Subject != "Te has inscrito en Heart Health" || !strings.HasPrefix(sent[1].UnsubscribeURL, PublicURL) {
+		t.Fatalf("after enrolling: %+v", sent)
+	}
+
+	// The link in the email unsubscribes from enrollment emails only.
+	s.Expect(t, 200, "GET", strings.TrimPrefix(sent[1].UnsubscribeURL, PublicURL), nil, nil)
+	admin.Expect(t, 201, "POST", "/courses", map[string]interface{}{"title": "Diabetes Care", "content": "C"}, &other)
+	admin.Expect(t, 200, "POST", fmt.Sprintf("/courses/%d/publish", other.ID), nil, nil)
+	s.Expect(t, 201, "POST", fmt.Sprintf("/users/%d/enrollments", user.ID), map[string]interface{}{
+		"user_id": user.ID, "course_id": other.ID, "status": "enrolled",
+	}, nil)
+	s.Expect(t, 200, "PUT", fmt.Sprintf("/users/%d/payment", user.ID), map[string]interface{}{"amount": 12.5}, nil)
+	if sent = send(); len(sent) != 3 || sent[2].Subject != "Pago recibido" {
+		t.Fatalf("after unsubscribing from enrollments: %+v", sent)
+	}
+
+	var notifications []notify.Notification
+	ada.Expect(t, 200, "GET", fmt.Sprintf("/users/%d/notifications", user.ID), nil, &notifications)
+	if len(notifications) != 3 || notifications[0].EventType != "payment.recorded" || notifications[0].Status != notify.StatusSent {
+		t.Fatalf("notifications = %+v", notifications)
+	}
+	s.Expect(t, 403, "GET", "/notifications/unsubscribe?token=1.all.forged", nil, nil)
+}
@@ -0,0 +1,185 @@
+package integration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+// The Postgres server is started once per test binary and shared; every
+// Stack gets its own freshly created database on it.
+var (
+	pgOnce     sync.Once
+	pgAdminURL string
+	pgStop     = func() {}
+	pgErr      error
+)
+
+// Postgres returns the admin URL of the shared server, starting it on first
+// use. It tries, in order:
+//
+//  1. MOPCARE_TEST_DATABASE_URL, an existing server the tests may create
+//     and drop databases on;
+//  2. initdb/pg_ctl from POSTGRES_BIN, PATH or /usr/lib/postgresql/*/bin,
+//     run as a throwaway cluster in a temp directory;
+//  3. docker, running MOPCARE_TEST_POSTGRES_IMAGE (default postgres:16-alpine).
+func Postgres() (string, error) {
+	pgOnce.Do(func() {
+		if u := os.Getenv("MOPCARE_TEST_DATABASE_URL"); u != "" {
+			pgAdminURL = u
+			return
+		}
+		var errs []string
+		for _, launch := range []func() (string, func(), error){launchBinary, launchDocker} {
+			adminURL, stop, err := launch()
+			if err == nil {
+				pgAdminURL, pgStop = adminURL, stop
+				return
+			}
+			errs = append(errs, err.Error())
+		}
+		pgErr = fmt.Errorf("no Postgres available (%s); set MOPCARE_TEST_DATABASE_URL, POSTGRES_BIN or install Docker", strings.Join(errs, "; "))
+	})
+	return pgAdminURL, pgErr
+}
+
+// StopPostgres tears down a server started by Postgres. Call it from TestMain.
+func StopPostgres() {
+	pgStop()
+}
+
+func findPostgresBin() (string, error) {
+	if dir := os.Getenv("POSTGRES_BIN"); dir != "" {
+		return dir, nil
+	}
+	if path, err := exec.LookPath("initdb"); err == nil {
+		return filepath.Dir(path), nil
+	}
+	if matches, _ := filepath.Glob("/usr/lib/postgresql/*/bin/initdb"); len(matches) > 0 {
+		return filepath.Dir(matches[len(matches)-1]), nil
+	}
+	return "", errors.New("initdb not found")
+}
+
+func launchBinary() (string, func(), error) {
+	bin, err := findPostgresBin()
+	if err != nil {
+		return "", nil, err
+	}
+	dir, err := os.MkdirTemp("", "mopcare-pg-")
+	if err != nil {
+		return "", nil, err
+	}
+	data := filepath.Join(dir, "data")
+	if out, err := exec.Command(filepath.Join(bin, "initdb"), "-D", data, "-U", "postgres", "-A", "trust", "-E", "UTF8", "--no-sync").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("initdb: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	port, err := freePort()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+	opts := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off", port, dir)
+	pgCtl := filepath.Join(bin, "pg_ctl")
+	if out, err := exec.Command(pgCtl, "-D", data, "-l", filepath.Join(dir, "postgres.log"), "-o", opts, "-w", "start").CombinedOutput(); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("pg_ctl start: %v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	stop := func() {
+		exec.Command(pgCtl, "-D", data, "-m", "immediate", "stop").Run()
+		os.RemoveAll(dir)
+	}
+	adminURL := fmt.Sprintf("postgres://postgres@127.0.0.1:%d/postgres?sslmode=disable", port)
+	if err := waitReady(adminURL, 30*time.Second); err != nil {
+		stop()
+		return "", nil, err
+	}
+	return adminURL, stop, nil
+}
+
+func launchDocker() (string, func(), error) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return "", nil, errors.New("docker not found")
+	}
+	image := os.Getenv("MOPCARE_TEST_POSTGRES_IMAGE")
+	if image == "" {
+		image = "postgres:16-alpine"
+	}
+	out, err := exec.Command("docker", "run", "-d", "--rm",
+		"-e", "POSTGRES_PASSWORD=mopcare",
+		"-p", "127.0.0.1::5432",
+		image, "-c", "fsync=off").Output()
+	if err != nil {
+		return "", nil, fmt.Errorf("docker run %s: %v", image, err)
+	}
+	id := strings.TrimSpace(string(out))
+	stop := func() { exec.Command("docker", "rm", "-f", id).Run() }
+
+	out, err = exec.Command("docker", "port", id, "5432/tcp").Output()
+	if err != nil {
+		stop()
+		return "", nil, fmt.Errorf("docker port: %v", err)
+	}
+	hostPort := strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	adminURL := fmt.Sprintf("postgres://postgres:mopcare@%s/postgres?sslmode=disable", hostPort)
+	if err := waitReady(adminURL, 60*time.Second); err != nil {
+		stop()
+		return "", nil, err
+	}
+	return adminURL, stop, nil
+}
+
+func freePort() (int, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port, nil
+}
+
+func waitReady(dsn string, timeout time.Duration) error {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	deadline := time.Now().Add(timeout)
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err = db.PingContext(ctx)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("postgres not ready after %s: %v", timeout, err)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+// withDatabase returns dsn pointed at a different database name.
+func withDatabase(dsn, name string) (string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", err
+	}
+	u.Path = "/" + name
+	return u.String(), nil
+}
@@ -0,0 +1,65 @@
+package integration
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"mopcare/client"
+	"mopcare/problem"
+)
+
+// TestPrerequisites requires one course before another through the course
+// service and holds enrollments to it through the enrollment service.
+func TestPrerequisites(t *testing.T) {
+	s := Start(t)
+	admin := client.New(client.Config{BaseURL: s.GatewayURL, Token: AdminToken})
+	ctx := context.Background()
+
+	var courses []*client.Course
+	for _, title := range []string{"Heart Health", "Heart Surgery", "Heart Transplants"} {
+		course, err := admin.CreateCourse(ctx, client.CourseInput{Title: title, Content: "C"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := admin.PublishCourse(ctx, course.ID, nil, 0); err != nil {
+			t.Fatal(err)
+		}
+		courses = append(courses, course)
+	}
+	basics, surgery, transplants := courses[0].ID, courses[1].ID, courses[2].ID
+	if _, err := admin.AddPrerequisite(ctx, surgery, basics); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.AddPrerequisite(ctx, transplants, surgery); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.AddPrerequisite(ctx, basics, transplants); !errors.Is(err, client.ErrConflict) || client.Code(err) != problem.CodePrerequisiteCycle {
+		t.Fatalf("closing a cycle = %v, want PREREQUISITE_CYCLE", err)
+	}
+
+	user, err := admin.CreateUser(ctx, client.UserInput{FirstName: "Ada", LastName: "Lovelace", Email: "ada@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := admin.GetEligibility(ctx, user.ID, surgery)
+	if err != nil || e.Eligible || len(e.MissingPrerequisites) != 1 || e.MissingPrerequisites[0].Title != "Heart Health" {
+		t.Fatalf("GetEligibility = %+v, %v", e, err)
+	}
+	if _, err := admin.Enroll(ctx, client.EnrollmentInput{UserID: user.ID, CourseID: surgery, Status: "enrolled"}); client.Code(err) != problem.CodePrerequisitesMissing {
+		t.Fatalf("Enroll without the prerequisite = %v, want PREREQUISITES_MISSING", err)
+	}
+	enrollment, err := admin.Enroll(ctx, client.EnrollmentInput{UserID: user.ID, CourseID: basics, Status: "enrolled"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := admin.CompleteEnrollment(ctx, enrollment.ID); err != nil {
+		t.Fatal(err)
+	}
+	if e, err = admin.GetEligibility(ctx, user.ID, surgery); err != nil || !e.Eligible {
+		t.Fatalf("GetEligibility after completing the prerequisite = %+v, %v", e, err)
+	}
+	if _, err := admin.Enroll(ctx, client.EnrollmentInput{UserID: user.ID, CourseID: surgery, Status: "enrolled"}); err != nil {
+		t.Fatal(err)
+	}
+}
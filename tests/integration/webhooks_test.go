@@ -0,0 +1,99 @@
+package integration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"mopcare/webhooks"
+)
+
+// TestWebhooksDeliverSignedEvents follows events from the outboxes to a
+// partner's endpoint, through a dead letter and its redelivery.
+func TestWebhooksDeliverSignedEvents(t *testing.T) {
+	s := Start(t)
+	admin := s.As(AdminToken)
+	const secret = "integration-webhook-secret"
+
+	var mu sync.Mutex
+	var received []string
+	status := http.StatusOK
+	partner := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if err := webhooks.Verify(secret, r.Header.Get(webhooks.HeaderSignature), body, time.Minute, time.Now()); err != nil {
+			t.Errorf("%s delivery: %v", r.Header.Get(webhooks.HeaderEvent), err)
+		}
+		received = append(received, r.Header.Get(webhooks.HeaderEvent))
+		w.WriteHeader(status)
+	}))
+	defer partner.Close()
+	answer := func(code int) {
+		mu.Lock()
+		defer mu.Unlock()
+		status = code
+	}
+	deliver := func() {
+		t.Helper()
+		s.Dispatch(t)
+		if err := s.Webhooks.DeliverDue(context.Background()); err != nil {
+			t.Fatalf("delivering webhooks: %v", err)
+		}
+	}
+
+	var hook idResponse
+	s.Expect(t, 403, "POST", "/webhooks", map[string]interface{}{"url": partner.URL, "event_types": []string{"enrollment.created"}}, nil)
+	admin.Expect(t, 201, "POST", "/webhooks", map[string]interface{}{
+		"url": partner.URL, "event_types": []string{"enrollment.created", "payment.recorded"}, "secret": secret,
+	}, &hook)
+
+	var user, course idResponse
+	s.Expect(t, 201, "POST", "/users", map[string]interface{}{"first_name": "Ada", "last_name": "Lovelace", "email": "ada@example.com"}, &user)
+	admin.Expect(t, 201, "POST", "/courses", map[string]interface{}{"title": "T", "content": "C"}, &course)
+	admin.Expect(t, 200, "POST", fmt.Sprintf("/courses/%d/publish", course.ID), nil, nil)
+	s.Expect(t, 201, "POST", fmt.Sprintf("/users/%d/enrollments", user.ID), map[string]interface{}{
+		"user_id": user.ID, "course_id": course.ID, "status": "completed",
+	}, nil)
+	s.Expect(t, 200, "PUT", fmt.Sprintf("/users/%d/payment", user.ID), map[string]interface{}{"amount": 12.5}, nil)
+	deliver()
+	deliver()
+
+	mu.Lock()
+	got := fmt.Sprint(received)
+	mu.Unlock()
+	if got != "[payment.recorded enrollment.created]" && got != "[enrollment.created payment.recorded]" {
+		t.Fatalf("partner received %s, want the subscribed events once each", got)
+	}
+	var delivered []webhooks.Delivery
+	admin.Expect(t, 200, "GET", fmt.Sprintf("/webhooks/%d/deliveries?status=delivered", hook.ID), nil, &delivered)
+	if len(delivered) != 2 || len(delivered[0].Log) != 1 || delivered[0].Log[0].StatusCode != 200 {
+		t.Fatalf("delivered = %+v", delivered)
+	}
+
+	// A delivery the partner keeps failing becomes a dead letter...
+	answer(http.StatusServiceUnavailable)
+	s.Webhooks.MaxAttempts = 1
+	s.Expect(t, 200, "PUT", fmt.Sprintf("/users/%d/payment", user.ID), map[string]interface{}{"amount": 5}, nil)
+	deliver()
+	var dead []webhooks.Delivery
+	admin.Expect(t, 200, "GET", "/webhooks/dead-letters", nil, &dead)
+	if len(dead) != 1 || dead[0].EventType != "payment.recorded" || dead[0].Log[0].StatusCode != 503 {
+		t.Fatalf("dead letters = %+v", dead)
+	}
+
+	// ...until it is redelivered by hand.
+	answer(http.StatusOK)
+	admin.Expect(t, 202, "POST", fmt.Sprintf("/webhooks/%d/deliveries/%d/redeliver", hook.ID, dead[0].ID), nil, nil)
+	deliver()
+	admin.Expect(t, 200, "GET", "/webhooks/dead-letters", nil, &dead)
+	admin.Expect(t, 200, "GET", fmt.Sprintf("/webhooks/%d/deliveries?status=delivered", hook.ID), nil, &delivered)
+	if len(dead) != 0 || len(delivered) != 3 || len(delivered[0].Log) != 2 {
+		t.Fatalf("after redelivering: dead %+v, delivered %+v", dead, delivered)
+	}
+}
@@ -0,0 +1,146 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"mopcare/events"
+)
+
+// Deliverer sends due deliveries. Any number of them may share a Store.
+type Deliverer struct {
+	store  Store
+	client *http.Client
+	// MaxAttempts is how often a delivery is tried before it is dead.
+	MaxAttempts int
+	// Backoff is the wait after the first failed attempt. It doubles after
+	// each further failure, up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Batch is how many deliveries one DeliverDue sends, concurrently.
+	Batch int
+	now   func() time.Time
+}
+
+// NewDeliverer sends with client, or with a client that gives up after 10
+// seconds if client is nil. Deliveries are tried 8 times over about an hour
+// by default.
+func NewDeliverer(store Store, client *http.Client) *Deliverer {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Deliverer{store: store, client: client, MaxAttempts: 8, Backoff: 30 * time.Second, MaxBackoff: time.Hour, Batch: 20, now: time.Now}
+}
+
+// DeliverDue sends every delivery that is due, one batch at a time. A
+// failed attempt is recorded against its delivery rather than returned.
+func (d *Deliverer) DeliverDue(ctx context.Context) error {
+	// Claims outlast the client's timeout, so a delivery being sent is
+	// never claimed twice.
+	lease := d.client.Timeout + time.Minute
+	for {
+		claimed, err := d.store.Claim(ctx, d.now(), d.Batch, lease)
+		if err != nil {
+			return err
+		}
+		var wg sync.WaitGroup
+		errs := make([]error, len(claimed))
+		for i, c := range claimed {
+			wg.Add(1)
+			go func(i int, c Claimed) {
+				defer wg.Done()
+				errs[i] = d.deliver(ctx, c)
+			}(i, c)
+		}
+		wg.Wait()
+		for _, err := range errs {
+			if err != nil {
+				return err
+			}
+		}
+		if len(claimed) < d.Batch {
+			return nil
+		}
+	}
+}
+
+// deliver makes one attempt at c and records how it went.
+func (d *Deliverer) deliver(ctx context.Context, c Claimed) error {
+	a := d.send(ctx, c)
+	status, next := StatusDelivered, (*time.Time)(nil)
+	if a.StatusCode < 200 || a.StatusCode > 299 {
+		status = StatusDead
+		if attempts := c.Attempts + 1; attempts < d.MaxAttempts {
+			status = StatusPending
+			at := a.At.Add(d.backoff(attempts))
+			next = &at
+		}
+	}
+	return d.store.Record(ctx, c.ID, a, status, next)
+}
+
+// backoff is the wait after the attempts-th failed attempt.
+func (d *Deliverer) backoff(attempts int) time.Duration {
+	wait := d.Backoff
+	for i := 1; i < attempts && wait < d.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.MaxBackoff {
+		wait = d.MaxBackoff
+	}
+	return wait
+}
+
+func (d *Deliverer) send(ctx context.Context, c Claimed) Attempt {
+	a := Attempt{At: d.now()}
+	body := []byte(c.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Mopcare-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, c.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(c.ID, 10))
+	req.Header.Set(HeaderSignature, Sign(c.Secret, a.At, body))
+
+	began := time.Now()
+	resp, err := d.client.Do(req)
+	a.DurationMS = time.Since(began).Milliseconds()
+	if err != nil {
+		a.Error = err.Error()
+		return a
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	a.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		a.Error = fmt.Sprintf("endpoint answered %s", resp.Status)
+	}
+	return a
+}
+
+// Subscribe queues deliveries for every event broker publishes that
+// webhooks can subscribe to. Queuing is idempotent, so every service and
+// replica may subscribe. An event that cannot be queued is published again.
+func Subscribe(broker events.Broker, store Store) (unsubscribe func(), err error) {
+	return events.SubscribeAll(broker, EventTypes, func(ctx context.Context, e events.Event) error {
+		// Deliveries send the event as published, which it encodes back to.
+		payload, err := json.Marshal(e)
+		if err == nil {
+			err = store.Enqueue(ctx, e, payload)
+		}
+		if err != nil {
+			return fmt.Errorf("webhooks: queueing %s: %w", e.Type, err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,218 @@
+package webhooks
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"mopcare/events"
+	"mopcare/paging"
+)
+
+// Memory is an in-memory Store for tests. Setting Err makes every method
+// fail with it, to exercise error paths.
+type Memory struct {
+	mu         sync.Mutex
+	nextID     int
+	webhooks   map[int]Webhook
+	deliveries map[int64]Delivery
+
+	Err error
+}
+
+func NewMemory() *Memory {
+	return &Memory{nextID: 1, webhooks: map[int]Webhook{}, deliveries: map[int64]Delivery{}}
+}
+
+func (m *Memory) CreateWebhook(ctx context.Context, w *Webhook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	w.ID = m.nextID
+	m.nextID++
+	w.CreatedAt = time.Now()
+	w.UpdatedAt = w.CreatedAt
+	m.webhooks[w.ID] = *w
+	return nil
+}
+
+func (m *Memory) ListWebhooks(ctx context.Context, page paging.Page) ([]Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var hooks []Webhook
+	for _, w := range m.webhooks {
+		hooks = append(hooks, w)
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].ID < hooks[j].ID })
+	return paging.Slice(hooks, page), nil
+}
+
+func (m *Memory) GetWebhook(ctx context.Context, id int) (Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return Webhook{}, m.Err
+	}
+	w, ok := m.webhooks[id]
+	if !ok {
+		return Webhook{}, ErrNotFound
+	}
+	return w, nil
+}
+
+func (m *Memory) UpdateWebhook(ctx context.Context, w *Webhook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	old, ok := m.webhooks[w.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	w.CreatedAt = old.CreatedAt
+	w.UpdatedAt = time.Now()
+	m.webhooks[w.ID] = *w
+	return nil
+}
+
+func (m *Memory) DeleteWebhook(ctx context.Context, id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	if _, ok := m.webhooks[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.webhooks, id)
+	for did, d := range m.deliveries {
+		if d.WebhookID == id {
+			delete(m.deliveries, did)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) Enqueue(ctx context.Context, e events.Event, payload []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	now := time.Now()
+	for _, w := range m.webhooks {
+		if !w.Active || !w.Subscribed(e.Type) || m.queued(w.ID, e) {
+			continue
+		}
+		id := int64(m.nextID)
+		m.nextID++
+		m.deliveries[id] = Delivery{ID: id, WebhookID: w.ID, EventSource: e.Source, EventID: e.ID, EventType: e.Type,
+			Payload: string(payload), Status: StatusPending, Log: []Attempt{}, NextAttemptAt: &now, CreatedAt: now}
+	}
+	return nil
+}
+
+// queued mirrors UNIQUE(webhook_id, event_source, event_id).
+func (m *Memory) queued(webhookID int, e events.Event) bool {
+	for _, d := range m.deliveries {
+		if d.WebhookID == webhookID && d.EventSource == e.Source && d.EventID == e.ID {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Memory) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]Claimed, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var due []Delivery
+	for _, d := range m.deliveries {
+		if d.Status == StatusPending && !d.NextAttemptAt.After(now) && m.webhooks[d.WebhookID].Active {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if !due[i].NextAttemptAt.Equal(*due[j].NextAttemptAt) {
+			return due[i].NextAttemptAt.Before(*due[j].NextAttemptAt)
+		}
+		return due[i].ID < due[j].ID
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	claimed := make([]Claimed, len(due))
+	for i, d := range due {
+		until := now.Add(lease)
+		d.NextAttemptAt = &until
+		m.deliveries[d.ID] = d
+		w := m.webhooks[d.WebhookID]
+		claimed[i] = Claimed{Delivery: d, URL: w.URL, Secret: w.Secret}
+	}
+	return claimed, nil
+}
+
+func (m *Memory) Record(ctx context.Context, id int64, a Attempt, status string, next *time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return m.Err
+	}
+	d, ok := m.deliveries[id]
+	if !ok {
+		return ErrNotFound
+	}
+	d.Attempts++
+	d.Status = status
+	d.NextAttemptAt = next
+	d.DeliveredAt = nil
+	if status == StatusDelivered {
+		d.DeliveredAt = &a.At
+	}
+	d.Log = append(append([]Attempt{}, d.Log...), a)
+	m.deliveries[id] = d
+	return nil
+}
+
+func (m *Memory) ListDeliveries(ctx context.Context, webhookID int, status string, page paging.Page) ([]Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	var deliveries []Delivery
+	for _, d := range m.deliveries {
+		if (webhookID == 0 || d.WebhookID == webhookID) && (status == "" || d.Status == status) {
+			deliveries = append(deliveries, d)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].ID > deliveries[j].ID })
+	return paging.Slice(deliveries, page), nil
+}
+
+func (m *Memory) Redeliver(ctx context.Context, webhookID int, id int64, now time.Time) (Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.Err != nil {
+		return Delivery{}, m.Err
+	}
+	d, ok := m.deliveries[id]
+	if !ok || d.WebhookID != webhookID {
+		return Delivery{}, ErrNotFound
+	}
+	d.Status = StatusPending
+	d.Attempts = 0
+	d.NextAttemptAt = &now
+	d.DeliveredAt = nil
+	m.deliveries[id] = d
+	return d, nil
+}
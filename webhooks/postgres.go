@@ -0,0 +1,204 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+
+	"mopcare/database"
+	"mopcare/events"
+	"mopcare/paging"
+)
+
+// Postgres is the production Store.
+type Postgres struct {
+	db *database.DB
+}
+
+func NewPostgres(db *database.DB) *Postgres {
+	return &Postgres{db: db}
+}
+
+const webhookColumns = "id, url, event_types, secret, active, created_at, updated_at"
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanWebhook(row scanner) (Webhook, error) {
+	var w Webhook
+	err := row.Scan(&w.ID, &w.URL, pq.Array(&w.EventTypes), &w.Secret, &w.Active, &w.CreatedAt, &w.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Webhook{}, ErrNotFound
+	}
+	return w, err
+}
+
+func (p *Postgres) CreateWebhook(ctx context.Context, w *Webhook) error {
+	return p.db.QueryRowContext(ctx,
+		"INSERT INTO webhooks (url, event_types, secret, active) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at",
+		w.URL, pq.Array(w.EventTypes), w.Secret, w.Active,
+	).Scan(&w.ID, &w.CreatedAt, &w.UpdatedAt)
+}
+
+func (p *Postgres) ListWebhooks(ctx context.Context, page paging.Page) ([]Webhook, error) {
+	rows, err := p.db.QueryContext(ctx, "SELECT "+webhookColumns+" FROM webhooks ORDER BY id LIMIT $1 OFFSET $2", page.LimitArg(), page.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var hooks []Webhook
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, w)
+	}
+	return hooks, rows.Err()
+}
+
+func (p *Postgres) GetWebhook(ctx context.Context, id int) (Webhook, error) {
+	return scanWebhook(p.db.QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", id))
+}
+
+func (p *Postgres) UpdateWebhook(ctx context.Context, w *Webhook) error {
+	err := p.db.QueryRowContext(ctx,
+		"UPDATE webhooks SET url = $2, event_types = $3, secret = $4, active = $5, updated_at = NOW() WHERE id = $1 RETURNING created_at, updated_at",
+		w.ID, w.URL, pq.Array(w.EventTypes), w.Secret, w.Active,
+	).Scan(&w.CreatedAt, &w.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (p *Postgres) DeleteWebhook(ctx context.Context, id int) error {
+	result, err := p.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	return expectRow(result)
+}
+
+func (p *Postgres) Enqueue(ctx context.Context, e events.Event, payload []byte) error {
+	_, err := p.db.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event_source, event_id, event_type, payload, next_attempt_at)
+		 SELECT id, $1::text, $2::bigint, $3::text, $4::text, NOW() FROM webhooks WHERE active AND $3::text = ANY(event_types)
+		 ON CONFLICT (webhook_id, event_source, event_id) DO NOTHING`,
+		e.Source, e.ID, e.Type, string(payload),
+	)
+	return err
+}
+
+const deliveryColumns = "d.id, d.webhook_id, d.event_source, d.event_id, d.event_type, d.payload, d.status, d.attempts, d.log, d.next_attempt_at, d.delivered_at, d.created_at"
+
+func scanDelivery(row scanner, extra ...interface{}) (Delivery, error) {
+	var d Delivery
+	var raw []byte
+	err := row.Scan(append([]interface{}{&d.ID, &d.WebhookID, &d.EventSource, &d.EventID, &d.EventType, &d.Payload,
+		&d.Status, &d.Attempts, &raw, &d.NextAttemptAt, &d.DeliveredAt, &d.CreatedAt}, extra...)...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Delivery{}, ErrNotFound
+	}
+	if err != nil {
+		return Delivery{}, err
+	}
+	return d, json.Unmarshal(raw, &d.Log)
+}
+
+// Claim skips rows another deliverer has locked, so several can claim at
+// once without waiting for each other.
+func (p *Postgres) Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]Claimed, error) {
+	rows, err := p.db.QueryContext(ctx,
+		`UPDATE webhook_deliveries d SET next_attempt_at = $2
+		 FROM webhooks w
+		 WHERE w.id = d.webhook_id AND d.id IN (
+		     SELECT due.id FROM webhook_deliveries due JOIN webhooks hook ON hook.id = due.webhook_id AND hook.active
+		     WHERE due.status = 'pending' AND due.next_attempt_at <= $1
+		     ORDER BY due.next_attempt_at, due.id LIMIT $3
+		     FOR UPDATE OF due SKIP LOCKED
+		 )
+		 RETURNING `+deliveryColumns+`, w.url, w.secret`,
+		now, now.Add(lease), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var claimed []Claimed
+	for rows.Next() {
+		var c Claimed
+		d, err := scanDelivery(rows, &c.URL, &c.Secret)
+		if err != nil {
+			return nil, err
+		}
+		c.Delivery = d
+		claimed = append(claimed, c)
+	}
+	return claimed, rows.Err()
+}
+
+func (p *Postgres) Record(ctx context.Context, id int64, a Attempt, status string, next *time.Time) error {
+	entry, err := json.Marshal([]Attempt{a})
+	if err != nil {
+		return err
+	}
+	result, err := p.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries SET attempts = attempts + 1, status = $2::text, next_attempt_at = $3,
+		     delivered_at = CASE WHEN $2::text = 'delivered' THEN $4::timestamptz END, log = log || $5::jsonb
+		 WHERE id = $1`,
+		id, status, next, a.At, string(entry),
+	)
+	if err != nil {
+		return err
+	}
+	return expectRow(result)
+}
+
+func (p *Postgres) ListDeliveries(ctx context.Context, webhookID int, status string, page paging.Page) ([]Delivery, error) {
+	rows, err := p.db.QueryContext(ctx,
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries d
+		 WHERE ($1 = 0 OR d.webhook_id = $1) AND ($2::text = '' OR d.status = $2::text)
+		 ORDER BY d.id DESC LIMIT $3 OFFSET $4`,
+		webhookID, status, page.LimitArg(), page.Offset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var deliveries []Delivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (p *Postgres) Redeliver(ctx context.Context, webhookID int, id int64, now time.Time) (Delivery, error) {
+	return scanDelivery(p.db.QueryRowContext(ctx,
+		`UPDATE webhook_deliveries d SET status = 'pending', attempts = 0, next_attempt_at = $3, delivered_at = NULL
+		 WHERE d.id = $2 AND d.webhook_id = $1
+		 RETURNING `+deliveryColumns,
+		webhookID, id, now,
+	))
+}
+
+// expectRow turns an UPDATE/DELETE that touched nothing into ErrNotFound.
+func expectRow(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
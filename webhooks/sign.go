@@ -0,0 +1,60 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery.
+const (
+	HeaderSignature = "X-Mopcare-Signature"
+	HeaderEvent     = "X-Mopcare-Event"
+	HeaderDelivery  = "X-Mopcare-Delivery"
+)
+
+// ErrSignatureInvalid is returned by Verify for a missing, forged or stale
+// signature.
+var ErrSignatureInvalid = errors.New("webhooks: invalid signature")
+
+// Sign returns the X-Mopcare-Signature value for body sent at t:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<unix seconds>.<body>">".
+// Covering the time lets receivers reject replays of old deliveries.
+func Sign(secret string, t time.Time, body []byte) string {
+	unix := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + unix + ",v1=" + mac(secret, unix, body)
+}
+
+// Verify checks a signature header against body, accepting it for
+// tolerance either side of now. Receivers written in Go can use it as is.
+func Verify(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var unix, signature string
+	for _, part := range strings.Split(header, ",") {
+		k, v, _ := strings.Cut(part, "=")
+		switch k {
+		case "t":
+			unix = v
+		case "v1":
+			signature = v
+		}
+	}
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil || !hmac.Equal([]byte(signature), []byte(mac(secret, unix, body))) {
+		return ErrSignatureInvalid
+	}
+	if skew := now.Sub(time.Unix(seconds, 0)); skew > tolerance || skew < -tolerance {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+func mac(secret, unix string, body []byte) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(unix + "."))
+	m.Write(body)
+	return hex.EncodeToString(m.Sum(nil))
+}
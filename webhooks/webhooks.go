@@ -0,0 +1,111 @@
+// Package webhooks notifies external systems of domain events. Admins
+// subscribe a URL to some event types; every matching event is queued as a
+// delivery, POSTed with an HMAC-SHA256 signature, and retried with
+// exponential backoff until the endpoint accepts it or the delivery runs out
+// of attempts and becomes a dead letter, which can be redelivered by hand.
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"mopcare/events"
+	"mopcare/paging"
+)
+
+// ErrNotFound is returned by a Store for a missing webhook or delivery.
+var ErrNotFound = errors.New("webhooks: not found")
+
+// EventTypes are the events webhooks can subscribe to.
+var EventTypes = []string{events.TypeEnrollmentCreated, events.TypeEnrollmentCompleted, events.TypePaymentRecorded}
+
+// Delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+// Webhook is a subscription of URL to EventTypes.
+type Webhook struct {
+	ID         int       `json:"id"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	Secret     string    `json:"secret"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Subscribed reports whether w wants events of eventType.
+func (w Webhook) Subscribed(eventType string) bool {
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is one event on its way to one webhook. Payload is the request
+// body, the event as it was published.
+type Delivery struct {
+	ID          int64     `json:"id"`
+	WebhookID   int       `json:"webhook_id"`
+	EventSource string    `json:"event_source"`
+	EventID     int64     `json:"event_id"`
+	EventType   string    `json:"event_type"`
+	Payload     string    `json:"payload"`
+	Status      string    `json:"status"`
+	Attempts    int       `json:"attempts"`
+	Log         []Attempt `json:"log"`
+	// NextAttemptAt is only set while the delivery is pending.
+	NextAttemptAt *time.Time `json:"next_attempt_at"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// Attempt is the outcome of one POST of a delivery. StatusCode is 0 when no
+// response arrived.
+type Attempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// Claimed is a due delivery together with where and how to send it.
+type Claimed struct {
+	Delivery
+	URL    string
+	Secret string
+}
+
+// Store keeps webhooks and their deliveries.
+type Store interface {
+	CreateWebhook(ctx context.Context, w *Webhook) error
+	ListWebhooks(ctx context.Context, page paging.Page) ([]Webhook, error)
+	GetWebhook(ctx context.Context, id int) (Webhook, error)
+	// UpdateWebhook saves URL, EventTypes, Secret and Active.
+	UpdateWebhook(ctx context.Context, w *Webhook) error
+	DeleteWebhook(ctx context.Context, id int) error
+
+	// Enqueue queues a delivery of e, whose published form is payload, to
+	// every active webhook subscribed to its type. Enqueuing an event again
+	// changes nothing.
+	Enqueue(ctx context.Context, e events.Event, payload []byte) error
+	// Claim returns up to limit pending deliveries of active webhooks that
+	// are due at now, and postpones them by lease so that no other
+	// deliverer picks them up in the meantime.
+	Claim(ctx context.Context, now time.Time, limit int, lease time.Duration) ([]Claimed, error)
+	// Record logs an attempt at delivery id and moves it to status, to be
+	// tried again at next if it is still pending.
+	Record(ctx context.Context, id int64, a Attempt, status string, next *time.Time) error
+	// ListDeliveries returns deliveries newest first, of one webhook or of
+	// all of them for webhookID 0, optionally only those with status.
+	ListDeliveries(ctx context.Context, webhookID int, status string, page paging.Page) ([]Delivery, error)
+	// Redeliver makes a delivery of the webhook pending again, due at now
+	// and with its attempts reset.
+	Redeliver(ctx context.Context, webhookID int, id int64, now time.Time) (Delivery, error)
+}
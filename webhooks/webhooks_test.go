@@ -0,0 +1,210 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"mopcare/events"
+	"mopcare/paging"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":1}`)
+	header := Sign("whsec", now, body)
+	if !strings.HasPrefix(header, "t=1700000000,v1=") || len(header) != len("t=1700000000,v1=")+64 {
+		t.Fatalf("Sign = %q, want the time and a hex SHA-256", header)
+	}
+	if err := Verify("whsec", header, body, time.Minute, now.Add(30*time.Second)); err != nil {
+		t.Errorf("Verify(genuine) = %v", err)
+	}
+	for name, check := range map[string]error{
+		"wrong secret":  Verify("other", header, body, time.Minute, now),
+		"tampered body": Verify("whsec", header, []byte(`{"id":2}`), time.Minute, now),
+		"stale":         Verify("whsec", header, body, time.Minute, now.Add(2*time.Minute)),
+		"malformed":     Verify("whsec", "v1=abc", body, time.Minute, now),
+	} {
+		if check != ErrSignatureInvalid {
+			t.Errorf("%s: Verify = %v, want ErrSignatureInvalid", name, check)
+		}
+	}
+}
+
+// publish sends an event the way a Dispatcher would.
+func publish(t *testing.T, b events.Broker, id int64, eventType string) {
+	t.Helper()
+	data, _ := json.Marshal(events.Event{ID: id, Source: "enrollment-service", Type: eventType, Payload: json.RawMessage(`{"enrollment_id":1}`)})
+	if err := b.Publish(context.Background(), eventType, data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSubscribeQueuesEachEventOncePerWebhook(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemory()
+	store.CreateWebhook(ctx, &Webhook{URL: "http://a", EventTypes: []string{events.TypeEnrollmentCompleted}, Active: true})
+	store.CreateWebhook(ctx, &Webhook{URL: "http://b", EventTypes: EventTypes, Active: true})
+	store.CreateWebhook(ctx, &Webhook{URL: "http://c", EventTypes: EventTypes})
+
+	broker := events.NewLocal()
+	unsubscribe, err := Subscribe(broker, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publish(t, broker, 1, events.TypeEnrollmentCompleted)
+	publish(t, broker, 1, events.TypeEnrollmentCompleted) // redelivered by the broker
+	publish(t, broker, 2, events.TypeEnrollmentCreated)
+	publish(t, broker, 3, events.TypeCourseDeleted)
+	unsubscribe()
+	publish(t, broker, 4, events.TypeEnrollmentCreated)
+
+	queued := map[int][]int64{}
+	deliveries, _ := store.ListDeliveries(ctx, 0, StatusPending, paging.Page{})
+	for _, d := range deliveries {
+		queued[d.WebhookID] = append(queued[d.WebhookID], d.EventID)
+	}
+	if len(queued) != 2 || len(queued[1]) != 1 || len(queued[2]) != 2 {
+		t.Errorf("queued event IDs by webhook = %v, want 1: [1], 2: [2 1]", queued)
+	}
+}
+
+// flaky is a Store whose Enqueue fails as often as fail says first.
+type flaky struct {
+	*Memory
+	fail int
+}
+
+func (f *flaky) Enqueue(ctx context.Context, e events.Event, payload []byte) error {
+	if f.fail > 0 {
+		f.fail--
+		return errors.New("connection pool exhausted")
+	}
+	return f.Memory.Enqueue(ctx, e, payload)
+}
+
+func TestSubscribeFailsUntilQueued(t *testing.T) {
+	var received int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { received++ }))
+	defer srv.Close()
+	ctx := context.Background()
+	store := &flaky{Memory: NewMemory(), fail: 1}
+	store.CreateWebhook(ctx, &Webhook{URL: srv.URL, EventTypes: EventTypes, Secret: "whsec", Active: true})
+	broker := events.NewLocal()
+	unsubscribe, err := Subscribe(broker, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribe()
+
+	// The failure reaches the publisher, which publishes the event again
+	// as a Dispatcher does on its next run.
+	data, _ := json.Marshal(events.Event{ID: 1, Source: "enrollment-service", Type: events.TypeEnrollmentCompleted, Payload: json.RawMessage(`{"enrollment_id":1}`)})
+	if err := broker.Publish(ctx, events.TypeEnrollmentCompleted, data); err == nil {
+		t.Fatal("Publish succeeded though the delivery was not queued")
+	}
+	publish(t, broker, 1, events.TypeEnrollmentCompleted)
+	if err := NewDeliverer(store, nil).DeliverDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if received != 1 {
+		t.Errorf("endpoint received %d deliveries, want 1", received)
+	}
+}
+
+func TestDeliverer(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	var received []*http.Request
+	status := http.StatusInternalServerError
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		if err := Verify("whsec", r.Header.Get(HeaderSignature), body, time.Minute, time.Now()); err != nil {
+			t.Errorf("delivery signature: %v", err)
+		}
+		received = append(received, r)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	store := NewMemory()
+	store.CreateWebhook(ctx, &Webhook{URL: srv.URL, EventTypes: EventTypes, Secret: "whsec", Active: true})
+	store.Enqueue(ctx, events.Event{ID: 7, Source: "enrollment-service", Type: events.TypeEnrollmentCompleted}, []byte(`{"id":7}`))
+	d := NewDeliverer(store, nil)
+	d.MaxAttempts = 2
+	clock := time.Now()
+	d.now = func() time.Time { return clock }
+
+	delivery := func() Delivery {
+		t.Helper()
+		deliveries, err := store.ListDeliveries(ctx, 1, "", paging.Page{})
+		if err != nil || len(deliveries) != 1 {
+			t.Fatalf("deliveries = %+v, %v", deliveries, err)
+		}
+		return deliveries[0]
+	}
+
+	// A failure is retried after the backoff, and not before.
+	if err := d.DeliverDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got := delivery()
+	if got.Status != StatusPending || got.Attempts != 1 || !got.NextAttemptAt.Equal(clock.Add(30*time.Second)) {
+		t.Fatalf("after a failure: %+v", got)
+	}
+	if a := got.Log[0]; a.StatusCode != 500 || a.Error != "endpoint answered 500 Internal Server Error" {
+		t.Errorf("attempt = %+v", a)
+	}
+	d.DeliverDue(ctx)
+	if len(received) != 1 {
+		t.Fatalf("retried %d times before the backoff elapsed", len(received)-1)
+	}
+
+	// Running out of attempts makes it a dead letter.
+	clock = clock.Add(30 * time.Second)
+	d.DeliverDue(ctx)
+	if got := delivery(); got.Status != StatusDead || got.Attempts != 2 || got.NextAttemptAt != nil || len(got.Log) != 2 {
+		t.Fatalf("after the last attempt: %+v", got)
+	}
+
+	// Redelivering by hand starts over, keeping the log.
+	mu.Lock()
+	status = http.StatusNoContent
+	mu.Unlock()
+	if _, err := store.Redeliver(ctx, 1, got.ID, clock); err != nil {
+		t.Fatal(err)
+	}
+	d.DeliverDue(ctx)
+	got = delivery()
+	if got.Status != StatusDelivered || got.Attempts != 1 || got.DeliveredAt == nil || len(got.Log) != 3 {
+		t.Fatalf("after redelivering: %+v", got)
+	}
+	r := received[2]
+	if r.Header.Get(HeaderEvent) != events.TypeEnrollmentCompleted || r.Header.Get(HeaderDelivery) != "2" || r.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("headers = %v", r.Header)
+	}
+}
+
+func TestBackoffDoublesUpToTheCap(t *testing.T) {
+	d := NewDeliverer(NewMemory(), nil)
+	var waits []time.Duration
+	for attempts := 1; attempts <= 9; attempts++ {
+		waits = append(waits, d.backoff(attempts))
+	}
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute,
+		16 * time.Minute, 32 * time.Minute, time.Hour, time.Hour}
+	for i := range want {
+		if waits[i] != want[i] {
+			t.Fatalf("backoff = %v, want %v", waits, want)
+		}
+	}
+}